@@ -0,0 +1,496 @@
+package playground
+
+import (
+  "bytes"
+  "cmp"
+  "encoding/json"
+  "errors"
+  "fmt"
+  "io"
+  "maps"
+  "net/http"
+  "slices"
+  "strconv"
+  "strings"
+  "time"
+)
+
+// An assertion is a check made on the response of a request, such as its status being 200.
+type assertion struct {
+  subject  string // What is checked: status, header, json or duration.
+  name     string // The name of the header or the JSON path of the value that is checked.
+  operator string // How it is checked: ==, !=, <, <=, >, >=, contains or exists.
+  expected string // The expected value: a status code, a header value, a JSON document or a duration.
+}
+
+// assertionOperators are the operators of every subject.
+var assertionOperators = map[string][]string{
+  "status":   {"==", "!=", "<", "<=", ">", ">="},
+  "header":   {"==", "!=", "contains", "exists"},
+  "json":     {"==", "!=", "<", "<=", ">", ">=", "contains", "exists"},
+  "duration": {"<", "<=", ">", ">="},
+}
+
+// parseAssertion parses a check written as <subject> <operator> [<expected>], E.g: status == 200,
+// header["Content-Type"] contains json, $.data[0].id exists or duration < 500ms. The expected value of
+// a JSON path that is not valid JSON is taken as a string.
+func parseAssertion(text string) (a assertion, err error) {
+  rest := strings.TrimSpace(text)
+
+  switch {
+  default:
+    return a, fmt.Errorf("unknown subject in %q", text)
+  case strings.HasPrefix(rest, "status"):
+    a.subject, rest = "status", rest[len("status"):]
+  case strings.HasPrefix(rest, "duration"):
+    a.subject, rest = "duration", rest[len("duration"):]
+  case strings.HasPrefix(rest, "header["):
+    end := strings.IndexByte(rest, ']')
+    if -1 == end {
+      return a, fmt.Errorf("unclosed [ in %q", text)
+    }
+
+    a.subject, a.name, rest = "header", strings.TrimSpace(rest[len("header["):end]), rest[end+1:]
+    if unquoted, err := strconv.Unquote(a.name); nil == err {
+      a.name = unquoted
+    } else if len(a.name) >= 2 && '\'' == a.name[0] && '\'' == a.name[len(a.name)-1] {
+      a.name = a.name[1 : len(a.name)-1]
+    }
+
+    if "" == a.name {
+      return a, fmt.Errorf("missing header name in %q", text)
+    }
+  case strings.HasPrefix(rest, "$"):
+    end, depth, quote := len(rest), 0, byte(0)
+    for n := 0; n < len(rest) && end == len(rest); n++ {
+      switch c := rest[n]; {
+      case 0 != quote:
+        if c == quote {
+          quote = 0
+        }
+      case '\'' == c || '"' == c:
+        quote = c
+      case '[' == c:
+        depth++
+      case ']' == c:
+        depth--
+      case 0 == depth && strings.ContainsRune(" \t=!<>", rune(c)):
+        end = n
+      }
+    }
+
+    a.subject, a.name, rest = "json", rest[:end], rest[end:]
+    if _, err := parseJSONPath(a.name); nil != err {
+      return a, err
+    }
+  }
+
+  if "" != rest && !strings.ContainsRune(" \t=!<>", rune(rest[0])) {
+    return a, fmt.Errorf("unknown subject in %q", text)
+  }
+
+  rest = strings.TrimSpace(rest)
+  for operator := range slices.Values([]string{"==", "!=", "<=", ">=", "<", ">", "contains", "exists"}) {
+    if strings.HasPrefix(rest, operator) {
+      a.operator, rest = operator, strings.TrimSpace(rest[len(operator):])
+      break
+    }
+  }
+
+  switch {
+  case "" == a.operator:
+    return a, fmt.Errorf("missing operator in %q", text)
+  case !slices.Contains(assertionOperators[a.subject], a.operator):
+    return a, fmt.Errorf("%s cannot be checked with %s", a.subject, a.operator)
+  case "exists" == a.operator && "" != rest:
+    return a, fmt.Errorf("unexpected %q after exists", rest)
+  case "exists" != a.operator && "" == rest:
+    return a, fmt.Errorf("missing expected value in %q", text)
+  }
+
+  switch {
+  case "exists" == a.operator:
+  case "status" == a.subject:
+    if _, err := strconv.Atoi(rest); nil != err {
+      return a, fmt.Errorf("invalid status %q", rest)
+    }
+  case "header" == a.subject:
+    if unquoted, err := strconv.Unquote(rest); nil == err {
+      rest = unquoted
+    }
+  case "json" == a.subject:
+    if !json.Valid([]byte(rest)) {
+      encoded, _ := json.Marshal(rest)
+      rest = string(encoded)
+    }
+
+    compacted := &bytes.Buffer{}
+    json.Compact(compacted, []byte(rest))
+    rest = compacted.String()
+
+    switch a.operator {
+    case "<", "<=", ">", ">=":
+      var number float64
+      if nil != json.Unmarshal(compacted.Bytes(), &number) {
+        return a, fmt.Errorf("%s needs a number, got %s", a.operator, rest)
+      }
+    }
+  case "duration" == a.subject:
+    if ms, err := strconv.Atoi(rest); nil == err {
+      rest = fmt.Sprint(ms, "ms")
+    }
+
+    if _, err := time.ParseDuration(rest); nil != err {
+      return a, fmt.Errorf("invalid duration %q", rest)
+    }
+  }
+
+  a.expected = rest
+  return a, nil
+}
+
+// String writes a in the way it reads, E.g: status == 200 or $.data[0].id == 5, which can be parsed back.
+func (a assertion) String() string {
+  subject := a.subject
+  switch a.subject {
+  case "header":
+    subject = fmt.Sprintf("header[%s]", strconv.Quote(a.name))
+  case "json":
+    subject = a.name
+  }
+
+  switch {
+  case "exists" == a.operator:
+    return fmt.Sprint(subject, " exists")
+  case "header" == a.subject:
+    return fmt.Sprint(subject, " ", a.operator, " ", strconv.Quote(a.expected))
+  default:
+    return fmt.Sprint(subject, " ", a.operator, " ", a.expected)
+  }
+}
+
+// compare reports whether got and want are related by operator.
+func compare[T int | float64 | time.Duration](operator string, got, want T) bool {
+  switch operator {
+  case "==":
+    return got == want
+  case "!=":
+    return got != want
+  case "<":
+    return got < want
+  case "<=":
+    return got <= want
+  case ">":
+    return got > want
+  case ">=":
+    return got >= want
+  }
+
+  return false
+}
+
+// decodeExactJSON decodes the JSON document input with its numbers kept as json.Number, so that they
+// are compared without being rounded to a float64.
+func decodeExactJSON(input []byte) (document any, err error) {
+  decoder := json.NewDecoder(bytes.NewReader(input))
+  decoder.UseNumber()
+  if err = decoder.Decode(&document); nil != err {
+    return nil, err
+  }
+
+  if _, err = decoder.Token(); !errors.Is(err, io.EOF) {
+    return nil, errors.New("invalid JSON after the document")
+  }
+
+  return document, nil
+}
+
+// exactJSONEqual reports whether two values decoded by decodeExactJSON are equal, their numbers by
+// their exact values, so that 1.0 equals 1 and 9007199254740993 does not equal 9007199254740992.
+func exactJSONEqual(a, b any) bool {
+  switch a := a.(type) {
+  case json.Number:
+    b, ok := b.(json.Number)
+    return ok && 0 == compareJSONNumbers(a, b)
+  case []any:
+    b, ok := b.([]any)
+    return ok && slices.EqualFunc(a, b, exactJSONEqual)
+  case map[string]any:
+    b, ok := b.(map[string]any)
+    return ok && maps.EqualFunc(a, b, exactJSONEqual)
+  }
+
+  return a == b
+}
+
+// compareJSONNumbers orders two JSON numbers by their exact values. Each is read as ±0.digits×10^exponent,
+// whose digits have no leading or trailing zeros, so that they are compared digit by digit, however
+// many they have or however large their exponents are.
+func compareJSONNumbers(a, b json.Number) int {
+  sign := func(negative bool, digits string) int {
+    switch {
+    case "" == digits:
+      return 0
+    case negative:
+      return -1
+    default:
+      return 1
+    }
+  }
+
+  an, ad, ae := decimalJSONNumber(a)
+  bn, bd, be := decimalJSONNumber(b)
+  as, bs := sign(an, ad), sign(bn, bd)
+  if as != bs || 0 == as {
+    return cmp.Compare(as, bs)
+  }
+
+  magnitude := cmp.Compare(ae, be)
+  if 0 == magnitude {
+    magnitude = strings.Compare(ad, bd)
+  }
+
+  return as * magnitude
+}
+
+// decimalJSONNumber splits the JSON number n into its sign, its significant digits and the exponent
+// that makes it 0.digits×10^exponent. An exponent too large for an int64 is clamped.
+func decimalJSONNumber(n json.Number) (negative bool, digits string, exponent int64) {
+  mantissa, power, _ := strings.Cut(strings.ToLower(string(n)), "e")
+  negative, mantissa = strings.HasPrefix(mantissa, "-"), strings.TrimPrefix(mantissa, "-")
+  if "" != power {
+    var err error
+    if exponent, err = strconv.ParseInt(power, 10, 64); nil != err { /* Out of range.  */
+      exponent = 1 << 62
+      if strings.HasPrefix(power, "-") {
+        exponent = -exponent
+      }
+    }
+  }
+
+  whole, fraction, _ := strings.Cut(mantissa, ".")
+  digits = whole + fraction
+  exponent += int64(len(whole))
+
+  trimmed := strings.TrimLeft(digits, "0")
+  exponent -= int64(len(digits) - len(trimmed))
+  digits = strings.TrimRight(trimmed, "0")
+  if "" == digits {
+    exponent = 0
+  }
+
+  return negative, digits, exponent
+}
+
+// evaluate checks a against response and returns why it does not hold, if it does not.
+func (a assertion) evaluate(response *responseBuilder) error {
+  switch a.subject {
+  default:
+    return fmt.Errorf("unknown subject %q", a.subject)
+  case "status":
+    want, _ := strconv.Atoi(a.expected)
+    switch {
+    case "==" == a.operator && response.status != want:
+      return fmt.Errorf("expected status %s, got %d", a.expected, response.status)
+    case !compare(a.operator, response.status, want):
+      return fmt.Errorf("expected status %s %s, got %d", a.operator, a.expected, response.status)
+    }
+  case "header":
+    key := http.CanonicalHeaderKey(a.name)
+    values, exists := response.header[key]
+    switch {
+    case !exists && "!=" != a.operator:
+      return fmt.Errorf("header %s is missing", key)
+    case "exists" == a.operator:
+    case "contains" == a.operator && !slices.ContainsFunc(values, func(v string) bool { return strings.Contains(v, a.expected) }):
+      return fmt.Errorf("expected header %s to contain %q, got %q", key, a.expected, strings.Join(values, ", "))
+    case "==" == a.operator && !slices.Contains(values, a.expected):
+      return fmt.Errorf("expected header %s to be %q, got %q", key, a.expected, strings.Join(values, ", "))
+    case "!=" == a.operator && slices.Contains(values, a.expected):
+      return fmt.Errorf("expected header %s not to be %q", key, a.expected)
+    }
+  case "json":
+    document, err := decodeExactJSON(response.raw)
+    if nil != err {
+      return errors.New("the response body is not JSON")
+    }
+
+    got, found, err := evalJSONPath(document, a.name)
+    switch {
+    case nil != err:
+      return err
+    case !found:
+      return fmt.Errorf("%s is missing", a.name)
+    case "exists" == a.operator:
+      return nil
+    }
+
+    expected, err := decodeExactJSON([]byte(a.expected))
+    if nil != err {
+      return fmt.Errorf("expected value %s is not JSON", a.expected)
+    }
+
+    actual, _ := json.Marshal(got)
+    switch a.operator {
+    case "==":
+      if !exactJSONEqual(expected, got) {
+        return fmt.Errorf("expected %s to be %s, got %s", a.name, a.expected, actual)
+      }
+    case "!=":
+      if exactJSONEqual(expected, got) {
+        return fmt.Errorf("expected %s not to be %s", a.name, a.expected)
+      }
+    case "contains":
+      text, isText := got.(string)
+      want, wantsText := expected.(string)
+      array, isArray := got.([]any)
+      if !(isText && wantsText && strings.Contains(text, want)) && !(isArray && slices.ContainsFunc(array, func(item any) bool { return exactJSONEqual(expected, item) })) {
+        return fmt.Errorf("expected %s to contain %s, got %s", a.name, a.expected, actual)
+      }
+    default:
+      number, isNumber := got.(json.Number)
+      want, wantsNumber := expected.(json.Number)
+      if !isNumber || !wantsNumber || !compare(a.operator, compareJSONNumbers(number, want), 0) {
+        return fmt.Errorf("expected %s %s %s, got %s", a.name, a.operator, a.expected, actual)
+      }
+    }
+  case "duration":
+    limit, err := time.ParseDuration(a.expected)
+    if nil != err {
+      return fmt.Errorf("invalid duration %q", a.expected)
+    }
+
+    took := response.duration.Round(time.Millisecond)
+    switch {
+    case "<" == a.operator && response.duration >= limit:
+      return fmt.Errorf("expected the response in less than %s, took %s", limit, took)
+    case !compare(a.operator, response.duration, limit):
+      return fmt.Errorf("expected duration %s %s, took %s", a.operator, limit, took)
+    }
+  }
+
+  return nil
+}
+
+// An assertionResult is the outcome of an assertion.
+type assertionResult struct {
+  Assertion string `json:"assertion"`
+  Passed    bool   `json:"passed"`
+  Message   string `json:"message,omitempty"` // Why the assertion failed.
+}
+
+// assertAll evaluates every assertion against response.
+func assertAll(assertions []assertion, response *responseBuilder) (results []assertionResult) {
+  for a := range slices.Values(assertions) {
+    result := assertionResult{Assertion: a.String(), Passed: true}
+    if err := a.evaluate(response); nil != err {
+      result.Passed, result.Message = false, err.Error()
+    }
+
+    results = append(results, result)
+  }
+
+  return results
+}
+
+// evaluateChecks parses the checks written in the assertion DSL, one per line, and evaluates them
+// against response. Blank lines and lines starting with # are ignored, and the checks that cannot be
+// parsed fail.
+func evaluateChecks(checks []string, response *responseBuilder) (results []assertionResult) {
+  for check := range slices.Values(checks) {
+    if check = strings.TrimSpace(check); "" == check || strings.HasPrefix(check, "#") {
+      continue
+    }
+
+    a, err := parseAssertion(check)
+    if nil != err {
+      results = append(results, assertionResult{Assertion: check, Message: fmt.Sprint("invalid check: ", err.Error())})
+      continue
+    }
+
+    results = append(results, assertAll([]assertion{a}, response)...)
+  }
+
+  return results
+}
+
+// runAssertions is the declarative form of the assertions of a request, as written in an assertions
+// file; the JSON values are indexed by their JSON path.
+type runAssertions struct {
+  Status       *int                       `json:"status"`
+  Header       map[string]string          `json:"header"` // Every header must contain its value.
+  JSON         map[string]json.RawMessage `json:"json"`
+  ResponseTime string                     `json:"responseTime"` // The limit of the response time, E.g: 500ms.
+}
+
+// parseRunAssertions parses an assertions file, a JSON or YAML object that maps the full names of
+// requests, such as "Posts / Create post", to their assertions, either declared as runAssertions or
+// as a list of checks in the assertion DSL. The assertions under "*" apply to every request.
+func parseRunAssertions(input []byte) (map[string][]assertion, error) {
+  document, err := toJSON(input)
+  if nil != err {
+    return nil, err
+  }
+
+  var declared map[string]json.RawMessage
+  if err := json.Unmarshal(document, &declared); nil != err {
+    return nil, err
+  }
+
+  assertions := make(map[string][]assertion, len(declared))
+  for name, raw := range declared {
+    var list []assertion
+    if checks := []string(nil); nil == json.Unmarshal(raw, &checks) {
+      for check := range slices.Values(checks) {
+        if check = strings.TrimSpace(check); "" == check || strings.HasPrefix(check, "#") {
+          continue
+        }
+
+        a, err := parseAssertion(check)
+        if nil != err {
+          return nil, fmt.Errorf("%s: %w", name, err)
+        }
+
+        list = append(list, a)
+      }
+
+      assertions[name] = list
+      continue
+    }
+
+    var d runAssertions
+    if err := json.Unmarshal(raw, &d); nil != err {
+      return nil, fmt.Errorf("%s: %w", name, err)
+    }
+
+    if nil != d.Status {
+      list = append(list, assertion{subject: "status", operator: "==", expected: strconv.Itoa(*d.Status)})
+    }
+
+    for key := range slices.Values(slices.Sorted(maps.Keys(d.Header))) {
+      list = append(list, assertion{subject: "header", name: key, operator: "contains", expected: d.Header[key]})
+    }
+
+    for path := range slices.Values(slices.Sorted(maps.Keys(d.JSON))) {
+      if _, err := parseJSONPath(path); nil != err {
+        return nil, fmt.Errorf("%s: %w", name, err)
+      }
+
+      expected := &bytes.Buffer{}
+      json.Compact(expected, d.JSON[path])
+      list = append(list, assertion{subject: "json", name: path, operator: "==", expected: expected.String()})
+    }
+
+    if "" != d.ResponseTime {
+      if _, err := time.ParseDuration(d.ResponseTime); nil != err {
+        return nil, fmt.Errorf("%s: invalid response time %q", name, d.ResponseTime)
+      }
+
+      list = append(list, assertion{subject: "duration", operator: "<", expected: d.ResponseTime})
+    }
+
+    assertions[name] = list
+  }
+
+  return assertions, nil
+}
@@ -0,0 +1,205 @@
+package playground
+
+import (
+  "github.com/google/go-cmp/cmp"
+  "net/http"
+  "reflect"
+  "testing"
+  "time"
+)
+
+func TestAssertion_evaluate(t *testing.T) {
+  response := &responseBuilder{
+    status:   201,
+    header:   http.Header{"Content-Type": {"application/json; charset=utf-8"}},
+    raw:      []byte(`{"id": 10, "tags": ["go", "http"], "author": {"name": "Jane"}}`),
+    duration: 120 * time.Millisecond,
+  }
+
+  tests := []struct {
+    assertion assertion
+    text, err string
+  }{
+    {assertion{subject: "status", operator: "==", expected: "201"}, "status == 201", ""},
+    {assertion{subject: "status", operator: "==", expected: "200"}, "status == 200", "expected status 200, got 201"},
+    {assertion{subject: "header", name: "content-type", operator: "contains", expected: "json"}, `header["content-type"] contains "json"`, ""},
+    {assertion{subject: "header", name: "Content-Type", operator: "contains", expected: "xml"}, `header["Content-Type"] contains "xml"`, `expected header Content-Type to contain "xml", got "application/json; charset=utf-8"`},
+    {assertion{subject: "header", name: "Content-Type", operator: "==", expected: "application/json"}, `header["Content-Type"] == "application/json"`, `expected header Content-Type to be "application/json", got "application/json; charset=utf-8"`},
+    {assertion{subject: "header", name: "ETag", operator: "exists"}, `header["ETag"] exists`, "header Etag is missing"},
+    {assertion{subject: "json", name: "$.id", operator: "==", expected: "10"}, "$.id == 10", ""},
+    {assertion{subject: "json", name: "$.tags", operator: "==", expected: `["go","http"]`}, `$.tags == ["go","http"]`, ""},
+    {assertion{subject: "json", name: "$.author.name", operator: "==", expected: `"John"`}, `$.author.name == "John"`, `expected $.author.name to be "John", got "Jane"`},
+    {assertion{subject: "json", name: "$.author.id", operator: "exists"}, "$.author.id exists", "$.author.id is missing"},
+    {assertion{subject: "duration", operator: "<", expected: "500ms"}, "duration < 500ms", ""},
+    {assertion{subject: "duration", operator: "<", expected: "100ms"}, "duration < 100ms", "expected the response in less than 100ms, took 120ms"},
+    {assertion{subject: "status", operator: ">=", expected: "400"}, "status >= 400", "expected status >= 400, got 201"},
+    {assertion{subject: "status", operator: "!=", expected: "500"}, "status != 500", ""},
+    {assertion{subject: "header", name: "ETag", operator: "!=", expected: "abc"}, `header["ETag"] != "abc"`, ""},
+    {assertion{subject: "json", name: "$.id", operator: ">", expected: "10"}, "$.id > 10", "expected $.id > 10, got 10"},
+    {assertion{subject: "json", name: "$.tags", operator: "contains", expected: `"go"`}, `$.tags contains "go"`, ""},
+    {assertion{subject: "json", name: "$.author.name", operator: "contains", expected: `"oh"`}, `$.author.name contains "oh"`, `expected $.author.name to contain "oh", got "Jane"`},
+    {assertion{subject: "json", name: "$.author", operator: "!=", expected: "null"}, "$.author != null", ""},
+    {assertion{subject: "duration", operator: ">", expected: "1s"}, "duration > 1s", "expected duration > 1s, took 120ms"},
+  }
+
+  for _, test := range tests {
+    if got := test.assertion.String(); test.text != got {
+      t.Errorf("String() = %q, want %q", got, test.text)
+    }
+
+    err := test.assertion.evaluate(response)
+    switch {
+    case "" == test.err && nil != err:
+      t.Errorf("%s: unexpected error: %s", test.text, err)
+    case "" != test.err && (nil == err || test.err != err.Error()):
+      t.Errorf("%s: error = %v, want %q", test.text, err, test.err)
+    }
+  }
+
+  large := &responseBuilder{raw: []byte(`{"id": 9007199254740993, "price": 1.10, "ids": [12345678901234567890]}`)}
+  for check, want := range map[string]string{
+    "$.id == 9007199254740993":            "",
+    "$.id == 9007199254740992":            "expected $.id to be 9007199254740992, got 9007199254740993",
+    "$.id > 9007199254740992":             "",
+    "$.id <= 9.007199254740992e15":        "expected $.id <= 9.007199254740992e15, got 9007199254740993",
+    "$.price == 1.1":                      "",
+    "$.price < 11e-1":                     "expected $.price < 11e-1, got 1.10",
+    "$.ids contains 12345678901234567890": "",
+    "$.ids contains 12345678901234567891": "expected $.ids to contain 12345678901234567891, got [12345678901234567890]",
+  } {
+    a, err := parseAssertion(check)
+    if nil != err {
+      t.Fatalf("parseAssertion(%q) error = %v", check, err)
+    }
+
+    if err := a.evaluate(large); "" == want && nil != err || "" != want && (nil == err || want != err.Error()) {
+      t.Errorf("%s: error = %v, want %q", check, err, want)
+    }
+  }
+
+  if err := (assertion{subject: "json", name: "$.id", operator: "==", expected: "10"}).evaluate(&responseBuilder{raw: []byte("<id>10</id>")}); nil == err || "the response body is not JSON" != err.Error() {
+    t.Errorf("error = %v, want \"the response body is not JSON\"", err)
+  }
+}
+
+func TestParseAssertion(t *testing.T) {
+  tests := []struct {
+    text string
+    want assertion
+  }{
+    {"status == 200", assertion{subject: "status", operator: "==", expected: "200"}},
+    {"  status>=400 ", assertion{subject: "status", operator: ">=", expected: "400"}},
+    {`header["Content-Type"] contains json`, assertion{subject: "header", name: "Content-Type", operator: "contains", expected: "json"}},
+    {`header['X-Request-ID'] exists`, assertion{subject: "header", name: "X-Request-ID", operator: "exists"}},
+    {`header[Location] == "/posts/1"`, assertion{subject: "header", name: "Location", operator: "==", expected: "/posts/1"}},
+    {"$.data[0].id exists", assertion{subject: "json", name: "$.data[0].id", operator: "exists"}},
+    {"$['first name']==Jane", assertion{subject: "json", name: "$['first name']", operator: "==", expected: `"Jane"`}},
+    {`$.tags == [ "go", "http" ]`, assertion{subject: "json", name: "$.tags", operator: "==", expected: `["go","http"]`}},
+    {"$.total <= 10", assertion{subject: "json", name: "$.total", operator: "<=", expected: "10"}},
+    {"duration < 500ms", assertion{subject: "duration", operator: "<", expected: "500ms"}},
+    {"duration < 250", assertion{subject: "duration", operator: "<", expected: "250ms"}},
+  }
+
+  for _, test := range tests {
+    got, err := parseAssertion(test.text)
+    if nil != err {
+      t.Errorf("parseAssertion(%q) unexpected error: %s", test.text, err)
+      continue
+    }
+
+    if !reflect.DeepEqual(test.want, got) {
+      t.Errorf("parseAssertion(%q): %s", test.text, cmp.Diff(test.want, got, cmp.AllowUnexported(assertion{})))
+    }
+
+    if again, _ := parseAssertion(got.String()); !reflect.DeepEqual(got, again) {
+      t.Errorf("parseAssertion(%q) does not parse back: %s", got.String(), cmp.Diff(got, again, cmp.AllowUnexported(assertion{})))
+    }
+  }
+
+  for text, want := range map[string]string{
+    "body == 1":                 `unknown subject in "body == 1"`,
+    "statuscode == 1":           `unknown subject in "statuscode == 1"`,
+    "status":                    `missing operator in "status"`,
+    "status contains 2":         "status cannot be checked with contains",
+    "status == ok":              `invalid status "ok"`,
+    "status ==":                 `missing expected value in "status =="`,
+    `header["ETag" exists`:      `unclosed [ in "header[\"ETag\" exists"`,
+    "header[] exists":           `missing header name in "header[] exists"`,
+    "header[ETag] exists 1":     `unexpected "1" after exists`,
+    "header[ETag] < 1":          "header cannot be checked with <",
+    "$.id[x] == 1":              `invalid index "x" in JSON path "$.id[x]"`,
+    "$.name < Jane":             `< needs a number, got "Jane"`,
+    "$.tags >= [1]":             ">= needs a number, got [1]",
+    "duration < fast":           `invalid duration "fast"`,
+    "duration == 1s":            "duration cannot be checked with ==",
+  } {
+    if _, err := parseAssertion(text); nil == err || want != err.Error() {
+      t.Errorf("parseAssertion(%q) error = %v, want %q", text, err, want)
+    }
+  }
+}
+
+func TestEvaluateChecks(t *testing.T) {
+  response := &responseBuilder{status: 200, raw: []byte(`{"data": [{"id": 1}]}`)}
+  got := evaluateChecks([]string{"# The post is listed.", "status == 200", "", "$.data[0].id exists", "$.data[1].id exists", "status is 200"}, response)
+  want := []assertionResult{
+    {Assertion: "status == 200", Passed: true},
+    {Assertion: "$.data[0].id exists", Passed: true},
+    {Assertion: "$.data[1].id exists", Message: "$.data[1].id is missing"},
+    {Assertion: "status is 200", Message: `invalid check: missing operator in "status is 200"`},
+  }
+
+  if !reflect.DeepEqual(want, got) {
+    t.Fatal(cmp.Diff(want, got))
+  }
+}
+
+func TestParseRunAssertions(t *testing.T) {
+  got, err := parseRunAssertions([]byte(`
+"*":
+  responseTime: 2s
+Posts / Create post:
+  status: 201
+  header: {Location: /posts/, Content-Type: json}
+  json:
+    $.title: Hello
+    $.tags: [go]
+Posts / List posts:
+  - status == 200
+  - "# Every post has an ID."
+  - $.data[0].id exists
+`))
+
+  if nil != err {
+    t.Fatalf("unexpected error: %s", err)
+  }
+
+  want := map[string][]assertion{
+    "*": {{subject: "duration", operator: "<", expected: "2s"}},
+    "Posts / Create post": {
+      {subject: "status", operator: "==", expected: "201"},
+      {subject: "header", name: "Content-Type", operator: "contains", expected: "json"},
+      {subject: "header", name: "Location", operator: "contains", expected: "/posts/"},
+      {subject: "json", name: "$.tags", operator: "==", expected: `["go"]`},
+      {subject: "json", name: "$.title", operator: "==", expected: `"Hello"`},
+    },
+    "Posts / List posts": {
+      {subject: "status", operator: "==", expected: "200"},
+      {subject: "json", name: "$.data[0].id", operator: "exists"},
+    },
+  }
+
+  if !reflect.DeepEqual(want, got) {
+    t.Fatal(cmp.Diff(want, got, cmp.AllowUnexported(assertion{})))
+  }
+
+  for input, want := range map[string]string{
+    `{"Ping": {"json": {"id": 1}}}`:      `Ping: JSON path "id" must start with $`,
+    `{"Ping": {"responseTime": "fast"}}`: `Ping: invalid response time "fast"`,
+    `{"Ping": ["status is 200"]}`:        `Ping: missing operator in "status is 200"`,
+  } {
+    if _, err := parseRunAssertions([]byte(input)); nil == err || want != err.Error() {
+      t.Errorf("parseRunAssertions(%s) error = %v, want %q", input, err, want)
+    }
+  }
+}
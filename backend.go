@@ -1,9 +1,11 @@
 package playground
 
 import (
+  "cmp"
   "compress/flate"
   "compress/gzip"
   "context"
+  "crypto/tls"
   "errors"
   "fmt"
   "io"
@@ -12,6 +14,8 @@ import (
   "mime"
   "net"
   "net/http"
+  "slices"
+  "strconv"
   "strings"
   "time"
 )
@@ -22,8 +26,14 @@ var allowedMethods = map[string]struct{}{
   http.MethodPut:    {},
   http.MethodPatch:  {},
   http.MethodDelete: {},
+  http.MethodHead:   {},
 }
 
+// AllowInsecure lets the requests sent from the request form skip the verification of TLS
+// certificates when they ask to. It is off unless the server opts in, so that a form field alone
+// cannot turn the verification off.
+var AllowInsecure = false
+
 // maxBodyBytes is the accepted body size for a playground request.
 const maxBodyBytes = 5 << 20 // 5 MB
 
@@ -31,11 +41,30 @@ type bodyFormatter interface {
   format(input []byte, output io.Writer, indent string)
 }
 
+// A bodyChecker is a formatter that can tell what is wrong with a body, which is reported as warnings.
+type bodyChecker interface {
+  check(input []byte) []string
+}
+
 var (
   textFormatter = &textFormatterImpl{}
   xmlFormatter  = &xmlFormatterImpl{}
   jsonFormatter = &jsonFormatterImpl{}
   htmlFormatter = &htmlFormatterImpl{}
+  yamlFormatter = &yamlFormatterImpl{}
+
+  cssFormatter        = &cssFormatterImpl{}
+  javascriptFormatter = &javascriptFormatterImpl{}
+
+  jsonLinesFormatter    = &jsonLinesFormatterImpl{}
+  jsonSequenceFormatter = &jsonLinesFormatterImpl{seq: true}
+
+  csvFormatter = &csvFormatterImpl{}
+  tsvFormatter = &csvFormatterImpl{delimiter: '\t'}
+
+  msgpackFormatter  = &binaryFormatterImpl{encoding: "MessagePack", decode: decodeMessagePack}
+  cborFormatter     = &binaryFormatterImpl{encoding: "CBOR", decode: decodeCBOR}
+  protobufFormatter = newProtobufFormatter(nil, "")
 )
 
 var supportedMediaTypes = map[string]bodyFormatter{
@@ -43,11 +72,35 @@ var supportedMediaTypes = map[string]bodyFormatter{
   "application/problem+xml":  xmlFormatter,
   "application/json":         jsonFormatter,
   "application/problem+json": jsonFormatter,
+  "application/x-ndjson":     jsonLinesFormatter,
+  "application/jsonl":        jsonLinesFormatter,
+  "application/json-seq":     jsonSequenceFormatter,
   "application/sql":          textFormatter,
-  "application/yaml":         textFormatter, // TODO: implement a YAML formatter
+  "application/yaml":         yamlFormatter,
+  "application/x-yaml":       yamlFormatter,
+  "application/msgpack":      msgpackFormatter,
+  "application/x-msgpack":    msgpackFormatter,
+  "application/vnd.msgpack":  msgpackFormatter,
+  "application/cbor":         cborFormatter,
+  "application/protobuf":     protobufFormatter,
+  "application/x-protobuf":   protobufFormatter,
+  "application/javascript":   javascriptFormatter,
+  "application/x-javascript": javascriptFormatter,
+  "application/ecmascript":   javascriptFormatter,
+
+  "application/vnd.google.protobuf": protobufFormatter,
 
   "text/xml":  xmlFormatter,
   "text/html": htmlFormatter,
+  "text/yaml": yamlFormatter,
+  "text/csv":  csvFormatter,
+  "text/css":  cssFormatter,
+
+  "text/javascript":           javascriptFormatter,
+  "text/ecmascript":           javascriptFormatter,
+  "text/tab-separated-values": tsvFormatter,
+
+  "image/svg+xml": xmlFormatter,
 }
 
 var supportedEncodings = map[string]func(io.Reader) io.ReadCloser{
@@ -62,15 +115,20 @@ var errNoRequest = errors.New("internal service error")
 // and returns a playgroundResponse with a formatted JSON body. If an error occurs during
 // the request, it logs the error and returns nil.
 func backend(ctx context.Context, in *request) (response *responseBuilder) {
-  client := &http.Client{
-    Timeout: 30 * time.Second,
-    Transport: &http.Transport{
+  recorder := &harRecorder{
+    next: &http.Transport{
       DialContext: (&net.Dialer{
         Timeout:   10 * time.Second,
         KeepAlive: 10 * time.Second,
       }).DialContext,
       TLSHandshakeTimeout: 10 * time.Second,
+      TLSClientConfig:     &tls.Config{InsecureSkipVerify: in.insecure},
     },
+  }
+
+  client := &http.Client{
+    Timeout:   30 * time.Second,
+    Transport: recorder,
     CheckRedirect: func(req *http.Request, via []*http.Request) error {
       if len(via) >= 5 {
         return http.ErrUseLastResponse
@@ -80,6 +138,7 @@ func backend(ctx context.Context, in *request) (response *responseBuilder) {
   }
 
   response = newResponseBuilder()
+  defer func() { response.exchanges = recorder.entries }()
 
   ctx, cancel := context.WithTimeout(ctx, client.Timeout)
   defer cancel()
@@ -90,10 +149,28 @@ func backend(ctx context.Context, in *request) (response *responseBuilder) {
     return
   }
 
+  if in.insecure {
+    response.Warn("the TLS certificates of the server are not verified")
+  }
+
+  scope := newResolver(in.variables)
+  scope.dynamic = newDynamicGenerator()
+  err := resolveRequest(in, scope)
+
+  for warning := range slices.Values(scope.warnings) {
+    response.Warn(warning)
+  }
+
+  if nil != err {
+    response.WriteError(err)
+    response.DefaultHeaders()
+    return
+  }
+
   var body io.Reader
 
   if "" != in.body {
-    body = io.LimitReader(strings.NewReader(in.body), maxBodyBytes)
+    body = strings.NewReader(in.body[:min(len(in.body), maxBodyBytes)]) /* Not a LimitReader, so that redirects can replay it.  */
   }
 
   req, err := http.NewRequestWithContext(ctx, in.method, in.target.String(), body)
@@ -115,6 +192,7 @@ func backend(ctx context.Context, in *request) (response *responseBuilder) {
 
   maps.Copy(req.Header, in.header)
 
+  started := time.Now()
   res, err := client.Do(req)
   if nil != err {
     switch {
@@ -134,12 +212,13 @@ func backend(ctx context.Context, in *request) (response *responseBuilder) {
   }
 
   response.SetStartLine(res.Proto, res.Status)
+  response.status, response.duration = res.StatusCode, time.Since(started)
   response.SetHeaders(res.Header)
 
   var (
-    contentType     = res.Header.Get("Content-Type")
-    mediatype, _, _ = mime.ParseMediaType(contentType)
-    formatter       bodyFormatter
+    contentType          = res.Header.Get("Content-Type")
+    mediatype, params, _ = mime.ParseMediaType(contentType)
+    formatter            bodyFormatter
   )
 
   for mtype, f := range maps.All(supportedMediaTypes) {
@@ -148,7 +227,8 @@ func backend(ctx context.Context, in *request) (response *responseBuilder) {
     }
   }
 
-  if nil == formatter {
+  sniffing := in.sniff && genericMediaTypes[mediatype]
+  if nil == formatter && !sniffing {
     if typ, _ := splitMediaType(mediatype); "text" != typ && "" != typ {
       response.WriteError(fmt.Errorf("unsupported media type %#q", mediatype))
       response.DefaultHeaders()
@@ -188,11 +268,128 @@ func backend(ctx context.Context, in *request) (response *responseBuilder) {
     return
   }
 
-  formatter.format(result, response, "  ")
+  recorder.finish(result)
+  if sniffing {
+    if sniffed := sniffMediaType(result); "" != sniffed {
+      mediatype, formatter = sniffed, supportedMediaTypes[sniffed]
+      response.AddMeta("Sniffed-Type", sniffed)
+    }
+
+    if nil == formatter {
+      if typ, _ := splitMediaType(mediatype); "text" != typ && "" != typ {
+        response.WriteError(fmt.Errorf("unsupported media type %#q", mediatype))
+        response.DefaultHeaders()
+        return
+      }
+
+      formatter = textFormatter
+    }
+  }
+
+  if table, ok := formatter.(*csvFormatterImpl); ok && strings.EqualFold("absent", params["header"]) {
+    formatter = &csvFormatterImpl{delimiter: table.delimiter, noHeader: true}
+  }
+
+  if protobufFormatter == formatter {
+    formatter = newProtobufFormatter(in.descriptorSet, cmp.Or(in.protobufMessage, params["proto"], params["messagetype"]))
+  }
+
+  if _, binary := formatter.(*binaryFormatterImpl); !binary && len(result) > 0 {
+    decoded, name, source, err := decodeCharset(result, mediatype, params["charset"])
+    if nil != err {
+      response.Warn(err.Error())
+    }
+
+    if "" != name {
+      response.AddMeta("Charset", fmt.Sprintf("%s (%s)", name, source))
+    }
+
+    result = decoded
+  }
+
+  response.raw, response.duration = result, time.Since(started)
+  if checker, ok := formatter.(bodyChecker); ok {
+    for warning := range slices.Values(checker.check(result)) {
+      response.Warn(warning)
+    }
+  }
+
+  if "" != in.filter && filterResponse(ctx, in.filter, formatter, result, response, in.highlight) {
+    return response
+  }
+
+  if jsonFormatter == formatter {
+    formatter = &jsonFormatterImpl{sortKeys: in.sortKeys, compact: in.compact}
+  }
+
+  if binary, ok := formatter.(*binaryFormatterImpl); ok {
+    decoded := *binary
+    decoded.json = jsonFormatterImpl{sortKeys: in.sortKeys, compact: in.compact}
+    formatter = &decoded
+  }
+
+  response.WriteFormatted(formatter, result, "  ", in.highlight)
 
   return response
 }
 
+// filterResponse writes what filter reduces the response body to, along with the size of the body,
+// and reports whether it did. The filter is a JSON path or a jq filter for JSON bodies, and an XPath
+// for XML and HTML bodies, whose matches are counted. It warns instead if the body is of another
+// type or the filter is wrong, so that the whole body is shown. The result is highlighted if
+// highlight is true. The filter stops when ctx is done.
+func filterResponse(ctx context.Context, filter string, formatter bodyFormatter, body []byte, response *responseBuilder, highlight bool) bool {
+  var apply func() ([]byte, error)
+  switch formatter {
+  default:
+    response.Warn("the filter was not applied: the response body is not JSON, XML or HTML")
+    return false
+  case jsonFormatter:
+    compiled, err := compileJSONFilter(filter, newJQBudget(ctx))
+    if nil != err {
+      response.Warn(fmt.Sprintf("invalid filter: %s", err.Error()))
+      return false
+    }
+
+    apply = func() ([]byte, error) { return filterJSON(body, compiled, "  ") }
+    if highlight {
+      apply = func() ([]byte, error) {
+        filtered, err := filterJSON(body, compiled, "  ")
+        return highlightTokens(jsonFormatter, filtered, "  "), err
+      }
+    }
+  case xmlFormatter, htmlFormatter:
+    expr, err := parseXPath(filter)
+    if nil != err {
+      response.Warn(fmt.Sprintf("invalid filter: %s", err.Error()))
+      return false
+    }
+
+    apply = func() ([]byte, error) {
+      filtered, matches, err := filterMarkup(ctx, body, expr, formatter.(tokenFormatter), "  ", highlight)
+      if nil == err && matches >= 0 {
+        response.AddMeta("Matches", strconv.Itoa(matches))
+      }
+
+      return filtered, err
+    }
+  }
+
+  filtered, err := apply()
+  if nil != err {
+    response.Warn(fmt.Sprintf("the filter failed: %s", err.Error()))
+    return false
+  }
+
+  response.AddMeta("Unfiltered-Size", strconv.Itoa(len(body)))
+  response.highlighted = highlight
+  if _, err := response.Write(filtered); nil != err {
+    slog.Error(err.Error())
+  }
+
+  return true
+}
+
 // splitMediaType extracts the type and subtype from a MIME type.
 func splitMediaType(v string) (typ string, subtype string) {
   if parts := strings.Split(v, "/"); len(parts) == 2 {
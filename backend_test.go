@@ -1,6 +1,11 @@
 package playground
 
 import (
+  "context"
+  "net/http"
+  "net/http/httptest"
+  "net/url"
+  "slices"
   "testing"
 )
 
@@ -32,3 +37,35 @@ func TestSplitMediaType(t *testing.T) {
     }
   }
 }
+
+func TestBackend_head(t *testing.T) {
+  var method string
+  server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+    method = r.Method
+    w.Header().Set("Content-Type", "text/plain")
+    w.Write([]byte("hello"))
+  }))
+  defer server.Close()
+
+  target, _ := url.Parse(server.URL)
+  response := backend(context.Background(), &request{method: http.MethodHead, target: target, header: http.Header{}})
+
+  if http.MethodHead != method || http.StatusOK != response.status {
+    t.Errorf("backend(HEAD) sent %s and got status %d, want HEAD and 200", method, response.status)
+  }
+}
+
+func TestBackend_insecure(t *testing.T) {
+  server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+  defer server.Close()
+
+  target, _ := url.Parse(server.URL)
+  for _, insecure := range []bool{false, true} {
+    response := backend(context.Background(), &request{method: http.MethodGet, target: target, header: http.Header{}, insecure: insecure})
+    warned := slices.Contains(response.meta.Values("Playground-Warning"), "the TLS certificates of the server are not verified")
+
+    if insecure != (http.StatusOK == response.status) || insecure != warned {
+      t.Errorf("backend(insecure: %t) = status %d and warned %t, want %t", insecure, response.status, warned, insecure)
+    }
+  }
+}
@@ -0,0 +1,537 @@
+package playground
+
+import (
+  "archive/zip"
+  "bufio"
+  "bytes"
+  "cmp"
+  "encoding/json"
+  "errors"
+  "fmt"
+  "io"
+  "maps"
+  "path"
+  "regexp"
+  "slices"
+  "strconv"
+  "strings"
+)
+
+// maxBrunoBytes bounds the uncompressed size of the files read from a Bruno collection archive.
+const maxBrunoBytes = 16 << 20 // 16 MB
+
+var (
+  bruReBlockStart = regexp.MustCompile(`^([\w:-]+)\s*([{\[])\s*$`) // regexp bruReBlockStart matches the line that opens a block
+  bruMethods      = []string{"get", "post", "put", "patch", "delete", "options", "head", "connect", "trace"}
+)
+
+// A bruBlock is a block of a .bru file. Depending on its name, it holds a dictionary (headers {...}),
+// a list (vars:secret [...]) or a text (body:json {...}).
+type bruBlock struct {
+  name  string
+  lines []string // The lines between the braces or brackets, without their indentation.
+}
+
+// A bruPair is an entry of a dictionary block. Disabled entries are prefixed with ~.
+type bruPair struct {
+  key      string
+  value    string
+  disabled bool
+}
+
+// parseBru splits a .bru file into its blocks.
+func parseBru(input []byte) (blocks []bruBlock, err error) {
+  var (
+    scanner = bufio.NewScanner(bytes.NewReader(input))
+    current *bruBlock
+    closing string
+  )
+
+  scanner.Buffer(make([]byte, 0, 64<<10), 1<<20)
+
+  for scanner.Scan() {
+    line := strings.TrimRight(scanner.Text(), "\r")
+
+    switch {
+    case nil != current && closing == line:
+      blocks = append(blocks, *current)
+      current = nil
+    case nil != current:
+      current.lines = append(current.lines, strings.TrimPrefix(line, "  "))
+    case "" == strings.TrimSpace(line):
+    default:
+      m := bruReBlockStart.FindStringSubmatch(line)
+      if nil == m {
+        return nil, fmt.Errorf("invalid .bru line %#q", line)
+      }
+
+      current, closing = &bruBlock{name: m[1]}, map[string]string{"{": "}", "[": "]"}[m[2]]
+    }
+  }
+
+  if err = scanner.Err(); nil != err {
+    return nil, err
+  }
+
+  if nil != current {
+    return nil, fmt.Errorf("unterminated .bru block %q", current.name)
+  }
+
+  return blocks, nil
+}
+
+// block returns the block named name, if any.
+func block(blocks []bruBlock, name string) *bruBlock {
+  n := slices.IndexFunc(blocks, func(b bruBlock) bool { return name == b.name })
+  if -1 == n {
+    return nil
+  }
+
+  return &blocks[n]
+}
+
+// pairs returns the entries of a dictionary block.
+func (b *bruBlock) pairs() (pairs []bruPair) {
+  if nil == b {
+    return nil
+  }
+
+  for line := range slices.Values(b.lines) {
+    key, value, found := strings.Cut(strings.TrimSpace(line), ":")
+    if !found {
+      continue
+    }
+
+    pair := bruPair{key: strings.TrimSpace(key), value: strings.TrimSpace(value)}
+    if strings.HasPrefix(pair.key, "~") {
+      pair.key, pair.disabled = pair.key[1:], true
+    }
+
+    pairs = append(pairs, pair)
+  }
+
+  return pairs
+}
+
+// get returns the value of the entry named key of a dictionary block.
+func (b *bruBlock) get(key string) string {
+  for p := range slices.Values(b.pairs()) {
+    if key == p.key {
+      return p.value
+    }
+  }
+
+  return ""
+}
+
+// text returns the content of a text block.
+func (b *bruBlock) text() string {
+  if nil == b {
+    return ""
+  }
+
+  return strings.Join(b.lines, "\n")
+}
+
+type brunoImporterImpl struct{}
+
+// sniff accepts single request files, whose first block is meta, and zip archives of collection folders.
+func (brunoImporterImpl) sniff(input []byte) bool {
+  if bytes.HasPrefix(input, []byte("PK\x03\x04")) {
+    archive, err := zip.NewReader(bytes.NewReader(input), int64(len(input)))
+    return nil == err && slices.ContainsFunc(archive.File, func(f *zip.File) bool {
+      return "bruno.json" == path.Base(f.Name) || ".bru" == path.Ext(f.Name)
+    })
+  }
+
+  blocks, err := parseBru(input)
+  return nil == err && len(blocks) > 0 && "meta" == blocks[0].name
+}
+
+// parse converts a Bruno collection. Folders and collection.bru become folders and the collection
+// itself; since the collection model only keeps auth at those levels, their headers are handed down
+// to the requests. The files of the environments folder become collection environments.
+func (brunoImporterImpl) parse(input []byte) (*coll, error) {
+  files := make(map[string][]byte)
+
+  if bytes.HasPrefix(input, []byte("PK\x03\x04")) {
+    archive, err := zip.NewReader(bytes.NewReader(input), int64(len(input)))
+    if nil != err {
+      return nil, err
+    }
+
+    var total int64
+    for f := range slices.Values(archive.File) {
+      if f.FileInfo().IsDir() || strings.HasPrefix(path.Base(f.Name), "._") || ("bruno.json" != path.Base(f.Name) && ".bru" != path.Ext(f.Name)) {
+        continue
+      }
+
+      content, err := readZipFile(f, maxBrunoBytes-total)
+      if nil != err {
+        return nil, err
+      }
+
+      total += int64(len(content))
+      files[path.Clean(f.Name)] = content
+    }
+  } else {
+    files["request.bru"] = input
+  }
+
+  root, depth := ".", -1
+  for name := range files {
+    if "bruno.json" == path.Base(name) && (-1 == depth || strings.Count(name, "/") < depth) {
+      root, depth = path.Dir(name), strings.Count(name, "/")
+    }
+  }
+
+  c := &coll{}
+  if manifest, found := files[path.Join(root, "bruno.json")]; found {
+    var info struct {
+      Name string `json:"name"`
+    }
+
+    if err := json.Unmarshal(manifest, &info); nil != err {
+      return nil, err
+    }
+
+    c.Info.Name = info.Name
+  }
+
+  var headers []collHeader
+  if content, found := files[path.Join(root, "collection.bru")]; found {
+    blocks, err := parseBru(content)
+    if nil != err {
+      return nil, fmt.Errorf("collection.bru: %w", err)
+    }
+
+    c.Auth, headers = brunoFolder(c, "collection", blocks)
+    c.Info.Description = markdownDescription(block(blocks, "docs").text())
+    for p := range slices.Values(block(blocks, "vars:pre-request").pairs()) {
+      if !p.disabled {
+        c.Variable = append(c.Variable, collVariable{Key: p.key, Value: p.value, Type: "string"})
+      }
+    }
+  }
+
+  for _, name := range slices.Sorted(maps.Keys(files)) {
+    if path.Join(root, "environments") != path.Dir(name) || ".bru" != path.Ext(name) {
+      continue
+    }
+
+    blocks, err := parseBru(files[name])
+    if nil != err {
+      return nil, fmt.Errorf("%s: %w", name, err)
+    }
+
+    environment := collEnvironment{Name: strings.TrimSuffix(path.Base(name), ".bru")}
+    for p := range slices.Values(block(blocks, "vars").pairs()) {
+      if !p.disabled {
+        environment.Variable = append(environment.Variable, collVariable{Key: p.key, Value: p.value, Type: "string"})
+      }
+    }
+
+    if secrets := block(blocks, "vars:secret"); nil != secrets {
+      for line := range slices.Values(secrets.lines) {
+        if key := strings.TrimSuffix(strings.TrimSpace(line), ","); "" != key && !strings.HasPrefix(key, "~") {
+          environment.Variable = append(environment.Variable, collVariable{Key: key, Type: "string"})
+          c.skip("environment %s: the secret variable %q has no value outside of Bruno", environment.Name, key)
+        }
+      }
+    }
+
+    c.environments = append(c.environments, environment)
+    delete(files, name)
+  }
+
+  items, err := brunoItems(c, files, root, "")
+  if nil != err {
+    return nil, err
+  }
+
+  c.Item = prependHeaders(items, headers)
+
+  return c, nil
+}
+
+// readZipFile reads a file of a zip archive, failing if it holds more than limit bytes.
+func readZipFile(f *zip.File, limit int64) ([]byte, error) {
+  r, err := f.Open()
+  if nil != err {
+    return nil, err
+  }
+
+  defer r.Close()
+
+  content, err := io.ReadAll(io.LimitReader(r, limit+1))
+  if nil != err {
+    return nil, err
+  }
+
+  if int64(len(content)) > limit {
+    return nil, errors.New("the collection archive is too large")
+  }
+
+  return content, nil
+}
+
+// brunoItems converts the folders and request files directly inside dir, sorted by their sequence
+// numbers, folders first. name is the path of dir in the import summary.
+func brunoItems(c *coll, files map[string][]byte, dir, name string) ([]collItem, error) {
+  type entry struct {
+    seq  int
+    item collItem
+  }
+
+  var (
+    folders, requests []entry
+    subdirs           = make(map[string]bool)
+  )
+
+  for _, file := range slices.Sorted(maps.Keys(files)) {
+    rel := file
+    if "." != dir {
+      var found bool
+      if rel, found = strings.CutPrefix(file, fmt.Sprint(dir, "/")); !found {
+        continue
+      }
+    }
+
+    if first, _, nested := strings.Cut(rel, "/"); nested {
+      subdirs[path.Join(dir, first)] = true
+      continue
+    }
+
+    if ".bru" != path.Ext(rel) || "folder.bru" == rel || "collection.bru" == rel {
+      continue
+    }
+
+    blocks, err := parseBru(files[file])
+    if nil != err {
+      return nil, fmt.Errorf("%s: %w", file, err)
+    }
+
+    meta := block(blocks, "meta")
+    if nil == meta {
+      return nil, fmt.Errorf("%s: missing meta block", file)
+    }
+
+    item := collItem{Name: meta.get("name")}
+    if "" == item.Name {
+      item.Name = strings.TrimSuffix(rel, ".bru")
+    }
+
+    item.Request = brunoRequest(c, fmt.Sprint(name, item.Name), blocks)
+    seq, _ := strconv.Atoi(meta.get("seq"))
+    requests = append(requests, entry{seq: seq, item: item})
+  }
+
+  for _, subdir := range slices.Sorted(maps.Keys(subdirs)) {
+    folder, seq := collItem{Name: path.Base(subdir)}, 0
+
+    var headers []collHeader
+    if content, found := files[path.Join(subdir, "folder.bru")]; found {
+      blocks, err := parseBru(content)
+      if nil != err {
+        return nil, fmt.Errorf("%s: %w", path.Join(subdir, "folder.bru"), err)
+      }
+
+      if meta := block(blocks, "meta"); nil != meta {
+        if "" != meta.get("name") {
+          folder.Name = meta.get("name")
+        }
+
+        seq, _ = strconv.Atoi(meta.get("seq"))
+      }
+
+      folder.Auth, headers = brunoFolder(c, fmt.Sprint(name, folder.Name), blocks)
+      folder.Description = markdownDescription(block(blocks, "docs").text())
+    }
+
+    items, err := brunoItems(c, files, subdir, fmt.Sprint(name, folder.Name, " / "))
+    if nil != err {
+      return nil, err
+    }
+
+    folder.Item = prependHeaders(items, headers)
+    folders = append(folders, entry{seq: seq, item: folder})
+  }
+
+  var items []collItem
+  for entries := range slices.Values([][]entry{folders, requests}) {
+    slices.SortFunc(entries, func(a, b entry) int {
+      return cmp.Or(cmp.Compare(a.seq, b.seq), cmp.Compare(a.item.Name, b.item.Name))
+    })
+
+    for e := range slices.Values(entries) {
+      items = append(items, e.item)
+    }
+  }
+
+  return items, nil
+}
+
+// prependHeaders adds header before the headers of every request in items.
+func prependHeaders(items []collItem, header []collHeader) []collItem {
+  if 0 == len(header) {
+    return items
+  }
+
+  for n := range items {
+    if nil != items[n].Request {
+      items[n].Request.Header = append(slices.Clone(header), items[n].Request.Header...)
+    }
+
+    items[n].Item = prependHeaders(items[n].Item, header)
+  }
+
+  return items
+}
+
+// brunoFolder returns the auth and the headers of collection.bru or a folder.bru file; their docs
+// are read by the caller.
+func brunoFolder(c *coll, name string, blocks []bruBlock) (auth *collAuth, header []collHeader) {
+  for p := range slices.Values(block(blocks, "headers").pairs()) {
+    header = append(header, collHeader{Key: p.key, Value: p.value, Disabled: p.disabled})
+  }
+
+  if mode := block(blocks, "auth"); nil != mode {
+    auth = brunoAuth(c, name, mode.get("mode"), blocks)
+  }
+
+  for b := range slices.Values(blocks) {
+    switch b.name {
+    case "meta", "headers", "auth", "auth:basic", "auth:bearer", "auth:apikey", "vars:pre-request", "docs":
+    default:
+      c.skip("%s: the %s block was not imported", name, b.name)
+    }
+  }
+
+  return auth, header
+}
+
+// brunoRequest converts the blocks of a request file, named name in the import summary.
+func brunoRequest(c *coll, name string, blocks []bruBlock) *collRequest {
+  req := &collRequest{Header: []collHeader{}}
+
+  n := slices.IndexFunc(blocks, func(b bruBlock) bool { return slices.Contains(bruMethods, b.name) })
+  if -1 == n {
+    c.skip("%s: only HTTP requests are supported", name)
+    return req
+  }
+
+  method := &blocks[n]
+  req.Method = strings.ToUpper(method.name)
+
+  req.URL.Raw = method.get("url")
+  req.Description = markdownDescription(block(blocks, "docs").text())
+  for p := range slices.Values(block(blocks, "params:query").pairs()) {
+    if p.disabled {
+      c.skip("%s: the disabled query parameter %q was not imported", name, p.key)
+      continue
+    }
+
+    req.URL.Query = append(req.URL.Query, collQueryParam{Key: p.key, Value: p.value})
+  }
+
+  for p := range slices.Values(block(blocks, "params:path").pairs()) {
+    req.URL.Variable = append(req.URL.Variable, collPathVariable{Key: p.key, Value: p.value})
+  }
+
+  for p := range slices.Values(block(blocks, "headers").pairs()) {
+    req.Header = append(req.Header, collHeader{Key: p.key, Value: p.value, Disabled: p.disabled})
+  }
+
+  if mode := method.get("auth"); "inherit" != mode && "" != mode {
+    req.Auth = brunoAuth(c, name, mode, blocks)
+  }
+
+  contentType := ""
+  switch mode := method.get("body"); mode {
+  case "", "none":
+  case "json", "text", "xml", "sparql":
+    req.Body = &collBody{Mode: "raw", Raw: block(blocks, fmt.Sprint("body:", mode)).text()}
+    contentType = map[string]string{
+      "json":   "application/json",
+      "text":   "text/plain",
+      "xml":    "application/xml",
+      "sparql": "application/sparql-query",
+    }[mode]
+  case "formUrlEncoded":
+    req.Body = &collBody{Mode: "urlencoded", URLEncoded: []collURLEncodedParameter{}}
+    contentType = "application/x-www-form-urlencoded"
+    for p := range slices.Values(block(blocks, "body:form-urlencoded").pairs()) {
+      if p.disabled {
+        c.skip("%s: the disabled form field %q was not imported", name, p.key)
+        continue
+      }
+
+      req.Body.URLEncoded = append(req.Body.URLEncoded, collURLEncodedParameter{Key: p.key, Value: p.value})
+    }
+  case "graphql":
+    var variables any
+    if text := block(blocks, "body:graphql:vars").text(); "" != strings.TrimSpace(text) {
+      if err := json.Unmarshal([]byte(text), &variables); nil != err {
+        c.skip("%s: the GraphQL variables are not valid JSON", name)
+      }
+    }
+
+    document, _ := json.MarshalIndent(map[string]any{"query": block(blocks, "body:graphql").text(), "variables": variables}, "", "  ")
+    req.Body = &collBody{Mode: "raw", Raw: string(document)}
+    contentType = "application/json"
+  default:
+    c.skip("%s: %s bodies are not supported", name, mode)
+  }
+
+  if "" != contentType && !slices.ContainsFunc(req.Header, func(h collHeader) bool { return strings.EqualFold("Content-Type", h.Key) }) {
+    req.Header = append(req.Header, collHeader{Key: "Content-Type", Value: contentType})
+  }
+
+  for b := range slices.Values(blocks) {
+    switch {
+    case "meta" == b.name, "settings" == b.name, "docs" == b.name, slices.Contains(bruMethods, b.name):
+    case "params:query" == b.name, "params:path" == b.name, "headers" == b.name:
+    case strings.HasPrefix(b.name, "auth:"), strings.HasPrefix(b.name, "body:"):
+    default:
+      c.skip("%s: the %s block was not imported", name, b.name)
+    }
+  }
+
+  return req
+}
+
+// brunoAuth converts the auth of the given mode, whose attributes are in the auth:<mode> block.
+func brunoAuth(c *coll, name, mode string, blocks []bruBlock) *collAuth {
+  attributes := block(blocks, fmt.Sprint("auth:", mode))
+  if nil == attributes {
+    attributes = &bruBlock{}
+  }
+
+  switch mode {
+  case "inherit":
+    return nil
+  case "none":
+    return &collAuth{Type: "noauth"}
+  case "bearer":
+    return &collAuth{Type: "bearer", Bearer: []collAuthAttribute{{Key: "token", Value: attributes.get("token"), Type: "string"}}}
+  case "basic":
+    return &collAuth{Type: "basic", Basic: []collAuthAttribute{
+      {Key: "username", Value: attributes.get("username"), Type: "string"},
+      {Key: "password", Value: attributes.get("password"), Type: "string"},
+    }}
+  case "apikey":
+    in := "header"
+    if "queryparams" == attributes.get("placement") {
+      in = "query"
+    }
+
+    return &collAuth{Type: "apikey", APIKey: []collAuthAttribute{
+      {Key: "key", Value: attributes.get("key"), Type: "string"},
+      {Key: "value", Value: attributes.get("value"), Type: "string"},
+      {Key: "in", Value: in, Type: "string"},
+    }}
+  default:
+    c.skip("%s: %s auth is not supported", name, mode)
+    return &collAuth{Type: mode}
+  }
+}
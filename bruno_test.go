@@ -0,0 +1,181 @@
+package playground
+
+import (
+  "archive/zip"
+  "bytes"
+  "github.com/google/go-cmp/cmp"
+  "reflect"
+  "slices"
+  "strings"
+  "testing"
+)
+
+func TestParseBru(t *testing.T) {
+  input := "meta {\n  name: Create user\n  seq: 2\n}\n\npost {\n  url: {{host}}/users\n  body: json\n}\n\n" +
+    "headers {\n  Accept: application/json\n  ~X-Debug: 1\n}\n\nbody:json {\n  {\n    \"name\": \"Jane\"\n  }\n}\n\n" +
+    "vars:secret [\n  token,\n  ~key\n]\n"
+
+  got, err := parseBru([]byte(input))
+  if nil != err {
+    t.Fatalf("unexpected error: %s", err)
+  }
+
+  want := []bruBlock{
+    {name: "meta", lines: []string{"name: Create user", "seq: 2"}},
+    {name: "post", lines: []string{"url: {{host}}/users", "body: json"}},
+    {name: "headers", lines: []string{"Accept: application/json", "~X-Debug: 1"}},
+    {name: "body:json", lines: []string{"{", "  \"name\": \"Jane\"", "}"}},
+    {name: "vars:secret", lines: []string{"token,", "~key"}},
+  }
+
+  if !reflect.DeepEqual(want, got) {
+    t.Fatal(cmp.Diff(want, got, cmp.AllowUnexported(bruBlock{})))
+  }
+
+  if want, got := []bruPair{{key: "Accept", value: "application/json"}, {key: "X-Debug", value: "1", disabled: true}}, block(got, "headers").pairs(); !reflect.DeepEqual(want, got) {
+    t.Errorf("pairs() = %+v, want %+v", got, want)
+  }
+
+  for input := range slices.Values([]string{"meta {\n  name: x\n", "name: x\n", "meta {\n}\n}\n"}) {
+    if _, err := parseBru([]byte(input)); nil == err {
+      t.Errorf("parseBru(%q) must fail", input)
+    }
+  }
+}
+
+func TestImportColl_Bruno(t *testing.T) {
+  files := []struct{ name, content string }{
+    {"Blog/bruno.json", `{"version": "1", "name": "Blog", "type": "collection"}`},
+    {"Blog/collection.bru", "headers {\n  X-Client: playground\n}\n\nauth {\n  mode: bearer\n}\n\nauth:bearer {\n  token: {{token}}\n}\n\nvars:pre-request {\n  host: https://fontseca.dev\n}\n"},
+    {"Blog/environments/Production.bru", "vars {\n  host: https://api.fontseca.dev\n  ~old: 1\n}\n\nvars:secret [\n  token\n]\n"},
+    {"Blog/Home.bru", "meta {\n  name: Home\n  type: http\n  seq: 1\n}\n\nget {\n  url: {{host}}/\n  body: none\n  auth: none\n}\n"},
+    {"Blog/Posts/folder.bru", "meta {\n  name: All posts\n}\n\nauth {\n  mode: basic\n}\n\nauth:basic {\n  username: jane\n  password: secret\n}\n"},
+    {"Blog/Posts/Search.bru", "meta {\n  name: Search posts\n  seq: 2\n}\n\nget {\n  url: {{host}}/posts?q=go\n  body: none\n  auth: inherit\n}\n\n" +
+      "params:query {\n  q: go\n  ~page: 2\n}\n\ntests {\n  test(\"ok\", function() {});\n}\n"},
+    {"Blog/Posts/Get.bru", "meta {\n  name: Get post\n  seq: 1\n}\n\nget {\n  url: {{host}}/posts/:id\n  body: none\n  auth: inherit\n}\n\nparams:path {\n  id: 5\n}\n"},
+    {"Blog/Posts/Create.bru", "meta {\n  name: Create post\n  seq: 3\n}\n\npost {\n  url: {{host}}/posts\n  body: graphql\n  auth: apikey\n}\n\n" +
+      "auth:apikey {\n  key: api_key\n  value: {{key}}\n  placement: queryparams\n}\n\n" +
+      "body:graphql {\n  mutation { post(title: $title) { id } }\n}\n\nbody:graphql:vars {\n  {\"title\": \"Hi\"}\n}\n"},
+    {"Blog/Posts/Login.bru", "meta {\n  name: Log in\n  seq: 4\n}\n\npost {\n  url: {{host}}/login\n  body: formUrlEncoded\n  auth: awsv4\n}\n\n" +
+      "body:form-urlencoded {\n  user: jane\n  ~remember: 1\n}\n\nbody:json {\n  {}\n}\n"},
+  }
+
+  var archive bytes.Buffer
+  z := zip.NewWriter(&archive)
+  for f := range slices.Values(files) {
+    w, _ := z.Create(f.name)
+    w.Write([]byte(f.content))
+  }
+  z.Close()
+
+  got, err := importColl(&archive)
+  if nil != err {
+    t.Fatalf("unexpected error: %s", err)
+  }
+
+  collectionHeader := collHeader{Key: "X-Client", Value: "playground"}
+  want := &coll{
+    Item: []collItem{
+      {
+        Name: "All posts",
+        Auth: &collAuth{Type: "basic", Basic: []collAuthAttribute{
+          {Key: "username", Value: "jane", Type: "string"},
+          {Key: "password", Value: "secret", Type: "string"},
+        }},
+        Item: []collItem{
+          {
+            Name: "Get post",
+            Request: &collRequest{
+              URL:    collURL{Raw: "{{host}}/posts/:id", Variable: []collPathVariable{{Key: "id", Value: "5"}}},
+              Method: "GET",
+              Header: []collHeader{collectionHeader},
+            },
+          },
+          {
+            Name: "Search posts",
+            Request: &collRequest{
+              URL:    collURL{Raw: "{{host}}/posts?q=go", Query: []collQueryParam{{Key: "q", Value: "go"}}},
+              Method: "GET",
+              Header: []collHeader{collectionHeader},
+            },
+          },
+          {
+            Name: "Create post",
+            Request: &collRequest{
+              URL:    collURL{Raw: "{{host}}/posts"},
+              Method: "POST",
+              Header: []collHeader{collectionHeader, {Key: "Content-Type", Value: "application/json"}},
+              Body:   &collBody{Mode: "raw", Raw: "{\n  \"query\": \"mutation { post(title: $title) { id } }\",\n  \"variables\": {\n    \"title\": \"Hi\"\n  }\n}"},
+              Auth: &collAuth{Type: "apikey", APIKey: []collAuthAttribute{
+                {Key: "key", Value: "api_key", Type: "string"},
+                {Key: "value", Value: "{{key}}", Type: "string"},
+                {Key: "in", Value: "query", Type: "string"},
+              }},
+            },
+          },
+          {
+            Name: "Log in",
+            Request: &collRequest{
+              URL:    collURL{Raw: "{{host}}/login"},
+              Method: "POST",
+              Header: []collHeader{collectionHeader, {Key: "Content-Type", Value: "application/x-www-form-urlencoded"}},
+              Body:   &collBody{Mode: "urlencoded", URLEncoded: []collURLEncodedParameter{{Key: "user", Value: "jane"}}},
+              Auth:   &collAuth{Type: "awsv4"},
+            },
+          },
+        },
+      },
+      {
+        Name: "Home",
+        Request: &collRequest{
+          URL:    collURL{Raw: "{{host}}/"},
+          Method: "GET",
+          Header: []collHeader{collectionHeader},
+          Auth:   &collAuth{Type: "noauth"},
+        },
+      },
+    },
+    Variable: []collVariable{{Key: "host", Value: "https://fontseca.dev", Type: "string"}},
+    Auth:     &collAuth{Type: "bearer", Bearer: []collAuthAttribute{{Key: "token", Value: "{{token}}", Type: "string"}}},
+    environments: []collEnvironment{
+      {Name: "Production", Variable: []collVariable{
+        {Key: "host", Value: "https://api.fontseca.dev", Type: "string"},
+        {Key: "token", Type: "string"},
+      }},
+    },
+    unsupported: []string{
+      "environment Production: the secret variable \"token\" has no value outside of Bruno",
+      "All posts / Log in: awsv4 auth is not supported",
+      "All posts / Log in: the disabled form field \"remember\" was not imported",
+      "All posts / Search posts: the disabled query parameter \"page\" was not imported",
+      "All posts / Search posts: the tests block was not imported",
+    },
+  }
+  want.Info.Name = "Blog"
+
+  if !reflect.DeepEqual(want, got) {
+    t.Fatal(cmp.Diff(want, got, collUnexported))
+  }
+}
+
+func TestImportColl_BrunoRequest(t *testing.T) {
+  got, err := importColl(strings.NewReader("meta {\n  name: Ping\n}\n\nget {\n  url: https://fontseca.dev/ping\n}\n\ndocs {\n  Checks the server.\n}\n"))
+  if nil != err {
+    t.Fatalf("unexpected error: %s", err)
+  }
+
+  want := &coll{
+    Item: []collItem{
+      {Name: "Ping", Request: &collRequest{
+        URL:         collURL{Raw: "https://fontseca.dev/ping"},
+        Method:      "GET",
+        Description: &collDescription{Content: "Checks the server.", Type: "text/markdown"},
+        Header:      []collHeader{},
+      }},
+    },
+  }
+
+  if !reflect.DeepEqual(want, got) {
+    t.Fatal(cmp.Diff(want, got, collUnexported))
+  }
+}
@@ -0,0 +1,245 @@
+package playground
+
+import (
+  "encoding/base64"
+  "fmt"
+  "math"
+  "math/big"
+  "strconv"
+  "time"
+)
+
+// cborIndefinite is the additional information of an item whose length is not known in advance.
+const cborIndefinite = 31
+
+// decodeCBOR decodes the CBOR items of input into JSON trees. Byte strings are written as base64
+// strings, keys that are not strings as their JSON text, undefined as null, epoch-based dates as RFC 3339
+// strings, and bignums as numbers. The other tags are left out, and their content kept.
+func decodeCBOR(input []byte) ([]*jsonNode, []string, error) {
+  r := &cborReader{binaryReader{input: input}}
+  documents, err := r.documents(r.value)
+  return documents, nil, err
+}
+
+// A cborReader reads CBOR items.
+type cborReader struct {
+  binaryReader
+}
+
+// head reads the head of the item at n: its major type and its argument, unless its length is
+// indefinite.
+func (r *cborReader) head() (major byte, argument uint64, indefinite bool, err error) {
+  initial, err := r.uint(1)
+  if nil != err {
+    return 0, 0, false, err
+  }
+
+  major, info := byte(initial>>5), byte(initial&0x1f)
+  switch {
+  case info < 24:
+    return major, uint64(info), false, nil
+  case info <= 27:
+    argument, err = r.uint(1 << (info - 24))
+    return major, argument, false, err
+  case cborIndefinite == info && (major >= 2 && major <= 5 || 7 == major):
+    return major, 0, true, nil
+  }
+
+  return 0, 0, false, fmt.Errorf("invalid additional information %d at offset %d", info, r.n-1)
+}
+
+// value reads the item at n.
+func (r *cborReader) value() (*jsonNode, error) {
+  offset := r.n
+  major, argument, indefinite, err := r.head()
+  if nil != err {
+    return nil, err
+  }
+
+  switch major {
+  case 0:
+    return jsonScalar(strconv.FormatUint(argument, 10)), nil
+  case 1:
+    return jsonScalar(new(big.Int).Not(new(big.Int).SetUint64(argument)).String()), nil /* -1 - argument.  */
+  case 2, 3:
+    data, err := r.bytes(major, argument, indefinite)
+    if nil != err {
+      return nil, err
+    }
+
+    if 2 == major {
+      return jsonString(base64.StdEncoding.EncodeToString(data)), nil
+    }
+
+    return jsonString(string(data)), nil
+  case 4, 5:
+    return r.container(major, argument, indefinite)
+  case 6:
+    return r.tag(argument)
+  }
+
+  switch {
+  case indefinite:
+    return nil, fmt.Errorf("unexpected break at offset %d", offset)
+  case 20 == argument:
+    return jsonScalar("false"), nil
+  case 21 == argument:
+    return jsonScalar("true"), nil
+  case 22 == argument, 23 == argument: /* null and undefined.  */
+    return jsonScalar("null"), nil
+  case 25 == r.input[offset]&0x1f:
+    return jsonFloat(cborHalfFloat(uint16(argument)), 32), nil
+  case 26 == r.input[offset]&0x1f:
+    return jsonFloat(float64(math.Float32frombits(uint32(argument))), 32), nil
+  case 27 == r.input[offset]&0x1f:
+    return jsonFloat(math.Float64frombits(argument), 64), nil
+  }
+
+  return jsonString(fmt.Sprintf("simple(%d)", argument)), nil
+}
+
+// bytes reads the content of a byte or a text string, whose chunks are joined if its length is
+// indefinite.
+func (r *cborReader) bytes(major byte, size uint64, indefinite bool) ([]byte, error) {
+  if !indefinite {
+    return r.take(size)
+  }
+
+  var data []byte
+  for {
+    if r.n < len(r.input) && 0xff == r.input[r.n] {
+      r.n++
+      return data, nil
+    }
+
+    offset := r.n
+    chunkMajor, chunkSize, chunkIndefinite, err := r.head()
+    if nil != err {
+      return nil, err
+    }
+
+    if chunkMajor != major || chunkIndefinite {
+      return nil, fmt.Errorf("invalid chunk of a string at offset %d", offset)
+    }
+
+    chunk, err := r.take(chunkSize)
+    if nil != err {
+      return nil, err
+    }
+
+    data = append(data, chunk...)
+  }
+}
+
+// container reads an array or a map of size items or pairs, or up to a break if its length is
+// indefinite.
+func (r *cborReader) container(major byte, size uint64, indefinite bool) (*jsonNode, error) {
+  if err := r.enter(); nil != err {
+    return nil, err
+  }
+
+  defer r.leave()
+  if !indefinite && size > uint64(len(r.input)-r.n) { /* Every item takes a byte at least.  */
+    return nil, fmt.Errorf("unexpected end of input at offset %d", len(r.input))
+  }
+
+  var items []*jsonNode
+  var members []jsonMember
+  for n := uint64(0); indefinite || n < size; n++ {
+    if indefinite && r.n < len(r.input) && 0xff == r.input[r.n] {
+      r.n++
+      break
+    }
+
+    item, err := r.value()
+    if nil != err {
+      return nil, err
+    }
+
+    if 4 == major {
+      items = append(items, item)
+      continue
+    }
+
+    value, err := r.value()
+    if nil != err {
+      return nil, err
+    }
+
+    members = append(members, newJSONMember(jsonKey(item), value))
+  }
+
+  if 4 == major {
+    return jsonArray(items), nil
+  }
+
+  return jsonObject(members), nil
+}
+
+// tag reads the content of an item tagged with number.
+func (r *cborReader) tag(number uint64) (*jsonNode, error) {
+  if err := r.enter(); nil != err {
+    return nil, err
+  }
+
+  defer r.leave()
+  offset := r.n
+  if 2 == number || 3 == number { /* Bignums, whose content is a byte string.  */
+    major, size, indefinite, err := r.head()
+    if nil != err {
+      return nil, err
+    }
+
+    if 2 != major {
+      return nil, fmt.Errorf("invalid bignum at offset %d", offset)
+    }
+
+    data, err := r.bytes(major, size, indefinite)
+    if nil != err {
+      return nil, err
+    }
+
+    value := new(big.Int).SetBytes(data)
+    if 3 == number {
+      value.Not(value) /* -1 - value.  */
+    }
+
+    return jsonScalar(value.String()), nil
+  }
+
+  content, err := r.value()
+  if nil != err || 1 != number {
+    return content, err
+  }
+
+  seconds, err := strconv.ParseFloat(string(content.raw), 64) /* An epoch-based date.  */
+  if nil != err {
+    return nil, fmt.Errorf("invalid epoch-based date at offset %d", offset)
+  }
+
+  whole, fraction := math.Modf(seconds)
+  return jsonString(time.Unix(int64(whole), int64(fraction*1e9)).UTC().Format(time.RFC3339Nano)), nil
+}
+
+// cborHalfFloat returns the value of an IEEE 754 half-precision number.
+func cborHalfFloat(half uint16) float64 {
+  exponent, mantissa := int(half>>10&0x1f), float64(half&0x3ff)
+  value := 0.0
+  switch exponent {
+  case 0:
+    value = math.Ldexp(mantissa, -24)
+  case 31:
+    value = math.Inf(1)
+    if 0 != mantissa {
+      value = math.NaN()
+    }
+  default:
+    value = math.Ldexp(mantissa+1024, exponent-25)
+  }
+
+  if 0 != half&0x8000 {
+    return -value
+  }
+
+  return value
+}
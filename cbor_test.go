@@ -0,0 +1,42 @@
+package playground
+
+import (
+  "bytes"
+  "reflect"
+  "strings"
+  "testing"
+)
+
+func TestDecodeCBOR(t *testing.T) {
+  tests := []struct {
+    input    string
+    want     string
+    warnings []string
+  }{
+    {"\xa2\x61a\x01\x61b\x82\xf5\xf6", `{"a":1,"b":[true,null]}`, nil},
+    {"\x83\x20\x38\x63\x3b\xff\xff\xff\xff\xff\xff\xff\xff", `[-1,-100,-18446744073709551616]`, nil},
+    {"\x84\xf9\x3c\x00\xfb\x3f\xf8\x00\x00\x00\x00\x00\x00\xf9\x7c\x00\xf7", `[1,1.5,"+Inf",null]`, nil},
+    {"\x83\x43\x01\x02\x03\x5f\x41\x01\x41\x02\xff\x7f\x61a\x61b\xff", `["AQID","AQI=","ab"]`, nil},
+    {"\x9f\x01\xbf\x01\x61x\xff\xff", `[1,{"1":"x"}]`, nil},
+    {"\x82\xc1\x1a\x00\x00\x00\x3c\xc2\x49\x01\x00\x00\x00\x00\x00\x00\x00\x00", `["1970-01-01T00:01:00Z",18446744073709551616]`, nil},
+    {"\xd8\x20\x63a:b", `"a:b"`, nil},
+    {"\xe0\x01", "\"simple(0)\"\n1", nil},
+    {"\xff", "00000000  ff  ", []string{"invalid CBOR: unexpected break at offset 0"}},
+    {"\x1c", "00000000  1c  ", []string{"invalid CBOR: invalid additional information 28 at offset 0"}},
+    {"\x82\x01", "00000000  82 01", []string{"invalid CBOR: unexpected end of input at offset 2"}},
+    {strings.Repeat("\xc6", maxDecodingDepth+1) + "\x01", "", []string{"invalid CBOR: more than 512 levels of nesting at offset 513"}},
+  }
+
+  formatter := &binaryFormatterImpl{encoding: "CBOR", decode: decodeCBOR, json: jsonFormatterImpl{compact: true}}
+  for _, test := range tests {
+    buffer := bytes.Buffer{}
+    formatter.format([]byte(test.input), &buffer, "  ")
+    if got := buffer.String(); !strings.HasPrefix(got, test.want) {
+      t.Errorf("format(%q) = %s, want %s", test.input, got, test.want)
+    }
+
+    if got := formatter.check([]byte(test.input)); !reflect.DeepEqual(test.warnings, got) {
+      t.Errorf("check(%q) = %q, want %q", test.input, got, test.warnings)
+    }
+  }
+}
@@ -0,0 +1,110 @@
+package playground
+
+import (
+  "bytes"
+  "fmt"
+  "mime"
+  "regexp"
+  "slices"
+  "strings"
+  "unicode/utf8"
+  xhtml "golang.org/x/net/html"
+  "golang.org/x/net/html/charset"
+)
+
+// charsetReXMLEncoding matches the XML declaration of a document, along with its encoding.
+var charsetReXMLEncoding = regexp.MustCompile(`^\s*<\?xml\s[^>]*?\bencoding\s*=\s*["']([A-Za-z0-9._:-]+)["']`)
+
+// charsetBOMs are the byte order marks that tell the charset of a body, which take precedence over the
+// declared one.
+var charsetBOMs = []struct {
+  bom   string
+  label string
+}{
+  {"\xef\xbb\xbf", "utf-8"},
+  {"\xfe\xff", "utf-16be"},
+  {"\xff\xfe", "utf-16le"},
+}
+
+// decodeCharset returns body transcoded to UTF-8, without its byte order mark, along with the name of
+// its charset and where it was found: the byte order mark, the charset parameter of the media type,
+// then the <meta> element of an HTML body or the declaration of an XML one. If none tells, body is
+// taken to be UTF-8, and the name is empty.
+func decodeCharset(body []byte, mediatype, label string) (decoded []byte, name, source string, err error) {
+  source = "Content-Type"
+  for candidate := range slices.Values(charsetBOMs) {
+    if bytes.HasPrefix(body, []byte(candidate.bom)) {
+      body, label, source = body[len(candidate.bom):], candidate.label, "byte order mark"
+      break
+    }
+  }
+
+  if "" == label {
+    switch formatter := supportedMediaTypes[mediatype]; {
+    case htmlFormatter == formatter:
+      if label = charsetMeta(body); "" != label {
+        source = "<meta> element"
+      }
+    case xmlFormatter == formatter:
+      if match := charsetReXMLEncoding.FindSubmatch(body[:min(len(body), 1024)]); nil != match {
+        label, source = string(match[1]), "XML declaration"
+      }
+    }
+  }
+
+  if "" == label {
+    if !utf8.Valid(body) {
+      return body, "", "", fmt.Errorf("the body declares no charset, and is not valid UTF-8")
+    }
+
+    return body, "", "", nil
+  }
+
+  encoding, name := charset.Lookup(label)
+  switch {
+  case nil == encoding:
+    return body, "", "", fmt.Errorf("unknown charset %q, the body is shown as it is", label)
+  case "utf-8" == name:
+    return body, name, source, nil
+  }
+
+  decoded, err = encoding.NewDecoder().Bytes(body)
+  if nil != err {
+    return body, "", "", fmt.Errorf("the body is not valid %s: %s", name, err.Error())
+  }
+
+  return decoded, name, source, nil
+}
+
+// charsetMeta returns the charset that a <meta> element among the first 1024 bytes of an HTML body
+// declares, either with its charset attribute or as the Content-Type it is equivalent to.
+func charsetMeta(body []byte) string {
+  tokenizer := xhtml.NewTokenizer(bytes.NewReader(body[:min(len(body), 1024)]))
+  for {
+    switch tokenizer.Next() {
+    case xhtml.ErrorToken:
+      return ""
+    case xhtml.StartTagToken, xhtml.SelfClosingTagToken:
+      token := tokenizer.Token()
+      if "meta" != token.Data {
+        continue
+      }
+
+      var equivalent, content string
+      for attribute := range slices.Values(token.Attr) {
+        switch attribute.Key {
+        case "charset":
+          return strings.TrimSpace(attribute.Val)
+        case "http-equiv":
+          equivalent = attribute.Val
+        case "content":
+          content = attribute.Val
+        }
+      }
+
+      if _, params, err := mime.ParseMediaType(content); nil == err && strings.EqualFold("content-type", equivalent) && "" != params["charset"] {
+        return params["charset"]
+      }
+    }
+  }
+}
@@ -0,0 +1,42 @@
+package playground
+
+import (
+  "testing"
+)
+
+func TestDecodeCharset(t *testing.T) {
+  tests := []struct {
+    body      string
+    mediatype string
+    label     string
+    want      string
+    name      string
+    source    string
+    err       string
+  }{
+    {"caf\xe9", "text/plain", "ISO-8859-1", "café", "windows-1252", "Content-Type", ""},
+    {"\x93quoted\x94", "text/plain", "windows-1252", "“quoted”", "windows-1252", "Content-Type", ""},
+    {"\x93\xfa\x96\x7b", "text/csv", "Shift_JIS", "日本", "shift_jis", "Content-Type", ""},
+    {"\xef\xbb\xbf{}", "application/json", "iso-8859-1", "{}", "utf-8", "byte order mark", ""},
+    {"\xff\xfea\x00\xe9\x00", "text/plain", "", "aé", "utf-16le", "byte order mark", ""},
+    {`<html><head><meta charset="iso-8859-1"></head><body>ol` + "\xe9", "text/html", "", `<html><head><meta charset="iso-8859-1"></head><body>olé`, "windows-1252", "<meta> element", ""},
+    {`<html><meta http-equiv="Content-Type" content="text/html; charset=shift_jis">` + "\x93\xfa", "text/html", "", `<html><meta http-equiv="Content-Type" content="text/html; charset=shift_jis">日`, "shift_jis", "<meta> element", ""},
+    {"<?xml version='1.0' encoding='ISO-8859-1'?><a>\xe9</a>", "application/xml", "", "<?xml version='1.0' encoding='ISO-8859-1'?><a>é</a>", "windows-1252", "XML declaration", ""},
+    {"<a>é</a>", "application/xml", "", "<a>é</a>", "", "", ""},
+    {"caf\xe9", "text/plain", "", "caf\xe9", "", "", "the body declares no charset, and is not valid UTF-8"},
+    {"abc", "text/plain", "klingon", "abc", "", "", `unknown charset "klingon", the body is shown as it is`},
+  }
+
+  for _, test := range tests {
+    got, name, source, err := decodeCharset([]byte(test.body), test.mediatype, test.label)
+    message := ""
+    if nil != err {
+      message = err.Error()
+    }
+
+    if test.want != string(got) || test.name != name || test.source != source || test.err != message {
+      t.Errorf("decodeCharset(%q, %q, %q) = %q, %q, %q, %q, want %q, %q, %q, %q",
+        test.body, test.mediatype, test.label, got, name, source, message, test.want, test.name, test.source, test.err)
+    }
+  }
+}
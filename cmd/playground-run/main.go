@@ -0,0 +1,15 @@
+package main
+
+import (
+  "context"
+  "os"
+  "os/signal"
+  "playground"
+)
+
+func main() {
+  ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+  code := playground.Runner(ctx, os.Args[1:], os.Stdout, os.Stderr)
+  stop()
+  os.Exit(code)
+}
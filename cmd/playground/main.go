@@ -2,6 +2,7 @@ package main
 
 import (
   "context"
+  "flag"
   "fmt"
   "log"
   "net"
@@ -12,6 +13,9 @@ import (
 )
 
 func main() {
+  flag.BoolVar(&playground.AllowInsecure, "allow-insecure", false, "let requests skip the verification of TLS certificates")
+  flag.Parse()
+
   mux := http.NewServeMux()
 
   mux.HandleFunc("GET /playground/engine.js", func(w http.ResponseWriter, r *http.Request) { http.ServeFile(w, r, "engine.js") })
@@ -23,6 +27,11 @@ func main() {
     playground.Scanner(playgroundCtx, w, r)
   })
 
+  mux.HandleFunc("GET /playground.har", playground.Archive)
+  mux.HandleFunc("POST /playground.snippet", playground.Snippet)
+  mux.HandleFunc("POST /playground.http", playground.HTTPFileExporter)
+  mux.HandleFunc("POST /playground.postman", playground.CollectionExporter)
+  mux.HandleFunc("POST /playground.curl-import", playground.CurlImporter)
   mux.HandleFunc("GET /", playground.Renderer)
   mux.HandleFunc("POST /", playground.Renderer)
 
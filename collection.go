@@ -1,11 +1,13 @@
 package playground
 
 import (
+  "encoding/base64"
   "encoding/json"
   "fmt"
   "github.com/google/uuid"
+  "html"
   "io"
-  "regexp"
+  "net/url"
   "slices"
   "strconv"
   "strings"
@@ -15,79 +17,207 @@ import (
 type collQueryParam struct {
   Key   string `json:"key"`
   Value string `json:"value"`
+
+  extra collExtra
+}
+
+// A collPathVariable is a key-value representation of a path variable, such as :id, in a collURL.
+type collPathVariable struct {
+  Key   string `json:"key"`
+  Value string `json:"value"`
+
+  extra collExtra
 }
 
 // A collURL contains the complete broken-down URL for this a collRequest.
 type collURL struct {
-  Raw      string           `json:"raw"`      // The string representation of the request URL, including the protocol, host, path, hash, query parameter(s) and path variable(s).
-  Protocol string           `json:"protocol"` // The protocol associated with the request, E.g: 'http'.
-  Host     []string         `json:"host"`     // The host for the URL, E.g: api.yourdomain.com. Can be stored as a string or as an array of strings.
-  Path     []string         `json:"path"`     // The complete path of the current url, broken down into segments. A segment could be a string, or a path variable.
-  Port     string           `json:"port"`     // The port number present in this URL.
-  Query    []collQueryParam `json:"query"`    // An array of collQueryParam, which is basically the query string part of the URL, parsed into separate variables
-
+  Raw      string             `json:"raw"`                // The string representation of the request URL, including the protocol, host, path, hash, query parameter(s) and path variable(s).
+  Protocol string             `json:"protocol,omitempty"` // The protocol associated with the request, E.g: 'http'.
+  Host     collSegments       `json:"host,omitempty"`     // The host for the URL, E.g: api.yourdomain.com. Can be stored as a string or as an array of strings.
+  Path     collSegments       `json:"path,omitempty"`     // The complete path of the current url, broken down into segments. A segment could be a string, or a path variable.
+  Port     string             `json:"port,omitempty"`     // The port number present in this URL.
+  Query    []collQueryParam   `json:"query,omitempty"`    // An array of collQueryParam, which is basically the query string part of the URL, parsed into separate variables
+  Hash     string             `json:"hash,omitempty"`     // The fragment of the URL, without the #.
+  Variable []collPathVariable `json:"variable,omitempty"` // The values of the path variables, such as :id, used in the path segments.
+
+  extra collExtra
+  short bool // The URL was written as a string.
 }
 
 // A collHeader represents a single HTTP Header.
 type collHeader struct {
   Key      string `json:"key"`
   Value    string `json:"value"`
-  Disabled bool   `json:"disabled"` //  If set to true, the current header should not be sent with requests.
+  Disabled bool   `json:"disabled,omitempty"` //  If set to true, the current header should not be sent with requests.
+
+  extra collExtra
 }
 
 type collURLEncodedParameter struct {
   Key   string `json:"key"`
   Value string `json:"value"`
+
+  extra collExtra
 }
 
 // collBody contains all the data needed for a request body.
 type collBody struct {
   Mode       string                    `json:"mode"` // The type of data associated with this request in this field. One of: raw, urlencoded, formdata, file or graphql.
-  Raw        string                    `json:"raw"`
-  URLEncoded []collURLEncodedParameter `json:"urlencoded"`
+  Raw        string                    `json:"raw,omitempty"`
+  URLEncoded []collURLEncodedParameter `json:"urlencoded,omitempty"`
+
+  extra collExtra // Form data, files, GraphQL queries and options.
+}
+
+// A collAuthAttribute represents an attribute for any authorization method provided by Postman.
+type collAuthAttribute struct {
+  Key   string `json:"key"`
+  Value any    `json:"value"` // Mostly a string, but some methods store booleans or numbers.
+  Type  string `json:"type,omitempty"`
+
+  extra collExtra
+}
+
+// collAuth represents the authentication helpers of a request, folder or collection.
+type collAuth struct {
+  Type   string              `json:"type"` // One of: noauth, inherit, apikey, basic, bearer, digest, oauth1, oauth2, etc.
+  APIKey []collAuthAttribute `json:"apikey,omitempty"`
+  Basic  []collAuthAttribute `json:"basic,omitempty"`
+  Bearer []collAuthAttribute `json:"bearer,omitempty"`
+
+  extra collExtra // The attributes of the other methods.
+}
+
+// attribute returns the string value of the attribute named key, if any.
+func attribute(attributes []collAuthAttribute, key string) string {
+  for a := range slices.Values(attributes) {
+    if key == a.Key {
+      if value, ok := a.Value.(string); ok {
+        return value
+      }
+    }
+  }
+
+  return ""
+}
+
+// A collDescription is the description of a collection, folder or request, written either as a
+// string or as an object that tells the type of its content, such as text/markdown.
+type collDescription struct {
+  Content string `json:"content"`
+  Type    string `json:"type,omitempty"`
+
+  extra collExtra
+}
+
+// A collScript is the code of a collEvent.
+type collScript struct {
+  ID   string          `json:"id,omitempty"`
+  Type string          `json:"type,omitempty"` // The type of the script, E.g: 'text/javascript'.
+  Exec collLines       `json:"exec,omitempty"` // The lines of the script.
+  Src  json.RawMessage `json:"src,omitempty"`  // The URL of a script to fetch instead.
+  Name string          `json:"name,omitempty"`
+
+  extra collExtra
+}
+
+// A collEvent is a script that runs on an event of a request: before it is sent (prerequest) or
+// once its response arrives (test).
+type collEvent struct {
+  ID       string      `json:"id,omitempty"`
+  Listen   string      `json:"listen"`
+  Script   *collScript `json:"script,omitempty"`
+  Disabled bool        `json:"disabled,omitempty"`
+
+  extra collExtra
 }
 
 // A collRequest represents an HTTP request.
 type collRequest struct {
-  URL    collURL      `json:"url"`
-  Method string       `json:"method"`
-  Header []collHeader `json:"header"` // A representation for a list of headers.
-  Body   *collBody    `json:"body"`   // Holds the data contained in the request body.
+  URL         collURL          `json:"url"`
+  Method      string           `json:"method"`
+  Description *collDescription `json:"description,omitempty"`
+  Header      []collHeader     `json:"header"`         // A representation for a list of headers.
+  Body        *collBody        `json:"body,omitempty"` // Holds the data contained in the request body.
+  Auth        *collAuth        `json:"auth,omitempty"` // If nil or of type "inherit", the auth of the parent folder or collection is used.
+
+  extra collExtra // The proxy and certificate settings.
+  short bool      // The request was written as the string of its URL.
 }
 
 // collItem are entities which contain an actual HTTP request.
 type collItem struct {
-  ID      string       `json:"id"`   // A unique ID that is used to identify collections internally.
-  Name    string       `json:"name"` // A human-readable identifier for the current item.
-  Item    []collItem   `json:"item"` // If not empty, the collItem is a folder, which may contain many `collItem`s.
-  Request *collRequest `json:"request"`
+  ID                      string           `json:"id,omitempty"` // A unique ID that is used to identify collections internally.
+  Name                    string           `json:"name"`         // A human-readable identifier for the current item.
+  Description             *collDescription `json:"description,omitempty"`
+  Variable                []collVariable   `json:"variable,omitempty"`
+  Event                   []collEvent      `json:"event,omitempty"`
+  Item                    []collItem       `json:"item,omitempty"` // If not empty, the collItem is a folder, which may contain many `collItem`s.
+  Request                 *collRequest     `json:"request,omitempty"`
+  Response                json.RawMessage  `json:"response,omitempty"` // The saved responses, kept as they are.
+  Auth                    *collAuth        `json:"auth,omitempty"`     // The auth of a folder, inherited by its requests.
+  ProtocolProfileBehavior json.RawMessage  `json:"protocolProfileBehavior,omitempty"`
+  Assertions              []string         `json:"assertions,omitempty"` // The checks of the response of the request, in the assertion DSL.
+  Extract                 []string         `json:"extract,omitempty"`    // The rules that keep values of the response of the request in variables.
+
+  extra collExtra
 }
 
 // A collVariable allows you to define a set of variables, that are a part of the collection.
 type collVariable struct {
-  ID       string `json:"id"`    // A unique user-defined value that identifies the variable within a collection.
-  Key      string `json:"key"`   // A human friendly value that identifies the variable within a collection.
-  Value    string `json:"value"` // The value that a variable holds in the coll struct. Ultimately, the variables will be replaced by this value, when say running a set of requests from a collection
-  Type     string `json:"type"`  // Specifies the type of the variable: string, boolean, any or number.
-  Name     string `json:"name"`  // Variable name.
-  Disabled bool   `json:"disabled"`
+  ID       string `json:"id,omitempty"`   // A unique user-defined value that identifies the variable within a collection.
+  Key      string `json:"key"`            // A human friendly value that identifies the variable within a collection.
+  Value    string `json:"value"`          // The value that a variable holds in the coll struct. Ultimately, the variables will be replaced by this value, when say running a set of requests from a collection
+  Type     string `json:"type,omitempty"` // Specifies the type of the variable: string, boolean, any or number.
+  Name     string `json:"name,omitempty"` // Variable name.
+  Disabled bool   `json:"disabled,omitempty"`
+
+  extra collExtra
+  value json.RawMessage // The value as it was read, which may be a number or a boolean.
+}
+
+// A collEnvironment is a named set of variables, such as an Insomnia sub-environment or a Bruno
+// environment, that overrides the variables of the collection when selected.
+type collEnvironment struct {
+  Name     string
+  Variable []collVariable
 }
 
-// coll holds (some) fields of the Postman Collection Format v2.1.0.
+// collInfo holds the metadata of a collection.
+type collInfo struct {
+  PostmanID   string           `json:"_postman_id,omitempty"`
+  Name        string           `json:"name"` // A collection's friendly name is defined by this field.
+  Description *collDescription `json:"description,omitempty"`
+  Version     json.RawMessage  `json:"version,omitempty"`
+  Schema      string           `json:"schema"` // The URL of the JSON schema of the format.
+
+  extra collExtra
+}
+
+// coll holds the Postman Collection Format v2.1.0. The fields it does not model are kept, so that
+// it can be written back without losing them.
 type coll struct {
-  Info struct {
-    Name string `json:"name"` // A collection's friendly name is defined by this field.
-  }
-  Item     []collItem     `json:"item"`
-  Variable []collVariable `json:"variable"`
+  Info                    collInfo        `json:"info"`
+  Item                    []collItem      `json:"item"`
+  Event                   []collEvent     `json:"event,omitempty"`
+  Variable                []collVariable  `json:"variable,omitempty"`
+  Auth                    *collAuth       `json:"auth,omitempty"`
+  ProtocolProfileBehavior json.RawMessage `json:"protocolProfileBehavior,omitempty"`
+
+  extra        collExtra
+  environments []collEnvironment // The environments of the formats that keep them along with the collection.
+  unsupported  []string          // What the importer of the collection could not convert, see coll.skip.
 }
 
-var (
-  regexpVariable = regexp.MustCompile(`\{\{([a-zA-Z0-9_]+)\}\}`)
-  // newString holds a reference to uuid.NewString, but it is replaced by a mock function in testing,
-  // so that deterministic behaviour is assured.
-  newString = uuid.NewString
-)
+// skip records something of the imported file that the collection model cannot hold, so that it is
+// reported in the import summary instead of being silently dropped.
+func (c *coll) skip(format string, args ...any) {
+  c.unsupported = append(c.unsupported, fmt.Sprintf(format, args...))
+}
+
+// newString holds a reference to uuid.NewString, but it is replaced by a mock function in testing,
+// so that deterministic behaviour is assured.
+var newString = uuid.NewString
 
 // writePair writes a JSON key-value pair to a strings.Builder.
 // This is used to construct JSON objects for requests and responses.
@@ -100,46 +230,65 @@ func writePair(builder *strings.Builder, key, value string) {
 }
 
 // walk recursively processes collItems and their sub-items (folders), generating
-// HTML tree structures and JSON request arrays. It resolves URL variables using
-// the provided map and generates a unique ID for each item.
-func walk(variables map[string]collVariable, array *strings.Builder, dirtree *strings.Builder, fullItemName string, item []collItem) {
+// HTML tree structures and JSON request arrays. It resolves the variables used in
+// the URL, headers, query, body and auth of every request using the provided map,
+// reports the ones it could not resolve, and generates a unique ID for each item
+// that has none. The resolvers of every request share budget.
+func walk(variables map[string]collVariable, budget *resolverBudget, array *strings.Builder, dirtree *strings.Builder, fullItemName string, item []collItem) {
   for i := range slices.Values(item) {
-    if len(i.Item) > 0 { /* folder */
+    if len(i.Item) > 0 || nil == i.Request { /* folder */
       dirtree.WriteString(fmt.Sprintf(
         `<div class="item folder">`+
           "<span class=\"name\">%s</span>", i.Name))
-      walk(variables, array, dirtree, fmt.Sprint(fullItemName, i.Name, " / "), i.Item)
+      walk(variables, budget, array, dirtree, fmt.Sprint(fullItemName, i.Name, " / "), i.Item)
       dirtree.WriteString("</div>")
     } else {
-      resolvedUrl := regexpVariable.ReplaceAllStringFunc(i.Request.URL.Raw, func(match string) string {
-        vname := match[2 : len(match)-2]
+      var (
+        res         = &resolver{variables: variables, budget: budget}
+        resolvedUrl string
+      )
 
-        if replacement, exists := variables[vname]; exists {
-          return replacement.Value
-        }
-
-        return match
-      })
-
-      i.ID = newString()
+      if "" == i.ID {
+        i.ID = newString()
+      }
 
       array.WriteByte('{')
 
       writePair(array, "id", i.ID)
       writePair(array, "name", i.Name)
       writePair(array, "full_name", fmt.Sprint(fullItemName, i.Name))
+      if len(i.Assertions) > 0 {
+        writePair(array, "request_checks", strings.Join(i.Assertions, "\n"))
+      }
+
+      if len(i.Extract) > 0 {
+        writePair(array, "request_extract", strings.Join(i.Extract, "\n"))
+      }
 
       if nil != i.Request {
+        resolvedUrl = res.resolve(i.Request.URL.Raw)
+        header := slices.Clone(i.Request.Header)
+        query := slices.Clone(i.Request.URL.Query)
+
+        h, q := authorize(i.Request.Auth, res)
+        if nil != h {
+          header = append(header, *h)
+        }
+
+        if nil != q {
+          query = append(query, *q)
+          resolvedUrl = appendQuery(resolvedUrl, q.Key, q.Value)
+        }
 
         writePair(array, "request_method", i.Request.Method)
 
         array.WriteString(`"request_header":`)
         array.WriteByte('[')
 
-        for u := range slices.Values(i.Request.Header) {
+        for u := range slices.Values(header) {
           array.WriteByte('{')
-          writePair(array, "key", u.Key)
-          writePair(array, "value", u.Value)
+          writePair(array, "key", res.resolve(u.Key))
+          writePair(array, "value", res.resolve(u.Value))
           array.WriteByte('}')
           array.WriteByte(',')
         }
@@ -153,7 +302,7 @@ func walk(variables map[string]collVariable, array *strings.Builder, dirtree *st
           }
 
           if "" != i.Request.Body.Raw {
-            writePair(array, "request_body_raw", i.Request.Body.Raw)
+            writePair(array, "request_body_raw", res.resolve(i.Request.Body.Raw))
           }
 
           if len(i.Request.Body.URLEncoded) > 0 {
@@ -162,8 +311,8 @@ func walk(variables map[string]collVariable, array *strings.Builder, dirtree *st
 
             for u := range slices.Values(i.Request.Body.URLEncoded) {
               array.WriteByte('{')
-              writePair(array, "key", u.Key)
-              writePair(array, "value", u.Value)
+              writePair(array, "key", res.resolve(u.Key))
+              writePair(array, "value", res.resolve(u.Value))
               array.WriteByte('}')
               array.WriteByte(',')
             }
@@ -179,10 +328,10 @@ func walk(variables map[string]collVariable, array *strings.Builder, dirtree *st
         array.WriteString(`"url_query":`)
         array.WriteByte('[')
 
-        for u := range slices.Values(i.Request.URL.Query) {
+        for u := range slices.Values(query) {
           array.WriteByte('{')
-          writePair(array, "key", u.Key)
-          writePair(array, "value", u.Value)
+          writePair(array, "key", res.resolve(u.Key))
+          writePair(array, "value", res.resolve(u.Value))
           array.WriteByte('}')
           array.WriteByte(',')
         }
@@ -190,9 +339,39 @@ func walk(variables map[string]collVariable, array *strings.Builder, dirtree *st
         array.WriteByte(']')
         array.WriteByte(',')
 
+        if len(i.Request.URL.Variable) > 0 {
+          array.WriteString(`"url_variable":`)
+          array.WriteByte('[')
+
+          for u := range slices.Values(i.Request.URL.Variable) {
+            array.WriteByte('{')
+            writePair(array, "key", u.Key)
+            writePair(array, "value", res.resolve(u.Value))
+            array.WriteByte('}')
+            array.WriteByte(',')
+          }
+
+          array.WriteByte(']')
+          array.WriteByte(',')
+        }
+
       }
 
       writePair(array, "url_resolved", resolvedUrl)
+
+      if len(res.warnings) > 0 {
+        array.WriteString(`"warnings":`)
+        array.WriteByte('[')
+
+        for w := range slices.Values(res.warnings) {
+          array.WriteString(strconv.Quote(w))
+          array.WriteByte(',')
+        }
+
+        array.WriteByte(']')
+        array.WriteByte(',')
+      }
+
       array.WriteByte('}')
       array.WriteByte(',')
 
@@ -201,6 +380,68 @@ func walk(variables map[string]collVariable, array *strings.Builder, dirtree *st
   }
 }
 
+// authorize translates the auth of a request into the header or the query parameter that carries
+// the credentials, resolving their variables along the way. Only API key, basic and bearer auth are
+// supported; other types are reported as warnings.
+func authorize(auth *collAuth, res *resolver) (header *collHeader, query *collQueryParam) {
+  if nil == auth {
+    return nil, nil
+  }
+
+  switch auth.Type {
+  default:
+    res.warn(fmt.Sprintf("unsupported auth type %q", auth.Type))
+  case "", "noauth", "inherit":
+  case "bearer":
+    token := res.resolve(attribute(auth.Bearer, "token"))
+    header = &collHeader{Key: "Authorization", Value: fmt.Sprint("Bearer ", token)}
+  case "basic":
+    username := res.resolve(attribute(auth.Basic, "username"))
+    password := res.resolve(attribute(auth.Basic, "password"))
+    credentials := base64.StdEncoding.EncodeToString([]byte(fmt.Sprint(username, ":", password)))
+    header = &collHeader{Key: "Authorization", Value: fmt.Sprint("Basic ", credentials)}
+  case "apikey":
+    key := res.resolve(attribute(auth.APIKey, "key"))
+    value := res.resolve(attribute(auth.APIKey, "value"))
+    if "query" == attribute(auth.APIKey, "in") {
+      query = &collQueryParam{Key: key, Value: value}
+    } else {
+      header = &collHeader{Key: key, Value: value}
+    }
+  }
+
+  return header, query
+}
+
+// inheritAuth hands down the auth of a collection or folder to the requests that
+// do not define their own or that explicitly inherit it.
+func inheritAuth(parent *collAuth, item []collItem) {
+  for n := range item {
+    i := &item[n]
+
+    if len(i.Item) > 0 { /* folder */
+      auth := parent
+      if nil != i.Auth && "inherit" != i.Auth.Type {
+        auth = i.Auth
+      }
+
+      inheritAuth(auth, i.Item)
+    } else if nil != i.Request && (nil == i.Request.Auth || "inherit" == i.Request.Auth.Type) {
+      i.Request.Auth = parent
+    }
+  }
+}
+
+// appendQuery adds an escaped key-value pair to the query string of a raw URL.
+func appendQuery(raw, key, value string) string {
+  separator := "?"
+  if strings.Contains(raw, "?") {
+    separator = "&"
+  }
+
+  return fmt.Sprint(raw, separator, url.QueryEscape(key), "=", url.QueryEscape(value))
+}
+
 // parseColl parses a JSON input representing a collection file and converts it
 // into a coll struct, returning any decoding errors encountered.
 func parseColl(collfile io.Reader) (c *coll, err error) {
@@ -214,16 +455,15 @@ func parseColl(collfile io.Reader) (c *coll, err error) {
   return c, nil
 }
 
-// collGen generates JavaScript and HTML snippets from a collection file, producing
+// collGen generates JavaScript and HTML snippets from a collection, producing
 // a JSON array of requests and an HTML directory tree of requests and folders.
-// It uses variables for URL resolution within requests.
-func collGen(collfile io.Reader) (collsrc, colldirtree string, err error) {
-  c, err := parseColl(collfile)
-  if nil != err {
-    return "", "", err
-  }
+// It uses variables for URL resolution within requests. The items of c are given
+// an ID, but are otherwise left as they are, so that c can be exported later.
+func collGen(c *coll) (collsrc, colldirtree string) {
+  identify(c.Item)
 
   var (
+    items                = cloneItems(c.Item)
     requestsArrayBuilder = &strings.Builder{}
     dirtreeBuilder       = &strings.Builder{}
     variables            = make(map[string]collVariable)
@@ -237,7 +477,17 @@ func collGen(collfile io.Reader) (collsrc, colldirtree string, err error) {
   dirtreeBuilder.WriteString(c.Info.Name)
   dirtreeBuilder.WriteString("</h3></header>")
 
-  walk(variables, requestsArrayBuilder, dirtreeBuilder, "", c.Item)
+  if len(c.unsupported) > 0 {
+    dirtreeBuilder.WriteString(`<details class="import-summary">`)
+    fmt.Fprintf(dirtreeBuilder, "<summary>Not imported (%d)</summary><ul>", len(c.unsupported))
+    for u := range slices.Values(c.unsupported) {
+      fmt.Fprintf(dirtreeBuilder, "<li>%s</li>", html.EscapeString(u))
+    }
+    dirtreeBuilder.WriteString("</ul></details>")
+  }
+
+  inheritAuth(c.Auth, items)
+  walk(variables, &resolverBudget{}, requestsArrayBuilder, dirtreeBuilder, "", items)
   collsrc = fmt.Sprintf("<script>const requests = [%s];</script>", requestsArrayBuilder.String())
-  return collsrc, dirtreeBuilder.String(), nil
+  return collsrc, dirtreeBuilder.String()
 }
@@ -1,6 +1,7 @@
 package playground
 
 import (
+  "encoding/json"
   "fmt"
   "github.com/google/go-cmp/cmp"
   "reflect"
@@ -11,13 +12,29 @@ import (
 
 var collfile = strings.NewReader(`{"info":{"_postman_id":"c5d10f58-0959-4eba-901b-6d10088ec094","name":"TEST_COLL","schema":"https://schema.getpostman.com/json/collection/v2.1.0/collection.json","_exporter_id":"25152555"},"item":[{"name":"Posts","item":[{"name":"Post Comments","item":[{"name":"Get all post comments (v1)","protocolProfileBehavior":{"disabledSystemHeaders":{"connection":true,"accept-encoding":true,"accept":true}},"request":{"method":"GET","header":[{"key":"Connection","value":"keep-alive","type":"text"},{"key":"Accept-Encoding","value":"gzip, deflate","type":"text"},{"key":"Accept","value":"*/*","type":"text"}],"url":{"raw":"{{host}}/posts/{{post_id}}/comments","host":["{{host}}"],"path":["posts","{{post_id}}","comments"]}},"response":[]},{"name":"Get all post comments (v2)","protocolProfileBehavior":{"disabledSystemHeaders":{"connection":true,"accept-encoding":true,"accept":true}},"request":{"method":"GET","header":[{"key":"Connection","value":"keep-alive","type":"text"},{"key":"Accept-Encoding","value":"gzip, deflate","type":"text"},{"key":"Accept","value":"*/*","type":"text"}],"url":{"raw":"{{host}}/comments?postId={{post_id}}","host":["{{host}}"],"path":["comments"],"query":[{"key":"postId","value":"{{post_id}}"}]}},"response":[]}]},{"name":"Get all posts","protocolProfileBehavior":{"disabledSystemHeaders":{"connection":true,"accept-encoding":true,"accept":true}},"request":{"method":"GET","header":[{"key":"Connection","value":"keep-alive","type":"text"},{"key":"Accept-Encoding","value":"gzip, deflate","type":"text"},{"key":"Accept","value":"*/*","type":"text"}],"url":{"raw":"{{host}}/posts","host":["{{host}}"],"path":["posts"]}},"response":[]},{"name":"Get one post","protocolProfileBehavior":{"disabledSystemHeaders":{"connection":true,"accept-encoding":true,"accept":true}},"request":{"method":"GET","header":[{"key":"Connection","value":"keep-alive","type":"text"},{"key":"Accept-Encoding","value":"gzip, deflate","type":"text"},{"key":"Accept","value":"*/*","type":"text"}],"url":{"raw":"{{host}}/posts/{{post_id}}","host":["{{host}}"],"path":["posts","{{post_id}}"]}},"response":[]},{"name":"Create post","protocolProfileBehavior":{"disabledSystemHeaders":{"connection":true,"accept-encoding":true,"accept":true}},"request":{"method":"POST","header":[{"key":"Connection","value":"keep-alive","type":"text"},{"key":"Accept-Encoding","value":"gzip, deflate","type":"text"},{"key":"Accept","value":"*/*","type":"text"},{"key":"Content-Type","value":"application/json","type":"text"}],"body":{"mode":"raw","raw":"{\n\t\"title\": \"sunt aut facere repellat\",\n\t\"body\": \"quia et suscipit quas totam\"\n}\n","options":{"raw":{"language":"json"}}},"url":{"raw":"{{host}}/posts","host":["{{host}}"],"path":["posts"]}},"response":[]}]},{"name":"Users","item":[{"name":"Get all users","protocolProfileBehavior":{"disabledSystemHeaders":{"connection":true,"accept-encoding":true,"accept":true}},"request":{"method":"GET","header":[{"key":"Connection","value":"keep-alive","type":"text"},{"key":"Accept-Encoding","value":"gzip, deflate","type":"text"},{"key":"Accept","value":"*/*","type":"text"}],"url":{"raw":"{{host}}/users?page=1&limit=100","host":["{{host}}"],"path":["users"],"query":[{"key":"page","value":"1"},{"key":"limit","value":"100"}]}},"response":[]},{"name":"Get one user","protocolProfileBehavior":{"disabledSystemHeaders":{"connection":true,"accept-encoding":true,"accept":true}},"request":{"method":"GET","header":[{"key":"Connection","value":"keep-alive","type":"text"},{"key":"Accept-Encoding","value":"gzip, deflate","type":"text"},{"key":"Accept","value":"*/*","type":"text"}],"url":{"raw":"{{host}}/users/{{user_id}}","host":["{{host}}"],"path":["users","{{user_id}}"]}},"response":[]}]},{"name":"Home","request":{"method":"GET","header":[],"url":{"raw":"{{host}}/","host":["{{host}}"],"path":[""]}},"response":[]},{"name":"Get all photos","protocolProfileBehavior":{"disabledSystemHeaders":{"connection":true,"accept-encoding":true,"accept":true}},"request":{"method":"GET","header":[{"key":"Connection","value":"keep-alive","type":"text"},{"key":"Accept-Encoding","value":"gzip, deflate","type":"text"},{"key":"Accept","value":"*/*","type":"text"}],"url":{"raw":"{{host}}/photos","host":["{{host}}"],"path":["photos"]}},"response":[]}],"event":[{"listen":"prerequest","script":{"type":"text/javascript","packages":{},"exec":[""]}},{"listen":"test","script":{"type":"text/javascript","packages":{},"exec":[""]}}],"variable":[{"key":"host","value":"https://jsonplaceholder.typicode.com","type":"string"},{"key":"post_id","value":"5","type":"string"},{"key":"user_id","value":"10","type":"string"}]}`)
 
+// collUnexported lets cmp.Diff look into the unexported fields of the collection model.
+var collUnexported = cmp.AllowUnexported(
+  coll{}, collInfo{}, collItem{}, collRequest{}, collURL{}, collQueryParam{}, collPathVariable{}, collHeader{},
+  collURLEncodedParameter{}, collBody{}, collAuth{}, collAuthAttribute{}, collDescription{}, collEvent{}, collScript{},
+  collVariable{},
+)
+
 func Test_parseColl(t *testing.T) {
   got, _ := parseColl(collfile)
 
+  var (
+    textHeader    = collExtra{"type": json.RawMessage(`"text"`)}
+    systemHeaders = json.RawMessage(`{"disabledSystemHeaders":{"connection":true,"accept-encoding":true,"accept":true}}`)
+    noResponses   = json.RawMessage(`[]`)
+  )
+
   want := &coll{
-    Info: struct {
-      Name string "json:\"name\""
-    }{Name: "TEST_COLL"},
+    Info: collInfo{
+      PostmanID: "c5d10f58-0959-4eba-901b-6d10088ec094",
+      Name:      "TEST_COLL",
+      Schema:    "https://schema.getpostman.com/json/collection/v2.1.0/collection.json",
+      extra:     collExtra{"_exporter_id": json.RawMessage(`"25152555"`)},
+    },
     Item: []collItem{
       {
         Name: "Posts",
@@ -28,6 +45,8 @@ func Test_parseColl(t *testing.T) {
               {
                 Name: "Get all post comments (v1)",
                 Item: []collItem(nil),
+                Response: noResponses,
+                ProtocolProfileBehavior: systemHeaders,
                 Request: &collRequest{
                   URL: collURL{
                     Raw:      "{{host}}/posts/{{post_id}}/comments",
@@ -39,15 +58,17 @@ func Test_parseColl(t *testing.T) {
                   },
                   Method: "GET",
                   Header: []collHeader{
-                    {Key: "Connection", Value: "keep-alive", Disabled: false},
-                    {Key: "Accept-Encoding", Value: "gzip, deflate", Disabled: false},
-                    {Key: "Accept", Value: "*/*", Disabled: false},
+                    {Key: "Connection", Value: "keep-alive", Disabled: false, extra: textHeader},
+                    {Key: "Accept-Encoding", Value: "gzip, deflate", Disabled: false, extra: textHeader},
+                    {Key: "Accept", Value: "*/*", Disabled: false, extra: textHeader},
                   },
                 },
               },
               {
                 Name: "Get all post comments (v2)",
                 Item: []collItem(nil),
+                Response: noResponses,
+                ProtocolProfileBehavior: systemHeaders,
                 Request: &collRequest{
                   URL: collURL{
                     Raw:      "{{host}}/comments?postId={{post_id}}",
@@ -59,9 +80,9 @@ func Test_parseColl(t *testing.T) {
                   },
                   Method: "GET",
                   Header: []collHeader{
-                    {Key: "Connection", Value: "keep-alive", Disabled: false},
-                    {Key: "Accept-Encoding", Value: "gzip, deflate", Disabled: false},
-                    {Key: "Accept", Value: "*/*", Disabled: false},
+                    {Key: "Connection", Value: "keep-alive", Disabled: false, extra: textHeader},
+                    {Key: "Accept-Encoding", Value: "gzip, deflate", Disabled: false, extra: textHeader},
+                    {Key: "Accept", Value: "*/*", Disabled: false, extra: textHeader},
                   },
                 },
               },
@@ -70,6 +91,8 @@ func Test_parseColl(t *testing.T) {
           {
             Name: "Get all posts",
             Item: []collItem(nil),
+            Response: noResponses,
+            ProtocolProfileBehavior: systemHeaders,
             Request: &collRequest{
               URL: collURL{
                 Raw:      "{{host}}/posts",
@@ -80,15 +103,17 @@ func Test_parseColl(t *testing.T) {
                 Query:    []collQueryParam(nil)},
               Method: "GET",
               Header: []collHeader{
-                {Key: "Connection", Value: "keep-alive", Disabled: false},
-                {Key: "Accept-Encoding", Value: "gzip, deflate", Disabled: false},
-                {Key: "Accept", Value: "*/*", Disabled: false},
+                {Key: "Connection", Value: "keep-alive", Disabled: false, extra: textHeader},
+                {Key: "Accept-Encoding", Value: "gzip, deflate", Disabled: false, extra: textHeader},
+                {Key: "Accept", Value: "*/*", Disabled: false, extra: textHeader},
               },
             },
           },
           {
             Name: "Get one post",
             Item: []collItem(nil),
+            Response: noResponses,
+            ProtocolProfileBehavior: systemHeaders,
             Request: &collRequest{
               URL: collURL{
                 Raw:      "{{host}}/posts/{{post_id}}",
@@ -99,15 +124,17 @@ func Test_parseColl(t *testing.T) {
                 Query:    []collQueryParam(nil)},
               Method: "GET",
               Header: []collHeader{
-                {Key: "Connection", Value: "keep-alive", Disabled: false},
-                {Key: "Accept-Encoding", Value: "gzip, deflate", Disabled: false},
-                {Key: "Accept", Value: "*/*", Disabled: false},
+                {Key: "Connection", Value: "keep-alive", Disabled: false, extra: textHeader},
+                {Key: "Accept-Encoding", Value: "gzip, deflate", Disabled: false, extra: textHeader},
+                {Key: "Accept", Value: "*/*", Disabled: false, extra: textHeader},
               },
             },
           },
           {
             Name: "Create post",
             Item: []collItem(nil),
+            Response: noResponses,
+            ProtocolProfileBehavior: systemHeaders,
             Request: &collRequest{
               URL: collURL{
                 Raw:      "{{host}}/posts",
@@ -118,15 +145,16 @@ func Test_parseColl(t *testing.T) {
                 Query:    []collQueryParam(nil)},
               Method: "POST",
               Header: []collHeader{
-                {Key: "Connection", Value: "keep-alive", Disabled: false},
-                {Key: "Accept-Encoding", Value: "gzip, deflate", Disabled: false},
-                {Key: "Accept", Value: "*/*", Disabled: false},
-                {Key: "Content-Type", Value: "application/json", Disabled: false},
+                {Key: "Connection", Value: "keep-alive", Disabled: false, extra: textHeader},
+                {Key: "Accept-Encoding", Value: "gzip, deflate", Disabled: false, extra: textHeader},
+                {Key: "Accept", Value: "*/*", Disabled: false, extra: textHeader},
+                {Key: "Content-Type", Value: "application/json", Disabled: false, extra: textHeader},
               },
               Body: &collBody{
                 Mode:       "raw",
                 Raw:        "{\n\t\"title\": \"sunt aut facere repellat\",\n\t\"body\": \"quia et suscipit quas totam\"\n}\n",
                 URLEncoded: []collURLEncodedParameter(nil),
+                extra:      collExtra{"options": json.RawMessage(`{"raw":{"language":"json"}}`)},
               },
             },
           },
@@ -138,6 +166,8 @@ func Test_parseColl(t *testing.T) {
           {
             Name: "Get all users",
             Item: []collItem(nil),
+            Response: noResponses,
+            ProtocolProfileBehavior: systemHeaders,
             Request: &collRequest{
               URL: collURL{
                 Raw:      "{{host}}/users?page=1&limit=100",
@@ -152,15 +182,17 @@ func Test_parseColl(t *testing.T) {
               },
               Method: "GET",
               Header: []collHeader{
-                {Key: "Connection", Value: "keep-alive", Disabled: false},
-                {Key: "Accept-Encoding", Value: "gzip, deflate", Disabled: false},
-                {Key: "Accept", Value: "*/*", Disabled: false},
+                {Key: "Connection", Value: "keep-alive", Disabled: false, extra: textHeader},
+                {Key: "Accept-Encoding", Value: "gzip, deflate", Disabled: false, extra: textHeader},
+                {Key: "Accept", Value: "*/*", Disabled: false, extra: textHeader},
               },
             },
           },
           {
             Name: "Get one user",
             Item: []collItem(nil),
+            Response: noResponses,
+            ProtocolProfileBehavior: systemHeaders,
             Request: &collRequest{
               URL: collURL{
                 Raw:      "{{host}}/users/{{user_id}}",
@@ -171,9 +203,9 @@ func Test_parseColl(t *testing.T) {
                 Query:    []collQueryParam(nil)},
               Method: "GET",
               Header: []collHeader{
-                {Key: "Connection", Value: "keep-alive", Disabled: false},
-                {Key: "Accept-Encoding", Value: "gzip, deflate", Disabled: false},
-                {Key: "Accept", Value: "*/*", Disabled: false},
+                {Key: "Connection", Value: "keep-alive", Disabled: false, extra: textHeader},
+                {Key: "Accept-Encoding", Value: "gzip, deflate", Disabled: false, extra: textHeader},
+                {Key: "Accept", Value: "*/*", Disabled: false, extra: textHeader},
               },
             },
           },
@@ -182,6 +214,7 @@ func Test_parseColl(t *testing.T) {
       {
         Name: "Home",
         Item: []collItem(nil),
+        Response: noResponses,
         Request: &collRequest{
           URL: collURL{
             Raw:      "{{host}}/",
@@ -196,6 +229,8 @@ func Test_parseColl(t *testing.T) {
       },
       {Name: "Get all photos",
         Item: []collItem(nil),
+        Response: noResponses,
+        ProtocolProfileBehavior: systemHeaders,
         Request: &collRequest{
           URL: collURL{
             Raw:      "{{host}}/photos",
@@ -206,13 +241,17 @@ func Test_parseColl(t *testing.T) {
             Query:    []collQueryParam(nil)},
           Method: "GET",
           Header: []collHeader{
-            {Key: "Connection", Value: "keep-alive", Disabled: false},
-            {Key: "Accept-Encoding", Value: "gzip, deflate", Disabled: false},
-            {Key: "Accept", Value: "*/*", Disabled: false},
+            {Key: "Connection", Value: "keep-alive", Disabled: false, extra: textHeader},
+            {Key: "Accept-Encoding", Value: "gzip, deflate", Disabled: false, extra: textHeader},
+            {Key: "Accept", Value: "*/*", Disabled: false, extra: textHeader},
           },
         },
       },
     },
+    Event: []collEvent{
+      {Listen: "prerequest", Script: &collScript{Type: "text/javascript", Exec: collLines{""}, extra: collExtra{"packages": json.RawMessage(`{}`)}}},
+      {Listen: "test", Script: &collScript{Type: "text/javascript", Exec: collLines{""}, extra: collExtra{"packages": json.RawMessage(`{}`)}}},
+    },
     Variable: []collVariable{
       {Key: "host", Value: "https://jsonplaceholder.typicode.com", Type: "string", Name: "", Disabled: false},
       {Key: "post_id", Value: "5", Type: "string", Name: "", Disabled: false},
@@ -221,7 +260,7 @@ func Test_parseColl(t *testing.T) {
   }
 
   if !reflect.DeepEqual(want, got) {
-    t.Fatal(cmp.Diff(want, got))
+    t.Fatal(cmp.Diff(want, got, collUnexported))
   }
 }
 
@@ -245,10 +284,10 @@ func Test_walk(t *testing.T) {
   }
 
   newString = func() string { return "714b9856-cac2-4a77-a149-ca1a797918cb" }
-  walk(variables, requestsArrayBuilder, dirtreeBuilder, "", c.Item)
+  walk(variables, &resolverBudget{}, requestsArrayBuilder, dirtreeBuilder, "", c.Item)
 
   got := requestsArrayBuilder.String()
-  want := `{"id":"714b9856-cac2-4a77-a149-ca1a797918cb","name":"Get all post comments (v1)","full_name":"Posts / Post Comments / Get all post comments (v1)","request_method":"GET","request_header":[{"key":"Connection","value":"keep-alive",},{"key":"Accept-Encoding","value":"gzip, deflate",},{"key":"Accept","value":"*/*",},],"url_raw":"{{host}}/posts/{{post_id}}/comments","url_port":"","url_protocol":"","url_query":[],"url_resolved":"https://jsonplaceholder.typicode.com/posts/5/comments",},{"id":"714b9856-cac2-4a77-a149-ca1a797918cb","name":"Get all post comments (v2)","full_name":"Posts / Post Comments / Get all post comments (v2)","request_method":"GET","request_header":[{"key":"Connection","value":"keep-alive",},{"key":"Accept-Encoding","value":"gzip, deflate",},{"key":"Accept","value":"*/*",},],"url_raw":"{{host}}/comments?postId={{post_id}}","url_port":"","url_protocol":"","url_query":[{"key":"postId","value":"{{post_id}}",},],"url_resolved":"https://jsonplaceholder.typicode.com/comments?postId=5",},{"id":"714b9856-cac2-4a77-a149-ca1a797918cb","name":"Get all posts","full_name":"Posts / Get all posts","request_method":"GET","request_header":[{"key":"Connection","value":"keep-alive",},{"key":"Accept-Encoding","value":"gzip, deflate",},{"key":"Accept","value":"*/*",},],"url_raw":"{{host}}/posts","url_port":"","url_protocol":"","url_query":[],"url_resolved":"https://jsonplaceholder.typicode.com/posts",},{"id":"714b9856-cac2-4a77-a149-ca1a797918cb","name":"Get one post","full_name":"Posts / Get one post","request_method":"GET","request_header":[{"key":"Connection","value":"keep-alive",},{"key":"Accept-Encoding","value":"gzip, deflate",},{"key":"Accept","value":"*/*",},],"url_raw":"{{host}}/posts/{{post_id}}","url_port":"","url_protocol":"","url_query":[],"url_resolved":"https://jsonplaceholder.typicode.com/posts/5",},{"id":"714b9856-cac2-4a77-a149-ca1a797918cb","name":"Create post","full_name":"Posts / Create post","request_method":"POST","request_header":[{"key":"Connection","value":"keep-alive",},{"key":"Accept-Encoding","value":"gzip, deflate",},{"key":"Accept","value":"*/*",},{"key":"Content-Type","value":"application/json",},],"request_body_mode":"raw","request_body_raw":"{\n\t\"title\": \"sunt aut facere repellat\",\n\t\"body\": \"quia et suscipit quas totam\"\n}\n","url_raw":"{{host}}/posts","url_port":"","url_protocol":"","url_query":[],"url_resolved":"https://jsonplaceholder.typicode.com/posts",},{"id":"714b9856-cac2-4a77-a149-ca1a797918cb","name":"Get all users","full_name":"Users / Get all users","request_method":"GET","request_header":[{"key":"Connection","value":"keep-alive",},{"key":"Accept-Encoding","value":"gzip, deflate",},{"key":"Accept","value":"*/*",},],"url_raw":"{{host}}/users?page=1&limit=100","url_port":"","url_protocol":"","url_query":[{"key":"page","value":"1",},{"key":"limit","value":"100",},],"url_resolved":"https://jsonplaceholder.typicode.com/users?page=1&limit=100",},{"id":"714b9856-cac2-4a77-a149-ca1a797918cb","name":"Get one user","full_name":"Users / Get one user","request_method":"GET","request_header":[{"key":"Connection","value":"keep-alive",},{"key":"Accept-Encoding","value":"gzip, deflate",},{"key":"Accept","value":"*/*",},],"url_raw":"{{host}}/users/{{user_id}}","url_port":"","url_protocol":"","url_query":[],"url_resolved":"https://jsonplaceholder.typicode.com/users/10",},{"id":"714b9856-cac2-4a77-a149-ca1a797918cb","name":"Home","full_name":"Home","request_method":"GET","request_header":[],"url_raw":"{{host}}/","url_port":"","url_protocol":"","url_query":[],"url_resolved":"https://jsonplaceholder.typicode.com/",},{"id":"714b9856-cac2-4a77-a149-ca1a797918cb","name":"Get all photos","full_name":"Get all photos","request_method":"GET","request_header":[{"key":"Connection","value":"keep-alive",},{"key":"Accept-Encoding","value":"gzip, deflate",},{"key":"Accept","value":"*/*",},],"url_raw":"{{host}}/photos","url_port":"","url_protocol":"","url_query":[],"url_resolved":"https://jsonplaceholder.typicode.com/photos",},`
+  want := `{"id":"714b9856-cac2-4a77-a149-ca1a797918cb","name":"Get all post comments (v1)","full_name":"Posts / Post Comments / Get all post comments (v1)","request_method":"GET","request_header":[{"key":"Connection","value":"keep-alive",},{"key":"Accept-Encoding","value":"gzip, deflate",},{"key":"Accept","value":"*/*",},],"url_raw":"{{host}}/posts/{{post_id}}/comments","url_port":"","url_protocol":"","url_query":[],"url_resolved":"https://jsonplaceholder.typicode.com/posts/5/comments",},{"id":"714b9856-cac2-4a77-a149-ca1a797918cb","name":"Get all post comments (v2)","full_name":"Posts / Post Comments / Get all post comments (v2)","request_method":"GET","request_header":[{"key":"Connection","value":"keep-alive",},{"key":"Accept-Encoding","value":"gzip, deflate",},{"key":"Accept","value":"*/*",},],"url_raw":"{{host}}/comments?postId={{post_id}}","url_port":"","url_protocol":"","url_query":[{"key":"postId","value":"5",},],"url_resolved":"https://jsonplaceholder.typicode.com/comments?postId=5",},{"id":"714b9856-cac2-4a77-a149-ca1a797918cb","name":"Get all posts","full_name":"Posts / Get all posts","request_method":"GET","request_header":[{"key":"Connection","value":"keep-alive",},{"key":"Accept-Encoding","value":"gzip, deflate",},{"key":"Accept","value":"*/*",},],"url_raw":"{{host}}/posts","url_port":"","url_protocol":"","url_query":[],"url_resolved":"https://jsonplaceholder.typicode.com/posts",},{"id":"714b9856-cac2-4a77-a149-ca1a797918cb","name":"Get one post","full_name":"Posts / Get one post","request_method":"GET","request_header":[{"key":"Connection","value":"keep-alive",},{"key":"Accept-Encoding","value":"gzip, deflate",},{"key":"Accept","value":"*/*",},],"url_raw":"{{host}}/posts/{{post_id}}","url_port":"","url_protocol":"","url_query":[],"url_resolved":"https://jsonplaceholder.typicode.com/posts/5",},{"id":"714b9856-cac2-4a77-a149-ca1a797918cb","name":"Create post","full_name":"Posts / Create post","request_method":"POST","request_header":[{"key":"Connection","value":"keep-alive",},{"key":"Accept-Encoding","value":"gzip, deflate",},{"key":"Accept","value":"*/*",},{"key":"Content-Type","value":"application/json",},],"request_body_mode":"raw","request_body_raw":"{\n\t\"title\": \"sunt aut facere repellat\",\n\t\"body\": \"quia et suscipit quas totam\"\n}\n","url_raw":"{{host}}/posts","url_port":"","url_protocol":"","url_query":[],"url_resolved":"https://jsonplaceholder.typicode.com/posts",},{"id":"714b9856-cac2-4a77-a149-ca1a797918cb","name":"Get all users","full_name":"Users / Get all users","request_method":"GET","request_header":[{"key":"Connection","value":"keep-alive",},{"key":"Accept-Encoding","value":"gzip, deflate",},{"key":"Accept","value":"*/*",},],"url_raw":"{{host}}/users?page=1&limit=100","url_port":"","url_protocol":"","url_query":[{"key":"page","value":"1",},{"key":"limit","value":"100",},],"url_resolved":"https://jsonplaceholder.typicode.com/users?page=1&limit=100",},{"id":"714b9856-cac2-4a77-a149-ca1a797918cb","name":"Get one user","full_name":"Users / Get one user","request_method":"GET","request_header":[{"key":"Connection","value":"keep-alive",},{"key":"Accept-Encoding","value":"gzip, deflate",},{"key":"Accept","value":"*/*",},],"url_raw":"{{host}}/users/{{user_id}}","url_port":"","url_protocol":"","url_query":[],"url_resolved":"https://jsonplaceholder.typicode.com/users/10",},{"id":"714b9856-cac2-4a77-a149-ca1a797918cb","name":"Home","full_name":"Home","request_method":"GET","request_header":[],"url_raw":"{{host}}/","url_port":"","url_protocol":"","url_query":[],"url_resolved":"https://jsonplaceholder.typicode.com/",},{"id":"714b9856-cac2-4a77-a149-ca1a797918cb","name":"Get all photos","full_name":"Get all photos","request_method":"GET","request_header":[{"key":"Connection","value":"keep-alive",},{"key":"Accept-Encoding","value":"gzip, deflate",},{"key":"Accept","value":"*/*",},],"url_raw":"{{host}}/photos","url_port":"","url_protocol":"","url_query":[],"url_resolved":"https://jsonplaceholder.typicode.com/photos",},`
 
   if !reflect.DeepEqual(want, got) {
     t.Fatal(cmp.Diff(want, got))
@@ -258,9 +297,14 @@ func Test_walk(t *testing.T) {
 func Test_collGen(t *testing.T) {
   collfile.Seek(0, 0)
   newString = func() string { return "714b9856-cac2-4a77-a149-ca1a797918cb" }
-  collsrc, colldirtree, _ := collGen(collfile)
+  c, err := importColl(collfile)
+  if nil != err {
+    t.Fatalf("unexpected error: %s", err)
+  }
+
+  collsrc, colldirtree := collGen(c)
 
-  want := `<script>const requests = [{"id":"714b9856-cac2-4a77-a149-ca1a797918cb","name":"Get all post comments (v1)","full_name":"Posts / Post Comments / Get all post comments (v1)","request_method":"GET","request_header":[{"key":"Connection","value":"keep-alive",},{"key":"Accept-Encoding","value":"gzip, deflate",},{"key":"Accept","value":"*/*",},],"url_raw":"{{host}}/posts/{{post_id}}/comments","url_port":"","url_protocol":"","url_query":[],"url_resolved":"https://jsonplaceholder.typicode.com/posts/5/comments",},{"id":"714b9856-cac2-4a77-a149-ca1a797918cb","name":"Get all post comments (v2)","full_name":"Posts / Post Comments / Get all post comments (v2)","request_method":"GET","request_header":[{"key":"Connection","value":"keep-alive",},{"key":"Accept-Encoding","value":"gzip, deflate",},{"key":"Accept","value":"*/*",},],"url_raw":"{{host}}/comments?postId={{post_id}}","url_port":"","url_protocol":"","url_query":[{"key":"postId","value":"{{post_id}}",},],"url_resolved":"https://jsonplaceholder.typicode.com/comments?postId=5",},{"id":"714b9856-cac2-4a77-a149-ca1a797918cb","name":"Get all posts","full_name":"Posts / Get all posts","request_method":"GET","request_header":[{"key":"Connection","value":"keep-alive",},{"key":"Accept-Encoding","value":"gzip, deflate",},{"key":"Accept","value":"*/*",},],"url_raw":"{{host}}/posts","url_port":"","url_protocol":"","url_query":[],"url_resolved":"https://jsonplaceholder.typicode.com/posts",},{"id":"714b9856-cac2-4a77-a149-ca1a797918cb","name":"Get one post","full_name":"Posts / Get one post","request_method":"GET","request_header":[{"key":"Connection","value":"keep-alive",},{"key":"Accept-Encoding","value":"gzip, deflate",},{"key":"Accept","value":"*/*",},],"url_raw":"{{host}}/posts/{{post_id}}","url_port":"","url_protocol":"","url_query":[],"url_resolved":"https://jsonplaceholder.typicode.com/posts/5",},{"id":"714b9856-cac2-4a77-a149-ca1a797918cb","name":"Create post","full_name":"Posts / Create post","request_method":"POST","request_header":[{"key":"Connection","value":"keep-alive",},{"key":"Accept-Encoding","value":"gzip, deflate",},{"key":"Accept","value":"*/*",},{"key":"Content-Type","value":"application/json",},],"request_body_mode":"raw","request_body_raw":"{\n\t\"title\": \"sunt aut facere repellat\",\n\t\"body\": \"quia et suscipit quas totam\"\n}\n","url_raw":"{{host}}/posts","url_port":"","url_protocol":"","url_query":[],"url_resolved":"https://jsonplaceholder.typicode.com/posts",},{"id":"714b9856-cac2-4a77-a149-ca1a797918cb","name":"Get all users","full_name":"Users / Get all users","request_method":"GET","request_header":[{"key":"Connection","value":"keep-alive",},{"key":"Accept-Encoding","value":"gzip, deflate",},{"key":"Accept","value":"*/*",},],"url_raw":"{{host}}/users?page=1&limit=100","url_port":"","url_protocol":"","url_query":[{"key":"page","value":"1",},{"key":"limit","value":"100",},],"url_resolved":"https://jsonplaceholder.typicode.com/users?page=1&limit=100",},{"id":"714b9856-cac2-4a77-a149-ca1a797918cb","name":"Get one user","full_name":"Users / Get one user","request_method":"GET","request_header":[{"key":"Connection","value":"keep-alive",},{"key":"Accept-Encoding","value":"gzip, deflate",},{"key":"Accept","value":"*/*",},],"url_raw":"{{host}}/users/{{user_id}}","url_port":"","url_protocol":"","url_query":[],"url_resolved":"https://jsonplaceholder.typicode.com/users/10",},{"id":"714b9856-cac2-4a77-a149-ca1a797918cb","name":"Home","full_name":"Home","request_method":"GET","request_header":[],"url_raw":"{{host}}/","url_port":"","url_protocol":"","url_query":[],"url_resolved":"https://jsonplaceholder.typicode.com/",},{"id":"714b9856-cac2-4a77-a149-ca1a797918cb","name":"Get all photos","full_name":"Get all photos","request_method":"GET","request_header":[{"key":"Connection","value":"keep-alive",},{"key":"Accept-Encoding","value":"gzip, deflate",},{"key":"Accept","value":"*/*",},],"url_raw":"{{host}}/photos","url_port":"","url_protocol":"","url_query":[],"url_resolved":"https://jsonplaceholder.typicode.com/photos",},];</script>`
+  want := `<script>const requests = [{"id":"714b9856-cac2-4a77-a149-ca1a797918cb","name":"Get all post comments (v1)","full_name":"Posts / Post Comments / Get all post comments (v1)","request_method":"GET","request_header":[{"key":"Connection","value":"keep-alive",},{"key":"Accept-Encoding","value":"gzip, deflate",},{"key":"Accept","value":"*/*",},],"url_raw":"{{host}}/posts/{{post_id}}/comments","url_port":"","url_protocol":"","url_query":[],"url_resolved":"https://jsonplaceholder.typicode.com/posts/5/comments",},{"id":"714b9856-cac2-4a77-a149-ca1a797918cb","name":"Get all post comments (v2)","full_name":"Posts / Post Comments / Get all post comments (v2)","request_method":"GET","request_header":[{"key":"Connection","value":"keep-alive",},{"key":"Accept-Encoding","value":"gzip, deflate",},{"key":"Accept","value":"*/*",},],"url_raw":"{{host}}/comments?postId={{post_id}}","url_port":"","url_protocol":"","url_query":[{"key":"postId","value":"5",},],"url_resolved":"https://jsonplaceholder.typicode.com/comments?postId=5",},{"id":"714b9856-cac2-4a77-a149-ca1a797918cb","name":"Get all posts","full_name":"Posts / Get all posts","request_method":"GET","request_header":[{"key":"Connection","value":"keep-alive",},{"key":"Accept-Encoding","value":"gzip, deflate",},{"key":"Accept","value":"*/*",},],"url_raw":"{{host}}/posts","url_port":"","url_protocol":"","url_query":[],"url_resolved":"https://jsonplaceholder.typicode.com/posts",},{"id":"714b9856-cac2-4a77-a149-ca1a797918cb","name":"Get one post","full_name":"Posts / Get one post","request_method":"GET","request_header":[{"key":"Connection","value":"keep-alive",},{"key":"Accept-Encoding","value":"gzip, deflate",},{"key":"Accept","value":"*/*",},],"url_raw":"{{host}}/posts/{{post_id}}","url_port":"","url_protocol":"","url_query":[],"url_resolved":"https://jsonplaceholder.typicode.com/posts/5",},{"id":"714b9856-cac2-4a77-a149-ca1a797918cb","name":"Create post","full_name":"Posts / Create post","request_method":"POST","request_header":[{"key":"Connection","value":"keep-alive",},{"key":"Accept-Encoding","value":"gzip, deflate",},{"key":"Accept","value":"*/*",},{"key":"Content-Type","value":"application/json",},],"request_body_mode":"raw","request_body_raw":"{\n\t\"title\": \"sunt aut facere repellat\",\n\t\"body\": \"quia et suscipit quas totam\"\n}\n","url_raw":"{{host}}/posts","url_port":"","url_protocol":"","url_query":[],"url_resolved":"https://jsonplaceholder.typicode.com/posts",},{"id":"714b9856-cac2-4a77-a149-ca1a797918cb","name":"Get all users","full_name":"Users / Get all users","request_method":"GET","request_header":[{"key":"Connection","value":"keep-alive",},{"key":"Accept-Encoding","value":"gzip, deflate",},{"key":"Accept","value":"*/*",},],"url_raw":"{{host}}/users?page=1&limit=100","url_port":"","url_protocol":"","url_query":[{"key":"page","value":"1",},{"key":"limit","value":"100",},],"url_resolved":"https://jsonplaceholder.typicode.com/users?page=1&limit=100",},{"id":"714b9856-cac2-4a77-a149-ca1a797918cb","name":"Get one user","full_name":"Users / Get one user","request_method":"GET","request_header":[{"key":"Connection","value":"keep-alive",},{"key":"Accept-Encoding","value":"gzip, deflate",},{"key":"Accept","value":"*/*",},],"url_raw":"{{host}}/users/{{user_id}}","url_port":"","url_protocol":"","url_query":[],"url_resolved":"https://jsonplaceholder.typicode.com/users/10",},{"id":"714b9856-cac2-4a77-a149-ca1a797918cb","name":"Home","full_name":"Home","request_method":"GET","request_header":[],"url_raw":"{{host}}/","url_port":"","url_protocol":"","url_query":[],"url_resolved":"https://jsonplaceholder.typicode.com/",},{"id":"714b9856-cac2-4a77-a149-ca1a797918cb","name":"Get all photos","full_name":"Get all photos","request_method":"GET","request_header":[{"key":"Connection","value":"keep-alive",},{"key":"Accept-Encoding","value":"gzip, deflate",},{"key":"Accept","value":"*/*",},],"url_raw":"{{host}}/photos","url_port":"","url_protocol":"","url_query":[],"url_resolved":"https://jsonplaceholder.typicode.com/photos",},];</script>`
 
   if !reflect.DeepEqual(want, collsrc) {
     t.Fatal(cmp.Diff(want, collsrc))
@@ -272,3 +316,32 @@ func Test_collGen(t *testing.T) {
     t.Fatal(cmp.Diff(want, colldirtree))
   }
 }
+
+func Test_walkResolvesEveryPart(t *testing.T) {
+  c, err := parseColl(strings.NewReader(`{"info":{"name":"AUTH"},"auth":{"type":"bearer","bearer":[{"key":"token","value":"{{token}}","type":"string"}]},"item":[{"name":"Admin","auth":{"type":"basic","basic":[{"key":"username","value":"{{user}}"},{"key":"password","value":"s3cr3t"},{"key":"saveHelperData","value":true}]},"item":[{"name":"Create user","request":{"method":"POST","header":[{"key":"X-Trace","value":"{{trace.id}}"}],"body":{"mode":"urlencoded","urlencoded":[{"key":"name","value":"{{user}}"}]},"url":{"raw":"{{host}}/users","query":[]}}}]},{"name":"Me","request":{"method":"GET","header":[],"body":{"mode":"raw","raw":"{\"id\": \"{{me}}\"}"},"url":{"raw":"{{host}}/me"}}},{"name":"Post","request":{"method":"GET","header":[],"url":{"raw":"{{host}}/users/:id/posts/:postId","path":["users",":id","posts",":postId"],"variable":[{"key":"id","value":"{{user}}"},{"key":"postId","value":"7","description":"The post"}]}}},{"name":"Search","request":{"auth":{"type":"apikey","apikey":[{"key":"key","value":"api_key"},{"key":"value","value":"{{key}}"},{"key":"in","value":"query"}]},"method":"GET","header":[],"url":{"raw":"{{host}}/search?q={{q}}","query":[{"key":"q","value":"{{q}}"}]}}}],"variable":[{"key":"host","value":"https://{{domain}}"},{"key":"domain","value":"fontseca.dev"},{"key":"token","value":"abc"},{"key":"user","value":"jane"},{"key":"trace.id","value":"t-1"},{"key":"key","value":"k&1"},{"key":"q","value":"go"}]}`))
+
+  if nil != err {
+    t.Fatalf("unexpected error: %s", err)
+  }
+
+  var (
+    requestsArrayBuilder = &strings.Builder{}
+    dirtreeBuilder       = &strings.Builder{}
+    variables            = make(map[string]collVariable)
+  )
+
+  for v := range slices.Values(c.Variable) {
+    variables[v.Key] = v
+  }
+
+  newString = func() string { return "714b9856-cac2-4a77-a149-ca1a797918cb" }
+  inheritAuth(c.Auth, c.Item)
+  walk(variables, &resolverBudget{}, requestsArrayBuilder, dirtreeBuilder, "", c.Item)
+
+  got := requestsArrayBuilder.String()
+  want := `{"id":"714b9856-cac2-4a77-a149-ca1a797918cb","name":"Create user","full_name":"Admin / Create user","request_method":"POST","request_header":[{"key":"X-Trace","value":"t-1",},{"key":"Authorization","value":"Basic amFuZTpzM2NyM3Q=",},],"request_body_mode":"urlencoded","request_body_urlencoded":[{"key":"name","value":"jane",},],"url_raw":"{{host}}/users","url_port":"","url_protocol":"","url_query":[],"url_resolved":"https://fontseca.dev/users",},{"id":"714b9856-cac2-4a77-a149-ca1a797918cb","name":"Me","full_name":"Me","request_method":"GET","request_header":[{"key":"Authorization","value":"Bearer abc",},],"request_body_mode":"raw","request_body_raw":"{\"id\": \"{{me}}\"}","url_raw":"{{host}}/me","url_port":"","url_protocol":"","url_query":[],"url_resolved":"https://fontseca.dev/me","warnings":["unresolved variable {{me}}",],},{"id":"714b9856-cac2-4a77-a149-ca1a797918cb","name":"Post","full_name":"Post","request_method":"GET","request_header":[{"key":"Authorization","value":"Bearer abc",},],"url_raw":"{{host}}/users/:id/posts/:postId","url_port":"","url_protocol":"","url_query":[],"url_variable":[{"key":"id","value":"jane",},{"key":"postId","value":"7",},],"url_resolved":"https://fontseca.dev/users/:id/posts/:postId",},{"id":"714b9856-cac2-4a77-a149-ca1a797918cb","name":"Search","full_name":"Search","request_method":"GET","request_header":[],"url_raw":"{{host}}/search?q={{q}}","url_port":"","url_protocol":"","url_query":[{"key":"q","value":"go",},{"key":"api_key","value":"k&1",},],"url_resolved":"https://fontseca.dev/search?q=go&api_key=k%261",},`
+
+  if !reflect.DeepEqual(want, got) {
+    t.Fatal(cmp.Diff(want, got))
+  }
+}
@@ -0,0 +1,317 @@
+package playground
+
+import (
+  "bytes"
+  "io"
+  "regexp"
+  "slices"
+  "strings"
+)
+
+// cssReValue matches the numbers, the colors and the !important of the values of declarations.
+var cssReValue = regexp.MustCompile(`#[0-9a-fA-F]{3,8}\b|!important\b|[-+]?(?:\d+\.?\d*|\.\d+)(?:%|[a-zA-Z]+)?`)
+
+type cssFormatterImpl struct{}
+
+// A cssToken is a piece of a style sheet.
+type cssToken struct {
+  kind tokenKind // tokenComment, tokenString, tokenPunctuation for { } and ;, and tokenText for the rest.
+  raw  []byte    // Blanks are a single space.
+}
+
+// cssIsSpace reports whether c is a blank.
+func cssIsSpace(c byte) bool {
+  return ' ' == c || '\t' == c || '\n' == c || '\r' == c || '\f' == c
+}
+
+// cssIsName reports whether c belongs to a name, such as a property or a unit.
+func cssIsName(c byte) bool {
+  return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' || '_' == c || '-' == c || c >= 0x80
+}
+
+// cssLex splits input into tokens. Strings, comments and the unquoted addresses of url() are read as
+// they are, so that the braces and semicolons in them are not taken for punctuation.
+func cssLex(input []byte) []cssToken {
+  var tokens []cssToken
+  for n := 0; n < len(input); {
+    start := n
+    switch c := input[n]; {
+    case cssIsSpace(c):
+      for n < len(input) && cssIsSpace(input[n]) {
+        n++
+      }
+
+      tokens = append(tokens, cssToken{tokenText, []byte{' '}})
+      continue
+    case bytes.HasPrefix(input[n:], []byte("/*")):
+      if end := bytes.Index(input[n+2:], []byte("*/")); -1 != end {
+        n += end + 4
+      } else {
+        n = len(input)
+      }
+
+      tokens = append(tokens, cssToken{tokenComment, input[start:n]})
+      continue
+    case '"' == c || '\'' == c:
+      for n++; n < len(input) && c != input[n] && '\n' != input[n]; n++ {
+        if '\\' == input[n] {
+          n++
+        }
+      }
+
+      n = min(n+1, len(input))
+      tokens = append(tokens, cssToken{tokenString, input[start:n]})
+      continue
+    case '{' == c || '}' == c || ';' == c:
+      n++
+      tokens = append(tokens, cssToken{tokenPunctuation, input[start:n]})
+      continue
+    }
+
+    for n < len(input) && !cssIsSpace(input[n]) && !strings.ContainsRune("{};\"'", rune(input[n])) && !bytes.HasPrefix(input[n:], []byte("/*")) {
+      n++
+      if n-start >= 4 && '(' == input[n-1] && strings.EqualFold("url(", string(input[n-4:n])) {
+        address := n
+        for address < len(input) && cssIsSpace(input[address]) {
+          address++
+        }
+
+        if address < len(input) && '"' != input[address] && '\'' != input[address] {
+          if end := bytes.IndexByte(input[address:], ')'); -1 != end {
+            n = address + end + 1
+          } else {
+            n = len(input)
+          }
+        }
+      }
+    }
+
+    tokens = append(tokens, cssToken{tokenText, input[start:n]})
+  }
+
+  return tokens
+}
+
+// cssTrim returns tokens without the blanks they start and end with.
+func cssTrim(tokens []cssToken) []cssToken {
+  blank := func(token cssToken) bool { return tokenText == token.kind && " " == string(token.raw) }
+  for len(tokens) > 0 && blank(tokens[0]) {
+    tokens = tokens[1:]
+  }
+
+  for len(tokens) > 0 && blank(tokens[len(tokens)-1]) {
+    tokens = tokens[:len(tokens)-1]
+  }
+
+  return tokens
+}
+
+// A cssWriter writes the rules of a style sheet, indented.
+type cssWriter struct {
+  output  tokenWriter
+  indent  string
+  depth   int        // The number of blocks that are open.
+  written bool       // Whether anything was written, before which no line is broken.
+  blank   bool       // Whether a blank line goes before the next line, after a rule at the top level.
+  opened  bool       // Whether the last thing written was an opening brace, so that an empty block stays on its line.
+  pending []cssToken // The tokens of the selector or the declaration read so far.
+}
+
+// line starts a new line at the depth of w.
+func (w *cssWriter) line() {
+  line := bytes.Repeat([]byte(w.indent), w.depth)
+  switch {
+  case w.written && w.blank && 0 == w.depth:
+    line = append([]byte("\n\n"), line...)
+  case w.written:
+    line = append([]byte{'\n'}, line...)
+  }
+
+  w.written, w.blank, w.opened = true, false, false
+  w.output.token(tokenText, line)
+}
+
+// flush writes the pending declaration or at-rule on a line of its own, followed by a semicolon if it
+// had one.
+func (w *cssWriter) flush(semicolon bool) {
+  tokens := cssTrim(w.pending)
+  w.pending = nil
+  if 0 == len(tokens) {
+    return
+  }
+
+  w.line()
+  w.declaration(tokens)
+  if semicolon {
+    w.output.token(tokenPunctuation, []byte{';'})
+  }
+}
+
+// declaration writes a declaration as property: value, or an at-rule such as @import.
+func (w *cssWriter) declaration(tokens []cssToken) {
+  if w.atRule(tokens) {
+    return
+  }
+
+  for n, token := range tokens {
+    colon := bytes.IndexByte(token.raw, ':')
+    if tokenText != token.kind || -1 == colon {
+      continue
+    }
+
+    if 0 == w.depth {
+      break
+    }
+
+    var property []byte
+    for token := range slices.Values(tokens[:n]) {
+      property = append(property, token.raw...)
+    }
+
+    w.output.token(tokenKey, bytes.TrimSpace(append(property, token.raw[:colon]...)))
+    w.output.token(tokenPunctuation, []byte{':'})
+    value := cssTrim(append([]cssToken{{tokenText, token.raw[colon+1:]}}, tokens[n+1:]...))
+    if len(value) > 0 && 0 == len(value[0].raw) {
+      value = cssTrim(value[1:])
+    }
+
+    if len(value) > 0 {
+      w.output.token(tokenText, []byte{' '})
+      w.value(value)
+    }
+
+    return
+  }
+
+  w.value(tokens)
+}
+
+// atRule writes tokens if they are an at-rule, such as @media screen, whose keyword is highlighted, and
+// reports whether they were.
+func (w *cssWriter) atRule(tokens []cssToken) bool {
+  if tokenText != tokens[0].kind || !bytes.HasPrefix(tokens[0].raw, []byte{'@'}) {
+    return false
+  }
+
+  keyword := tokens[0].raw
+  end := 1
+  for end < len(keyword) && cssIsName(keyword[end]) {
+    end++
+  }
+
+  w.output.token(tokenKeyword, keyword[:end])
+  w.value(append([]cssToken{{tokenText, keyword[end:]}}, tokens[1:]...))
+  return true
+}
+
+// selector writes the selector of a rule, with a blank after every comma that separates its parts.
+func (w *cssWriter) selector(tokens []cssToken) {
+  if w.atRule(tokens) {
+    return
+  }
+
+  parentheses, comma := 0, false
+  for token := range slices.Values(tokens) {
+    if comma && tokenText == token.kind && " " == string(token.raw) {
+      continue
+    }
+
+    comma = false
+    if tokenText != token.kind {
+      w.output.token(token.kind, token.raw)
+      continue
+    }
+
+    start := 0
+    for n, c := range token.raw {
+      switch {
+      case '(' == c:
+        parentheses++
+      case ')' == c:
+        parentheses = max(0, parentheses-1)
+      case ',' == c && 0 == parentheses:
+        w.output.token(tokenTag, token.raw[start:n])
+        w.output.token(tokenPunctuation, []byte{','})
+        w.output.token(tokenText, []byte{' '})
+        start, comma = n+1, n == len(token.raw)-1
+      }
+    }
+
+    w.output.token(tokenTag, token.raw[start:])
+  }
+}
+
+// value writes the value of a declaration, whose numbers, colors and !important are highlighted.
+func (w *cssWriter) value(tokens []cssToken) {
+  for token := range slices.Values(tokens) {
+    if tokenText != token.kind {
+      w.output.token(token.kind, token.raw)
+      continue
+    }
+
+    start := 0
+    for match := range slices.Values(cssReValue.FindAllIndex(token.raw, -1)) {
+      if '!' != token.raw[match[0]] && match[0] > 0 && cssIsName(token.raw[match[0]-1]) {
+        continue /* Such as the 3 of translate3d.  */
+      }
+
+      kind := tokenNumber
+      if '!' == token.raw[match[0]] {
+        kind = tokenLiteral
+      }
+
+      w.output.token(tokenText, token.raw[start:match[0]])
+      w.output.token(kind, token.raw[match[0]:match[1]])
+      start = match[1]
+    }
+
+    w.output.token(tokenText, token.raw[start:])
+  }
+}
+
+// format implements a CSS formatter.
+func (f cssFormatterImpl) format(input []byte, output io.Writer, indent string) {
+  formatTokens(f, input, output, indent)
+}
+
+// tokens writes every selector, declaration and at-rule of input on a line of its own, indented by the
+// number of blocks it is in, with a blank line between the rules at the top level. Blanks are
+// collapsed, but strings, comments and addresses are written as they are.
+func (cssFormatterImpl) tokens(input []byte, output tokenWriter, indent string) {
+  w := &cssWriter{output: output, indent: indent}
+  for token := range slices.Values(cssLex(input)) {
+    switch {
+    case tokenComment == token.kind && 0 == len(cssTrim(w.pending)):
+      w.pending = nil
+      w.line()
+      w.output.token(tokenComment, token.raw)
+    case tokenPunctuation != token.kind:
+      w.pending = append(w.pending, token)
+    case '{' == token.raw[0]:
+      selector := cssTrim(w.pending)
+      w.pending = nil
+      w.line()
+      if len(selector) > 0 {
+        w.selector(selector)
+        w.output.token(tokenText, []byte{' '})
+      }
+
+      w.output.token(tokenPunctuation, token.raw)
+      w.depth, w.opened = w.depth+1, true
+    case '}' == token.raw[0]:
+      w.flush(false)
+      w.depth = max(0, w.depth-1)
+      if !w.opened {
+        w.line()
+      }
+
+      w.opened = false
+      w.output.token(tokenPunctuation, token.raw)
+      w.blank = 0 == w.depth
+    default:
+      w.flush(true)
+    }
+  }
+
+  w.flush(false)
+}
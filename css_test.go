@@ -0,0 +1,51 @@
+package playground
+
+import (
+  "bytes"
+  "testing"
+)
+
+func TestFormatCSS(t *testing.T) {
+  tests := []struct {
+    input string
+    want  string
+  }{
+    {`@charset "utf-8";@import url(a.css);a,b>c:hover{color:red;margin:-1px 0 .5em!important}`, `@charset "utf-8";
+@import url(a.css);
+a, b>c:hover {
+  color: red;
+  margin: -1px 0 .5em!important
+}`},
+    {`.icon{background:url(data:image/svg+xml;charset=utf8,%3Csvg%3E%7B%7D) no-repeat;content:"{;}"}/* dark */`, `.icon {
+  background: url(data:image/svg+xml;charset=utf8,%3Csvg%3E%7B%7D) no-repeat;
+  content: "{;}"
+}
+
+/* dark */`},
+    {"@media (max-width: 600px) {\n\n  .x , .y { transform : translate3d(0,0,0) }\n}\np{}", `@media (max-width: 600px) {
+  .x , .y {
+    transform: translate3d(0,0,0)
+  }
+}
+
+p {}`},
+    {"}}a{b:c", "}\n\n}\n\na {\n  b: c"},
+    {"  ", ""},
+  }
+
+  for _, test := range tests {
+    buffer := bytes.Buffer{}
+    cssFormatter.format([]byte(test.input), &buffer, "  ")
+    if got := buffer.String(); test.want != got {
+      t.Errorf("format(%q) =\n%s\nwant\n%s", test.input, got, test.want)
+    }
+  }
+
+  want := `<span class="tok-tag">a</span> <span class="tok-punct">{</span>
+  <span class="tok-key">color</span><span class="tok-punct">:</span> <span class="tok-number">#fff</span><span class="tok-punct">;</span>
+  <span class="tok-key">width</span><span class="tok-punct">:</span> calc(<span class="tok-number">100%</span> - <span class="tok-number">2px</span>)
+<span class="tok-punct">}</span>`
+  if got := string(highlightTokens(cssFormatter, []byte("a{color:#fff;width:calc(100% - 2px)}"), "  ")); want != got {
+    t.Errorf("highlightTokens(...) =\n%s\nwant\n%s", got, want)
+  }
+}
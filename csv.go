@@ -0,0 +1,180 @@
+package playground
+
+import (
+  "bytes"
+  "encoding/csv"
+  "errors"
+  "fmt"
+  "io"
+  "slices"
+  "strconv"
+  "strings"
+  "unicode/utf8"
+)
+
+const (
+  maxTableRows    = 1000 // The number of rows of a CSV table that are shown.
+  maxTableColumns = 30   // The number of columns of a CSV table that are shown.
+  maxCellWidth    = 60   // The number of characters of a cell that are shown.
+)
+
+// csvDelimiters are the delimiters that a CSV body is tried with, when its media type does not tell.
+var csvDelimiters = []rune{',', ';', '\t', '|'}
+
+type csvFormatterImpl struct {
+  delimiter rune // The separator of fields, or 0 to detect it.
+  noHeader  bool // Whether the first record is data rather than the names of the columns, as with header=absent.
+}
+
+// detect returns the delimiter of input: the candidate that splits its first records into the most
+// fields, the same number for each.
+func (f csvFormatterImpl) detect(input []byte) rune {
+  if 0 != f.delimiter {
+    return f.delimiter
+  }
+
+  best, fields := ',', 1
+  for delimiter := range slices.Values(csvDelimiters) {
+    reader := csv.NewReader(bytes.NewReader(input))
+    reader.Comma, reader.LazyQuotes = delimiter, true
+    width := 0
+    for range 20 {
+      record, err := reader.Read()
+      if errors.Is(err, io.EOF) {
+        break
+      }
+
+      if nil != err { /* The records do not have the same number of fields.  */
+        width = 0
+        break
+      }
+
+      width = len(record)
+    }
+
+    if width > fields {
+      best, fields = delimiter, width
+    }
+  }
+
+  return best
+}
+
+// read returns the records of input.
+func (f csvFormatterImpl) read(input []byte) ([][]string, error) {
+  reader := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(input, []byte("\ufeff"))))
+  reader.Comma, reader.LazyQuotes, reader.FieldsPerRecord = f.detect(input), true, -1
+  return reader.ReadAll()
+}
+
+// format implements a CSV and TSV formatter.
+func (f csvFormatterImpl) format(input []byte, output io.Writer, indent string) {
+  formatTokens(f, input, output, indent)
+}
+
+// tokens writes the records of input as a table whose columns are padded to the same width, with a
+// line under the header, if there is one, and a last line that counts the rows and the columns. New
+// lines in fields are written as ↵, and the cells, rows and columns past the limits are left out. A
+// body that is not CSV is written as it is.
+func (f csvFormatterImpl) tokens(input []byte, output tokenWriter, _ string) {
+  if 0 == len(bytes.TrimSpace(input)) {
+    return
+  }
+
+  records, err := f.read(input)
+  if nil != err {
+    output.token(tokenText, bytes.TrimSpace(input))
+    return
+  }
+
+  columns := 0
+  for record := range slices.Values(records) {
+    columns = max(columns, len(record))
+  }
+
+  headers := 0
+  if !f.noHeader {
+    headers = 1
+  }
+
+  rows := len(records) - headers
+  shown := records[:min(len(records), headers+maxTableRows)]
+  widths := make([]int, min(columns, maxTableColumns))
+  cells := make([][]string, len(shown))
+  for n, record := range shown {
+    cells[n] = make([]string, len(widths))
+    for column := range widths {
+      if column < len(record) {
+        cells[n][column] = csvCell(record[column])
+      }
+
+      widths[column] = max(widths[column], utf8.RuneCountInString(cells[n][column]))
+    }
+  }
+
+  for n, row := range cells {
+    header := 0 == n && !f.noHeader
+    for column, cell := range row {
+      if column > 0 {
+        output.token(tokenPunctuation, []byte(" | "))
+      }
+
+      kind := tokenText
+      switch _, err := strconv.ParseFloat(cell, 64); {
+      case header:
+        kind = tokenKey
+      case nil == err:
+        kind = tokenNumber
+      }
+
+      output.token(kind, []byte(cell))
+      if column < len(row)-1 {
+        output.token(tokenText, []byte(strings.Repeat(" ", widths[column]-utf8.RuneCountInString(cell))))
+      }
+    }
+
+    output.token(tokenText, []byte{'\n'})
+    if header {
+      rule := make([]string, len(widths))
+      for column, width := range widths {
+        rule[column] = strings.Repeat("-", width)
+      }
+
+      output.token(tokenPunctuation, []byte(strings.Join(rule, "-+-")))
+      output.token(tokenText, []byte{'\n'})
+    }
+  }
+
+  summary := fmt.Sprintf("%s, %s", csvCount(rows, "row"), csvCount(columns, "column"))
+  switch {
+  case rows > maxTableRows && columns > maxTableColumns:
+    summary += fmt.Sprintf(" (truncated to the first %d rows and %d columns)", maxTableRows, maxTableColumns)
+  case rows > maxTableRows:
+    summary += fmt.Sprintf(" (truncated to the first %d rows)", maxTableRows)
+  case columns > maxTableColumns:
+    summary += fmt.Sprintf(" (truncated to the first %d columns)", maxTableColumns)
+  }
+
+  output.token(tokenText, []byte{'\n'})
+  output.token(tokenComment, []byte(summary))
+}
+
+// csvCell returns what is shown of field in a table: its new lines are written as ↵, and it is cut at
+// maxCellWidth characters.
+func csvCell(field string) string {
+  field = strings.NewReplacer("\r\n", "↵", "\n", "↵", "\r", "↵", "\t", " ").Replace(field)
+  if utf8.RuneCountInString(field) > maxCellWidth {
+    field = string([]rune(field)[:maxCellWidth-1]) + "…"
+  }
+
+  return field
+}
+
+// csvCount returns n followed by noun, in the plural unless n is 1.
+func csvCount(n int, noun string) string {
+  if 1 == n {
+    return fmt.Sprint("1 ", noun)
+  }
+
+  return fmt.Sprintf("%d %ss", n, noun)
+}
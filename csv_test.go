@@ -0,0 +1,93 @@
+package playground
+
+import (
+  "bytes"
+  "strings"
+  "testing"
+)
+
+func TestFormatCSV(t *testing.T) {
+  tests := []struct {
+    formatter *csvFormatterImpl
+    input     string
+    want      string
+  }{
+    {csvFormatter, "id,name,note\r\n1,Ann,\"multi\nline, quoted\"\r\n22,Bob,ok\r\n", `id | name | note
+---+------+-------------------
+1  | Ann  | multi↵line, quoted
+22 | Bob  | ok
+
+2 rows, 3 columns`},
+    {csvFormatter, "\ufeffa;b;c\n1;2,5;x\n3;4;y\n", `a | b   | c
+--+-----+--
+1 | 2,5 | x
+3 | 4   | y
+
+2 rows, 3 columns`},
+    {tsvFormatter, "x\ty, z\n1\t2\n", `x | y, z
+--+-----
+1 | 2
+
+1 row, 2 columns`},
+    {&csvFormatterImpl{noHeader: true}, "1,2,3\n4,5,6\n", `1 | 2 | 3
+4 | 5 | 6
+
+2 rows, 3 columns`},
+    {csvFormatter, "name\n" + strings.Repeat("x", 70) + "\n", "name\n" + strings.Repeat("-", maxCellWidth) + "\n" + strings.Repeat("x", 59) + `…
+
+1 row, 1 column`},
+    {csvFormatter, "  \n", ""},
+  }
+
+  for _, test := range tests {
+    buffer := bytes.Buffer{}
+    test.formatter.format([]byte(test.input), &buffer, "  ")
+    if got := buffer.String(); test.want != got {
+      t.Errorf("format(%q) =\n%s\nwant\n%s", test.input, got, test.want)
+    }
+  }
+
+  wide := &bytes.Buffer{}
+  for row := range maxTableRows + 5 {
+    for column := range maxTableColumns + 2 {
+      if column > 0 {
+        wide.WriteByte(',')
+      }
+
+      wide.WriteString(strings.Repeat("v", row%3+column%2))
+    }
+
+    wide.WriteByte('\n')
+  }
+
+  buffer := bytes.Buffer{}
+  csvFormatter.format(wide.Bytes(), &buffer, "  ")
+  lines := strings.Split(buffer.String(), "\n")
+  if want := "1004 rows, 32 columns (truncated to the first 1000 rows and 30 columns)"; want != lines[len(lines)-1] {
+    t.Errorf("the last line of a large table is %q, want %q", lines[len(lines)-1], want)
+  }
+
+  if want := 1 + 1 + maxTableRows + 2; want != len(lines) {
+    t.Errorf("a large table has %d lines, want %d", len(lines), want)
+  }
+
+  if columns := strings.Count(lines[0], "|") + 1; maxTableColumns != columns {
+    t.Errorf("a wide table shows %d columns, want %d", columns, maxTableColumns)
+  }
+}
+
+func TestCSVFormatter_detect(t *testing.T) {
+  for input, want := range map[string]rune{
+    "a,b\n1,2\n":            ',',
+    "a;b;c\n1,5;2;3\n":      ';',
+    "a\tb\n1\t2\n":          '\t',
+    "a|b\n1|2\n":            '|',
+    "single\ncolumn\n":      ',',
+    "a;b\n\"x;y\";2\n":      ';',
+    "a,b;c\n1,2;3\n4,5;6\n": ',',
+  } {
+    if got := csvFormatter.detect([]byte(input)); want != got {
+      t.Errorf("detect(%q) = %q, want %q", input, got, want)
+    }
+  }
+}
@@ -0,0 +1,551 @@
+package playground
+
+import (
+  "bytes"
+  "encoding/base64"
+  "errors"
+  "fmt"
+  "maps"
+  "mime/multipart"
+  "net/http"
+  "net/url"
+  "slices"
+  "strconv"
+  "strings"
+)
+
+var errNotCurl = errors.New("not a curl command")
+
+// curlShortOptions maps the short options of curl to their long names.
+var curlShortOptions = map[byte]string{
+  'A': "--user-agent",
+  'b': "--cookie",
+  'c': "--cookie-jar",
+  'C': "--continue-at",
+  'd': "--data",
+  'D': "--dump-header",
+  'e': "--referer",
+  'E': "--cert",
+  'f': "--fail",
+  'F': "--form",
+  'g': "--globoff",
+  'G': "--get",
+  'H': "--header",
+  'i': "--include",
+  'I': "--head",
+  'k': "--insecure",
+  'K': "--config",
+  'L': "--location",
+  'm': "--max-time",
+  'o': "--output",
+  'r': "--range",
+  's': "--silent",
+  'S': "--show-error",
+  'T': "--upload-file",
+  'u': "--user",
+  'U': "--proxy-user",
+  'v': "--verbose",
+  'w': "--write-out",
+  'x': "--proxy",
+  'X': "--request",
+  'y': "--speed-time",
+  'Y': "--speed-limit",
+  'z': "--time-cond",
+}
+
+// curlOptions tells whether every supported long option of curl takes an argument.
+var curlOptions = map[string]bool{
+  "--compressed":      false,
+  "--connect-timeout": true,
+  "--cookie":          true,
+  "--data":            true,
+  "--data-ascii":      true,
+  "--data-binary":     true,
+  "--data-raw":        true,
+  "--data-urlencode":  true,
+  "--fail":            false,
+  "--form":            true,
+  "--form-string":     true,
+  "--get":             false,
+  "--globoff":         false,
+  "--head":            false,
+  "--header":          true,
+  "--http1.1":         false,
+  "--include":         false,
+  "--insecure":        false,
+  "--location":        false,
+  "--max-time":        true,
+  "--output":          true,
+  "--referer":         true,
+  "--request":         true,
+  "--show-error":      false,
+  "--silent":          false,
+  "--url":             true,
+  "--user":            true,
+  "--user-agent":      true,
+  "--verbose":         false,
+}
+
+// curlIgnoredArgumentOptions lists the unsupported long options of curl that take an argument, so
+// that it can be skipped along with them; the other unsupported options are assumed to take none.
+var curlIgnoredArgumentOptions = map[string]bool{
+  "--aws-sigv4":       true,
+  "--cacert":          true,
+  "--capath":          true,
+  "--cert":            true,
+  "--cert-type":       true,
+  "--ciphers":         true,
+  "--config":          true,
+  "--connect-to":      true,
+  "--continue-at":     true,
+  "--cookie-jar":      true,
+  "--dns-servers":     true,
+  "--dump-header":     true,
+  "--interface":       true,
+  "--keepalive-time":  true,
+  "--key":             true,
+  "--key-type":        true,
+  "--limit-rate":      true,
+  "--max-filesize":    true,
+  "--max-redirs":      true,
+  "--netrc-file":      true,
+  "--oauth2-bearer":   true,
+  "--output-dir":      true,
+  "--pass":            true,
+  "--proxy":           true,
+  "--proxy-user":      true,
+  "--range":           true,
+  "--resolve":         true,
+  "--retry":           true,
+  "--retry-delay":     true,
+  "--retry-max-time":  true,
+  "--speed-limit":     true,
+  "--speed-time":      true,
+  "--stderr":          true,
+  "--time-cond":       true,
+  "--trace":           true,
+  "--trace-ascii":     true,
+  "--unix-socket":     true,
+  "--upload-file":     true,
+  "--write-out":       true,
+}
+
+// shellSplit splits a command line into words the way a POSIX shell does, honoring single, double
+// and ANSI-C ($'...') quotes, backslash escapes and line continuations.
+func shellSplit(command string) (words []string, err error) {
+  var (
+    word   strings.Builder
+    inWord bool
+    input  = []rune(command)
+  )
+
+  for n := 0; n < len(input); n++ {
+    switch c := input[n]; {
+    case ' ' == c || '\t' == c || '\n' == c || '\r' == c:
+      if inWord {
+        words = append(words, word.String())
+        word.Reset()
+        inWord = false
+      }
+    case '\\' == c:
+      n++
+      switch {
+      case n >= len(input):
+        return nil, errors.New("unterminated escape sequence")
+      case '\n' == input[n]: /* Line continuation.  */
+      case '\r' == input[n] && n+1 < len(input) && '\n' == input[n+1]:
+        n++
+      default:
+        word.WriteRune(input[n])
+        inWord = true
+      }
+    case '\'' == c:
+      end := slices.Index(input[n+1:], '\'')
+      if -1 == end {
+        return nil, errors.New("unterminated single quote")
+      }
+
+      word.WriteString(string(input[n+1 : n+1+end]))
+      inWord = true
+      n += 1 + end
+    case '"' == c:
+      inWord = true
+      for n++; ; n++ {
+        if n >= len(input) {
+          return nil, errors.New("unterminated double quote")
+        }
+
+        if '"' == input[n] {
+          break
+        }
+
+        if '\\' == input[n] && n+1 < len(input) && strings.ContainsRune("$`\"\\\n", input[n+1]) {
+          n++
+          if '\n' == input[n] {
+            continue
+          }
+        }
+
+        word.WriteRune(input[n])
+      }
+    case '$' == c && n+1 < len(input) && '\'' == input[n+1]:
+      inWord = true
+      for n += 2; ; n++ {
+        if n >= len(input) {
+          return nil, errors.New("unterminated ANSI-C quote")
+        }
+
+        if '\'' == input[n] {
+          break
+        }
+
+        if '\\' != input[n] || n+1 >= len(input) {
+          word.WriteRune(input[n])
+          continue
+        }
+
+        n++
+        switch e := input[n]; e {
+        case 'n':
+          word.WriteByte('\n')
+        case 't':
+          word.WriteByte('\t')
+        case 'r':
+          word.WriteByte('\r')
+        case 'x':
+          end := n + 1
+          for end < len(input) && end < n+3 && strings.ContainsRune("0123456789abcdefABCDEF", input[end]) {
+            end++
+          }
+
+          b, err := strconv.ParseUint(string(input[n+1:end]), 16, 8)
+          if nil != err {
+            return nil, fmt.Errorf("invalid escape sequence \\x%s", string(input[n+1:end]))
+          }
+
+          word.WriteByte(byte(b))
+          n = end - 1
+        default:
+          word.WriteRune(e)
+        }
+      }
+    default:
+      word.WriteRune(c)
+      inWord = true
+    }
+  }
+
+  if inWord {
+    words = append(words, word.String())
+  }
+
+  return words, nil
+}
+
+// curlOptionArguments expands the words of a curl command into (option, argument) pairs, where
+// the URLs are given as --url options. The unsupported options are skipped, along with their
+// argument, and reported as warnings.
+func curlOptionArguments(words []string) (pairs [][2]string, warnings []string, err error) {
+  for n := 0; n < len(words); n++ {
+    word := words[n]
+
+    switch {
+    case "--" == word:
+      for target := range slices.Values(words[n+1:]) {
+        pairs = append(pairs, [2]string{"--url", target})
+      }
+
+      return pairs, warnings, nil
+    case strings.HasPrefix(word, "--"):
+      argument, supported := curlOptions[word]
+      if !supported {
+        argument = curlIgnoredArgumentOptions[word]
+        warnings = append(warnings, fmt.Sprintf("the curl option %#q is not supported and was ignored", word))
+      }
+
+      if !argument {
+        if supported {
+          pairs = append(pairs, [2]string{word, ""})
+        }
+
+        continue
+      }
+
+      if n++; n >= len(words) {
+        return nil, nil, fmt.Errorf("curl option %#q requires an argument", word)
+      }
+
+      if supported {
+        pairs = append(pairs, [2]string{word, words[n]})
+      }
+    case strings.HasPrefix(word, "-") && len(word) > 1:
+      for i := 1; i < len(word); i++ {
+        option := curlShortOptions[word[i]]
+        argument, supported := curlOptions[option]
+        if !supported {
+          argument = curlIgnoredArgumentOptions[option]
+          warnings = append(warnings, fmt.Sprintf("the curl option `-%c` is not supported and was ignored", word[i]))
+        }
+
+        if !argument {
+          if supported {
+            pairs = append(pairs, [2]string{option, ""})
+          }
+
+          continue
+        }
+
+        if i+1 < len(word) { /* Attached argument, as in -XPOST.  */
+          if supported {
+            pairs = append(pairs, [2]string{option, word[i+1:]})
+          }
+
+          break
+        }
+
+        if n++; n >= len(words) {
+          return nil, nil, fmt.Errorf("curl option `-%c` requires an argument", word[i])
+        }
+
+        if supported {
+          pairs = append(pairs, [2]string{option, words[n]})
+        }
+      }
+    default:
+      pairs = append(pairs, [2]string{"--url", word})
+    }
+  }
+
+  return pairs, warnings, nil
+}
+
+// encodeCurlData encodes the argument of --data-urlencode as curl does.
+func encodeCurlData(argument string) (string, error) {
+  escape := func(s string) string { return strings.ReplaceAll(url.QueryEscape(s), "+", "%20") }
+
+  if n := strings.IndexAny(argument, "=@"); -1 != n {
+    if '@' == argument[n] {
+      return "", errors.New("reading data from files is not supported")
+    }
+
+    if 0 == n {
+      return escape(argument[1:]), nil
+    }
+
+    return fmt.Sprint(argument[:n], "=", escape(argument[n+1:])), nil
+  }
+
+  return escape(argument), nil
+}
+
+// parseCurl parses a curl command line into a request, along with warnings about the options that
+// were ignored.
+func parseCurl(command string) (req *request, warnings []string, err error) {
+  words, err := shellSplit(strings.TrimSpace(command))
+  if nil != err {
+    return nil, nil, err
+  }
+
+  if 0 == len(words) || "curl" != words[0] {
+    return nil, nil, errNotCurl
+  }
+
+  pairs, warnings, err := curlOptionArguments(words[1:])
+  if nil != err {
+    return nil, nil, err
+  }
+
+  req = &request{header: http.Header{}, pathVariables: map[string]string{}}
+
+  var (
+    target  string
+    data    []string
+    form    [][2]string
+    get     bool
+    head    bool
+    removed = map[string]bool{}
+  )
+
+  for pair := range slices.Values(pairs) {
+    switch option, argument := pair[0], pair[1]; option {
+    case "--url":
+      target = argument
+    case "--request":
+      req.method = strings.ToUpper(argument)
+    case "--head":
+      head = true
+    case "--header":
+      key, value, found := strings.Cut(argument, ":")
+      key, value = http.CanonicalHeaderKey(strings.TrimSuffix(strings.TrimSpace(key), ";")), strings.TrimSpace(value)
+
+      switch {
+      case !found && strings.HasSuffix(strings.TrimSpace(argument), ";"): /* "Name;" sends an empty header.  */
+        req.header.Add(key, "")
+      case found && "" == value: /* "Name:" removes a header curl would send.  */
+        req.header.Del(key)
+        removed[key] = true
+      case found && "" != key:
+        req.header.Add(key, value)
+      }
+    case "--data", "--data-ascii", "--data-binary":
+      if strings.HasPrefix(argument, "@") {
+        return nil, nil, errors.New("reading data from files is not supported")
+      }
+
+      data = append(data, argument)
+    case "--data-raw":
+      data = append(data, argument)
+    case "--data-urlencode":
+      encoded, err := encodeCurlData(argument)
+      if nil != err {
+        return nil, nil, err
+      }
+
+      data = append(data, encoded)
+    case "--form", "--form-string":
+      name, value, ok := strings.Cut(argument, "=")
+      if !ok {
+        return nil, nil, fmt.Errorf("invalid form field %#q", argument)
+      }
+
+      if "--form" == option && (strings.HasPrefix(value, "@") || strings.HasPrefix(value, "<")) {
+        return nil, nil, errors.New("reading form fields from files is not supported")
+      }
+
+      form = append(form, [2]string{name, value})
+    case "--user":
+      if !strings.Contains(argument, ":") {
+        argument = fmt.Sprint(argument, ":")
+      }
+
+      req.header.Set("Authorization", fmt.Sprint("Basic ", base64.StdEncoding.EncodeToString([]byte(argument))))
+    case "--cookie":
+      if !strings.Contains(argument, "=") {
+        return nil, nil, errors.New("reading cookies from files is not supported")
+      }
+
+      req.header.Add("Cookie", argument)
+    case "--compressed":
+      req.header.Set("Accept-Encoding", "deflate, gzip")
+    case "--insecure":
+      req.insecure = true
+    case "--get":
+      get = true
+    case "--user-agent":
+      req.header.Set("User-Agent", argument)
+    case "--referer":
+      req.header.Set("Referer", argument)
+    }
+  }
+
+  if "" == target {
+    return nil, nil, errors.New("no URL specified")
+  }
+
+  if !strings.Contains(target, "://") {
+    target = fmt.Sprint("http://", target)
+  }
+
+  switch {
+  case len(data) > 0 && len(form) > 0:
+    return nil, nil, errors.New("data and form fields cannot be combined")
+  case len(form) > 0:
+    var (
+      body   bytes.Buffer
+      writer = multipart.NewWriter(&body)
+    )
+
+    _ = writer.SetBoundary(strings.ReplaceAll(newString(), "-", ""))
+    for field := range slices.Values(form) {
+      _ = writer.WriteField(field[0], field[1])
+    }
+
+    _ = writer.Close()
+    req.body = body.String()
+
+    if "" == req.header.Get("Content-Type") {
+      req.header.Set("Content-Type", writer.FormDataContentType())
+    }
+  case len(data) > 0 && get:
+    separator := "?"
+    if strings.Contains(target, "?") {
+      separator = "&"
+    }
+
+    target = fmt.Sprint(target, separator, strings.Join(data, "&"))
+  case len(data) > 0:
+    req.body = strings.Join(data, "&")
+
+    if "" == req.header.Get("Content-Type") && !removed["Content-Type"] {
+      req.header.Set("Content-Type", "application/x-www-form-urlencoded")
+    }
+  }
+
+  if "" == req.method {
+    req.method = http.MethodGet
+    if head {
+      req.method = http.MethodHead
+    } else if "" != req.body {
+      req.method = http.MethodPost
+    }
+  }
+
+  if req.target, err = url.Parse(target); nil != err {
+    return nil, nil, fmt.Errorf("invalid URL %#q", target)
+  }
+
+  return req, warnings, nil
+}
+
+// shellQuote quotes s so that a POSIX shell reads it as a single word.
+func shellQuote(s string) string {
+  if "" != s && -1 == strings.IndexFunc(s, func(r rune) bool {
+    return !('a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' || '0' <= r && r <= '9' || strings.ContainsRune("_@%+=:,./-", r))
+  }) {
+    return s
+  }
+
+  return fmt.Sprint("'", strings.ReplaceAll(s, "'", `'\''`), "'")
+}
+
+// curlCommand returns a curl command line that sends exactly the request in.
+func curlCommand(in *request) string {
+  var (
+    command strings.Builder
+    target  = *in.target
+  )
+
+  substitutePathVariables(&target, in.pathVariables)
+  command.WriteString("curl")
+
+  if http.MethodGet != in.method || "" != in.body {
+    fmt.Fprint(&command, " --request ", in.method)
+  }
+
+  written := target.String()
+  if "" != in.rawTarget {
+    written = in.rawTarget
+  }
+
+  fmt.Fprint(&command, " \\\n  --url ", shellQuote(written))
+
+  for key := range slices.Values(slices.Sorted(maps.Keys(in.header))) {
+    for value := range slices.Values(in.header[key]) {
+      fmt.Fprint(&command, " \\\n  --header ", shellQuote(fmt.Sprint(key, ": ", value)))
+    }
+  }
+
+  if "" != in.body {
+    if _, exists := in.header["Content-Type"]; !exists { /* Otherwise curl sends a form content type.  */
+      command.WriteString(" \\\n  --header Content-Type:")
+    }
+
+    fmt.Fprint(&command, " \\\n  --data-raw ", shellQuote(in.body))
+  }
+
+  if in.insecure {
+    command.WriteString(" \\\n  --insecure")
+  }
+
+  return command.String()
+}
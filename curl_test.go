@@ -0,0 +1,194 @@
+package playground
+
+import (
+  "net/http"
+  "net/url"
+  "reflect"
+  "testing"
+)
+
+func TestShellSplit(t *testing.T) {
+  tests := []struct {
+    command string
+    want    []string
+  }{
+    {"", nil},
+    {"curl", []string{"curl"}},
+    {"  curl \t https://fontseca.dev  ", []string{"curl", "https://fontseca.dev"}},
+    {`curl 'a b' "c d"`, []string{"curl", "a b", "c d"}},
+    {`curl 'it'\''s'`, []string{"curl", "it's"}},
+    {`curl "say \"hi\" \$HOME \n"`, []string{"curl", `say "hi" $HOME \n`}},
+    {`curl $'line\none\ttab \'q\' \x41'`, []string{"curl", "line\none\ttab 'q' A"}},
+    {"curl \\\n  -H a \\\r\n  -H b", []string{"curl", "-H", "a", "-H", "b"}},
+    {`curl a\ b ''`, []string{"curl", "a b", ""}},
+    {`curl x"y"'z'`, []string{"curl", "xyz"}},
+  }
+
+  for _, test := range tests {
+    got, err := shellSplit(test.command)
+    if nil != err {
+      t.Errorf("shellSplit(%q) failed: %s", test.command, err)
+      continue
+    }
+
+    if !reflect.DeepEqual(test.want, got) {
+      t.Errorf("shellSplit(%q) = %q, want %q", test.command, got, test.want)
+    }
+  }
+
+  for _, command := range []string{`curl 'a`, `curl "a`, `curl $'a`, `curl a\`} {
+    if _, err := shellSplit(command); nil == err {
+      t.Errorf("shellSplit(%q) must fail", command)
+    }
+  }
+}
+
+func TestParseCurl(t *testing.T) {
+  newString = func() string { return "714b9856-cac2-4a77-a149-ca1a797918cb" }
+
+  tests := []struct {
+    command  string
+    want     *request
+    warnings []string
+  }{
+    {
+      `curl fontseca.dev`,
+      &request{method: "GET", target: &url.URL{Scheme: "http", Host: "fontseca.dev"}, header: http.Header{}},
+      nil,
+    },
+    {
+      `curl -X put 'https://fontseca.dev/users/:id?a=1' -H 'Content-Type: application/json' -H "x-empty;" -H 'Accept:' --data-raw '{"name": "Jane"}' -k`,
+      &request{
+        method:   "PUT",
+        target:   &url.URL{Scheme: "https", Host: "fontseca.dev", Path: "/users/:id", RawQuery: "a=1"},
+        header:   http.Header{"Content-Type": {"application/json"}, "X-Empty": {""}},
+        body:     `{"name": "Jane"}`,
+        insecure: true,
+      },
+      nil,
+    },
+    {
+      `curl https://fontseca.dev -d a=1 --data b=2 --data-urlencode 'q=x y&z' --data-urlencode '=ñ' -u jane:secret --compressed -b 'session=abc'`,
+      &request{
+        method: "POST",
+        target: &url.URL{Scheme: "https", Host: "fontseca.dev"},
+        header: http.Header{
+          "Accept-Encoding": {"deflate, gzip"},
+          "Authorization":   {"Basic amFuZTpzZWNyZXQ="},
+          "Content-Type":    {"application/x-www-form-urlencoded"},
+          "Cookie":          {"session=abc"},
+        },
+        body: "a=1&b=2&q=x%20y%26z&%C3%B1",
+      },
+      nil,
+    },
+    {
+      `curl -sSLG https://fontseca.dev/search?page=2 -d q=go -A agent/1.0`,
+      &request{
+        method: "GET",
+        target: &url.URL{Scheme: "https", Host: "fontseca.dev", Path: "/search", RawQuery: "page=2&q=go"},
+        header: http.Header{"User-Agent": {"agent/1.0"}},
+      },
+      nil,
+    },
+    {
+      `curl -XPOST https://fontseca.dev/upload -F name=Jane --form-string 'note=@not-a-file'`,
+      &request{
+        method: "POST",
+        target: &url.URL{Scheme: "https", Host: "fontseca.dev", Path: "/upload"},
+        header: http.Header{"Content-Type": {"multipart/form-data; boundary=714b9856cac24a77a149ca1a797918cb"}},
+        body: "--714b9856cac24a77a149ca1a797918cb\r\n" +
+          "Content-Disposition: form-data; name=\"name\"\r\n\r\nJane\r\n" +
+          "--714b9856cac24a77a149ca1a797918cb\r\n" +
+          "Content-Disposition: form-data; name=\"note\"\r\n\r\n@not-a-file\r\n" +
+          "--714b9856cac24a77a149ca1a797918cb--\r\n",
+      },
+      nil,
+    },
+    {
+      `curl -sI --http2 -x http://proxy:8080 --location-trusted --compressed-ssh https://fontseca.dev -4# --retry 3`,
+      &request{method: "HEAD", target: &url.URL{Scheme: "https", Host: "fontseca.dev"}, header: http.Header{}},
+      []string{
+        "the curl option `--http2` is not supported and was ignored",
+        "the curl option `-x` is not supported and was ignored",
+        "the curl option `--location-trusted` is not supported and was ignored",
+        "the curl option `--compressed-ssh` is not supported and was ignored",
+        "the curl option `-4` is not supported and was ignored",
+        "the curl option `-#` is not supported and was ignored",
+        "the curl option `--retry` is not supported and was ignored",
+      },
+    },
+  }
+
+  for _, test := range tests {
+    got, warnings, err := parseCurl(test.command)
+    if nil != err {
+      t.Errorf("parseCurl(%q) failed: %s", test.command, err)
+      continue
+    }
+
+    test.want.pathVariables = map[string]string{}
+    if !reflect.DeepEqual(test.want, got) {
+      t.Errorf("parseCurl(%q) = %+v, want %+v", test.command, got, test.want)
+    }
+
+    if !reflect.DeepEqual(test.warnings, warnings) {
+      t.Errorf("parseCurl(%q) warned %q, want %q", test.command, warnings, test.warnings)
+    }
+  }
+
+  for _, command := range []string{
+    "wget https://fontseca.dev",
+    "curl",
+    "curl -d @body.json https://fontseca.dev",
+    "curl -F file=@photo.png https://fontseca.dev",
+    "curl -b cookies.txt https://fontseca.dev",
+    "curl --data-urlencode name@file https://fontseca.dev",
+    "curl -d a=1 -F b=2 https://fontseca.dev",
+    "curl https://fontseca.dev -H",
+    "curl https://fontseca.dev -x",
+  } {
+    if _, _, err := parseCurl(command); nil == err {
+      t.Errorf("parseCurl(%q) must fail", command)
+    }
+  }
+}
+
+func TestCurlCommand(t *testing.T) {
+  target, _ := url.Parse("https://fontseca.dev/users/:id?q=it's")
+  in := &request{
+    method:        http.MethodPatch,
+    target:        target,
+    header:        http.Header{"X-Note": {"it's \"quoted\""}, "Accept": {"application/json", "text/plain"}},
+    body:          `{"name": "O'Hara", "home": "$HOME"}`,
+    pathVariables: map[string]string{"id": "5"},
+    insecure:      true,
+  }
+
+  want := `curl --request PATCH \
+  --url 'https://fontseca.dev/users/5?q=it'\''s' \
+  --header 'Accept: application/json' \
+  --header 'Accept: text/plain' \
+  --header 'X-Note: it'\''s "quoted"' \
+  --header Content-Type: \
+  --data-raw '{"name": "O'\''Hara", "home": "$HOME"}' \
+  --insecure`
+
+  if got := curlCommand(in); want != got {
+    t.Fatalf("curlCommand(...) = %s\nwant %s", got, want)
+  }
+
+  target, _ = url.Parse("https://fontseca.dev/users")
+  if want, got := "curl \\\n  --url https://fontseca.dev/users", curlCommand(&request{method: http.MethodGet, target: target}); want != got {
+    t.Errorf("curlCommand(...) = %q, want %q", got, want)
+  }
+
+  roundtrip, _, err := parseCurl(curlCommand(in))
+  if nil != err {
+    t.Fatalf("parseCurl(curlCommand(...)) failed: %s", err)
+  }
+
+  if in.body != roundtrip.body || !reflect.DeepEqual(in.header, roundtrip.header) || "https://fontseca.dev/users/5?q=it's" != roundtrip.target.String() {
+    t.Errorf("parseCurl(curlCommand(...)) = %+v, want %+v", roundtrip, in)
+  }
+}
@@ -0,0 +1,133 @@
+package playground
+
+import (
+  "bytes"
+  "encoding/binary"
+  "encoding/hex"
+  "fmt"
+  "io"
+)
+
+const (
+  maxDecodingDepth = 512     // The number of arrays, maps and messages a binary body can nest.
+  maxHexDumpBytes  = 4 << 10 // The number of bytes of a body that cannot be decoded that are dumped.
+)
+
+// binaryFormatterImpl decodes a binary body, such as a MessagePack one, into JSON trees, which it writes
+// the way jsonFormatterImpl does.
+type binaryFormatterImpl struct {
+  encoding string                                          // The name of the encoding, such as CBOR.
+  decode   func(input []byte) ([]*jsonNode, []string, error) // Returns the documents of input, along with warnings.
+  json     jsonFormatterImpl                               // How the documents are written.
+}
+
+// format implements a formatter of binary bodies.
+func (f binaryFormatterImpl) format(input []byte, output io.Writer, indent string) {
+  formatTokens(f, input, output, indent)
+}
+
+// tokens writes the documents that input decodes to, one after the other. A body that cannot be
+// decoded is written as a hex dump, and check tells why.
+func (f binaryFormatterImpl) tokens(input []byte, output tokenWriter, indent string) {
+  if 0 == len(input) {
+    return
+  }
+
+  documents, _, err := f.decode(input)
+  if nil != err {
+    output.token(tokenText, bytes.TrimSuffix([]byte(hex.Dump(input[:min(len(input), maxHexDumpBytes)])), []byte{'\n'}))
+    if len(input) > maxHexDumpBytes {
+      output.token(tokenComment, []byte(fmt.Sprintf("\n... %d more bytes", len(input)-maxHexDumpBytes)))
+    }
+
+    return
+  }
+
+  for n, document := range documents {
+    if n > 0 {
+      output.token(tokenText, []byte{'\n'})
+    }
+
+    f.json.write(document, output, indent, 0)
+  }
+}
+
+// check reports why a body cannot be decoded, and what the decoder warned about.
+func (f binaryFormatterImpl) check(input []byte) []string {
+  if 0 == len(input) {
+    return nil
+  }
+
+  _, warnings, err := f.decode(input)
+  if nil != err {
+    return append(warnings, fmt.Sprintf("invalid %s: %s", f.encoding, err.Error()))
+  }
+
+  return warnings
+}
+
+// A binaryReader reads the big-endian values of a binary body.
+type binaryReader struct {
+  input []byte
+  n     int
+  depth int // The number of containers the value at n is nested in.
+}
+
+// take returns the next size bytes.
+func (r *binaryReader) take(size uint64) ([]byte, error) {
+  if size > uint64(len(r.input)-r.n) {
+    return nil, fmt.Errorf("unexpected end of input at offset %d", len(r.input))
+  }
+
+  start := r.n
+  r.n += int(size)
+  return r.input[start:r.n], nil
+}
+
+// uint reads an unsigned integer of size bytes, which is 1, 2, 4 or 8.
+func (r *binaryReader) uint(size int) (uint64, error) {
+  data, err := r.take(uint64(size))
+  if nil != err {
+    return 0, err
+  }
+
+  switch size {
+  case 1:
+    return uint64(data[0]), nil
+  case 2:
+    return uint64(binary.BigEndian.Uint16(data)), nil
+  case 4:
+    return uint64(binary.BigEndian.Uint32(data)), nil
+  }
+
+  return binary.BigEndian.Uint64(data), nil
+}
+
+// enter starts reading a container, and fails if too many are nested.
+func (r *binaryReader) enter() error {
+  if r.depth++; r.depth > maxDecodingDepth {
+    return fmt.Errorf("more than %d levels of nesting at offset %d", maxDecodingDepth, r.n)
+  }
+
+  return nil
+}
+
+// leave ends reading a container.
+func (r *binaryReader) leave() {
+  r.depth--
+}
+
+// documents reads the values of input one after the other with value.
+func (r *binaryReader) documents(value func() (*jsonNode, error)) ([]*jsonNode, error) {
+  var documents []*jsonNode
+  for r.n < len(r.input) {
+    document, err := value()
+    if nil != err {
+      return nil, err
+    }
+
+    documents = append(documents, document)
+  }
+
+  return documents, nil
+}
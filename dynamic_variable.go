@@ -0,0 +1,143 @@
+package playground
+
+import (
+  crand "crypto/rand"
+  "fmt"
+  "github.com/google/uuid"
+  "math/rand/v2"
+  "strconv"
+  "strings"
+  "time"
+)
+
+// A dynamicGenerator produces the values of Postman dynamic variables, such as {{$guid}} or
+// {{$randomInt}}. Every reference gets a fresh value, as it does in Postman.
+type dynamicGenerator struct {
+  source *rand.ChaCha8
+  rand   *rand.Rand
+  now    func() time.Time
+}
+
+// newDynamicGenerator returns a generator seeded from crypto/rand, but it is replaced by a function
+// that returns a generator with a fixed seed and clock in testing, so that deterministic behaviour
+// is assured.
+var newDynamicGenerator = func() *dynamicGenerator {
+  var seed [32]byte
+  _, _ = crand.Read(seed[:])
+  return newSeededDynamicGenerator(seed, time.Now)
+}
+
+// newSeededDynamicGenerator returns a generator whose values depend only on seed and now.
+func newSeededDynamicGenerator(seed [32]byte, now func() time.Time) *dynamicGenerator {
+  source := rand.NewChaCha8(seed)
+  return &dynamicGenerator{
+    source: source,
+    rand:   rand.New(source),
+    now:    now,
+  }
+}
+
+var (
+  dynamicFirstNames = []string{"Ada", "Alan", "Barbara", "Dennis", "Edsger", "Frances", "Grace", "John", "Ken", "Margaret", "Niklaus", "Radia"}
+  dynamicLastNames  = []string{"Allen", "Dijkstra", "Hamilton", "Hopper", "Kernighan", "Liskov", "Lovelace", "McCarthy", "Perlman", "Ritchie", "Turing", "Wirth"}
+  dynamicWords      = []string{"array", "bandwidth", "cache", "driver", "firewall", "kernel", "matrix", "monitor", "pixel", "protocol", "sensor", "system"}
+  dynamicCities     = []string{"Amsterdam", "Buenos Aires", "Cairo", "Kyoto", "Lagos", "Lima", "Managua", "Oslo", "Seoul", "Toronto"}
+  dynamicCountries  = []string{"Argentina", "Canada", "Egypt", "Japan", "Netherlands", "Nicaragua", "Nigeria", "Norway", "Peru", "South Korea"}
+  dynamicCodes      = []string{"AR", "CA", "EG", "JP", "NL", "NI", "NG", "NO", "PE", "KR"}
+  dynamicColors     = []string{"black", "blue", "cyan", "gold", "green", "indigo", "magenta", "orange", "purple", "red", "teal", "white"}
+  dynamicTLDs       = []string{"com", "dev", "info", "io", "net", "org"}
+)
+
+const dynamicAlphaNumeric = "abcdefghijklmnopqrstuvwxyz0123456789"
+
+// dynamicVariables maps the name of every supported dynamic variable to the function producing its values.
+var dynamicVariables = map[string]func(g *dynamicGenerator) string{
+  "$guid":               (*dynamicGenerator).uuid,
+  "$randomUUID":         (*dynamicGenerator).uuid,
+  "$timestamp":          func(g *dynamicGenerator) string { return strconv.FormatInt(g.now().Unix(), 10) },
+  "$isoTimestamp":       func(g *dynamicGenerator) string { return g.now().UTC().Format("2006-01-02T15:04:05.000Z") },
+  "$randomInt":          func(g *dynamicGenerator) string { return strconv.Itoa(g.rand.IntN(1001)) },
+  "$randomBoolean":      func(g *dynamicGenerator) string { return strconv.FormatBool(0 == g.rand.IntN(2)) },
+  "$randomAlphaNumeric": func(g *dynamicGenerator) string { return g.alphaNumeric(1) },
+  "$randomPassword":     func(g *dynamicGenerator) string { return g.alphaNumeric(15) },
+  "$randomHexColor":     func(g *dynamicGenerator) string { return fmt.Sprintf("#%06x", g.rand.IntN(0x1000000)) },
+  "$randomColor":        func(g *dynamicGenerator) string { return g.pick(dynamicColors) },
+  "$randomIP": func(g *dynamicGenerator) string {
+    return fmt.Sprintf("%d.%d.%d.%d", g.rand.IntN(256), g.rand.IntN(256), g.rand.IntN(256), g.rand.IntN(256))
+  },
+  "$randomIPV6": func(g *dynamicGenerator) string {
+    groups := make([]string, 8)
+    for n := range groups {
+      groups[n] = strconv.FormatInt(int64(g.rand.IntN(0x10000)), 16)
+    }
+    return strings.Join(groups, ":")
+  },
+  "$randomMACAddress": func(g *dynamicGenerator) string {
+    octets := make([]string, 6)
+    for n := range octets {
+      octets[n] = fmt.Sprintf("%02x", g.rand.IntN(256))
+    }
+    return strings.Join(octets, ":")
+  },
+  "$randomFirstName": func(g *dynamicGenerator) string { return g.pick(dynamicFirstNames) },
+  "$randomLastName":  func(g *dynamicGenerator) string { return g.pick(dynamicLastNames) },
+  "$randomFullName":  func(g *dynamicGenerator) string { return fmt.Sprint(g.pick(dynamicFirstNames), " ", g.pick(dynamicLastNames)) },
+  "$randomUserName":  func(g *dynamicGenerator) string { return fmt.Sprint(g.pick(dynamicFirstNames), ".", g.pick(dynamicLastNames), g.rand.IntN(100)) },
+  "$randomEmail": func(g *dynamicGenerator) string {
+    return strings.ToLower(fmt.Sprint(g.pick(dynamicFirstNames), ".", g.pick(dynamicLastNames), "@", g.domainName()))
+  },
+  "$randomExampleEmail": func(g *dynamicGenerator) string {
+    return strings.ToLower(fmt.Sprint(g.pick(dynamicFirstNames), ".", g.pick(dynamicLastNames), "@example.com"))
+  },
+  "$randomPhoneNumber": func(g *dynamicGenerator) string {
+    return fmt.Sprintf("%03d-%03d-%04d", 200+g.rand.IntN(800), g.rand.IntN(1000), g.rand.IntN(10000))
+  },
+  "$randomWord":        func(g *dynamicGenerator) string { return g.pick(dynamicWords) },
+  "$randomWords":       func(g *dynamicGenerator) string { return fmt.Sprint(g.pick(dynamicWords), " ", g.pick(dynamicWords), " ", g.pick(dynamicWords)) },
+  "$randomCity":        func(g *dynamicGenerator) string { return g.pick(dynamicCities) },
+  "$randomCountry":     func(g *dynamicGenerator) string { return g.pick(dynamicCountries) },
+  "$randomCountryCode": func(g *dynamicGenerator) string { return g.pick(dynamicCodes) },
+  "$randomDomainName":  (*dynamicGenerator).domainName,
+  "$randomUrl":         func(g *dynamicGenerator) string { return fmt.Sprint("https://", g.domainName()) },
+  "$randomPrice":       func(g *dynamicGenerator) string { return fmt.Sprintf("%d.%02d", g.rand.IntN(1000), g.rand.IntN(100)) },
+}
+
+// uuid returns a version 4 UUID read from the random source of g.
+func (g *dynamicGenerator) uuid() string {
+  id, err := uuid.NewRandomFromReader(g.source)
+  if nil != err {
+    return uuid.NewString()
+  }
+
+  return id.String()
+}
+
+// pick returns a random element of words.
+func (g *dynamicGenerator) pick(words []string) string {
+  return words[g.rand.IntN(len(words))]
+}
+
+// alphaNumeric returns n random lowercase letters and digits.
+func (g *dynamicGenerator) alphaNumeric(n int) string {
+  b := make([]byte, n)
+  for i := range b {
+    b[i] = dynamicAlphaNumeric[g.rand.IntN(len(dynamicAlphaNumeric))]
+  }
+
+  return string(b)
+}
+
+// domainName returns a random domain name such as kernel.dev.
+func (g *dynamicGenerator) domainName() string {
+  return fmt.Sprint(g.pick(dynamicWords), ".", g.pick(dynamicTLDs))
+}
+
+// generate returns a value for the dynamic variable name, reporting whether name is supported.
+func (g *dynamicGenerator) generate(name string) (value string, ok bool) {
+  generator, ok := dynamicVariables[name]
+  if !ok {
+    return "", false
+  }
+
+  return generator(g), true
+}
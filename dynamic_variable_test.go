@@ -0,0 +1,94 @@
+package playground
+
+import (
+  "maps"
+  "regexp"
+  "slices"
+  "testing"
+  "time"
+)
+
+func TestDynamicGenerator_generate(t *testing.T) {
+  now := func() time.Time { return time.Date(2024, time.October, 20, 8, 30, 15, 250_000_000, time.UTC) }
+
+  tests := map[string]string{
+    "$guid":               `^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`,
+    "$randomUUID":         `^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`,
+    "$timestamp":          `^1729413015$`,
+    "$isoTimestamp":       `^2024-10-20T08:30:15\.250Z$`,
+    "$randomInt":          `^([0-9]|[1-9][0-9]{1,2}|1000)$`,
+    "$randomBoolean":      `^(true|false)$`,
+    "$randomAlphaNumeric": `^[a-z0-9]$`,
+    "$randomPassword":     `^[a-z0-9]{15}$`,
+    "$randomHexColor":     `^#[0-9a-f]{6}$`,
+    "$randomColor":        `^[a-z]+$`,
+    "$randomIP":           `^(\d{1,3}\.){3}\d{1,3}$`,
+    "$randomIPV6":         `^([0-9a-f]{1,4}:){7}[0-9a-f]{1,4}$`,
+    "$randomMACAddress":   `^([0-9a-f]{2}:){5}[0-9a-f]{2}$`,
+    "$randomFirstName":    `^[A-Z][a-z]+$`,
+    "$randomLastName":     `^[A-Z][A-Za-z]+$`,
+    "$randomFullName":     `^[A-Z][a-z]+ [A-Z][A-Za-z]+$`,
+    "$randomUserName":     `^[A-Z][a-z]+\.[A-Z][A-Za-z]+\d{1,2}$`,
+    "$randomEmail":        `^[a-z]+\.[a-z]+@[a-z]+\.[a-z]+$`,
+    "$randomExampleEmail": `^[a-z]+\.[a-z]+@example\.com$`,
+    "$randomPhoneNumber":  `^\d{3}-\d{3}-\d{4}$`,
+    "$randomWord":         `^[a-z]+$`,
+    "$randomWords":        `^[a-z]+ [a-z]+ [a-z]+$`,
+    "$randomCity":         `^[A-Z][a-z]+( [A-Z][a-z]+)?$`,
+    "$randomCountry":      `^[A-Z][a-z]+( [A-Z][a-z]+)?$`,
+    "$randomCountryCode":  `^[A-Z]{2}$`,
+    "$randomDomainName":   `^[a-z]+\.[a-z]+$`,
+    "$randomUrl":          `^https://[a-z]+\.[a-z]+$`,
+    "$randomPrice":        `^\d{1,3}\.\d{2}$`,
+  }
+
+  if !slices.Equal(slices.Sorted(maps.Keys(tests)), slices.Sorted(maps.Keys(dynamicVariables))) {
+    t.Fatalf("every dynamic variable must be tested")
+  }
+
+  var (
+    g1 = newSeededDynamicGenerator([32]byte{1, 2, 3}, now)
+    g2 = newSeededDynamicGenerator([32]byte{1, 2, 3}, now)
+  )
+
+  for name := range slices.Values(slices.Sorted(maps.Keys(tests))) {
+    for range 20 {
+      v1, ok := g1.generate(name)
+      if !ok {
+        t.Fatalf("generate(%q) is not supported", name)
+      }
+
+      if v2, _ := g2.generate(name); v1 != v2 {
+        t.Errorf("generate(%q) is not deterministic: %q != %q", name, v1, v2)
+      }
+
+      if !regexp.MustCompile(tests[name]).MatchString(v1) {
+        t.Errorf("generate(%q) = %q, want match for %#q", name, v1, tests[name])
+      }
+    }
+  }
+
+  if _, ok := g1.generate("$unknown"); ok {
+    t.Errorf("generate(%q) must not be supported", "$unknown")
+  }
+}
+
+func TestResolver_resolveDynamic(t *testing.T) {
+  res := newResolver(map[string]collVariable{"id": {Key: "id", Value: "{{$randomInt}}"}})
+  res.dynamic = newSeededDynamicGenerator([32]byte{}, time.Now)
+
+  got := res.resolve("{{$guid}} {{$guid}} {{id}}")
+  matches := regexp.MustCompile(`^(\S{36}) (\S{36}) \d+$`).FindStringSubmatch(got)
+
+  if nil == matches {
+    t.Fatalf("resolve(...) = %q, want two UUIDs and a number", got)
+  }
+
+  if matches[1] == matches[2] {
+    t.Errorf("every reference must get a fresh value, got %q twice", matches[1])
+  }
+
+  if 0 != len(res.warnings) {
+    t.Errorf("unexpected warnings: %q", res.warnings)
+  }
+}
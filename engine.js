@@ -58,17 +58,163 @@ const collectionFolders = collectionExplorer.querySelectorAll(".item.folder");
 const collectionRequests = collectionExplorer.querySelectorAll(".playground-collection-container .content .item:not(.folder)");
 const btnToggleCollection = collectionExplorer.querySelector("button.collection-files-toggle");
 const btnImportCollection = collectionExplorer.querySelector("button.collection-files-import");
+const btnExportArchive = collectionExplorer.querySelector("button.collection-files-export");
+const btnSaveCollection = collectionExplorer.querySelector("button.collection-files-save");
 const dialogImportCollection = document.querySelector(".import-collection-dialog");
 const dialogImportCollectionCloser = dialogImportCollection.querySelector(".closer");
 const requestForm = document.getElementById("http-request-form");
+const requestId = document.getElementById("http-request-id");
 const requestBody = document.getElementById("http-request-body");
+const requestChecks = document.getElementById("http-request-checks");
+const requestExtract = document.getElementById("http-request-extract");
 const inputCollUpload = document.getElementById("coll");
 const btnCollUpload = document.getElementById("btn-coll-upload");
+const btnCollConvert = document.getElementById("btn-coll-convert");
 
 btnToggleCollection.onclick = () => {
   collectionExplorer.classList.toggle("open");
 };
 
+document.getElementById("http-request-snippet-picker").onchange = async (ev) => {
+  const picker = ev.target;
+  const language = picker.value;
+  const name = picker.options[picker.selectedIndex].textContent;
+  picker.selectedIndex = 0;
+
+  if ("" === language) {
+    return;
+  }
+
+  const response = await fetch(`playground.snippet?language=${encodeURIComponent(language)}`, {
+    method: "POST",
+    body: new URLSearchParams(new FormData(requestForm)),
+  });
+
+  const code = await response.text();
+
+  if (!response.ok) {
+    ShowNotes([code]);
+    return;
+  }
+
+  document.getElementById("http-response-body").textContent = code;
+
+  try {
+    await navigator.clipboard.writeText(code);
+    ShowNotes([`The ${name} code was copied to your clipboard.`]);
+  } catch (e) {
+    ShowNotes([]);
+  }
+};
+
+requestTarget.addEventListener("paste", async (ev) => {
+  const pasted = (ev.clipboardData || window.clipboardData).getData("text").trim();
+
+  if (!pasted.startsWith("curl ")) {
+    return;
+  }
+
+  ev.preventDefault();
+  const response = await fetch("playground.curl-import", {method: "POST", body: new URLSearchParams({curl: pasted})});
+
+  if (!response.ok) {
+    ShowNotes([await response.text()]);
+    return;
+  }
+
+  const imported = await response.json();
+  ShowNotes(imported.warnings);
+
+  requestTarget.value = imported.target;
+  methodPicker.selectedIndex = [].slice.call(methodPicker.options).findIndex(element => element.value === imported.method);
+
+  GetHeadersTable().innerHTML = "";
+  for (const header of imported.header) {
+    AppendHeaderRow(header.key, header.value);
+  }
+
+  AppendHeaderRow("", "");
+
+  requestBody.value = imported.body;
+  document.getElementById("http-request-insecure").checked = imported.insecure;
+
+  ParseQueryParametersFromRequestBar();
+  ParsePathVariablesFromRequestBar();
+});
+
+btnExportArchive.onclick = () => {
+  window.location.href = "/playground.har";
+};
+
+/* The snapshots of the request form of the collection requests that were edited, by item ID.  */
+const edits = {};
+let requestEdited = false;
+
+document.addEventListener("input", ev => {
+  if (requestForm === ev.target.form) {
+    requestEdited = true;
+  }
+});
+
+function SnapshotSelectedRequest() {
+  const selected = document.querySelector(".playground-collection-container .content .item:not(.folder).selected");
+
+  if (selected && requestEdited) {
+    edits[selected.querySelector(".name").getAttribute("data-id")] = new URLSearchParams(new FormData(requestForm)).toString();
+  }
+}
+
+function RestoreRequestForm(form) {
+  requestTarget.value = form.get("request_target");
+  methodPicker.selectedIndex = [].slice.call(methodPicker.options).findIndex(element => element.value === form.get("request_method"));
+
+  const keys = form.getAll("header-key");
+  const values = form.getAll("header-value");
+  GetHeadersTable().innerHTML = "";
+  keys.forEach((key, i) => {
+    if ("" !== key) {
+      AppendHeaderRow(key, values[i] ?? "");
+    }
+  });
+
+  AppendHeaderRow("", "");
+
+  const variableKeys = form.getAll("path-variable-key");
+  const variableValues = form.getAll("path-variable-value");
+  GetPathVariablesTable().innerHTML = "";
+  variableKeys.forEach((key, i) => AppendPathVariableRow(key, variableValues[i] ?? ""));
+
+  requestBody.value = form.get("http-request-body") ?? "";
+  requestChecks.value = form.get("checks") ?? "";
+  requestExtract.value = form.get("extract") ?? "";
+  ParseQueryParametersFromRequestBar();
+  ParsePathVariablesFromRequestBar();
+}
+
+if (btnSaveCollection) {
+  btnSaveCollection.onclick = async () => {
+    SnapshotSelectedRequest();
+
+    const response = await fetch("/playground.postman", {
+      method: "POST",
+      headers: {"Content-Type": "application/json"},
+      body: JSON.stringify(edits),
+    });
+
+    if (!response.ok) {
+      ShowNotes([await response.text()]);
+      return;
+    }
+
+    const disposition = /filename="(.+)"/.exec(response.headers.get("Content-Disposition") ?? "");
+    const link = document.createElement("a");
+    link.href = URL.createObjectURL(await response.blob());
+    link.download = disposition ? disposition[1] : "collection.postman_collection.json";
+    link.click();
+    URL.revokeObjectURL(link.href);
+  };
+}
+
 btnImportCollection.onclick = () => {
   dialogImportCollection.showModal();
 };
@@ -90,11 +236,11 @@ inputCollUpload.onchange = ev => {
     const coll = ev.target.files[0];
     const lblErrMsg = document.getElementById("coll-error-msg");
 
-    if (!coll.name.endsWith(".json")) {
+    if (![".json", ".har", ".yaml", ".yml", ".http", ".rest", ".bru", ".zip"].some(extension => coll.name.endsWith(extension))) {
       ev.target.value = "";
-      lblErrMsg.textContent = "Only `.json` files are accepted.";
+      lblErrMsg.textContent = "Only `.json`, `.har`, `.yaml`, `.yml`, `.http`, `.rest`, `.bru` and `.zip` files are accepted.";
       lblErrMsg.style.display = "block";
-      btnCollUpload.disabled = true;
+      btnCollUpload.disabled = btnCollConvert.disabled = true;
       return;
     }
 
@@ -102,24 +248,46 @@ inputCollUpload.onchange = ev => {
       ev.target.value = "";
       lblErrMsg.textContent = "File size must be less than 1 MB.";
       lblErrMsg.style.display = "block";
-      btnCollUpload.disabled = true;
+      btnCollUpload.disabled = btnCollConvert.disabled = true;
       return;
     }
 
     lblErrMsg.style.display = "none";
     lblErrMsg.textContent = "";
-    btnCollUpload.disabled = false;
+    btnCollUpload.disabled = btnCollConvert.disabled = false;
   } else {
-    btnCollUpload.disabled = true;
+    btnCollUpload.disabled = btnCollConvert.disabled = true;
   }
 };
 
+document.getElementById("http-response-protobuf-file").onchange = ev => {
+  const descriptorSet = document.getElementById("http-response-protobuf-descriptor-set");
+  const lblErrMsg = document.getElementById("http-response-protobuf-error");
+  descriptorSet.value = lblErrMsg.textContent = "";
+  if (1 !== ev.target.files.length) {
+    return;
+  }
+
+  if (MAX_FILE_SIZE < ev.target.files[0].size) {
+    ev.target.value = "";
+    lblErrMsg.textContent = "File size must be less than 1 MB.";
+    return;
+  }
+
+  const reader = new FileReader();
+  reader.onload = () => {
+    descriptorSet.value = reader.result.substring(reader.result.indexOf(",") + 1);
+  };
+  reader.readAsDataURL(ev.target.files[0]);
+};
+
 requestForm.onsubmit = (ev) => {
   if (!ev.target.children[1].checkValidity()) {
     return
   }
 
   document.getElementById("http-response-body").innerHTML = "";
+  ShowNotes([]);
   document.getElementById("centered-label-response-body").classList.add("disable");
   const responseStatus = document.getElementById("response-status");
   const responseStats = document.getElementById("response-stats");
@@ -141,6 +309,7 @@ collectionRequests.forEach(request => {
       if (id === selected.querySelector(".name").getAttribute("data-id")) { /* Clicking same request.  */
         return;
       }
+      SnapshotSelectedRequest();
       selected.classList.remove("selected");
     } catch (e) {
     }
@@ -148,6 +317,7 @@ collectionRequests.forEach(request => {
     ResetResponse();
 
     request.classList.add("selected");
+    requestId.value = id;
 
     const selectedRequestFromCollection = requests.find(element => element["id"] === id);
 
@@ -159,7 +329,17 @@ collectionRequests.forEach(request => {
     }
 
     document.querySelector(".playground-content .canvas header.request-name").innerHTML = nestedHTML;
+    requestEdited = id in edits;
+    ShowNotes(selectedRequestFromCollection["warnings"] ?? []);
+
+    if (requestEdited) { /* Coming back to an edited request.  */
+      RestoreRequestForm(new URLSearchParams(edits[id]));
+      return;
+    }
+
     requestTarget.value = selectedRequestFromCollection["url_resolved"];
+    requestChecks.value = selectedRequestFromCollection["request_checks"] ?? "";
+    requestExtract.value = selectedRequestFromCollection["request_extract"] ?? "";
 
     const options = [].slice.call(methodPicker.options);
     methodPicker.selectedIndex = options.findIndex(element => element.value === selectedRequestFromCollection["request_method"]);
@@ -183,6 +363,14 @@ collectionRequests.forEach(request => {
 
     AppendQueryParameterRow("", "");
 
+    GetPathVariablesTable().innerHTML = "";
+
+    for (const variable of selectedRequestFromCollection["url_variable"] ?? []) {
+      AppendPathVariableRow(variable.key, variable.value);
+    }
+
+    ParsePathVariablesFromRequestBar();
+
     if ("POST" !== selectedRequestFromCollection["request_method"]
       && "PUT" !== selectedRequestFromCollection["request_method"]
       && "PATCH" !== selectedRequestFromCollection["request_method"]) {
@@ -237,6 +425,7 @@ document.addEventListener("DOMContentLoaded", function () {
 
   if (requestTarget) {
     requestTarget.addEventListener("keyup", ParseQueryParametersFromRequestBar);
+    requestTarget.addEventListener("keyup", ParsePathVariablesFromRequestBar);
   }
 
   if (!alreadyLoaded) {
@@ -244,6 +433,7 @@ document.addEventListener("DOMContentLoaded", function () {
   }
 
   ParseQueryParametersFromRequestBar();
+  ParsePathVariablesFromRequestBar();
 });
 
 
@@ -284,6 +474,7 @@ function ParseHTTPResponse(httpResponseMessage) {
     statusCode: 0,
     statusText: "",
     headers: [],
+    meta: [],
     body: "",
     cookies: [],
   };
@@ -301,7 +492,14 @@ function ParseHTTPResponse(httpResponseMessage) {
   const headers = httpResponseMessage.substring(1 + endOfStartLine, endOfHeaders);
 
   for (const line of headers.split("\n")) {
-    const [key, value] = line.split(": ");
+    const separator = line.indexOf(": ");
+    const key = line.substring(0, separator);
+    const value = line.substring(2 + separator);
+
+    if (key.startsWith("Playground-")) { /* Pseudo-headers written by the playground itself.  */
+      result.meta.push({key: key.substring("Playground-".length), value});
+      continue;
+    }
 
     if (key.toLowerCase() === "set-cookie") {
       result.cookies.push(ParseCookie(value));
@@ -336,13 +534,37 @@ function HandleAfterOnLoad(event) {
   responseStatus.classList.add("active");
   responseStats.classList.add("active");
 
+  ShowNotes(response.meta
+    .filter(meta => ["Warning", "Variable", "Sniffed-Type"].includes(meta.key))
+    .map(meta => {
+      switch (meta.key) {
+        case "Variable":
+          return `Set ${meta.value}`;
+        case "Sniffed-Type":
+          return `Formatted as ${meta.value}, which the body was sniffed as, rather than as its declared Content-Type`;
+      }
+
+      return meta.value;
+    }));
+
+  ShowTests(response.meta
+    .filter(meta => "Test" === meta.key)
+    .map(meta => meta.value));
+
   const statusAnchor = responseStatus.getElementsByTagName("a")[0];
   statusAnchor.setAttribute("href", `https://developer.mozilla.org/en-US/docs/Web/HTTP/Status/${response.statusCode}`)
   statusAnchor.setAttribute("title", `Read more about the \`${response.statusCode} ${response.statusText}\` response.`)
   statusAnchor.textContent = `${response.statusCode} ${response.statusText}`;
 
   responseStats.getElementsByTagName("span")[0].textContent = `${Date.now() - requestStarts} MS`;
-  responseStats.getElementsByTagName("span")[1].textContent = `${response.body.length / 1000} KB`;
+  const unfiltered = response.meta.find(meta => "Unfiltered-Size" === meta.key);
+  const matches = response.meta.find(meta => "Matches" === meta.key);
+  const charset = response.meta.find(meta => "Charset" === meta.key);
+  const size = bodyContainer.textContent.length; /* The body may be highlighted: its markup does not count.  */
+  responseStats.getElementsByTagName("span")[1].textContent = (undefined === unfiltered
+    ? `${size / 1000} KB`
+    : `${size / 1000} KB (${undefined === matches ? "" : `${matches.value} matches, `}filtered from ${parseInt(unfiltered.value) / 1000} KB)`)
+    + (undefined === charset ? "" : `, charset ${charset.value}`);
 
   document.querySelector("li[data-tab-response-target='#tab-response-headers']").textContent = `Headers (${response.headers.length})`;
 
@@ -392,6 +614,29 @@ function HandleAfterOnLoad(event) {
   }
 }
 
+function ShowNotes(notes) {
+  const list = document.getElementById("response-notes");
+  list.innerHTML = "";
+
+  for (const note of notes) {
+    const item = document.createElement("li");
+    item.textContent = note;
+    list.appendChild(item);
+  }
+}
+
+function ShowTests(tests) {
+  const list = document.getElementById("response-tests");
+  list.innerHTML = "";
+
+  for (const test of tests) { /* Written as "✓ name" or "✗ name: reason" by the scripts of the request.  */
+    const item = document.createElement("li");
+    item.textContent = test;
+    item.classList.add(test.startsWith("✓") ? "passed" : "failed");
+    list.appendChild(item);
+  }
+}
+
 function StoreRequestTargetURL() {
   localStorage.setItem("fontseca.dev/playground@http-request-target", requestTarget.value.trim());
 }
@@ -554,6 +799,57 @@ function InterceptQueryParameterEntry(event) {
   }
 }
 
+function GetPathVariablesTable() {
+  return document.getElementById("http-request-path-variables");
+}
+
+function AppendPathVariableRow(key, value) {
+  const tbody = GetPathVariablesTable();
+  const entry = tbody.insertRow();
+  entry.innerHTML = `
+    <td>
+      <input class="http-request-path-variable-key"
+             type="text"
+             form="http-request-form"
+             name="path-variable-key"
+             readonly />
+    </td>
+    <td>
+      <input class="http-request-path-variable-value"
+             type="text"
+             form="http-request-form"
+             name="path-variable-value"
+             placeholder="Value" />
+    </td>
+  `;
+
+  entry.querySelector(".http-request-path-variable-key").value = key;
+  entry.querySelector(".http-request-path-variable-value").value = value;
+}
+
+function ParsePathVariablesFromRequestBar() {
+  const url = requestTarget.value.split(/[?#]/)[0];
+  const names = url
+    .replace(/^[a-zA-Z][a-zA-Z0-9+.-]*:\/\/[^/]*/, "") // drop scheme, host and port
+    .split("/")
+    .filter(segment => segment.startsWith(":") && segment.length > 1)
+    .map(segment => segment.substring(1));
+
+  const table = GetPathVariablesTable();
+  const values = {};
+
+  for (const row of table.rows) {
+    values[row.querySelector(".http-request-path-variable-key").value] =
+      row.querySelector(".http-request-path-variable-value").value;
+  }
+
+  table.innerHTML = "";
+
+  for (const name of new Set(names)) {
+    AppendPathVariableRow(name, values[name] ?? "");
+  }
+}
+
 function GetHeadersTable() {
   return document.getElementById("http-request-headers");
 }
@@ -635,6 +931,8 @@ function ResetResponse() {
 
 
   GetQueryParametersTable().innerHTML = "";
+  ShowNotes([]);
+  ShowTests([]);
   document.getElementById("http-response-body").innerHTML = "";
   document.querySelector("li[data-tab-response-target='#tab-response-headers']").textContent = "Headers";
   document.getElementById("http-response-headers").innerHTML = "";
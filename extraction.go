@@ -0,0 +1,203 @@
+package playground
+
+import (
+  "encoding/json"
+  "errors"
+  "fmt"
+  "net/http"
+  "regexp"
+  "slices"
+  "strconv"
+  "strings"
+)
+
+// An extraction is a rule that takes a value out of the response of a request and keeps it in a
+// variable, so that the requests sent after it can refer to it, such as token = $.data.token.
+type extraction struct {
+  variable   string // The name of the variable that the value is kept in.
+  source     string // Where the value is taken from: json, xpath, regex, header or cookie.
+  expression string // The JSON path, the XPath, the regular expression, or the name of the header or the cookie.
+}
+
+// parseExtraction parses a rule written as <variable> = <source>, where the source is a JSON path
+// ($.data.token), an XPath (xpath //token/text(), or just //token), a regular expression whose
+// first group, if any, is taken (regex "id=(\d+)"), a header (header["ETag"]) or a cookie
+// (cookie["session"]).
+func parseExtraction(text string) (e extraction, err error) {
+  variable, source, found := strings.Cut(text, "=")
+  e.variable, source = strings.TrimSpace(variable), strings.TrimSpace(source)
+  switch {
+  case !found:
+    return e, fmt.Errorf("missing = in %q", text)
+  case "" == e.variable || strings.ContainsAny(e.variable, " \t{}[]\"'"):
+    return e, fmt.Errorf("invalid variable name %q", e.variable)
+  case "" == source:
+    return e, fmt.Errorf("missing source in %q", text)
+  }
+
+  switch {
+  default:
+    return e, fmt.Errorf("unknown source %q", source)
+  case strings.HasPrefix(source, "$"):
+    e.source, e.expression = "json", source
+    if _, err := parseJSONPath(source); nil != err {
+      return e, err
+    }
+  case strings.HasPrefix(source, "/"), strings.HasPrefix(source, "xpath "):
+    e.source, e.expression = "xpath", strings.TrimSpace(strings.TrimPrefix(source, "xpath "))
+    if _, err := parseXPath(e.expression); nil != err {
+      return e, err
+    }
+  case strings.HasPrefix(source, "regex "):
+    e.source, e.expression = "regex", strings.TrimSpace(strings.TrimPrefix(source, "regex "))
+    if unquoted, err := strconv.Unquote(e.expression); nil == err {
+      e.expression = unquoted
+    }
+
+    if _, err := regexp.Compile(e.expression); nil != err {
+      return e, fmt.Errorf("invalid regular expression %q", e.expression)
+    }
+  case strings.HasPrefix(source, "header["), strings.HasPrefix(source, "cookie["):
+    if !strings.HasSuffix(source, "]") {
+      return e, fmt.Errorf("unclosed [ in %q", source)
+    }
+
+    e.source, e.expression = source[:len("header")], strings.TrimSpace(source[len("header["):len(source)-1])
+    if unquoted, err := strconv.Unquote(e.expression); nil == err {
+      e.expression = unquoted
+    } else if len(e.expression) >= 2 && '\'' == e.expression[0] && '\'' == e.expression[len(e.expression)-1] {
+      e.expression = e.expression[1 : len(e.expression)-1]
+    }
+
+    if "" == e.expression {
+      return e, fmt.Errorf("missing %s name in %q", e.source, source)
+    }
+  }
+
+  return e, nil
+}
+
+// String writes e in the way it reads, E.g: token = $.data.token, which can be parsed back.
+func (e extraction) String() string {
+  switch e.source {
+  case "xpath":
+    return fmt.Sprint(e.variable, " = xpath ", e.expression)
+  case "regex":
+    return fmt.Sprint(e.variable, " = regex ", strconv.Quote(e.expression))
+  case "header", "cookie":
+    return fmt.Sprint(e.variable, " = ", e.source, "[", strconv.Quote(e.expression), "]")
+  default:
+    return fmt.Sprint(e.variable, " = ", e.expression)
+  }
+}
+
+// extract returns the value that e takes out of response. A JSON value that is not a string is
+// kept as JSON, a node of an XML or HTML document as its text, and the value of an XPath that
+// selects no nodes, such as count(//item), as its written form.
+func (e extraction) extract(response *responseBuilder) (string, error) {
+  switch e.source {
+  default:
+    return "", fmt.Errorf("unknown source %q", e.source)
+  case "json":
+    var document any
+    if err := json.Unmarshal(response.raw, &document); nil != err {
+      return "", errors.New("the response body is not JSON")
+    }
+
+    value, found, err := evalJSONPath(document, e.expression)
+    switch {
+    case nil != err:
+      return "", err
+    case !found:
+      return "", fmt.Errorf("%s is missing", e.expression)
+    }
+
+    if text, ok := value.(string); ok {
+      return text, nil
+    }
+
+    encoded, _ := json.Marshal(value)
+    return string(encoded), nil
+  case "xpath":
+    return e.extractXPath(response)
+  case "regex":
+    match := regexp.MustCompile(e.expression).FindSubmatch(response.raw)
+    switch {
+    case nil == match:
+      return "", fmt.Errorf("%s matches nothing", e.expression)
+    case len(match) > 1:
+      return string(match[1]), nil
+    default:
+      return string(match[0]), nil
+    }
+  case "header":
+    key := http.CanonicalHeaderKey(e.expression)
+    if values, exists := response.header[key]; exists {
+      return strings.Join(values, ", "), nil
+    }
+
+    return "", fmt.Errorf("header %s is missing", key)
+  case "cookie":
+    for cookie := range slices.Values((&http.Response{Header: response.header}).Cookies()) {
+      if e.expression == cookie.Name {
+        return cookie.Value, nil
+      }
+    }
+
+    return "", fmt.Errorf("cookie %s is missing", e.expression)
+  }
+}
+
+// extractXPath evaluates the XPath of e against the body of response, read as HTML if its
+// Content-Type says so and as XML otherwise.
+func (e extraction) extractXPath(response *responseBuilder) (string, error) {
+  parse := parseXMLNodes
+  if strings.Contains(response.header.Get("Content-Type"), "html") {
+    parse = parseHTMLNodes
+  }
+
+  root, err := parse(response.raw)
+  if nil != err {
+    return "", errors.New("the response body is not XML")
+  }
+
+  value, err := queryXPath(root, e.expression)
+  if nil != err {
+    return "", err
+  }
+
+  if nodes, ok := value.([]*xmlNode); ok && 0 == len(nodes) {
+    return "", fmt.Errorf("%s matches nothing", e.expression)
+  }
+
+  return xpathString(value), nil
+}
+
+// extractVariables applies the extraction rules, one per line, to response and keeps the values
+// they take in the environment of scope, which shadows the variables of the collection. Blank lines
+// and lines starting with # are ignored. It returns the variables it set, as name = value, and why
+// the rest of the rules could not be applied.
+func extractVariables(rules []string, response *responseBuilder, scope *scriptScope) (set, warnings []string) {
+  for rule := range slices.Values(rules) {
+    if rule = strings.TrimSpace(rule); "" == rule || strings.HasPrefix(rule, "#") {
+      continue
+    }
+
+    e, err := parseExtraction(rule)
+    if nil != err {
+      warnings = append(warnings, fmt.Sprintf("invalid extraction %q: %s", rule, err.Error()))
+      continue
+    }
+
+    value, err := e.extract(response)
+    if nil != err {
+      warnings = append(warnings, fmt.Sprintf("could not extract {{%s}}: %s", e.variable, err.Error()))
+      continue
+    }
+
+    scope.environment[e.variable] = value
+    set = append(set, fmt.Sprint(e.variable, " = ", value))
+  }
+
+  return set, warnings
+}
@@ -0,0 +1,119 @@
+package playground
+
+import (
+  "github.com/google/go-cmp/cmp"
+  "net/http"
+  "reflect"
+  "testing"
+)
+
+func TestParseExtraction(t *testing.T) {
+  tests := []struct {
+    text string
+    want extraction
+  }{
+    {"token = $.data.token", extraction{variable: "token", source: "json", expression: "$.data.token"}},
+    {"first=$['first name']", extraction{variable: "first", source: "json", expression: "$['first name']"}},
+    {"title = //book[@id='b1']/title", extraction{variable: "title", source: "xpath", expression: "//book[@id='b1']/title"}},
+    {"title = xpath /html/head/title", extraction{variable: "title", source: "xpath", expression: "/html/head/title"}},
+    {`id = regex "id=(\\d+)"`, extraction{variable: "id", source: "regex", expression: `id=(\d+)`}},
+    {"id = regex [0-9]+", extraction{variable: "id", source: "regex", expression: "[0-9]+"}},
+    {`etag = header["ETag"]`, extraction{variable: "etag", source: "header", expression: "ETag"}},
+    {"session = cookie['sid']", extraction{variable: "session", source: "cookie", expression: "sid"}},
+  }
+
+  for _, test := range tests {
+    got, err := parseExtraction(test.text)
+    if nil != err {
+      t.Errorf("parseExtraction(%q) unexpected error: %s", test.text, err)
+      continue
+    }
+
+    if !reflect.DeepEqual(test.want, got) {
+      t.Errorf("parseExtraction(%q): %s", test.text, cmp.Diff(test.want, got, cmp.AllowUnexported(extraction{})))
+    }
+
+    if again, _ := parseExtraction(got.String()); !reflect.DeepEqual(got, again) {
+      t.Errorf("parseExtraction(%q) does not parse back: %s", got.String(), cmp.Diff(got, again, cmp.AllowUnexported(extraction{})))
+    }
+  }
+
+  for text, want := range map[string]string{
+    "token":                  `missing = in "token"`,
+    "{{token}} = $.token":    `invalid variable name "{{token}}"`,
+    "token =":                `missing source in "token ="`,
+    "token = body":           `unknown source "body"`,
+    "token = $.data[x]":      `invalid index "x" in JSON path "$.data[x]"`,
+    "token = //a[":           `missing expression in XPath "//a["`,
+    "token = regex (":        `invalid regular expression "("`,
+    `etag = header["ETag"`:   `unclosed [ in "header[\"ETag\""`,
+    "session = cookie[]":     `missing cookie name in "cookie[]"`,
+  } {
+    if _, err := parseExtraction(text); nil == err || want != err.Error() {
+      t.Errorf("parseExtraction(%q) error = %v, want %q", text, err, want)
+    }
+  }
+}
+
+func TestExtractVariables(t *testing.T) {
+  response := &responseBuilder{
+    status: 200,
+    header: http.Header{
+      "Content-Type": {"application/json"},
+      "Etag":         {`"v1"`},
+      "Set-Cookie":   {"sid=abc123; Path=/; HttpOnly", "theme=dark"},
+    },
+    raw: []byte(`{"data": {"token": "s3cr3t", "user": {"id": 7, "roles": ["admin"]}}, "next": "/users?page=2"}`),
+  }
+
+  scope := newScriptScope(&coll{Variable: []collVariable{{Key: "token", Value: "old"}}}, nil)
+  set, warnings := extractVariables([]string{
+    "# The token of the session.",
+    "token = $.data.token",
+    "",
+    "user = $.data.user",
+    "page = regex page=(\\d+)",
+    `etag = header["ETag"]`,
+    "sid = cookie[sid]",
+    "missing = $.data.refresh",
+    "title = //title",
+    "broken",
+  }, response, scope)
+
+  wantSet := []string{"token = s3cr3t", `user = {"id":7,"roles":["admin"]}`, "page = 2", `etag = "v1"`, "sid = abc123"}
+  wantWarnings := []string{
+    "could not extract {{missing}}: $.data.refresh is missing",
+    "could not extract {{title}}: the response body is not XML",
+    `invalid extraction "broken": missing = in "broken"`,
+  }
+
+  if !reflect.DeepEqual(wantSet, set) {
+    t.Error(cmp.Diff(wantSet, set))
+  }
+
+  if !reflect.DeepEqual(wantWarnings, warnings) {
+    t.Error(cmp.Diff(wantWarnings, warnings))
+  }
+
+  if value, _ := scope.lookup("token"); "s3cr3t" != value || "old" != scope.collection["token"] {
+    t.Errorf("token = %q in a scope with %+v, want s3cr3t in the environment", value, scope)
+  }
+
+  html := &responseBuilder{header: http.Header{"Content-Type": {"text/html"}}, raw: []byte("<title>Log in</title><input name=csrf value=t0k3n>")}
+  set, warnings = extractVariables([]string{"csrf = //input[@name='csrf']/@value", "inputs = xpath count(//input)", "form = //form"}, html, scope)
+  wantSet, wantWarnings = []string{"csrf = t0k3n", "inputs = 1"}, []string{"could not extract {{form}}: //form matches nothing"}
+  if !reflect.DeepEqual(wantSet, set) || !reflect.DeepEqual(wantWarnings, warnings) {
+    t.Errorf("extractVariables = %q with warnings %q, want %q with %q", set, warnings, wantSet, wantWarnings)
+  }
+
+  xml := &responseBuilder{
+    header: http.Header{"Content-Type": {"application/xml; charset=utf-8"}},
+    raw:    []byte(`<books><book id="b1"><title>Go</title></book><book id="b2"><title>Rust</title></book></books>`),
+  }
+
+  set, warnings = extractVariables([]string{"title = //book[@id='b2']/title", "books = xpath count(/books/book)", "price = //book/price"}, xml, scope)
+  wantSet, wantWarnings = []string{"title = Rust", "books = 2"}, []string{"could not extract {{price}}: //book/price matches nothing"}
+  if !reflect.DeepEqual(wantSet, set) || !reflect.DeepEqual(wantWarnings, warnings) {
+    t.Errorf("extractVariables = %q with warnings %q, want %q with %q", set, warnings, wantSet, wantWarnings)
+  }
+}
@@ -0,0 +1,121 @@
+package playground
+
+import (
+  "bytes"
+  "context"
+  "encoding/json"
+  "errors"
+  "fmt"
+  "strings"
+)
+
+// compileJSONFilter compiles the filter of a JSON response: a JSON path when it starts with $, such
+// as $.data[*].id, whose matches are returned as one array, or a jq filter otherwise, such as
+// .data[] | select(.active) | .id. The filter fails once it exceeds budget.
+func compileJSONFilter(expression string, budget *jqBudget) (jsonFilter, error) {
+  if !strings.HasPrefix(expression, "$") {
+    return compileJQ(expression, budget)
+  }
+
+  if len(expression) > maxFilterLength {
+    return nil, errors.New("the filter is too long")
+  }
+
+  steps, err := parseJSONPath(expression)
+  if nil != err {
+    return nil, err
+  }
+
+  return func(document any) ([]any, error) {
+    matches := append([]any{}, queryJSONPath(document, document, steps)...)
+    return []any{matches}, budget.spend(len(matches))
+  }, nil
+}
+
+// filterJSON applies filter to the JSON document input and returns its outputs, indented and
+// separated by new lines, the way jq writes them.
+func filterJSON(input []byte, filter jsonFilter, indent string) ([]byte, error) {
+  decoder := json.NewDecoder(bytes.NewReader(input))
+  decoder.UseNumber() /* Keeps the numbers that are passed through as they were written.  */
+
+  var document any
+  if err := decoder.Decode(&document); nil != err {
+    return nil, errors.New("the response body is not JSON")
+  }
+
+  outputs, err := filter(document)
+  if nil != err {
+    return nil, err
+  }
+
+  buffer := bytes.Buffer{}
+  encoder := json.NewEncoder(&buffer)
+  encoder.SetEscapeHTML(false)
+  encoder.SetIndent("", indent)
+  for _, output := range outputs {
+    if err := encoder.Encode(output); nil != err {
+      return nil, err
+    }
+
+    if buffer.Len() > maxBodyBytes {
+      return nil, fmt.Errorf("the result is larger than %d MB", maxBodyBytes>>20)
+    }
+  }
+
+  return bytes.TrimSpace(buffer.Bytes()), nil
+}
+
+// filterMarkup evaluates expr on the XML document input, or on the HTML one if formatter is the HTML
+// formatter, and returns the nodes it selects, each formatted on its own, along with their number.
+// Attributes and texts are written as their value. An expression whose value is not a node-set, such
+// as count(//item), returns that value, and -1 matches. The nodes are highlighted if highlight is true.
+// The evaluation stops when ctx is done.
+func filterMarkup(ctx context.Context, input []byte, expr xpathExpr, formatter tokenFormatter, indent string, highlight bool) ([]byte, int, error) {
+  parse := parseXMLNodes
+  if htmlFormatter == formatter {
+    parse = parseHTMLNodes
+  }
+
+  root, err := parse(input)
+  if nil != err {
+    return nil, 0, errors.New("the response body is not XML")
+  }
+
+  value, err := expr.eval(xpathContext{node: root, position: 1, size: 1, budget: newXPathBudget(ctx)})
+  if nil != err {
+    return nil, 0, err
+  }
+
+  buffer := bytes.Buffer{}
+  var output tokenWriter = &plainTokens{output: &buffer}
+  if highlight {
+    output = &htmlTokens{output: &buffer}
+  }
+
+  nodes, ok := value.([]*xmlNode)
+  if !ok {
+    output.token(tokenText, []byte(xpathString(value)))
+    return buffer.Bytes(), -1, nil
+  }
+
+  for n, node := range nodes {
+    if n > 0 {
+      output.token(tokenText, []byte("\n"))
+    }
+
+    switch node.kind {
+    case attributeNode:
+      output.token(tokenValue, []byte(strings.TrimSpace(node.value)))
+    case textNode:
+      output.token(tokenText, []byte(strings.TrimSpace(node.value)))
+    default:
+      formatter.tokens([]byte(node.markup(htmlFormatter == formatter)), output, indent)
+    }
+
+    if buffer.Len() > maxBodyBytes {
+      return nil, 0, fmt.Errorf("the result is larger than %d MB", maxBodyBytes>>20)
+    }
+  }
+
+  return buffer.Bytes(), len(nodes), nil
+}
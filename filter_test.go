@@ -0,0 +1,114 @@
+package playground
+
+import (
+  "context"
+  "fmt"
+  "strings"
+  "testing"
+)
+
+func TestFilterResponse(t *testing.T) {
+  body := []byte(`{"data": [{"id": 1, "url": "https://example.com/?a=1&b=2"}, {"id": 2}]}`)
+
+  tests := []struct {
+    filter    string
+    formatter bodyFormatter
+    filtered  bool
+    output    string
+  }{
+    {"$.data[*].id", jsonFormatter, true, "[\n  1,\n  2\n]"},
+    {"$.data[0].url", jsonFormatter, true, `[
+  "https://example.com/?a=1&b=2"
+]`},
+    {".data[].id", jsonFormatter, true, "1\n2"},
+    {".data[0].url", jsonFormatter, true, `"https://example.com/?a=1&b=2"`},
+    {".data[].id", textFormatter, false, "Playground-Warning: the filter was not applied: the response body is not JSON, XML or HTML"},
+    {".data[].id", xmlFormatter, false, `Playground-Warning: invalid filter: unexpected "data[].id" in XPath ".data[].id"`},
+    {".data[", jsonFormatter, false, "Playground-Warning: invalid filter: unexpected end of filter at offset 6"},
+    {"$.data[", jsonFormatter, false, `Playground-Warning: invalid filter: unclosed [ in JSON path "$.data["`},
+    {".data.id", jsonFormatter, false, `Playground-Warning: the filter failed: cannot index array with "id"`},
+  }
+
+  for _, test := range tests {
+    response := newResponseBuilder()
+    filtered := filterResponse(context.Background(), test.filter, test.formatter, body, response, false)
+    output := response.String()
+    if test.filtered != filtered || !strings.Contains(output, test.output) {
+      t.Errorf("filterResponse(%q) = %t\n%s\nwant %t with\n%s", test.filter, filtered, output, test.filtered, test.output)
+    }
+
+    if test.filtered && !strings.Contains(output, fmt.Sprint("Playground-Unfiltered-Size: ", len(body))) {
+      t.Errorf("filterResponse(%q) did not report the unfiltered size:\n%s", test.filter, output)
+    }
+  }
+}
+
+func TestFilterResponse_xpath(t *testing.T) {
+  soap := []byte(`<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/"><soap:Body><m:GetPriceResponse xmlns:m="urn:shop"><m:Price currency="USD">1.90</m:Price><m:Price currency="EUR">1.75</m:Price></m:GetPriceResponse></soap:Body></soap:Envelope>`)
+  page := []byte(`<!DOCTYPE html><html><body><ul><li><a href="/a">A</a></li><li><a href="/b">B</a></li></ul></body></html>`)
+
+  tests := []struct {
+    body      []byte
+    filter    string
+    formatter bodyFormatter
+    output    string
+    matches   string
+  }{
+    {soap, "//Price", xmlFormatter, "<m:Price currency=\"USD\">1.90</m:Price>\n<m:Price currency=\"EUR\">1.75</m:Price>", "2"},
+    {soap, "//GetPriceResponse", xmlFormatter, `<m:GetPriceResponse xmlns:m="urn:shop">
+  <m:Price currency="USD">1.90</m:Price>
+  <m:Price currency="EUR">1.75</m:Price>
+</m:GetPriceResponse>`, "1"},
+    {soap, "//Price/@currency", xmlFormatter, "USD\nEUR", "2"},
+    {soap, "//Price[@currency='GBP']", xmlFormatter, "", "0"},
+    {soap, "sum(//Price)", xmlFormatter, "3.65", ""},
+    {page, "//li", htmlFormatter, "<li>\n  <a href=\"/a\">A</a>\n</li>\n<li>\n  <a href=\"/b\">B</a>\n</li>", "2"},
+    {page, "//a/@href", htmlFormatter, "/a\n/b", "2"},
+  }
+
+  for _, test := range tests {
+    response := newResponseBuilder()
+    if !filterResponse(context.Background(), test.filter, test.formatter, test.body, response, false) {
+      t.Errorf("filterResponse(%q) was not applied:\n%s", test.filter, response.String())
+      continue
+    }
+
+    if got := response.body.String(); test.output != got {
+      t.Errorf("filterResponse(%q) =\n%s\nwant\n%s", test.filter, got, test.output)
+    }
+
+    if got := response.meta.Get("Playground-Matches"); test.matches != got {
+      t.Errorf("filterResponse(%q) matches = %q, want %q", test.filter, got, test.matches)
+    }
+  }
+
+  response := newResponseBuilder()
+  if filterResponse(context.Background(), "//a", xmlFormatter, []byte(`{"a": 1}`), response, false) || !strings.Contains(response.String(), "the filter failed: the response body is not XML") {
+    t.Errorf("filterResponse on a body that is not XML:\n%s", response.String())
+  }
+}
+
+func TestFilterResponse_highlight(t *testing.T) {
+  tests := []struct {
+    body      string
+    filter    string
+    formatter bodyFormatter
+    output    string
+  }{
+    {`{"a": [1, "<b>"]}`, ".a[]", jsonFormatter, `<span class="tok-number">1</span>` + "\n" + `<span class="tok-string">&#34;&lt;b&gt;&#34;</span>`},
+    {`<r><i id="1">x</i></r>`, "//i", xmlFormatter, `<span class="tok-punct">&lt;</span><span class="tok-tag">i</span> <span class="tok-attr">id</span><span class="tok-punct">=</span><span class="tok-value">&#34;1&#34;</span><span class="tok-punct">&gt;</span>x<span class="tok-punct">&lt;/</span><span class="tok-tag">i</span><span class="tok-punct">&gt;</span>`},
+    {`<r><i id="&lt;1">x</i></r>`, "//i/@id", xmlFormatter, `<span class="tok-value">&lt;1</span>`},
+  }
+
+  for _, test := range tests {
+    response := newResponseBuilder()
+    if !filterResponse(context.Background(), test.filter, test.formatter, []byte(test.body), response, true) || !response.highlighted {
+      t.Errorf("filterResponse(%q) was not applied or not highlighted:\n%s", test.filter, response.String())
+      continue
+    }
+
+    if got := response.body.String(); test.output != got {
+      t.Errorf("filterResponse(%q) =\n%s\nwant\n%s", test.filter, got, test.output)
+    }
+  }
+}
@@ -2,15 +2,15 @@ package playground
 
 import (
   "bytes"
-  "encoding/json"
   "errors"
   "fmt"
   xhtml "golang.org/x/net/html"
-  "html"
   "io"
   "log/slog"
   "regexp"
+  "slices"
   "strings"
+  "unicode"
   "unicode/utf8"
 )
 
@@ -28,139 +28,587 @@ func (textFormatterImpl) format(input []byte, output io.Writer, _ string) {
   }
 }
 
-type jsonFormatterImpl struct{}
+type jsonFormatterImpl struct {
+  sortKeys bool // Whether the members of objects are written sorted by their keys.
+  compact  bool // Whether documents are written without blanks, one per line.
+}
 
 // format implements a JSON formatter.
-func (jsonFormatterImpl) format(input []byte, output io.Writer, indent string) {
+func (f jsonFormatterImpl) format(input []byte, output io.Writer, indent string) {
+  formatTokens(f, input, output, indent)
+}
+
+// tokens indents a JSON document, or a stream of them such as the outputs of a filter, each on lines
+// of its own, the way json.Indent does. Numbers and strings are written as they are, so that integers
+// too large for a float64 keep their digits. An invalid document is written as it is, and check tells
+// why.
+func (f jsonFormatterImpl) tokens(input []byte, output tokenWriter, indent string) {
+  input = bytes.TrimSpace(input)
   if len(input) == 0 {
     return
   }
 
-  buffer := bytes.Buffer{}
-  err := json.Indent(&buffer, input, "", indent)
+  documents, _, err := parseJSON(input)
   if nil != err {
-    slog.Error(err.Error())
+    output.token(tokenText, input)
     return
   }
 
-  _, err = output.Write(bytes.TrimSpace(buffer.Bytes()))
-  if nil != err {
-    slog.Error(err.Error())
+  for n, document := range documents {
+    if n > 0 {
+      output.token(tokenText, []byte{'\n'})
+    }
+
+    f.write(document, output, indent, 0)
   }
 }
 
-var (
-  xmlReTag              = regexp.MustCompile(`<([/!]?)([^>]+?)(/?)>`)        // regexp xmlReTag matches an XML tag
-  xmlReComment          = regexp.MustCompile(`(?s)(<!--)(.*?)(-->)`)         // regexp xmlReComment matches an XML comment
-  xmlReXMLInsideComment = regexp.MustCompile(`<!--[^>]*?<[^>]+?[^-]*?-->`)   // regexp xmlReXMLInsideComment matches XML tags inside comments
-  xmlReBlanksAround     = regexp.MustCompile(`\s*(<|/?>)\s*`)                // regexp xmlReBlanksAround matches blanks around XML tags
-  xmlReBlanksInsideTags = regexp.MustCompile(`>([^<]*[\n\r\t]| {3,})[^<]*<`) // regexp xmlReBlanksInsideTags matches the content of an XML tags that contains repeated spaces or \r\n\t
-  xmlReBlanks           = regexp.MustCompile(`\s{2,}`)                       // regexp xmlReBlanks matches the content of an XML tag that contains more than two spaces together
-)
+// check reports where the syntax of a JSON document breaks, and the keys it repeats.
+func (jsonFormatterImpl) check(input []byte) []string {
+  return checkJSON(input)
+}
+
+// write writes node, nested in depth objects and arrays.
+func (f jsonFormatterImpl) write(node *jsonNode, output tokenWriter, indent string, depth int) {
+  line := func(depth int) {
+    if !f.compact {
+      output.token(tokenText, append([]byte{'\n'}, bytes.Repeat([]byte(indent), depth)...))
+    }
+  }
+
+  switch node.raw[0] {
+  case '{':
+    if 0 == len(node.members) {
+      output.token(tokenPunctuation, []byte("{}"))
+      return
+    }
+
+    members := node.members
+    if f.sortKeys {
+      members = slices.Clone(members)
+      slices.SortStableFunc(members, func(a, b jsonMember) int { return strings.Compare(a.name, b.name) })
+    }
+
+    output.token(tokenPunctuation, node.raw)
+    for n, member := range members {
+      if n > 0 {
+        output.token(tokenPunctuation, []byte{','})
+      }
+
+      line(depth + 1)
+      output.token(tokenKey, member.key)
+      output.token(tokenPunctuation, []byte{':'})
+      if !f.compact {
+        output.token(tokenText, []byte{' '})
+      }
+
+      f.write(member.value, output, indent, depth+1)
+    }
+
+    line(depth)
+    output.token(tokenPunctuation, []byte{'}'})
+  case '[':
+    if 0 == len(node.items) {
+      output.token(tokenPunctuation, []byte("[]"))
+      return
+    }
+
+    output.token(tokenPunctuation, node.raw)
+    for n, item := range node.items {
+      if n > 0 {
+        output.token(tokenPunctuation, []byte{','})
+      }
+
+      line(depth + 1)
+      f.write(item, output, indent, depth+1)
+    }
+
+    line(depth)
+    output.token(tokenPunctuation, []byte{']'})
+  case '"':
+    output.token(tokenString, node.raw)
+  case 't', 'f', 'n':
+    output.token(tokenLiteral, node.raw)
+  default:
+    output.token(tokenNumber, node.raw)
+  }
+}
+
+type jsonLinesFormatterImpl struct {
+  seq bool // Whether records start with an RS character, as in RFC 7464, instead of taking a line each.
+}
+
+// A jsonRecord is a record of a stream of JSON texts, such as a line of an NDJSON body.
+type jsonRecord struct {
+  text      []byte // The record, without the blanks around it.
+  offset    int    // Where the text starts in the body.
+  line      int    // The line the text starts at, from 1.
+  lineStart int    // Where that line starts in the body.
+}
+
+// records splits input into its records, leaving out the blank ones. The lines are counted as the
+// input is split, since locating every record from the start of the body would take quadratic time.
+func (f jsonLinesFormatterImpl) records(input []byte) []jsonRecord {
+  separator := []byte{'\n'}
+  if f.seq {
+    separator = []byte{0x1e}
+  }
+
+  var records []jsonRecord
+  offset, line, lineStart := 0, 1, 0
+  for part := range bytes.SplitSeq(input, separator) {
+    blank := part[:len(part)-len(bytes.TrimLeft(part, " \t\r\n"))]
+    if text := bytes.TrimRight(part[len(blank):], " \t\r\n"); len(text) > 0 {
+      record := jsonRecord{text: text, offset: offset + len(blank), line: line + bytes.Count(blank, []byte{'\n'}), lineStart: lineStart}
+      if n := bytes.LastIndexByte(blank, '\n'); -1 != n {
+        record.lineStart = offset + n + 1
+      }
+
+      records = append(records, record)
+    }
+
+    if n := bytes.LastIndexByte(part, '\n'); -1 != n {
+      line, lineStart = line+bytes.Count(part, []byte{'\n'}), offset+n+1
+    }
+
+    if offset += len(part) + len(separator); !f.seq {
+      line, lineStart = line+1, offset
+    }
+  }
+
+  return records
+}
+
+// format implements a formatter of JSON Lines, NDJSON and JSON text sequences.
+func (f jsonLinesFormatterImpl) format(input []byte, output io.Writer, indent string) {
+  formatTokens(f, input, output, indent)
+}
+
+// tokens writes every record indented on its own, after a comment that tells its index and the line it
+// starts at. Invalid records are written as they are, and check tells why.
+func (f jsonLinesFormatterImpl) tokens(input []byte, output tokenWriter, indent string) {
+  for n, record := range slices.All(f.records(input)) {
+    if n > 0 {
+      output.token(tokenText, []byte("\n\n"))
+    }
+
+    documents, _, err := parseJSON(record.text)
+    header := fmt.Sprintf("# record %d, line %d", n+1, record.line)
+    if nil != err || 1 != len(documents) {
+      header += " (invalid)"
+    }
+
+    output.token(tokenComment, []byte(header))
+    output.token(tokenText, []byte{'\n'})
+    if nil != err || 1 != len(documents) {
+      output.token(tokenText, record.text)
+      continue
+    }
+
+    jsonFormatterImpl{}.write(documents[0], output, indent, 0)
+  }
+}
+
+// check reports the records that are not one JSON text, and the keys that records repeat.
+func (f jsonLinesFormatterImpl) check(input []byte) []string {
+  var warnings []string
+  for n, record := range slices.All(f.records(input)) {
+    documents, duplicates, err := parseJSON(record.text)
+    if nil == err && len(documents) > 1 {
+      warnings = append(warnings, fmt.Sprintf("record %d at line %d holds %d JSON texts instead of one", n+1, record.line, len(documents)))
+    }
+
+    end := record.offset + len(record.text)
+    for problem := range slices.Values(jsonProblems(input[record.lineStart:end], record.offset-record.lineStart, record.line-1, duplicates, err)) {
+      warnings = append(warnings, fmt.Sprintf("record %d: %s", n+1, problem))
+    }
+  }
+
+  if len(warnings) > maxJSONWarnings {
+    warnings = append(warnings[:maxJSONWarnings], fmt.Sprintf("and %d more problems", len(warnings)-maxJSONWarnings))
+  }
+
+  return warnings
+}
 
 type xmlFormatterImpl struct{}
 
-// format implements basic formatting for XML input by applying indentation, normalizing whitespace, handling
-// comments and XML tags inside comments.
-func (xmlFormatterImpl) format(input []byte, output io.Writer, indent string) {
-  if nil == input || len(input) < 2 {
-    return
+// format indents XML input one node per line, streaming it from a single pass of xmlLexer. An element
+// whose content starts with text, such as <name>Fred</name>, is written on one line with its blanks
+// collapsed, up to its first child element, which goes on a line of its own, and one with
+// xml:space="preserve" as it is. Tags are written back
+// with their prefixes and attribute values untouched, and comments, CDATA sections and the internal
+// subset of a DOCTYPE are kept as they are.
+func (f xmlFormatterImpl) format(input []byte, output io.Writer, indent string) {
+  formatTokens(f, input, output, indent)
+}
+
+// tokens writes what format does, split into tag names, attributes, comments, and so on.
+func (xmlFormatterImpl) tokens(input []byte, output tokenWriter, indent string) {
+  w := &xmlWriter{output: output, indent: indent}
+  lexer := &xmlLexer{input: input}
+
+  for token, ok := lexer.next(); ok; token, ok = lexer.next() {
+    w.write(token)
   }
 
-  var needsUnescape bool
-
-  // Comments might contain further XML code. In that's the case, we want to escape
-  // that code to avoid formatting.
-  out := xmlReXMLInsideComment.ReplaceAllFunc(input, func(comment []byte) []byte {
-    needsUnescape = true
-    submatches := xmlReComment.FindSubmatch(comment)
-    b := bytes.Buffer{}
-    b.Grow(len(comment))
-    b.Write(submatches[1])                                  // <!--
-    b.WriteString(html.EscapeString(string(submatches[2]))) // ... (which includes XML code)
-    b.Write(submatches[3])                                  // -->
-    return b.Bytes()
-  })
-
-  out = xmlReBlanksAround.ReplaceAll(out, []byte("$1"))
-  out = xmlReBlanksInsideTags.ReplaceAllFunc(out, func(m []byte) []byte {
-    return xmlReBlanks.ReplaceAll(m, []byte(" "))
-  })
-
-  out = xmlReTag.ReplaceAllFunc(out, xmlTagReplacer(indent))
-
-  if needsUnescape {
-    // restore the original comment escaped content
-    out = xmlReComment.ReplaceAllFunc(out, func(comment []byte) []byte {
-      submatches := xmlReComment.FindSubmatch(comment)
-      b := bytes.Buffer{}
-      b.Grow(len(comment))
-      b.Write(submatches[1])                                    // <!--
-      b.WriteString(html.UnescapeString(string(submatches[2]))) // ... (which include XML code)
-      b.Write(submatches[3])                                    // -->
-      return b.Bytes()
-    })
-  }
-
-  _, err := output.Write(out[1:])
-  if nil != err {
-    slog.Error(err.Error())
+  if 0 != w.inline { /* The document ended before the end tag of an element written on one line.  */
+    w.output.token(tokenText, bytes.TrimRightFunc(w.pending, unicode.IsSpace))
   }
 }
 
-// xmlTagReplacer returns a closure that processes XML tag slices, applying indentation and
-// whitespace normalization based on the type of XML tag provided.
-func xmlTagReplacer(indent string) func([]byte) []byte {
-  var (
-    depth       = 0
-    wasEndOfTag = true
-    buffer      = bytes.Buffer{}
-  )
+// xmlTokenKind tells apart the tokens that xmlLexer reads.
+type xmlTokenKind int
 
-  buffer.Grow(64)
+const (
+  xmlTextToken xmlTokenKind = iota
+  xmlStartToken
+  xmlEndToken
+  xmlCommentToken
+  xmlCDATAToken
+  xmlDeclarationToken // A processing instruction, such as <?xml version="1.0"?>, or a <!DOCTYPE ...>.
+)
 
-  return func(tag []byte) []byte {
-    // XML declaration
-    if bytes.HasPrefix(tag, []byte("<?xml")) {
-      return append([]byte("\n"), xmlReBlanks.ReplaceAll(tag, []byte(" "))...)
+// An xmlToken is a piece of an XML document, along with how it is written back.
+type xmlToken struct {
+  kind        xmlTokenKind
+  raw         []byte
+  selfClosing bool // Whether a start tag ends with />.
+  preserve    bool // Whether a start tag has xml:space="preserve".
+}
+
+// An xmlLexer splits an XML document into tokens. It does not check that the document is well formed:
+// what is not markup is read as text, so that it shows as it is.
+type xmlLexer struct {
+  input []byte
+  n     int
+}
+
+// xmlIsSpace reports whether c is a blank of XML.
+func xmlIsSpace(c byte) bool {
+  return ' ' == c || '\t' == c || '\n' == c || '\r' == c
+}
+
+// next returns the token at n, or false at the end of the input.
+func (l *xmlLexer) next() (xmlToken, bool) {
+  if l.n >= len(l.input) {
+    return xmlToken{}, false
+  }
+
+  rest := l.input[l.n:]
+  switch {
+  case '<' != rest[0]:
+    end := bytes.IndexByte(rest, '<')
+    if -1 == end {
+      end = len(rest)
     }
 
-    defer buffer.Reset()
-    buffer.Write([]byte("\n"))
+    l.n += end
+    return xmlToken{kind: xmlTextToken, raw: rest[:end]}, true
+  case bytes.HasPrefix(rest, []byte("<!--")):
+    return xmlToken{kind: xmlCommentToken, raw: l.until(4, "-->")}, true
+  case bytes.HasPrefix(rest, []byte("<![CDATA[")):
+    return xmlToken{kind: xmlCDATAToken, raw: l.until(9, "]]>")}, true
+  case bytes.HasPrefix(rest, []byte("<?")):
+    return xmlToken{kind: xmlDeclarationToken, raw: xmlCollapseSpace(l.until(2, "?>"))}, true
+  case bytes.HasPrefix(rest, []byte("<!")):
+    return xmlToken{kind: xmlDeclarationToken, raw: l.declaration()}, true
+  }
+
+  if token, ok := l.tag(); ok {
+    return token, true
+  }
+
+  l.n++ /* A < that starts no markup, such as in a < b.  */
+  return xmlToken{kind: xmlTextToken, raw: rest[:1]}, true
+}
+
+// until returns the token at n up to the end of delimiter, which starts past skip bytes, or up to the
+// end of the input.
+func (l *xmlLexer) until(skip int, delimiter string) []byte {
+  rest := l.input[l.n:]
+  end := bytes.Index(rest[skip:], []byte(delimiter))
+  if -1 == end {
+    l.n = len(l.input)
+    return rest
+  }
+
+  end += skip + len(delimiter)
+  l.n += end
+  return rest[:end]
+}
 
+// declaration returns the <!DOCTYPE ...> or the other declaration at n, whose blanks are collapsed
+// outside the internal subset and the quoted strings.
+func (l *xmlLexer) declaration() []byte {
+  rest := l.input[l.n:]
+  var out []byte
+  var quote byte
+  brackets := 0
+  for n := 0; n < len(rest); n++ {
+    c := rest[n]
     switch {
-    default: // start element
-      wasEndOfTag = false
-      buffer.WriteString(strings.Repeat(indent, depth))
-      buffer.Write(xmlReBlanks.ReplaceAll(tag, []byte(" ")))
-      depth++
-      return buffer.Bytes()
-
-    case bytes.HasSuffix(tag, []byte("/>")): // empty element
-      wasEndOfTag = true
-      buffer.WriteString(strings.Repeat(indent, depth))
-      buffer.Write(tag)
-      return buffer.Bytes()
-
-    case bytes.HasPrefix(tag, []byte("<!")): // comment or doctype
-      if bytes.HasPrefix(bytes.ToLower(tag), []byte("<!doctype")) {
-        tag = xmlReBlanks.ReplaceAll(tag, []byte(" "))
+    case 0 != quote:
+      if quote == c {
+        quote = 0
       }
+    case '"' == c || '\'' == c:
+      quote = c
+    case '[' == c:
+      brackets++
+    case ']' == c:
+      brackets = max(0, brackets-1)
+    case '>' == c && 0 == brackets:
+      l.n += n + 1
+      return append(out, '>')
+    case xmlIsSpace(c) && 0 == brackets:
+      if n+1 < len(rest) && (xmlIsSpace(rest[n+1]) || '>' == rest[n+1]) || ' ' == out[len(out)-1] {
+        continue
+      }
+
+      c = ' '
+    }
+
+    out = append(out, c)
+  }
+
+  l.n = len(l.input)
+  return out
+}
 
-      wasEndOfTag = true
-      buffer.WriteString(strings.Repeat(indent, depth))
-      buffer.Write(tag)
-      return buffer.Bytes()
-
-    case bytes.HasPrefix(tag, []byte("</")): // end element
-      depth--
-      if wasEndOfTag {
-        buffer.WriteString(strings.Repeat(indent, depth))
-        buffer.Write(tag)
-        return buffer.Bytes()
+// tag reads the start or the end tag at n, if there is one, and writes it back with single spaces
+// between its name and its attributes, whose values are kept as they are.
+func (l *xmlLexer) tag() (token xmlToken, ok bool) {
+  n := l.n + 1
+  skip := func() {
+    for n < len(l.input) && xmlIsSpace(l.input[n]) {
+      n++
+    }
+  }
+
+  name := func() []byte {
+    start := n
+    for n < len(l.input) && !xmlIsSpace(l.input[n]) && !bytes.ContainsRune([]byte("/>=<\"'"), rune(l.input[n])) {
+      n++
+    }
+
+    return l.input[start:n]
+  }
+
+  skip()
+  token.kind = xmlStartToken
+  if n < len(l.input) && '/' == l.input[n] {
+    n++
+    skip()
+    token.kind = xmlEndToken
+  }
+
+  tagName := name()
+  if 0 == len(tagName) {
+    return token, false
+  }
+
+  if xmlEndToken == token.kind {
+    skip()
+    if n >= len(l.input) || '>' != l.input[n] {
+      return token, false
+    }
+
+    l.n, token.raw = n+1, slices.Concat([]byte("</"), tagName, []byte(">"))
+    return token, true
+  }
+
+  token.raw = slices.Concat([]byte("<"), tagName)
+  for {
+    skip()
+    switch {
+    case n >= len(l.input) || '<' == l.input[n]:
+      return token, false
+    case '>' == l.input[n]:
+      l.n, token.raw = n+1, append(token.raw, '>')
+      return token, true
+    case bytes.HasPrefix(l.input[n:], []byte("/>")):
+      l.n, token.raw, token.selfClosing = n+2, append(token.raw, "/>"...), true
+      return token, true
+    }
+
+    attr := name()
+    if 0 == len(attr) {
+      return token, false
+    }
+
+    token.raw = append(append(token.raw, ' '), attr...)
+    if skip(); n >= len(l.input) || '=' != l.input[n] {
+      continue /* An attribute without a value.  */
+    }
+
+    n++
+    skip()
+    start := n
+    if n < len(l.input) && ('"' == l.input[n] || '\'' == l.input[n]) {
+      end := bytes.IndexByte(l.input[n+1:], l.input[n])
+      if -1 == end {
+        return token, false
       }
-      wasEndOfTag = true
-      return tag
+
+      n += end + 2
+    } else {
+      for n < len(l.input) && !xmlIsSpace(l.input[n]) && '>' != l.input[n] {
+        n++
+      }
+    }
+
+    value := l.input[start:n]
+    token.raw = append(append(token.raw, '='), value...)
+    if "xml:space" == string(attr) && len(value) > 2 && "preserve" == string(value[1:len(value)-1]) {
+      token.preserve = true
+    }
+  }
+}
+
+// xmlCollapseSpace replaces every run of blanks in text by a single space.
+func xmlCollapseSpace(text []byte) []byte {
+  out := make([]byte, 0, len(text))
+  for n, c := range text {
+    if !xmlIsSpace(c) {
+      out = append(out, c)
+    } else if 0 == n || !xmlIsSpace(text[n-1]) {
+      out = append(out, ' ')
+    }
+  }
+
+  return out
+}
+
+// An xmlWriter writes the tokens of an XML document, indented.
+type xmlWriter struct {
+  output  tokenWriter
+  indent  string
+  depth   int    // The number of elements that are open.
+  opened  bool   // Whether the last token was a start tag.
+  written bool   // Whether anything was written, before which no line is broken.
+  inline  int    // The depth of the element written on one line, or 0.
+  keep    bool   // Whether the element written on one line has xml:space="preserve".
+  pending []byte // The text read last in an element written on one line, whose trailing blanks go if its end tag is next.
+}
+
+// line starts a new line at the depth of w.
+func (w *xmlWriter) line() {
+  line := bytes.Repeat([]byte(w.indent), w.depth)
+  if w.written {
+    line = append([]byte{'\n'}, line...)
+  }
+
+  w.written = true
+  w.output.token(tokenText, line)
+}
+
+// emit writes raw, which is a token of the given kind.
+func (w *xmlWriter) emit(kind xmlTokenKind, raw []byte) {
+  switch kind {
+  case xmlTextToken:
+    w.output.token(tokenText, raw)
+  case xmlStartToken, xmlEndToken:
+    markupTagTokens(raw, w.output)
+  case xmlCommentToken:
+    w.output.token(tokenComment, raw)
+  case xmlCDATAToken:
+    w.output.token(tokenString, raw)
+  default:
+    w.output.token(tokenDeclaration, raw)
+  }
+}
+
+// write writes token, on a line of its own, or after the last one if they belong to an element written
+// on one line.
+func (w *xmlWriter) write(token xmlToken) {
+  if 0 != w.inline {
+    w.writeInline(token)
+    return
+  }
+
+  opened := w.opened
+  w.opened = false
+  switch token.kind {
+  case xmlTextToken, xmlCDATAToken:
+    text := token.raw
+    if xmlTextToken == token.kind {
+      if text = bytes.TrimSpace(text); 0 == len(text) {
+        w.opened = opened /* Blanks between tags are not content.  */
+        return
+      }
+    }
+
+    if !opened || 0 == w.depth {
+      w.line()
+      w.emit(token.kind, xmlCollapseSpace(text))
+      return
+    }
+
+    w.inline, w.keep = w.depth, false
+    if xmlTextToken == token.kind {
+      token.raw = bytes.TrimLeftFunc(token.raw, unicode.IsSpace)
+    }
+
+    w.writeInline(token)
+  case xmlStartToken:
+    w.line()
+    w.emit(token.kind, token.raw)
+    if !token.selfClosing {
+      w.depth++
+      w.opened = true
+      if token.preserve {
+        w.inline, w.keep = w.depth, true
+      }
+    }
+  case xmlEndToken:
+    w.depth = max(0, w.depth-1)
+    if !opened {
+      w.line()
+    }
+
+    w.emit(token.kind, token.raw)
+  default:
+    w.line()
+    w.emit(token.kind, token.raw)
+  }
+}
+
+// writeInline writes token right after the last one, in an element written on one line. A child
+// element of that element, unless it keeps its blanks, ends the line, and is indented as usual.
+func (w *xmlWriter) writeInline(token xmlToken) {
+  if xmlStartToken == token.kind && w.depth == w.inline && !w.keep {
+    w.output.token(tokenText, bytes.TrimRightFunc(w.pending, unicode.IsSpace))
+    w.pending, w.inline = w.pending[:0], 0
+    w.write(token)
+    return
+  }
+
+  if xmlTextToken == token.kind {
+    text := token.raw
+    if !w.keep {
+      text = xmlCollapseSpace(text)
+    }
+
+    w.pending = append(w.pending, text...)
+    return
+  }
+
+  pending := w.pending
+  if xmlEndToken == token.kind && w.depth == w.inline && !w.keep {
+    pending = bytes.TrimRightFunc(pending, unicode.IsSpace)
+  }
+
+  w.output.token(tokenText, pending)
+  w.pending = w.pending[:0]
+  w.written = true
+  w.emit(token.kind, token.raw)
+  switch {
+  case xmlStartToken == token.kind && !token.selfClosing:
+    w.depth++
+  case xmlEndToken == token.kind:
+    if w.depth--; w.depth < w.inline {
+      w.inline, w.opened = 0, false
     }
   }
 }
@@ -227,7 +675,12 @@ func adjustTextIndentation(text []byte, depth int) []byte {
 }
 
 // format formats the HTML input with proper indentation.
-func (htmlFormatterImpl) format(input []byte, output io.Writer, indent string) {
+func (f htmlFormatterImpl) format(input []byte, output io.Writer, indent string) {
+  formatTokens(f, input, output, indent)
+}
+
+// tokens writes what format does, split into tag names, attributes, comments, and so on.
+func (htmlFormatterImpl) tokens(input []byte, output tokenWriter, indent string) {
   var (
     reader           = bytes.NewReader(input)
     tokenizer        = xhtml.NewTokenizer(reader)
@@ -240,13 +693,14 @@ func (htmlFormatterImpl) format(input []byte, output io.Writer, indent string) {
   )
 
   writeIndent := func(depth int) {
+    line := bytes.Repeat([]byte(indent), depth)
     if skipFirstNewline {
       skipFirstNewline = false
     } else {
-      output.Write([]byte("\n"))
+      line = append([]byte{'\n'}, line...)
     }
 
-    output.Write(bytes.Repeat([]byte(indent), depth))
+    output.token(tokenText, line)
   }
 
   for {
@@ -271,14 +725,22 @@ func (htmlFormatterImpl) format(input []byte, output io.Writer, indent string) {
         writeIndent(depth)
       }
 
-      output.Write(tokenizer.Raw())
+      markupTagTokens(tokenizer.Raw(), output)
       if !isSelfClosingTag(tagName) {
         depth++
       }
 
-    case xhtml.SelfClosingTagToken, xhtml.CommentToken, xhtml.DoctypeToken:
+    case xhtml.SelfClosingTagToken:
+      writeIndent(depth)
+      markupTagTokens(tokenizer.Raw(), output)
+
+    case xhtml.CommentToken:
       writeIndent(depth)
-      output.Write(tokenizer.Raw())
+      output.token(tokenComment, tokenizer.Raw())
+
+    case xhtml.DoctypeToken:
+      writeIndent(depth)
+      output.token(tokenDeclaration, tokenizer.Raw())
 
     case xhtml.EndTagToken:
       if depth > 0 {
@@ -290,7 +752,7 @@ func (htmlFormatterImpl) format(input []byte, output io.Writer, indent string) {
         writeIndent(depth)
       }
 
-      output.Write(tokenizer.Raw())
+      markupTagTokens(tokenizer.Raw(), output)
 
     case xhtml.TextToken:
       t := bytes.Replace(tokenizer.Raw(), []byte{'\t'}, []byte(indent), -1)
@@ -309,7 +771,7 @@ func (htmlFormatterImpl) format(input []byte, output io.Writer, indent string) {
             text = append([]byte{' '}, text...)
           }
 
-          output.Write(adjustTextIndentation(text, depth))
+          output.token(tokenText, adjustTextIndentation(text, depth))
           longText = true
         } else {
           if utf8.RuneCount(text) > 80 || prevType != xhtml.StartTagToken {
@@ -320,7 +782,7 @@ func (htmlFormatterImpl) format(input []byte, output io.Writer, indent string) {
               text = append([]byte{' '}, text...)
             }
           }
-          output.Write(text)
+          output.token(tokenText, text)
         }
       }
     }
@@ -328,3 +790,125 @@ func (htmlFormatterImpl) format(input []byte, output io.Writer, indent string) {
     prevType = tokenType
   }
 }
+
+type yamlFormatterImpl struct{}
+
+var (
+  // yamlReKey matches the key of a mapping entry, quoted or not, up to its colon.
+  yamlReKey = regexp.MustCompile(`^("(?:[^"\\]|\\.)*"|'(?:[^']|'')*'|[^\s#'"\[\]{},&*!|>%@` + "`" + `][^#]*?)(\s*)(:)(?:\s|$)`)
+
+  // yamlReNumber matches a number scalar, such as 42, -1.5e3, 0x1F or .inf.
+  yamlReNumber = regexp.MustCompile(`^[-+]?(?:\.inf|\.Inf|\.INF|\.nan|\.NaN|0x[0-9a-fA-F]+|0o[0-7]+|[0-9][0-9_]*(?:\.[0-9]*)?(?:[eE][-+]?[0-9]+)?|\.[0-9]+(?:[eE][-+]?[0-9]+)?)$`)
+
+  // yamlReBlockIndicator matches the indicator of a literal or a folded block scalar, such as | or >-.
+  yamlReBlockIndicator = regexp.MustCompile(`^[|>][-+0-9]*$`)
+
+  // yamlLiterals contains the scalars that are booleans or null.
+  yamlLiterals = map[string]struct{}{
+    "true": {}, "True": {}, "TRUE": {}, "false": {}, "False": {}, "FALSE": {},
+    "null": {}, "Null": {}, "NULL": {}, "~": {},
+  }
+)
+
+// format trims left and right spaces from input and returns the actual content as is, like the text
+// formatter does: YAML is meant to be read as it is written.
+func (f yamlFormatterImpl) format(input []byte, output io.Writer, indent string) {
+  formatTokens(f, input, output, indent)
+}
+
+// tokens writes what format does, split line by line into keys, scalars, comments, sequence dashes and
+// document markers. The lines of block scalars are strings.
+func (yamlFormatterImpl) tokens(input []byte, output tokenWriter, _ string) {
+  block := -1 /* The indentation of the line that started a block scalar, or -1.  */
+  for line := range bytes.Lines(bytes.TrimSpace(input)) {
+    content := bytes.TrimRight(line, "\r\n")
+    rest := bytes.TrimLeft(content, " \t")
+    indentation := len(content) - len(rest)
+    output.token(tokenText, content[:indentation])
+
+    if block >= 0 && (0 == len(rest) || indentation > block) {
+      output.token(tokenString, rest)
+      output.token(tokenText, line[len(content):])
+      continue
+    }
+
+    block = -1
+    switch {
+    case bytes.HasPrefix(rest, []byte("#")):
+      output.token(tokenComment, rest)
+      rest = nil
+    case 0 == indentation && (bytes.HasPrefix(rest, []byte("---")) || bytes.HasPrefix(rest, []byte("..."))):
+      output.token(tokenDeclaration, rest[:3])
+      rest = rest[3:]
+    case 0 == indentation && bytes.HasPrefix(rest, []byte("%")):
+      output.token(tokenDeclaration, rest)
+      rest = nil
+    }
+
+    for bytes.Equal(rest, []byte("-")) || bytes.HasPrefix(rest, []byte("- ")) {
+      output.token(tokenPunctuation, rest[:1])
+      blank := len(rest[1:]) - len(bytes.TrimLeft(rest[1:], " \t"))
+      output.token(tokenText, rest[1:1+blank])
+      rest = rest[1+blank:]
+    }
+
+    if match := yamlReKey.FindSubmatchIndex(rest); nil != match {
+      output.token(tokenKey, rest[:match[3]])
+      output.token(tokenText, rest[match[4]:match[5]])
+      output.token(tokenPunctuation, rest[match[6]:match[7]])
+      rest = rest[match[7]:]
+    }
+
+    if yamlValueTokens(rest, output) {
+      block = indentation
+    }
+
+    output.token(tokenText, line[len(content):])
+  }
+}
+
+// yamlValueTokens writes the tokens of value, which is a scalar followed by an optional comment, and
+// reports whether it starts a block scalar.
+func yamlValueTokens(value []byte, output tokenWriter) (block bool) {
+  scalar := bytes.TrimLeft(value, " \t")
+  output.token(tokenText, value[:len(value)-len(scalar)])
+
+  comment := []byte(nil)
+  start := 0
+  if len(scalar) > 0 && ('"' == scalar[0] || '\'' == scalar[0]) {
+    if end := bytes.IndexByte(scalar[1:], scalar[0]); -1 != end {
+      start = end + 2
+    }
+  }
+
+  if bytes.HasPrefix(scalar, []byte("#")) {
+    scalar, comment = nil, scalar
+  } else if n := bytes.Index(scalar[start:], []byte(" #")); -1 != n {
+    scalar, comment = scalar[:start+n], scalar[start+n:]
+  }
+
+  trimmed := bytes.TrimRight(scalar, " \t")
+  kind := tokenString
+  switch _, literal := yamlLiterals[string(trimmed)]; {
+  case 0 == len(trimmed):
+    kind = tokenText
+  case literal:
+    kind = tokenLiteral
+  case yamlReNumber.Match(trimmed):
+    kind = tokenNumber
+  case yamlReBlockIndicator.Match(trimmed):
+    kind, block = tokenPunctuation, true
+  case '[' == trimmed[0] || '{' == trimmed[0]:
+    kind = tokenText /* A flow collection.  */
+  }
+
+  output.token(kind, trimmed)
+  output.token(tokenText, scalar[len(trimmed):])
+  if len(comment) > 0 {
+    blank := len(comment) - len(bytes.TrimLeft(comment, " \t"))
+    output.token(tokenText, comment[:blank])
+    output.token(tokenComment, comment[blank:])
+  }
+
+  return block
+}
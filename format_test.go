@@ -2,6 +2,9 @@ package playground
 
 import (
   "bytes"
+  "reflect"
+  "slices"
+  "strings"
   "testing"
 )
 
@@ -288,6 +291,148 @@ func TestFormatJSON(t *testing.T) {
   }
 }
 
+func TestFormatJSON_options(t *testing.T) {
+  tests := []struct {
+    formatter jsonFormatterImpl
+    input     string
+    want      string
+  }{
+    {jsonFormatterImpl{}, `{"id": 12345678901234567890123, "price": 1.10, "e": 1E+400}`, `{
+  "id": 12345678901234567890123,
+  "price": 1.10,
+  "e": 1E+400
+}`},
+    {jsonFormatterImpl{}, `{"a": "\u00e9\n", "b": [], "c": {}}`, `{
+  "a": "\u00e9\n",
+  "b": [],
+  "c": {}
+}`},
+    {jsonFormatterImpl{sortKeys: true}, `{"b": 1, "a": {"d": [{"z": 0, "y": 1}], "c": 2}, "b": 3}`, `{
+  "a": {
+    "c": 2,
+    "d": [
+      {
+        "y": 1,
+        "z": 0
+      }
+    ]
+  },
+  "b": 1,
+  "b": 3
+}`},
+    {jsonFormatterImpl{compact: true}, "{\n  \"a\": [1, 2],\n  \"b\": {\"c\": null}\n}\n[ ]", `{"a":[1,2],"b":{"c":null}}
+[]`},
+    {jsonFormatterImpl{sortKeys: true, compact: true}, `{"b": 1, "a": 2}`, `{"a":2,"b":1}`},
+    {jsonFormatterImpl{}, "  {\"a\": 1,\n \"b\": }  ", "{\"a\": 1,\n \"b\": }"},
+  }
+
+  for _, test := range tests {
+    buffer := bytes.Buffer{}
+    test.formatter.format([]byte(test.input), &buffer, "  ")
+    if got := buffer.String(); test.want != got {
+      t.Errorf("%+v.format(%q) =\n%s\nwant\n%s", test.formatter, test.input, got, test.want)
+    }
+  }
+}
+
+func TestFormatJSONLines(t *testing.T) {
+  tests := []struct {
+    formatter *jsonLinesFormatterImpl
+    input     string
+    want      string
+    warnings  []string
+  }{
+    {
+      jsonLinesFormatter,
+      "{\"level\": \"info\", \"n\": 12345678901234567890}\r\n\n{\"level\": \"error\",\n[1, 2]\n1 2\n{\"a\": 1, \"a\": 2}\n",
+      `# record 1, line 1
+{
+  "level": "info",
+  "n": 12345678901234567890
+}
+
+# record 2, line 3 (invalid)
+{"level": "error",
+
+# record 3, line 4
+[
+  1,
+  2
+]
+
+# record 4, line 5 (invalid)
+1 2
+
+# record 5, line 6
+{
+  "a": 1,
+  "a": 2
+}`,
+      []string{
+        "record 2: invalid JSON at line 3, column 19: unexpected end of JSON input",
+        "record 4 at line 5 holds 2 JSON texts instead of one",
+        `record 5: duplicate key "a" at line 6, column 10`,
+      },
+    },
+    {
+      jsonSequenceFormatter,
+      "\x1e{\"a\": 1}\n\x1e{\"b\":\n\x1e\n  [true]\n",
+      `# record 1, line 1
+{
+  "a": 1
+}
+
+# record 2, line 2 (invalid)
+{"b":
+
+# record 3, line 4
+[
+  true
+]`,
+      []string{"record 2: invalid JSON at line 2, column 7: unexpected end of JSON input"},
+    },
+    {
+      jsonSequenceFormatter,
+      "\x1e\n{\"a\": 1,\n \"a\": 2}",
+      "# record 1, line 2\n{\n  \"a\": 1,\n  \"a\": 2\n}",
+      []string{`record 1: duplicate key "a" at line 3, column 2`},
+    },
+    {jsonLinesFormatter, "\n\n", "", nil},
+  }
+
+  for _, test := range tests {
+    buffer := bytes.Buffer{}
+    test.formatter.format([]byte(test.input), &buffer, "  ")
+    if got := buffer.String(); test.want != got {
+      t.Errorf("format(%q) =\n%s\nwant\n%s", test.input, got, test.want)
+    }
+
+    if got := test.formatter.check([]byte(test.input)); !reflect.DeepEqual(test.warnings, got) {
+      t.Errorf("check(%q) = %q, want %q", test.input, got, test.warnings)
+    }
+  }
+}
+
+func TestFormatJSONLines_manyRecords(t *testing.T) {
+  input := []byte(strings.Repeat("{\"a\": 1, \"a\": 2}\n", 300000))
+
+  // The records are located as the body is split, and each is checked from the start of its own line,
+  // so the bytes gone through add up to no more than the body.
+  records, scanned := jsonLinesFormatter.records(input), 0
+  for record := range slices.Values(records) {
+    scanned += record.offset + len(record.text) - record.lineStart
+  }
+
+  if last := records[len(records)-1]; 300000 != len(records) || 300000 != last.line || scanned > len(input) {
+    t.Errorf("records() = %d records, the last at line %d, checked through %d bytes of %d", len(records), last.line, scanned, len(input))
+  }
+
+  warnings := jsonLinesFormatter.check(input)
+  if want := `record 2: duplicate key "a" at line 2, column 10`; len(warnings) < 2 || want != warnings[1] {
+    t.Errorf("check() = %q, want %q second", warnings[:min(len(warnings), 2)], want)
+  }
+}
+
 func TestFormatXML(t *testing.T) {
   tests := [...][2]string{
     {"", ""},
@@ -452,6 +597,76 @@ encoding="UTF-8"?>
     <gender>Male</gender>
   </person>
 </people_list>`},
+    {`<a>x</a>`, `<a>x</a>`},
+    {`<a><![CDATA[ <not> a tag ]]></a>`, `<a><![CDATA[ <not> a tag ]]></a>`},
+    {
+      `<?xml version="1.0"?><?xml-stylesheet   type="text/xsl" href="s.xsl"?><r/>`,
+      `<?xml version="1.0"?>
+<?xml-stylesheet type="text/xsl" href="s.xsl"?>
+<r/>`,
+    },
+    {
+      `<r><e expr="a > b" other='x"y'>t</e></r>`,
+      `<r>
+  <e expr="a > b" other='x"y'>t</e>
+</r>`,
+    },
+    {
+      `<p>Hello <b>big</b>   world !</p>`,
+      `<p>Hello
+  <b>big</b>
+  world !
+</p>`,
+    },
+    {
+      `<root><![CDATA[x < y]]><item><id>1</id></item>  text  <item xml:space="preserve"><id> 2 </id></item></root>`,
+      `<root><![CDATA[x < y]]>
+  <item>
+    <id>1</id>
+  </item>
+  text
+  <item xml:space="preserve"><id> 2 </id></item>
+</root>`,
+    },
+    {
+      `<root>text<a>1</a></root>`,
+      `<root>text
+  <a>1</a>
+</root>`,
+    },
+    {
+      `<doc><p xml:space="preserve">  keep   this  </p></doc>`,
+      `<doc>
+  <p xml:space="preserve">  keep   this  </p>
+</doc>`,
+    },
+    {
+      `<!DOCTYPE note [
+  <!ENTITY   writer "Writer: Donald Duck.">
+]><note>&writer;</note>`,
+      `<!DOCTYPE note [
+  <!ENTITY   writer "Writer: Donald Duck.">
+]>
+<note>&writer;</note>`,
+    },
+    {
+      `<soap:Envelope xmlns:soap="urn:s"   soap:encodingStyle = "urn:e"><soap:Body><m:x xmlns:m="urn:m">1</m:x></soap:Body></soap:Envelope>`,
+      `<soap:Envelope xmlns:soap="urn:s" soap:encodingStyle="urn:e">
+  <soap:Body>
+    <m:x xmlns:m="urn:m">1</m:x>
+  </soap:Body>
+</soap:Envelope>`,
+    },
+    {
+      `<a><b>1</b>tail<c/></a>`,
+      `<a>
+  <b>1</b>
+  tail
+  <c/>
+</a>`,
+    },
+    {`<a>if 1 < 2 then</a>`, `<a>if 1 < 2 then</a>`},
+    {`<a><b>unclosed`, "<a>\n  <b>unclosed"},
   }
 
   formatter := xmlFormatterImpl{}
@@ -2,17 +2,20 @@ package playground
 
 import (
   "context"
+  "encoding/base64"
+  "encoding/json"
   "errors"
   "fmt"
-  "html/template"
   "io"
   "log/slog"
+  "maps"
   "mime/multipart"
   "net/http"
   "net/url"
   "os"
   "path"
   "runtime"
+  "slices"
   "strings"
 )
 
@@ -30,11 +33,226 @@ func Scanner(ctx context.Context, w http.ResponseWriter, r *http.Request) {
     response.WriteError(err)
     response.DefaultHeaders()
   } else {
+    id := session(w, r)
+    checks := strings.Split(r.PostFormValue("checks"), "\n")
+    script := collections.script(id, r.PostFormValue("request_id"))
+    if nil == script { /* A request of no collection, which has neither scripts nor a name.  */
+      script = &scriptRun{scope: collections.scope(id)}
+    }
+
+    script.request = req
+    script.exec("prerequest")
+    req.variables = script.scope.variables()
+    refused := req.insecure && !AllowInsecure
+    req.insecure = req.insecure && AllowInsecure
     response = backend(ctx, req)
+    if refused {
+      response.Warn("this server does not skip the verification of TLS certificates, so they were verified")
+    }
+
+    var tests []assertionResult
+    if 0 != response.status {
+      set, warnings := extractVariables(strings.Split(r.PostFormValue("extract"), "\n"), response, script.scope)
+      for variable := range slices.Values(set) {
+        response.AddMeta("Variable", variable)
+      }
+
+      for warning := range slices.Values(warnings) {
+        response.Warn(warning)
+      }
+
+      script.response = response
+      script.exec("test")
+      tests = evaluateChecks(checks, response)
+    }
+
+    tests = append(tests, script.tests...)
+    collections.keepScope(id, script.scope)
+
+    for test := range slices.Values(tests) {
+      if test.Passed {
+        response.AddMeta("Test", fmt.Sprint("✓ ", test.Assertion))
+      } else {
+        response.AddMeta("Test", fmt.Sprint("✗ ", test.Assertion, ": ", test.Message))
+      }
+    }
+
+    archive.record(id, response.exchanges)
   }
 
   w.WriteHeader(http.StatusOK)
-  template.HTMLEscape(w, response.Bytes())
+  w.Write(response.HTML())
+}
+
+// Archive writes the exchanges made during the current playground session as an HTTP Archive (HAR 1.2) file.
+func Archive(w http.ResponseWriter, r *http.Request) {
+  w.Header().Set("Content-Type", "application/json; charset=utf-8")
+  w.Header().Set("Content-Disposition", `attachment; filename="playground.har"`)
+
+  if err := writeHAR(w, archive.export(session(w, r))); nil != err {
+    slog.Error("writeHAR(...) failed", slog.Group("error", slog.String("message", err.Error())))
+  }
+}
+
+// CurlImporter parses the curl command in the "curl" form value and writes the resulting request as JSON,
+// so that the website can fill the request form with it.
+func CurlImporter(w http.ResponseWriter, r *http.Request) {
+  req, warnings, err := parseCurl(r.PostFormValue("curl"))
+  if nil != err {
+    w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+    w.WriteHeader(http.StatusUnprocessableEntity)
+    fmt.Fprint(w, "Playground could not import your curl command: ", err.Error())
+    return
+  }
+
+  type pair struct {
+    Key   string `json:"key"`
+    Value string `json:"value"`
+  }
+
+  imported := struct {
+    Method   string   `json:"method"`
+    Target   string   `json:"target"`
+    Header   []pair   `json:"header"`
+    Body     string   `json:"body"`
+    Insecure bool     `json:"insecure"`
+    Warnings []string `json:"warnings"`
+  }{
+    Method:   req.method,
+    Target:   req.target.String(),
+    Header:   []pair{},
+    Body:     req.body,
+    Insecure: req.insecure,
+    Warnings: append([]string{}, warnings...),
+  }
+
+  for key := range slices.Values(slices.Sorted(maps.Keys(req.header))) {
+    for value := range slices.Values(req.header[key]) {
+      imported.Header = append(imported.Header, pair{Key: key, Value: value})
+    }
+  }
+
+  w.Header().Set("Content-Type", "application/json; charset=utf-8")
+  if err := json.NewEncoder(w).Encode(imported); nil != err {
+    slog.Error("json.NewEncoder(...).Encode(...) failed", slog.Group("error", slog.String("message", err.Error())))
+  }
+}
+
+// Snippet writes the code that sends the request of the request form in the language given by the
+// "language" query parameter, such as curl, go or python.
+func Snippet(w http.ResponseWriter, r *http.Request) {
+  w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+
+  req, err := parse(r)
+  if nil != err {
+    w.WriteHeader(http.StatusUnprocessableEntity)
+    fmt.Fprint(w, err.Error())
+    return
+  }
+
+  code, err := snippet(r.URL.Query().Get("language"), req)
+  if nil != err {
+    w.WriteHeader(http.StatusUnprocessableEntity)
+    fmt.Fprint(w, "Playground could not generate the code: ", err.Error())
+    return
+  }
+
+  fmt.Fprint(w, code)
+}
+
+// HTTPFileExporter converts the collection file in the "coll" form value, in any of the formats
+// that can be imported, into a .http file.
+func HTTPFileExporter(w http.ResponseWriter, r *http.Request) {
+  collfile, collfileheader, err := r.FormFile("coll")
+  if nil != err {
+    slog.Error("could not open collection file", slog.Group("error", slog.String("message", err.Error())))
+    http.Error(w, "Playground could not open file.", http.StatusBadRequest)
+    return
+  }
+
+  defer collfile.Close()
+
+  if 1024*1024 < collfileheader.Size {
+    http.Error(w, "Playground only accepts file sizes less than 1 MB.", http.StatusRequestEntityTooLarge)
+    return
+  }
+
+  c, err := importColl(collfile)
+  if nil != err {
+    slog.Error("could not import collection file", slog.Group("error", slog.String("message", err.Error())))
+    http.Error(w, "Playground could not read your file as a collection.", http.StatusUnprocessableEntity)
+    return
+  }
+
+  filename := strings.TrimSuffix(collfileheader.Filename, path.Ext(collfileheader.Filename))
+  w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+  w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", fmt.Sprint(filename, ".http")))
+
+  if err := writeHTTPFile(c, w); nil != err {
+    slog.Error("writeHTTPFile(...) failed", slog.Group("error", slog.String("message", err.Error())))
+  }
+}
+
+// CollectionExporter writes the collection imported in the current playground session as a Postman
+// Collection v2.1.0. The request body is a JSON object that maps the IDs of the edited requests to the
+// snapshots of the request form, encoded as form values, so that the edits are saved in the collection.
+func CollectionExporter(w http.ResponseWriter, r *http.Request) {
+  c := collections.load(session(w, r))
+  if nil == c {
+    http.Error(w, "Playground has no collection to save, import one first.", http.StatusNotFound)
+    return
+  }
+
+  var snapshots map[string]string
+  if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 5<<20)).Decode(&snapshots); nil != err && !errors.Is(err, io.EOF) {
+    slog.Error("could not decode the edits of the collection", slog.Group("error", slog.String("message", err.Error())))
+    http.Error(w, "Playground could not read your edits.", http.StatusBadRequest)
+    return
+  }
+
+  edits := make(map[string]url.Values, len(snapshots))
+  for id, snapshot := range snapshots {
+    form, err := url.ParseQuery(snapshot)
+    if nil != err {
+      http.Error(w, "Playground could not read your edits.", http.StatusBadRequest)
+      return
+    }
+
+    edits[id] = form
+  }
+
+  filename := c.Info.Name
+  if "" == filename {
+    filename = "collection"
+  }
+
+  w.Header().Set("Content-Type", "application/json; charset=utf-8")
+  w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", fmt.Sprint(filename, ".postman_collection.json")))
+
+  if err := writePostman(applyCollEdits(c, edits), w); nil != err {
+    slog.Error("writePostman(...) failed", slog.Group("error", slog.String("message", err.Error())))
+  }
+}
+
+// sessionCookie is the name of the cookie that identifies a playground session.
+const sessionCookie = "playground_session"
+
+// session returns the identifier of the playground session of r, starting a new session if r has none.
+func session(w http.ResponseWriter, r *http.Request) string {
+  if cookie, err := r.Cookie(sessionCookie); nil == err && "" != cookie.Value {
+    return cookie.Value
+  }
+
+  id := newString()
+  http.SetCookie(w, &http.Cookie{
+    Name:     sessionCookie,
+    Value:    id,
+    Path:     "/",
+    HttpOnly: true,
+    SameSite: http.SameSiteLaxMode,
+  })
+
+  return id
 }
 
 // Renderer renders the website template and writes it to the HTTP response writer.
@@ -61,11 +279,16 @@ func Renderer(w http.ResponseWriter, r *http.Request) {
   if "" != collname { /* Lookup file in collections folder.  */
     var (
       _, current, _, _ = runtime.Caller(0)
-      collfullname     = fmt.Sprint(path.Join(path.Dir(current), "public", "collections", collname), ".json")
+      collbasename     = path.Join(path.Dir(current), "public", "collections", collname)
       file             *os.File
     )
 
-    file, err = os.Open(collfullname)
+    for extension := range slices.Values([]string{".json", ".har", ".yaml", ".yml", ".http", ".rest", ".bru", ".zip"}) {
+      if file, err = os.Open(fmt.Sprint(collbasename, extension)); nil == err {
+        break
+      }
+    }
+
     if nil != err {
       slog.Error("could not open collection file", slog.Group("error", slog.String("message", err.Error())))
       abortWithAlert("Playground could find this collection :o")
@@ -94,11 +317,6 @@ func Renderer(w http.ResponseWriter, r *http.Request) {
       return
     }
 
-    if !strings.Contains(collfileheader.Header.Get("Content-Type"), "application/json") {
-      abortWithAlert("Playground only accepts `application/json` files.")
-      return
-    }
-
     if 1024*1024 < collfileheader.Size {
       abortWithAlert("Playground only accepts file sizes less than 1 MB.")
       return
@@ -107,13 +325,15 @@ func Renderer(w http.ResponseWriter, r *http.Request) {
 
   defer collfile.Close()
 
-  collsrc, colltree, err := collGen(collfile)
+  c, err := importColl(collfile)
   if nil != err {
     slog.Error("could not generate from collection file", slog.Group("error", slog.String("message", err.Error())))
-    abortWithAlert("Playground suffered an internal failure while processing your JSON file.")
+    abortWithAlert("Playground could not read your file as a collection or an OpenAPI specification.")
     return
   }
 
+  collsrc, colltree := collGen(c)
+  collections.keep(session(w, r), c)
   website(colltree, collsrc, "").Render(r.Context(), w)
 }
 
@@ -125,11 +345,45 @@ type request struct {
   // target is the URL to which the request is sent.
   target *url.URL
 
+  // rawTarget is the target as written, if it is no URL until its variable references are resolved,
+  // such as https://{{host}}/users, in which case target is empty.
+  rawTarget string
+
   // header contains the HTTP headers to be included in the request.
   header http.Header
 
   // The HTTP body of the request.
   body string
+
+  // pathVariables holds the values of the path variables, such as :id, used in the target.
+  pathVariables map[string]string
+
+  // insecure skips the verification of the TLS certificate of the target.
+  insecure bool
+
+  // variables are the ones that the references left in the request are resolved with, if it belongs to a collection.
+  variables map[string]collVariable
+
+  // filter is a JSON path or a jq filter that a JSON response body is reduced to, if not empty.
+  filter string
+
+  // highlight writes the response body as HTML whose tokens are highlighted, if its formatter can.
+  highlight bool
+
+  // sortKeys sorts the members of the objects of a JSON response body by their keys.
+  sortKeys bool
+
+  // compact writes a JSON response body without blanks.
+  compact bool
+
+  // sniff formats a response body by the type it looks like, if its Content-Type is missing or generic.
+  sniff bool
+
+  // descriptorSet is the FileDescriptorSet that a protobuf response body is decoded with, if not empty.
+  descriptorSet []byte
+
+  // protobufMessage is the name of the message type of a protobuf response body.
+  protobufMessage string
 }
 
 // parse extracts the HTTP method and target URL from an incoming HTTP request
@@ -146,6 +400,8 @@ func parse(r *http.Request) (*request, error) {
   method := r.PostFormValue("request_method")
   headerKeys := r.PostForm["header-key"]
   headerValues := r.PostForm["header-value"]
+  pathVariableKeys := r.PostForm["path-variable-key"]
+  pathVariableValues := r.PostForm["path-variable-value"]
   if len(r.PostForm["http-request-body"]) > 0 {
     if 5<<20 <= len(r.PostForm["http-request-body"][0]) {
       return nil, errors.New("request body too long")
@@ -163,12 +419,29 @@ func parse(r *http.Request) (*request, error) {
     req.header.Add(http.CanonicalHeaderKey(key), value)
   }
 
+  req.pathVariables = map[string]string{}
+  for n := range min(len(pathVariableKeys), len(pathVariableValues)) {
+    if key := strings.TrimSpace(pathVariableKeys[n]); "" != key {
+      req.pathVariables[key] = strings.TrimSpace(pathVariableValues[n])
+    }
+  }
+
   req.method = method
+  req.insecure = "true" == r.PostFormValue("insecure")
+  req.filter = strings.TrimSpace(r.PostFormValue("filter"))
+  req.highlight = "true" == r.PostFormValue("highlight")
+  req.sortKeys = "true" == r.PostFormValue("sort-keys")
+  req.compact = "true" == r.PostFormValue("compact")
+  req.sniff = "true" == r.PostFormValue("sniff")
+  req.protobufMessage = strings.TrimSpace(r.PostFormValue("protobuf-message"))
+  if descriptorSet := strings.TrimSpace(r.PostFormValue("protobuf-descriptor-set")); "" != descriptorSet {
+    if req.descriptorSet, err = base64.StdEncoding.DecodeString(descriptorSet); nil != err {
+      return nil, errors.New("invalid protobuf descriptor set")
+    }
+  }
 
-  req.target, err = url.Parse(target)
-  if nil != err {
-    slog.Error(err.Error())
-    req.target = &url.URL{}
+  if req.target, req.rawTarget, err = parseTarget(target); nil != err {
+    return nil, err
   }
 
   return req, nil
@@ -0,0 +1,577 @@
+package playground
+
+import (
+  "bytes"
+  "crypto/tls"
+  "encoding/base64"
+  "encoding/json"
+  "errors"
+  "fmt"
+  "io"
+  "net"
+  "net/http"
+  "net/http/httptrace"
+  "net/url"
+  "slices"
+  "strings"
+  "sync"
+  "time"
+  "unicode/utf8"
+)
+
+// harNameValue is a name-value pair of a header, query string or form parameter in a HAR file.
+type harNameValue struct {
+  Name  string `json:"name"`
+  Value string `json:"value"`
+}
+
+// harCookie is a cookie sent or received in a HAR entry.
+type harCookie struct {
+  Name     string `json:"name"`
+  Value    string `json:"value"`
+  Path     string `json:"path,omitempty"`
+  Domain   string `json:"domain,omitempty"`
+  Expires  string `json:"expires,omitempty"`
+  HTTPOnly bool   `json:"httpOnly"`
+  Secure   bool   `json:"secure"`
+}
+
+// harPostData is the body of a HAR request.
+type harPostData struct {
+  MimeType string         `json:"mimeType"`
+  Text     string         `json:"text"`
+  Params   []harNameValue `json:"params,omitempty"`
+  Comment  string         `json:"comment,omitempty"`
+}
+
+// harRequest is the request of a HAR entry.
+type harRequest struct {
+  Method      string         `json:"method"`
+  URL         string         `json:"url"`
+  HTTPVersion string         `json:"httpVersion"`
+  Cookies     []harCookie    `json:"cookies"`
+  Headers     []harNameValue `json:"headers"`
+  QueryString []harNameValue `json:"queryString"`
+  PostData    *harPostData   `json:"postData,omitempty"`
+  HeadersSize int64          `json:"headersSize"`
+  BodySize    int64          `json:"bodySize"`
+}
+
+// harContent is the body of a HAR response.
+type harContent struct {
+  Size     int64  `json:"size"`
+  MimeType string `json:"mimeType"`
+  Text     string `json:"text,omitempty"`
+  Encoding string `json:"encoding,omitempty"` // base64 if the body is not UTF-8 text.
+  Comment  string `json:"comment,omitempty"`
+}
+
+// harResponse is the response of a HAR entry.
+type harResponse struct {
+  Status      int            `json:"status"`
+  StatusText  string         `json:"statusText"`
+  HTTPVersion string         `json:"httpVersion"`
+  Cookies     []harCookie    `json:"cookies"`
+  Headers     []harNameValue `json:"headers"`
+  Content     harContent     `json:"content"`
+  RedirectURL string         `json:"redirectURL"`
+  HeadersSize int64          `json:"headersSize"`
+  BodySize    int64          `json:"bodySize"`
+}
+
+// harTimings holds the duration in milliseconds of every phase of an exchange; -1 means that the
+// phase does not apply, e.g., dns when a connection was reused.
+type harTimings struct {
+  Blocked float64 `json:"blocked"`
+  DNS     float64 `json:"dns"`
+  Connect float64 `json:"connect"` // Includes SSL.
+  SSL     float64 `json:"ssl"`
+  Send    float64 `json:"send"`
+  Wait    float64 `json:"wait"`
+  Receive float64 `json:"receive"`
+}
+
+// harEntry is a single request/response exchange.
+type harEntry struct {
+  StartedDateTime time.Time   `json:"startedDateTime"`
+  Time            float64     `json:"time"`
+  Request         harRequest  `json:"request"`
+  Response        harResponse `json:"response"`
+  Cache           struct{}    `json:"cache"`
+  Timings         harTimings  `json:"timings"`
+  ServerIPAddress string      `json:"serverIPAddress,omitempty"`
+}
+
+// har holds the fields of the HTTP Archive format v1.2.
+type har struct {
+  Log struct {
+    Version string `json:"version"`
+    Creator struct {
+      Name    string `json:"name"`
+      Version string `json:"version"`
+    } `json:"creator"`
+    Entries []harEntry `json:"entries"`
+  } `json:"log"`
+}
+
+const (
+  // maxArchivedBodyBytes is the largest response body kept in an archived HAR entry.
+  maxArchivedBodyBytes = 256 << 10 // 256 KB
+
+  // maxArchivedEntries is the number of exchanges archived for every session.
+  maxArchivedEntries = 100
+
+  // maxArchivedSessions is the number of sessions archived at once; the least recently used ones are evicted.
+  maxArchivedSessions = 256
+
+  // maxArchivedBytes bounds the size of the exchanges archived across all the sessions; the least
+  // recently used sessions are evicted to stay under it.
+  maxArchivedBytes = 64 << 20 // 64 MB
+)
+
+var errNotHAR = errors.New("not an HTTP Archive")
+
+// newHAR returns an HTTP Archive holding entries.
+func newHAR(entries []harEntry) *har {
+  h := &har{}
+  h.Log.Version = "1.2"
+  h.Log.Creator.Name = "fontseca.dev/playground"
+  h.Log.Creator.Version = "1.0"
+  h.Log.Entries = entries
+
+  if nil == h.Log.Entries {
+    h.Log.Entries = []harEntry{}
+  }
+
+  return h
+}
+
+// parseHAR parses a JSON document as an HTTP Archive. It returns errNotHAR if the document is valid
+// JSON but not an HTTP Archive.
+func parseHAR(input []byte) (*har, error) {
+  var probe struct {
+    Log *struct {
+      Entries json.RawMessage `json:"entries"`
+    } `json:"log"`
+  }
+
+  if err := json.Unmarshal(input, &probe); nil != err {
+    return nil, err
+  }
+
+  if nil == probe.Log || nil == probe.Log.Entries {
+    return nil, errNotHAR
+  }
+
+  h := &har{}
+  if err := json.Unmarshal(input, h); nil != err {
+    return nil, err
+  }
+
+  return h, nil
+}
+
+// harToColl converts the entries of an HTTP Archive into a collection with one folder per host.
+func harToColl(h *har) *coll {
+  c := &coll{}
+  c.Info.Name = fmt.Sprint(h.Log.Creator.Name, " HAR")
+
+  var (
+    folders = map[string]*collItem{}
+    order   []string
+  )
+
+  for entry := range slices.Values(h.Log.Entries) {
+    target, err := url.Parse(entry.Request.URL)
+    if nil != err {
+      continue
+    }
+
+    if _, exists := folders[target.Host]; !exists {
+      folders[target.Host] = &collItem{Name: target.Host}
+      order = append(order, target.Host)
+    }
+
+    req := &collRequest{
+      URL: collURL{
+        Raw:      entry.Request.URL,
+        Protocol: target.Scheme,
+        Host:     strings.Split(target.Hostname(), "."),
+        Path:     strings.Split(strings.TrimPrefix(target.Path, "/"), "/"),
+        Port:     target.Port(),
+      },
+      Method: entry.Request.Method,
+      Header: []collHeader{},
+    }
+
+    for q := range slices.Values(entry.Request.QueryString) {
+      req.URL.Query = append(req.URL.Query, collQueryParam{Key: q.Name, Value: q.Value})
+    }
+
+    for header := range slices.Values(entry.Request.Headers) {
+      if strings.HasPrefix(header.Name, ":") { /* HTTP/2 pseudo-headers.  */
+        continue
+      }
+
+      req.Header = append(req.Header, collHeader{Key: header.Name, Value: header.Value})
+    }
+
+    if postData := entry.Request.PostData; nil != postData {
+      if len(postData.Params) > 0 && strings.HasPrefix(postData.MimeType, "application/x-www-form-urlencoded") {
+        req.Body = &collBody{Mode: "urlencoded"}
+        for p := range slices.Values(postData.Params) {
+          req.Body.URLEncoded = append(req.Body.URLEncoded, collURLEncodedParameter{Key: p.Name, Value: p.Value})
+        }
+      } else {
+        req.Body = &collBody{Mode: "raw", Raw: postData.Text}
+      }
+    }
+
+    folders[target.Host].Item = append(folders[target.Host].Item, collItem{
+      Name:    fmt.Sprint(entry.Request.Method, " ", target.EscapedPath()),
+      Request: req,
+    })
+  }
+
+  for host := range slices.Values(order) {
+    c.Item = append(c.Item, *folders[host])
+  }
+
+  return c
+}
+
+// harHeaders converts HTTP headers into HAR name-value pairs sorted by name.
+func harHeaders(header http.Header) []harNameValue {
+  pairs := []harNameValue{}
+  for name := range slices.Values(slices.Sorted(func(yield func(string) bool) {
+    for name := range header {
+      if !yield(name) {
+        return
+      }
+    }
+  })) {
+    for value := range slices.Values(header[name]) {
+      pairs = append(pairs, harNameValue{Name: name, Value: value})
+    }
+  }
+
+  return pairs
+}
+
+// harCookies converts HTTP cookies into HAR cookies.
+func harCookies(cookies []*http.Cookie) []harCookie {
+  converted := []harCookie{}
+  for cookie := range slices.Values(cookies) {
+    c := harCookie{
+      Name:     cookie.Name,
+      Value:    cookie.Value,
+      Path:     cookie.Path,
+      Domain:   cookie.Domain,
+      HTTPOnly: cookie.HttpOnly,
+      Secure:   cookie.Secure,
+    }
+
+    if !cookie.Expires.IsZero() {
+      c.Expires = cookie.Expires.UTC().Format(time.RFC3339)
+    }
+
+    converted = append(converted, c)
+  }
+
+  return converted
+}
+
+// milliseconds returns the duration between from and to in milliseconds, or -1 if any of them is unknown.
+func milliseconds(from, to time.Time) float64 {
+  if from.IsZero() || to.IsZero() {
+    return -1
+  }
+
+  return float64(to.Sub(from).Microseconds()) / 1000
+}
+
+// A harRecorder is an http.RoundTripper that records every exchange made through it, including
+// the ones of a redirect chain, as HAR entries.
+type harRecorder struct {
+  next    http.RoundTripper
+  entries []harEntry
+
+  // finalAt is the moment the headers of the last response were received.
+  finalAt time.Time
+}
+
+// RoundTrip sends req through the next http.RoundTripper and records the exchange.
+func (h *harRecorder) RoundTrip(req *http.Request) (*http.Response, error) {
+  var (
+    mu                                 sync.Mutex
+    dnsStart, dnsDone                  time.Time
+    connectStart, connectDone          time.Time
+    tlsStart, tlsDone                  time.Time
+    gotConn, wroteRequest, gotResponse time.Time
+    serverIP                           string
+    start                              = time.Now()
+  )
+
+  stamp := func(t *time.Time) {
+    mu.Lock()
+    defer mu.Unlock()
+    *t = time.Now()
+  }
+
+  trace := &httptrace.ClientTrace{
+    DNSStart:             func(httptrace.DNSStartInfo) { stamp(&dnsStart) },
+    DNSDone:              func(httptrace.DNSDoneInfo) { stamp(&dnsDone) },
+    ConnectStart:         func(string, string) { stamp(&connectStart) },
+    ConnectDone:          func(string, string, error) { stamp(&connectDone) },
+    TLSHandshakeStart:    func() { stamp(&tlsStart) },
+    TLSHandshakeDone:     func(tls.ConnectionState, error) { stamp(&tlsDone) },
+    WroteRequest:         func(httptrace.WroteRequestInfo) { stamp(&wroteRequest) },
+    GotFirstResponseByte: func() { stamp(&gotResponse) },
+    GotConn: func(info httptrace.GotConnInfo) {
+      stamp(&gotConn)
+      mu.Lock()
+      defer mu.Unlock()
+      serverIP = info.Conn.RemoteAddr().String()
+    },
+  }
+
+  entry := harEntry{
+    StartedDateTime: start,
+    Request: harRequest{
+      Method:      req.Method,
+      URL:         req.URL.String(),
+      HTTPVersion: req.Proto,
+      Cookies:     harCookies(req.Cookies()),
+      Headers:     harHeaders(req.Header),
+      QueryString: []harNameValue{},
+      HeadersSize: -1,
+      BodySize:    0,
+    },
+  }
+
+  for name, values := range req.URL.Query() {
+    for value := range slices.Values(values) {
+      entry.Request.QueryString = append(entry.Request.QueryString, harNameValue{Name: name, Value: value})
+    }
+  }
+
+  slices.SortStableFunc(entry.Request.QueryString, func(a, b harNameValue) int { return strings.Compare(a.Name, b.Name) })
+
+  if nil != req.GetBody {
+    if body, err := req.GetBody(); nil == err {
+      text, _ := io.ReadAll(io.LimitReader(body, maxBodyBytes))
+      _ = body.Close()
+      entry.Request.BodySize = int64(len(text))
+      entry.Request.PostData = &harPostData{MimeType: req.Header.Get("Content-Type"), Text: string(text)}
+      if len(text) > maxArchivedBodyBytes {
+        entry.Request.PostData.Text = ""
+        entry.Request.PostData.Comment = fmt.Sprintf("body of %d bytes was not archived", len(text))
+      }
+    }
+  }
+
+  res, err := h.next.RoundTrip(req.WithContext(httptrace.WithClientTrace(req.Context(), trace)))
+  if nil != err {
+    return nil, err
+  }
+
+  mu.Lock()
+  defer mu.Unlock()
+
+  if host, _, err := net.SplitHostPort(serverIP); nil == err {
+    entry.ServerIPAddress = host
+  }
+  entry.Response = harResponse{
+    Status:      res.StatusCode,
+    StatusText:  strings.TrimSpace(strings.TrimPrefix(res.Status, fmt.Sprint(res.StatusCode))),
+    HTTPVersion: res.Proto,
+    Cookies:     harCookies(res.Cookies()),
+    Headers:     harHeaders(res.Header),
+    Content:     harContent{Size: max(res.ContentLength, 0), MimeType: res.Header.Get("Content-Type")},
+    RedirectURL: res.Header.Get("Location"),
+    HeadersSize: -1,
+    BodySize:    res.ContentLength,
+  }
+
+  if !connectDone.IsZero() && !tlsDone.IsZero() {
+    connectDone = tlsDone
+  }
+
+  entry.Timings = harTimings{
+    DNS:     milliseconds(dnsStart, dnsDone),
+    Connect: milliseconds(connectStart, connectDone),
+    SSL:     milliseconds(tlsStart, tlsDone),
+    Send:    max(milliseconds(gotConn, wroteRequest), 0),
+    Wait:    max(milliseconds(wroteRequest, gotResponse), 0),
+    Receive: 0,
+  }
+
+  entry.Timings.Blocked = max(milliseconds(start, gotConn)-max(entry.Timings.DNS, 0)-max(entry.Timings.Connect, 0), 0)
+  entry.Time = entry.Timings.Blocked + max(entry.Timings.DNS, 0) + max(entry.Timings.Connect, 0) +
+    entry.Timings.Send + entry.Timings.Wait
+
+  h.entries = append(h.entries, entry)
+  h.finalAt = time.Now()
+  return res, nil
+}
+
+// finish records the body of the last response, which is read once the exchange is over. body is
+// kept as it was received, in base64 unless it is UTF-8 text.
+func (h *harRecorder) finish(body []byte) {
+  if 0 == len(h.entries) {
+    return
+  }
+
+  last := &h.entries[len(h.entries)-1]
+  last.Timings.Receive = milliseconds(h.finalAt, time.Now())
+  last.Time += last.Timings.Receive
+  last.Response.Content.Size = int64(len(body))
+
+  if len(body) > maxArchivedBodyBytes {
+    last.Response.Content.Comment = fmt.Sprintf("body of %d bytes was not archived", len(body))
+    return
+  }
+
+  if !utf8.Valid(body) {
+    last.Response.Content.Text, last.Response.Content.Encoding = base64.StdEncoding.EncodeToString(body), "base64"
+    return
+  }
+
+  last.Response.Content.Text = string(body)
+}
+
+// harArchive keeps the latest exchanges of every playground session in memory, so that they can
+// be exported as HAR files.
+type harArchive struct {
+  mu       sync.Mutex
+  sessions map[string]*harSession
+  bytes    int // The size of the exchanges of all the sessions.
+}
+
+// harSession holds the archived exchanges of a playground session.
+type harSession struct {
+  entries  []harEntry
+  bytes    int
+  lastUsed time.Time
+}
+
+// size approximates the memory held by the strings of e.
+func (e *harEntry) size() int {
+  n := len(e.Request.URL) + len(e.Response.Content.Text) + len(e.Response.RedirectURL)
+  if nil != e.Request.PostData {
+    n += len(e.Request.PostData.Text)
+    for p := range slices.Values(e.Request.PostData.Params) {
+      n += len(p.Name) + len(p.Value)
+    }
+  }
+
+  for pairs := range slices.Values([][]harNameValue{e.Request.Headers, e.Request.QueryString, e.Response.Headers}) {
+    for pair := range slices.Values(pairs) {
+      n += len(pair.Name) + len(pair.Value)
+    }
+  }
+
+  for cookies := range slices.Values([][]harCookie{e.Request.Cookies, e.Response.Cookies}) {
+    for cookie := range slices.Values(cookies) {
+      n += len(cookie.Name) + len(cookie.Value) + len(cookie.Path) + len(cookie.Domain)
+    }
+  }
+
+  return n
+}
+
+var archive = &harArchive{sessions: map[string]*harSession{}}
+
+// record appends entries to the archive of session, evicting the oldest exchanges and sessions.
+func (a *harArchive) record(session string, entries []harEntry) {
+  if "" == session || 0 == len(entries) {
+    return
+  }
+
+  a.mu.Lock()
+  defer a.mu.Unlock()
+
+  s, exists := a.sessions[session]
+  if !exists {
+    if len(a.sessions) >= maxArchivedSessions {
+      a.evict(session)
+    }
+
+    s = &harSession{}
+    a.sessions[session] = s
+  }
+
+  s.lastUsed = time.Now()
+  for entry := range slices.Values(entries) {
+    s.bytes += entry.size()
+    a.bytes += entry.size()
+  }
+
+  s.entries = append(s.entries, entries...)
+
+  var dropped int
+  drop := func() {
+    size := s.entries[dropped].size()
+    s.bytes, a.bytes, dropped = s.bytes-size, a.bytes-size, dropped+1
+  }
+
+  for len(s.entries)-dropped > maxArchivedEntries {
+    drop()
+  }
+
+  for a.bytes > maxArchivedBytes && a.evict(session) { /* Other sessions go first.  */
+  }
+
+  for a.bytes > maxArchivedBytes && dropped < len(s.entries) { /* The session alone is too large.  */
+    drop()
+  }
+
+  if dropped > 0 {
+    s.entries = slices.Clone(s.entries[dropped:])
+  }
+}
+
+// evict removes the least recently used session other than keep, reporting whether there was one;
+// a.mu must be held.
+func (a *harArchive) evict(keep string) bool {
+  var oldest string
+  for id, candidate := range a.sessions {
+    if keep != id && ("" == oldest || candidate.lastUsed.Before(a.sessions[oldest].lastUsed)) {
+      oldest = id
+    }
+  }
+
+  if "" == oldest {
+    return false
+  }
+
+  a.bytes -= a.sessions[oldest].bytes
+  delete(a.sessions, oldest)
+  return true
+}
+
+// export returns the archived exchanges of session as an HTTP Archive.
+func (a *harArchive) export(session string) *har {
+  a.mu.Lock()
+  defer a.mu.Unlock()
+
+  if s, exists := a.sessions[session]; exists {
+    return newHAR(slices.Clone(s.entries))
+  }
+
+  return newHAR(nil)
+}
+
+// writeHAR writes h as indented JSON.
+func writeHAR(w io.Writer, h *har) error {
+  buffer := &bytes.Buffer{}
+  encoder := json.NewEncoder(buffer)
+  encoder.SetIndent("", "  ")
+
+  if err := encoder.Encode(h); nil != err {
+    return err
+  }
+
+  _, err := w.Write(buffer.Bytes())
+  return err
+}
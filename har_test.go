@@ -0,0 +1,307 @@
+package playground
+
+import (
+  "bytes"
+  "context"
+  "encoding/base64"
+  "fmt"
+  "github.com/google/go-cmp/cmp"
+  "net/http"
+  "net/http/httptest"
+  "net/url"
+  "reflect"
+  "strings"
+  "testing"
+  "unicode/utf8"
+)
+
+func TestImportColl_HAR(t *testing.T) {
+  archive := `{
+  "log": {
+    "version": "1.2",
+    "creator": {"name": "WebInspector", "version": "537.36"},
+    "entries": [
+      {
+        "request": {
+          "method": "GET",
+          "url": "https://api.example.com/users?page=2",
+          "headers": [{"name": ":authority", "value": "api.example.com"}, {"name": "Accept", "value": "application/json"}],
+          "queryString": [{"name": "page", "value": "2"}]
+        },
+        "response": {"status": 200}
+      },
+      {
+        "request": {
+          "method": "POST",
+          "url": "https://auth.example.com/login",
+          "headers": [{"name": "Content-Type", "value": "application/x-www-form-urlencoded"}],
+          "queryString": [],
+          "postData": {"mimeType": "application/x-www-form-urlencoded", "text": "user=jane", "params": [{"name": "user", "value": "jane"}]}
+        },
+        "response": {"status": 302}
+      },
+      {
+        "request": {
+          "method": "PUT",
+          "url": "https://api.example.com:8443/users/5",
+          "headers": [],
+          "queryString": [],
+          "postData": {"mimeType": "application/json", "text": "{\"name\":\"Jane\"}"}
+        },
+        "response": {"status": 204}
+      }
+    ]
+  }
+}`
+
+  got, err := importColl(strings.NewReader(archive))
+  if nil != err {
+    t.Fatalf("unexpected error: %s", err)
+  }
+
+  want := &coll{
+    Item: []collItem{
+      {
+        Name: "api.example.com",
+        Item: []collItem{
+          {
+            Name: "GET /users",
+            Request: &collRequest{
+              URL: collURL{
+                Raw:      "https://api.example.com/users?page=2",
+                Protocol: "https",
+                Host:     []string{"api", "example", "com"},
+                Path:     []string{"users"},
+                Query:    []collQueryParam{{Key: "page", Value: "2"}},
+              },
+              Method: "GET",
+              Header: []collHeader{{Key: "Accept", Value: "application/json"}},
+            },
+          },
+        },
+      },
+      {
+        Name: "auth.example.com",
+        Item: []collItem{
+          {
+            Name: "POST /login",
+            Request: &collRequest{
+              URL:    collURL{Raw: "https://auth.example.com/login", Protocol: "https", Host: []string{"auth", "example", "com"}, Path: []string{"login"}},
+              Method: "POST",
+              Header: []collHeader{{Key: "Content-Type", Value: "application/x-www-form-urlencoded"}},
+              Body:   &collBody{Mode: "urlencoded", URLEncoded: []collURLEncodedParameter{{Key: "user", Value: "jane"}}},
+            },
+          },
+        },
+      },
+      {
+        Name: "api.example.com:8443",
+        Item: []collItem{
+          {
+            Name: "PUT /users/5",
+            Request: &collRequest{
+              URL:    collURL{Raw: "https://api.example.com:8443/users/5", Protocol: "https", Host: []string{"api", "example", "com"}, Path: []string{"users", "5"}, Port: "8443"},
+              Method: "PUT",
+              Header: []collHeader{},
+              Body:   &collBody{Mode: "raw", Raw: `{"name":"Jane"}`},
+            },
+          },
+        },
+      },
+    },
+  }
+  want.Info.Name = "WebInspector HAR"
+
+  if !reflect.DeepEqual(want, got) {
+    t.Fatal(cmp.Diff(want, got))
+  }
+}
+
+func TestBackend_recordsExchanges(t *testing.T) {
+  server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+    switch r.URL.Path {
+    case "/login":
+      http.SetCookie(w, &http.Cookie{Name: "session", Value: "abc", Path: "/", HttpOnly: true})
+      http.Redirect(w, r, "/me", http.StatusFound)
+    case "/me":
+      w.Header().Set("Content-Type", "application/json")
+      fmt.Fprint(w, `{"name":"Jane"}`)
+    }
+  }))
+  defer server.Close()
+
+  target, _ := url.Parse(fmt.Sprint(server.URL, "/login?next=me"))
+  response := backend(context.Background(), &request{
+    method: http.MethodPost,
+    target: target,
+    header: http.Header{"Content-Type": {"application/json"}, "Cookie": {"theme=dark"}},
+    body:   `{"user":"jane"}`,
+  })
+
+  if 2 != len(response.exchanges) {
+    t.Fatalf("len(response.exchanges) = %d, want 2", len(response.exchanges))
+  }
+
+  first, last := response.exchanges[0], response.exchanges[1]
+
+  if http.StatusFound != first.Response.Status || "/me" != first.Response.RedirectURL {
+    t.Errorf("first exchange = %d redirecting to %q, want 302 redirecting to \"/me\"", first.Response.Status, first.Response.RedirectURL)
+  }
+
+  if want := []harNameValue{{Name: "next", Value: "me"}}; !reflect.DeepEqual(want, first.Request.QueryString) {
+    t.Errorf("first.Request.QueryString = %v, want %v", first.Request.QueryString, want)
+  }
+
+  if nil == first.Request.PostData || `{"user":"jane"}` != first.Request.PostData.Text {
+    t.Errorf("first.Request.PostData = %+v, want the request body", first.Request.PostData)
+  }
+
+  if want := []harCookie{{Name: "theme", Value: "dark"}}; !reflect.DeepEqual(want, first.Request.Cookies) {
+    t.Errorf("first.Request.Cookies = %+v, want %+v", first.Request.Cookies, want)
+  }
+
+  if want := []harCookie{{Name: "session", Value: "abc", Path: "/", HTTPOnly: true}}; !reflect.DeepEqual(want, first.Response.Cookies) {
+    t.Errorf("first.Response.Cookies = %+v, want %+v", first.Response.Cookies, want)
+  }
+
+  if http.MethodGet != last.Request.Method || http.StatusOK != last.Response.Status || "OK" != last.Response.StatusText {
+    t.Errorf("last exchange = %s %d %q, want GET 200 \"OK\"", last.Request.Method, last.Response.Status, last.Response.StatusText)
+  }
+
+  if `{"name":"Jane"}` != last.Response.Content.Text || 15 != last.Response.Content.Size {
+    t.Errorf("last.Response.Content = %+v, want the response body", last.Response.Content)
+  }
+
+  if "127.0.0.1" != last.ServerIPAddress {
+    t.Errorf("last.ServerIPAddress = %q, want \"127.0.0.1\"", last.ServerIPAddress)
+  }
+
+  for n, e := range response.exchanges {
+    if e.Time < 0 || e.Timings.Send < 0 || e.Timings.Wait < 0 || e.Timings.Receive < 0 || e.Timings.Blocked < 0 {
+      t.Errorf("exchange %d has negative timings: %+v", n, e.Timings)
+    }
+  }
+}
+
+func TestBackend_recordsBinaryBodies(t *testing.T) {
+  bodies := map[string][]byte{
+    "application/msgpack":             {0x82, 0xa1, 'a', 0x01, 0xa1, 'b', 0xc3},
+    "text/plain; charset=iso-8859-1":  []byte("caf\xe9"),
+    "text/plain; charset=utf-8":       []byte("café"),
+    "application/json; charset=utf-8": []byte(`{"name":"Jane"}`),
+  }
+
+  server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+    contentType := r.URL.Query().Get("type")
+    w.Header().Set("Content-Type", contentType)
+    w.Write(bodies[contentType])
+  }))
+  defer server.Close()
+
+  for contentType, body := range bodies {
+    target, _ := url.Parse(fmt.Sprint(server.URL, "/?type=", url.QueryEscape(contentType)))
+    response := backend(context.Background(), &request{method: http.MethodGet, target: target, header: http.Header{}})
+
+    var buffer strings.Builder
+    if err := writeHAR(&buffer, newHAR(response.exchanges)); nil != err {
+      t.Fatalf("unexpected error: %s", err)
+    }
+
+    h, err := parseHAR([]byte(buffer.String()))
+    if nil != err || 1 != len(h.Log.Entries) {
+      t.Fatalf("%s: parseHAR(writeHAR(...)) = %+v, %v, want 1 entry", contentType, h, err)
+    }
+
+    content := h.Log.Entries[0].Response.Content
+    got := []byte(content.Text)
+    if "base64" == content.Encoding {
+      got, _ = base64.StdEncoding.DecodeString(content.Text)
+    }
+
+    if !bytes.Equal(body, got) || utf8.Valid(body) != ("" == content.Encoding) {
+      t.Errorf("%s: content = %+v, want %q", contentType, content, body)
+    }
+  }
+}
+
+func TestHARArchive(t *testing.T) {
+  a := &harArchive{sessions: map[string]*harSession{}}
+
+  for n := range maxArchivedEntries + 5 {
+    a.record("one", []harEntry{{Request: harRequest{URL: fmt.Sprint("https://fontseca.dev/", n)}}})
+  }
+
+  a.record("two", nil)
+
+  entries := a.export("one").Log.Entries
+  if maxArchivedEntries != len(entries) {
+    t.Fatalf("len(entries) = %d, want %d", len(entries), maxArchivedEntries)
+  }
+
+  if want := "https://fontseca.dev/5"; want != entries[0].Request.URL {
+    t.Errorf("entries[0].Request.URL = %q, want %q", entries[0].Request.URL, want)
+  }
+
+  if 0 != len(a.export("two").Log.Entries) || 0 != len(a.export("three").Log.Entries) {
+    t.Error("sessions without exchanges must export empty archives")
+  }
+
+  for n := range maxArchivedSessions {
+    a.record(fmt.Sprint("session-", n), []harEntry{{}})
+  }
+
+  if _, exists := a.sessions["one"]; exists {
+    t.Error("the least recently used session must be evicted")
+  }
+
+  var buffer strings.Builder
+  if err := writeHAR(&buffer, a.export("session-0")); nil != err {
+    t.Fatalf("unexpected error: %s", err)
+  }
+
+  got, err := parseHAR([]byte(buffer.String()))
+  if nil != err {
+    t.Fatalf("parseHAR(writeHAR(...)) failed: %s", err)
+  }
+
+  if "1.2" != got.Log.Version || 1 != len(got.Log.Entries) {
+    t.Errorf("round trip = %+v, want a HAR 1.2 log with 1 entry", got.Log)
+  }
+}
+
+func TestHARArchive_bytes(t *testing.T) {
+  a := &harArchive{sessions: map[string]*harSession{}}
+  body := harContent{Text: strings.Repeat("x", maxArchivedBodyBytes)}
+
+  for n := range maxArchivedSessions {
+    a.record(fmt.Sprint("session-", n), []harEntry{{Response: harResponse{Content: body}}, {Response: harResponse{Content: body}}})
+  }
+
+  total := 0
+  for _, s := range a.sessions {
+    total += s.bytes
+  }
+
+  if a.bytes > maxArchivedBytes || total != a.bytes {
+    t.Errorf("a.bytes = %d, sessions hold %d, want at most %d", a.bytes, total, maxArchivedBytes)
+  }
+
+  if _, exists := a.sessions["session-0"]; exists {
+    t.Error("the least recently used session must be evicted")
+  }
+
+  if 2 != len(a.export(fmt.Sprint("session-", maxArchivedSessions-1)).Log.Entries) {
+    t.Error("the most recently used session must be kept")
+  }
+
+  huge := harContent{Text: strings.Repeat("x", maxArchivedBytes)}
+  a.record("huge", []harEntry{{Response: harResponse{Content: huge}}, {Request: harRequest{URL: "https://fontseca.dev/"}}})
+
+  if entries := a.export("huge").Log.Entries; 1 != len(entries) || "https://fontseca.dev/" != entries[0].Request.URL {
+    t.Errorf("entries = %d, want only the exchange that fits", len(entries))
+  }
+
+  if 1 != len(a.sessions) || a.bytes != a.sessions["huge"].bytes {
+    t.Errorf("len(a.sessions) = %d, a.bytes = %d, want only the huge session", len(a.sessions), a.bytes)
+  }
+}
@@ -0,0 +1,159 @@
+package playground
+
+import (
+  "bufio"
+  "bytes"
+  "cmp"
+  "html/template"
+  "io"
+  "log/slog"
+  "strings"
+)
+
+// A tokenKind is the role of a piece of the output of a formatter, which it is colored by.
+type tokenKind string
+
+const (
+  tokenText        tokenKind = ""        // Plain text, such as blanks or the text of an element.
+  tokenKey         tokenKind = "key"     // The key of a JSON member or of a YAML mapping, or a CSS property.
+  tokenString      tokenKind = "string"  // A quoted string, a YAML scalar, a CDATA section or a JavaScript regular expression.
+  tokenNumber      tokenKind = "number"  // A number.
+  tokenLiteral     tokenKind = "literal" // true, false or null.
+  tokenPunctuation tokenKind = "punct"   // Brackets, commas, colons, and the angle brackets of tags.
+  tokenTag         tokenKind = "tag"     // The name of an XML or HTML element, or a CSS selector.
+  tokenAttribute   tokenKind = "attr"    // The name of an attribute.
+  tokenValue       tokenKind = "value"   // The value of an attribute.
+  tokenComment     tokenKind = "comment" // A comment.
+  tokenDeclaration tokenKind = "decl"    // A DOCTYPE, a processing instruction, or a YAML directive or document marker.
+  tokenKeyword     tokenKind = "keyword" // A JavaScript keyword or a CSS at-rule.
+)
+
+// A tokenWriter receives the output of a formatter piece by piece, along with the kind of every piece.
+type tokenWriter interface {
+  token(kind tokenKind, text []byte)
+}
+
+// A tokenFormatter is a formatter that can tell the kind of every piece of its output, so that the
+// output can be highlighted. Its format writes the same pieces as tokens, without their kinds.
+type tokenFormatter interface {
+  bodyFormatter
+  tokens(input []byte, output tokenWriter, indent string)
+}
+
+// plainTokens writes the text of the tokens as it is, and keeps the first error.
+type plainTokens struct {
+  output io.Writer
+  err    error
+}
+
+func (w *plainTokens) token(_ tokenKind, text []byte) {
+  if nil == w.err {
+    _, w.err = w.output.Write(text)
+  }
+}
+
+// htmlTokens writes the tokens as HTML, where every piece of text is escaped, and wrapped in a
+// <span class="tok-<kind>"> unless it is plain text.
+type htmlTokens struct {
+  output io.Writer
+}
+
+func (w *htmlTokens) token(kind tokenKind, text []byte) {
+  if 0 == len(text) {
+    return
+  }
+
+  if tokenText != kind {
+    io.WriteString(w.output, `<span class="tok-`+string(kind)+`">`)
+  }
+
+  if bytes.ContainsAny(text, "<>&'\"\x00") {
+    template.HTMLEscape(w.output, text)
+  } else {
+    w.output.Write(text)
+  }
+
+  if tokenText != kind {
+    io.WriteString(w.output, "</span>")
+  }
+}
+
+// highlightTokens returns input formatted by formatter as highlighted HTML.
+func highlightTokens(formatter tokenFormatter, input []byte, indent string) []byte {
+  buffer := bytes.Buffer{}
+  formatter.tokens(input, &htmlTokens{output: &buffer}, indent)
+  return buffer.Bytes()
+}
+
+// formatTokens writes the tokens of formatter as plain text, which is what its format does.
+func formatTokens(formatter tokenFormatter, input []byte, output io.Writer, indent string) {
+  buffered := bufio.NewWriter(output)
+  plain := &plainTokens{output: buffered}
+  formatter.tokens(input, plain, indent)
+  if err := cmp.Or(plain.err, buffered.Flush()); nil != err {
+    slog.Error(err.Error())
+  }
+}
+
+// markupTagTokens splits an XML or HTML start or end tag, such as <a href="/">, into its tokens.
+func markupTagTokens(tag []byte, output tokenWriter) {
+  n := 0
+  for n < len(tag) && strings.ContainsRune("</", rune(tag[n])) {
+    n++
+  }
+
+  output.token(tokenPunctuation, tag[:n])
+  word := func() []byte {
+    start := n
+    for n < len(tag) && !xmlIsSpace(tag[n]) && !strings.ContainsRune("/>=", rune(tag[n])) {
+      n++
+    }
+
+    return tag[start:n]
+  }
+
+  output.token(tokenTag, word())
+  for n < len(tag) {
+    start := n
+    switch c := tag[n]; {
+    case xmlIsSpace(c):
+      for n < len(tag) && xmlIsSpace(tag[n]) {
+        n++
+      }
+
+      output.token(tokenText, tag[start:n])
+    case '/' == c || '>' == c:
+      output.token(tokenPunctuation, tag[n:])
+      return
+    case '=' == c:
+      n++
+      output.token(tokenPunctuation, tag[start:n])
+      for n < len(tag) && xmlIsSpace(tag[n]) {
+        n++
+      }
+
+      output.token(tokenText, tag[start+1:n])
+      start = n
+      if n < len(tag) && ('"' == tag[n] || '\'' == tag[n]) {
+        if end := bytes.IndexByte(tag[n+1:], tag[n]); -1 != end {
+          n += end + 2
+        } else {
+          n = len(tag)
+        }
+      } else {
+        for n < len(tag) && !xmlIsSpace(tag[n]) && '>' != tag[n] {
+          n++
+        }
+      }
+
+      output.token(tokenValue, tag[start:n])
+    default:
+      if attr := word(); len(attr) > 0 {
+        output.token(tokenAttribute, attr)
+      } else {
+        n++
+        output.token(tokenText, tag[start:n])
+      }
+    }
+  }
+}
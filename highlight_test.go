@@ -0,0 +1,93 @@
+package playground
+
+import (
+  "bytes"
+  "regexp"
+  "strings"
+  "testing"
+)
+
+func TestHighlightTokens(t *testing.T) {
+  tests := []struct {
+    name      string
+    formatter tokenFormatter
+    input     string
+    want      string
+  }{
+    {
+      "JSON",
+      jsonFormatter,
+      `{"html": "<script>alert(1)</script>", "n": [1.5e3, true, null], "o": {}}`,
+      `<span class="tok-punct">{</span>
+  <span class="tok-key">&#34;html&#34;</span><span class="tok-punct">:</span> <span class="tok-string">&#34;&lt;script&gt;alert(1)&lt;/script&gt;&#34;</span><span class="tok-punct">,</span>
+  <span class="tok-key">&#34;n&#34;</span><span class="tok-punct">:</span> <span class="tok-punct">[</span>
+    <span class="tok-number">1.5e3</span><span class="tok-punct">,</span>
+    <span class="tok-literal">true</span><span class="tok-punct">,</span>
+    <span class="tok-literal">null</span>
+  <span class="tok-punct">]</span><span class="tok-punct">,</span>
+  <span class="tok-key">&#34;o&#34;</span><span class="tok-punct">:</span> <span class="tok-punct">{}</span>
+<span class="tok-punct">}</span>`,
+    },
+    {
+      "JSON stream",
+      jsonFormatter,
+      `"a" 1 {"b": "c"}`,
+      `<span class="tok-string">&#34;a&#34;</span>
+<span class="tok-number">1</span>
+<span class="tok-punct">{</span>
+  <span class="tok-key">&#34;b&#34;</span><span class="tok-punct">:</span> <span class="tok-string">&#34;c&#34;</span>
+<span class="tok-punct">}</span>`,
+    },
+    {
+      "XML",
+      xmlFormatter,
+      `<?xml version="1.0"?><a x='1 > 0'><!-- c --><b>t &amp; u</b><![CDATA[<x>]]></a>`,
+      `<span class="tok-decl">&lt;?xml version=&#34;1.0&#34;?&gt;</span>
+<span class="tok-punct">&lt;</span><span class="tok-tag">a</span> <span class="tok-attr">x</span><span class="tok-punct">=</span><span class="tok-value">&#39;1 &gt; 0&#39;</span><span class="tok-punct">&gt;</span>
+  <span class="tok-comment">&lt;!-- c --&gt;</span>
+  <span class="tok-punct">&lt;</span><span class="tok-tag">b</span><span class="tok-punct">&gt;</span>t &amp;amp; u<span class="tok-punct">&lt;/</span><span class="tok-tag">b</span><span class="tok-punct">&gt;</span>
+  <span class="tok-string">&lt;![CDATA[&lt;x&gt;]]&gt;</span>
+<span class="tok-punct">&lt;/</span><span class="tok-tag">a</span><span class="tok-punct">&gt;</span>`,
+    },
+    {
+      "HTML",
+      htmlFormatter,
+      `<!DOCTYPE html><p class=x data-y = "1">Hi<br/></p>`,
+      `<span class="tok-decl">&lt;!DOCTYPE html&gt;</span>
+<span class="tok-punct">&lt;</span><span class="tok-tag">p</span> <span class="tok-attr">class</span><span class="tok-punct">=</span><span class="tok-value">x</span> <span class="tok-attr">data-y</span> <span class="tok-punct">=</span> <span class="tok-value">&#34;1&#34;</span><span class="tok-punct">&gt;</span>Hi
+  <span class="tok-punct">&lt;</span><span class="tok-tag">br</span><span class="tok-punct">/&gt;</span>
+<span class="tok-punct">&lt;/</span><span class="tok-tag">p</span><span class="tok-punct">&gt;</span>`,
+    },
+    {
+      "YAML",
+      yamlFormatter,
+      "%YAML 1.2\n---\n# users\nusers:\n  - name: \"Ann\" # admin\n    age: 31\n    active: true\n    bio: |\n      a: b\n  - {name: Bob}\nurl: http://x/#y\n",
+      `<span class="tok-decl">%YAML 1.2</span>
+<span class="tok-decl">---</span>
+<span class="tok-comment"># users</span>
+<span class="tok-key">users</span><span class="tok-punct">:</span>
+  <span class="tok-punct">-</span> <span class="tok-key">name</span><span class="tok-punct">:</span> <span class="tok-string">&#34;Ann&#34;</span> <span class="tok-comment"># admin</span>
+    <span class="tok-key">age</span><span class="tok-punct">:</span> <span class="tok-number">31</span>
+    <span class="tok-key">active</span><span class="tok-punct">:</span> <span class="tok-literal">true</span>
+    <span class="tok-key">bio</span><span class="tok-punct">:</span> <span class="tok-punct">|</span>
+      <span class="tok-string">a: b</span>
+  <span class="tok-punct">-</span> {name: Bob}
+<span class="tok-key">url</span><span class="tok-punct">:</span> <span class="tok-string">http://x/#y</span>`,
+    },
+  }
+
+  spans := regexp.MustCompile(`</?span[^>]*>`)
+  for _, test := range tests {
+    if got := string(highlightTokens(test.formatter, []byte(test.input), "  ")); test.want != got {
+      t.Errorf("%s: highlightTokens(%q) =\n%s\nwant\n%s", test.name, test.input, got, test.want)
+    }
+
+    plain := bytes.Buffer{}
+    test.formatter.format([]byte(test.input), &plain, "  ")
+    text := strings.NewReplacer("&lt;", "<", "&gt;", ">", "&#34;", `"`, "&#39;", "'", "&amp;", "&").
+      Replace(spans.ReplaceAllString(test.want, ""))
+    if text != plain.String() {
+      t.Errorf("%s: format(%q) =\n%s\nwhich is not the text of the highlighted output\n%s", test.name, test.input, plain.String(), text)
+    }
+  }
+}
@@ -0,0 +1,318 @@
+package playground
+
+import (
+  "bufio"
+  "bytes"
+  "fmt"
+  "io"
+  "regexp"
+  "slices"
+  "strings"
+)
+
+var (
+  httpFileReVariable    = regexp.MustCompile(`^@([^\s=]+)\s*=\s*(.*)$`)                                                            // regexp httpFileReVariable matches a file variable declaration
+  httpFileReName        = regexp.MustCompile(`^(?:#|//)\s*@name\s*=?\s*(\S.*)$`)                                                   // regexp httpFileReName matches a # @name annotation
+  httpFileReRequestLine = regexp.MustCompile(`^(?:(GET|POST|PUT|PATCH|DELETE|HEAD|OPTIONS|TRACE|CONNECT)\s+)?(\S+)(?:\s+HTTP/[\d.]+)?$`) // regexp httpFileReRequestLine matches a request line
+  httpFileReHeader      = regexp.MustCompile(`^([^\s:]+)\s*:\s*(.*)$`)                                                             // regexp httpFileReHeader matches a header line
+  httpFileReVersion     = regexp.MustCompile(`\s+HTTP/[\d.]+$`)                                                                    // regexp httpFileReVersion matches the version closing a request line
+)
+
+// isHTTPFileComment reports whether line is a comment of a .http file.
+func isHTTPFileComment(line string) bool {
+  return strings.HasPrefix(line, "#") && !strings.HasPrefix(line, "###") || strings.HasPrefix(line, "//")
+}
+
+// looksLikeHTTPFile reports whether input starts like a .http file, that is, whether its first line
+// that is not blank, a comment, a separator or a variable declaration is a request line.
+func looksLikeHTTPFile(input []byte) bool {
+  scanner := bufio.NewScanner(bytes.NewReader(input))
+  for scanner.Scan() {
+    line := strings.TrimSpace(scanner.Text())
+    if "" == line || isHTTPFileComment(line) || strings.HasPrefix(line, "###") || httpFileReVariable.MatchString(line) {
+      continue
+    }
+
+    m := httpFileReRequestLine.FindStringSubmatch(line)
+    return nil != m && ("" != m[1] || strings.Contains(m[2], "://"))
+  }
+
+  return false
+}
+
+// httpFileRequest is a request of a .http file being parsed.
+type httpFileRequest struct {
+  title   string // The text after ###.
+  name    string // The value of the # @name annotation.
+  method  string
+  target  string
+  header  []collHeader
+  body    []string
+  started bool // The request line was read.
+  inBody  bool
+  handler bool // Inside a > {% ... %} response handler.
+}
+
+// item converts r into a collection item.
+func (r *httpFileRequest) item() collItem {
+  name := r.name
+  if "" == name {
+    name = r.title
+  }
+
+  if "" == name {
+    name = fmt.Sprint(r.method, " ", r.target)
+  }
+
+  req := &collRequest{
+    URL:    collURL{Raw: r.target, Query: rawQuery(r.target)},
+    Method: r.method,
+    Header: r.header,
+  }
+
+  if nil == req.Header {
+    req.Header = []collHeader{}
+  }
+
+  body := strings.Trim(strings.Join(r.body, "\n"), "\n")
+  if slices.ContainsFunc(r.header, func(h collHeader) bool {
+    return strings.EqualFold("Content-Type", h.Key) && strings.HasPrefix(h.Value, "application/x-www-form-urlencoded")
+  }) { /* Form fields may be continued on the lines that start with &.  */
+    lines := strings.Split(body, "\n")
+    for n := range lines {
+      lines[n] = strings.TrimSpace(lines[n])
+    }
+
+    body = strings.Join(lines, "")
+  }
+
+  if "" != body {
+    req.Body = &collBody{Mode: "raw", Raw: body}
+  }
+
+  return collItem{Name: name, Request: req}
+}
+
+// rawQuery splits the query string of a raw URL, which may hold variables, into query parameters.
+func rawQuery(raw string) (query []collQueryParam) {
+  _, rawquery, found := strings.Cut(raw, "?")
+  if !found {
+    return nil
+  }
+
+  rawquery, _, _ = strings.Cut(rawquery, "#")
+  for pair := range strings.SplitSeq(rawquery, "&") {
+    if "" != pair {
+      key, value, _ := strings.Cut(pair, "=")
+      query = append(query, collQueryParam{Key: key, Value: value})
+    }
+  }
+
+  return query
+}
+
+// insertItem adds item to the tree of items, under the folders named by path.
+func insertItem(items []collItem, path []string, item collItem) []collItem {
+  if 0 == len(path) {
+    return append(items, item)
+  }
+
+  n := slices.IndexFunc(items, func(i collItem) bool { return path[0] == i.Name && nil == i.Request })
+  if -1 == n {
+    items = append(items, collItem{Name: path[0]})
+    n = len(items) - 1
+  }
+
+  items[n].Item = insertItem(items[n].Item, path[1:], item)
+  return items
+}
+
+// parseHTTPFile parses a .http or .rest file, as written for the VS Code REST Client or the JetBrains
+// HTTP Client, into a collection. Requests whose names hold " / " are put in folders.
+func parseHTTPFile(input io.Reader) (*coll, error) {
+  var (
+    c       = &coll{}
+    current = &httpFileRequest{}
+    scanner = bufio.NewScanner(input)
+  )
+
+  scanner.Buffer(make([]byte, 0, 64<<10), 1<<20)
+
+  flush := func() {
+    if current.started {
+      item := current.item()
+      path := strings.Split(item.Name, " / ")
+      item.Name = path[len(path)-1]
+      c.Item = insertItem(c.Item, path[:len(path)-1], item)
+    }
+  }
+
+  for scanner.Scan() {
+    line := strings.TrimRight(scanner.Text(), "\r")
+    trimmed := strings.TrimSpace(line)
+
+    if strings.HasPrefix(trimmed, "###") {
+      flush()
+      current = &httpFileRequest{title: strings.TrimSpace(strings.TrimPrefix(trimmed, "###"))}
+      continue
+    }
+
+    switch {
+    case !current.started:
+      if m := httpFileReName.FindStringSubmatch(trimmed); nil != m {
+        current.name = strings.TrimSpace(m[1])
+        continue
+      }
+
+      if "" == trimmed || isHTTPFileComment(trimmed) {
+        continue
+      }
+
+      if m := httpFileReVariable.FindStringSubmatch(trimmed); nil != m {
+        c.Variable = append(c.Variable, collVariable{Key: m[1], Value: strings.TrimSpace(m[2]), Type: "string"})
+        continue
+      }
+
+      m := httpFileReRequestLine.FindStringSubmatch(trimmed)
+      if nil == m {
+        return nil, fmt.Errorf("invalid request line %#q", trimmed)
+      }
+
+      current.method, current.target, current.started = m[1], m[2], true
+      if "" == current.method {
+        current.method = "GET"
+      }
+    case !current.inBody:
+      switch {
+      case "" == trimmed:
+        current.inBody = true
+      case line != trimmed && (strings.HasPrefix(trimmed, "?") || strings.HasPrefix(trimmed, "&")): /* Query continuation.  */
+        current.target = fmt.Sprint(current.target, httpFileReVersion.ReplaceAllString(trimmed, ""))
+      case isHTTPFileComment(trimmed):
+      default:
+        m := httpFileReHeader.FindStringSubmatch(trimmed)
+        if nil == m {
+          return nil, fmt.Errorf("invalid header %#q", trimmed)
+        }
+
+        current.header = append(current.header, collHeader{Key: m[1], Value: m[2]})
+      }
+    case current.handler:
+      current.handler = !strings.HasSuffix(trimmed, "%}")
+    case strings.HasPrefix(trimmed, "> {%"):
+      current.handler = !strings.HasSuffix(trimmed, "%}")
+    case strings.HasPrefix(trimmed, "<> "): /* Reference to a previous response.  */
+    default:
+      current.body = append(current.body, line)
+    }
+  }
+
+  if err := scanner.Err(); nil != err {
+    return nil, err
+  }
+
+  flush()
+  return c, nil
+}
+
+// substituteRawPathVariables replaces the path variables of a raw URL, which may hold other
+// variables, with their values, leaving the ones without a value in place.
+func substituteRawPathVariables(raw string, variables []collPathVariable) string {
+  path, query, found := strings.Cut(raw, "?")
+  segments := strings.Split(path, "/")
+
+  for n, segment := range segments {
+    if !strings.HasPrefix(segment, ":") {
+      continue
+    }
+
+    for v := range slices.Values(variables) {
+      if segment[1:] == v.Key && "" != v.Value {
+        segments[n] = v.Value
+      }
+    }
+  }
+
+  if path = strings.Join(segments, "/"); found {
+    return fmt.Sprint(path, "?", query)
+  }
+
+  return path
+}
+
+// writeHTTPFile writes c as a .http file. Folders are flattened into the names of their requests,
+// auth is written as headers or query parameters, and path variables are substituted. Basic auth
+// whose credentials hold variables is written as Basic {{user}} {{password}}, which the clients
+// encode themselves.
+func writeHTTPFile(c *coll, output io.Writer) error {
+  w := bufio.NewWriter(output)
+
+  for v := range slices.Values(c.Variable) {
+    if !v.Disabled {
+      fmt.Fprintf(w, "@%s = %s\n", v.Key, v.Value)
+    }
+  }
+
+  inheritAuth(c.Auth, c.Item)
+
+  var write func(prefix string, items []collItem)
+  write = func(prefix string, items []collItem) {
+    for i := range slices.Values(items) {
+      if len(i.Item) > 0 || nil == i.Request {
+        write(fmt.Sprint(prefix, i.Name, " / "), i.Item)
+        continue
+      }
+
+      var (
+        target = substituteRawPathVariables(i.Request.URL.Raw, i.Request.URL.Variable)
+        header = slices.DeleteFunc(slices.Clone(i.Request.Header), func(h collHeader) bool { return h.Disabled })
+        body   string
+      )
+
+      h, q := authorize(i.Request.Auth, newResolver(nil))
+      if a := i.Request.Auth; nil != h && "basic" == a.Type {
+        username, password := attribute(a.Basic, "username"), attribute(a.Basic, "password")
+        if regexpVariable.MatchString(username) || regexpVariable.MatchString(password) { /* Encoded, the references would be lost.  */
+          h.Value = fmt.Sprint("Basic ", username, " ", password)
+        }
+      }
+
+      if nil != h {
+        header = append(header, *h)
+      }
+
+      if nil != q {
+        target = appendQuery(target, q.Key, q.Value)
+      }
+
+      if b := i.Request.Body; nil != b {
+        switch b.Mode {
+        case "urlencoded":
+          fields := make([]string, 0, len(b.URLEncoded))
+          for field := range slices.Values(b.URLEncoded) {
+            fields = append(fields, fmt.Sprint(queryEscapeVariables(field.Key), "=", queryEscapeVariables(field.Value)))
+          }
+
+          body = strings.Join(fields, "\n&")
+          if !slices.ContainsFunc(header, func(h collHeader) bool { return strings.EqualFold("Content-Type", h.Key) }) {
+            header = append(header, collHeader{Key: "Content-Type", Value: "application/x-www-form-urlencoded"})
+          }
+        default:
+          body = b.Raw
+        }
+      }
+
+      fmt.Fprintf(w, "\n### %s%s\n%s %s\n", prefix, i.Name, i.Request.Method, target)
+      for h := range slices.Values(header) {
+        fmt.Fprintf(w, "%s: %s\n", h.Key, h.Value)
+      }
+
+      if "" != body {
+        fmt.Fprintf(w, "\n%s\n", body)
+      }
+    }
+  }
+
+  write("", c.Item)
+  return w.Flush()
+}
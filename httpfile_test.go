@@ -0,0 +1,271 @@
+package playground
+
+import (
+  "bytes"
+  "github.com/google/go-cmp/cmp"
+  "net/url"
+  "reflect"
+  "slices"
+  "strings"
+  "testing"
+)
+
+const httpFileTest = `@host = https://fontseca.dev
+@token = abc
+
+# A comment before the first request.
+### Users / List users
+GET {{host}}/users
+    ?page=2
+    &size=10 HTTP/1.1
+Accept: application/json
+// A comment between headers.
+
+###
+# @name login
+POST {{host}}/login
+Content-Type: application/json
+
+{
+  "user": "jane",
+
+  "password": "{{$randomPassword}}"
+}
+
+> {%
+  client.global.set("token", response.body.token);
+%}
+
+### Users / Admins / Delete user
+DELETE {{host}}/users/5
+Authorization: Bearer {{token}}
+
+### Ping
+https://fontseca.dev/ping
+`
+
+func TestImportColl_HTTPFile(t *testing.T) {
+  got, err := importColl(strings.NewReader(httpFileTest))
+  if nil != err {
+    t.Fatalf("unexpected error: %s", err)
+  }
+
+  want := &coll{
+    Item: []collItem{
+      {
+        Name: "Users",
+        Item: []collItem{
+          {
+            Name: "List users",
+            Request: &collRequest{
+              URL: collURL{
+                Raw:   "{{host}}/users?page=2&size=10",
+                Query: []collQueryParam{{Key: "page", Value: "2"}, {Key: "size", Value: "10"}},
+              },
+              Method: "GET",
+              Header: []collHeader{{Key: "Accept", Value: "application/json"}},
+            },
+          },
+          {
+            Name: "Admins",
+            Item: []collItem{
+              {
+                Name: "Delete user",
+                Request: &collRequest{
+                  URL:    collURL{Raw: "{{host}}/users/5"},
+                  Method: "DELETE",
+                  Header: []collHeader{{Key: "Authorization", Value: "Bearer {{token}}"}},
+                },
+              },
+            },
+          },
+        },
+      },
+      {
+        Name: "login",
+        Request: &collRequest{
+          URL:    collURL{Raw: "{{host}}/login"},
+          Method: "POST",
+          Header: []collHeader{{Key: "Content-Type", Value: "application/json"}},
+          Body:   &collBody{Mode: "raw", Raw: "{\n  \"user\": \"jane\",\n\n  \"password\": \"{{$randomPassword}}\"\n}"},
+        },
+      },
+      {
+        Name: "Ping",
+        Request: &collRequest{
+          URL:    collURL{Raw: "https://fontseca.dev/ping"},
+          Method: "GET",
+          Header: []collHeader{},
+        },
+      },
+    },
+    Variable: []collVariable{
+      {Key: "host", Value: "https://fontseca.dev", Type: "string"},
+      {Key: "token", Value: "abc", Type: "string"},
+    },
+  }
+
+  if !reflect.DeepEqual(want, got) {
+    t.Fatal(cmp.Diff(want, got))
+  }
+}
+
+func TestWriteHTTPFile(t *testing.T) {
+  c := &coll{
+    Item: []collItem{
+      {
+        Name: "Posts",
+        Auth: &collAuth{Type: "bearer", Bearer: []collAuthAttribute{{Key: "token", Value: "{{token}}"}}},
+        Item: []collItem{
+          {
+            Name: "Get post",
+            Request: &collRequest{
+              URL:    collURL{Raw: "{{host}}/posts/:id?full=true", Variable: []collPathVariable{{Key: "id", Value: "7"}}},
+              Method: "GET",
+              Header: []collHeader{{Key: "Accept", Value: "application/json"}, {Key: "X-Debug", Value: "1", Disabled: true}},
+            },
+          },
+        },
+      },
+      {
+        Name: "Log in",
+        Request: &collRequest{
+          URL:    collURL{Raw: "{{host}}/login"},
+          Method: "POST",
+          Header: []collHeader{},
+          Body:   &collBody{Mode: "urlencoded", URLEncoded: []collURLEncodedParameter{{Key: "user", Value: "jane"}, {Key: "password", Value: "{{password}}"}}},
+        },
+      },
+      {
+        Name: "Me",
+        Request: &collRequest{
+          URL:    collURL{Raw: "{{host}}/me"},
+          Method: "GET",
+          Header: []collHeader{},
+          Auth:   &collAuth{Type: "basic", Basic: []collAuthAttribute{{Key: "username", Value: "{{user}}"}, {Key: "password", Value: "{{pass}}"}}},
+        },
+      },
+      {
+        Name: "Health",
+        Request: &collRequest{
+          URL:    collURL{Raw: "{{host}}/health"},
+          Method: "GET",
+          Header: []collHeader{},
+          Auth:   &collAuth{Type: "basic", Basic: []collAuthAttribute{{Key: "username", Value: "jane"}, {Key: "password", Value: "secret"}}},
+        },
+      },
+    },
+    Variable: []collVariable{{Key: "host", Value: "https://fontseca.dev"}, {Key: "unused", Disabled: true}},
+  }
+
+  want := `@host = https://fontseca.dev
+
+### Posts / Get post
+GET {{host}}/posts/7?full=true
+Accept: application/json
+Authorization: Bearer {{token}}
+
+### Log in
+POST {{host}}/login
+Content-Type: application/x-www-form-urlencoded
+
+user=jane
+&password={{password}}
+
+### Me
+GET {{host}}/me
+Authorization: Basic {{user}} {{pass}}
+
+### Health
+GET {{host}}/health
+Authorization: Basic amFuZTpzZWNyZXQ=
+`
+
+  var b bytes.Buffer
+  if err := writeHTTPFile(c, &b); nil != err {
+    t.Fatalf("unexpected error: %s", err)
+  }
+
+  if want != b.String() {
+    t.Errorf("\n"+
+      "\nexpected:\n\n---\n%s\n---\n"+
+      "\ngot:\n\n---\n%s---",
+      want, b.String())
+  }
+}
+
+func TestHTTPFileRoundTrip(t *testing.T) {
+  parsed, err := parseHTTPFile(strings.NewReader(httpFileTest))
+  if nil != err {
+    t.Fatalf("unexpected error: %s", err)
+  }
+
+  var b bytes.Buffer
+  if err := writeHTTPFile(parsed, &b); nil != err {
+    t.Fatalf("unexpected error: %s", err)
+  }
+
+  reparsed, err := parseHTTPFile(&b)
+  if nil != err {
+    t.Fatalf("unexpected error: %s", err)
+  }
+
+  if !reflect.DeepEqual(parsed, reparsed) {
+    t.Fatal(cmp.Diff(parsed, reparsed))
+  }
+}
+
+func TestHTTPFileRoundTrip_urlencoded(t *testing.T) {
+  fields := []collURLEncodedParameter{{Key: "user", Value: "jane"}, {Key: "a&b", Value: "x=y z"}, {Key: "password", Value: "{{password}}"}}
+  c := &coll{Item: []collItem{{
+    Name:    "Log in",
+    Request: &collRequest{URL: collURL{Raw: "{{host}}/login"}, Method: "POST", Header: []collHeader{}, Body: &collBody{Mode: "urlencoded", URLEncoded: fields}},
+  }}}
+
+  var b bytes.Buffer
+  if err := writeHTTPFile(c, &b); nil != err {
+    t.Fatalf("unexpected error: %s", err)
+  }
+
+  parsed, err := parseHTTPFile(&b)
+  if nil != err {
+    t.Fatalf("unexpected error: %s", err)
+  }
+
+  body := parsed.Item[0].Request.Body
+  if want := "user=jane&a%26b=x%3Dy+z&password={{password}}"; nil == body || want != body.Raw {
+    t.Fatalf("body = %+v, want %q", body, want)
+  }
+
+  values, err := url.ParseQuery(body.Raw)
+  if nil != err {
+    t.Fatalf("unexpected error: %s", err)
+  }
+
+  for field := range slices.Values(fields) {
+    if got := values.Get(field.Key); field.Value != got {
+      t.Errorf("field %q = %q, want %q", field.Key, got, field.Value)
+    }
+  }
+}
+
+func TestLooksLikeHTTPFile(t *testing.T) {
+  tests := []struct {
+    input string
+    want  bool
+  }{
+    {"", false},
+    {`{"info": {}}`, false},
+    {"openapi: 3.0.3\ninfo:\n  title: x", false},
+    {"GET https://fontseca.dev", true},
+    {"# comment\n@host = x\n\n### First\nPOST {{host}}/users HTTP/1.1", true},
+    {"https://fontseca.dev/ping", true},
+    {"fontseca.dev", false},
+  }
+
+  for _, test := range tests {
+    if got := looksLikeHTTPFile([]byte(test.input)); test.want != got {
+      t.Errorf("looksLikeHTTPFile(%q) = %t, want %t", test.input, got, test.want)
+    }
+  }
+}
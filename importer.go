@@ -0,0 +1,133 @@
+package playground
+
+import (
+  "bytes"
+  "encoding/json"
+  "errors"
+  "io"
+  "slices"
+)
+
+// A collImporter converts the files of a collection format, such as Postman or Insomnia exports,
+// into a coll. Whatever the collection model cannot hold is reported with coll.skip.
+type collImporter interface {
+  sniff(input []byte) bool           // sniff reports whether input looks like a file of the format.
+  parse(input []byte) (*coll, error) // parse converts input into a collection.
+}
+
+// collImporters are tried in order by importColl, so the formats that are the easiest to tell
+// apart come first and Postman, whose files have the least distinctive shape, comes last.
+var collImporters = []collImporter{
+  &httpFileImporterImpl{},
+  &brunoImporterImpl{},
+  &openapiImporterImpl{},
+  &harImporterImpl{},
+  &insomniaImporterImpl{},
+  &postmanImporterImpl{},
+}
+
+var errUnknownCollFormat = errors.New("unknown collection format")
+
+// importColl reads a collection file and converts it with the first importer that recognizes it.
+func importColl(collfile io.Reader) (*coll, error) {
+  input, err := io.ReadAll(collfile)
+  if nil != err {
+    return nil, err
+  }
+
+  for importer := range slices.Values(collImporters) {
+    if importer.sniff(input) {
+      return importer.parse(input)
+    }
+  }
+
+  return nil, errUnknownCollFormat
+}
+
+// probeJSON converts input, a JSON or YAML document, into JSON and decodes it into probe,
+// reporting whether both steps succeeded.
+func probeJSON(input []byte, probe any) bool {
+  document, err := toJSON(input)
+  return nil == err && nil == json.Unmarshal(document, probe)
+}
+
+type httpFileImporterImpl struct{}
+
+func (httpFileImporterImpl) sniff(input []byte) bool {
+  return looksLikeHTTPFile(input)
+}
+
+func (httpFileImporterImpl) parse(input []byte) (*coll, error) {
+  return parseHTTPFile(bytes.NewReader(input))
+}
+
+type openapiImporterImpl struct{}
+
+func (openapiImporterImpl) sniff(input []byte) bool {
+  var probe struct {
+    OpenAPI any `json:"openapi"`
+    Swagger any `json:"swagger"`
+  }
+
+  return probeJSON(input, &probe) && (nil != probe.OpenAPI || nil != probe.Swagger)
+}
+
+func (openapiImporterImpl) parse(input []byte) (*coll, error) {
+  document, err := toJSON(input)
+  if nil != err {
+    return nil, err
+  }
+
+  doc, err := parseOpenAPI(document)
+  if nil != err {
+    return nil, err
+  }
+
+  return openapiToColl(doc), nil
+}
+
+type harImporterImpl struct{}
+
+func (harImporterImpl) sniff(input []byte) bool {
+  var probe struct {
+    Log *struct {
+      Entries json.RawMessage `json:"entries"`
+    } `json:"log"`
+  }
+
+  return probeJSON(input, &probe) && nil != probe.Log && nil != probe.Log.Entries
+}
+
+func (harImporterImpl) parse(input []byte) (*coll, error) {
+  document, err := toJSON(input)
+  if nil != err {
+    return nil, err
+  }
+
+  h, err := parseHAR(document)
+  if nil != err {
+    return nil, err
+  }
+
+  return harToColl(h), nil
+}
+
+type postmanImporterImpl struct{}
+
+func (postmanImporterImpl) sniff(input []byte) bool {
+  var probe struct {
+    Info json.RawMessage `json:"info"`
+    Item json.RawMessage `json:"item"`
+  }
+
+  return probeJSON(input, &probe) && (nil != probe.Info || nil != probe.Item)
+}
+
+func (postmanImporterImpl) parse(input []byte) (*coll, error) {
+  document, err := toJSON(input)
+  if nil != err {
+    return nil, err
+  }
+
+  return parseColl(bytes.NewReader(document))
+}
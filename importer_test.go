@@ -0,0 +1,81 @@
+package playground
+
+import (
+  "archive/zip"
+  "bytes"
+  "errors"
+  "reflect"
+  "slices"
+  "strings"
+  "testing"
+)
+
+func TestCollImporters_sniff(t *testing.T) {
+  var archive bytes.Buffer
+  z := zip.NewWriter(&archive)
+  f, _ := z.Create("API/bruno.json")
+  f.Write([]byte(`{"version": "1", "name": "API", "type": "collection"}`))
+  z.Close()
+
+  tests := []struct {
+    input string
+    want  collImporter
+  }{
+    {"GET https://fontseca.dev", &httpFileImporterImpl{}},
+    {"meta {\n  name: Ping\n}\n\nget {\n  url: https://fontseca.dev\n}\n", &brunoImporterImpl{}},
+    {archive.String(), &brunoImporterImpl{}},
+    {"openapi: 3.0.3\ninfo:\n  title: API\npaths: {}\n", &openapiImporterImpl{}},
+    {`{"swagger": "2.0", "paths": {}}`, &openapiImporterImpl{}},
+    {`{"log": {"version": "1.2", "entries": []}}`, &harImporterImpl{}},
+    {`{"_type": "export", "__export_format": 4, "resources": []}`, &insomniaImporterImpl{}},
+    {`{"info": {"name": "API"}, "item": []}`, &postmanImporterImpl{}},
+    {`{"_type": "export", "__export_format": 3, "resources": []}`, nil},
+    {`{"name": "not a collection"}`, nil},
+    {"vars {\n  host: localhost\n}\n", nil},
+    {"PK\x03\x04 not a zip", nil},
+  }
+
+  for _, test := range tests {
+    var got collImporter
+    for importer := range slices.Values(collImporters) {
+      if importer.sniff([]byte(test.input)) {
+        got = importer
+        break
+      }
+    }
+
+    if !reflect.DeepEqual(test.want, got) {
+      t.Errorf("the importer of %q is %T, want %T", test.input, got, test.want)
+    }
+  }
+
+  if _, err := importColl(strings.NewReader(`{"name": "not a collection"}`)); !errors.Is(err, errUnknownCollFormat) {
+    t.Errorf("importColl(...) = %v, want %v", err, errUnknownCollFormat)
+  }
+}
+
+func TestCollGen_importSummary(t *testing.T) {
+  newString = func() string { return "714b9856-cac2-4a77-a149-ca1a797918cb" }
+  input := `{"_type": "export", "__export_format": 4, "resources": [
+    {"_id": "wrk_1", "_type": "workspace", "parentId": null, "name": "API"},
+    {"_id": "req_1", "_type": "request", "parentId": "wrk_1", "name": "Ping", "method": "GET", "url": "https://fontseca.dev", "preRequestScript": "insomnia.environment.set('a', 1);"}
+  ]}`
+
+  c, err := importColl(strings.NewReader(input))
+  if nil != err {
+    t.Fatalf("unexpected error: %s", err)
+  }
+
+  _, colldirtree := collGen(c)
+
+  want := `<header><h3>API</h3></header>` +
+    `<details class="import-summary"><summary>Not imported (1)</summary><ul><li>Ping: scripts are not supported</li></ul></details>` +
+    `<div class="item"><span data-id="714b9856-cac2-4a77-a149-ca1a797918cb" class="name">Ping</span></div>`
+
+  if want != colldirtree {
+    t.Errorf("\n"+
+      "\nexpected:\n\n---\n%s\n---\n"+
+      "\ngot:\n\n---\n%s---",
+      want, colldirtree)
+  }
+}
@@ -0,0 +1,358 @@
+package playground
+
+import (
+  "cmp"
+  "encoding/json"
+  "fmt"
+  "maps"
+  "regexp"
+  "slices"
+  "strings"
+)
+
+// insomniaParameter is a name-value pair of an Insomnia request: a query parameter, a header or a form field.
+type insomniaParameter struct {
+  Name     string `json:"name"`
+  Value    string `json:"value"`
+  Disabled bool   `json:"disabled"`
+  Type     string `json:"type"` // "file" for the multipart fields that upload a file.
+}
+
+// insomniaResource holds (some) fields of the resources of an Insomnia v4 export. Workspaces,
+// request groups (folders), requests and environments all share this shape.
+type insomniaResource struct {
+  ID          string  `json:"_id"`
+  Type        string  `json:"_type"`
+  ParentID    string  `json:"parentId"`
+  Name        string  `json:"name"`
+  Description string  `json:"description"`
+  MetaSortKey float64 `json:"metaSortKey"`
+
+  // Requests.
+  Method string `json:"method"`
+  URL    string `json:"url"`
+  Body   struct {
+    MimeType string              `json:"mimeType"`
+    Text     string              `json:"text"`
+    Params   []insomniaParameter `json:"params"`
+    FileName string              `json:"fileName"`
+  } `json:"body"`
+  Parameters          []insomniaParameter `json:"parameters"`
+  Headers             []insomniaParameter `json:"headers"`
+  PreRequestScript    string              `json:"preRequestScript"`
+  AfterResponseScript string              `json:"afterResponseScript"`
+
+  // Requests and request groups.
+  Authentication map[string]any `json:"authentication"`
+  Environment    map[string]any `json:"environment"` // The variables of a request group.
+
+  // Environments.
+  Data map[string]any `json:"data"`
+
+  // Cookie jars.
+  Cookies []json.RawMessage `json:"cookies"`
+}
+
+// insomniaExport is an Insomnia v4 export, a flat list of resources linked by their parent IDs.
+type insomniaExport struct {
+  Type      string             `json:"_type"`
+  Format    int                `json:"__export_format"`
+  Resources []insomniaResource `json:"resources"`
+}
+
+var (
+  insomniaReVariable = regexp.MustCompile(`{{\s*(?:_\.)?([\w.-]+)\s*}}`) // regexp insomniaReVariable matches a variable such as {{ _.host }}
+  insomniaReTag      = regexp.MustCompile(`{%\s*(\w+)\s*([^%]*?)\s*%}`)  // regexp insomniaReTag matches a template tag such as {% uuid 'v4' %}
+)
+
+type insomniaImporterImpl struct{}
+
+func (insomniaImporterImpl) sniff(input []byte) bool {
+  var probe insomniaExport
+  return probeJSON(input, &probe) && "export" == probe.Type && 4 == probe.Format
+}
+
+// parse converts an Insomnia v4 export. A single workspace becomes the collection, while several
+// ones become its top-level folders. Base environments become the collection variables, and
+// sub-environments are kept as collection environments.
+func (insomniaImporterImpl) parse(input []byte) (*coll, error) {
+  document, err := toJSON(input)
+  if nil != err {
+    return nil, err
+  }
+
+  var export insomniaExport
+  if err := json.Unmarshal(document, &export); nil != err {
+    return nil, err
+  }
+
+  var (
+    c          = &coll{}
+    children   = make(map[string][]*insomniaResource)
+    ids        = make(map[string]string) // The type of every resource, by ID.
+    workspaces []*insomniaResource
+  )
+
+  for n := range export.Resources { /* With unique IDs and no self-parented resource, no cycle is reachable from the roots.  */
+    r := &export.Resources[n]
+    switch _, found := ids[r.ID]; {
+    case "" == r.ID:
+      return nil, fmt.Errorf("the Insomnia resource %q has no ID", r.Name)
+    case found:
+      return nil, fmt.Errorf("duplicate Insomnia resource ID %q", r.ID)
+    case r.ID == r.ParentID:
+      return nil, fmt.Errorf("the Insomnia resource %q is its own parent", r.ID)
+    }
+
+    ids[r.ID] = r.Type
+    children[r.ParentID] = append(children[r.ParentID], r)
+  }
+
+  for siblings := range maps.Values(children) { /* Environments first, so that folders can override their variables.  */
+    slices.SortStableFunc(siblings, func(a, b *insomniaResource) int {
+      if ("environment" == a.Type) != ("environment" == b.Type) {
+        if "environment" == a.Type {
+          return -1
+        }
+
+        return 1
+      }
+
+      return cmp.Compare(a.MetaSortKey, b.MetaSortKey)
+    })
+  }
+
+  var build func(parent, path string) []collItem
+  build = func(parent, path string) (items []collItem) {
+    for r := range slices.Values(children[parent]) {
+      name := fmt.Sprint(path, r.Name)
+      if "" != r.Description && "request_group" != r.Type && "request" != r.Type && "workspace" != r.Type {
+        c.skip("%s: the description was not imported", name)
+      }
+
+      switch r.Type {
+      case "request_group":
+        folder := collItem{Name: r.Name, Description: markdownDescription(r.Description), Auth: insomniaAuth(c, name, r.Authentication)}
+        if len(r.Environment) > 0 {
+          c.Variable = append(c.Variable, insomniaVariables(r.Environment)...)
+          c.skip("%s: the variables of the folder were merged into the collection variables", name)
+        }
+
+        folder.Item = build(r.ID, fmt.Sprint(name, " / "))
+        items = append(items, folder)
+      case "request":
+        items = append(items, collItem{Name: r.Name, Description: markdownDescription(r.Description), Request: insomniaRequest(c, name, r)})
+      case "environment":
+        if "environment" == ids[parent] {
+          c.environments = append(c.environments, collEnvironment{Name: r.Name, Variable: insomniaVariables(r.Data)})
+        } else {
+          c.Variable = append(c.Variable, insomniaVariables(r.Data)...)
+        }
+
+        build(r.ID, path) /* Sub-environments.  */
+      case "cookie_jar":
+        if len(r.Cookies) > 0 {
+          c.skip("%s: the cookies of the cookie jar were not imported", name)
+        }
+      case "workspace":
+      default:
+        c.skip("%s: Insomnia resources of type %q are not supported", name, r.Type)
+      }
+    }
+
+    return items
+  }
+
+  for n := range export.Resources {
+    if r := &export.Resources[n]; "workspace" == r.Type {
+      workspaces = append(workspaces, r)
+    }
+  }
+
+  switch len(workspaces) {
+  case 1:
+    c.Info.Name = workspaces[0].Name
+    c.Info.Description = markdownDescription(workspaces[0].Description)
+    c.Item = build(workspaces[0].ID, "")
+  default:
+    for w := range slices.Values(workspaces) {
+      c.Item = append(c.Item, collItem{Name: w.Name, Description: markdownDescription(w.Description), Item: build(w.ID, fmt.Sprint(w.Name, " / "))})
+    }
+  }
+
+  for _, parent := range slices.Sorted(maps.Keys(children)) {
+    if _, found := ids[parent]; !found { /* Resources exported without their workspace.  */
+      c.Item = append(c.Item, build(parent, "")...)
+    }
+  }
+
+  for n := range c.Variable {
+    c.Variable[n].Value = insomniaTemplate(c, "collection variables", c.Variable[n].Value)
+  }
+
+  for e := range slices.Values(c.environments) {
+    for n := range e.Variable {
+      e.Variable[n].Value = insomniaTemplate(c, fmt.Sprint("environment ", e.Name), e.Variable[n].Value)
+    }
+  }
+
+  return c, nil
+}
+
+// insomniaRequest converts an Insomnia request, named name in the import summary, into a collRequest.
+func insomniaRequest(c *coll, name string, r *insomniaResource) *collRequest {
+  req := &collRequest{
+    URL:    collURL{Raw: insomniaTemplate(c, name, r.URL)},
+    Method: strings.ToUpper(r.Method),
+    Header: []collHeader{},
+    Auth:   insomniaAuth(c, name, r.Authentication),
+  }
+
+  for p := range slices.Values(r.Parameters) {
+    if p.Disabled {
+      c.skip("%s: the disabled query parameter %q was not imported", name, p.Name)
+      continue
+    }
+
+    key, value := insomniaTemplate(c, name, p.Name), insomniaTemplate(c, name, p.Value)
+    req.URL.Query = append(req.URL.Query, collQueryParam{Key: key, Value: value})
+    if strings.Contains(req.URL.Raw, "?") {
+      req.URL.Raw = fmt.Sprint(req.URL.Raw, "&", key, "=", value)
+    } else {
+      req.URL.Raw = fmt.Sprint(req.URL.Raw, "?", key, "=", value)
+    }
+  }
+
+  for h := range slices.Values(r.Headers) {
+    req.Header = append(req.Header, collHeader{
+      Key:      insomniaTemplate(c, name, h.Name),
+      Value:    insomniaTemplate(c, name, h.Value),
+      Disabled: h.Disabled,
+    })
+  }
+
+  switch body := r.Body; {
+  case "" != body.FileName:
+    c.skip("%s: the file body %q was not imported", name, body.FileName)
+  case "multipart/form-data" == body.MimeType:
+    c.skip("%s: multipart bodies are not supported", name)
+  case "application/x-www-form-urlencoded" == body.MimeType:
+    req.Body = &collBody{Mode: "urlencoded", URLEncoded: []collURLEncodedParameter{}}
+    for p := range slices.Values(body.Params) {
+      if p.Disabled {
+        c.skip("%s: the disabled form field %q was not imported", name, p.Name)
+        continue
+      }
+
+      req.Body.URLEncoded = append(req.Body.URLEncoded, collURLEncodedParameter{
+        Key:   insomniaTemplate(c, name, p.Name),
+        Value: insomniaTemplate(c, name, p.Value),
+      })
+    }
+  case "" != body.Text:
+    req.Body = &collBody{Mode: "raw", Raw: insomniaTemplate(c, name, body.Text)}
+  }
+
+  if nil != req.Body && "" != r.Body.MimeType &&
+    !slices.ContainsFunc(req.Header, func(h collHeader) bool { return strings.EqualFold("Content-Type", h.Key) }) {
+    contentType := r.Body.MimeType
+    if "application/graphql" == contentType { /* The text of GraphQL bodies is a JSON document.  */
+      contentType = "application/json"
+    }
+
+    req.Header = append(req.Header, collHeader{Key: "Content-Type", Value: contentType})
+  }
+
+  if "" != r.PreRequestScript || "" != r.AfterResponseScript {
+    c.skip("%s: scripts are not supported", name)
+  }
+
+  return req
+}
+
+// insomniaAuth converts the authentication of an Insomnia request or request group. An empty
+// authentication is inherited from the parent.
+func insomniaAuth(c *coll, name string, authentication map[string]any) *collAuth {
+  value := func(key string) string {
+    s, _ := authentication[key].(string)
+    return insomniaTemplate(c, name, s)
+  }
+
+  if disabled, _ := authentication["disabled"].(bool); disabled {
+    return &collAuth{Type: "noauth"}
+  }
+
+  switch t := value("type"); t {
+  case "":
+    return nil
+  case "none":
+    return &collAuth{Type: "noauth"}
+  case "bearer":
+    if prefix := value("prefix"); "" != prefix && "Bearer" != prefix {
+      c.skip("%s: the token prefix %q was replaced with Bearer", name, prefix)
+    }
+
+    return &collAuth{Type: "bearer", Bearer: []collAuthAttribute{{Key: "token", Value: value("token"), Type: "string"}}}
+  case "basic":
+    return &collAuth{Type: "basic", Basic: []collAuthAttribute{
+      {Key: "username", Value: value("username"), Type: "string"},
+      {Key: "password", Value: value("password"), Type: "string"},
+    }}
+  case "apikey":
+    in := "header"
+    switch value("addTo") {
+    case "queryParams":
+      in = "query"
+    case "cookie":
+      c.skip("%s: API keys sent as cookies are sent as headers", name)
+    }
+
+    return &collAuth{Type: "apikey", APIKey: []collAuthAttribute{
+      {Key: "key", Value: value("key"), Type: "string"},
+      {Key: "value", Value: value("value"), Type: "string"},
+      {Key: "in", Value: in, Type: "string"},
+    }}
+  default:
+    c.skip("%s: %s authentication is not supported", name, t)
+    return &collAuth{Type: t}
+  }
+}
+
+// insomniaVariables flattens the data of an Insomnia environment into variables, joining the keys
+// of nested objects with dots, which is how Insomnia templates refer to them.
+func insomniaVariables(data map[string]any) (variables []collVariable) {
+  var flatten func(prefix string, value any)
+  flatten = func(prefix string, value any) {
+    switch v := value.(type) {
+    case map[string]any:
+      for _, key := range slices.Sorted(maps.Keys(v)) {
+        flatten(fmt.Sprint(prefix, key, "."), v[key])
+      }
+    default:
+      variables = append(variables, collVariable{Key: strings.TrimSuffix(prefix, "."), Value: exampleString(v), Type: "string"})
+    }
+  }
+
+  flatten("", data)
+  return variables
+}
+
+// insomniaTemplate rewrites the Nunjucks templates of Insomnia into Postman variables. The tags
+// that have an equivalent dynamic variable are translated; the others are kept as text and reported.
+func insomniaTemplate(c *coll, name, s string) string {
+  s = insomniaReVariable.ReplaceAllString(s, "{{$1}}")
+  return insomniaReTag.ReplaceAllStringFunc(s, func(tag string) string {
+    m := insomniaReTag.FindStringSubmatch(tag)
+    switch arguments := strings.Trim(m[2], `'"`); {
+    case "uuid" == m[1]:
+      return "{{$guid}}"
+    case "now" == m[1] && ("" == arguments || "iso-8601" == arguments):
+      return "{{$isoTimestamp}}"
+    case "now" == m[1] && "unix" == arguments:
+      return "{{$timestamp}}"
+    }
+
+    c.skip("%s: the template tag %s was kept as text", name, tag)
+    return tag
+  })
+}
@@ -0,0 +1,179 @@
+package playground
+
+import (
+  "github.com/google/go-cmp/cmp"
+  "reflect"
+  "strings"
+  "testing"
+)
+
+const insomniaTest = `{
+  "_type": "export",
+  "__export_format": 4,
+  "__export_source": "insomnia.desktop.app:v2023.5.8",
+  "resources": [
+    {"_id": "wrk_1", "_type": "workspace", "parentId": null, "name": "Blog API", "description": "The blog."},
+    {"_id": "env_1", "_type": "environment", "parentId": "wrk_1", "name": "Base Environment", "data": {"host": "https://fontseca.dev", "auth": {"token": "abc"}, "retries": 3}},
+    {"_id": "env_2", "_type": "environment", "parentId": "env_1", "name": "Production", "data": {"host": "https://api.fontseca.dev"}},
+    {"_id": "jar_1", "_type": "cookie_jar", "parentId": "wrk_1", "name": "Default Jar", "cookies": []},
+    {"_id": "fld_1", "_type": "request_group", "parentId": "wrk_1", "name": "Posts", "metaSortKey": -2, "environment": {"page": 1},
+     "authentication": {"type": "bearer", "token": "{{ _.auth.token }}"}},
+    {"_id": "req_2", "_type": "request", "parentId": "fld_1", "name": "Create post", "metaSortKey": 2, "method": "post",
+     "url": "{{ _.host }}/posts", "headers": [{"name": "X-Request-ID", "value": "{% uuid 'v4' %}"}, {"name": "X-Debug", "value": "1", "disabled": true}],
+     "body": {"mimeType": "application/json", "text": "{\"title\": \"{{ title }}\", \"at\": \"{% now 'iso-8601', '' %}\"}"},
+     "authentication": {}},
+    {"_id": "req_1", "_type": "request", "parentId": "fld_1", "name": "List posts", "description": "Lists the **published** posts.", "metaSortKey": 1, "method": "GET",
+     "url": "{{ _.host }}/posts", "parameters": [{"name": "page", "value": "{{ _.page }}"}, {"name": "draft", "value": "true", "disabled": true}],
+     "headers": [], "body": {}, "authentication": {"type": "none"}},
+    {"_id": "req_3", "_type": "request", "parentId": "wrk_1", "name": "Log in", "metaSortKey": -1, "method": "POST",
+     "url": "{{ _.host }}/login", "headers": [],
+     "body": {"mimeType": "application/x-www-form-urlencoded", "params": [{"name": "user", "value": "jane"}, {"name": "remember", "value": "1", "disabled": true}]},
+     "authentication": {"type": "apikey", "key": "api_key", "value": "{{ key }}", "addTo": "queryParams"},
+     "afterResponseScript": "insomnia.environment.set('token', insomnia.response.json().token);"},
+    {"_id": "req_4", "_type": "request", "parentId": "wrk_1", "name": "Upload", "metaSortKey": 0, "method": "POST",
+     "url": "{{ _.host }}/upload", "headers": [], "body": {"mimeType": "multipart/form-data", "params": []},
+     "authentication": {"type": "oauth2", "grantType": "client_credentials"}},
+    {"_id": "grpc_1", "_type": "grpc_request", "parentId": "wrk_1", "name": "Stream", "metaSortKey": 5}
+  ]
+}`
+
+func TestImportColl_Insomnia(t *testing.T) {
+  got, err := importColl(strings.NewReader(insomniaTest))
+  if nil != err {
+    t.Fatalf("unexpected error: %s", err)
+  }
+
+  want := &coll{
+    Item: []collItem{
+      {
+        Name: "Posts",
+        Auth: &collAuth{Type: "bearer", Bearer: []collAuthAttribute{{Key: "token", Value: "{{auth.token}}", Type: "string"}}},
+        Item: []collItem{
+          {
+            Name:        "List posts",
+            Description: &collDescription{Content: "Lists the **published** posts.", Type: "text/markdown"},
+            Request: &collRequest{
+              URL: collURL{
+                Raw:   "{{host}}/posts?page={{page}}",
+                Query: []collQueryParam{{Key: "page", Value: "{{page}}"}},
+              },
+              Method: "GET",
+              Header: []collHeader{},
+              Auth:   &collAuth{Type: "noauth"},
+            },
+          },
+          {
+            Name: "Create post",
+            Request: &collRequest{
+              URL:    collURL{Raw: "{{host}}/posts"},
+              Method: "POST",
+              Header: []collHeader{
+                {Key: "X-Request-ID", Value: "{{$guid}}"},
+                {Key: "X-Debug", Value: "1", Disabled: true},
+                {Key: "Content-Type", Value: "application/json"},
+              },
+              Body: &collBody{Mode: "raw", Raw: `{"title": "{{title}}", "at": "{% now 'iso-8601', '' %}"}`},
+            },
+          },
+        },
+      },
+      {
+        Name: "Log in",
+        Request: &collRequest{
+          URL:    collURL{Raw: "{{host}}/login"},
+          Method: "POST",
+          Header: []collHeader{{Key: "Content-Type", Value: "application/x-www-form-urlencoded"}},
+          Body:   &collBody{Mode: "urlencoded", URLEncoded: []collURLEncodedParameter{{Key: "user", Value: "jane"}}},
+          Auth: &collAuth{Type: "apikey", APIKey: []collAuthAttribute{
+            {Key: "key", Value: "api_key", Type: "string"},
+            {Key: "value", Value: "{{key}}", Type: "string"},
+            {Key: "in", Value: "query", Type: "string"},
+          }},
+        },
+      },
+      {
+        Name: "Upload",
+        Request: &collRequest{
+          URL:    collURL{Raw: "{{host}}/upload"},
+          Method: "POST",
+          Header: []collHeader{},
+          Auth:   &collAuth{Type: "oauth2"},
+        },
+      },
+    },
+    Variable: []collVariable{
+      {Key: "auth.token", Value: "abc", Type: "string"},
+      {Key: "host", Value: "https://fontseca.dev", Type: "string"},
+      {Key: "retries", Value: "3", Type: "string"},
+      {Key: "page", Value: "1", Type: "string"},
+    },
+    environments: []collEnvironment{
+      {Name: "Production", Variable: []collVariable{{Key: "host", Value: "https://api.fontseca.dev", Type: "string"}}},
+    },
+    unsupported: []string{
+      "Posts: the variables of the folder were merged into the collection variables",
+      "Posts / List posts: the disabled query parameter \"draft\" was not imported",
+      "Posts / Create post: the template tag {% now 'iso-8601', '' %} was kept as text",
+      "Log in: the disabled form field \"remember\" was not imported",
+      "Log in: scripts are not supported",
+      "Upload: oauth2 authentication is not supported",
+      "Upload: multipart bodies are not supported",
+      "Stream: Insomnia resources of type \"grpc_request\" are not supported",
+    },
+  }
+  want.Info.Name = "Blog API"
+  want.Info.Description = &collDescription{Content: "The blog.", Type: "text/markdown"}
+
+  if !reflect.DeepEqual(want, got) {
+    t.Fatal(cmp.Diff(want, got, collUnexported))
+  }
+}
+
+func TestInsomniaTemplate(t *testing.T) {
+  tests := []struct {
+    input, want string
+  }{
+    {"{{ _.host }}/users/{{id}}", "{{host}}/users/{{id}}"},
+    {"{{_.a.b-c}}", "{{a.b-c}}"},
+    {"{% uuid 'v4' %}", "{{$guid}}"},
+    {"{% now %} {% now 'unix' %}", "{{$isoTimestamp}} {{$timestamp}}"},
+    {"{% response 'body', 'req_1', 'b64::JC50b2tlbg==::46b' %}", "{% response 'body', 'req_1', 'b64::JC50b2tlbg==::46b' %}"},
+  }
+
+  for _, test := range tests {
+    if got := insomniaTemplate(&coll{}, "", test.input); test.want != got {
+      t.Errorf("insomniaTemplate(%q) = %q, want %q", test.input, got, test.want)
+    }
+  }
+}
+
+func TestImportColl_InsomniaInvalidIDs(t *testing.T) {
+  tests := []struct {
+    name, resources, want string
+  }{
+    {
+      name:      "self-parented group",
+      resources: `{"_id": "wrk_1", "_type": "workspace", "name": "Blog"}, {"_id": "fld_1", "_type": "request_group", "parentId": "fld_1", "name": "Posts"}`,
+      want:      `the Insomnia resource "fld_1" is its own parent`,
+    },
+    {
+      name:      "empty ID",
+      resources: `{"_id": "", "_type": "workspace", "name": "Blog"}, {"_id": "", "_type": "request_group", "parentId": "", "name": "Posts"}`,
+      want:      `the Insomnia resource "Blog" has no ID`,
+    },
+    {
+      name:      "duplicate ID",
+      resources: `{"_id": "wrk_1", "_type": "workspace", "name": "Blog"}, {"_id": "wrk_1", "_type": "request_group", "parentId": "wrk_1", "name": "Posts"}`,
+      want:      `duplicate Insomnia resource ID "wrk_1"`,
+    },
+  }
+
+  for _, test := range tests {
+    t.Run(test.name, func(t *testing.T) {
+      input := `{"_type": "export", "__export_format": 4, "resources": [` + test.resources + `]}`
+      if _, err := importColl(strings.NewReader(input)); nil == err || test.want != err.Error() {
+        t.Errorf("importColl() error = %v, want %q", err, test.want)
+      }
+    })
+  }
+}
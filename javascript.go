@@ -0,0 +1,294 @@
+package playground
+
+import (
+  "bytes"
+  "io"
+  "slices"
+  "strings"
+)
+
+var (
+  // jsKeywords are the reserved words of JavaScript, along with the contextual ones that start statements.
+  jsKeywords = map[string]bool{
+    "async": true, "await": true, "break": true, "case": true, "catch": true, "class": true, "const": true,
+    "continue": true, "debugger": true, "default": true, "delete": true, "do": true, "else": true,
+    "export": true, "extends": true, "finally": true, "for": true, "function": true, "if": true,
+    "import": true, "in": true, "instanceof": true, "let": true, "new": true, "return": true, "static": true,
+    "super": true, "switch": true, "this": true, "throw": true, "try": true, "typeof": true, "var": true,
+    "void": true, "while": true, "with": true, "yield": true,
+  }
+
+  // jsLiterals are the words that are values.
+  jsLiterals = map[string]bool{"true": true, "false": true, "null": true, "undefined": true, "NaN": true, "Infinity": true}
+
+  // jsRegexpKeywords are the keywords after which a slash starts a regular expression rather than a division.
+  jsRegexpKeywords = map[string]bool{
+    "return": true, "typeof": true, "instanceof": true, "in": true, "of": true, "new": true, "delete": true,
+    "void": true, "throw": true, "case": true, "do": true, "else": true, "yield": true, "await": true,
+  }
+
+  // jsObjectKeywords are the keywords and the punctuation after which a brace opens an object literal,
+  // written on one line, rather than a block; a colon only does so outside of blocks, where it ends
+  // the labels of cases.
+  jsObjectKeywords = map[string]bool{"=": true, "(": true, "[": true, ",": true, ":": true, "?": true, "return": true}
+
+  // jsSameLineKeywords are the keywords that stay on the line of the brace that ends the block before them.
+  jsSameLineKeywords = map[string]bool{"else": true, "catch": true, "finally": true, "while": true, "from": true, "as": true}
+)
+
+type javascriptFormatterImpl struct{}
+
+// A jsToken is a piece of a script.
+type jsToken struct {
+  kind     tokenKind // tokenText for identifiers, tokenString for strings, template literals and regular expressions.
+  raw      []byte
+  newlines int  // The number of line breaks before the token.
+  space    bool // Whether blanks are before the token.
+}
+
+// jsIsIdentifier reports whether c belongs to an identifier.
+func jsIsIdentifier(c byte) bool {
+  return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' || '_' == c || '$' == c || '\\' == c || c >= 0x80
+}
+
+// jsQuoted returns the end of the string that starts at n with a quote, which is at the next quote that is
+// not escaped, or at the end of the line if there is none.
+func jsQuoted(input []byte, n int) int {
+  quote := input[n]
+  for n++; n < len(input) && quote != input[n] && '\n' != input[n]; n++ {
+    if '\\' == input[n] {
+      n++
+    }
+  }
+
+  return min(n+1, len(input))
+}
+
+// jsTemplate returns the end of the template literal that starts at n with a backquote, skipping the
+// expressions in it, which may hold braces, strings and other template literals.
+func jsTemplate(input []byte, n int) int {
+  for n++; n < len(input); n++ {
+    switch {
+    case '\\' == input[n]:
+      n++
+    case '`' == input[n]:
+      return n + 1
+    case '$' == input[n] && n+1 < len(input) && '{' == input[n+1]:
+      braces := 0
+      for n += 2; n < len(input) && (braces > 0 || '}' != input[n]); n++ {
+        switch input[n] {
+        case '{':
+          braces++
+        case '}':
+          braces--
+        case '"', '\'':
+          n = jsQuoted(input, n) - 1
+        case '`':
+          n = jsTemplate(input, n) - 1
+        }
+      }
+    }
+  }
+
+  return len(input)
+}
+
+// jsRegexp returns the end of the regular expression that starts at n with a slash, along with its flags,
+// or -1 if the line ends before it does.
+func jsRegexp(input []byte, n int) int {
+  class := false
+  for n++; n < len(input) && '\n' != input[n]; n++ {
+    switch c := input[n]; {
+    case '\\' == c:
+      n++
+    case '[' == c:
+      class = true
+    case ']' == c:
+      class = false
+    case '/' == c && !class:
+      for n++; n < len(input) && jsIsIdentifier(input[n]); n++ {
+      }
+
+      return n
+    }
+  }
+
+  return -1
+}
+
+// jsRegexpAllowed reports whether a slash after the token last starts a regular expression.
+func jsRegexpAllowed(last *jsToken) bool {
+  switch {
+  case nil == last:
+    return true
+  case tokenPunctuation == last.kind:
+    return !slices.Contains([]string{")", "]", "}", "++", "--"}, string(last.raw))
+  case tokenKeyword == last.kind:
+    return jsRegexpKeywords[string(last.raw)]
+  }
+
+  return false
+}
+
+// jsLex splits input into tokens. Strings, template literals, regular expressions and comments are read
+// as they are, so that the braces and semicolons in them are not taken for punctuation.
+func jsLex(input []byte) []jsToken {
+  var tokens []jsToken
+  var last *jsToken /* The last token that is not a comment.  */
+  newlines, space := 0, false
+  for n := 0; n < len(input); {
+    start, kind := n, tokenPunctuation
+    switch c := input[n]; {
+    case ' ' == c || '\t' == c || '\r' == c || '\n' == c || '\f' == c || '\v' == c:
+      if '\n' == c {
+        newlines++
+      }
+
+      space = true
+      n++
+      continue
+    case bytes.HasPrefix(input[n:], []byte("//")):
+      if end := bytes.IndexByte(input[n:], '\n'); -1 != end {
+        n += end
+      } else {
+        n = len(input)
+      }
+
+      kind = tokenComment
+    case bytes.HasPrefix(input[n:], []byte("/*")):
+      if end := bytes.Index(input[n+2:], []byte("*/")); -1 != end {
+        n += end + 4
+      } else {
+        n = len(input)
+      }
+
+      kind = tokenComment
+    case '"' == c || '\'' == c:
+      n, kind = jsQuoted(input, n), tokenString
+    case '`' == c:
+      n, kind = jsTemplate(input, n), tokenString
+    case '/' == c && jsRegexpAllowed(last) && -1 != jsRegexp(input, n):
+      n, kind = jsRegexp(input, n), tokenString
+    case '0' <= c && c <= '9' || '.' == c && n+1 < len(input) && '0' <= input[n+1] && input[n+1] <= '9':
+      hexadecimal := bytes.HasPrefix(bytes.ToLower(input[n:min(n+2, len(input))]), []byte("0x"))
+      for n++; n < len(input); n++ {
+        exponent := !hexadecimal && ('+' == input[n] || '-' == input[n]) && ('e' == input[n-1] || 'E' == input[n-1])
+        if !jsIsIdentifier(input[n]) && '.' != input[n] && !exponent {
+          break
+        }
+      }
+
+      kind = tokenNumber
+    case jsIsIdentifier(c):
+      for n++; n < len(input) && jsIsIdentifier(input[n]); n++ {
+      }
+
+      switch word := string(input[start:n]); {
+      case jsKeywords[word] && (nil == last || !bytes.Equal([]byte{'.'}, last.raw)): /* Not a property, as in x.default.  */
+        kind = tokenKeyword
+      case jsLiterals[word]:
+        kind = tokenLiteral
+      default:
+        kind = tokenText
+      }
+    case bytes.HasPrefix(input[n:], []byte("++")), bytes.HasPrefix(input[n:], []byte("--")):
+      n += 2
+    default:
+      n++
+    }
+
+    tokens = append(tokens, jsToken{kind: kind, raw: input[start:n], newlines: newlines, space: space})
+    if tokenComment != kind {
+      last = &tokens[len(tokens)-1]
+    }
+
+    newlines, space = 0, false
+  }
+
+  return tokens
+}
+
+// A jsBracket is a bracket that is open.
+type jsBracket struct {
+  char   byte
+  indent int  // The indentation of the line it is on.
+  inline bool // Whether it is the brace of an object literal, which is not broken into lines.
+}
+
+// format implements a JavaScript formatter.
+func (f javascriptFormatterImpl) format(input []byte, output io.Writer, indent string) {
+  formatTokens(f, input, output, indent)
+}
+
+// tokens re-indents input by the brackets that are open at the start of every line, and breaks the lines
+// of minified scripts after opening braces and semicolons, and around closing braces. Nothing else is
+// changed: the blanks between the tokens on a line are kept, and the line breaks too, so that automatic
+// semicolon insertion works the same.
+func (javascriptFormatterImpl) tokens(input []byte, output tokenWriter, indent string) {
+  var (
+    brackets []jsBracket
+    previous *jsToken
+    current  int  // The indentation of the line being written.
+    inline   bool // Whether the last bracket that was closed was the brace of an object literal.
+  )
+
+  tokens := jsLex(input)
+  for n := range tokens {
+    token := &tokens[n]
+    top := jsBracket{}
+    if len(brackets) > 0 {
+      top = brackets[len(brackets)-1]
+    }
+
+    closing := tokenPunctuation == token.kind && 1 == len(token.raw) && strings.ContainsRune("}])", rune(token.raw[0]))
+    breaking := nil != previous && 0 != token.newlines
+    if nil != previous && tokenPunctuation == previous.kind {
+      switch string(previous.raw) {
+      case "{":
+        breaking = breaking || "}" != string(token.raw) && !top.inline
+      case ";":
+        breaking = breaking || (0 == top.char || '{' == top.char && !top.inline) && tokenComment != token.kind
+      case "}":
+        breaking = breaking || (tokenText == token.kind || tokenKeyword == token.kind) && !jsSameLineKeywords[string(token.raw)] && !inline
+      }
+    }
+
+    if nil != previous && "}" == string(token.raw) && "{" != string(previous.raw) && !top.inline {
+      breaking = true
+    }
+
+    if breaking {
+      current = 0
+      if len(brackets) > 0 {
+        current = top.indent
+        if !closing {
+          current++
+        }
+      }
+
+      line := "\n"
+      if token.newlines > 1 {
+        line = "\n\n"
+      }
+
+      output.token(tokenText, []byte(line+strings.Repeat(indent, current)))
+    } else if nil != previous && token.space {
+      output.token(tokenText, []byte{' '})
+    }
+
+    output.token(token.kind, token.raw)
+    switch {
+    case closing && len(brackets) > 0:
+      brackets, inline = brackets[:len(brackets)-1], top.inline
+    case tokenPunctuation == token.kind && 1 == len(token.raw) && strings.ContainsRune("{[(", rune(token.raw[0])):
+      object := '{' == token.raw[0] && nil != previous && jsObjectKeywords[string(previous.raw)]
+      if object && ":" == string(previous.raw) && '{' == top.char && !top.inline {
+        object = false
+      }
+
+      brackets = append(brackets, jsBracket{char: token.raw[0], indent: current, inline: object})
+    }
+
+    previous = token
+  }
+}
@@ -0,0 +1,46 @@
+package playground
+
+import (
+  "bytes"
+  "testing"
+)
+
+func TestFormatJavaScript(t *testing.T) {
+  tests := []struct {
+    input string
+    want  string
+  }{
+    {"function f(a){if(a){return a}else{g()}for(var i=0;i<3;i++){h(i)}}", `function f(a){
+  if(a){
+    return a
+  }else{
+    g()
+  }
+  for(var i=0;i<3;i++){
+    h(i)
+  }
+}`},
+    {"var r=/[/{]}/g;s='};{';t=`a${ {b:1}.b }c${`}`}`;x=a/2/b", "var r=/[/{]}/g;\ns='};{';\nt=`a${ {b:1}.b }c${`}`}`;\nx=a/2/b"},
+    {"if (a) {\n        b()\n\n\n    c() // {\n}\n", "if (a) {\n  b()\n\n  c() // {\n}"},
+    {"f(function(){return 1},[1,\n2]);x.default={}", "f(function(){\n  return 1\n},[1,\n  2]);\nx.default={}"},
+    {"do{i++}while(i<3)\nimport{a}from\"a\"", "do{\n  i++\n}while(i<3)\nimport{\n  a\n}from\"a\""},
+    {"}}a", "}\n}\na"},
+    {"var o={a:1,b:[1,2]};f({c:{d:2}},function(){return{e:3}})", "var o={a:1,b:[1,2]};\nf({c:{d:2}},function(){\n  return{e:3}\n})"},
+    {"const t=`${a}{`;if(a){b=`}`}", "const t=`${a}{`;\nif(a){\n  b=`}`\n}"},
+    {"x=/{/.test(s)?{a:1}:{};y()", "x=/{/.test(s)?{a:1}:{};\ny()"},
+    {"switch(a){case 1:{b()}default:c={d:1}}", "switch(a){\n  case 1:{\n    b()\n  }\n  default:c={d:1}\n}"},
+  }
+
+  for _, test := range tests {
+    buffer := bytes.Buffer{}
+    javascriptFormatter.format([]byte(test.input), &buffer, "  ")
+    if got := buffer.String(); test.want != got {
+      t.Errorf("format(%q) =\n%s\nwant\n%s", test.input, got, test.want)
+    }
+  }
+
+  want := `<span class="tok-keyword">return</span> <span class="tok-string">/a;}/</span><span class="tok-punct">.</span>test<span class="tok-punct">(</span><span class="tok-literal">null</span><span class="tok-punct">,</span><span class="tok-number">1e-3</span><span class="tok-punct">)</span>`
+  if got := string(highlightTokens(javascriptFormatter, []byte("return /a;}/.test(null,1e-3)"), "  ")); want != got {
+    t.Errorf("highlightTokens(...) =\n%s\nwant\n%s", got, want)
+  }
+}
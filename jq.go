@@ -0,0 +1,1578 @@
+package playground
+
+import (
+  "context"
+  "encoding/json"
+  "errors"
+  "fmt"
+  "maps"
+  "math"
+  "regexp"
+  "slices"
+  "strconv"
+  "strings"
+  "unicode/utf8"
+)
+
+// A jsonFilter turns a decoded JSON document into the values it selects or computes.
+type jsonFilter func(document any) ([]any, error)
+
+// maxFilterLength is the length of the longest filter expression that is accepted.
+const maxFilterLength = 4 << 10
+
+const (
+  maxFilterOutputs = 4 << 20         // maxFilterOutputs is the number of values, intermediate ones included, that a filter may output.
+  maxFilterBytes   = 8 * maxBodyBytes // maxFilterBytes is the size of the values that a filter may build, as JSON.
+)
+
+// A jqBudget bounds the work of a jq filter while it runs, since a short filter may output or build
+// exponentially many values, E.g: (.,.)|(.,.)|... or tostring|.+.|.+.|...
+type jqBudget struct {
+  ctx     context.Context // The filter stops when ctx is done.
+  outputs int             // The values that the filter may still output.
+  bytes   int             // The bytes that the values it builds may still take.
+}
+
+// newJQBudget returns the budget of a filter that runs until ctx is done.
+func newJQBudget(ctx context.Context) *jqBudget {
+  return &jqBudget{ctx: ctx, outputs: maxFilterOutputs, bytes: maxFilterBytes}
+}
+
+// spend counts n values output by the filter, and fails once there are too many of them or the
+// context of the filter is done.
+func (b *jqBudget) spend(n int) error {
+  if b.outputs -= n; b.outputs < 0 {
+    return fmt.Errorf("the filter outputs more than %d values", maxFilterOutputs)
+  }
+
+  return b.ctx.Err()
+}
+
+// build counts values that the filter built, and fails once they take too much memory.
+func (b *jqBudget) build(values ...any) error {
+  for value := range slices.Values(values) {
+    if b.bytes -= jsonSize(value, b.bytes); b.bytes < 0 {
+      return fmt.Errorf("the filter builds more than %d MB of values", maxFilterBytes>>20)
+    }
+  }
+
+  return b.spend(len(values))
+}
+
+// jsonSize returns about the size of value written as JSON, or a size larger than limit as soon as
+// it exceeds it, since the values that a filter builds may share their items.
+func jsonSize(value any, limit int) (size int) {
+  switch value := value.(type) {
+  case string:
+    return len(value) + 2
+  case []any:
+    size = 2
+    for n := 0; n < len(value) && size <= limit; n++ {
+      size += 1 + jsonSize(value[n], limit-size)
+    }
+  case map[string]any:
+    size = 2
+    for key, member := range value {
+      if size += len(key) + 4 + jsonSize(member, limit-size); size > limit {
+        break
+      }
+    }
+  default:
+    size = 8
+  }
+
+  return size
+}
+
+// A jqSyntaxError is a mistake in the writing of a jq filter, as opposed to an error raised while
+// the filter runs.
+type jqSyntaxError struct {
+  message string
+  offset  int // The offset of the mistake in the filter.
+}
+
+func (e *jqSyntaxError) Error() string {
+  return fmt.Sprintf("%s at offset %d", e.message, e.offset)
+}
+
+// compileJQ compiles a filter written in a subset of the jq language: paths (.a.b, .[0], .[1:3],
+// .["a b"], .[], ..), the optional operator ?, pipes, commas, parentheses, array and object
+// construction, literals, arithmetic (+ - * / %), comparisons, and, or, the alternative operator //,
+// and the most common builtins, such as select, map, keys, length, sort_by and to_entries. The
+// filter fails once it exceeds budget.
+func compileJQ(expression string, budget *jqBudget) (jsonFilter, error) {
+  if len(expression) > maxFilterLength {
+    return nil, &jqSyntaxError{message: "the filter is too long", offset: maxFilterLength}
+  }
+
+  tokens, err := lexJQ(expression)
+  if nil != err {
+    return nil, err
+  }
+
+  p := &jqParser{tokens: tokens, budget: budget}
+  filter, err := p.pipe()
+  if nil != err {
+    return nil, err
+  }
+
+  if token := p.peek(); "" != token.kind {
+    return nil, p.fail(token, fmt.Sprintf("unexpected %s", token.text))
+  }
+
+  return filter, nil
+}
+
+// A jqToken is a lexeme of a jq filter: punctuation, an identifier, a field (.name), a number or a
+// string, whose decoded value is kept.
+type jqToken struct {
+  kind   string // punct, ident, field, number, string, or empty at the end of the filter.
+  text   string
+  value  any
+  offset int
+}
+
+// jqPunctuation lists the operators and the punctuation of jq, the longest first.
+var jqPunctuation = []string{"..", "//", "==", "!=", "<=", ">=", ".", "[", "]", "{", "}", "(", ")", "|", ",", ":", ";", "?", "<", ">", "+", "-", "*", "/", "%", "$"}
+
+// lexJQ splits a jq filter into its tokens.
+func lexJQ(expression string) (tokens []jqToken, err error) {
+  isName := func(c byte, first bool) bool {
+    return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '_' == c || !first && '0' <= c && c <= '9'
+  }
+
+  for n := 0; n < len(expression); {
+    c := expression[n]
+    switch {
+    case strings.ContainsRune(" \t\r\n", rune(c)):
+      n++
+    case '#' == c: /* A comment, up to the end of the line.  */
+      for n < len(expression) && '\n' != expression[n] {
+        n++
+      }
+    case '.' == c && n+1 < len(expression) && isName(expression[n+1], true):
+      start := n
+      for n++; n < len(expression) && isName(expression[n], false); n++ {
+      }
+
+      tokens = append(tokens, jqToken{kind: "field", text: expression[start:n], value: expression[start+1 : n], offset: start})
+    case isName(c, true):
+      start := n
+      for ; n < len(expression) && (isName(expression[n], false) || "::" == expression[n:min(n+2, len(expression))]); n++ {
+      }
+
+      tokens = append(tokens, jqToken{kind: "ident", text: expression[start:n], offset: start})
+    case '0' <= c && c <= '9' || '.' == c && n+1 < len(expression) && '0' <= expression[n+1] && expression[n+1] <= '9':
+      start := n
+      for n < len(expression) && strings.ContainsRune("0123456789.eE", rune(expression[n])) || n > start && strings.ContainsRune("eE", rune(expression[n-1])) && strings.ContainsRune("+-", rune(expression[n])) {
+        n++
+      }
+
+      number, err := strconv.ParseFloat(expression[start:n], 64)
+      if nil != err {
+        return nil, &jqSyntaxError{message: fmt.Sprintf("invalid number %s", expression[start:n]), offset: start}
+      }
+
+      tokens = append(tokens, jqToken{kind: "number", text: expression[start:n], value: number, offset: start})
+    case '"' == c:
+      start := n
+      for n++; n < len(expression) && '"' != expression[n]; n++ {
+        if '\\' == expression[n] {
+          n++
+        }
+      }
+
+      if n >= len(expression) {
+        return nil, &jqSyntaxError{message: "unterminated string", offset: start}
+      }
+
+      n++
+      var value string
+      if err := json.Unmarshal([]byte(expression[start:n]), &value); nil != err {
+        if strings.Contains(expression[start:n], `\(`) {
+          return nil, &jqSyntaxError{message: "string interpolation is not supported", offset: start}
+        }
+
+        return nil, &jqSyntaxError{message: fmt.Sprintf("invalid string %s", expression[start:n]), offset: start}
+      }
+
+      tokens = append(tokens, jqToken{kind: "string", text: expression[start:n], value: value, offset: start})
+    default:
+      i := slices.IndexFunc(jqPunctuation, func(p string) bool { return strings.HasPrefix(expression[n:], p) })
+      if -1 == i {
+        return nil, &jqSyntaxError{message: fmt.Sprintf("unexpected %q", c), offset: n}
+      }
+
+      tokens = append(tokens, jqToken{kind: "punct", text: jqPunctuation[i], offset: n})
+      n += len(jqPunctuation[i])
+    }
+  }
+
+  return tokens, nil
+}
+
+// A jqParser builds a filter from tokens, starting at n, whose evaluation draws from budget.
+type jqParser struct {
+  tokens []jqToken
+  n      int
+  budget *jqBudget
+}
+
+// peek returns the token at n, which is empty at the end of the filter.
+func (p *jqParser) peek() jqToken {
+  if p.n < len(p.tokens) {
+    return p.tokens[p.n]
+  }
+
+  end := 0
+  if len(p.tokens) > 0 {
+    last := p.tokens[len(p.tokens)-1]
+    end = last.offset + len(last.text)
+  }
+
+  return jqToken{text: "end of filter", offset: end}
+}
+
+// accept skips the punctuation or the identifier text at n, if it is there, and reports whether it was.
+func (p *jqParser) accept(text string) bool {
+  if token := p.peek(); ("punct" == token.kind || "ident" == token.kind) && text == token.text {
+    p.n++
+    return true
+  }
+
+  return false
+}
+
+// expect skips text at n, or fails if it is not there.
+func (p *jqParser) expect(text string) error {
+  if !p.accept(text) {
+    return p.fail(p.peek(), fmt.Sprintf("expected %s, got %s", text, p.peek().text))
+  }
+
+  return nil
+}
+
+// fail returns a syntax error at token.
+func (p *jqParser) fail(token jqToken, message string) error {
+  return &jqSyntaxError{message: message, offset: token.offset}
+}
+
+// pipe reads filters separated by |, the loosest operator.
+func (p *jqParser) pipe() (jsonFilter, error) {
+  left, err := p.comma()
+  if nil != err || !p.accept("|") {
+    return left, err
+  }
+
+  right, err := p.pipe()
+  if nil != err {
+    return nil, err
+  }
+
+  return jqThen(p.budget, left, right), nil
+}
+
+// comma reads filters separated by commas, whose outputs are concatenated.
+func (p *jqParser) comma() (jsonFilter, error) {
+  left, err := p.alternative()
+  for nil == err && p.accept(",") {
+    var right jsonFilter
+    if right, err = p.alternative(); nil == err {
+      left = func(first, second jsonFilter) jsonFilter {
+        return func(input any) ([]any, error) {
+          outputs, err := first(input)
+          if nil != err {
+            return outputs, err
+          }
+
+          more, err := second(input)
+          if nil == err {
+            err = p.budget.spend(len(more))
+          }
+
+          return append(outputs, more...), err
+        }
+      }(left, right)
+    }
+  }
+
+  return left, err
+}
+
+// alternative reads a // b, which outputs the values of a that are neither false nor null, or the
+// values of b if there are none.
+func (p *jqParser) alternative() (jsonFilter, error) {
+  left, err := p.binary(0)
+  if nil != err || !p.accept("//") {
+    return left, err
+  }
+
+  right, err := p.alternative()
+  if nil != err {
+    return nil, err
+  }
+
+  return func(input any) ([]any, error) {
+    values, _ := left(input)
+    var outputs []any
+    for value := range slices.Values(values) {
+      if jsonTruthy(value) {
+        outputs = append(outputs, value)
+      }
+    }
+
+    if len(outputs) > 0 {
+      return outputs, nil
+    }
+
+    return right(input)
+  }, nil
+}
+
+// jqPrecedence lists the binary operators from the loosest to the tightest.
+var jqPrecedence = [][]string{{"or"}, {"and"}, {"==", "!=", "<", "<=", ">", ">="}, {"+", "-"}, {"*", "/", "%"}}
+
+// binary reads the operands of the binary operators of the given level of precedence and tighter.
+func (p *jqParser) binary(level int) (jsonFilter, error) {
+  if level == len(jqPrecedence) {
+    return p.unary()
+  }
+
+  left, err := p.binary(level + 1)
+  for nil == err {
+    operator := p.peek().text
+    if !slices.Contains(jqPrecedence[level], operator) || !p.accept(operator) {
+      break
+    }
+
+    var right jsonFilter
+    if right, err = p.binary(level + 1); nil == err {
+      left = jqBinary(p.budget, operator, left, right)
+    }
+
+    if 2 == level && nil == err && slices.Contains(jqPrecedence[level], p.peek().text) {
+      return nil, p.fail(p.peek(), fmt.Sprintf("unexpected %s", p.peek().text)) /* Comparisons do not chain.  */
+    }
+  }
+
+  return left, err
+}
+
+// jqBinary applies operator to every pair of the outputs of left and right.
+func jqBinary(budget *jqBudget, operator string, left, right jsonFilter) jsonFilter {
+  return func(input any) (outputs []any, err error) {
+    rights, err := right(input)
+    if nil != err {
+      return nil, err
+    }
+
+    lefts, err := left(input)
+    if nil != err {
+      return nil, err
+    }
+
+    if err := budget.spend(len(lefts) * len(rights)); nil != err {
+      return nil, err
+    }
+
+    for r := range slices.Values(rights) {
+      for l := range slices.Values(lefts) {
+        var value any
+        switch operator {
+        case "and":
+          value = jsonTruthy(l) && jsonTruthy(r)
+        case "or":
+          value = jsonTruthy(l) || jsonTruthy(r)
+        case "==":
+          value = jsonEqual(l, r)
+        case "!=":
+          value = !jsonEqual(l, r)
+        case "<":
+          value = jsonCompare(l, r) < 0
+        case "<=":
+          value = jsonCompare(l, r) <= 0
+        case ">":
+          value = jsonCompare(l, r) > 0
+        case ">=":
+          value = jsonCompare(l, r) >= 0
+        default:
+          if value, err = jqArithmetic(operator, l, r); nil != err {
+            return outputs, err
+          }
+
+          if err = budget.build(value); nil != err {
+            return outputs, err
+          }
+        }
+
+        outputs = append(outputs, value)
+      }
+    }
+
+    return outputs, nil
+  }
+}
+
+// jqDescribe writes a value the way jq does in its errors, E.g: number (1) or string ("abc...").
+func jqDescribe(value any) string {
+  encoded, _ := json.Marshal(value)
+  if utf8.RuneCount(encoded) > 11 {
+    encoded = append([]byte(string([]rune(string(encoded))[:10])), "..."...)
+  }
+
+  return fmt.Sprintf("%s (%s)", jsonType(value), encoded)
+}
+
+// jqArithmetic computes l operator r for +, -, *, / and %.
+func jqArithmetic(operator string, l, r any) (any, error) {
+  x, lNumber := jsonNumber(l)
+  y, rNumber := jsonNumber(r)
+  switch {
+  case lNumber && rNumber:
+    switch operator {
+    case "+":
+      return x + y, nil
+    case "-":
+      return x - y, nil
+    case "*":
+      return x * y, nil
+    case "/":
+      if 0 == y {
+        return nil, fmt.Errorf("%s and %s cannot be divided because the divisor is zero", jqDescribe(l), jqDescribe(r))
+      }
+
+      return x / y, nil
+    case "%":
+      if 0 == int64(y) {
+        return nil, fmt.Errorf("%s and %s cannot be divided because the divisor is zero", jqDescribe(l), jqDescribe(r))
+      }
+
+      return float64(int64(x) % int64(y)), nil
+    }
+  case "+" == operator && nil == l:
+    return r, nil
+  case "+" == operator && nil == r:
+    return l, nil
+  }
+
+  switch l := l.(type) {
+  case string:
+    if r, ok := r.(string); ok {
+      switch operator {
+      case "+":
+        return l + r, nil
+      case "/":
+        return jqStrings(strings.Split(l, r)), nil
+      }
+    }
+  case []any:
+    if r, ok := r.([]any); ok {
+      switch operator {
+      case "+":
+        return slices.Concat(l, r), nil
+      case "-":
+        return slices.DeleteFunc(slices.Clone(l), func(v any) bool {
+          return slices.ContainsFunc(r, func(w any) bool { return jsonEqual(v, w) })
+        }), nil
+      }
+    }
+  case map[string]any:
+    if r, ok := r.(map[string]any); ok && "+" == operator {
+      merged := maps.Clone(l)
+      maps.Copy(merged, r)
+      return merged, nil
+    }
+  }
+
+  verb := map[string]string{"+": "added", "-": "subtracted", "*": "multiplied", "/": "divided", "%": "divided"}[operator]
+  return nil, fmt.Errorf("%s and %s cannot be %s", jqDescribe(l), jqDescribe(r), verb)
+}
+
+// jqStrings converts strings to decoded JSON values.
+func jqStrings(values []string) []any {
+  converted := make([]any, len(values))
+  for n, value := range values {
+    converted[n] = value
+  }
+
+  return converted
+}
+
+// unary reads a negated term, or a term.
+func (p *jqParser) unary() (jsonFilter, error) {
+  if !p.accept("-") {
+    return p.postfix()
+  }
+
+  operand, err := p.postfix()
+  if nil != err {
+    return nil, err
+  }
+
+  return func(input any) (outputs []any, err error) {
+    values, err := operand(input)
+    for value := range slices.Values(values) {
+      number, ok := jsonNumber(value)
+      if !ok {
+        return outputs, fmt.Errorf("%s cannot be negated", jqDescribe(value))
+      }
+
+      outputs = append(outputs, -number)
+    }
+
+    return outputs, err
+  }, nil
+}
+
+// postfix reads a term followed by any number of fields, brackets and ? operators.
+func (p *jqParser) postfix() (jsonFilter, error) {
+  term, err := p.term()
+  for nil == err {
+    switch token := p.peek(); {
+    case "field" == token.kind:
+      p.n++
+      term = jqThen(p.budget, term, jqIndex(jqLiteral(token.value)))
+    case "." == token.text && "punct" == token.kind && p.n+1 < len(p.tokens) && ("string" == p.tokens[p.n+1].kind || "[" == p.tokens[p.n+1].text):
+      p.n++
+    case "[" == token.text && "punct" == token.kind:
+      p.n++
+      var bracket jsonFilter
+      if bracket, err = p.bracket(); nil == err {
+        term = jqThen(p.budget, term, bracket)
+      }
+    case "string" == token.kind && p.n > 0 && "." == p.tokens[p.n-1].text:
+      p.n++
+      term = jqThen(p.budget, term, jqIndex(jqLiteral(token.value)))
+    case "?" == token.text && "punct" == token.kind:
+      p.n++
+      term = jqTry(term)
+    default:
+      return term, nil
+    }
+  }
+
+  return nil, err
+}
+
+// bracket reads what follows a [ that indexes, slices or iterates, up to the ].
+func (p *jqParser) bracket() (jsonFilter, error) {
+  if p.accept("]") {
+    return jqIterate, nil
+  }
+
+  var from, to jsonFilter
+  var err error
+  if !p.accept(":") {
+    if from, err = p.pipe(); nil != err {
+      return nil, err
+    }
+
+    if !p.accept(":") {
+      return jqIndex(from), p.expect("]")
+    }
+  }
+
+  if "]" != p.peek().text {
+    if to, err = p.pipe(); nil != err {
+      return nil, err
+    }
+  }
+
+  if nil == from && nil == to {
+    return nil, p.fail(p.peek(), "missing slice bounds")
+  }
+
+  return jqSlice(from, to), p.expect("]")
+}
+
+// term reads the identity, a recursion, a field, a literal, a parenthesized filter, an array or an
+// object construction, or a call to a builtin.
+func (p *jqParser) term() (jsonFilter, error) {
+  token := p.peek()
+  switch token.kind {
+  case "":
+    return nil, p.fail(token, "unexpected end of filter")
+  case "field":
+    p.n++
+    return jqIndex(jqLiteral(token.value)), nil
+  case "number", "string":
+    p.n++
+    return jqLiteral(token.value), nil
+  case "ident":
+    return p.call()
+  }
+
+  p.n++
+  switch token.text {
+  case ".":
+    return jqIdentity, nil
+  case "..":
+    return func(input any) ([]any, error) {
+      descendants := jsonDescendants(input)
+      return descendants, p.budget.spend(len(descendants))
+    }, nil
+  case "(":
+    filter, err := p.pipe()
+    if nil != err {
+      return nil, err
+    }
+
+    return filter, p.expect(")")
+  case "[":
+    if p.accept("]") {
+      return jqLiteral([]any{}), nil
+    }
+
+    filter, err := p.pipe()
+    if nil != err {
+      return nil, err
+    }
+
+    return func(input any) ([]any, error) {
+      items, err := filter(input)
+      if nil != err {
+        return nil, err
+      }
+
+      array := append([]any{}, items...)
+      return []any{array}, p.budget.build(array)
+    }, p.expect("]")
+  case "{":
+    return p.object()
+  case "$":
+    return nil, p.fail(token, "variables are not supported")
+  }
+
+  return nil, p.fail(token, fmt.Sprintf("unexpected %s", token.text))
+}
+
+// object reads the members of an object construction, up to the }: key: value, "key": value,
+// (filter): value, or just key, which is short for key: .key.
+func (p *jqParser) object() (jsonFilter, error) {
+  type member struct{ key, value jsonFilter }
+  var members []member
+
+  for !p.accept("}") {
+    if len(members) > 0 {
+      if err := p.expect(","); nil != err {
+        return nil, err
+      }
+    }
+
+    var m member
+    switch token := p.peek(); {
+    case "ident" == token.kind || "string" == token.kind:
+      p.n++
+      name := token.text
+      if "string" == token.kind {
+        name = token.value.(string)
+      }
+
+      m.key, m.value = jqLiteral(name), jqIndex(jqLiteral(name))
+    case "(" == token.text:
+      p.n++
+      key, err := p.pipe()
+      if nil != err {
+        return nil, err
+      }
+
+      if err := p.expect(")"); nil != err {
+        return nil, err
+      }
+
+      m.key = key
+    default:
+      return nil, p.fail(token, fmt.Sprintf("unexpected %s in object", token.text))
+    }
+
+    if p.accept(":") {
+      value, err := p.alternative()
+      if nil != err {
+        return nil, err
+      }
+
+      m.value = value
+    } else if nil == m.value {
+      return nil, p.fail(p.peek(), "expected :")
+    }
+
+    members = append(members, m)
+  }
+
+  return func(input any) ([]any, error) {
+    objects := []map[string]any{{}}
+    for m := range slices.Values(members) {
+      keys, err := m.key(input)
+      if nil != err {
+        return nil, err
+      }
+
+      values, err := m.value(input)
+      if nil != err {
+        return nil, err
+      }
+
+      if err := p.budget.spend(len(objects) * len(keys) * len(values)); nil != err {
+        return nil, err
+      }
+
+      var combined []map[string]any
+      for object := range slices.Values(objects) {
+        for key := range slices.Values(keys) {
+          name, ok := key.(string)
+          if !ok {
+            return nil, fmt.Errorf("object keys must be strings, got %s", jqDescribe(key))
+          }
+
+          for value := range slices.Values(values) {
+            extended := maps.Clone(object)
+            extended[name] = value
+            if err := p.budget.build(extended); nil != err {
+              return nil, err
+            }
+
+            combined = append(combined, extended)
+          }
+        }
+      }
+
+      objects = combined
+    }
+
+    outputs := make([]any, len(objects))
+    for n, object := range objects {
+      outputs[n] = object
+    }
+
+    return outputs, nil
+  }, nil
+}
+
+// call reads a literal (true, false, null) or a call to a builtin, whose arguments are separated by
+// semicolons.
+func (p *jqParser) call() (jsonFilter, error) {
+  token := p.tokens[p.n]
+  p.n++
+  switch token.text {
+  case "true":
+    return jqLiteral(true), nil
+  case "false":
+    return jqLiteral(false), nil
+  case "null":
+    return jqLiteral(nil), nil
+  }
+
+  var args []jsonFilter
+  if p.accept("(") {
+    for {
+      arg, err := p.pipe()
+      if nil != err {
+        return nil, err
+      }
+
+      args = append(args, arg)
+      if !p.accept(";") {
+        break
+      }
+    }
+
+    if err := p.expect(")"); nil != err {
+      return nil, err
+    }
+  }
+
+  name := fmt.Sprint(token.text, "/", len(args))
+  builtin, exists := jqBuiltins[name]
+  if !exists {
+    return nil, p.fail(token, fmt.Sprintf("unknown function %s", name))
+  }
+
+  return func(input any) ([]any, error) {
+    outputs, err := builtin(input, args)
+    switch {
+    case nil != err:
+      return outputs, err
+    case jqPassthroughs[name]:
+      return outputs, p.budget.spend(len(outputs))
+    }
+
+    return outputs, p.budget.build(outputs...)
+  }, nil
+}
+
+// jqIdentity outputs its input.
+func jqIdentity(input any) ([]any, error) {
+  return []any{input}, nil
+}
+
+// jqLiteral returns a filter that outputs value.
+func jqLiteral(value any) jsonFilter {
+  return func(any) ([]any, error) { return []any{value}, nil }
+}
+
+// jqThen returns a filter that runs then on every output of first.
+func jqThen(budget *jqBudget, first, then jsonFilter) jsonFilter {
+  return func(input any) (outputs []any, err error) {
+    values, err := first(input)
+    for value := range slices.Values(values) {
+      results, err := then(value)
+      outputs = append(outputs, results...)
+      if nil != err {
+        return outputs, err
+      }
+
+      if err := budget.spend(len(results)); nil != err {
+        return outputs, err
+      }
+    }
+
+    return outputs, err
+  }
+}
+
+// jqTry returns a filter that outputs what filter outputs before failing, without failing.
+func jqTry(filter jsonFilter) jsonFilter {
+  return func(input any) ([]any, error) {
+    outputs, _ := filter(input)
+    return outputs, nil
+  }
+}
+
+// jqIterate outputs the items of an array or the values of an object, sorted by key.
+func jqIterate(input any) ([]any, error) {
+  switch input.(type) {
+  case []any, map[string]any:
+    return jsonChildren(input), nil
+  }
+
+  return nil, fmt.Errorf("cannot iterate over %s", jqDescribe(input))
+}
+
+// jqIndex returns a filter that outputs the member or the item of its input at every output of key.
+func jqIndex(key jsonFilter) jsonFilter {
+  return func(input any) (outputs []any, err error) {
+    keys, err := key(input)
+    if nil != err {
+      return nil, err
+    }
+
+    for k := range slices.Values(keys) {
+      index, isNumber := jsonNumber(k)
+      name, isString := k.(string)
+      switch input := input.(type) {
+      case nil:
+        if !isNumber && !isString && nil != k {
+          return outputs, fmt.Errorf("cannot index null with %s", jsonType(k))
+        }
+
+        outputs = append(outputs, nil)
+        continue
+      case map[string]any:
+        if isString {
+          outputs = append(outputs, input[name])
+          continue
+        }
+      case []any:
+        if isNumber {
+          n := int(math.Floor(index))
+          if n < 0 {
+            n += len(input)
+          }
+
+          if n < 0 || n >= len(input) {
+            outputs = append(outputs, nil)
+          } else {
+            outputs = append(outputs, input[n])
+          }
+
+          continue
+        }
+      }
+
+      if isString {
+        return outputs, fmt.Errorf("cannot index %s with %q", jsonType(input), name)
+      }
+
+      return outputs, fmt.Errorf("cannot index %s with %s", jsonType(input), jsonType(k))
+    }
+
+    return outputs, nil
+  }
+}
+
+// jqSlice returns a filter that outputs the part of an array or a string between the outputs of from
+// and to, either of which may be nil.
+func jqSlice(from, to jsonFilter) jsonFilter {
+  bounds := func(filter jsonFilter, input any) ([]any, error) {
+    if nil == filter {
+      return []any{nil}, nil
+    }
+
+    return filter(input)
+  }
+
+  return func(input any) (outputs []any, err error) {
+    starts, err := bounds(from, input)
+    if nil != err {
+      return nil, err
+    }
+
+    ends, err := bounds(to, input)
+    if nil != err {
+      return nil, err
+    }
+
+    for start := range slices.Values(starts) {
+      for end := range slices.Values(ends) {
+        var length int
+        switch input := input.(type) {
+        case nil:
+          outputs = append(outputs, nil)
+          continue
+        case []any:
+          length = len(input)
+        case string:
+          length = utf8.RuneCountInString(input)
+        default:
+          return outputs, fmt.Errorf("cannot slice %s", jsonType(input))
+        }
+
+        position := func(bound any, fallback int) (int, error) {
+          if nil == bound {
+            return fallback, nil
+          }
+
+          number, ok := jsonNumber(bound)
+          if !ok {
+            return 0, fmt.Errorf("slice bounds must be numbers, got %s", jqDescribe(bound))
+          }
+
+          n := int(math.Floor(number))
+          if n < 0 {
+            n += length
+          }
+
+          return min(max(n, 0), length), nil
+        }
+
+        s, err := position(start, 0)
+        if nil != err {
+          return outputs, err
+        }
+
+        e, err := position(end, length)
+        if nil != err {
+          return outputs, err
+        }
+
+        e = max(s, e)
+        if array, ok := input.([]any); ok {
+          outputs = append(outputs, append([]any{}, array[s:e]...))
+        } else {
+          outputs = append(outputs, string([]rune(input.(string))[s:e]))
+        }
+      }
+    }
+
+    return outputs, nil
+  }
+}
+
+// jqBuiltin is a builtin function of jq, called with its input and its arguments.
+type jqBuiltin func(input any, args []jsonFilter) ([]any, error)
+
+// jqBuiltins are the builtins of jq that are supported, by name and arity, E.g: map/1.
+var jqBuiltins map[string]jqBuiltin
+
+// jqPassthroughs are the builtins that output their input or parts of it, rather than values they
+// build, so that only their number counts against the budget of a filter.
+var jqPassthroughs = map[string]bool{
+  "empty/0": true, "values/0": true, "recurse/0": true, "first/0": true, "last/0": true, "min/0": true,
+  "max/0": true, "select/1": true, "first/1": true, "last/1": true,
+}
+
+func init() {
+  /* Assigned here, since some builtins refer to the map.  */
+  jqBuiltins = map[string]jqBuiltin{
+    "empty/0":          func(any, []jsonFilter) ([]any, error) { return nil, nil },
+    "not/0":            jqFunc(func(input any) (any, error) { return !jsonTruthy(input), nil }),
+    "type/0":           jqFunc(func(input any) (any, error) { return jsonType(input), nil }),
+    "length/0":         jqFunc(jqLength),
+    "keys/0":           jqFunc(jqKeys),
+    "values/0":         jqBuiltinSelect(func(v any) bool { return nil != v }),
+    "recurse/0":        func(input any, _ []jsonFilter) ([]any, error) { return jsonDescendants(input), nil },
+    "first/0":          func(input any, _ []jsonFilter) ([]any, error) { return jqIndex(jqLiteral(0.0))(input) },
+    "last/0":           func(input any, _ []jsonFilter) ([]any, error) { return jqIndex(jqLiteral(-1.0))(input) },
+    "reverse/0":        jqFunc(jqReverse),
+    "sort/0":           jqFunc(func(input any) (any, error) { return jqSortBy(input, nil) }),
+    "unique/0":         jqFunc(func(input any) (any, error) { return jqUnique(input) }),
+    "min/0":            jqFunc(func(input any) (any, error) { return jqExtreme(input, -1) }),
+    "max/0":            jqFunc(func(input any) (any, error) { return jqExtreme(input, 1) }),
+    "add/0":            jqFunc(jqAdd),
+    "any/0":            jqFunc(func(input any) (any, error) { return jqQuantify(input, nil, true) }),
+    "all/0":            jqFunc(func(input any) (any, error) { return jqQuantify(input, nil, false) }),
+    "floor/0":          jqFunc(jqFloor),
+    "tostring/0":       jqFunc(jqToString),
+    "tonumber/0":       jqFunc(jqToNumber),
+    "tojson/0":         jqFunc(func(input any) (any, error) { encoded, err := json.Marshal(input); return string(encoded), err }),
+    "fromjson/0":       jqFunc(jqFromJSON),
+    "ascii_downcase/0": jqFunc(jqStringFunc("ascii_downcase", strings.ToLower)),
+    "ascii_upcase/0":   jqFunc(jqStringFunc("ascii_upcase", strings.ToUpper)),
+    "to_entries/0":     jqFunc(jqToEntries),
+    "from_entries/0":   jqFunc(jqFromEntries),
+    "select/1":         jqSelect,
+    "map/1":            jqMap,
+    "map_values/1":     jqMapValues,
+    "has/1":            jqArgFunc(jqHas),
+    "contains/1":       jqArgFunc(func(input, arg any) (any, error) { return jqContains(input, arg) }),
+    "join/1":           jqArgFunc(jqJoin),
+    "split/1":          jqArgFunc(jqSplit),
+    "test/1":           jqArgFunc(jqTest),
+    "startswith/1":     jqArgFunc(jqAffix("startswith", strings.HasPrefix)),
+    "endswith/1":       jqArgFunc(jqAffix("endswith", strings.HasSuffix)),
+    "sort_by/1":        func(input any, args []jsonFilter) ([]any, error) { sorted, err := jqSortBy(input, args[0]); return []any{sorted}, err },
+    "any/1":            func(input any, args []jsonFilter) ([]any, error) { value, err := jqQuantify(input, args[0], true); return []any{value}, err },
+    "all/1":            func(input any, args []jsonFilter) ([]any, error) { value, err := jqQuantify(input, args[0], false); return []any{value}, err },
+    "first/1":          jqFirst,
+    "last/1":           jqLast,
+    "with_entries/1":   jqWithEntries,
+  }
+}
+
+// jqFunc makes a builtin without arguments out of a function of its input.
+func jqFunc(f func(input any) (any, error)) jqBuiltin {
+  return func(input any, _ []jsonFilter) ([]any, error) {
+    value, err := f(input)
+    if nil != err {
+      return nil, err
+    }
+
+    return []any{value}, nil
+  }
+}
+
+// jqArgFunc makes a builtin with one argument out of a function of its input and every output of
+// the argument.
+func jqArgFunc(f func(input, arg any) (any, error)) jqBuiltin {
+  return func(input any, args []jsonFilter) (outputs []any, err error) {
+    values, err := args[0](input)
+    if nil != err {
+      return nil, err
+    }
+
+    for arg := range slices.Values(values) {
+      value, err := f(input, arg)
+      if nil != err {
+        return outputs, err
+      }
+
+      outputs = append(outputs, value)
+    }
+
+    return outputs, nil
+  }
+}
+
+// jqBuiltinSelect makes a builtin that outputs its input if keep holds for it.
+func jqBuiltinSelect(keep func(any) bool) jqBuiltin {
+  return func(input any, _ []jsonFilter) ([]any, error) {
+    if keep(input) {
+      return []any{input}, nil
+    }
+
+    return nil, nil
+  }
+}
+
+func jqLength(input any) (any, error) {
+  switch input := input.(type) {
+  case nil:
+    return 0.0, nil
+  case string:
+    return float64(utf8.RuneCountInString(input)), nil
+  case []any:
+    return float64(len(input)), nil
+  case map[string]any:
+    return float64(len(input)), nil
+  }
+
+  if number, ok := jsonNumber(input); ok {
+    return math.Abs(number), nil
+  }
+
+  return nil, fmt.Errorf("%s has no length", jqDescribe(input))
+}
+
+func jqKeys(input any) (any, error) {
+  switch input := input.(type) {
+  case map[string]any:
+    return jqStrings(slices.Sorted(maps.Keys(input))), nil
+  case []any:
+    keys := make([]any, len(input))
+    for n := range input {
+      keys[n] = float64(n)
+    }
+
+    return keys, nil
+  }
+
+  return nil, fmt.Errorf("%s has no keys", jqDescribe(input))
+}
+
+func jqReverse(input any) (any, error) {
+  switch input := input.(type) {
+  case nil:
+    return []any{}, nil
+  case string:
+    runes := []rune(input)
+    slices.Reverse(runes)
+    return string(runes), nil
+  case []any:
+    reversed := slices.Clone(input)
+    slices.Reverse(reversed)
+    return reversed, nil
+  }
+
+  return nil, fmt.Errorf("cannot reverse %s", jqDescribe(input))
+}
+
+// jqSortBy sorts an array by the outputs of by, or by its items if by is nil.
+func jqSortBy(input any, by jsonFilter) (any, error) {
+  array, ok := input.([]any)
+  if !ok {
+    return nil, fmt.Errorf("%s cannot be sorted, as it is not an array", jqDescribe(input))
+  }
+
+  keys := make([]any, len(array))
+  for n, item := range array {
+    keys[n] = item
+    if nil != by {
+      outputs, err := by(item)
+      if nil != err {
+        return nil, err
+      }
+
+      keys[n] = append([]any{}, outputs...)
+    }
+  }
+
+  order := make([]int, len(array))
+  for n := range order {
+    order[n] = n
+  }
+
+  slices.SortStableFunc(order, func(a, b int) int { return jsonCompare(keys[a], keys[b]) })
+  sorted := make([]any, len(array))
+  for n, index := range order {
+    sorted[n] = array[index]
+  }
+
+  return sorted, nil
+}
+
+func jqUnique(input any) (any, error) {
+  sorted, err := jqSortBy(input, nil)
+  if nil != err {
+    return nil, err
+  }
+
+  return slices.CompactFunc(sorted.([]any), jsonEqual), nil
+}
+
+// jqExtreme returns the smallest item of an array if sign is negative, or the largest one otherwise.
+func jqExtreme(input any, sign int) (any, error) {
+  array, ok := input.([]any)
+  switch {
+  case !ok:
+    return nil, fmt.Errorf("%s has no minimum or maximum, as it is not an array", jqDescribe(input))
+  case 0 == len(array):
+    return nil, nil
+  }
+
+  extreme := array[0]
+  for item := range slices.Values(array[1:]) {
+    if sign*jsonCompare(item, extreme) >= 0 {
+      extreme = item
+    }
+  }
+
+  return extreme, nil
+}
+
+func jqAdd(input any) (any, error) {
+  items, err := jqIterate(input)
+  if nil != err {
+    return nil, err
+  }
+
+  var sum any
+  for item := range slices.Values(items) {
+    if sum, err = jqArithmetic("+", sum, item); nil != err {
+      return nil, err
+    }
+  }
+
+  return sum, nil
+}
+
+// jqQuantify reports whether some item of an array (or all of them, if some is false) is truthy, or
+// makes f output a truthy value if f is not nil.
+func jqQuantify(input any, f jsonFilter, some bool) (bool, error) {
+  items, err := jqIterate(input)
+  if nil != err {
+    return false, err
+  }
+
+  for item := range slices.Values(items) {
+    values := []any{item}
+    if nil != f {
+      if values, err = f(item); nil != err {
+        return false, err
+      }
+    }
+
+    if slices.ContainsFunc(values, jsonTruthy) == some {
+      return some, nil
+    }
+  }
+
+  return !some, nil
+}
+
+func jqFloor(input any) (any, error) {
+  if number, ok := jsonNumber(input); ok {
+    return math.Floor(number), nil
+  }
+
+  return nil, fmt.Errorf("%s has no floor, as it is not a number", jqDescribe(input))
+}
+
+func jqToString(input any) (any, error) {
+  if text, ok := input.(string); ok {
+    return text, nil
+  }
+
+  encoded, err := json.Marshal(input)
+  return string(encoded), err
+}
+
+func jqToNumber(input any) (any, error) {
+  if _, ok := jsonNumber(input); ok {
+    return input, nil
+  }
+
+  if text, ok := input.(string); ok {
+    if number, err := strconv.ParseFloat(strings.TrimSpace(text), 64); nil == err {
+      return number, nil
+    }
+  }
+
+  return nil, fmt.Errorf("cannot parse %s as a number", jqDescribe(input))
+}
+
+func jqFromJSON(input any) (any, error) {
+  text, ok := input.(string)
+  if !ok {
+    return nil, fmt.Errorf("%s cannot be parsed, as it is not a string", jqDescribe(input))
+  }
+
+  var value any
+  if err := json.Unmarshal([]byte(text), &value); nil != err {
+    return nil, fmt.Errorf("%s is not valid JSON", jqDescribe(input))
+  }
+
+  return value, nil
+}
+
+// jqStringFunc makes a function of a string out of f.
+func jqStringFunc(name string, f func(string) string) func(any) (any, error) {
+  return func(input any) (any, error) {
+    if text, ok := input.(string); ok {
+      return f(text), nil
+    }
+
+    return nil, fmt.Errorf("%s input must be a string, got %s", name, jqDescribe(input))
+  }
+}
+
+func jqToEntries(input any) (any, error) {
+  object, ok := input.(map[string]any)
+  if !ok {
+    return nil, fmt.Errorf("%s has no entries, as it is not an object", jqDescribe(input))
+  }
+
+  var entries []any
+  for key := range slices.Values(slices.Sorted(maps.Keys(object))) {
+    entries = append(entries, map[string]any{"key": key, "value": object[key]})
+  }
+
+  return append([]any{}, entries...), nil
+}
+
+func jqFromEntries(input any) (any, error) {
+  items, err := jqIterate(input)
+  if nil != err {
+    return nil, err
+  }
+
+  object := map[string]any{}
+  for item := range slices.Values(items) {
+    entry, ok := item.(map[string]any)
+    if !ok {
+      return nil, fmt.Errorf("%s is not an entry", jqDescribe(item))
+    }
+
+    var key, value any
+    for name := range slices.Values([]string{"key", "k", "name", "Key", "Name", "K"}) {
+      if key = entry[name]; nil != key {
+        break
+      }
+    }
+
+    for name := range slices.Values([]string{"value", "v", "Value", "V"}) {
+      if value = entry[name]; nil != value {
+        break
+      }
+    }
+
+    switch key := key.(type) {
+    case string:
+      object[key] = value
+    case bool, float64, json.Number:
+      encoded, _ := json.Marshal(key)
+      object[string(encoded)] = value
+    default:
+      return nil, fmt.Errorf("%s has no key", jqDescribe(item))
+    }
+  }
+
+  return object, nil
+}
+
+// jqSelect outputs its input for every truthy output of its argument.
+func jqSelect(input any, args []jsonFilter) (outputs []any, err error) {
+  values, err := args[0](input)
+  for value := range slices.Values(values) {
+    if jsonTruthy(value) {
+      outputs = append(outputs, input)
+    }
+  }
+
+  return outputs, err
+}
+
+// jqMap outputs an array of the outputs of its argument for every item of its input.
+func jqMap(input any, args []jsonFilter) ([]any, error) {
+  items, err := jqIterate(input)
+  if nil != err {
+    return nil, err
+  }
+
+  mapped := []any{}
+  for item := range slices.Values(items) {
+    outputs, err := args[0](item)
+    if nil != err {
+      return nil, err
+    }
+
+    mapped = append(mapped, outputs...)
+  }
+
+  return []any{mapped}, nil
+}
+
+// jqMapValues replaces every item or member value of its input by the first output of its argument,
+// and drops the ones for which there is none.
+func jqMapValues(input any, args []jsonFilter) ([]any, error) {
+  switch input := input.(type) {
+  case []any:
+    mapped := []any{}
+    for item := range slices.Values(input) {
+      outputs, err := args[0](item)
+      if nil != err {
+        return nil, err
+      }
+
+      if len(outputs) > 0 {
+        mapped = append(mapped, outputs[0])
+      }
+    }
+
+    return []any{mapped}, nil
+  case map[string]any:
+    mapped := map[string]any{}
+    for key, value := range input {
+      outputs, err := args[0](value)
+      if nil != err {
+        return nil, err
+      }
+
+      if len(outputs) > 0 {
+        mapped[key] = outputs[0]
+      }
+    }
+
+    return []any{mapped}, nil
+  }
+
+  return nil, fmt.Errorf("cannot iterate over %s", jqDescribe(input))
+}
+
+func jqHas(input, key any) (any, error) {
+  switch input := input.(type) {
+  case map[string]any:
+    if name, ok := key.(string); ok {
+      _, exists := input[name]
+      return exists, nil
+    }
+  case []any:
+    if index, ok := jsonNumber(key); ok {
+      return index >= 0 && int(index) < len(input), nil
+    }
+  }
+
+  return nil, fmt.Errorf("cannot check whether %s has a %s key", jsonType(input), jsonType(key))
+}
+
+// jqContains tells whether a contains b: a substring, the items of an array contained in an item of
+// the other, the members of an object contained in the same members of the other, or equal values.
+func jqContains(a, b any) (bool, error) {
+  if jsonType(a) != jsonType(b) {
+    return false, fmt.Errorf("%s and %s cannot have their containment checked", jqDescribe(a), jqDescribe(b))
+  }
+
+  switch a := a.(type) {
+  case string:
+    return strings.Contains(a, b.(string)), nil
+  case []any:
+    for wanted := range slices.Values(b.([]any)) {
+      found := false
+      for item := range slices.Values(a) {
+        if contains, _ := jqContains(item, wanted); contains {
+          found = true
+          break
+        }
+      }
+
+      if !found {
+        return false, nil
+      }
+    }
+
+    return true, nil
+  case map[string]any:
+    for key, wanted := range b.(map[string]any) {
+      value, exists := a[key]
+      if !exists {
+        return false, nil
+      }
+
+      if contains, _ := jqContains(value, wanted); !contains {
+        return false, nil
+      }
+    }
+
+    return true, nil
+  }
+
+  return jsonEqual(a, b), nil
+}
+
+func jqJoin(input, separator any) (any, error) {
+  items, err := jqIterate(input)
+  if nil != err {
+    return nil, err
+  }
+
+  sep, ok := separator.(string)
+  if !ok {
+    return nil, fmt.Errorf("the separator must be a string, got %s", jqDescribe(separator))
+  }
+
+  parts := make([]string, len(items))
+  for n, item := range items {
+    switch item := item.(type) {
+    case nil:
+    case string:
+      parts[n] = item
+    case bool, float64, json.Number:
+      encoded, _ := json.Marshal(item)
+      parts[n] = string(encoded)
+    default:
+      return nil, fmt.Errorf("cannot join with %s", jqDescribe(item))
+    }
+  }
+
+  return strings.Join(parts, sep), nil
+}
+
+func jqSplit(input, separator any) (any, error) {
+  text, isText := input.(string)
+  sep, isSeparator := separator.(string)
+  if !isText || !isSeparator {
+    return nil, errors.New("split input and separator must be strings")
+  }
+
+  return jqStrings(strings.Split(text, sep)), nil
+}
+
+func jqTest(input, pattern any) (any, error) {
+  text, isText := input.(string)
+  expression, isPattern := pattern.(string)
+  if !isText || !isPattern {
+    return nil, fmt.Errorf("%s cannot be matched, as it is not a string", jqDescribe(input))
+  }
+
+  re, err := regexp.Compile(expression)
+  if nil != err {
+    return nil, fmt.Errorf("invalid regular expression %q", expression)
+  }
+
+  return re.MatchString(text), nil
+}
+
+// jqAffix makes the startswith and endswith builtins out of has.
+func jqAffix(name string, has func(s, affix string) bool) func(input, affix any) (any, error) {
+  return func(input, affix any) (any, error) {
+    text, isText := input.(string)
+    a, isAffix := affix.(string)
+    if !isText || !isAffix {
+      return nil, fmt.Errorf("%s() requires string inputs", name)
+    }
+
+    return has(text, a), nil
+  }
+}
+
+func jqFirst(input any, args []jsonFilter) ([]any, error) {
+  outputs, err := args[0](input)
+  if len(outputs) > 0 {
+    return outputs[:1], nil
+  }
+
+  return nil, err
+}
+
+func jqLast(input any, args []jsonFilter) ([]any, error) {
+  outputs, err := args[0](input)
+  if nil != err {
+    return nil, err
+  }
+
+  return outputs[max(0, len(outputs)-1):], nil
+}
+
+func jqWithEntries(input any, args []jsonFilter) ([]any, error) {
+  entries, err := jqToEntries(input)
+  if nil != err {
+    return nil, err
+  }
+
+  mapped, err := jqMap(entries, args)
+  if nil != err {
+    return nil, err
+  }
+
+  object, err := jqFromEntries(mapped[0])
+  return []any{object}, err
+}
@@ -0,0 +1,114 @@
+package playground
+
+import (
+  "context"
+  "strings"
+  "testing"
+)
+
+func TestCompileJQ(t *testing.T) {
+  document := `{"users": [{"name": "Ann", "age": 31, "tags": ["admin"]}, {"name": "Bob", "age": 25, "active": true}], "total": 2, "id": 12345678901234567890}`
+
+  tests := []struct {
+    filter string
+    output string
+    err    string
+  }{
+    {".", `{"id":12345678901234567890,"total":2,"users":[{"age":31,"name":"Ann","tags":["admin"]},{"active":true,"age":25,"name":"Bob"}]}`, ""},
+    {".total", "2", ""},
+    {".id", "12345678901234567890", ""},
+    {".users[0].name", `"Ann"`, ""},
+    {`.["total"]`, "2", ""},
+    {`."total"`, "2", ""},
+    {".users[-1].name", `"Bob"`, ""},
+    {".users[5]", "null", ""},
+    {".users[1:].[].name", `"Bob"`, ""},
+    {".users[].name", "\"Ann\"\n\"Bob\"", ""},
+    {".users | map(.name)", `["Ann","Bob"]`, ""},
+    {".users[] | select(.age > 30) | .name", `"Ann"`, ""},
+    {".users[] | select(.active).name", `"Bob"`, ""},
+    {"[.users[] | .age] | add / length", "28", ""},
+    {".users | length, (.[0] | keys)", "2\n[\"age\",\"name\",\"tags\"]", ""},
+    {"{count: .total, names: [.users[].name]}", `{"count":2,"names":["Ann","Bob"]}`, ""},
+    {`{(.users[0].name): .total}`, `{"Ann":2}`, ""},
+    {"{total}", `{"total":2}`, ""},
+    {".users | sort_by(.age) | first.name", `"Bob"`, ""},
+    {".users[0].missing // \"none\"", `"none"`, ""},
+    {".users[0] | has(\"tags\"), has(\"active\")", "true\nfalse", ""},
+    {".users[0] | to_entries | map(.key)", `["age","name","tags"]`, ""},
+    {".users[0] | with_entries(select(.key != \"tags\"))", `{"age":31,"name":"Ann"}`, ""},
+    {".users | map(.name | ascii_downcase) | join(\", \")", `"ann, bob"`, ""},
+    {".users | any(.age < 30), all(.age < 30)", "true\nfalse", ""},
+    {"[.users[].age] | min, max, (sort | reverse)", "25\n31\n[31,25]", ""},
+    {"[1, 2, 2, 1] | unique", "[1,2]", ""},
+    {"[.. | numbers?]", "", "unknown function numbers/0 at offset 6"},
+    {"[..] | length", "13", ""},
+    {".users[0].name | test(\"^A\"), startswith(\"B\"), split(\"n\")", "true\nfalse\n[\"A\",\"\",\"\"]", ""},
+    {".total | tostring, tojson, (\"3\" | tonumber), -.", "\"2\"\n\"2\"\n3\n-2", ""},
+    {"\"[1]\" | fromjson", "[1]", ""},
+    {"7 % 3, 1 - 0.5, 2 * 3, (1 == 1.0) and (null != false)", "1\n0.5\n6\ntrue", ""},
+    {"[.users[].tags] | map(values)", `[["admin"]]`, ""},
+    {"first(.users[].name), last(.users[].name)", "\"Ann\"\n\"Bob\"", ""},
+    {".users | map_values(.age)", "[31,25]", ""},
+    {".users[0].tags | contains([\"admin\"])", "true", ""},
+    {"empty", "", ""},
+    {".total.name?", "", ""},
+    {".total.name", "", `cannot index number with "name"`},
+    {".total[]", "", "cannot iterate over number (2)"},
+    {".users[0].name + 1", "", `string ("Ann") and number (1) cannot be added`},
+    {"1 / 0", "", "number (1) and number (0) cannot be divided because the divisor is zero"},
+    {".users[", "", "unexpected end of filter at offset 7"},
+    {".users | frobnicate", "", "unknown function frobnicate/0 at offset 9"},
+    {"1 < 2 < 3", "", "unexpected < at offset 6"},
+    {"(.total", "", "expected ), got end of filter at offset 7"},
+    {"\"abc", "", "unterminated string at offset 0"},
+    {".a | $x", "", "variables are not supported at offset 5"},
+    {"{a b}", "", "expected ,, got b at offset 3"},
+  }
+
+  for _, test := range tests {
+    filter, err := compileJSONFilter(test.filter, newJQBudget(context.Background()))
+    if nil == err {
+      var output []byte
+      output, err = filterJSON([]byte(document), filter, "")
+      if nil == err && test.output != string(output) {
+        t.Errorf("filterJSON(%q) = %s, want %s", test.filter, output, test.output)
+      }
+    }
+
+    switch {
+    case "" == test.err && nil != err:
+      t.Errorf("filterJSON(%q) error = %v", test.filter, err)
+    case "" != test.err && (nil == err || test.err != err.Error()):
+      t.Errorf("filterJSON(%q) error = %v, want %q", test.filter, err, test.err)
+    }
+  }
+}
+
+func TestCompileJQ_budget(t *testing.T) {
+  cancelled, cancel := context.WithCancel(context.Background())
+  cancel()
+
+  tests := []struct {
+    ctx    context.Context
+    filter string
+    err    string
+  }{
+    {context.Background(), "tostring" + strings.Repeat("|.+.", 40), "the filter builds more than 40 MB of values"},
+    {context.Background(), strings.Repeat("(.,.)|", 40) + ".", "the filter outputs more than 4194304 values"},
+    {context.Background(), "[.]" + strings.Repeat("|[.,.]", 40), "the filter builds more than 40 MB of values"},
+    {context.Background(), "{a: (.,.), b: (.,.)}" + strings.Repeat("|{a: (.,.), b: (.,.)}", 20), "the filter builds more than 40 MB of values"},
+    {cancelled, ".[] | .", "context canceled"},
+  }
+
+  for _, test := range tests {
+    filter, err := compileJSONFilter(test.filter, newJQBudget(test.ctx))
+    if nil == err {
+      _, err = filterJSON([]byte(`[1, 2, 3]`), filter, "")
+    }
+
+    if nil == err || test.err != err.Error() {
+      t.Errorf("filterJSON(%q) error = %v, want %q", test.filter, err, test.err)
+    }
+  }
+}
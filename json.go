@@ -0,0 +1,275 @@
+package playground
+
+import (
+  "bytes"
+  "encoding/json"
+  "errors"
+  "fmt"
+  "io"
+  "math"
+  "strconv"
+  "unicode/utf8"
+)
+
+// maxJSONWarnings is the number of problems of a JSON body, such as duplicate keys, that are reported
+// one by one.
+const maxJSONWarnings = 10
+
+// A jsonNode is a JSON value as it is written: numbers keep their digits, however large, strings their
+// escapes, and objects the order and the duplicates of their members.
+type jsonNode struct {
+  raw     []byte       // The text of a scalar, or the opening bracket of an object or an array.
+  members []jsonMember // The members of an object, in the order they are written.
+  items   []*jsonNode  // The items of an array.
+}
+
+// A jsonMember is a member of a JSON object.
+type jsonMember struct {
+  key   []byte // The key, quoted as it is written.
+  name  string // The key, unquoted.
+  value *jsonNode
+}
+
+// A jsonDuplicate is a key written more than once in the same JSON object.
+type jsonDuplicate struct {
+  name   string
+  offset int // Where the key that repeats the first one starts.
+}
+
+// jsonSyntaxError is an error of a JSON document, located by its line and its column, in characters.
+type jsonSyntaxError struct {
+  message      string
+  offset       int // Where the character that breaks the syntax ends.
+  line, column int
+}
+
+func (e *jsonSyntaxError) Error() string {
+  return fmt.Sprintf("invalid JSON at line %d, column %d: %s", e.line, e.column, e.message)
+}
+
+// jsonLocation returns the line and the column, counted in characters from 1, of the byte of input
+// that ends at offset, which is past the last one when the input is cut short.
+func jsonLocation(input []byte, offset int) (line, column int) {
+  past := max(0, offset-len(input))
+  offset = min(max(offset, 0), len(input))
+  start := bytes.LastIndexByte(input[:offset], '\n') + 1
+  return 1 + bytes.Count(input[:start], []byte{'\n'}), max(1, utf8.RuneCount(input[start:offset])+past)
+}
+
+// parseJSON parses input, a JSON document or a stream of them, without losing anything but the blanks
+// between tokens. It returns the keys that are repeated in the same object along with the documents, or
+// a *jsonSyntaxError.
+func parseJSON(input []byte) ([]*jsonNode, []jsonDuplicate, error) {
+  decoder := json.NewDecoder(bytes.NewReader(input))
+  for {
+    var value json.RawMessage
+    err := decoder.Decode(&value)
+    if errors.Is(err, io.EOF) {
+      break
+    }
+
+    var syntax *json.SyntaxError
+    switch {
+    case errors.As(err, &syntax):
+      line, column := jsonLocation(input, int(syntax.Offset))
+      return nil, nil, &jsonSyntaxError{message: syntax.Error(), offset: int(syntax.Offset), line: line, column: column}
+    case nil != err:
+      end := len(bytes.TrimRight(input, " \t\r\n")) + 1
+      line, column := jsonLocation(input[:end-1], end)
+      return nil, nil, &jsonSyntaxError{message: "unexpected end of JSON input", offset: end, line: line, column: column}
+    }
+  }
+
+  p := &jsonParser{input: input}
+  var documents []*jsonNode
+  for p.skip(); p.n < len(p.input); p.skip() {
+    documents = append(documents, p.value())
+  }
+
+  return documents, p.duplicates, nil
+}
+
+// A jsonParser reads a JSON document that is known to be valid.
+type jsonParser struct {
+  input      []byte
+  n          int
+  duplicates []jsonDuplicate
+}
+
+// skip skips the blanks at n.
+func (p *jsonParser) skip() {
+  for p.n < len(p.input) && xmlIsSpace(p.input[p.n]) {
+    p.n++
+  }
+}
+
+// value reads the value at n.
+func (p *jsonParser) value() *jsonNode {
+  start := p.n
+  switch p.input[p.n] {
+  case '{':
+    node := &jsonNode{raw: p.input[start : start+1]}
+    names := map[string]struct{}{}
+    p.n++
+    for p.skip(); '}' != p.input[p.n]; p.skip() {
+      if ',' == p.input[p.n] {
+        p.n++
+        p.skip()
+      }
+
+      offset := p.n
+      key := p.string()
+      member := jsonMember{key: key, name: string(key[1 : len(key)-1])}
+      if bytes.IndexByte(key, '\\') >= 0 {
+        json.Unmarshal(key, &member.name)
+      }
+
+      if _, repeated := names[member.name]; repeated {
+        p.duplicates = append(p.duplicates, jsonDuplicate{name: member.name, offset: offset})
+      }
+
+      names[member.name] = struct{}{}
+      p.skip()
+      p.n++ /* The colon.  */
+      p.skip()
+      member.value = p.value()
+      node.members = append(node.members, member)
+    }
+
+    p.n++
+    return node
+  case '[':
+    node := &jsonNode{raw: p.input[start : start+1]}
+    p.n++
+    for p.skip(); ']' != p.input[p.n]; p.skip() {
+      if ',' == p.input[p.n] {
+        p.n++
+        p.skip()
+      }
+
+      node.items = append(node.items, p.value())
+    }
+
+    p.n++
+    return node
+  case '"':
+    return &jsonNode{raw: p.string()}
+  }
+
+  for p.n < len(p.input) && !xmlIsSpace(p.input[p.n]) && !bytes.ContainsRune([]byte(",:]}[{\""), rune(p.input[p.n])) {
+    p.n++
+  }
+
+  return &jsonNode{raw: p.input[start:p.n]}
+}
+
+// string reads the string at n, quotes included.
+func (p *jsonParser) string() []byte {
+  start := p.n
+  for p.n++; '"' != p.input[p.n]; p.n++ {
+    if '\\' == p.input[p.n] {
+      p.n++
+    }
+  }
+
+  p.n++
+  return p.input[start:p.n]
+}
+
+// checkJSON returns what is wrong with the JSON document input: where its syntax breaks, the keys
+// that are repeated in the same object, whose values all but the last are usually ignored, or the
+// documents that trail the first one, which most parsers reject or ignore.
+func checkJSON(input []byte) []string {
+  input = bytes.TrimRight(input, " \t\r\n") /* So that a document cut short ends at its last character.  */
+  if 0 == len(bytes.TrimSpace(input)) {
+    return nil
+  }
+
+  documents, duplicates, err := parseJSON(input)
+  warnings := jsonProblems(input, 0, 0, duplicates, err)
+  if len(documents) > 1 {
+    decoder := json.NewDecoder(bytes.NewReader(input))
+    decoder.Decode(&json.RawMessage{})
+    second := len(input) - len(bytes.TrimLeft(input[decoder.InputOffset():], " \t\r\n"))
+    line, column := jsonLocation(input, second+1)
+    warnings = append(warnings, fmt.Sprintf("the body holds %d JSON texts instead of one, the second at line %d, column %d", len(documents), line, column))
+  }
+
+  return warnings
+}
+
+// jsonProblems describes the error or the duplicate keys that parseJSON found in the part of input that
+// starts at base and ends with it, located in the whole input, which comes after lines other lines.
+func jsonProblems(input []byte, base, lines int, duplicates []jsonDuplicate, err error) []string {
+  if syntax := (*jsonSyntaxError)(nil); errors.As(err, &syntax) {
+    located := *syntax
+    located.line, located.column = jsonLocation(input, base+syntax.offset)
+    located.line += lines
+    return []string{located.Error()}
+  }
+
+  var warnings []string
+  for n, duplicate := range duplicates {
+    if maxJSONWarnings == n {
+      warnings = append(warnings, fmt.Sprintf("and %d more duplicate keys", len(duplicates)-n))
+      break
+    }
+
+    line, column := jsonLocation(input, base+duplicate.offset+1)
+    warnings = append(warnings, fmt.Sprintf("duplicate key %q at line %d, column %d", duplicate.name, lines+line, column))
+  }
+
+  return warnings
+}
+
+// jsonString returns the node of the JSON string s, whose invalid UTF-8 is replaced.
+func jsonString(s string) *jsonNode {
+  buffer := bytes.Buffer{}
+  encoder := json.NewEncoder(&buffer)
+  encoder.SetEscapeHTML(false)
+  encoder.Encode(s)
+  return &jsonNode{raw: bytes.TrimSuffix(buffer.Bytes(), []byte{'\n'})}
+}
+
+// jsonScalar returns the node of text, which is a JSON number or literal.
+func jsonScalar(text string) *jsonNode {
+  return &jsonNode{raw: []byte(text)}
+}
+
+// jsonFloat returns the node of the number f, or of a string for NaN and the infinities, which JSON
+// has no numbers for.
+func jsonFloat(f float64, bits int) *jsonNode {
+  if math.IsNaN(f) || math.IsInf(f, 0) {
+    return jsonString(strconv.FormatFloat(f, 'g', -1, bits))
+  }
+
+  return jsonScalar(strconv.FormatFloat(f, 'g', -1, bits))
+}
+
+// jsonArray returns the node of an array of items.
+func jsonArray(items []*jsonNode) *jsonNode {
+  return &jsonNode{raw: []byte{'['}, items: items}
+}
+
+// jsonObject returns the node of an object of members.
+func jsonObject(members []jsonMember) *jsonNode {
+  return &jsonNode{raw: []byte{'{'}, members: members}
+}
+
+// newJSONMember returns the member of an object named name.
+func newJSONMember(name string, value *jsonNode) jsonMember {
+  return jsonMember{key: jsonString(name).raw, name: name, value: value}
+}
+
+// jsonKey returns the name of the member whose key is node: its text, unless it is a string already.
+func jsonKey(node *jsonNode) string {
+  if '"' == node.raw[0] {
+    var name string
+    json.Unmarshal(node.raw, &name)
+    return name
+  }
+
+  buffer := bytes.Buffer{}
+  jsonFormatterImpl{compact: true}.write(node, &plainTokens{output: &buffer}, "", 0)
+  return buffer.String()
+}
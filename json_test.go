@@ -0,0 +1,40 @@
+package playground
+
+import (
+  "reflect"
+  "testing"
+
+  "github.com/google/go-cmp/cmp"
+)
+
+func TestCheckJSON(t *testing.T) {
+  tests := []struct {
+    input string
+    want  []string
+  }{
+    {`{"a": 1, "b": [true, null]}`, nil},
+    {"", nil},
+    {`1 "two" [3]`, []string{"the body holds 3 JSON texts instead of one, the second at line 1, column 3"}},
+    {"{}{}", []string{"the body holds 2 JSON texts instead of one, the second at line 1, column 3"}},
+    {"{\"a\": 1}\n  [2]", []string{"the body holds 2 JSON texts instead of one, the second at line 2, column 3"}},
+    {"{\n  \"a\": 1,\n  \"b\": }", []string{"invalid JSON at line 3, column 8: invalid character '}' looking for beginning of value"}},
+    {`{"a": [1, 2`, []string{"invalid JSON at line 1, column 12: unexpected end of JSON input"}},
+    {`{"a": "é", "b" 1}`, []string{"invalid JSON at line 1, column 16: invalid character '1' after object key"}},
+    {"{\n  \"id\": 1,\n  \"id\": 2,\n  \"x\": {\"id\": 3, \"\\u0069d\": 4}\n}", []string{
+      `duplicate key "id" at line 3, column 3`,
+      `duplicate key "id" at line 4, column 18`,
+    }},
+    {`[{"a": 1}, {"a": 2}]`, nil},
+  }
+
+  for _, test := range tests {
+    if got := checkJSON([]byte(test.input)); !reflect.DeepEqual(test.want, got) {
+      t.Errorf("checkJSON(%q): %s", test.input, cmp.Diff(test.want, got))
+    }
+  }
+
+  many := []byte(`{"a": 0, "a": 1, "a": 2, "a": 3, "a": 4, "a": 5, "a": 6, "a": 7, "a": 8, "a": 9, "a": 10, "a": 11, "a": 12}`)
+  if got := checkJSON(many); 11 != len(got) || "and 2 more duplicate keys" != got[10] {
+    t.Errorf("checkJSON(%s) = %q", many, got)
+  }
+}
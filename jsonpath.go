@@ -0,0 +1,664 @@
+package playground
+
+import (
+  "encoding/json"
+  "fmt"
+  "maps"
+  "reflect"
+  "slices"
+  "strconv"
+  "strings"
+)
+
+// A jsonPathStep selects values from the ones the steps before it selected: members by name, items
+// by index or by slice, every member or item (wildcard), or the ones that pass a filter. A recursive
+// step selects from the value and all of its descendants, as in $..id.
+type jsonPathStep struct {
+  recursive bool
+  wildcard  bool
+  names     []string
+  indexes   []int
+  slices    [][3]*int // The start, the end and the step of every slice, which may be missing.
+  filter    *jsonPathFilter
+}
+
+// A jsonPathFilter is the expression of a filter selector, such as ?(@.price < 10 && @.tags).
+type jsonPathFilter struct {
+  operator    string // ||, &&, !, a comparison operator, or empty for an operand.
+  left, right *jsonPathFilter
+  path        []jsonPathStep // The path of an operand, relative to the current value (@) unless absolute.
+  absolute    bool
+  literal     any // The value of an operand that is not a path.
+}
+
+// evalJSONPath returns the value at path in document, a value decoded by encoding/json. The path is
+// made of member names and array indexes, such as $.data[0].id or $['first name'][-1], where a
+// negative index counts from the end of the array. found is false if the path leads nowhere. A path
+// that may select many values, such as $.data[*].id, returns all of them as an array, and it is
+// found if it selects any.
+func evalJSONPath(document any, path string) (value any, found bool, err error) {
+  steps, err := parseJSONPath(path)
+  if nil != err {
+    return nil, false, err
+  }
+
+  values := queryJSONPath(document, document, steps)
+  if !definiteJSONPath(steps) {
+    return append([]any{}, values...), len(values) > 0, nil
+  }
+
+  if 0 == len(values) {
+    return nil, false, nil
+  }
+
+  return values[0], true, nil
+}
+
+// definiteJSONPath reports whether steps select one value at most.
+func definiteJSONPath(steps []jsonPathStep) bool {
+  return !slices.ContainsFunc(steps, func(s jsonPathStep) bool {
+    return s.recursive || s.wildcard || nil != s.filter || len(s.slices) > 0 || len(s.names)+len(s.indexes) > 1
+  })
+}
+
+// queryJSONPath returns the values that steps select from value, in order; root is the document that $
+// refers to in filters.
+func queryJSONPath(root, value any, steps []jsonPathStep) []any {
+  values := []any{value}
+  for step := range slices.Values(steps) {
+    var selected []any
+    for v := range slices.Values(values) {
+      if !step.recursive {
+        selected = append(selected, step.selectFrom(root, v)...)
+        continue
+      }
+
+      for descendant := range slices.Values(jsonDescendants(v)) {
+        selected = append(selected, step.selectFrom(root, descendant)...)
+      }
+    }
+
+    values = selected
+  }
+
+  return values
+}
+
+// jsonDescendants returns value and all the values it contains, in document order.
+func jsonDescendants(value any) []any {
+  descendants := []any{value}
+  for child := range slices.Values(jsonChildren(value)) {
+    descendants = append(descendants, jsonDescendants(child)...)
+  }
+
+  return descendants
+}
+
+// jsonChildren returns the items of an array, or the members of an object sorted by name.
+func jsonChildren(value any) []any {
+  switch value := value.(type) {
+  case []any:
+    return value
+  case map[string]any:
+    children := make([]any, 0, len(value))
+    for key := range slices.Values(slices.Sorted(maps.Keys(value))) {
+      children = append(children, value[key])
+    }
+
+    return children
+  }
+
+  return nil
+}
+
+// selectFrom returns the values that s selects from value.
+func (s *jsonPathStep) selectFrom(root, value any) (selected []any) {
+  if s.wildcard {
+    return jsonChildren(value)
+  }
+
+  if nil != s.filter {
+    for child := range slices.Values(jsonChildren(value)) {
+      if jsonTruthy(s.filter.eval(root, child)) {
+        selected = append(selected, child)
+      }
+    }
+
+    return selected
+  }
+
+  if object, ok := value.(map[string]any); ok {
+    for name := range slices.Values(s.names) {
+      if member, exists := object[name]; exists {
+        selected = append(selected, member)
+      }
+    }
+  }
+
+  array, ok := value.([]any)
+  if !ok {
+    return selected
+  }
+
+  for index := range slices.Values(s.indexes) {
+    if index < 0 {
+      index += len(array)
+    }
+
+    if index >= 0 && index < len(array) {
+      selected = append(selected, array[index])
+    }
+  }
+
+  for slice := range slices.Values(s.slices) {
+    for index := range slices.Values(sliceIndexes(len(array), slice)) {
+      selected = append(selected, array[index])
+    }
+  }
+
+  return selected
+}
+
+// sliceIndexes returns the indexes that slice selects from an array of length items, the way Python
+// slices do.
+func sliceIndexes(length int, slice [3]*int) (indexes []int) {
+  step := 1
+  if nil != slice[2] {
+    step = *slice[2]
+  }
+
+  lower, upper := 0, length
+  if step < 0 {
+    lower, upper = -1, length-1
+  }
+
+  bound := func(n *int, fallback int) int {
+    if nil == n {
+      return fallback
+    }
+
+    if *n < 0 {
+      return min(max(*n+length, lower), upper)
+    }
+
+    return min(max(*n, lower), upper)
+  }
+
+  if step > 0 {
+    for n := bound(slice[0], 0); n < bound(slice[1], length); n += step {
+      indexes = append(indexes, n)
+    }
+  } else {
+    for n := bound(slice[0], length-1); n > bound(slice[1], -1); n += step {
+      indexes = append(indexes, n)
+    }
+  }
+
+  return indexes
+}
+
+// eval returns the value of f for the current value: a boolean for the logical and comparison
+// operators, the first value selected by a path, or nil if it selects none.
+func (f *jsonPathFilter) eval(root, current any) any {
+  switch f.operator {
+  case "":
+    if nil == f.path {
+      return f.literal
+    }
+
+    start := current
+    if f.absolute {
+      start = root
+    }
+
+    if values := queryJSONPath(root, start, f.path); len(values) > 0 {
+      return values[0]
+    }
+
+    return nil
+  case "!":
+    return !jsonTruthy(f.left.eval(root, current))
+  case "&&":
+    return jsonTruthy(f.left.eval(root, current)) && jsonTruthy(f.right.eval(root, current))
+  case "||":
+    return jsonTruthy(f.left.eval(root, current)) || jsonTruthy(f.right.eval(root, current))
+  }
+
+  left, right := f.left.eval(root, current), f.right.eval(root, current)
+  switch f.operator {
+  case "==":
+    return jsonEqual(left, right)
+  case "!=":
+    return !jsonEqual(left, right)
+  }
+
+  if jsonType(left) != jsonType(right) || ("number" != jsonType(left) && "string" != jsonType(left)) {
+    return false /* Only numbers and strings are ordered.  */
+  }
+
+  switch c := jsonCompare(left, right); f.operator {
+  case "<":
+    return c < 0
+  case "<=":
+    return c <= 0
+  case ">":
+    return c > 0
+  default:
+    return c >= 0
+  }
+}
+
+// jsonNumber returns the value of a number decoded by encoding/json, either as a float64 or, if
+// the decoder used numbers, as a json.Number.
+func jsonNumber(value any) (float64, bool) {
+  switch value := value.(type) {
+  case float64:
+    return value, true
+  case json.Number:
+    number, err := value.Float64()
+    return number, nil == err
+  }
+
+  return 0, false
+}
+
+// jsonType returns the name of the type of a decoded JSON value: null, boolean, number, string,
+// array or object.
+func jsonType(value any) string {
+  switch value.(type) {
+  case bool:
+    return "boolean"
+  case float64, json.Number:
+    return "number"
+  case string:
+    return "string"
+  case []any:
+    return "array"
+  case map[string]any:
+    return "object"
+  }
+
+  return "null"
+}
+
+// jsonTruthy reports whether value is neither null nor false.
+func jsonTruthy(value any) bool {
+  return nil != value && false != value
+}
+
+// jsonEqual reports whether two decoded JSON values are equal, whatever the type of their numbers.
+func jsonEqual(a, b any) bool {
+  return 0 == jsonCompare(a, b)
+}
+
+// jsonCompare orders two decoded JSON values the way jq does: null, false, true, numbers, strings,
+// arrays and objects, each of them by its content.
+func jsonCompare(a, b any) int {
+  order := []string{"null", "boolean", "number", "string", "array", "object"}
+  if c := slices.Index(order, jsonType(a)) - slices.Index(order, jsonType(b)); 0 != c {
+    return c
+  }
+
+  switch a := a.(type) {
+  case bool:
+    switch {
+    case a == b.(bool):
+      return 0
+    case a:
+      return 1
+    default:
+      return -1
+    }
+  case float64, json.Number:
+    x, _ := jsonNumber(a)
+    y, _ := jsonNumber(b)
+    switch {
+    case x < y:
+      return -1
+    case x > y:
+      return 1
+    default:
+      return 0
+    }
+  case string:
+    return strings.Compare(a, b.(string))
+  case []any:
+    b := b.([]any)
+    for n := range min(len(a), len(b)) {
+      if c := jsonCompare(a[n], b[n]); 0 != c {
+        return c
+      }
+    }
+
+    return len(a) - len(b)
+  case map[string]any:
+    b := b.(map[string]any)
+    keys, otherKeys := slices.Sorted(maps.Keys(a)), slices.Sorted(maps.Keys(b))
+    if !reflect.DeepEqual(keys, otherKeys) {
+      return slices.Compare(keys, otherKeys)
+    }
+
+    for key := range slices.Values(keys) {
+      if c := jsonCompare(a[key], b[key]); 0 != c {
+        return c
+      }
+    }
+  }
+
+  return 0
+}
+
+// parseJSONPath splits a JSON path into its steps. Besides member names and indexes, a path may use
+// wildcards ($.data[*] or $.*), recursive descent ($..id), unions ($['a','b'] or $[0,2]), slices
+// ($[1:3] or $[::-1]) and filters ($.data[?(@.price < 10)]).
+func parseJSONPath(path string) (steps []jsonPathStep, err error) {
+  if !strings.HasPrefix(path, "$") {
+    return nil, fmt.Errorf("JSON path %q must start with $", path)
+  }
+
+  p := &jsonPathParser{input: path, n: 1}
+  if steps, err = p.steps(false); nil != err {
+    return nil, err
+  }
+
+  if p.n < len(p.input) {
+    return nil, fmt.Errorf("unexpected %q in JSON path %q", p.input[p.n], path)
+  }
+
+  return steps, nil
+}
+
+// A jsonPathParser reads a JSON path from input, starting at n.
+type jsonPathParser struct {
+  input string
+  n     int
+}
+
+// fail returns an error about the JSON path that is being parsed.
+func (p *jsonPathParser) fail(format string, a ...any) error {
+  return fmt.Errorf("%s in JSON path %q", fmt.Sprintf(format, a...), p.input)
+}
+
+// skip skips the whitespace at n.
+func (p *jsonPathParser) skip() {
+  for p.n < len(p.input) && strings.ContainsRune(" \t\r\n", rune(p.input[p.n])) {
+    p.n++
+  }
+}
+
+// consume skips prefix at n, after any whitespace, if it is there, and reports whether it was.
+func (p *jsonPathParser) consume(prefix string) bool {
+  start := p.n
+  if p.skip(); strings.HasPrefix(p.input[p.n:], prefix) {
+    p.n += len(prefix)
+    return true
+  }
+
+  p.n = start
+  return false
+}
+
+// steps reads the steps at n, until something that is not a step; in a filter, the steps of a path
+// end where the filter expression goes on.
+func (p *jsonPathParser) steps(inFilter bool) (steps []jsonPathStep, err error) {
+  for p.n < len(p.input) {
+    var step jsonPathStep
+    switch {
+    case strings.HasPrefix(p.input[p.n:], ".."):
+      p.n += 2
+      step.recursive = true
+      if p.n < len(p.input) && '[' == p.input[p.n] {
+        p.n++
+        if err := p.bracket(&step); nil != err {
+          return nil, err
+        }
+
+        break
+      }
+
+      if err := p.member(&step); nil != err {
+        return nil, err
+      }
+    case '.' == p.input[p.n]:
+      p.n++
+      if err := p.member(&step); nil != err {
+        return nil, err
+      }
+    case '[' == p.input[p.n]:
+      p.n++
+      if err := p.bracket(&step); nil != err {
+        return nil, err
+      }
+    default:
+      if inFilter {
+        return steps, nil
+      }
+
+      return nil, p.fail("unexpected %q", p.input[p.n])
+    }
+
+    steps = append(steps, step)
+  }
+
+  return steps, nil
+}
+
+// member reads the name of a member after a dot, or the wildcard.
+func (p *jsonPathParser) member(step *jsonPathStep) error {
+  if p.n < len(p.input) && '*' == p.input[p.n] {
+    p.n++
+    step.wildcard = true
+    return nil
+  }
+
+  start := p.n
+  for p.n < len(p.input) && !strings.ContainsRune(".[ \t\r\n()=!<>&|,]", rune(p.input[p.n])) {
+    p.n++
+  }
+
+  if start == p.n {
+    return p.fail("missing member name")
+  }
+
+  step.names = append(step.names, p.input[start:p.n])
+  return nil
+}
+
+// bracket reads the selectors of a bracket, after the [ and up to the ].
+func (p *jsonPathParser) bracket(step *jsonPathStep) (err error) {
+  switch {
+  case p.consume("*"):
+    step.wildcard = true
+  case p.consume("?"):
+    parenthesized := p.consume("(")
+    if step.filter, err = p.or(); nil != err {
+      return err
+    }
+
+    if parenthesized && !p.consume(")") {
+      return p.fail("unclosed (")
+    }
+  default:
+    for {
+      if err := p.selector(step); nil != err {
+        return err
+      }
+
+      if !p.consume(",") {
+        break
+      }
+    }
+  }
+
+  if !p.consume("]") {
+    return p.fail("unclosed [")
+  }
+
+  return nil
+}
+
+// selector reads a quoted name, an index or a slice of a bracket.
+func (p *jsonPathParser) selector(step *jsonPathStep) error {
+  if p.skip(); p.n < len(p.input) && ('\'' == p.input[p.n] || '"' == p.input[p.n]) {
+    name, err := p.quoted()
+    if nil != err {
+      return err
+    }
+
+    step.names = append(step.names, name)
+    return nil
+  }
+
+  start := p.n
+  for p.n < len(p.input) && !strings.ContainsRune(",]", rune(p.input[p.n])) {
+    p.n++
+  }
+
+  if p.n == len(p.input) {
+    return p.fail("unclosed [")
+  }
+
+  selector := strings.TrimSpace(p.input[start:p.n])
+  if !strings.Contains(selector, ":") {
+    index, err := strconv.Atoi(selector)
+    if nil != err {
+      return p.fail("invalid index %q", selector)
+    }
+
+    step.indexes = append(step.indexes, index)
+    return nil
+  }
+
+  var slice [3]*int
+  parts := strings.Split(selector, ":")
+  if len(parts) > 3 {
+    return p.fail("invalid slice %q", selector)
+  }
+
+  for n, part := range parts {
+    if part = strings.TrimSpace(part); "" == part {
+      continue
+    }
+
+    number, err := strconv.Atoi(part)
+    if nil != err || (2 == n && 0 == number) {
+      return p.fail("invalid slice %q", selector)
+    }
+
+    slice[n] = &number
+  }
+
+  step.slices = append(step.slices, slice)
+  return nil
+}
+
+// quoted reads a name between single or double quotes, where a backslash escapes the next character.
+func (p *jsonPathParser) quoted() (string, error) {
+  quote := p.input[p.n]
+  var name strings.Builder
+  for p.n++; p.n < len(p.input); p.n++ {
+    switch c := p.input[p.n]; {
+    case quote == c:
+      p.n++
+      return name.String(), nil
+    case '\\' == c && p.n+1 < len(p.input):
+      p.n++
+      name.WriteByte(p.input[p.n])
+    default:
+      name.WriteByte(c)
+    }
+  }
+
+  return "", p.fail("unclosed [")
+}
+
+// or reads a filter expression, whose operators are, from the loosest: ||, && and the comparisons.
+func (p *jsonPathParser) or() (*jsonPathFilter, error) {
+  left, err := p.and()
+  for nil == err && p.consume("||") {
+    var right *jsonPathFilter
+    if right, err = p.and(); nil == err {
+      left = &jsonPathFilter{operator: "||", left: left, right: right}
+    }
+  }
+
+  return left, err
+}
+
+// and reads the operands of &&.
+func (p *jsonPathParser) and() (*jsonPathFilter, error) {
+  left, err := p.comparison()
+  for nil == err && p.consume("&&") {
+    var right *jsonPathFilter
+    if right, err = p.comparison(); nil == err {
+      left = &jsonPathFilter{operator: "&&", left: left, right: right}
+    }
+  }
+
+  return left, err
+}
+
+// comparison reads an operand, which may be compared with another one.
+func (p *jsonPathParser) comparison() (*jsonPathFilter, error) {
+  left, err := p.operand()
+  if nil != err {
+    return nil, err
+  }
+
+  for operator := range slices.Values([]string{"==", "!=", "<=", ">=", "<", ">"}) {
+    if p.consume(operator) {
+      right, err := p.operand()
+      if nil != err {
+        return nil, err
+      }
+
+      return &jsonPathFilter{operator: operator, left: left, right: right}, nil
+    }
+  }
+
+  return left, nil
+}
+
+// operand reads a negation, a parenthesized expression, a path or a literal.
+func (p *jsonPathParser) operand() (f *jsonPathFilter, err error) {
+  switch p.skip(); {
+  case p.n == len(p.input):
+    return nil, p.fail("missing filter operand")
+  case p.consume("!"):
+    operand, err := p.operand()
+    if nil != err {
+      return nil, err
+    }
+
+    return &jsonPathFilter{operator: "!", left: operand}, nil
+  case p.consume("("):
+    if f, err = p.or(); nil == err && !p.consume(")") {
+      return nil, p.fail("unclosed (")
+    }
+
+    return f, err
+  case '@' == p.input[p.n] || '$' == p.input[p.n]:
+    f = &jsonPathFilter{absolute: '$' == p.input[p.n]}
+    p.n++
+    if f.path, err = p.steps(true); nil == err && nil == f.path {
+      f.path = []jsonPathStep{}
+    }
+
+    return f, err
+  case '\'' == p.input[p.n] || '"' == p.input[p.n]:
+    literal, err := p.quoted()
+    return &jsonPathFilter{literal: literal}, err
+  }
+
+  start := p.n
+  for p.n < len(p.input) && !strings.ContainsRune(" \t\r\n()=!<>&|]", rune(p.input[p.n])) {
+    p.n++
+  }
+
+  var literal any
+  if err := json.Unmarshal([]byte(p.input[start:p.n]), &literal); nil != err || "array" == jsonType(literal) || "object" == jsonType(literal) {
+    return nil, p.fail("invalid filter operand %q", p.input[start:p.n])
+  }
+
+  return &jsonPathFilter{literal: literal}, nil
+}
@@ -0,0 +1,82 @@
+package playground
+
+import (
+  "encoding/json"
+  "reflect"
+  "testing"
+)
+
+func TestEvalJSONPath(t *testing.T) {
+  var document any
+  json.Unmarshal([]byte(`{"data": [{"id": 1, "tags": ["go"]}, {"id": 2, "tags": []}], "first name": "Jane", "a]b": true}`), &document)
+
+  tests := []struct {
+    path  string
+    value any
+    found bool
+    err   string
+  }{
+    {"$", document, true, ""},
+    {"$.data[0].id", 1.0, true, ""},
+    {"$.data[-1].id", 2.0, true, ""},
+    {"$.data[1].tags", []any{}, true, ""},
+    {"$['first name']", "Jane", true, ""},
+    {`$["a]b"]`, true, true, ""},
+    {"$.data[2]", nil, false, ""},
+    {"$.data.id", nil, false, ""},
+    {"$.missing", nil, false, ""},
+    {"data[0]", nil, false, `JSON path "data[0]" must start with $`},
+    {"$.data[first]", nil, false, `invalid index "first" in JSON path "$.data[first]"`},
+    {"$.data[0", nil, false, `unclosed [ in JSON path "$.data[0"`},
+    {"$..id", []any{1.0, 2.0}, true, ""},
+    {"$.data[*].id", []any{1.0, 2.0}, true, ""},
+    {"$.data[*].name", []any{}, false, ""},
+    {"$.data[0].*", []any{1.0, []any{"go"}}, true, ""},
+    {"$.data[0]['id','tags']", []any{1.0, []any{"go"}}, true, ""},
+    {"$.data[1,0,5].id", []any{2.0, 1.0}, true, ""},
+    {"$.data[-1:].id", []any{2.0}, true, ""},
+    {"$.data[::-1].id", []any{2.0, 1.0}, true, ""},
+    {"$.data[-9:9].id", []any{1.0, 2.0}, true, ""},
+    {"$.data[?(@.id > 1)].id", []any{2.0}, true, ""},
+    {"$.data[?(@.tags[0] == 'go' || @.id == 2)].id", []any{1.0, 2.0}, true, ""},
+    {"$.data[?(@.id >= 1 && !(@.id == 2))].id", []any{1.0}, true, ""},
+    {"$.data[?@.missing].id", []any{}, false, ""},
+    {`$.data[?(@.id == $.data[1].id)].id`, []any{2.0}, true, ""},
+    {"$..tags[0]", []any{"go"}, true, ""},
+    {"$..*", nil, false, ""},
+    {"$..", nil, false, `missing member name in JSON path "$.."`},
+    {"$.data[1:2:0]", nil, false, `invalid slice "1:2:0" in JSON path "$.data[1:2:0]"`},
+    {"$.data[?(@.id > )]", nil, false, `invalid filter operand "" in JSON path "$.data[?(@.id > )]"`},
+    {"$.data[?(@.id > 1]", nil, false, `unclosed ( in JSON path "$.data[?(@.id > 1]"`},
+    {"$.data['id", nil, false, `unclosed [ in JSON path "$.data['id"`},
+    {"$.data x", nil, false, `unexpected ' ' in JSON path "$.data x"`},
+  }
+
+  for _, test := range tests {
+    value, found, err := evalJSONPath(document, test.path)
+    if "" != test.err {
+      if nil == err || test.err != err.Error() {
+        t.Errorf("evalJSONPath(%q) error = %v, want %q", test.path, err, test.err)
+      }
+
+      continue
+    }
+
+    if nil != err {
+      t.Errorf("evalJSONPath(%q): unexpected error: %s", test.path, err)
+      continue
+    }
+
+    if "$..*" == test.path { /* Every value of the document, which is too long to be written here.  */
+      if values, _ := value.([]any); 10 != len(values) || !found {
+        t.Errorf("evalJSONPath(%q) = %d values, want 10", test.path, len(values))
+      }
+
+      continue
+    }
+
+    if test.found != found || !reflect.DeepEqual(test.value, value) {
+      t.Errorf("evalJSONPath(%q) = %#v, %t, want %#v, %t", test.path, value, found, test.value, test.found)
+    }
+  }
+}
@@ -0,0 +1,211 @@
+package playground
+
+import (
+  "encoding/base64"
+  "encoding/binary"
+  "fmt"
+  "math"
+  "strconv"
+  "time"
+)
+
+// decodeMessagePack decodes the MessagePack values of input into JSON trees. Binary data is written as
+// base64 strings, keys that are not strings as their JSON text, timestamps as RFC 3339 strings, and the
+// other extensions as objects of their type and their base64 data.
+func decodeMessagePack(input []byte) ([]*jsonNode, []string, error) {
+  r := &msgpackReader{binaryReader{input: input}}
+  documents, err := r.documents(r.value)
+  return documents, nil, err
+}
+
+// A msgpackReader reads MessagePack values.
+type msgpackReader struct {
+  binaryReader
+}
+
+// value reads the value at n.
+func (r *msgpackReader) value() (*jsonNode, error) {
+  offset := r.n
+  format, err := r.uint(1)
+  if nil != err {
+    return nil, err
+  }
+
+  switch b := byte(format); {
+  case b <= 0x7f:
+    return jsonScalar(strconv.FormatUint(format, 10)), nil
+  case b >= 0xe0:
+    return jsonScalar(strconv.Itoa(int(int8(b)))), nil
+  case b <= 0x8f:
+    return r.object(uint64(b & 0x0f))
+  case b <= 0x9f:
+    return r.array(uint64(b & 0x0f))
+  case b <= 0xbf:
+    return r.string(uint64(b & 0x1f))
+  }
+
+  switch format {
+  case 0xc0:
+    return jsonScalar("null"), nil
+  case 0xc2:
+    return jsonScalar("false"), nil
+  case 0xc3:
+    return jsonScalar("true"), nil
+  case 0xc4, 0xc5, 0xc6: /* bin 8, 16 and 32.  */
+    size, err := r.uint(1 << (format - 0xc4))
+    if nil != err {
+      return nil, err
+    }
+
+    data, err := r.take(size)
+    if nil != err {
+      return nil, err
+    }
+
+    return jsonString(base64.StdEncoding.EncodeToString(data)), nil
+  case 0xc7, 0xc8, 0xc9: /* ext 8, 16 and 32.  */
+    size, err := r.uint(1 << (format - 0xc7))
+    if nil != err {
+      return nil, err
+    }
+
+    return r.extension(size)
+  case 0xca:
+    bits, err := r.uint(4)
+    return jsonFloat(float64(math.Float32frombits(uint32(bits))), 32), err
+  case 0xcb:
+    bits, err := r.uint(8)
+    return jsonFloat(math.Float64frombits(bits), 64), err
+  case 0xcc, 0xcd, 0xce, 0xcf:
+    value, err := r.uint(1 << (format - 0xcc))
+    return jsonScalar(strconv.FormatUint(value, 10)), err
+  case 0xd0, 0xd1, 0xd2, 0xd3:
+    size := 1 << (format - 0xd0)
+    value, err := r.uint(size)
+    shift := 64 - 8*size /* Extends the sign of the integer.  */
+    return jsonScalar(strconv.FormatInt(int64(value<<shift)>>shift, 10)), err
+  case 0xd4, 0xd5, 0xd6, 0xd7, 0xd8: /* fixext 1, 2, 4, 8 and 16.  */
+    return r.extension(1 << (format - 0xd4))
+  case 0xd9, 0xda, 0xdb:
+    size, err := r.uint(1 << (format - 0xd9))
+    if nil != err {
+      return nil, err
+    }
+
+    return r.string(size)
+  case 0xdc, 0xdd:
+    size, err := r.uint(2 << (format - 0xdc))
+    if nil != err {
+      return nil, err
+    }
+
+    return r.array(size)
+  case 0xde, 0xdf:
+    size, err := r.uint(2 << (format - 0xde))
+    if nil != err {
+      return nil, err
+    }
+
+    return r.object(size)
+  }
+
+  return nil, fmt.Errorf("unknown format 0x%02x at offset %d", format, offset)
+}
+
+// string reads a string of size bytes.
+func (r *msgpackReader) string(size uint64) (*jsonNode, error) {
+  data, err := r.take(size)
+  if nil != err {
+    return nil, err
+  }
+
+  return jsonString(string(data)), nil
+}
+
+// array reads an array of size values.
+func (r *msgpackReader) array(size uint64) (*jsonNode, error) {
+  if err := r.enter(); nil != err {
+    return nil, err
+  }
+
+  defer r.leave()
+  if size > uint64(len(r.input)-r.n) { /* Every value takes a byte at least.  */
+    return nil, fmt.Errorf("unexpected end of input at offset %d", len(r.input))
+  }
+
+  var items []*jsonNode /* Grown as the values are read: size is not to be trusted.  */
+  for range size {
+    item, err := r.value()
+    if nil != err {
+      return nil, err
+    }
+
+    items = append(items, item)
+  }
+
+  return jsonArray(items), nil
+}
+
+// object reads a map of size pairs.
+func (r *msgpackReader) object(size uint64) (*jsonNode, error) {
+  if err := r.enter(); nil != err {
+    return nil, err
+  }
+
+  defer r.leave()
+  if size > uint64(len(r.input)-r.n)/2 {
+    return nil, fmt.Errorf("unexpected end of input at offset %d", len(r.input))
+  }
+
+  var members []jsonMember
+  for range size {
+    key, err := r.value()
+    if nil != err {
+      return nil, err
+    }
+
+    value, err := r.value()
+    if nil != err {
+      return nil, err
+    }
+
+    members = append(members, newJSONMember(jsonKey(key), value))
+  }
+
+  return jsonObject(members), nil
+}
+
+// extension reads the type and the size bytes of data of an extension.
+func (r *msgpackReader) extension(size uint64) (*jsonNode, error) {
+  kind, err := r.uint(1)
+  if nil != err {
+    return nil, err
+  }
+
+  data, err := r.take(size)
+  if nil != err {
+    return nil, err
+  }
+
+  if -1 == int8(kind) { /* A timestamp.  */
+    var seconds, nanoseconds int64
+    switch len(data) {
+    case 4:
+      seconds = int64(binary.BigEndian.Uint32(data))
+    case 8:
+      value := binary.BigEndian.Uint64(data)
+      seconds, nanoseconds = int64(value&(1<<34-1)), int64(value>>34)
+    case 12:
+      seconds, nanoseconds = int64(binary.BigEndian.Uint64(data[4:])), int64(binary.BigEndian.Uint32(data))
+    default:
+      return nil, fmt.Errorf("invalid timestamp of %d bytes at offset %d", len(data), r.n-len(data))
+    }
+
+    return jsonString(time.Unix(seconds, nanoseconds).UTC().Format(time.RFC3339Nano)), nil
+  }
+
+  return jsonObject([]jsonMember{
+    newJSONMember("type", jsonScalar(strconv.Itoa(int(int8(kind))))),
+    newJSONMember("data", jsonString(base64.StdEncoding.EncodeToString(data))),
+  }), nil
+}
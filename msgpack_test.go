@@ -0,0 +1,68 @@
+package playground
+
+import (
+  "bytes"
+  "encoding/binary"
+  "reflect"
+  "runtime"
+  "strings"
+  "testing"
+)
+
+func TestDecodeMessagePack(t *testing.T) {
+  tests := []struct {
+    input    string
+    want     string
+    warnings []string
+  }{
+    {"\x82\xa1a\x01\xa1b\x92\xc3\xc0", `{"a":1,"b":[true,null]}`, nil},
+    {"\x93\xff\xd1\xff\x38\xcf\xff\xff\xff\xff\xff\xff\xff\xff", `[-1,-200,18446744073709551615]`, nil},
+    {"\x92\xcb\x3f\xf8\x00\x00\x00\x00\x00\x00\xca\x7f\x80\x00\x00", `[1.5,"+Inf"]`, nil},
+    {"\xc4\x03\x01\x02\x03", `"AQID"`, nil},
+    {"\xd6\xff\x00\x00\x00\x3c", `"1970-01-01T00:01:00Z"`, nil},
+    {"\xd4\x05\x2a", `{"type":5,"data":"Kg=="}`, nil},
+    {"\x82\x01\xa1x\x91\x02\xa1y", `{"1":"x","[2]":"y"}`, nil},
+    {"\x01\x02", "1\n2", nil},
+    {"\xc1", "00000000  c1  ", []string{"invalid MessagePack: unknown format 0xc1 at offset 0"}},
+    {"\x92\x01", "00000000  92 01", []string{"invalid MessagePack: unexpected end of input at offset 2"}},
+    {strings.Repeat("\x91", maxDecodingDepth+1) + "\x01", "", []string{"invalid MessagePack: more than 512 levels of nesting at offset 513"}},
+  }
+
+  formatter := &binaryFormatterImpl{encoding: "MessagePack", decode: decodeMessagePack, json: jsonFormatterImpl{compact: true}}
+  for _, test := range tests {
+    buffer := bytes.Buffer{}
+    formatter.format([]byte(test.input), &buffer, "  ")
+    if got := buffer.String(); !strings.HasPrefix(got, test.want) {
+      t.Errorf("format(%q) = %s, want %s", test.input, got, test.want)
+    }
+
+    if got := formatter.check([]byte(test.input)); !reflect.DeepEqual(test.warnings, got) {
+      t.Errorf("check(%q) = %q, want %q", test.input, got, test.warnings)
+    }
+  }
+}
+
+func TestDecodeMessagePack_sizes(t *testing.T) {
+  const levels, padding = maxDecodingDepth - 1, 1 << 20
+
+  input := make([]byte, 5*levels+padding)
+  for n := range levels { /* Arrays that claim as many values as there are bytes left.  */
+    input[5*n] = 0xdd
+    binary.BigEndian.PutUint32(input[5*n+1:], uint32(len(input)-5*n-5))
+  }
+
+  input[5*levels] = 0xc1
+
+  var before, after runtime.MemStats
+  runtime.ReadMemStats(&before)
+  _, _, err := decodeMessagePack(input)
+  runtime.ReadMemStats(&after)
+
+  if nil == err {
+    t.Fatal("decodeMessagePack(...) must fail")
+  }
+
+  if allocated := after.TotalAlloc - before.TotalAlloc; allocated > 64<<20 {
+    t.Errorf("decodeMessagePack(...) allocated %d MB", allocated>>20)
+  }
+}
@@ -0,0 +1,625 @@
+package playground
+
+import (
+  "bytes"
+  "encoding/json"
+  "errors"
+  "fmt"
+  "gopkg.in/yaml.v3"
+  "maps"
+  "net/http"
+  "slices"
+  "strings"
+)
+
+// openapiExample is a named example of a parameter or a media type.
+type openapiExample struct {
+  Value any `json:"value"`
+}
+
+// openapiSchema holds (some) fields of a Schema Object, shared by OpenAPI 3.x and Swagger 2.0.
+type openapiSchema struct {
+  Ref        string                    `json:"$ref"`
+  Type       any                       `json:"type"` // A string, or an array of strings since OpenAPI 3.1.
+  Format     string                    `json:"format"`
+  Example    any                       `json:"example"`
+  Examples   []any                     `json:"examples"` // Since OpenAPI 3.1.
+  Default    any                       `json:"default"`
+  Enum       []any                     `json:"enum"`
+  Properties map[string]*openapiSchema `json:"properties"`
+  Items      *openapiSchema            `json:"items"`
+  AllOf      []*openapiSchema          `json:"allOf"`
+  OneOf      []*openapiSchema          `json:"oneOf"`
+  AnyOf      []*openapiSchema          `json:"anyOf"`
+}
+
+// openapiParameter holds (some) fields of a Parameter Object. Type, Default and Enum are only
+// used by Swagger 2.0, which describes non-body parameters without a schema.
+type openapiParameter struct {
+  Ref      string                    `json:"$ref"`
+  Name     string                    `json:"name"`
+  In       string                    `json:"in"` // One of: path, query, header, cookie, body (2.0) or formData (2.0).
+  Example  any                       `json:"example"`
+  Examples map[string]openapiExample `json:"examples"`
+  Schema   *openapiSchema            `json:"schema"`
+  Type     string                    `json:"type"`
+  Default  any                       `json:"default"`
+  Enum     []any                     `json:"enum"`
+}
+
+// openapiMediaType holds (some) fields of a Media Type Object.
+type openapiMediaType struct {
+  Schema   *openapiSchema            `json:"schema"`
+  Example  any                       `json:"example"`
+  Examples map[string]openapiExample `json:"examples"`
+}
+
+// openapiRequestBody holds (some) fields of a Request Body Object.
+type openapiRequestBody struct {
+  Ref     string                      `json:"$ref"`
+  Content map[string]openapiMediaType `json:"content"`
+}
+
+// openapiOperation describes a single API operation on a path.
+type openapiOperation struct {
+  OperationID string              `json:"operationId"`
+  Summary     string              `json:"summary"`
+  Tags        []string            `json:"tags"`
+  Parameters  []*openapiParameter `json:"parameters"`
+  RequestBody *openapiRequestBody `json:"requestBody"`
+  Consumes    []string            `json:"consumes"` // Swagger 2.0 only.
+}
+
+// openapiPathItem describes the operations available on a single path. Only the methods the
+// playground can send are kept.
+type openapiPathItem struct {
+  Get        *openapiOperation   `json:"get"`
+  Post       *openapiOperation   `json:"post"`
+  Put        *openapiOperation   `json:"put"`
+  Patch      *openapiOperation   `json:"patch"`
+  Delete     *openapiOperation   `json:"delete"`
+  Parameters []*openapiParameter `json:"parameters"`
+}
+
+// openapiDoc holds (some) fields of an OpenAPI 3.x or a Swagger 2.0 document.
+type openapiDoc struct {
+  OpenAPI any `json:"openapi"` // The version, which YAML documents might hold as a number.
+  Swagger any `json:"swagger"`
+  Info    struct {
+    Title string `json:"title"`
+  } `json:"info"`
+  Tags []struct {
+    Name string `json:"name"`
+  } `json:"tags"`
+  Paths map[string]openapiPathItem `json:"paths"`
+
+  Servers []struct {
+    URL       string `json:"url"`
+    Variables map[string]struct {
+      Default string `json:"default"`
+    } `json:"variables"`
+  } `json:"servers"`
+  Components struct {
+    Schemas       map[string]*openapiSchema      `json:"schemas"`
+    Parameters    map[string]*openapiParameter   `json:"parameters"`
+    RequestBodies map[string]*openapiRequestBody `json:"requestBodies"`
+  } `json:"components"`
+
+  // Swagger 2.0 only.
+  Host        string                       `json:"host"`
+  BasePath    string                       `json:"basePath"`
+  Schemes     []string                     `json:"schemes"`
+  Consumes    []string                     `json:"consumes"`
+  Definitions map[string]*openapiSchema    `json:"definitions"`
+  Parameters  map[string]*openapiParameter `json:"parameters"`
+
+  examples     map[string]generatedExample // The examples generated for the references, each expanded once.
+  exampleNodes int                         // The number of values generated for all the examples of the document.
+}
+
+// A generatedExample is the example generated for a reference, with the number of values it holds.
+type generatedExample struct {
+  value any
+  nodes int
+}
+
+// maxSchemaDepth bounds the chains of references followed while resolving a component or generating an example.
+const maxSchemaDepth = 8
+
+// maxExampleNodes bounds the number of values generated for the examples of a whole document, which
+// schemas referring to each other could otherwise make grow exponentially.
+const maxExampleNodes = 10000
+
+var errNotOpenAPI = errors.New("not an OpenAPI or Swagger document")
+
+// toJSON converts a JSON or YAML document into JSON. JSON input is returned as is.
+func toJSON(input []byte) ([]byte, error) {
+  if trimmed := bytes.TrimSpace(bytes.TrimPrefix(input, []byte("\xef\xbb\xbf"))); bytes.HasPrefix(trimmed, []byte("{")) {
+    return trimmed, nil
+  }
+
+  var document any
+  if err := yaml.Unmarshal(input, &document); nil != err {
+    return nil, err
+  }
+
+  return json.Marshal(stringKeys(document))
+}
+
+// stringKeys turns the map[any]any values decoded from YAML (e.g., because of numeric keys such as
+// response codes) into map[string]any, so that they can be encoded as JSON.
+func stringKeys(value any) any {
+  switch v := value.(type) {
+  default:
+    return v
+  case map[string]any:
+    for key, element := range v {
+      v[key] = stringKeys(element)
+    }
+    return v
+  case map[any]any:
+    m := make(map[string]any, len(v))
+    for key, element := range v {
+      m[fmt.Sprint(key)] = stringKeys(element)
+    }
+    return m
+  case []any:
+    for n, element := range v {
+      v[n] = stringKeys(element)
+    }
+    return v
+  }
+}
+
+// parseOpenAPI parses a JSON document as an OpenAPI 3.x or Swagger 2.0 specification. It returns
+// errNotOpenAPI if the document is valid JSON but not one of these specifications.
+func parseOpenAPI(input []byte) (doc *openapiDoc, err error) {
+  var version struct {
+    OpenAPI any `json:"openapi"`
+    Swagger any `json:"swagger"`
+  }
+
+  if err = json.Unmarshal(input, &version); nil != err {
+    return nil, err
+  }
+
+  if nil == version.OpenAPI && nil == version.Swagger {
+    return nil, errNotOpenAPI
+  }
+
+  doc = &openapiDoc{}
+  if err = json.Unmarshal(input, doc); nil != err {
+    return nil, err
+  }
+
+  return doc, nil
+}
+
+// openapiToColl converts an OpenAPI document into a collection whose folders are the tags of
+// its operations. The base URL of the API is stored in the {{baseUrl}} collection variable.
+func openapiToColl(doc *openapiDoc) *coll {
+  c := &coll{}
+  c.Info.Name = doc.Info.Title
+  c.Variable = []collVariable{{Key: "baseUrl", Value: doc.baseURL(), Type: "string"}}
+
+  var (
+    folders = map[string]*collItem{}
+    order   []string
+  )
+
+  for tag := range slices.Values(doc.Tags) {
+    if _, exists := folders[tag.Name]; !exists {
+      folders[tag.Name] = &collItem{Name: tag.Name}
+      order = append(order, tag.Name)
+    }
+  }
+
+  var untagged []collItem
+
+  for path := range slices.Values(slices.Sorted(maps.Keys(doc.Paths))) {
+    pathItem := doc.Paths[path]
+    operations := [...]struct {
+      method    string
+      operation *openapiOperation
+    }{
+      {http.MethodGet, pathItem.Get},
+      {http.MethodPost, pathItem.Post},
+      {http.MethodPut, pathItem.Put},
+      {http.MethodPatch, pathItem.Patch},
+      {http.MethodDelete, pathItem.Delete},
+    }
+
+    for o := range slices.Values(operations[:]) {
+      if nil == o.operation {
+        continue
+      }
+
+      item := collItem{
+        Name:    o.operation.name(o.method, path),
+        Request: doc.request(o.method, path, pathItem.Parameters, o.operation),
+      }
+
+      if 0 == len(o.operation.Tags) {
+        untagged = append(untagged, item)
+        continue
+      }
+
+      tag := o.operation.Tags[0]
+      if _, exists := folders[tag]; !exists {
+        folders[tag] = &collItem{Name: tag}
+        order = append(order, tag)
+      }
+
+      folders[tag].Item = append(folders[tag].Item, item)
+    }
+  }
+
+  for tag := range slices.Values(order) {
+    if len(folders[tag].Item) > 0 {
+      c.Item = append(c.Item, *folders[tag])
+    }
+  }
+
+  c.Item = append(c.Item, untagged...)
+  return c
+}
+
+// name returns a human-readable name for an operation.
+func (o *openapiOperation) name(method, path string) string {
+  switch {
+  default:
+    return fmt.Sprint(method, " ", path)
+  case "" != o.Summary:
+    return o.Summary
+  case "" != o.OperationID:
+    return o.OperationID
+  }
+}
+
+// baseURL returns the URL of the first server of the API, or the one made of the host, base path
+// and schemes of a Swagger 2.0 document.
+func (doc *openapiDoc) baseURL() string {
+  var base string
+
+  if nil != doc.Swagger {
+    if "" != doc.Host {
+      scheme := "https"
+      if len(doc.Schemes) > 0 && !slices.Contains(doc.Schemes, "https") {
+        scheme = doc.Schemes[0]
+      }
+
+      base = fmt.Sprint(scheme, "://", doc.Host)
+    }
+
+    base += doc.BasePath
+  } else if len(doc.Servers) > 0 {
+    base = doc.Servers[0].URL
+    for name, variable := range doc.Servers[0].Variables {
+      base = strings.ReplaceAll(base, fmt.Sprint("{", name, "}"), variable.Default)
+    }
+  }
+
+  return strings.TrimSuffix(base, "/")
+}
+
+// request builds the collRequest of an operation. Parameters declared on the path are overridden
+// by the ones of the operation with the same name and location.
+func (doc *openapiDoc) request(method, path string, common []*openapiParameter, o *openapiOperation) *collRequest {
+  req := &collRequest{
+    Method: method,
+    Header: []collHeader{},
+  }
+
+  var (
+    parameters = map[string]*openapiParameter{}
+    order      []string
+    cookies    []string
+    form       []collURLEncodedParameter
+  )
+
+  for p := range slices.Values(slices.Concat(common, o.Parameters)) {
+    if p = doc.parameter(p); nil == p {
+      continue
+    }
+
+    key := fmt.Sprint(p.In, ":", p.Name)
+    if _, exists := parameters[key]; !exists {
+      order = append(order, key)
+    }
+
+    parameters[key] = p
+  }
+
+  segments := strings.Split(strings.TrimPrefix(path, "/"), "/")
+  for n, segment := range segments {
+    if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+      segments[n] = fmt.Sprint(":", segment[1:len(segment)-1])
+    }
+  }
+
+  req.URL.Path = segments
+  req.URL.Host = []string{"{{baseUrl}}"}
+  req.URL.Raw = fmt.Sprint("{{baseUrl}}/", strings.Join(segments, "/"))
+
+  for key := range slices.Values(order) {
+    p := parameters[key]
+
+    switch p.In {
+    case "path":
+      req.URL.Variable = append(req.URL.Variable, collPathVariable{Key: p.Name, Value: doc.parameterExample(p)})
+    case "query":
+      req.URL.Query = append(req.URL.Query, collQueryParam{Key: p.Name, Value: doc.parameterExample(p)})
+    case "header":
+      req.Header = append(req.Header, collHeader{Key: p.Name, Value: doc.parameterExample(p)})
+    case "cookie":
+      cookies = append(cookies, fmt.Sprint(p.Name, "=", doc.parameterExample(p)))
+    case "formData":
+      form = append(form, collURLEncodedParameter{Key: p.Name, Value: doc.parameterExample(p)})
+    case "body":
+      req.Body = doc.body(doc.contentType(o), &openapiMediaType{Schema: p.Schema, Example: p.Example})
+    }
+  }
+
+  if len(req.URL.Query) > 0 {
+    query := make([]string, 0, len(req.URL.Query))
+    for q := range slices.Values(req.URL.Query) {
+      query = append(query, fmt.Sprint(queryEscapeVariables(q.Key), "=", queryEscapeVariables(q.Value)))
+    }
+
+    req.URL.Raw = fmt.Sprint(req.URL.Raw, "?", strings.Join(query, "&"))
+  }
+
+  if len(cookies) > 0 {
+    req.Header = append(req.Header, collHeader{Key: "Cookie", Value: strings.Join(cookies, "; ")})
+  }
+
+  if len(form) > 0 {
+    req.Body = &collBody{Mode: "urlencoded", URLEncoded: form}
+    req.Header = append(req.Header, collHeader{Key: "Content-Type", Value: "application/x-www-form-urlencoded"})
+  } else if nil != req.Body {
+    req.Header = append(req.Header, collHeader{Key: "Content-Type", Value: doc.contentType(o)})
+  }
+
+  if body := doc.requestBody(o.RequestBody); nil != body && len(body.Content) > 0 {
+    contentType := slices.Sorted(maps.Keys(body.Content))[0]
+    if _, exists := body.Content["application/json"]; exists {
+      contentType = "application/json"
+    }
+
+    media := body.Content[contentType]
+    req.Body = doc.body(contentType, &media)
+    req.Header = append(req.Header, collHeader{Key: "Content-Type", Value: contentType})
+  }
+
+  return req
+}
+
+// contentType returns the media type consumed by a Swagger 2.0 operation.
+func (doc *openapiDoc) contentType(o *openapiOperation) string {
+  switch {
+  default:
+    return "application/json"
+  case len(o.Consumes) > 0:
+    return o.Consumes[0]
+  case len(doc.Consumes) > 0:
+    return doc.Consumes[0]
+  }
+}
+
+// body builds a request body of the given media type from the example of media, or from one
+// generated from its schema.
+func (doc *openapiDoc) body(contentType string, media *openapiMediaType) *collBody {
+  example := media.Example
+  if nil == example {
+    for name := range slices.Values(slices.Sorted(maps.Keys(media.Examples))) {
+      example = media.Examples[name].Value
+      break
+    }
+  }
+
+  if nil == example {
+    example = doc.schemaExample(media.Schema, nil)
+  }
+
+  if s, ok := example.(string); ok && !strings.Contains(contentType, "json") {
+    return &collBody{Mode: "raw", Raw: s}
+  }
+
+  if "application/x-www-form-urlencoded" == contentType {
+    if fields, ok := example.(map[string]any); ok {
+      body := &collBody{Mode: "urlencoded"}
+      for key := range slices.Values(slices.Sorted(maps.Keys(fields))) {
+        body.URLEncoded = append(body.URLEncoded, collURLEncodedParameter{Key: key, Value: exampleString(fields[key])})
+      }
+      return body
+    }
+  }
+
+  raw, err := json.MarshalIndent(example, "", "  ")
+  if nil != err || nil == example {
+    return &collBody{Mode: "raw"}
+  }
+
+  return &collBody{Mode: "raw", Raw: string(raw)}
+}
+
+// parameter resolves a reference to a parameter defined in the components (3.x) or at the top level (2.0).
+func (doc *openapiDoc) parameter(p *openapiParameter) *openapiParameter {
+  for depth := 0; nil != p && "" != p.Ref && depth < maxSchemaDepth; depth++ {
+    name := p.Ref[strings.LastIndex(p.Ref, "/")+1:]
+    if strings.HasPrefix(p.Ref, "#/components/") {
+      p = doc.Components.Parameters[name]
+    } else {
+      p = doc.Parameters[name]
+    }
+  }
+
+  return p
+}
+
+// requestBody resolves a reference to a request body defined in the components.
+func (doc *openapiDoc) requestBody(body *openapiRequestBody) *openapiRequestBody {
+  for depth := 0; nil != body && "" != body.Ref && depth < maxSchemaDepth; depth++ {
+    body = doc.Components.RequestBodies[body.Ref[strings.LastIndex(body.Ref, "/")+1:]]
+  }
+
+  return body
+}
+
+// schema resolves a reference to a schema defined in the components (3.x) or the definitions (2.0).
+func (doc *openapiDoc) schema(s *openapiSchema) *openapiSchema {
+  for depth := 0; nil != s && "" != s.Ref && depth < maxSchemaDepth; depth++ {
+    name := s.Ref[strings.LastIndex(s.Ref, "/")+1:]
+    if strings.HasPrefix(s.Ref, "#/components/") {
+      s = doc.Components.Schemas[name]
+    } else {
+      s = doc.Definitions[name]
+    }
+  }
+
+  return s
+}
+
+// parameterExample returns the example value of a parameter as a string.
+func (doc *openapiDoc) parameterExample(p *openapiParameter) string {
+  switch {
+  case nil != p.Example:
+    return exampleString(p.Example)
+  case len(p.Examples) > 0:
+    return exampleString(p.Examples[slices.Sorted(maps.Keys(p.Examples))[0]].Value)
+  case nil != p.Schema:
+    return exampleString(doc.schemaExample(p.Schema, nil))
+  default:
+    return exampleString(doc.schemaExample(&openapiSchema{Type: p.Type, Default: p.Default, Enum: p.Enum}, nil))
+  }
+}
+
+// schemaExample generates an example value for a schema, preferring the examples, defaults and
+// enumerations it declares over placeholders derived from its type and format. refs holds the
+// references being expanded, so that recursive schemas end with a null value. The example of a
+// reference is generated once and reused, and values stop being generated, null instead, once the
+// document has maxExampleNodes of them.
+func (doc *openapiDoc) schemaExample(s *openapiSchema, refs []string) any {
+  if nil != s && "" != s.Ref {
+    ref := s.Ref
+    if slices.Contains(refs, ref) || len(refs) > maxSchemaDepth {
+      return nil
+    }
+
+    if cached, ok := doc.examples[ref]; ok {
+      if doc.exampleNodes+cached.nodes > maxExampleNodes {
+        return nil
+      }
+
+      doc.exampleNodes += cached.nodes
+      return cached.value
+    }
+
+    start := doc.exampleNodes
+    value := doc.schemaExample(doc.schema(s), append(slices.Clone(refs), ref))
+    if nil == doc.examples {
+      doc.examples = map[string]generatedExample{}
+    }
+
+    doc.examples[ref] = generatedExample{value: value, nodes: doc.exampleNodes - start}
+    return value
+  }
+
+  if nil == s || doc.exampleNodes >= maxExampleNodes {
+    return nil
+  }
+
+  doc.exampleNodes++
+  switch {
+  case nil != s.Example:
+    return s.Example
+  case len(s.Examples) > 0:
+    return s.Examples[0]
+  case nil != s.Default:
+    return s.Default
+  case len(s.Enum) > 0:
+    return s.Enum[0]
+  case len(s.AllOf) > 0:
+    merged := map[string]any{}
+    for sub := range slices.Values(s.AllOf) {
+      if fields, ok := doc.schemaExample(sub, refs).(map[string]any); ok {
+        maps.Copy(merged, fields)
+      }
+    }
+    return merged
+  case len(s.OneOf) > 0:
+    return doc.schemaExample(s.OneOf[0], refs)
+  case len(s.AnyOf) > 0:
+    return doc.schemaExample(s.AnyOf[0], refs)
+  }
+
+  switch schemaType(s) {
+  default:
+    if 0 == len(s.Properties) {
+      return nil
+    }
+    fallthrough
+  case "object":
+    fields := map[string]any{}
+    for name, property := range s.Properties {
+      fields[name] = doc.schemaExample(property, refs)
+    }
+    return fields
+  case "array":
+    if item := doc.schemaExample(s.Items, refs); nil != item {
+      return []any{item}
+    }
+    return []any{}
+  case "integer", "number":
+    return 0
+  case "boolean":
+    return true
+  case "string":
+    switch s.Format {
+    default:
+      return "string"
+    case "date-time":
+      return "2024-01-01T00:00:00Z"
+    case "date":
+      return "2024-01-01"
+    case "email":
+      return "user@example.com"
+    case "uuid":
+      return "3fa85f64-5717-4562-b3fc-2c963f66afa6"
+    case "uri", "url":
+      return "https://example.com"
+    }
+  }
+}
+
+// schemaType returns the type of a schema, which might be given as a list since OpenAPI 3.1.
+func schemaType(s *openapiSchema) string {
+  switch t := s.Type.(type) {
+  case string:
+    return t
+  case []any:
+    for element := range slices.Values(t) {
+      if name, ok := element.(string); ok && "null" != name {
+        return name
+      }
+    }
+  }
+
+  return ""
+}
+
+// exampleString formats an example value for a parameter, URL-encoded field or header.
+func exampleString(example any) string {
+  switch v := example.(type) {
+  case nil:
+    return ""
+  case string:
+    return v
+  default:
+    b, err := json.Marshal(v)
+    if nil != err {
+      return fmt.Sprint(v)
+    }
+    return string(b)
+  }
+}
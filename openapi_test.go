@@ -0,0 +1,347 @@
+package playground
+
+import (
+  "bytes"
+  "encoding/json"
+  "fmt"
+  "github.com/google/go-cmp/cmp"
+  "reflect"
+  "strings"
+  "testing"
+  "time"
+)
+
+func TestImportColl_OpenAPI3(t *testing.T) {
+  spec := `openapi: 3.0.3
+info:
+  title: Pet Store
+  version: 1.0.0
+servers:
+  - url: "{scheme}://api.example.com/v1/"
+    variables:
+      scheme:
+        default: https
+tags:
+  - name: users
+  - name: pets
+paths:
+  /pets/{petId}:
+    parameters:
+      - name: petId
+        in: path
+        required: true
+        schema:
+          type: integer
+          example: 7
+    get:
+      summary: Get a pet
+      tags: [pets]
+      parameters:
+        - $ref: "#/components/parameters/Verbose"
+        - name: X-Request-ID
+          in: header
+          schema:
+            type: string
+            format: uuid
+        - name: session
+          in: cookie
+          example: abc
+      responses:
+        200:
+          description: OK
+    delete:
+      operationId: deletePet
+      tags: [pets]
+      responses:
+        204:
+          description: No Content
+  /pets:
+    post:
+      summary: Create a pet
+      tags: [pets]
+      requestBody:
+        $ref: "#/components/requestBodies/Pet"
+      responses:
+        201:
+          description: Created
+  /health:
+    get:
+      responses:
+        200:
+          description: OK
+components:
+  parameters:
+    Verbose:
+      name: verbose
+      in: query
+      examples:
+        yes:
+          value: true
+  requestBodies:
+    Pet:
+      content:
+        application/json:
+          schema:
+            $ref: "#/components/schemas/Pet"
+  schemas:
+    Pet:
+      allOf:
+        - $ref: "#/components/schemas/Named"
+        - type: object
+          properties:
+            tags:
+              type: array
+              items:
+                type: string
+            born:
+              type: string
+              format: date
+            owner:
+              $ref: "#/components/schemas/Pet"
+    Named:
+      type: object
+      properties:
+        name:
+          type: string
+          example: Rex
+`
+
+  got, err := importColl(strings.NewReader(spec))
+  if nil != err {
+    t.Fatalf("unexpected error: %s", err)
+  }
+
+  want := &coll{
+    Item: []collItem{
+      {
+        Name: "pets",
+        Item: []collItem{
+          {
+            Name: "Create a pet",
+            Request: &collRequest{
+              URL:    collURL{Raw: "{{baseUrl}}/pets", Host: []string{"{{baseUrl}}"}, Path: []string{"pets"}},
+              Method: "POST",
+              Header: []collHeader{{Key: "Content-Type", Value: "application/json"}},
+              Body: &collBody{Mode: "raw", Raw: `{
+  "born": "2024-01-01",
+  "name": "Rex",
+  "owner": null,
+  "tags": [
+    "string"
+  ]
+}`},
+            },
+          },
+          {
+            Name: "Get a pet",
+            Request: &collRequest{
+              URL: collURL{
+                Raw:      "{{baseUrl}}/pets/:petId?verbose=true",
+                Host:     []string{"{{baseUrl}}"},
+                Path:     []string{"pets", ":petId"},
+                Query:    []collQueryParam{{Key: "verbose", Value: "true"}},
+                Variable: []collPathVariable{{Key: "petId", Value: "7"}},
+              },
+              Method: "GET",
+              Header: []collHeader{
+                {Key: "X-Request-ID", Value: "3fa85f64-5717-4562-b3fc-2c963f66afa6"},
+                {Key: "Cookie", Value: "session=abc"},
+              },
+            },
+          },
+          {
+            Name: "deletePet",
+            Request: &collRequest{
+              URL: collURL{
+                Raw:      "{{baseUrl}}/pets/:petId",
+                Host:     []string{"{{baseUrl}}"},
+                Path:     []string{"pets", ":petId"},
+                Variable: []collPathVariable{{Key: "petId", Value: "7"}},
+              },
+              Method: "DELETE",
+              Header: []collHeader{},
+            },
+          },
+        },
+      },
+      {
+        Name: "GET /health",
+        Request: &collRequest{
+          URL:    collURL{Raw: "{{baseUrl}}/health", Host: []string{"{{baseUrl}}"}, Path: []string{"health"}},
+          Method: "GET",
+          Header: []collHeader{},
+        },
+      },
+    },
+    Variable: []collVariable{{Key: "baseUrl", Value: "https://api.example.com/v1", Type: "string"}},
+  }
+  want.Info.Name = "Pet Store"
+
+  if !reflect.DeepEqual(want, got) {
+    t.Fatal(cmp.Diff(want, got))
+  }
+}
+
+func TestImportColl_Swagger2(t *testing.T) {
+  spec := `{
+  "swagger": "2.0",
+  "info": {"title": "Legacy", "version": "1"},
+  "host": "legacy.example.com",
+  "basePath": "/api",
+  "schemes": ["http"],
+  "consumes": ["application/json"],
+  "parameters": {"limit": {"name": "limit", "in": "query", "type": "integer", "default": 20}},
+  "definitions": {"Login": {"type": "object", "properties": {"user": {"type": "string", "example": "jane"}}}},
+  "paths": {
+    "/items": {
+      "get": {"tags": ["items"], "summary": "List items", "parameters": [{"$ref": "#/parameters/limit"}, {"name": "sort", "in": "query", "type": "string", "enum": ["asc", "desc"]}]}
+    },
+    "/login": {
+      "post": {"tags": ["auth"], "summary": "Log in", "parameters": [{"name": "body", "in": "body", "schema": {"$ref": "#/definitions/Login"}}]}
+    },
+    "/logout": {
+      "post": {"tags": ["auth"], "summary": "Log out", "consumes": ["application/x-www-form-urlencoded"], "parameters": [{"name": "everywhere", "in": "formData", "type": "boolean"}]}
+    }
+  }
+}`
+
+  got, err := importColl(strings.NewReader(spec))
+  if nil != err {
+    t.Fatalf("unexpected error: %s", err)
+  }
+
+  want := &coll{
+    Item: []collItem{
+      {
+        Name: "items",
+        Item: []collItem{
+          {
+            Name: "List items",
+            Request: &collRequest{
+              URL: collURL{
+                Raw:   "{{baseUrl}}/items?limit=20&sort=asc",
+                Host:  []string{"{{baseUrl}}"},
+                Path:  []string{"items"},
+                Query: []collQueryParam{{Key: "limit", Value: "20"}, {Key: "sort", Value: "asc"}},
+              },
+              Method: "GET",
+              Header: []collHeader{},
+            },
+          },
+        },
+      },
+      {
+        Name: "auth",
+        Item: []collItem{
+          {
+            Name: "Log in",
+            Request: &collRequest{
+              URL:    collURL{Raw: "{{baseUrl}}/login", Host: []string{"{{baseUrl}}"}, Path: []string{"login"}},
+              Method: "POST",
+              Header: []collHeader{{Key: "Content-Type", Value: "application/json"}},
+              Body:   &collBody{Mode: "raw", Raw: "{\n  \"user\": \"jane\"\n}"},
+            },
+          },
+          {
+            Name: "Log out",
+            Request: &collRequest{
+              URL:    collURL{Raw: "{{baseUrl}}/logout", Host: []string{"{{baseUrl}}"}, Path: []string{"logout"}},
+              Method: "POST",
+              Header: []collHeader{{Key: "Content-Type", Value: "application/x-www-form-urlencoded"}},
+              Body:   &collBody{Mode: "urlencoded", URLEncoded: []collURLEncodedParameter{{Key: "everywhere", Value: "true"}}},
+            },
+          },
+        },
+      },
+    },
+    Variable: []collVariable{{Key: "baseUrl", Value: "http://legacy.example.com/api", Type: "string"}},
+  }
+  want.Info.Name = "Legacy"
+
+  if !reflect.DeepEqual(want, got) {
+    t.Fatal(cmp.Diff(want, got))
+  }
+}
+
+func TestImportColl_Postman(t *testing.T) {
+  collfile.Seek(0, 0)
+  want, _ := parseColl(collfile)
+
+  collfile.Seek(0, 0)
+  got, err := importColl(collfile)
+  if nil != err {
+    t.Fatalf("unexpected error: %s", err)
+  }
+
+  if !reflect.DeepEqual(want, got) {
+    t.Fatal(cmp.Diff(want, got))
+  }
+}
+
+func TestImportColl_Invalid(t *testing.T) {
+  for input := range strings.SplitSeq("{\n\"openapi\": [\n---\n: :\n", "\n---\n") {
+    if _, err := importColl(strings.NewReader(input)); nil == err {
+      t.Errorf("importColl(%q) must fail", input)
+    }
+  }
+}
+
+func TestImportColl_MutuallyReferencingSchemas(t *testing.T) {
+  schemas := map[string]any{}
+  for n := range 12 {
+    properties := map[string]any{}
+    for m := range 12 {
+      properties[fmt.Sprint("s", m)] = map[string]any{"$ref": fmt.Sprint("#/components/schemas/S", m)}
+    }
+    schemas[fmt.Sprint("S", n)] = map[string]any{"type": "object", "properties": properties}
+  }
+
+  spec, _ := json.Marshal(map[string]any{
+    "openapi":    "3.0.0",
+    "components": map[string]any{"schemas": schemas},
+    "paths": map[string]any{
+      "/a": map[string]any{"post": map[string]any{"requestBody": map[string]any{
+        "content": map[string]any{"application/json": map[string]any{"schema": map[string]any{"$ref": "#/components/schemas/S0"}}},
+      }}},
+    },
+  })
+
+  start := time.Now()
+  got, err := importColl(bytes.NewReader(spec))
+  if nil != err {
+    t.Fatalf("unexpected error: %s", err)
+  }
+
+  if elapsed := time.Since(start); elapsed > 5*time.Second {
+    t.Errorf("importColl took %s", elapsed)
+  }
+
+  body := got.Item[0].Request.Body.Raw
+  if !strings.HasPrefix(body, "{\n  \"s0\": null,\n  \"s1\": {") || strings.Count(body, ":") > maxExampleNodes {
+    t.Errorf("unexpected example of %d bytes: %.200s", len(body), body)
+  }
+}
+
+func TestImportColl_OpenAPIQueryExamples(t *testing.T) {
+  spec := `{
+  "openapi": "3.0.0",
+  "paths": {
+    "/search": {
+      "get": {"parameters": [
+        {"name": "q", "in": "query", "example": "cats & dogs #1"},
+        {"name": "ids", "in": "query", "example": [1, 2]},
+        {"name": "token", "in": "query", "example": "{{token}}"}
+      ]}
+    }
+  }
+}`
+
+  got, err := importColl(strings.NewReader(spec))
+  if nil != err {
+    t.Fatalf("unexpected error: %s", err)
+  }
+
+  if want, raw := "{{baseUrl}}/search?q=cats+%26+dogs+%231&ids=%5B1%2C2%5D&token={{token}}", got.Item[0].Request.URL.Raw; want != raw {
+    t.Errorf("raw URL = %q, want %q", raw, want)
+  }
+}
@@ -0,0 +1,868 @@
+package playground
+
+import (
+  "bytes"
+  "encoding/json"
+  "fmt"
+  "io"
+  "maps"
+  "net/url"
+  "reflect"
+  "slices"
+  "strings"
+  "sync"
+  "time"
+)
+
+// postmanSchema is the URL of the JSON schema of the Postman Collection Format v2.1.0.
+const postmanSchema = "https://schema.getpostman.com/json/collection/v2.1.0/collection.json"
+
+// collExtra holds the members of a JSON object of a collection that its model does not know about,
+// such as the settings of auth methods or the options of a body, so that they are written back.
+type collExtra map[string]json.RawMessage
+
+// decodeLossless unmarshals the JSON object in data into v, a pointer to a struct, and returns the
+// members of the object that none of the fields of the struct hold, compacted so that they do not
+// depend on the indentation of the file. The object is decoded once into its raw members, which are
+// handed down to the fields that hold them, instead of decoding the whole object again into v.
+func decodeLossless(data []byte, v any) (collExtra, error) {
+  var members map[string]json.RawMessage
+  if err := json.Unmarshal(data, &members); nil != err {
+    return nil, err
+  }
+
+  var (
+    target = reflect.ValueOf(v).Elem()
+    fields = jsonFields(target.Type())
+    extra  collExtra
+  )
+
+  for key := range slices.Values(slices.Sorted(maps.Keys(members))) {
+    n := slices.IndexFunc(fields, func(f jsonField) bool { return key == f.name })
+    if -1 == n { /* encoding/json falls back to a case-insensitive match.  */
+      n = slices.IndexFunc(fields, func(f jsonField) bool { return strings.EqualFold(key, f.name) })
+    }
+
+    if -1 != n {
+      if err := decodeRaw(members[key], target.FieldByIndex(fields[n].index)); nil != err {
+        return nil, err
+      }
+
+      continue
+    }
+
+    compact, err := compactJSON(members[key])
+    if nil != err {
+      return nil, err
+    }
+
+    if nil == extra {
+      extra = collExtra{}
+    }
+
+    extra[key] = compact
+  }
+
+  return extra, nil
+}
+
+// decodeRaw unmarshals the raw value of a member into field; raw values are kept compacted. Since
+// raw was checked along with the object that holds it, the fields that decode themselves, and the
+// pointers and slices of them, are handed raw right away.
+func decodeRaw(raw json.RawMessage, field reflect.Value) error {
+  var (
+    t           = field.Type()
+    unmarshaler = reflect.TypeFor[json.Unmarshaler]()
+  )
+
+  switch {
+  case reflect.TypeFor[json.RawMessage]() == t:
+    compact, err := compactJSON(raw)
+    if nil != err {
+      return err
+    }
+
+    field.Set(reflect.ValueOf(compact))
+    return nil
+  case "null" == string(raw):
+    if slices.Contains([]reflect.Kind{reflect.Pointer, reflect.Slice, reflect.Map, reflect.Interface}, t.Kind()) {
+      field.SetZero()
+    }
+
+    return nil
+  case reflect.PointerTo(t).Implements(unmarshaler):
+    return field.Addr().Interface().(json.Unmarshaler).UnmarshalJSON(raw)
+  case reflect.Pointer == t.Kind() && t.Implements(unmarshaler):
+    value := reflect.New(t.Elem())
+    if err := value.Interface().(json.Unmarshaler).UnmarshalJSON(raw); nil != err {
+      return err
+    }
+
+    field.Set(value)
+    return nil
+  case reflect.Slice == t.Kind() && reflect.PointerTo(t.Elem()).Implements(unmarshaler) && '[' == raw[0]:
+    var items []json.RawMessage
+    if err := json.Unmarshal(raw, &items); nil != err {
+      return err
+    }
+
+    slice := reflect.MakeSlice(t, len(items), len(items))
+    for n, item := range items {
+      if err := decodeRaw(item, slice.Index(n)); nil != err {
+        return err
+      }
+    }
+
+    field.Set(slice)
+    return nil
+  }
+
+  return json.Unmarshal(raw, field.Addr().Interface())
+}
+
+// compactJSON returns raw without the whitespace between its tokens.
+func compactJSON(raw json.RawMessage) (json.RawMessage, error) {
+  compact := &bytes.Buffer{}
+  if err := json.Compact(compact, raw); nil != err {
+    return nil, err
+  }
+
+  return compact.Bytes(), nil
+}
+
+// A jsonField is a field of a struct that encoding/json maps a member of a JSON object to.
+type jsonField struct {
+  name  string
+  index []int // As taken by reflect.Value.FieldByIndex.
+}
+
+// jsonFields returns the fields of the struct type t that encoding/json maps the members of a JSON
+// object to. A field hides the fields of the same name that are nested deeper, as in encoding/json.
+func jsonFields(t reflect.Type) (fields []jsonField) {
+  for f := range slices.Values(reflect.VisibleFields(t)) {
+    tag := f.Tag.Get("json")
+    if !f.IsExported() || "-" == tag || (f.Anonymous && "" == tag) {
+      continue
+    }
+
+    name, _, _ := strings.Cut(tag, ",")
+    if "" == name {
+      name = f.Name
+    }
+
+    n := slices.IndexFunc(fields, func(field jsonField) bool { return name == field.name })
+    switch {
+    case -1 == n:
+      fields = append(fields, jsonField{name: name, index: f.Index})
+    case len(f.Index) < len(fields[n].index):
+      fields[n].index = f.Index
+    }
+  }
+
+  return fields
+}
+
+// encodeLossless marshals v, whose JSON form is an object, along with the members in extra.
+func encodeLossless(v any, extra collExtra) ([]byte, error) {
+  data, err := json.Marshal(v)
+  if nil != err || 0 == len(extra) {
+    return data, err
+  }
+
+  buffer := bytes.NewBuffer(slices.Clone(data[:len(data)-1]))
+  for key := range slices.Values(slices.Sorted(maps.Keys(extra))) {
+    if buffer.Len() > 1 {
+      buffer.WriteByte(',')
+    }
+
+    name, _ := json.Marshal(key)
+    buffer.Write(name)
+    buffer.WriteByte(':')
+    buffer.Write(extra[key])
+  }
+
+  buffer.WriteByte('}')
+  return buffer.Bytes(), nil
+}
+
+func (c *coll) UnmarshalJSON(data []byte) (err error) {
+  type plain coll
+  c.extra, err = decodeLossless(data, (*plain)(c))
+  return err
+}
+
+func (c coll) MarshalJSON() ([]byte, error) {
+  type plain coll
+  if nil == c.Item { /* The schema requires the items, even if there are none.  */
+    c.Item = []collItem{}
+  }
+
+  return encodeLossless(plain(c), c.extra)
+}
+
+func (i *collInfo) UnmarshalJSON(data []byte) (err error) {
+  type plain collInfo
+  i.extra, err = decodeLossless(data, (*plain)(i))
+  return err
+}
+
+func (i collInfo) MarshalJSON() ([]byte, error) {
+  type plain collInfo
+  return encodeLossless(plain(i), i.extra)
+}
+
+func (i *collItem) UnmarshalJSON(data []byte) (err error) {
+  type plain collItem
+  i.extra, err = decodeLossless(data, (*plain)(i))
+  return err
+}
+
+func (i collItem) MarshalJSON() ([]byte, error) {
+  type plain collItem
+  extra := i.extra
+  if nil == i.Request && 0 == len(i.Item) { /* An empty folder must still be told apart from a request.  */
+    extra = maps.Clone(extra)
+    if nil == extra {
+      extra = collExtra{}
+    }
+
+    extra["item"] = json.RawMessage("[]")
+  }
+
+  return encodeLossless(plain(i), extra)
+}
+
+// UnmarshalJSON reads a request, which may be written as the string of its URL.
+func (r *collRequest) UnmarshalJSON(data []byte) (err error) {
+  type plain collRequest
+  if bytes.HasPrefix(bytes.TrimSpace(data), []byte(`"`)) {
+    *r = collRequest{Method: "GET", short: true}
+    return json.Unmarshal(data, &r.URL)
+  }
+
+  r.extra, err = decodeLossless(data, (*plain)(r))
+  return err
+}
+
+// MarshalJSON writes a request as the string of its URL if it was read that way and is still a
+// plain GET request.
+func (r collRequest) MarshalJSON() ([]byte, error) {
+  type plain collRequest
+  if r.short && r.URL.short && "GET" == r.Method && 0 == len(r.Header) && nil == r.Body && nil == r.Auth && nil == r.Description && 0 == len(r.extra) {
+    return json.Marshal(r.URL.Raw)
+  }
+
+  if nil == r.Header {
+    r.Header = []collHeader{}
+  }
+
+  return encodeLossless(plain(r), r.extra)
+}
+
+// UnmarshalJSON reads a URL, which may be written as a string.
+func (u *collURL) UnmarshalJSON(data []byte) (err error) {
+  type plain collURL
+  if bytes.HasPrefix(bytes.TrimSpace(data), []byte(`"`)) {
+    *u = collURL{short: true}
+    return json.Unmarshal(data, &u.Raw)
+  }
+
+  u.extra, err = decodeLossless(data, (*plain)(u))
+  return err
+}
+
+func (u collURL) MarshalJSON() ([]byte, error) {
+  type plain collURL
+  if u.short {
+    return json.Marshal(u.Raw)
+  }
+
+  return encodeLossless(plain(u), u.extra)
+}
+
+func (q *collQueryParam) UnmarshalJSON(data []byte) (err error) {
+  type plain collQueryParam
+  q.extra, err = decodeLossless(data, (*plain)(q))
+  return err
+}
+
+func (q collQueryParam) MarshalJSON() ([]byte, error) {
+  type plain collQueryParam
+  return encodeLossless(plain(q), q.extra)
+}
+
+func (v *collPathVariable) UnmarshalJSON(data []byte) (err error) {
+  type plain collPathVariable
+  v.extra, err = decodeLossless(data, (*plain)(v))
+  return err
+}
+
+func (v collPathVariable) MarshalJSON() ([]byte, error) {
+  type plain collPathVariable
+  return encodeLossless(plain(v), v.extra)
+}
+
+func (h *collHeader) UnmarshalJSON(data []byte) (err error) {
+  type plain collHeader
+  h.extra, err = decodeLossless(data, (*plain)(h))
+  return err
+}
+
+func (h collHeader) MarshalJSON() ([]byte, error) {
+  type plain collHeader
+  return encodeLossless(plain(h), h.extra)
+}
+
+func (p *collURLEncodedParameter) UnmarshalJSON(data []byte) (err error) {
+  type plain collURLEncodedParameter
+  p.extra, err = decodeLossless(data, (*plain)(p))
+  return err
+}
+
+func (p collURLEncodedParameter) MarshalJSON() ([]byte, error) {
+  type plain collURLEncodedParameter
+  return encodeLossless(plain(p), p.extra)
+}
+
+func (b *collBody) UnmarshalJSON(data []byte) (err error) {
+  type plain collBody
+  b.extra, err = decodeLossless(data, (*plain)(b))
+  return err
+}
+
+func (b collBody) MarshalJSON() ([]byte, error) {
+  type plain collBody
+  return encodeLossless(plain(b), b.extra)
+}
+
+func (a *collAuth) UnmarshalJSON(data []byte) (err error) {
+  type plain collAuth
+  a.extra, err = decodeLossless(data, (*plain)(a))
+  return err
+}
+
+func (a collAuth) MarshalJSON() ([]byte, error) {
+  type plain collAuth
+  return encodeLossless(plain(a), a.extra)
+}
+
+func (a *collAuthAttribute) UnmarshalJSON(data []byte) (err error) {
+  type plain collAuthAttribute
+  a.extra, err = decodeLossless(data, (*plain)(a))
+  return err
+}
+
+func (a collAuthAttribute) MarshalJSON() ([]byte, error) {
+  type plain collAuthAttribute
+  return encodeLossless(plain(a), a.extra)
+}
+
+func (e *collEvent) UnmarshalJSON(data []byte) (err error) {
+  type plain collEvent
+  e.extra, err = decodeLossless(data, (*plain)(e))
+  return err
+}
+
+func (e collEvent) MarshalJSON() ([]byte, error) {
+  type plain collEvent
+  return encodeLossless(plain(e), e.extra)
+}
+
+func (s *collScript) UnmarshalJSON(data []byte) (err error) {
+  type plain collScript
+  s.extra, err = decodeLossless(data, (*plain)(s))
+  return err
+}
+
+func (s collScript) MarshalJSON() ([]byte, error) {
+  type plain collScript
+  return encodeLossless(plain(s), s.extra)
+}
+
+// UnmarshalJSON reads a description, which may be written as a string.
+func (d *collDescription) UnmarshalJSON(data []byte) (err error) {
+  type plain collDescription
+  if bytes.HasPrefix(bytes.TrimSpace(data), []byte(`"`)) {
+    *d = collDescription{}
+    return json.Unmarshal(data, &d.Content)
+  }
+
+  d.extra, err = decodeLossless(data, (*plain)(d))
+  return err
+}
+
+// MarshalJSON writes a description as a string, unless it tells the type of its content.
+func (d collDescription) MarshalJSON() ([]byte, error) {
+  type plain collDescription
+  if "" == d.Type && 0 == len(d.extra) {
+    return json.Marshal(d.Content)
+  }
+
+  return encodeLossless(plain(d), d.extra)
+}
+
+// markdownDescription returns content as a Markdown description, or nil if there is no content.
+func markdownDescription(content string) *collDescription {
+  if "" == strings.TrimSpace(content) {
+    return nil
+  }
+
+  return &collDescription{Content: content, Type: "text/markdown"}
+}
+
+// UnmarshalJSON reads a variable whose value may not be a string, such as a number or a boolean.
+// Such a value is turned into its JSON text and kept as it is, so that it is written back unchanged.
+func (v *collVariable) UnmarshalJSON(data []byte) error {
+  type plain collVariable
+  var shadow struct {
+    plain
+    Value json.RawMessage `json:"value"`
+  }
+
+  extra, err := decodeLossless(data, &shadow)
+  if nil != err {
+    return err
+  }
+
+  *v = collVariable(shadow.plain)
+  v.extra = extra
+  switch {
+  case 0 == len(shadow.Value), "null" == string(shadow.Value):
+  case '"' == shadow.Value[0]:
+    return json.Unmarshal(shadow.Value, &v.Value)
+  default:
+    v.Value, v.value = string(shadow.Value), shadow.Value
+  }
+
+  return nil
+}
+
+func (v collVariable) MarshalJSON() ([]byte, error) {
+  type plain collVariable
+  if nil == v.value || v.Value != string(v.value) {
+    return encodeLossless(plain(v), v.extra)
+  }
+
+  return encodeLossless(struct {
+    plain
+    Value json.RawMessage `json:"value"`
+  }{plain(v), v.value}, v.extra)
+}
+
+// collSegments are the segments of the host or the path of a collURL.
+type collSegments []string
+
+// UnmarshalJSON reads segments written as a single string or as an array whose elements are
+// strings or objects with a value, such as the path variables.
+func (s *collSegments) UnmarshalJSON(data []byte) error {
+  var segment string
+  if nil == json.Unmarshal(data, &segment) {
+    *s = collSegments{segment}
+    return nil
+  }
+
+  var segments []json.RawMessage
+  if err := json.Unmarshal(data, &segments); nil != err {
+    return err
+  }
+
+  *s = make(collSegments, 0, len(segments))
+  for raw := range slices.Values(segments) {
+    var object struct {
+      Value string `json:"value"`
+    }
+
+    if err := json.Unmarshal(raw, &object.Value); nil != err {
+      if err := json.Unmarshal(raw, &object); nil != err {
+        return err
+      }
+    }
+
+    *s = append(*s, object.Value)
+  }
+
+  return nil
+}
+
+// collLines are the lines of a collScript.
+type collLines []string
+
+// UnmarshalJSON reads lines written as a single string or as an array of strings.
+func (l *collLines) UnmarshalJSON(data []byte) error {
+  var line string
+  if nil == json.Unmarshal(data, &line) {
+    *l = collLines{line}
+    return nil
+  }
+
+  return json.Unmarshal(data, (*[]string)(l))
+}
+
+// writePostman writes c as an indented Postman Collection v2.1.0.
+func writePostman(c *coll, w io.Writer) error {
+  exported := *c
+  exported.Info.Schema = postmanSchema
+
+  buffer := &bytes.Buffer{}
+  encoder := json.NewEncoder(buffer)
+  encoder.SetIndent("", "\t")
+
+  if err := encoder.Encode(exported); nil != err {
+    return err
+  }
+
+  _, err := w.Write(buffer.Bytes())
+  return err
+}
+
+// splitRawURL breaks a raw URL down into the parts of a collURL, as Postman does: the values of the
+// query parameters are kept as they are written, and a host with variables is kept as one segment.
+func splitRawURL(raw string) collURL {
+  u, rest := collURL{Raw: raw}, raw
+
+  if protocol, after, found := strings.Cut(rest, "://"); found {
+    u.Protocol, rest = protocol, after
+  }
+
+  if before, hash, found := strings.Cut(rest, "#"); found {
+    rest, u.Hash = before, hash
+  }
+
+  if before, query, found := strings.Cut(rest, "?"); found {
+    rest = before
+    for pair := range strings.SplitSeq(query, "&") {
+      key, value, _ := strings.Cut(pair, "=")
+      u.Query = append(u.Query, collQueryParam{Key: key, Value: value})
+    }
+  }
+
+  host, path, found := strings.Cut(rest, "/")
+  if n := strings.LastIndexByte(host, ':'); -1 != n && !strings.Contains(host[n:], "]") && !strings.Contains(host[n:], "}") {
+    host, u.Port = host[:n], host[n+1:]
+  }
+
+  if strings.Contains(host, "{{") {
+    u.Host = collSegments{host}
+  } else if "" != host {
+    u.Host = strings.Split(host, ".")
+  }
+
+  if found {
+    u.Path = strings.Split(path, "/")
+  }
+
+  return u
+}
+
+// cloneItems copies the tree of items along with their requests, so that the requests can be
+// changed without altering the original ones.
+func cloneItems(item []collItem) []collItem {
+  if nil == item {
+    return nil
+  }
+
+  clone := slices.Clone(item)
+  for n := range clone {
+    if nil != clone[n].Request {
+      request := *clone[n].Request
+      clone[n].Request = &request
+    }
+
+    clone[n].Item = cloneItems(clone[n].Item)
+  }
+
+  return clone
+}
+
+// identify gives an ID to the items that have none, so that the website can refer to them.
+func identify(item []collItem) {
+  for n := range item {
+    if "" == item[n].ID {
+      item[n].ID = newString()
+    }
+
+    identify(item[n].Item)
+  }
+}
+
+// applyCollEdits returns a copy of c whose requests are replaced by their edits, which are the
+// snapshots of the request form of the website indexed by the ID of their items. The parts of an
+// edited request that were not changed keep their variables.
+func applyCollEdits(c *coll, edits map[string]url.Values) *coll {
+  edited := *c
+  edited.Item = cloneItems(c.Item)
+
+  inherited := cloneItems(c.Item)
+  inheritAuth(c.Auth, inherited)
+
+  variables := make(map[string]collVariable)
+  for v := range slices.Values(c.Variable) {
+    variables[v.Key] = v
+  }
+
+  var apply func(item, inherited []collItem)
+  apply = func(item, inherited []collItem) {
+    for n := range item {
+      if form, found := edits[item[n].ID]; found && nil != item[n].Request {
+        applyCollEdit(item[n].Request, inherited[n].Request.Auth, newResolver(variables), form)
+        if _, found := form["checks"]; found {
+          item[n].Assertions = splitChecks(form.Get("checks"))
+        }
+
+        if _, found := form["extract"]; found {
+          item[n].Extract = splitChecks(form.Get("extract"))
+        }
+      }
+
+      apply(item[n].Item, inherited[n].Item)
+    }
+  }
+
+  apply(edited.Item, inherited)
+  return &edited
+}
+
+// splitChecks splits the checks or the extraction rules written in the request form into lines,
+// without the blank ones.
+func splitChecks(checks string) (lines []string) {
+  for line := range strings.Lines(checks) {
+    if line = strings.TrimSpace(line); "" != line {
+      lines = append(lines, line)
+    }
+  }
+
+  return lines
+}
+
+// applyCollEdit changes req, a copy whose slices and pointers are still shared with the original
+// request, to the snapshot of the request form in form. Values that equal the resolved ones of req
+// are left untouched, and so is the header or the query parameter that carries the auth.
+func applyCollEdit(req *collRequest, auth *collAuth, res *resolver, form url.Values) {
+  req.Method = form.Get("request_method")
+
+  authHeader, authQuery := authorize(auth, res)
+  resolvedURL := res.resolve(req.URL.Raw)
+  if nil != authQuery {
+    resolvedURL = appendQuery(resolvedURL, authQuery.Key, authQuery.Value)
+  }
+
+  if target := form.Get("request_target"); resolvedURL != target {
+    if nil != authQuery {
+      target = strings.Replace(target, fmt.Sprint(url.QueryEscape(authQuery.Key), "=", url.QueryEscape(authQuery.Value)), "", 1)
+      target = strings.TrimRight(strings.Replace(strings.Replace(target, "?&", "?", 1), "&&", "&", 1), "?&")
+    }
+
+    variable := req.URL.Variable
+    req.URL = splitRawURL(target)
+    req.URL.Variable = variable
+  }
+
+  var variable []collPathVariable
+  keys, values := form["path-variable-key"], form["path-variable-value"]
+  for n := range min(len(keys), len(values)) {
+    original := slices.IndexFunc(req.URL.Variable, func(v collPathVariable) bool { return keys[n] == v.Key })
+    switch {
+    case -1 == original:
+      variable = append(variable, collPathVariable{Key: keys[n], Value: values[n]})
+    case res.resolve(req.URL.Variable[original].Value) == values[n]:
+      variable = append(variable, req.URL.Variable[original])
+    default:
+      changed := req.URL.Variable[original]
+      changed.Value = values[n]
+      variable = append(variable, changed)
+    }
+  }
+
+  req.URL.Variable = variable
+
+  header := []collHeader{}
+  keys, values = form["header-key"], form["header-value"]
+  for n := range min(len(keys), len(values)) {
+    if "" == keys[n] || (nil != authHeader && authHeader.Key == keys[n] && authHeader.Value == values[n]) {
+      continue
+    }
+
+    original := slices.IndexFunc(req.Header, func(h collHeader) bool {
+      return keys[n] == res.resolve(h.Key) && values[n] == res.resolve(h.Value)
+    })
+
+    if -1 == original {
+      header = append(header, collHeader{Key: keys[n], Value: values[n]})
+    } else {
+      header = append(header, req.Header[original])
+    }
+  }
+
+  req.Header = header
+
+  if req.Method != "POST" && req.Method != "PUT" && req.Method != "PATCH" {
+    return
+  }
+
+  body := form.Get("http-request-body")
+  switch {
+  case nil == req.Body && "" == body:
+  case nil == req.Body:
+    req.Body = &collBody{Mode: "raw", Raw: body}
+  case "urlencoded" == req.Body.Mode:
+    var resolved []string
+    for p := range slices.Values(req.Body.URLEncoded) {
+      resolved = append(resolved, fmt.Sprint(res.resolve(p.Key), "=", res.resolve(p.Value)))
+    }
+
+    if strings.Join(resolved, "\n&") != body {
+      changed := *req.Body
+      changed.URLEncoded = []collURLEncodedParameter{}
+      for pair := range strings.SplitSeq(body, "&") {
+        key, value, _ := strings.Cut(strings.TrimSpace(pair), "=")
+        changed.URLEncoded = append(changed.URLEncoded, collURLEncodedParameter{Key: key, Value: value})
+      }
+
+      req.Body = &changed
+    }
+  case res.resolve(req.Body.Raw) != body:
+    changed := *req.Body
+    changed.Mode, changed.Raw = "raw", body
+    req.Body = &changed
+  }
+}
+
+// collStore keeps the latest collection imported in every playground session, so that it can be
+// exported back with the edits made in the website.
+type collStore struct {
+  mu       sync.Mutex
+  sessions map[string]*collSession
+}
+
+// collSession holds the collection imported in a playground session, if any, and its variables.
+type collSession struct {
+  c        *coll
+  scope    *scriptScope // The variables of the collection, as the scripts of its requests left them.
+  lastUsed time.Time
+}
+
+// maxCollSessions is the number of sessions whose collections are kept at once; the least recently
+// used ones are evicted.
+const maxCollSessions = 256
+
+var collections = &collStore{sessions: map[string]*collSession{}}
+
+// keep stores c as the collection of session, evicting the least recently used session if needed.
+func (s *collStore) keep(session string, c *coll) {
+  if "" == session {
+    return
+  }
+
+  s.mu.Lock()
+  defer s.mu.Unlock()
+
+  if _, exists := s.sessions[session]; !exists {
+    s.evict()
+  }
+
+  s.sessions[session] = &collSession{c: c, scope: newScriptScope(c, nil), lastUsed: time.Now()}
+}
+
+// evict removes the least recently used session if there is no room for another one; s.mu must be held.
+func (s *collStore) evict() {
+  if len(s.sessions) < maxCollSessions {
+    return
+  }
+
+  var oldest string
+  for id, candidate := range s.sessions {
+    if "" == oldest || candidate.lastUsed.Before(s.sessions[oldest].lastUsed) {
+      oldest = id
+    }
+  }
+
+  delete(s.sessions, oldest)
+}
+
+// load returns the collection of session, or nil if none was imported.
+func (s *collStore) load(session string) *coll {
+  s.mu.Lock()
+  defer s.mu.Unlock()
+
+  if stored, exists := s.sessions[session]; exists {
+    stored.lastUsed = time.Now()
+    return stored.c
+  }
+
+  return nil
+}
+
+// script returns the scripts of the request whose item has the given ID in the collection of session,
+// with a copy of the variables of the session, or nil if there is no such request.
+func (s *collStore) script(session, id string) *scriptRun {
+  s.mu.Lock()
+  defer s.mu.Unlock()
+
+  stored, exists := s.sessions[session]
+  if !exists || nil == stored.c || "" == id {
+    return nil
+  }
+
+  script := &scriptRun{scope: stored.scope.clone()}
+  if !findRequest(stored.c.Item, id, "", stored.c.Event, script) {
+    return nil
+  }
+
+  stored.lastUsed = time.Now()
+  return script
+}
+
+// scope returns a copy of the variables of session, which has none if no collection was imported
+// and no value was extracted from a response in it.
+func (s *collStore) scope(session string) *scriptScope {
+  s.mu.Lock()
+  defer s.mu.Unlock()
+
+  if stored, exists := s.sessions[session]; exists {
+    stored.lastUsed = time.Now()
+    return stored.scope.clone()
+  }
+
+  return newScriptScope(&coll{}, nil)
+}
+
+// keepScope stores the variables of session, once the scripts of a request or its extraction rules
+// changed them. A session without a collection keeps its variables as well.
+func (s *collStore) keepScope(session string, scope *scriptScope) {
+  if "" == session {
+    return
+  }
+
+  s.mu.Lock()
+  defer s.mu.Unlock()
+
+  scope.local = map[string]string{}
+  if stored, exists := s.sessions[session]; exists {
+    stored.scope, stored.lastUsed = scope, time.Now()
+    return
+  }
+
+  if 0 == len(scope.variables()) {
+    return
+  }
+
+  s.evict()
+  s.sessions[session] = &collSession{scope: scope, lastUsed: time.Now()}
+}
+
+// findRequest looks for the request whose item has the given ID and sets the full name and the events
+// of script to its own, which follow the ones of its parents; prefix and events are the name and the
+// events of the parents of item.
+func findRequest(item []collItem, id, prefix string, events []collEvent, script *scriptRun) bool {
+  for i := range slices.Values(item) {
+    switch {
+    case id == i.ID && nil != i.Request:
+      script.name, script.events = fmt.Sprint(prefix, i.Name), slices.Concat(events, i.Event)
+      return true
+    case len(i.Item) > 0 && findRequest(i.Item, id, fmt.Sprint(prefix, i.Name, " / "), slices.Concat(events, i.Event), script):
+      return true
+    }
+  }
+
+  return false
+}
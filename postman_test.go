@@ -0,0 +1,360 @@
+package playground
+
+import (
+  "bytes"
+  "encoding/json"
+  "fmt"
+  "github.com/google/go-cmp/cmp"
+  "github.com/santhosh-tekuri/jsonschema/v5"
+  "io"
+  "net/url"
+  "reflect"
+  "strings"
+  "testing"
+)
+
+// postmanTest uses the parts of the format that the collection model does not know about, at every
+// level, along with the alternative forms of URLs, requests, descriptions and variables.
+const postmanTest = `{
+  "info": {
+    "_postman_id": "6e2a1b3c-0d4e-4f5a-8b6c-7d8e9f0a1b2c",
+    "name": "Blog",
+    "description": {"content": "The **blog** API.", "type": "text/markdown"},
+    "version": "1.2.0",
+    "schema": "https://schema.getpostman.com/json/collection/v2.1.0/collection.json",
+    "_exporter_id": "25152555"
+  },
+  "item": [
+    {
+      "id": "f0a1",
+      "name": "Posts",
+      "description": "Everything about posts.",
+      "auth": {"type": "oauth2", "oauth2": [{"key": "grant_type", "value": "client_credentials", "type": "string"}, {"key": "addTokenTo", "value": "header", "type": "string"}]},
+      "event": [{"listen": "prerequest", "script": {"type": "text/javascript", "exec": ["pm.variables.set('page', 1);"]}}],
+      "item": [
+        {
+          "id": "r1",
+          "name": "Create post",
+          "event": [{"listen": "test", "script": {"id": "s1", "type": "text/javascript", "exec": ["pm.test('ok', function () {", "  pm.response.to.have.status(201);", "});"], "packages": {}}}],
+          "request": {
+            "method": "POST",
+            "description": "Creates a post.",
+            "header": [{"key": "Content-Type", "value": "application/json", "type": "text", "description": "The format of the body."}],
+            "body": {"mode": "raw", "raw": "{\"title\": \"{{title}}\"}", "options": {"raw": {"language": "json"}}},
+            "url": {
+              "raw": "https://{{domain}}:8443/posts/:id?draft=true#top",
+              "protocol": "https",
+              "host": ["{{domain}}"],
+              "port": "8443",
+              "path": ["posts", ":id"],
+              "query": [{"key": "draft", "value": "true", "description": "Keep it hidden."}, {"key": "page", "value": "2", "disabled": true}],
+              "hash": "top",
+              "variable": [{"key": "id", "value": "5", "description": "The post."}]
+            },
+            "proxy": {"host": "localhost", "port": 3128}
+          },
+          "response": [{"name": "Created", "status": "Created", "code": 201, "header": [], "body": "{}"}],
+          "protocolProfileBehavior": {"disableBodyPruning": true}
+        },
+        {
+          "name": "Upload cover",
+          "request": {
+            "method": "PUT",
+            "header": [],
+            "body": {"mode": "formdata", "formdata": [{"key": "cover", "type": "file", "src": "/tmp/cover.png"}]},
+            "url": "{{host}}/posts/5/cover"
+          }
+        },
+        {
+          "name": "Drafts",
+          "item": []
+        }
+      ]
+    },
+    {
+      "name": "Ping",
+      "request": "https://fontseca.dev/ping"
+    }
+  ],
+  "event": [{"listen": "test", "script": {"type": "text/javascript", "exec": [""]}, "disabled": true}],
+  "variable": [
+    {"key": "host", "value": "https://fontseca.dev", "type": "string"},
+    {"key": "retries", "value": 3, "type": "number"},
+    {"key": "verbose", "value": false, "type": "boolean", "description": "Logs everything."}
+  ],
+  "auth": {"type": "bearer", "bearer": [{"key": "token", "value": "{{token}}", "type": "string"}]},
+  "protocolProfileBehavior": {"followRedirects": false}
+}`
+
+// validatePostman fails t if data is not a valid Postman Collection v2.1.0.
+func validatePostman(t *testing.T, data []byte) {
+  t.Helper()
+
+  schema, err := jsonschema.NewCompiler().Compile("testdata/postman-collection-v2.1.0.json")
+  if nil != err {
+    t.Fatalf("could not compile the schema: %s", err)
+  }
+
+  var document any
+  decoder := json.NewDecoder(bytes.NewReader(data))
+  decoder.UseNumber()
+  if err := decoder.Decode(&document); nil != err {
+    t.Fatalf("unexpected error: %s", err)
+  }
+
+  if err := schema.Validate(document); nil != err {
+    t.Errorf("the collection does not follow the schema: %#v\n\n%s", err, data)
+  }
+}
+
+func TestWritePostman_roundTrip(t *testing.T) {
+  c, err := importColl(strings.NewReader(postmanTest))
+  if nil != err {
+    t.Fatalf("unexpected error: %s", err)
+  }
+
+  var output bytes.Buffer
+  if err := writePostman(c, &output); nil != err {
+    t.Fatalf("unexpected error: %s", err)
+  }
+
+  validatePostman(t, output.Bytes())
+
+  var want, got any
+  json.Unmarshal([]byte(postmanTest), &want)
+  json.Unmarshal(output.Bytes(), &got)
+  if !reflect.DeepEqual(want, got) {
+    t.Fatal(cmp.Diff(want, got))
+  }
+
+  again, err := importColl(&output)
+  if nil != err {
+    t.Fatalf("unexpected error: %s", err)
+  }
+
+  if !reflect.DeepEqual(c, again) {
+    t.Fatal(cmp.Diff(c, again, collUnexported))
+  }
+}
+
+func TestDecodeLossless(t *testing.T) {
+  const depth = 100
+
+  data := `{"info": {"name": "Deep", "schema": "` + postmanSchema + `"}, "item": [` +
+    strings.Repeat(`{"NAME": "folder", "x-order": [ 1, 2 ], "item": [`, depth) +
+    `{"name": "ping", "request": {"method": "GET", "url": "https://fontseca.dev", "header": null}}` +
+    strings.Repeat(`]}`, depth) + `]}`
+
+  var c coll
+  if err := json.Unmarshal([]byte(data), &c); nil != err {
+    t.Fatalf("unexpected error: %s", err)
+  }
+
+  item := c.Item[0]
+  if "folder" != item.Name || "[1,2]" != string(item.extra["x-order"]) {
+    t.Fatalf("c.Item[0] = %q with %s, want the folder with its order compacted", item.Name, item.extra)
+  }
+
+  for range depth - 1 {
+    item = item.Item[0]
+  }
+
+  if ping := item.Item[0]; "ping" != ping.Name || "https://fontseca.dev" != ping.Request.URL.Raw || nil != ping.Request.Header {
+    t.Errorf("innermost item = %+v, want the ping request", ping)
+  }
+}
+
+func TestWritePostman_importers(t *testing.T) {
+  collfile.Seek(0, io.SeekStart)
+  fixture, _ := io.ReadAll(collfile)
+
+  tests := []struct {
+    name, input string
+  }{
+    {"Postman", string(fixture)},
+    {"Insomnia", insomniaTest},
+    {"HTTP file", httpFileTest},
+    {"Bruno", "meta {\n  name: Ping\n}\n\nget {\n  url: https://fontseca.dev/ping\n}\n\ndocs {\n  Checks the server.\n}\n"},
+    {"OpenAPI", "openapi: 3.0.3\ninfo:\n  title: API\n  version: 1.0.0\npaths:\n  /users/{id}:\n    get:\n      parameters:\n        - {name: id, in: path, required: true, schema: {type: integer}}\n"},
+    {"HAR", `{"log": {"version": "1.2", "creator": {"name": "WebInspector", "version": "537.36"}, "entries": [{"request": {"method": "GET", "url": "https://fontseca.dev/?q=go", "headers": [{"name": "Accept", "value": "*/*"}], "queryString": [{"name": "q", "value": "go"}]}}]}}`},
+  }
+
+  for _, test := range tests {
+    c, err := importColl(strings.NewReader(test.input))
+    if nil != err {
+      t.Fatalf("%s: unexpected error: %s", test.name, err)
+    }
+
+    var output bytes.Buffer
+    if err := writePostman(c, &output); nil != err {
+      t.Fatalf("%s: unexpected error: %s", test.name, err)
+    }
+
+    validatePostman(t, output.Bytes())
+  }
+}
+
+func TestSplitRawURL(t *testing.T) {
+  tests := []struct {
+    raw  string
+    want collURL
+  }{
+    {"https://api.fontseca.dev:8443/users/:id?page=2&q=a%20b#top", collURL{
+      Raw:      "https://api.fontseca.dev:8443/users/:id?page=2&q=a%20b#top",
+      Protocol: "https",
+      Host:     collSegments{"api", "fontseca", "dev"},
+      Port:     "8443",
+      Path:     collSegments{"users", ":id"},
+      Query:    []collQueryParam{{Key: "page", Value: "2"}, {Key: "q", Value: "a%20b"}},
+      Hash:     "top",
+    }},
+    {"{{host}}/", collURL{Raw: "{{host}}/", Host: collSegments{"{{host}}"}, Path: collSegments{""}}},
+    {"{{api.base-url}}", collURL{Raw: "{{api.base-url}}", Host: collSegments{"{{api.base-url}}"}}},
+    {"http://[::1]:80/", collURL{Raw: "http://[::1]:80/", Protocol: "http", Host: collSegments{"[::1]"}, Port: "80", Path: collSegments{""}}},
+  }
+
+  for _, test := range tests {
+    if got := splitRawURL(test.raw); !reflect.DeepEqual(test.want, got) {
+      t.Errorf("splitRawURL(%q):\n%s", test.raw, cmp.Diff(test.want, got, collUnexported))
+    }
+  }
+}
+
+func TestApplyCollEdits(t *testing.T) {
+  c, err := importColl(strings.NewReader(`{
+    "info": {"name": "Blog", "schema": "https://schema.getpostman.com/json/collection/v2.1.0/collection.json"},
+    "auth": {"type": "apikey", "apikey": [{"key": "key", "value": "api_key"}, {"key": "value", "value": "{{key}}"}, {"key": "in", "value": "query"}]},
+    "variable": [{"key": "host", "value": "https://fontseca.dev"}, {"key": "key", "value": "k3y"}],
+    "item": [
+      {"id": "r1", "name": "Get post", "request": {
+        "method": "GET",
+        "header": [{"key": "Accept", "value": "{{accept}}"}, {"key": "X-Trace", "value": "1", "type": "text"}],
+        "url": {"raw": "{{host}}/posts/:id", "host": ["{{host}}"], "path": ["posts", ":id"], "variable": [{"key": "id", "value": "5", "description": "The post."}]}
+      }},
+      {"id": "r2", "name": "Log in", "request": {
+        "method": "POST",
+        "header": [],
+        "body": {"mode": "urlencoded", "urlencoded": [{"key": "user", "value": "jane"}, {"key": "password", "value": "{{password}}", "type": "text"}]},
+        "url": "{{host}}/login"
+      }},
+      {"id": "r3", "name": "Untouched", "request": {"method": "GET", "header": [], "url": "{{host}}/"}}
+    ]
+  }`))
+
+  if nil != err {
+    t.Fatalf("unexpected error: %s", err)
+  }
+
+  original, _ := json.Marshal(c)
+  edited := applyCollEdits(c, map[string]url.Values{
+    "r1": {
+      "request_method":      {"GET"},
+      "request_target":      {"https://fontseca.dev/posts/:id?api_key=k3y"},
+      "header-key":          {"Accept", "X-Trace", "X-Debug", ""},
+      "header-value":        {"{{accept}}", "2", "true", ""},
+      "path-variable-key":   {"id"},
+      "path-variable-value": {"7"},
+      "checks":              {"status == 200\r\n\n$.id exists\n"},
+    },
+    "r2": {
+      "request_method":    {"POST"},
+      "request_target":    {"https://fontseca.dev/login?api_key=k3y&remember=1"},
+      "header-key":        {""},
+      "header-value":      {""},
+      "http-request-body": {"user=john\n&password={{password}}"},
+    },
+  })
+
+  if got, _ := json.Marshal(c); !bytes.Equal(original, got) {
+    t.Errorf("applyCollEdits changed the original collection:\n%s", cmp.Diff(string(original), string(got)))
+  }
+
+  want := []collItem{
+    {ID: "r1", Name: "Get post", Assertions: []string{"status == 200", "$.id exists"}, Request: &collRequest{
+      Method: "GET",
+      Header: []collHeader{
+        {Key: "Accept", Value: "{{accept}}"},
+        {Key: "X-Trace", Value: "2"},
+        {Key: "X-Debug", Value: "true"},
+      },
+      URL: collURL{
+        Raw:      "{{host}}/posts/:id",
+        Host:     collSegments{"{{host}}"},
+        Path:     collSegments{"posts", ":id"},
+        Variable: []collPathVariable{{Key: "id", Value: "7", extra: collExtra{"description": json.RawMessage(`"The post."`)}}},
+      },
+    }},
+    {ID: "r2", Name: "Log in", Request: &collRequest{
+      Method: "POST",
+      Header: []collHeader{},
+      Body:   &collBody{Mode: "urlencoded", URLEncoded: []collURLEncodedParameter{{Key: "user", Value: "john"}, {Key: "password", Value: "{{password}}"}}},
+      URL: collURL{
+        Raw:      "https://fontseca.dev/login?remember=1",
+        Protocol: "https",
+        Host:     collSegments{"fontseca", "dev"},
+        Path:     collSegments{"login"},
+        Query:    []collQueryParam{{Key: "remember", Value: "1"}},
+      },
+    }},
+    {ID: "r3", Name: "Untouched", Request: &collRequest{Method: "GET", Header: []collHeader{}, URL: collURL{Raw: "{{host}}/", short: true}}},
+  }
+
+  if !reflect.DeepEqual(want, edited.Item) {
+    t.Fatal(cmp.Diff(want, edited.Item, collUnexported))
+  }
+}
+
+func TestCollStore_evict(t *testing.T) {
+  store := &collStore{sessions: map[string]*collSession{}}
+  for n := range maxCollSessions + 1 {
+    store.keep(fmt.Sprint("session-", n), &coll{})
+  }
+
+  if _, exists := store.sessions["session-0"]; exists || maxCollSessions != len(store.sessions) {
+    t.Errorf("%d sessions are kept, want %d without the least recently used one", len(store.sessions), maxCollSessions)
+  }
+}
+
+func TestCollStore_script(t *testing.T) {
+  c, err := importColl(strings.NewReader(postmanTest))
+  if nil != err {
+    t.Fatalf("unexpected error: %s", err)
+  }
+
+  store := &collStore{sessions: map[string]*collSession{}}
+  store.keep("one", c)
+
+  script := store.script("one", "r1")
+  if nil == script {
+    t.Fatal("the request r1 was not found")
+  }
+
+  if "Posts / Create post" != script.name || 3 != len(script.events) || "test" != script.events[2].Listen {
+    t.Errorf("script = %q with %d events, want \"Posts / Create post\" with the events of the collection, the folder and the request", script.name, len(script.events))
+  }
+
+  script.scope.environment["token"] = "s3cr3t"
+  store.keepScope("one", script.scope)
+
+  if again := store.script("one", "r1"); "s3cr3t" != again.scope.environment["token"] || "https://fontseca.dev" != again.scope.collection["host"] {
+    t.Errorf("the variables of the session were not kept: %+v", again.scope)
+  }
+
+  for session, id := range map[string]string{"one": "f0a1", "two": "r1", "three": ""} {
+    if nil != store.script(session, id) {
+      t.Errorf("script(%q, %q) found a request", session, id)
+    }
+  }
+
+  scope := store.scope("two")
+  store.keepScope("two", scope)
+  if _, exists := store.sessions["two"]; exists {
+    t.Error("keepScope kept a session without variables")
+  }
+
+  scope.environment["token"] = "t0k3n"
+  store.keepScope("two", scope)
+  if got := store.scope("two"); "t0k3n" != got.environment["token"] || nil != store.script("two", "r1") {
+    t.Errorf("the variables of a session without a collection were not kept: %+v", got)
+  }
+}
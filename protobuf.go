@@ -0,0 +1,700 @@
+package playground
+
+import (
+  "encoding/base64"
+  "encoding/binary"
+  "fmt"
+  "math"
+  "slices"
+  "strconv"
+  "strings"
+  "unicode"
+  "unicode/utf8"
+)
+
+// The wire types of the fields of protobuf messages.
+const (
+  protoWireVarint     = 0
+  protoWireFixed64    = 1
+  protoWireBytes      = 2
+  protoWireStartGroup = 3
+  protoWireEndGroup   = 4
+  protoWireFixed32    = 5
+)
+
+// The types of the fields of protobuf messages, numbered as in descriptor.proto.
+const (
+  protoDouble   = 1
+  protoFloat    = 2
+  protoInt64    = 3
+  protoUint64   = 4
+  protoInt32    = 5
+  protoFixed64  = 6
+  protoFixed32  = 7
+  protoBool     = 8
+  protoString   = 9
+  protoGroup    = 10
+  protoMessage  = 11
+  protoBytes    = 12
+  protoUint32   = 13
+  protoEnum     = 14
+  protoSfixed32 = 15
+  protoSfixed64 = 16
+  protoSint32   = 17
+  protoSint64   = 18
+)
+
+// A protoValue is a field of a protobuf message as it is on the wire.
+type protoValue struct {
+  number uint64
+  wire   uint64
+  scalar uint64 // The value of a varint or of a fixed-size field.
+  data   []byte // The content of a length-delimited field or of a group.
+  offset int    // Where data starts in the body.
+}
+
+// A protoReader reads the fields of a protobuf message.
+type protoReader struct {
+  input []byte
+  n     int
+  base  int // Where input starts in the body.
+  depth int // The number of groups the field at n is nested in.
+}
+
+// protoScan returns the fields of the message input, which starts at base in the body.
+func protoScan(input []byte, base int) ([]protoValue, error) {
+  r := &protoReader{input: input, base: base}
+  var values []protoValue
+  for r.n < len(r.input) {
+    value, err := r.field()
+    if nil != err {
+      return nil, err
+    }
+
+    if protoWireEndGroup == value.wire {
+      return nil, fmt.Errorf("unexpected end of group %d at offset %d", value.number, value.offset)
+    }
+
+    values = append(values, value)
+  }
+
+  return values, nil
+}
+
+// varint reads a varint.
+func (r *protoReader) varint() (uint64, error) {
+  value, size := binary.Uvarint(r.input[r.n:])
+  if size <= 0 {
+    return 0, fmt.Errorf("invalid varint at offset %d", r.base+r.n)
+  }
+
+  r.n += size
+  return value, nil
+}
+
+// take returns the next size bytes.
+func (r *protoReader) take(size uint64) ([]byte, error) {
+  if size > uint64(len(r.input)-r.n) {
+    return nil, fmt.Errorf("unexpected end of input at offset %d", r.base+len(r.input))
+  }
+
+  start := r.n
+  r.n += int(size)
+  return r.input[start:r.n], nil
+}
+
+// field reads the field at n, which may be the end of a group.
+func (r *protoReader) field() (value protoValue, err error) {
+  offset := r.n
+  tag, err := r.varint()
+  if nil != err {
+    return value, err
+  }
+
+  value = protoValue{number: tag >> 3, wire: tag & 7, offset: r.base + offset}
+  if 0 == value.number {
+    return value, fmt.Errorf("invalid field number 0 at offset %d", value.offset)
+  }
+
+  var data []byte
+  switch value.wire {
+  case protoWireVarint:
+    value.scalar, err = r.varint()
+  case protoWireFixed64:
+    data, err = r.take(8)
+    if nil == err {
+      value.scalar = binary.LittleEndian.Uint64(data)
+    }
+  case protoWireFixed32:
+    data, err = r.take(4)
+    if nil == err {
+      value.scalar = uint64(binary.LittleEndian.Uint32(data))
+    }
+  case protoWireBytes:
+    var size uint64
+    if size, err = r.varint(); nil == err {
+      value.offset = r.base + r.n
+      value.data, err = r.take(size)
+    }
+  case protoWireStartGroup:
+    if r.depth++; r.depth > maxDecodingDepth {
+      return value, fmt.Errorf("more than %d levels of nesting at offset %d", maxDecodingDepth, value.offset)
+    }
+
+    defer func() { r.depth-- }()
+    start := r.n
+    for {
+      end := r.n
+      if end >= len(r.input) {
+        return value, fmt.Errorf("unterminated group %d at offset %d", value.number, value.offset)
+      }
+
+      inner, err := r.field()
+      if nil != err {
+        return value, err
+      }
+
+      if protoWireEndGroup == inner.wire && value.number == inner.number {
+        value.data, value.offset = r.input[start:end], r.base+start
+        break
+      }
+    }
+  case protoWireEndGroup:
+  default:
+    err = fmt.Errorf("invalid wire type %d at offset %d", value.wire, value.offset)
+  }
+
+  return value, err
+}
+
+// protoRaw returns the JSON tree of the message input, decoded without a schema, the way protoc
+// --decode_raw does: fields are named by their number, and the ones that are repeated are arrays.
+func protoRaw(input []byte, base, depth int) (*jsonNode, error) {
+  if depth > maxDecodingDepth {
+    return nil, fmt.Errorf("more than %d levels of nesting at offset %d", maxDecodingDepth, base)
+  }
+
+  values, err := protoScan(input, base)
+  if nil != err {
+    return nil, err
+  }
+
+  fields := protoFields{}
+  for value := range slices.Values(values) {
+    node, err := protoRawValue(value, depth)
+    if nil != err {
+      return nil, err
+    }
+
+    fields.add(strconv.FormatUint(value.number, 10), false, node)
+  }
+
+  return fields.object(), nil
+}
+
+// protoRawValue returns the JSON tree of value, decoded without a schema. Length-delimited fields are
+// strings if they are text, messages if they parse as such, and base64 strings otherwise.
+func protoRawValue(value protoValue, depth int) (*jsonNode, error) {
+  switch value.wire {
+  case protoWireStartGroup:
+    return protoRaw(value.data, value.offset, depth+1)
+  case protoWireBytes:
+    if protoIsText(value.data) {
+      return jsonString(string(value.data)), nil
+    }
+
+    if message, err := protoRaw(value.data, value.offset, depth+1); nil == err {
+      return message, nil
+    }
+
+    return jsonString(base64.StdEncoding.EncodeToString(value.data)), nil
+  }
+
+  return jsonScalar(strconv.FormatUint(value.scalar, 10)), nil
+}
+
+// protoIsText reports whether data is UTF-8 text, without control characters other than blanks.
+func protoIsText(data []byte) bool {
+  if !utf8.Valid(data) {
+    return false
+  }
+
+  for _, r := range string(data) {
+    if unicode.IsControl(r) && !unicode.IsSpace(r) {
+      return false
+    }
+  }
+
+  return true
+}
+
+// protoFields gathers the fields of a message, in the order they first appear.
+type protoFields struct {
+  names    []string
+  values   map[string][]*jsonNode
+  repeated map[string]bool
+  entries  map[string][]jsonMember // The entries of map fields.
+}
+
+// add adds value to the field name, which is an array if it is repeated, or appears more than once.
+func (f *protoFields) add(name string, repeated bool, values ...*jsonNode) {
+  if nil == f.values {
+    f.values, f.repeated, f.entries = map[string][]*jsonNode{}, map[string]bool{}, map[string][]jsonMember{}
+  }
+
+  if _, ok := f.values[name]; !ok {
+    f.names = append(f.names, name)
+  }
+
+  f.values[name] = append(f.values[name], values...)
+  f.repeated[name] = f.repeated[name] || repeated
+}
+
+// addEntry adds the entry key: value to the map field name.
+func (f *protoFields) addEntry(name string, key string, value *jsonNode) {
+  f.add(name, false)
+  f.entries[name] = append(f.entries[name], newJSONMember(key, value))
+}
+
+// object returns the object of the fields.
+func (f *protoFields) object() *jsonNode {
+  members := make([]jsonMember, 0, len(f.names))
+  for name := range slices.Values(f.names) {
+    values := f.values[name]
+    switch {
+    case nil != f.entries[name]:
+      members = append(members, newJSONMember(name, jsonObject(f.entries[name])))
+    case f.repeated[name] || len(values) > 1:
+      members = append(members, newJSONMember(name, jsonArray(values)))
+    default:
+      members = append(members, newJSONMember(name, values[0]))
+    }
+  }
+
+  return jsonObject(members)
+}
+
+// A protoSchema holds the message and enum types of a descriptor set.
+type protoSchema struct {
+  messages map[string]*protoMessageType // By full name, such as shop.v1.Order.
+  enums    map[string]map[int32]string  // The names of the values of enum types, by full name.
+}
+
+// A protoMessageType is a message type of a descriptor set.
+type protoMessageType struct {
+  name     string
+  fields   map[uint64]*protoField
+  mapEntry bool // Whether it is the entry of a map field.
+}
+
+// A protoField is a field of a message type.
+type protoField struct {
+  name     string
+  repeated bool
+  kind     uint64 // Such as protoString.
+  typeName string // The full name of the type of a message or an enum field.
+}
+
+// parseProtoDescriptorSet reads the message and enum types of a FileDescriptorSet, such as the output of
+// protoc --descriptor_set_out.
+func parseProtoDescriptorSet(data []byte) (*protoSchema, error) {
+  schema := &protoSchema{messages: map[string]*protoMessageType{}, enums: map[string]map[int32]string{}}
+  files, err := protoScan(data, 0)
+  if nil != err {
+    return nil, err
+  }
+
+  for file := range slices.Values(files) {
+    if 1 != file.number || protoWireBytes != file.wire {
+      continue
+    }
+
+    values, err := protoScan(file.data, file.offset)
+    if nil != err {
+      return nil, err
+    }
+
+    pkg := ""
+    for value := range slices.Values(values) {
+      if 2 == value.number {
+        pkg = string(value.data)
+      }
+    }
+
+    for value := range slices.Values(values) {
+      switch value.number {
+      case 4:
+        err = schema.addMessage(pkg, value)
+      case 5:
+        err = schema.addEnum(pkg, value)
+      }
+
+      if nil != err {
+        return nil, err
+      }
+    }
+  }
+
+  if 0 == len(schema.messages) {
+    return nil, fmt.Errorf("no message types")
+  }
+
+  return schema, nil
+}
+
+// protoFullName returns the full name of the type name declared in scope.
+func protoFullName(scope, name string) string {
+  if "" == scope {
+    return name
+  }
+
+  return fmt.Sprint(scope, ".", name)
+}
+
+// addMessage adds the message type that the DescriptorProto value declares in scope, along with the
+// types nested in it.
+func (s *protoSchema) addMessage(scope string, value protoValue) error {
+  values, err := protoScan(value.data, value.offset)
+  if nil != err {
+    return err
+  }
+
+  message := &protoMessageType{fields: map[uint64]*protoField{}}
+  for value := range slices.Values(values) {
+    if 1 == value.number {
+      message.name = protoFullName(scope, string(value.data))
+    }
+  }
+
+  s.messages[message.name] = message
+  for value := range slices.Values(values) {
+    switch value.number {
+    case 2:
+      number, field, err := parseProtoField(value)
+      if nil != err {
+        return err
+      }
+
+      message.fields[number] = field
+    case 3:
+      err = s.addMessage(message.name, value)
+    case 4:
+      err = s.addEnum(message.name, value)
+    case 7: /* MessageOptions, whose field 7 is map_entry.  */
+      options, err := protoScan(value.data, value.offset)
+      if nil != err {
+        return err
+      }
+
+      for option := range slices.Values(options) {
+        message.mapEntry = message.mapEntry || 7 == option.number && 0 != option.scalar
+      }
+    }
+
+    if nil != err {
+      return err
+    }
+  }
+
+  return nil
+}
+
+// parseProtoField reads the FieldDescriptorProto value.
+func parseProtoField(value protoValue) (uint64, *protoField, error) {
+  values, err := protoScan(value.data, value.offset)
+  if nil != err {
+    return 0, nil, err
+  }
+
+  var number uint64
+  field, jsonName := &protoField{}, ""
+  for value := range slices.Values(values) {
+    switch value.number {
+    case 1:
+      field.name = string(value.data)
+    case 3:
+      number = value.scalar
+    case 4:
+      field.repeated = 3 == value.scalar
+    case 5:
+      field.kind = value.scalar
+    case 6:
+      field.typeName = strings.TrimPrefix(string(value.data), ".")
+    case 10:
+      jsonName = string(value.data)
+    }
+  }
+
+  if "" != jsonName {
+    field.name = jsonName
+  }
+
+  return number, field, nil
+}
+
+// addEnum adds the enum type that the EnumDescriptorProto value declares in scope.
+func (s *protoSchema) addEnum(scope string, value protoValue) error {
+  values, err := protoScan(value.data, value.offset)
+  if nil != err {
+    return err
+  }
+
+  name, names := "", map[int32]string{}
+  for value := range slices.Values(values) {
+    switch value.number {
+    case 1:
+      name = protoFullName(scope, string(value.data))
+    case 2:
+      constant, err := protoScan(value.data, value.offset)
+      if nil != err {
+        return err
+      }
+
+      constantName, number := "", int32(0)
+      for value := range slices.Values(constant) {
+        switch value.number {
+        case 1:
+          constantName = string(value.data)
+        case 2:
+          number = int32(value.scalar)
+        }
+      }
+
+      names[number] = constantName
+    }
+  }
+
+  s.enums[name] = names
+  return nil
+}
+
+// lookup returns the message type named name, such as shop.v1.Order, or just Order if no other type
+// of the schema has that name.
+func (s *protoSchema) lookup(name string) *protoMessageType {
+  name = strings.TrimPrefix(name, ".")
+  if message, ok := s.messages[name]; ok {
+    return message
+  }
+
+  var found *protoMessageType
+  for fullName, message := range s.messages {
+    if strings.HasSuffix(fullName, "."+name) {
+      if nil != found {
+        return nil
+      }
+
+      found = message
+    }
+  }
+
+  return found
+}
+
+// decode returns the JSON tree of input, a message of type message, which starts at base in the body.
+// Fields that the type does not declare, or whose wire type does not match theirs, are decoded without
+// a schema.
+func (s *protoSchema) decode(input []byte, message *protoMessageType, base, depth int) (*jsonNode, error) {
+  if depth > maxDecodingDepth {
+    return nil, fmt.Errorf("more than %d levels of nesting at offset %d", maxDecodingDepth, base)
+  }
+
+  values, err := protoScan(input, base)
+  if nil != err {
+    return nil, err
+  }
+
+  fields := protoFields{}
+  for value := range slices.Values(values) {
+    field := message.fields[value.number]
+    nodes, ok := []*jsonNode(nil), false
+    if nil != field {
+      if nodes, ok, err = s.decodeField(field, value, depth); nil != err {
+        return nil, err
+      }
+    }
+
+    if !ok {
+      node, err := protoRawValue(value, depth)
+      if nil != err {
+        return nil, err
+      }
+
+      fields.add(strconv.FormatUint(value.number, 10), false, node)
+      continue
+    }
+
+    if entry := s.messages[field.typeName]; protoMessage == field.kind && nil != entry && entry.mapEntry {
+      key, value := jsonScalar(`""`), jsonScalar("null")
+      for member := range slices.Values(nodes[0].members) {
+        switch {
+        case nil != entry.fields[1] && entry.fields[1].name == member.name:
+          key = member.value
+        case nil != entry.fields[2] && entry.fields[2].name == member.name:
+          value = member.value
+        }
+      }
+
+      fields.addEntry(field.name, jsonKey(key), value)
+      continue
+    }
+
+    fields.add(field.name, field.repeated, nodes...)
+  }
+
+  return fields.object(), nil
+}
+
+// decodeField returns the values of field that value holds: one, or several if they are packed. It
+// reports false if the wire type of value does not match the type of field.
+func (s *protoSchema) decodeField(field *protoField, value protoValue, depth int) ([]*jsonNode, bool, error) {
+  wire := uint64(protoWireVarint)
+  switch field.kind {
+  case protoDouble, protoFixed64, protoSfixed64:
+    wire = protoWireFixed64
+  case protoFloat, protoFixed32, protoSfixed32:
+    wire = protoWireFixed32
+  case protoString, protoBytes, protoMessage:
+    wire = protoWireBytes
+  case protoGroup:
+    wire = protoWireStartGroup
+  }
+
+  switch {
+  case protoWireBytes == value.wire && protoWireBytes != wire && protoWireStartGroup != wire:
+    return s.unpack(field, wire, value)
+  case wire != value.wire:
+    return nil, false, nil
+  }
+
+  switch field.kind {
+  case protoString:
+    return []*jsonNode{jsonString(string(value.data))}, true, nil
+  case protoBytes:
+    return []*jsonNode{jsonString(base64.StdEncoding.EncodeToString(value.data))}, true, nil
+  case protoMessage, protoGroup:
+    message := s.messages[field.typeName]
+    if nil == message {
+      node, err := protoRaw(value.data, value.offset, depth+1)
+      return []*jsonNode{node}, nil == err, nil
+    }
+
+    node, err := s.decode(value.data, message, value.offset, depth+1)
+    return []*jsonNode{node}, nil == err, err
+  }
+
+  return []*jsonNode{s.scalar(field, value.scalar)}, true, nil
+}
+
+// unpack returns the values of a packed repeated field, whose wire type is wire.
+func (s *protoSchema) unpack(field *protoField, wire uint64, value protoValue) ([]*jsonNode, bool, error) {
+  r := &protoReader{input: value.data, base: value.offset}
+  var nodes []*jsonNode
+  for r.n < len(r.input) {
+    var scalar uint64
+    switch wire {
+    case protoWireFixed64:
+      data, err := r.take(8)
+      if nil != err {
+        return nil, false, nil
+      }
+
+      scalar = binary.LittleEndian.Uint64(data)
+    case protoWireFixed32:
+      data, err := r.take(4)
+      if nil != err {
+        return nil, false, nil
+      }
+
+      scalar = uint64(binary.LittleEndian.Uint32(data))
+    default:
+      varint, err := r.varint()
+      if nil != err {
+        return nil, false, nil
+      }
+
+      scalar = varint
+    }
+
+    nodes = append(nodes, s.scalar(field, scalar))
+  }
+
+  return nodes, true, nil
+}
+
+// scalar returns the JSON value of a scalar field: a number, a boolean, or the name of an enum value if
+// it is known.
+func (s *protoSchema) scalar(field *protoField, value uint64) *jsonNode {
+  switch field.kind {
+  case protoDouble:
+    return jsonFloat(math.Float64frombits(value), 64)
+  case protoFloat:
+    return jsonFloat(float64(math.Float32frombits(uint32(value))), 32)
+  case protoInt64, protoSfixed64:
+    return jsonScalar(strconv.FormatInt(int64(value), 10))
+  case protoInt32, protoSfixed32:
+    return jsonScalar(strconv.FormatInt(int64(int32(value)), 10))
+  case protoUint32, protoFixed32:
+    return jsonScalar(strconv.FormatUint(uint64(uint32(value)), 10))
+  case protoBool:
+    return jsonScalar(strconv.FormatBool(0 != value))
+  case protoSint32:
+    return jsonScalar(strconv.FormatInt(int64(int32(uint32(value)>>1)^-int32(value&1)), 10))
+  case protoSint64:
+    return jsonScalar(strconv.FormatInt(int64(value>>1)^-int64(value&1), 10))
+  case protoEnum:
+    if name, ok := s.enums[field.typeName][int32(value)]; ok {
+      return jsonString(name)
+    }
+
+    return jsonScalar(strconv.FormatInt(int64(int32(value)), 10))
+  }
+
+  return jsonScalar(strconv.FormatUint(value, 10))
+}
+
+// newProtobufFormatter returns the formatter of protobuf bodies that decodes them as messages of the type
+// named message, which the descriptor set declares, or without a schema if there is none.
+func newProtobufFormatter(descriptorSet []byte, message string) *binaryFormatterImpl {
+  var (
+    schema      *protoSchema
+    messageType *protoMessageType
+    warning     string
+    err         error
+  )
+
+  switch {
+  case 0 == len(descriptorSet):
+    warning = "decoded without a schema, so fields are named by their number: set a descriptor set and a message name in the Settings tab to see their names"
+  case "" == message:
+    warning = "decoded without a schema: no message name was given"
+  default:
+    if schema, err = parseProtoDescriptorSet(descriptorSet); nil != err {
+      warning = fmt.Sprintf("decoded without a schema: invalid descriptor set: %s", err.Error())
+    } else if messageType = schema.lookup(message); nil == messageType {
+      warning = fmt.Sprintf("decoded without a schema: the descriptor set has no message %q", message)
+    }
+  }
+
+  decode := func(input []byte) ([]*jsonNode, []string, error) {
+    var warnings []string
+    if "" != warning {
+      warnings = append(warnings, warning)
+    }
+
+    var node *jsonNode
+    var err error
+    if nil == messageType {
+      node, err = protoRaw(input, 0, 0)
+    } else {
+      node, err = schema.decode(input, messageType, 0, 0)
+    }
+
+    if nil != err {
+      return nil, warnings, err
+    }
+
+    return []*jsonNode{node}, warnings, nil
+  }
+
+  return &binaryFormatterImpl{encoding: "Protobuf", decode: decode}
+}
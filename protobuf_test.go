@@ -0,0 +1,151 @@
+package playground
+
+import (
+  "bytes"
+  "encoding/binary"
+  "math"
+  "reflect"
+  "testing"
+)
+
+// protoTestField encodes the field number of wire type wire, whose value is a varint, 8 or 4 bytes, or
+// the content of a length-delimited field.
+func protoTestField(number, wire uint64, value any) []byte {
+  data := binary.AppendUvarint(nil, number<<3|wire)
+  switch value := value.(type) {
+  case uint64:
+    switch wire {
+    case protoWireFixed64:
+      return binary.LittleEndian.AppendUint64(data, value)
+    case protoWireFixed32:
+      return binary.LittleEndian.AppendUint32(data, uint32(value))
+    }
+
+    return binary.AppendUvarint(data, value)
+  case string:
+    return append(binary.AppendUvarint(data, uint64(len(value))), value...)
+  }
+
+  return append(binary.AppendUvarint(data, uint64(len(value.([]byte)))), value.([]byte)...)
+}
+
+// protoTestMessage joins fields.
+func protoTestMessage(fields ...[]byte) []byte {
+  return bytes.Join(fields, nil)
+}
+
+func TestDecodeProtobuf(t *testing.T) {
+  field := func(name string, number, label, kind uint64, typeName, jsonName string) []byte {
+    return protoTestField(2, protoWireBytes, protoTestMessage(
+      protoTestField(1, protoWireBytes, name),
+      protoTestField(3, protoWireVarint, number),
+      protoTestField(4, protoWireVarint, label),
+      protoTestField(5, protoWireVarint, kind),
+      protoTestField(6, protoWireBytes, typeName),
+      protoTestField(10, protoWireBytes, jsonName),
+    ))
+  }
+
+  order := protoTestMessage(
+    protoTestField(1, protoWireBytes, "Order"),
+    field("id", 1, 1, protoString, "", "id"),
+    field("qty", 2, 1, protoInt32, "", "quantity"),
+    field("deltas", 3, 3, protoSint32, "", "deltas"),
+    field("status", 4, 1, protoEnum, ".shop.Order.Status", "status"),
+    field("stock", 5, 3, protoMessage, ".shop.Order.StockEntry", "stock"),
+    field("item", 6, 1, protoMessage, ".shop.Item", "item"),
+    field("price", 7, 1, protoDouble, "", "price"),
+    protoTestField(3, protoWireBytes, protoTestMessage(
+      protoTestField(1, protoWireBytes, "StockEntry"),
+      field("key", 1, 1, protoString, "", "key"),
+      field("value", 2, 1, protoInt32, "", "value"),
+      protoTestField(7, protoWireBytes, protoTestField(7, protoWireVarint, uint64(1))),
+    )),
+    protoTestField(4, protoWireBytes, protoTestMessage(
+      protoTestField(1, protoWireBytes, "Status"),
+      protoTestField(2, protoWireBytes, protoTestMessage(protoTestField(1, protoWireBytes, "UNKNOWN"), protoTestField(2, protoWireVarint, uint64(0)))),
+      protoTestField(2, protoWireBytes, protoTestMessage(protoTestField(1, protoWireBytes, "SHIPPED"), protoTestField(2, protoWireVarint, uint64(2)))),
+    )),
+  )
+
+  item := protoTestMessage(protoTestField(1, protoWireBytes, "Item"), field("name", 1, 1, protoString, "", "name"))
+  descriptorSet := protoTestField(1, protoWireBytes, protoTestMessage(
+    protoTestField(1, protoWireBytes, "shop.proto"),
+    protoTestField(2, protoWireBytes, "shop"),
+    protoTestField(4, protoWireBytes, order),
+    protoTestField(4, protoWireBytes, item),
+  ))
+
+  body := protoTestMessage(
+    protoTestField(1, protoWireBytes, "A-1"),
+    protoTestField(2, protoWireVarint, uint64(3)),
+    protoTestField(3, protoWireBytes, []byte{1, 4}),
+    protoTestField(4, protoWireVarint, uint64(2)),
+    protoTestField(5, protoWireBytes, protoTestMessage(protoTestField(1, protoWireBytes, "apples"), protoTestField(2, protoWireVarint, uint64(7)))),
+    protoTestField(5, protoWireBytes, protoTestMessage(protoTestField(1, protoWireBytes, "pears"), protoTestField(2, protoWireVarint, uint64(1)))),
+    protoTestField(6, protoWireBytes, protoTestField(1, protoWireBytes, "pen")),
+    protoTestField(7, protoWireFixed64, math.Float64bits(2.5)),
+    protoTestField(9, protoWireVarint, uint64(42)),
+  )
+
+  tests := []struct {
+    descriptorSet []byte
+    message       string
+    input         []byte
+    want          string
+    warnings      []string
+  }{
+    {
+      descriptorSet, "shop.Order", body,
+      `{"id":"A-1","quantity":3,"deltas":[-1,2],"status":"SHIPPED","stock":{"apples":7,"pears":1},"item":{"name":"pen"},"price":2.5,"9":42}`,
+      nil,
+    },
+    {
+      descriptorSet, ".Item", protoTestField(1, protoWireBytes, "pen"),
+      `{"name":"pen"}`,
+      nil,
+    },
+    {
+      nil, "", body,
+      `{"1":"A-1","2":3,"3":"AQQ=","4":2,"5":[{"1":"apples","2":7},{"1":"pears","2":1}],"6":{"1":"pen"},"7":4612811918334230528,"9":42}`,
+      []string{"decoded without a schema, so fields are named by their number: set a descriptor set and a message name in the Settings tab to see their names"},
+    },
+    {
+      descriptorSet, "Cart", []byte("\x0b\x10\x01\x0c"),
+      `{"1":{"2":1}}`,
+      []string{`decoded without a schema: the descriptor set has no message "Cart"`},
+    },
+    {
+      []byte{0xff}, "shop.Order", []byte("\x08\x01"),
+      `{"1":1}`,
+      []string{"decoded without a schema: invalid descriptor set: invalid varint at offset 0"},
+    },
+    {
+      nil, "", []byte("\x0a\x05a"),
+      "00000000  0a 05 61",
+      []string{
+        "decoded without a schema, so fields are named by their number: set a descriptor set and a message name in the Settings tab to see their names",
+        "invalid Protobuf: unexpected end of input at offset 3",
+      },
+    },
+    {
+      descriptorSet, "shop.Order", []byte("\x0e"),
+      "00000000  0e",
+      []string{"invalid Protobuf: invalid wire type 6 at offset 0"},
+    },
+  }
+
+  for _, test := range tests {
+    formatter := newProtobufFormatter(test.descriptorSet, test.message)
+    formatter.json = jsonFormatterImpl{compact: true}
+    buffer := bytes.Buffer{}
+    formatter.format(test.input, &buffer, "  ")
+    if got := buffer.String(); !bytes.HasPrefix([]byte(got), []byte(test.want)) {
+      t.Errorf("format(%q) = %s, want %s", test.input, got, test.want)
+    }
+
+    if got := formatter.check(test.input); !reflect.DeepEqual(test.warnings, got) {
+      t.Errorf("check(%q) = %q, want %q", test.input, got, test.warnings)
+    }
+  }
+}
@@ -3,6 +3,7 @@ package playground
 import (
   "bytes"
   "fmt"
+  "html/template"
   "maps"
   "net/http"
   "slices"
@@ -13,10 +14,17 @@ import (
 
 // responseBuilder is used to construct an HTTP response message with custom start lines, headers, and body content.
 type responseBuilder struct {
-  startLine []byte
-  header    http.Header
-  body      bytes.Buffer
-  errored   bool
+  startLine   []byte
+  header      http.Header
+  meta        http.Header // Playground-* pseudo-headers that describe how the playground handled the response.
+  body        bytes.Buffer
+  errored     bool
+  highlighted bool       // Whether the body is HTML whose tokens are highlighted, rather than text.
+  exchanges   []harEntry // The exchanges, redirects included, that produced the response.
+
+  status   int           // The status code of the response, or zero if none was received.
+  raw      []byte        // The decoded body of the response, before it was formatted.
+  duration time.Duration // The time taken to send the request and read the whole response.
 }
 
 func newResponseBuilder() *responseBuilder {
@@ -44,6 +52,21 @@ func (r *responseBuilder) DefaultHeaders() {
   r.header.Set("Server", "fontseca.dev/playground (v1.0)")
 }
 
+// AddMeta adds a Playground-* pseudo-header, which is written after the actual headers of the
+// HTTP response and survives DefaultHeaders.
+func (r *responseBuilder) AddMeta(key, value string) {
+  if nil == r.meta {
+    r.meta = http.Header{}
+  }
+
+  r.meta.Add(fmt.Sprint("Playground-", key), value)
+}
+
+// Warn records a non-fatal problem found while handling the request as a Playground-Warning pseudo-header.
+func (r *responseBuilder) Warn(warning string) {
+  r.AddMeta("Warning", warning)
+}
+
 // Write appends the provided byte slice to the body of the HTTP response.
 func (r *responseBuilder) Write(p []byte) (n int, err error) {
   if r.errored {
@@ -53,9 +76,22 @@ func (r *responseBuilder) Write(p []byte) (n int, err error) {
   return r.body.Write(p)
 }
 
+// WriteFormatted writes input formatted by formatter to the body of the HTTP response, as HTML whose
+// tokens are highlighted if highlight is true and formatter can tell them apart.
+func (r *responseBuilder) WriteFormatted(formatter bodyFormatter, input []byte, indent string, highlight bool) {
+  tokens, ok := formatter.(tokenFormatter)
+  if !highlight || !ok || r.errored {
+    formatter.format(input, r, indent)
+    return
+  }
+
+  r.highlighted = true
+  tokens.tokens(input, &htmlTokens{output: r}, indent)
+}
+
 // WriteError writes an error message to the HTTP response, discarding any previous written bytes to the body.
 func (r *responseBuilder) WriteError(err error) {
-  r.errored = true
+  r.errored, r.highlighted = true, false
   r.body.Reset()
   r.body.WriteString(err.Error())
 }
@@ -73,28 +109,34 @@ func (r *responseBuilder) build() *bytes.Buffer {
   buffer.Write(r.startLine)
   buffer.WriteRune('\n')
 
-  keys := make([]string, 0, len(r.header))
+  writeHeaders(buffer, r.header)
+  writeHeaders(buffer, r.meta)
+
+  buffer.WriteRune('\n')
 
-  for key := range maps.Keys(r.header) {
+  if r.errored {
+    buffer.WriteString("Playground server failed: ")
+  }
+
+  buffer.Write(r.body.Bytes())
+  return buffer
+}
+
+// writeHeaders writes the header fields to buffer sorted by key.
+func writeHeaders(buffer *bytes.Buffer, header http.Header) {
+  keys := make([]string, 0, len(header))
+
+  for key := range maps.Keys(header) {
     keys = append(keys, key)
   }
 
   sort.Strings(keys)
 
   for key := range slices.Values(keys) {
-    for kk := range slices.Values(r.header.Values(key)) {
+    for kk := range slices.Values(header.Values(key)) {
       buffer.WriteString(fmt.Sprintf("%s: %s\n", key, kk))
     }
   }
-
-  buffer.WriteRune('\n')
-
-  if r.errored {
-    buffer.WriteString("Playground server failed: ")
-  }
-
-  buffer.Write(r.body.Bytes())
-  return buffer
 }
 
 // Bytes returns the HTTP response as a byte slice.
@@ -102,6 +144,21 @@ func (r *responseBuilder) Bytes() []byte {
   return r.build().Bytes()
 }
 
+// HTML returns the HTTP response as HTML text: it is escaped, but for a highlighted body, which is
+// HTML already.
+func (r *responseBuilder) HTML() []byte {
+  message := r.build().Bytes()
+  head := len(message)
+  if r.highlighted && !r.errored {
+    head -= r.body.Len()
+  }
+
+  buffer := &bytes.Buffer{}
+  template.HTMLEscape(buffer, message[:head])
+  buffer.Write(message[head:])
+  return buffer.Bytes()
+}
+
 // String returns the HTTP response as a string.
 func (r *responseBuilder) String() string {
   return r.build().String()
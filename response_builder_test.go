@@ -106,6 +106,24 @@ func TestResponseBuilder_WriteError(t *testing.T) {
   }
 }
 
+func TestResponseBuilder_Warn(t *testing.T) {
+  r := newResponseBuilder()
+  r.Warn("unresolved variable {{token}}")
+  r.DefaultHeaders()
+  r.Warn("unresolved variable {{host}}")
+
+  got := r.meta.Values("Playground-Warning")
+  want := []string{"unresolved variable {{token}}", "unresolved variable {{host}}"}
+
+  if !reflect.DeepEqual(want, got) {
+    t.Errorf("expected: %v, got: %v", want, got)
+  }
+
+  if !strings.Contains(r.String(), "\nPlayground-Warning: unresolved variable {{host}}\n\n") {
+    t.Errorf("warnings are not written after the headers:\n%s", r.String())
+  }
+}
+
 func makeBuilderForTest() (r *responseBuilder, expected string) {
   r = newResponseBuilder()
   h := http.Header{}
@@ -205,3 +223,28 @@ func TestResponseBuilder_String(t *testing.T) {
       "\n---\n%s\n---\n", expected, got)
   }
 }
+
+func TestResponseBuilder_HTML(t *testing.T) {
+  r := newResponseBuilder()
+  r.SetStartLine("HTTP/1.1", "200 OK")
+  r.SetHeaders(http.Header{"X-Name": {"<b>"}})
+  r.WriteFormatted(jsonFormatter, []byte(`{"a": "<i>"}`), "  ", true)
+
+  expected := "HTTP/1.1 200 OK\nX-Name: &lt;b&gt;\n\n" +
+    "<span class=\"tok-punct\">{</span>\n  <span class=\"tok-key\">&#34;a&#34;</span><span class=\"tok-punct\">:</span> " +
+    "<span class=\"tok-string\">&#34;&lt;i&gt;&#34;</span>\n<span class=\"tok-punct\">}</span>"
+  if got := string(r.HTML()); expected != got {
+    t.Errorf("\nexpected:\n---\n%s\n---\ngot:\n---\n%s\n---", expected, got)
+  }
+
+  r.WriteError(errors.New("<oops>"))
+  if got := string(r.HTML()); !strings.HasSuffix(got, "\n\nPlayground server failed: &lt;oops&gt;") {
+    t.Errorf("the error of a highlighted response is not escaped:\n%s", got)
+  }
+
+  r = newResponseBuilder()
+  r.WriteFormatted(jsonFormatter, []byte(`{"a": "<i>"}`), "  ", false)
+  if got := string(r.HTML()); !strings.HasSuffix(got, "\n\n{\n  &#34;a&#34;: &#34;&lt;i&gt;&#34;\n}") {
+    t.Errorf("the body of a response that is not highlighted is not escaped:\n%s", got)
+  }
+}
@@ -0,0 +1,623 @@
+package playground
+
+import (
+  "bytes"
+  "context"
+  "encoding/csv"
+  "encoding/json"
+  "encoding/xml"
+  "errors"
+  "flag"
+  "fmt"
+  "io"
+  "maps"
+  "net/http"
+  "net/url"
+  "os"
+  "slices"
+  "strings"
+  "time"
+)
+
+// runOptions tell how a collection is run.
+type runOptions struct {
+  folder      string                 // The full name of the folder to run, E.g: "Posts / Drafts"; the whole collection if empty.
+  environment *collEnvironment       // The variables that override the ones of the collection.
+  bail        bool                   // Stop at the first request that fails.
+  delay       time.Duration          // The pause between two requests.
+  iterations  int                    // How many times the requests are run; one per data row if zero.
+  data        []map[string]string    // The variables of every iteration, which override the ones of the environment.
+  assertions  map[string][]assertion // The assertions of every request by full name; the ones under "*" apply to all.
+  insecure    bool                   // Skip the verification of TLS certificates.
+}
+
+// A runExecution is the outcome of a request sent by the runner.
+type runExecution struct {
+  Iteration  int               `json:"iteration"`
+  Name       string            `json:"name"` // The full name of the request, E.g: "Posts / Create post".
+  Method     string            `json:"method"`
+  URL        string            `json:"url"`
+  Status     int               `json:"status,omitempty"`
+  Time       float64           `json:"time"`            // The response time in milliseconds.
+  Error      string            `json:"error,omitempty"` // Why no response was received.
+  Warnings   []string          `json:"warnings,omitempty"`
+  Variables  []string          `json:"variables,omitempty"` // The variables set by the extraction rules, as name = value.
+  Assertions []assertionResult `json:"assertions"` // The declared assertions, then the tests of the scripts.
+  Logs       []string          `json:"logs,omitempty"` // What the scripts wrote to the console.
+}
+
+// failed reports whether the request could not be sent or any of its assertions failed.
+func (e *runExecution) failed() bool {
+  return "" != e.Error || slices.ContainsFunc(e.Assertions, func(r assertionResult) bool { return !r.Passed })
+}
+
+// A runReport is the outcome of a collection run.
+type runReport struct {
+  Collection string         `json:"collection"`
+  Started    time.Time      `json:"started"`
+  Time       float64        `json:"time"` // The duration of the run in milliseconds.
+  Iterations int            `json:"iterations"`
+  Bailed     bool           `json:"bailed,omitempty"` // The run stopped at the first failure.
+  Executions []runExecution `json:"executions"`
+  Stats      struct {
+    Requests         int `json:"requests"`
+    FailedRequests   int `json:"failed_requests"`
+    Assertions       int `json:"assertions"`
+    FailedAssertions int `json:"failed_assertions"`
+  } `json:"stats"`
+}
+
+// failed reports whether any request of the run failed.
+func (r *runReport) failed() bool {
+  return r.Stats.FailedRequests > 0
+}
+
+// A runnable is a request of a collection along with its full name, the checks and the extraction
+// rules of its item, and the events of the collection, the folders and the item it belongs to, in
+// that order.
+type runnable struct {
+  name    string
+  request *collRequest
+  checks  []string
+  extract []string
+  events  []collEvent
+}
+
+// runnables lists the requests of item in order, with their auth inherited. If folder is not empty,
+// only the requests under the folder of that full name are listed.
+func runnables(item []collItem, prefix, folder string, events []collEvent) (list []runnable, found bool) {
+  for i := range slices.Values(item) {
+    name := fmt.Sprint(prefix, i.Name)
+    if len(i.Item) > 0 || nil == i.Request { /* folder */
+      if "" != folder && name == folder {
+        sublist, _ := runnables(i.Item, fmt.Sprint(name, " / "), "", slices.Concat(events, i.Event))
+        return sublist, true
+      }
+
+      sublist, subfound := runnables(i.Item, fmt.Sprint(name, " / "), folder, slices.Concat(events, i.Event))
+      if "" != folder && subfound {
+        return sublist, true
+      }
+
+      list = append(list, sublist...)
+    } else if "" == folder {
+      list = append(list, runnable{name: name, request: i.Request, checks: i.Assertions, extract: i.Extract, events: slices.Concat(events, i.Event)})
+    }
+  }
+
+  return list, "" == folder
+}
+
+// run sends the requests of c in order, once per iteration, and evaluates their assertions and the
+// tests of their scripts. The variables that scripts set in the environment and the collection are
+// kept for the rest of the run. The outcome of every request is written to progress as soon as it
+// is known.
+func run(ctx context.Context, c *coll, options runOptions, progress io.Writer) (*runReport, error) {
+  items := cloneItems(c.Item)
+  inheritAuth(c.Auth, items)
+
+  list, found := runnables(items, "", options.folder, c.Event)
+  if !found {
+    return nil, fmt.Errorf("folder %q not found", options.folder)
+  }
+
+  iterations := options.iterations
+  if iterations <= 0 {
+    iterations = max(1, len(options.data))
+  }
+
+  report := &runReport{Collection: c.Info.Name, Started: time.Now(), Iterations: iterations, Executions: []runExecution{}}
+  defer func() { report.Time = milliseconds(report.Started, time.Now()) }()
+
+  scope := newScriptScope(c, options.environment)
+  for iteration := range iterations {
+    if len(options.data) > 0 {
+      scope.data = maps.Clone(options.data[min(iteration, len(options.data)-1)])
+    }
+
+    for n, r := range list {
+      if (iteration > 0 || n > 0) && options.delay > 0 {
+        select {
+        case <-ctx.Done():
+          return report, ctx.Err()
+        case <-time.After(options.delay):
+        }
+      }
+
+      script := &scriptRun{name: r.name, events: r.events, iteration: iteration, scope: scope}
+      execution := runRequest(ctx, r, script, slices.Concat(options.assertions["*"], options.assertions[r.name]), options.insecure)
+      execution.Iteration = iteration + 1
+      report.Executions = append(report.Executions, execution)
+      writeExecution(progress, &execution)
+
+      report.Stats.Requests++
+      report.Stats.Assertions += len(execution.Assertions)
+      for result := range slices.Values(execution.Assertions) {
+        if !result.Passed {
+          report.Stats.FailedAssertions++
+        }
+      }
+
+      if execution.failed() {
+        report.Stats.FailedRequests++
+        if options.bail {
+          report.Bailed = true
+          return report, nil
+        }
+      }
+
+      if nil != ctx.Err() {
+        return report, ctx.Err()
+      }
+    }
+  }
+
+  return report, nil
+}
+
+// runRequest runs the prerequest scripts of r, resolves its variables, sends it through the backend,
+// and then evaluates assertions and runs the test scripts against its response.
+func runRequest(ctx context.Context, r runnable, script *scriptRun, assertions []assertion, insecure bool) (execution runExecution) {
+  execution = runExecution{Name: r.name, Method: r.request.Method, URL: r.request.URL.Raw, Assertions: []assertionResult{}}
+  defer func() { execution.Logs = script.logs }()
+
+  /* The target is resolved before the prerequest scripts, so that they see a URL, and again after them,
+     unless they changed it, so that the variables they set are used.  */
+  target, err := url.Parse(newResolver(script.scope.variables()).resolve(r.request.URL.Raw))
+  if nil != err {
+    target = &url.URL{}
+  }
+
+  in := &request{
+    method:        r.request.Method,
+    target:        target,
+    header:        http.Header{},
+    pathVariables: map[string]string{},
+    insecure:      insecure,
+  }
+
+  for h := range slices.Values(r.request.Header) {
+    if !h.Disabled {
+      in.header.Add(h.Key, h.Value)
+    }
+  }
+
+  for v := range slices.Values(r.request.URL.Variable) {
+    in.pathVariables[v.Key] = v.Value
+  }
+
+  if nil != r.request.Body && "raw" == r.request.Body.Mode {
+    in.body = r.request.Body.Raw
+  }
+
+  script.scope.local = map[string]string{}
+  script.request = in
+  script.exec("prerequest")
+
+  res := newResolver(script.scope.variables())
+  if target == in.target {
+    resolved := res.resolve(r.request.URL.Raw)
+    if in.target, err = url.Parse(resolved); nil != err {
+      execution.Error = fmt.Sprintf("invalid URL %#q", resolved)
+      execution.Assertions = append(execution.Assertions, script.tests...)
+      return execution
+    }
+  }
+
+  header, query := authorize(r.request.Auth, res)
+  if nil != header {
+    in.header.Set(header.Key, header.Value)
+  }
+
+  if nil != query {
+    in.target.RawQuery = strings.TrimPrefix(appendQuery(fmt.Sprint("?", in.target.RawQuery), query.Key, query.Value), "?")
+  }
+
+  if nil != r.request.Body && "urlencoded" == r.request.Body.Mode {
+    form := url.Values{}
+    for p := range slices.Values(r.request.Body.URLEncoded) {
+      form.Add(res.resolve(p.Key), res.resolve(p.Value))
+    }
+
+    in.body = form.Encode()
+    if "" == in.header.Get("Content-Type") {
+      in.header.Set("Content-Type", "application/x-www-form-urlencoded")
+    }
+  } else if nil != r.request.Body && "raw" != r.request.Body.Mode {
+    res.warn(fmt.Sprintf("unsupported body mode %q", r.request.Body.Mode))
+  }
+
+  in.variables = script.scope.variables()
+  response := backend(ctx, in)
+
+  execution.Method = in.method
+  execution.URL = unescapeVariables(in.target.String())
+  execution.Status = response.status
+  execution.Time = float64(response.duration.Microseconds()) / 1000
+  execution.Warnings = res.warnings
+  for warning := range slices.Values(response.meta.Values("Playground-Warning")) {
+    if !slices.Contains(execution.Warnings, warning) {
+      execution.Warnings = append(execution.Warnings, warning)
+    }
+  }
+
+  if response.errored {
+    if 0 == response.status {
+      execution.Error = response.body.String()
+      execution.Assertions = append(execution.Assertions, script.tests...)
+      return execution
+    }
+
+    execution.Warnings = append(execution.Warnings, response.body.String())
+  }
+
+  variables, warnings := extractVariables(r.extract, response, script.scope)
+  execution.Variables, execution.Warnings = variables, append(execution.Warnings, warnings...)
+
+  script.response = response
+  script.exec("test")
+
+  execution.Assertions = append(execution.Assertions, assertAll(assertions, response)...)
+  execution.Assertions = append(execution.Assertions, evaluateChecks(r.checks, response)...)
+  execution.Assertions = append(execution.Assertions, script.tests...)
+  return execution
+}
+
+// writeExecution writes the outcome of a request the way it is shown while a collection runs.
+func writeExecution(w io.Writer, e *runExecution) {
+  fmt.Fprintf(w, "→ %s\n", e.Name)
+
+  if "" != e.Error {
+    fmt.Fprintf(w, "  %s %s [error: %s]\n", e.Method, e.URL, e.Error)
+  } else {
+    fmt.Fprintf(w, "  %s %s [%d, %.0fms]\n", e.Method, e.URL, e.Status, e.Time)
+  }
+
+  for warning := range slices.Values(e.Warnings) {
+    fmt.Fprintf(w, "  ! %s\n", warning)
+  }
+
+  for variable := range slices.Values(e.Variables) {
+    fmt.Fprintf(w, "  = %s\n", variable)
+  }
+
+  for log := range slices.Values(e.Logs) {
+    fmt.Fprintf(w, "  · %s\n", log)
+  }
+
+  for result := range slices.Values(e.Assertions) {
+    if result.Passed {
+      fmt.Fprintf(w, "  ✓ %s\n", result.Assertion)
+    } else {
+      fmt.Fprintf(w, "  ✗ %s: %s\n", result.Assertion, result.Message)
+    }
+  }
+
+  fmt.Fprintln(w)
+}
+
+// writeRunJSON writes report as indented JSON.
+func writeRunJSON(w io.Writer, report *runReport) error {
+  encoder := json.NewEncoder(w)
+  encoder.SetIndent("", "  ")
+  return encoder.Encode(report)
+}
+
+type junitFailure struct {
+  Message string `xml:"message,attr"`
+  Text    string `xml:",chardata"`
+}
+
+type junitTestCase struct {
+  Name      string        `xml:"name,attr"`
+  ClassName string        `xml:"classname,attr"`
+  Time      string        `xml:"time,attr"`
+  Failure   *junitFailure `xml:"failure,omitempty"`
+  Error     *junitFailure `xml:"error,omitempty"`
+}
+
+type junitTestSuite struct {
+  Name      string          `xml:"name,attr"`
+  Tests     int             `xml:"tests,attr"`
+  Failures  int             `xml:"failures,attr"`
+  Errors    int             `xml:"errors,attr"`
+  Time      string          `xml:"time,attr"`
+  TestCases []junitTestCase `xml:"testcase"`
+}
+
+type junitTestSuites struct {
+  XMLName    xml.Name         `xml:"testsuites"`
+  Name       string           `xml:"name,attr"`
+  Tests      int              `xml:"tests,attr"`
+  Failures   int              `xml:"failures,attr"`
+  Errors     int              `xml:"errors,attr"`
+  Time       string           `xml:"time,attr"`
+  TestSuites []junitTestSuite `xml:"testsuite"`
+}
+
+// writeRunJUnit writes report as JUnit XML, with a test suite per request and a test case per
+// assertion. A request without assertions is a single test case, which only fails if the request
+// could not be sent.
+func writeRunJUnit(w io.Writer, report *runReport) error {
+  seconds := func(ms float64) string { return fmt.Sprintf("%.3f", ms/1000) }
+  suites := junitTestSuites{Name: report.Collection, Time: seconds(report.Time)}
+
+  for e := range slices.Values(report.Executions) {
+    suite := junitTestSuite{Name: e.Name, Time: seconds(e.Time)}
+    if report.Iterations > 1 {
+      suite.Name = fmt.Sprintf("%s (iteration %d)", e.Name, e.Iteration)
+    }
+
+    switch {
+    case "" != e.Error:
+      suite.TestCases = append(suite.TestCases, junitTestCase{Name: fmt.Sprint(e.Method, " ", e.URL), Error: &junitFailure{Message: e.Error, Text: e.Error}})
+      suite.Errors++
+    case 0 == len(e.Assertions):
+      suite.TestCases = append(suite.TestCases, junitTestCase{Name: fmt.Sprint(e.Method, " ", e.URL)})
+    }
+
+    for result := range slices.Values(e.Assertions) {
+      testcase := junitTestCase{Name: result.Assertion}
+      if !result.Passed {
+        testcase.Failure = &junitFailure{Message: result.Message, Text: result.Message}
+        suite.Failures++
+      }
+
+      suite.TestCases = append(suite.TestCases, testcase)
+    }
+
+    for n := range suite.TestCases {
+      suite.TestCases[n].ClassName, suite.TestCases[n].Time = suite.Name, seconds(e.Time)
+    }
+
+    suite.Tests = len(suite.TestCases)
+    suites.Tests += suite.Tests
+    suites.Failures += suite.Failures
+    suites.Errors += suite.Errors
+    suites.TestSuites = append(suites.TestSuites, suite)
+  }
+
+  buffer := &bytes.Buffer{}
+  buffer.WriteString(xml.Header)
+  encoder := xml.NewEncoder(buffer)
+  encoder.Indent("", "  ")
+
+  if err := encoder.Encode(suites); nil != err {
+    return err
+  }
+
+  buffer.WriteByte('\n')
+  _, err := w.Write(buffer.Bytes())
+  return err
+}
+
+// parseRunData parses a data file, either a JSON array of objects or a CSV file whose first row
+// holds the names of the variables, into the variables of every iteration.
+func parseRunData(input []byte) (rows []map[string]string, err error) {
+  input = bytes.TrimPrefix(input, []byte("\xef\xbb\xbf"))
+
+  if bytes.HasPrefix(bytes.TrimSpace(input), []byte("[")) {
+    var objects []map[string]any
+    if err := json.Unmarshal(input, &objects); nil != err {
+      return nil, err
+    }
+
+    for object := range slices.Values(objects) {
+      row := make(map[string]string, len(object))
+      for key, value := range object {
+        if s, ok := value.(string); ok {
+          row[key] = s
+        } else {
+          encoded, _ := json.Marshal(value)
+          row[key] = string(encoded)
+        }
+      }
+
+      rows = append(rows, row)
+    }
+
+    return rows, nil
+  }
+
+  records, err := csv.NewReader(bytes.NewReader(input)).ReadAll()
+  if nil != err {
+    return nil, err
+  }
+
+  if 0 == len(records) {
+    return nil, errors.New("the data file has no header row")
+  }
+
+  for record := range slices.Values(records[1:]) {
+    row := make(map[string]string, len(records[0]))
+    for n, key := range records[0] {
+      row[strings.TrimSpace(key)] = record[n]
+    }
+
+    rows = append(rows, row)
+  }
+
+  return rows, nil
+}
+
+// parsePostmanEnvironment parses an environment exported from Postman.
+func parsePostmanEnvironment(input []byte) (*collEnvironment, error) {
+  var exported struct {
+    Name   string `json:"name"`
+    Values []struct {
+      Key     string `json:"key"`
+      Value   any    `json:"value"`
+      Enabled *bool  `json:"enabled"`
+    } `json:"values"`
+  }
+
+  if err := json.Unmarshal(input, &exported); nil != err {
+    return nil, err
+  }
+
+  if nil == exported.Values {
+    return nil, errors.New("not a Postman environment")
+  }
+
+  environment := &collEnvironment{Name: exported.Name}
+  for v := range slices.Values(exported.Values) {
+    variable := collVariable{Key: v.Key, Disabled: nil != v.Enabled && !*v.Enabled}
+    if s, ok := v.Value.(string); ok {
+      variable.Value = s
+    } else if nil != v.Value {
+      encoded, _ := json.Marshal(v.Value)
+      variable.Value = string(encoded)
+    }
+
+    environment.Variable = append(environment.Variable, variable)
+  }
+
+  return environment, nil
+}
+
+// Runner runs a collection from the command line, the way Newman does, and returns the exit code:
+// 0 if every request passed, 1 if any failed and 2 if the run could not start. args are the
+// command-line arguments without the program name.
+func Runner(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+  flags := flag.NewFlagSet("playground-run", flag.ContinueOnError)
+  flags.SetOutput(stderr)
+  flags.Usage = func() {
+    fmt.Fprintln(stderr, "usage: playground-run [flags] collection")
+    flags.PrintDefaults()
+  }
+
+  var (
+    options      runOptions
+    environment  = flags.String("environment", "", "the name of an environment of the collection, or a Postman environment file")
+    dataFile     = flags.String("data", "", "a CSV or JSON file with the variables of every iteration")
+    assertFile   = flags.String("assertions", "", "a JSON or YAML file with the assertions of the requests")
+    reportJSON   = flags.String("report-json", "", "write a JSON report to this file")
+    reportJUnit  = flags.String("report-junit", "", "write a JUnit XML report to this file")
+    setupFailure = func(err error) int { fmt.Fprintln(stderr, "playground-run:", err); return 2 }
+  )
+
+  flags.StringVar(&options.folder, "folder", "", "run only the requests of this folder, E.g: \"Posts / Drafts\"")
+  flags.IntVar(&options.iterations, "iterations", 0, "how many times the requests are run (default: one per data row, or 1)")
+  flags.DurationVar(&options.delay, "delay", 0, "the pause between two requests, E.g: 500ms")
+  flags.BoolVar(&options.bail, "bail", false, "stop at the first request that fails")
+  flags.BoolVar(&options.insecure, "insecure", false, "skip the verification of TLS certificates")
+
+  if err := flags.Parse(args); nil != err {
+    return 2
+  }
+
+  if 1 != flags.NArg() {
+    flags.Usage()
+    return 2
+  }
+
+  collfile, err := os.Open(flags.Arg(0))
+  if nil != err {
+    return setupFailure(err)
+  }
+
+  c, err := importColl(collfile)
+  collfile.Close()
+  if nil != err {
+    return setupFailure(fmt.Errorf("%s: %w", flags.Arg(0), err))
+  }
+
+  if "" != *environment {
+    index := slices.IndexFunc(c.environments, func(e collEnvironment) bool { return *environment == e.Name })
+    if -1 != index {
+      options.environment = &c.environments[index]
+    } else {
+      input, err := os.ReadFile(*environment)
+      if nil != err {
+        return setupFailure(fmt.Errorf("environment %q is neither in the collection nor a file", *environment))
+      }
+
+      if options.environment, err = parsePostmanEnvironment(input); nil != err {
+        return setupFailure(fmt.Errorf("%s: %w", *environment, err))
+      }
+    }
+  }
+
+  if "" != *dataFile {
+    input, err := os.ReadFile(*dataFile)
+    if nil != err {
+      return setupFailure(err)
+    }
+
+    if options.data, err = parseRunData(input); nil != err {
+      return setupFailure(fmt.Errorf("%s: %w", *dataFile, err))
+    }
+  }
+
+  if "" != *assertFile {
+    input, err := os.ReadFile(*assertFile)
+    if nil != err {
+      return setupFailure(err)
+    }
+
+    if options.assertions, err = parseRunAssertions(input); nil != err {
+      return setupFailure(fmt.Errorf("%s: %w", *assertFile, err))
+    }
+  }
+
+  report, err := run(ctx, c, options, stdout)
+  if nil == report {
+    return setupFailure(err)
+  }
+
+  if nil != err {
+    fmt.Fprintln(stderr, "playground-run:", err)
+  }
+
+  fmt.Fprintf(stdout, "%d requests, %d failed; %d assertions, %d failed\n",
+    report.Stats.Requests, report.Stats.FailedRequests, report.Stats.Assertions, report.Stats.FailedAssertions)
+
+  reports := []struct {
+    path  string
+    write func(io.Writer, *runReport) error
+  }{
+    {*reportJSON, writeRunJSON},
+    {*reportJUnit, writeRunJUnit},
+  }
+
+  for r := range slices.Values(reports) {
+    if "" == r.path {
+      continue
+    }
+
+    output := &bytes.Buffer{}
+    if err := r.write(output, report); nil != err {
+      return setupFailure(err)
+    }
+
+    if err := os.WriteFile(r.path, output.Bytes(), 0o644); nil != err {
+      return setupFailure(err)
+    }
+  }
+
+  if nil != err || report.failed() {
+    return 1
+  }
+
+  return 0
+}
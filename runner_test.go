@@ -0,0 +1,403 @@
+package playground
+
+import (
+  "bytes"
+  "context"
+  "encoding/json"
+  "fmt"
+  "github.com/google/go-cmp/cmp"
+  "io"
+  "net/http"
+  "net/http/httptest"
+  "os"
+  "path/filepath"
+  "reflect"
+  "strings"
+  "testing"
+  "time"
+)
+
+// runnerTest is a collection whose host and token come from an environment.
+const runnerTest = `{
+  "info": {"name": "Blog", "schema": "https://schema.getpostman.com/json/collection/v2.1.0/collection.json"},
+  "auth": {"type": "bearer", "bearer": [{"key": "token", "value": "{{token}}"}]},
+  "variable": [{"key": "host", "value": "http://localhost:1"}, {"key": "title", "value": "Untitled"}],
+  "item": [
+    {"name": "Posts", "item": [
+      {"name": "Create post", "request": {
+        "method": "POST",
+        "header": [{"key": "Content-Type", "value": "application/json"}, {"key": "X-Debug", "value": "1", "disabled": true}],
+        "body": {"mode": "raw", "raw": "{\"title\": \"{{title}}\"}"},
+        "url": "{{host}}/posts"
+      }},
+      {"name": "Get post", "request": {
+        "method": "GET",
+        "header": [],
+        "url": {"raw": "{{host}}/posts/:id", "variable": [{"key": "id", "value": "5"}]}
+      }}
+    ]},
+    {"name": "Ping", "request": {"method": "GET", "header": [], "url": "{{host}}/ping"}}
+  ]
+}`
+
+// newRunnerServer serves the requests of runnerTest.
+func newRunnerServer(t *testing.T) *httptest.Server {
+  server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+    if "Bearer s3cr3t" != r.Header.Get("Authorization") || "" != r.Header.Get("X-Debug") {
+      w.WriteHeader(http.StatusUnauthorized)
+      return
+    }
+
+    w.Header().Set("Content-Type", "application/json")
+    switch fmt.Sprint(r.Method, " ", r.URL.Path) {
+    default:
+      w.WriteHeader(http.StatusNotFound)
+    case "POST /posts":
+      var post map[string]any
+      json.NewDecoder(r.Body).Decode(&post)
+      post["id"] = 5
+      w.WriteHeader(http.StatusCreated)
+      json.NewEncoder(w).Encode(post)
+    case "GET /posts/5":
+      fmt.Fprint(w, `{"id": 5, "title": "Hello"}`)
+    case "GET /ping":
+      fmt.Fprint(w, `"pong"`)
+    }
+  }))
+
+  t.Cleanup(server.Close)
+  return server
+}
+
+// timeless zeroes the times of report, which change from run to run.
+func timeless(report *runReport) *runReport {
+  report.Started, report.Time = time.Time{}, 0
+  for n := range report.Executions {
+    report.Executions[n].Time = 0
+  }
+
+  return report
+}
+
+func TestRun(t *testing.T) {
+  server := newRunnerServer(t)
+  c, err := importColl(strings.NewReader(runnerTest))
+  if nil != err {
+    t.Fatalf("unexpected error: %s", err)
+  }
+
+  environment := &collEnvironment{Name: "Local", Variable: []collVariable{{Key: "host", Value: server.URL}, {Key: "token", Value: "s3cr3t"}}}
+  assertions := map[string][]assertion{
+    "*":                   {{subject: "header", name: "Content-Type", operator: "contains", expected: "json"}},
+    "Posts / Create post": {{subject: "status", operator: "==", expected: "201"}, {subject: "json", name: "$.title", operator: "==", expected: `"Hello"`}},
+  }
+
+  var progress bytes.Buffer
+  report, err := run(context.Background(), c, runOptions{
+    folder:      "Posts",
+    environment: environment,
+    data:        []map[string]string{{"title": "Hello"}, {"title": "Bye"}},
+    assertions:  assertions,
+  }, &progress)
+
+  if nil != err {
+    t.Fatalf("unexpected error: %s", err)
+  }
+
+  contentType := assertionResult{Assertion: `header["Content-Type"] contains "json"`, Passed: true}
+  status := assertionResult{Assertion: "status == 201", Passed: true}
+  want := &runReport{
+    Collection: "Blog",
+    Iterations: 2,
+    Executions: []runExecution{
+      {Iteration: 1, Name: "Posts / Create post", Method: "POST", URL: fmt.Sprint(server.URL, "/posts"), Status: 201, Assertions: []assertionResult{
+        contentType, status, {Assertion: `$.title == "Hello"`, Passed: true},
+      }},
+      {Iteration: 1, Name: "Posts / Get post", Method: "GET", URL: fmt.Sprint(server.URL, "/posts/5"), Status: 200, Assertions: []assertionResult{contentType}},
+      {Iteration: 2, Name: "Posts / Create post", Method: "POST", URL: fmt.Sprint(server.URL, "/posts"), Status: 201, Assertions: []assertionResult{
+        contentType, status, {Assertion: `$.title == "Hello"`, Message: `expected $.title to be "Hello", got "Bye"`},
+      }},
+      {Iteration: 2, Name: "Posts / Get post", Method: "GET", URL: fmt.Sprint(server.URL, "/posts/5"), Status: 200, Assertions: []assertionResult{contentType}},
+    },
+  }
+  want.Stats.Requests, want.Stats.FailedRequests, want.Stats.Assertions, want.Stats.FailedAssertions = 4, 1, 8, 1
+
+  if got := timeless(report); !reflect.DeepEqual(want, got) {
+    t.Fatal(cmp.Diff(want, got))
+  }
+
+  if !strings.Contains(progress.String(), "  ✗ $.title == \"Hello\": expected $.title to be \"Hello\", got \"Bye\"\n") {
+    t.Errorf("the progress does not show the failed assertion:\n%s", progress.String())
+  }
+
+  report, err = run(context.Background(), c, runOptions{environment: environment, bail: true, assertions: map[string][]assertion{
+    "Posts / Get post": {{subject: "status", operator: "==", expected: "404"}},
+  }}, io.Discard)
+
+  if nil != err {
+    t.Fatalf("unexpected error: %s", err)
+  }
+
+  if !report.Bailed || 2 != len(report.Executions) {
+    t.Errorf("report.Bailed = %t with %d executions, want true with 2", report.Bailed, len(report.Executions))
+  }
+
+  report, _ = run(context.Background(), c, runOptions{}, io.Discard)
+  if e := report.Executions[2]; "Ping" != e.Name || "" == e.Error || !report.failed() {
+    t.Errorf("Ping = %+v, want an error", e)
+  }
+
+  if _, err := run(context.Background(), c, runOptions{folder: "Comments"}, io.Discard); nil == err || `folder "Comments" not found` != err.Error() {
+    t.Errorf("error = %v, want folder \"Comments\" not found", err)
+  }
+}
+
+func TestRun_checks(t *testing.T) {
+  server := newRunnerServer(t)
+  c, _ := importColl(strings.NewReader(runnerTest))
+  c.Variable = []collVariable{{Key: "host", Value: server.URL}, {Key: "token", Value: "s3cr3t"}}
+  c.Item[0].Item[1].Assertions = []string{"$.id == 5", "$.title contains Bye", "status is 200"}
+
+  report, err := run(context.Background(), c, runOptions{folder: "Posts", assertions: map[string][]assertion{
+    "*": {{subject: "status", operator: "==", expected: "200"}},
+  }}, io.Discard)
+
+  if nil != err {
+    t.Fatalf("unexpected error: %s", err)
+  }
+
+  want := []assertionResult{
+    {Assertion: "status == 200", Passed: true},
+    {Assertion: "$.id == 5", Passed: true},
+    {Assertion: `$.title contains "Bye"`, Message: `expected $.title to contain "Bye", got "Hello"`},
+    {Assertion: "status is 200", Message: `invalid check: missing operator in "status is 200"`},
+  }
+
+  if 2 != len(report.Executions) {
+    t.Fatalf("%d requests were sent, want 2", len(report.Executions))
+  }
+
+  if got := report.Executions[1].Assertions; !reflect.DeepEqual(want, got) {
+    t.Fatal(cmp.Diff(want, got))
+  }
+}
+
+func TestRun_extract(t *testing.T) {
+  server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+    switch fmt.Sprint(r.Method, " ", r.URL.Path) {
+    case "POST /login":
+      http.SetCookie(w, &http.Cookie{Name: "sid", Value: "abc123"})
+      fmt.Fprint(w, `{"data": {"token": "s3cr3t", "id": 7}}`)
+    case "GET /users/7":
+      if "Bearer s3cr3t" != r.Header.Get("Authorization") || "abc123" != r.Header.Get("X-Session") {
+        w.WriteHeader(http.StatusUnauthorized)
+      }
+    }
+  }))
+
+  t.Cleanup(server.Close)
+  c, err := importColl(strings.NewReader(`{
+    "info": {"name": "Users", "schema": "https://schema.getpostman.com/json/collection/v2.1.0/collection.json"},
+    "variable": [{"key": "token", "value": "expired"}],
+    "item": [
+      {"name": "Log in", "extract": ["token = $.data.token", "id = $.data.id", "sid = cookie[sid]", "name = $.data.name"], "request": {"method": "POST", "header": [], "url": "{{host}}/login"}},
+      {"name": "Me", "assertions": ["status == 200"], "request": {
+        "method": "GET",
+        "header": [{"key": "Authorization", "value": "Bearer {{token}}"}, {"key": "X-Session", "value": "{{sid}}"}],
+        "url": "{{host}}/users/{{id}}"
+      }}
+    ]
+  }`))
+
+  if nil != err {
+    t.Fatalf("unexpected error: %s", err)
+  }
+
+  var progress bytes.Buffer
+  environment := &collEnvironment{Variable: []collVariable{{Key: "host", Value: server.URL}}}
+  report, err := run(context.Background(), c, runOptions{environment: environment}, &progress)
+  if nil != err {
+    t.Fatalf("unexpected error: %s", err)
+  }
+
+  login, me := report.Executions[0], report.Executions[1]
+  if want := []string{"token = s3cr3t", "id = 7", "sid = abc123"}; !reflect.DeepEqual(want, login.Variables) {
+    t.Error(cmp.Diff(want, login.Variables))
+  }
+
+  if want := []string{"could not extract {{name}}: $.data.name is missing"}; !reflect.DeepEqual(want, login.Warnings) {
+    t.Error(cmp.Diff(want, login.Warnings))
+  }
+
+  if fmt.Sprint(server.URL, "/users/7") != me.URL || me.failed() {
+    t.Errorf("Me = %+v, want a passed request to /users/7", me)
+  }
+
+  if !strings.Contains(progress.String(), "  = token = s3cr3t\n") {
+    t.Errorf("the progress does not show the extracted variables:\n%s", progress.String())
+  }
+}
+
+func TestRun_delay(t *testing.T) {
+  server := newRunnerServer(t)
+  c, _ := importColl(strings.NewReader(runnerTest))
+  c.Variable = []collVariable{{Key: "host", Value: server.URL}, {Key: "token", Value: "s3cr3t"}}
+
+  started := time.Now()
+  report, err := run(context.Background(), c, runOptions{delay: 50 * time.Millisecond, iterations: 2}, io.Discard)
+  if nil != err {
+    t.Fatalf("unexpected error: %s", err)
+  }
+
+  if 6 != len(report.Executions) || time.Since(started) < 250*time.Millisecond {
+    t.Errorf("%d requests took %s, want 6 requests in 250ms or more", len(report.Executions), time.Since(started))
+  }
+
+  ctx, cancel := context.WithCancel(context.Background())
+  cancel()
+  if report, err := run(ctx, c, runOptions{delay: time.Minute}, io.Discard); nil == err || 1 != len(report.Executions) {
+    t.Errorf("a canceled run sent %d requests with error %v, want 1 and an error", len(report.Executions), err)
+  }
+}
+
+func TestWriteRunJUnit(t *testing.T) {
+  report := &runReport{Collection: "Blog", Time: 1500, Iterations: 1, Executions: []runExecution{
+    {Iteration: 1, Name: "Posts / Create post", Method: "POST", URL: "https://fontseca.dev/posts", Status: 201, Time: 120, Assertions: []assertionResult{
+      {Assertion: "status == 201", Passed: true},
+      {Assertion: "$.id == 10", Message: "expected $.id to be 10, got 11"},
+    }},
+    {Iteration: 1, Name: "Ping", Method: "GET", URL: "https://fontseca.dev/ping", Status: 200, Time: 30, Assertions: []assertionResult{}},
+    {Iteration: 1, Name: "Down", Method: "GET", URL: "https://down.fontseca.dev/", Error: "request timed out", Assertions: []assertionResult{}},
+  }}
+
+  var output bytes.Buffer
+  if err := writeRunJUnit(&output, report); nil != err {
+    t.Fatalf("unexpected error: %s", err)
+  }
+
+  want := `<?xml version="1.0" encoding="UTF-8"?>
+<testsuites name="Blog" tests="4" failures="1" errors="1" time="1.500">
+  <testsuite name="Posts / Create post" tests="2" failures="1" errors="0" time="0.120">
+    <testcase name="status == 201" classname="Posts / Create post" time="0.120"></testcase>
+    <testcase name="$.id == 10" classname="Posts / Create post" time="0.120">
+      <failure message="expected $.id to be 10, got 11">expected $.id to be 10, got 11</failure>
+    </testcase>
+  </testsuite>
+  <testsuite name="Ping" tests="1" failures="0" errors="0" time="0.030">
+    <testcase name="GET https://fontseca.dev/ping" classname="Ping" time="0.030"></testcase>
+  </testsuite>
+  <testsuite name="Down" tests="1" failures="0" errors="1" time="0.000">
+    <testcase name="GET https://down.fontseca.dev/" classname="Down" time="0.000">
+      <error message="request timed out">request timed out</error>
+    </testcase>
+  </testsuite>
+</testsuites>
+`
+
+  if got := output.String(); want != got {
+    t.Fatal(cmp.Diff(want, got))
+  }
+}
+
+func TestParseRunData(t *testing.T) {
+  want := []map[string]string{{"title": "Hello", "id": "1"}, {"title": "Bye, bye", "id": "2"}}
+
+  for input := range strings.SplitSeq("title,id\nHello,1\n\"Bye, bye\",2\n|\xef\xbb\xbf[{\"title\": \"Hello\", \"id\": 1}, {\"title\": \"Bye, bye\", \"id\": 2}]", "|") {
+    got, err := parseRunData([]byte(input))
+    if nil != err {
+      t.Fatalf("parseRunData(%q): unexpected error: %s", input, err)
+    }
+
+    if !reflect.DeepEqual(want, got) {
+      t.Errorf("parseRunData(%q):\n%s", input, cmp.Diff(want, got))
+    }
+  }
+
+  if _, err := parseRunData(nil); nil == err {
+    t.Error("parseRunData(nil) succeeded, want an error")
+  }
+}
+
+func TestRunner(t *testing.T) {
+  server := newRunnerServer(t)
+  dir := t.TempDir()
+
+  files := map[string]string{
+    "blog.json":       runnerTest,
+    "local.json":      fmt.Sprintf(`{"name": "Local", "values": [{"key": "host", "value": %q, "enabled": true}, {"key": "token", "value": "s3cr3t"}]}`, server.URL),
+    "assertions.yaml": "Posts / Create post:\n  status: 201\n",
+    "failing.yaml":    "Ping:\n  status: 204\n",
+  }
+
+  for name, content := range files {
+    os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644)
+  }
+
+  path := func(name string) string { return filepath.Join(dir, name) }
+  tests := []struct {
+    args []string
+    code int
+  }{
+    {[]string{"-environment", path("local.json"), "-assertions", path("assertions.yaml"), "-report-json", path("report.json"), "-report-junit", path("report.xml"), path("blog.json")}, 0},
+    {[]string{"-environment", path("local.json"), "-assertions", path("failing.yaml"), path("blog.json")}, 1},
+    {[]string{"-environment", "Staging", path("blog.json")}, 2},
+    {[]string{"-folder", "Comments", path("blog.json")}, 2},
+    {[]string{path("missing.json")}, 2},
+    {[]string{}, 2},
+  }
+
+  for _, test := range tests {
+    var stdout, stderr bytes.Buffer
+    if code := Runner(context.Background(), test.args, &stdout, &stderr); test.code != code {
+      t.Errorf("Runner(%q) = %d, want %d\n%s%s", test.args, code, test.code, stdout.String(), stderr.String())
+    }
+  }
+
+  var report runReport
+  if data, err := os.ReadFile(path("report.json")); nil != err || nil != json.Unmarshal(data, &report) || 3 != report.Stats.Requests {
+    t.Errorf("the JSON report is missing or wrong: %v %+v", err, report.Stats)
+  }
+
+  if data, err := os.ReadFile(path("report.xml")); nil != err || !bytes.Contains(data, []byte(`<testcase name="status == 201" classname="Posts / Create post"`)) {
+    t.Errorf("the JUnit report is missing or wrong: %v\n%s", err, data)
+  }
+}
+
+func TestRun_scripts(t *testing.T) {
+  server := newRunnerServer(t)
+  c, err := importColl(strings.NewReader(fmt.Sprintf(`{
+    "info": {"name": "Blog", "schema": "https://schema.getpostman.com/json/collection/v2.1.0/collection.json"},
+    "auth": {"type": "bearer", "bearer": [{"key": "token", "value": "{{token}}"}]},
+    "event": [{"listen": "prerequest", "script": {"exec": ["pm.variables.set('token', 's3cr3t');"]}}],
+    "variable": [{"key": "host", "value": %q}],
+    "item": [
+      {"name": "Posts", "event": [{"listen": "test", "script": {"exec": ["pm.test('json', () => pm.response.to.be.json);"]}}], "item": [
+        {"name": "Create post", "event": [
+          {"listen": "prerequest", "script": {"exec": ["pm.request.headers.upsert({key: 'Content-Type', value: 'application/json'});", "console.log('creating');"]}},
+          {"listen": "test", "script": {"exec": ["pm.collectionVariables.set('id', pm.response.json().id);"]}}
+        ], "request": {"method": "POST", "header": [], "body": {"mode": "raw", "raw": "{\"title\": \"Hello\"}"}, "url": "{{host}}/posts"}},
+        {"name": "Get post", "event": [
+          {"listen": "test", "script": {"exec": ["pm.test('title', () => pm.expect(pm.response.json().title).to.equal('Hello'));"]}}
+        ], "request": {"method": "GET", "header": [], "url": {"raw": "{{host}}/posts/:id", "variable": [{"key": "id", "value": "{{id}}"}]}}}
+      ]}
+    ]
+  }`, server.URL)))
+
+  if nil != err {
+    t.Fatalf("unexpected error: %s", err)
+  }
+
+  report, err := run(context.Background(), c, runOptions{}, io.Discard)
+  if nil != err {
+    t.Fatalf("unexpected error: %s", err)
+  }
+
+  want := []runExecution{
+    {Iteration: 1, Name: "Posts / Create post", Method: "POST", URL: fmt.Sprint(server.URL, "/posts"), Status: 201, Assertions: []assertionResult{{Assertion: "json", Passed: true}}, Logs: []string{"creating"}},
+    {Iteration: 1, Name: "Posts / Get post", Method: "GET", URL: fmt.Sprint(server.URL, "/posts/5"), Status: 200, Assertions: []assertionResult{{Assertion: "json", Passed: true}, {Assertion: "title", Passed: true}}},
+  }
+
+  if got := timeless(report).Executions; !reflect.DeepEqual(want, got) {
+    t.Fatal(cmp.Diff(want, got))
+  }
+}
@@ -0,0 +1,545 @@
+package playground
+
+import (
+  "errors"
+  "fmt"
+  "github.com/dop251/goja"
+  "maps"
+  "net/http"
+  "slices"
+  "strings"
+  "time"
+)
+
+const (
+  // maxScriptBytes is the size of the longest script that is run.
+  maxScriptBytes = 256 << 10 // 256 KB
+
+  // maxScriptLogs is the number of console messages kept for a request.
+  maxScriptLogs = 100
+)
+
+var (
+  // scriptTimeout is the wall-clock time a script may run for, waits included; it is replaced in
+  // testing.
+  scriptTimeout = 1 * time.Second
+
+  // scriptMemory is the memory that the process running a script may map on top of what it maps
+  // before the script starts; it is replaced in testing.
+  scriptMemory uint64 = 64 << 20 // 64 MB
+)
+
+var errScriptMemory = errors.New("the script used too much memory")
+
+// A scriptScope holds the variables that scripts read and write, by scope. A variable of a scope
+// shadows the ones of the scopes before it: collection, environment, data and local.
+type scriptScope struct {
+  collection  map[string]string
+  environment map[string]string
+  data        map[string]string // The variables of the current iteration of a run.
+  local       map[string]string // The variables set with pm.variables, which only live during a request.
+}
+
+// newScriptScope returns the scope of the enabled variables of c and environment, which may be nil.
+func newScriptScope(c *coll, environment *collEnvironment) *scriptScope {
+  scope := &scriptScope{collection: map[string]string{}, environment: map[string]string{}, data: map[string]string{}, local: map[string]string{}}
+
+  for v := range slices.Values(c.Variable) {
+    if !v.Disabled {
+      scope.collection[v.Key] = v.Value
+    }
+  }
+
+  if nil != environment {
+    for v := range slices.Values(environment.Variable) {
+      if !v.Disabled {
+        scope.environment[v.Key] = v.Value
+      }
+    }
+  }
+
+  return scope
+}
+
+// clone returns a copy of s that can be changed on its own.
+func (s *scriptScope) clone() *scriptScope {
+  return &scriptScope{
+    collection:  maps.Clone(s.collection),
+    environment: maps.Clone(s.environment),
+    data:        maps.Clone(s.data),
+    local:       maps.Clone(s.local),
+  }
+}
+
+// lookup returns the value of the variable named key in the innermost scope that has it.
+func (s *scriptScope) lookup(key string) (value string, found bool) {
+  for scope := range slices.Values([]map[string]string{s.local, s.data, s.environment, s.collection}) {
+    if value, found = scope[key]; found {
+      return value, true
+    }
+  }
+
+  return "", false
+}
+
+// variables returns the variables that the references of a request are resolved with.
+func (s *scriptScope) variables() map[string]collVariable {
+  variables := make(map[string]collVariable)
+  for scope := range slices.Values([]map[string]string{s.collection, s.environment, s.data, s.local}) {
+    for key, value := range scope {
+      variables[key] = collVariable{Key: key, Value: value}
+    }
+  }
+
+  return variables
+}
+
+// A scriptRun runs the scripts of the events of a request with a subset of the Postman sandbox API:
+// the pm.variables, pm.environment, pm.collectionVariables and pm.iterationData scopes, pm.request,
+// pm.response, pm.test, pm.expect and pm.info.
+type scriptRun struct {
+  name      string           // The full name of the request.
+  events    []collEvent      // The events of the collection, the folders and the request, in that order.
+  iteration int              // The iteration of a run, starting at zero.
+  scope     *scriptScope     // The variables, which scripts may change.
+  request   *request         // The request, which prerequest scripts may change.
+  response  *responseBuilder // The response, in test scripts.
+
+  tests []assertionResult // The results of pm.test, and the errors of the scripts.
+  logs  []string          // What was written with console.log and the like.
+}
+
+// exec runs the enabled scripts that listen to event ("prerequest" or "test"), in order. A script
+// that fails is recorded as a failed test, and the scripts after it still run.
+func (s *scriptRun) exec(event string) {
+  for e := range slices.Values(s.events) {
+    if event != e.Listen || e.Disabled || nil == e.Script || 0 == len(e.Script.Exec) {
+      continue
+    }
+
+    if err := s.execScript(event, strings.Join(e.Script.Exec, "\n")); nil != err {
+      s.tests = append(s.tests, assertionResult{Assertion: fmt.Sprint(event, " script"), Message: err.Error()})
+    }
+  }
+}
+
+// execScript runs a script in a process of its own, which cannot map more than scriptMemory and is
+// killed once the timeout is over, and takes back the variables and the request that it changed.
+func (s *scriptRun) execScript(event, code string) error {
+  if len(code) > maxScriptBytes {
+    return fmt.Errorf("the script is longer than %d KB", maxScriptBytes>>10)
+  }
+
+  outcome, err := runScriptWorker(s.job(event, code))
+  if nil != err {
+    return err
+  }
+
+  s.apply(outcome)
+  if "" != outcome.Error {
+    return errors.New(outcome.Error)
+  }
+
+  return nil
+}
+
+// runScript runs a script in vm within the wall-clock timeout; it is called by the process that
+// runs the script, see execScript.
+func (s *scriptRun) runScript(event, code string) (err error) {
+  vm := goja.New()
+  vm.SetMaxCallStackSize(1024)
+
+  if err := s.bind(vm, event); nil != err {
+    return err
+  }
+
+  timeout := scriptTimeout
+  timer := time.AfterFunc(timeout, func() { vm.Interrupt(fmt.Errorf("the script ran for more than %s", timeout)) })
+  defer timer.Stop()
+
+  _, err = vm.RunString(code)
+
+  var (
+    interrupted *goja.InterruptedError
+    overflow    *goja.StackOverflowError
+    exception   *goja.Exception
+  )
+
+  switch {
+  case errors.As(err, &overflow):
+    return errors.New("the script nested too many calls")
+  case errors.As(err, &interrupted):
+    return fmt.Errorf("%v", interrupted.Value())
+  case errors.As(err, &exception):
+    return errors.New(exception.Value().String())
+  }
+
+  return err
+}
+
+// bind defines the pm and console objects in vm.
+func (s *scriptRun) bind(vm *goja.Runtime, event string) error {
+  pm := vm.NewObject()
+  pm.Set("variables", s.variableScope(vm, s.scope.local, true))
+  pm.Set("environment", s.variableScope(vm, s.scope.environment, false))
+  pm.Set("collectionVariables", s.variableScope(vm, s.scope.collection, false))
+  pm.Set("iterationData", s.variableScope(vm, s.scope.data, false))
+  pm.Set("info", map[string]any{"eventName": event, "requestName": s.name, "iteration": s.iteration})
+  pm.Set("request", s.requestObject(vm))
+
+  if nil != s.response {
+    pm.Set("response", s.responseObject(vm))
+  }
+
+  console := vm.NewObject()
+  for level := range slices.Values([]string{"log", "info", "warn", "error", "debug"}) {
+    console.Set(level, func(call goja.FunctionCall) goja.Value {
+      var words []string
+      for argument := range slices.Values(call.Arguments) {
+        words = append(words, scriptString(vm, argument))
+      }
+
+      if len(s.logs) < maxScriptLogs {
+        s.logs = append(s.logs, strings.Join(words, " "))
+      }
+
+      return goja.Undefined()
+    })
+  }
+
+  vm.Set("pm", pm)
+  vm.Set("console", console)
+
+  prelude, err := vm.RunString(scriptPrelude)
+  if nil != err {
+    return err
+  }
+
+  install, _ := goja.AssertFunction(prelude)
+  record := func(name string, passed bool, message string) {
+    s.tests = append(s.tests, assertionResult{Assertion: name, Passed: passed, Message: message})
+  }
+
+  _, err = install(goja.Undefined(), pm, vm.ToValue(record))
+  return err
+}
+
+// scriptString converts a JavaScript value into the string that a variable holds: strings are kept
+// as they are and other values are written as JSON.
+func scriptString(vm *goja.Runtime, value goja.Value) string {
+  if nil == value || goja.IsUndefined(value) {
+    return ""
+  }
+
+  if _, isObject := value.(*goja.Object); isObject {
+    stringify, _ := goja.AssertFunction(vm.Get("JSON").ToObject(vm).Get("stringify"))
+    if encoded, err := stringify(goja.Undefined(), value); nil == err && !goja.IsUndefined(encoded) {
+      return encoded.String()
+    }
+  }
+
+  return value.String()
+}
+
+// variableScope returns the object of a variable scope, such as pm.environment. The get method of
+// pm.variables reads every scope, but its set method only writes the local one.
+func (s *scriptRun) variableScope(vm *goja.Runtime, scope map[string]string, every bool) *goja.Object {
+  lookup := func(key string) (string, bool) {
+    if every {
+      return s.scope.lookup(key)
+    }
+
+    value, found := scope[key]
+    return value, found
+  }
+
+  object := vm.NewObject()
+  object.Set("get", func(key string) goja.Value {
+    if value, found := lookup(key); found {
+      return vm.ToValue(value)
+    }
+
+    return goja.Undefined()
+  })
+
+  object.Set("has", func(key string) bool { _, found := lookup(key); return found })
+  object.Set("set", func(key string, value goja.Value) { scope[key] = scriptString(vm, value) })
+  object.Set("unset", func(key string) { delete(scope, key) })
+  object.Set("clear", func() { clear(scope) })
+  object.Set("toObject", func() map[string]any {
+    values := make(map[string]any, len(scope))
+    for key, value := range scope {
+      values[key] = value
+    }
+
+    return values
+  })
+
+  object.Set("replaceIn", func(template string) string {
+    return newResolver(s.scope.variables()).resolve(template)
+  })
+
+  return object
+}
+
+// headerList returns the object of the headers of a request or a response, with the methods of a
+// Postman HeaderList. The methods that change it are only defined if writable.
+func headerList(vm *goja.Runtime, header func() http.Header, writable bool) *goja.Object {
+  pair := func(value goja.Value) (key, v string) {
+    object := value.ToObject(vm)
+    return object.Get("key").String(), scriptString(vm, object.Get("value"))
+  }
+
+  object := vm.NewObject()
+  object.Set("get", func(name string) goja.Value {
+    if values := header().Values(name); len(values) > 0 {
+      return vm.ToValue(strings.Join(values, ", "))
+    }
+
+    return goja.Undefined()
+  })
+
+  object.Set("has", func(name string) bool { return len(header().Values(name)) > 0 })
+  object.Set("toObject", func() map[string]any {
+    values := make(map[string]any, len(header()))
+    for key := range maps.Keys(header()) {
+      values[strings.ToLower(key)] = header().Get(key)
+    }
+
+    return values
+  })
+
+  if writable {
+    object.Set("add", func(value goja.Value) { key, v := pair(value); header().Add(key, v) })
+    object.Set("upsert", func(value goja.Value) { key, v := pair(value); header().Set(key, v) })
+    object.Set("remove", func(name string) { header().Del(name) })
+  }
+
+  return object
+}
+
+// requestObject returns pm.request, whose URL, method, headers and raw body can be changed.
+func (s *scriptRun) requestObject(vm *goja.Runtime) *goja.Object {
+  object := vm.NewObject()
+
+  object.DefineAccessorProperty("url",
+    vm.ToValue(func() string { return s.request.rawURL() }),
+    vm.ToValue(func(raw string) {
+      target, rawTarget, err := parseTarget(raw)
+      if nil != err {
+        panic(vm.NewTypeError(fmt.Sprintf("invalid URL %q", raw)))
+      }
+
+      s.request.target, s.request.rawTarget = target, rawTarget
+    }),
+    goja.FLAG_FALSE, goja.FLAG_TRUE)
+
+  object.DefineAccessorProperty("method",
+    vm.ToValue(func() string { return s.request.method }),
+    vm.ToValue(func(method string) { s.request.method = strings.ToUpper(method) }),
+    goja.FLAG_FALSE, goja.FLAG_TRUE)
+
+  body := vm.NewObject()
+  body.Set("mode", "raw")
+  body.DefineAccessorProperty("raw",
+    vm.ToValue(func() string { return s.request.body }),
+    vm.ToValue(func(raw string) { s.request.body = raw }),
+    goja.FLAG_FALSE, goja.FLAG_TRUE)
+
+  object.Set("headers", headerList(vm, func() http.Header { return s.request.header }, nil == s.response))
+  object.Set("body", body)
+  return object
+}
+
+// responseObject returns pm.response.
+func (s *scriptRun) responseObject(vm *goja.Runtime) *goja.Object {
+  status := http.StatusText(s.response.status)
+  if _, text, found := strings.Cut(string(s.response.startLine), fmt.Sprint(" ", s.response.status, " ")); found {
+    status = text
+  }
+
+  object := vm.NewObject()
+  object.Set("code", s.response.status)
+  object.Set("status", status)
+  object.Set("responseTime", s.response.duration.Milliseconds())
+  object.Set("responseSize", len(s.response.raw))
+  object.Set("headers", headerList(vm, func() http.Header { return s.response.header }, false))
+  object.Set("text", func() string { return string(s.response.raw) })
+  return object
+}
+
+// scriptPrelude completes the pm object with the parts that are easier to write in JavaScript:
+// pm.test, a subset of the Chai assertions of pm.expect, pm.response.json and pm.response.to.
+const scriptPrelude = `(function (pm, record) {
+  function show(value) {
+    if ("string" === typeof value) return JSON.stringify(value);
+    if (undefined === value || "function" === typeof value) return String(value);
+    try { return JSON.stringify(value); } catch (e) { return String(value); }
+  }
+
+  function typeOf(value) {
+    if (null === value) return "null";
+    if (Array.isArray(value)) return "array";
+    return typeof value;
+  }
+
+  function deepEqual(a, b) {
+    if (a === b) return true;
+    if ("object" !== typeof a || "object" !== typeof b || null === a || null === b) return a !== a && b !== b;
+    if (Array.isArray(a) !== Array.isArray(b)) return false;
+    const keys = Object.keys(a);
+    if (keys.length !== Object.keys(b).length) return false;
+    return keys.every(key => Object.prototype.hasOwnProperty.call(b, key) && deepEqual(a[key], b[key]));
+  }
+
+  function AssertionError(message) {
+    this.name = "AssertionError";
+    this.message = message;
+  }
+
+  AssertionError.prototype = Object.create(Error.prototype);
+
+  function Assertion(value, negate, deep, message) {
+    this.value = value;
+    this.negate = !!negate;
+    this.isDeep = !!deep;
+    this.message = message;
+  }
+
+  Assertion.prototype.assert = function (ok, expectation) {
+    if (this.negate === !!ok) {
+      const failure = "expected " + show(this.value) + (this.negate ? " not " : " ") + expectation;
+      throw new AssertionError(undefined === this.message ? failure : this.message + ": " + failure);
+    }
+
+    return this;
+  };
+
+  Assertion.prototype.derive = function (value) {
+    return new Assertion(value, this.negate, this.isDeep, this.message);
+  };
+
+  for (const word of ["to", "be", "been", "is", "that", "which", "and", "has", "have", "with", "at", "of", "same", "does"]) {
+    Object.defineProperty(Assertion.prototype, word, {get: function () { return this; }});
+  }
+
+  Object.defineProperty(Assertion.prototype, "not", {get: function () { return new Assertion(this.value, !this.negate, this.isDeep, this.message); }});
+  Object.defineProperty(Assertion.prototype, "deep", {get: function () { return new Assertion(this.value, this.negate, true, this.message); }});
+
+  const isResponse = value => null !== value && "object" === typeof value && value === pm.response;
+  const getters = {
+    ok: function () {
+      return isResponse(this.value) ? this.assert(this.value.code >= 200 && this.value.code < 300, "to be a success") : this.assert(this.value, "to be truthy");
+    },
+    success: function () { return this.assert(this.value.code >= 200 && this.value.code < 300, "to be a success"); },
+    true: function () { return this.assert(true === this.value, "to be true"); },
+    false: function () { return this.assert(false === this.value, "to be false"); },
+    null: function () { return this.assert(null === this.value, "to be null"); },
+    undefined: function () { return this.assert(undefined === this.value, "to be undefined"); },
+    NaN: function () { return this.assert(this.value !== this.value, "to be NaN"); },
+    exist: function () { return this.assert(null !== this.value && undefined !== this.value, "to exist"); },
+    empty: function () {
+      const value = this.value;
+      const size = "string" === typeof value || Array.isArray(value) ? value.length : Object.keys(value ?? {}).length;
+      return this.assert(0 === size, "to be empty");
+    },
+    json: function () {
+      let ok = true;
+      try { JSON.parse(this.value.text()); } catch (e) { ok = false; }
+      return this.assert(ok, "to have a JSON body");
+    },
+  };
+
+  for (const name of Object.keys(getters)) {
+    Object.defineProperty(Assertion.prototype, name, {get: getters[name]});
+  }
+
+  const methods = {
+    equal: function (expected) {
+      return this.assert(this.isDeep ? deepEqual(this.value, expected) : this.value === expected, "to equal " + show(expected));
+    },
+    eql: function (expected) { return this.assert(deepEqual(this.value, expected), "to deeply equal " + show(expected)); },
+    above: function (n) { return this.assert(this.value > n, "to be above " + show(n)); },
+    below: function (n) { return this.assert(this.value < n, "to be below " + show(n)); },
+    least: function (n) { return this.assert(this.value >= n, "to be at least " + show(n)); },
+    most: function (n) { return this.assert(this.value <= n, "to be at most " + show(n)); },
+    within: function (low, high) { return this.assert(this.value >= low && this.value <= high, "to be within " + low + ".." + high); },
+    a: function (type) { return this.assert(typeOf(this.value) === type.toLowerCase(), "to be a " + type); },
+    include: function (expected) {
+      const value = this.value;
+      let ok;
+      if ("string" === typeof value) ok = value.includes(expected);
+      else if (Array.isArray(value)) ok = value.some(item => this.isDeep ? deepEqual(item, expected) : item === expected);
+      else if (null !== value && "object" === typeof value) ok = Object.keys(expected).every(key => deepEqual(value[key], expected[key]));
+      return this.assert(ok, "to include " + show(expected));
+    },
+    property: function (name, expected) {
+      const has = null !== this.value && undefined !== this.value && name in Object(this.value);
+      if (arguments.length < 2) return this.assert(has, "to have property " + show(name));
+      return this.assert(has && (this.isDeep ? deepEqual(this.value[name], expected) : this.value[name] === expected), "to have property " + show(name) + " of " + show(expected));
+    },
+    lengthOf: function (n) { return this.assert(null != this.value && this.value.length === n, "to have a length of " + n); },
+    match: function (pattern) { return this.assert(pattern.test(this.value), "to match " + pattern); },
+    oneOf: function (list) { return this.assert(list.some(item => deepEqual(item, this.value)), "to be one of " + show(list)); },
+    keys: function () {
+      const expected = Array.isArray(arguments[0]) ? arguments[0] : Array.prototype.slice.call(arguments);
+      return this.assert(expected.every(key => Object.prototype.hasOwnProperty.call(this.value ?? {}, key)), "to have keys " + show(expected));
+    },
+    status: function (expected) {
+      const response = this.value;
+      if ("number" === typeof expected) {
+        return this.derive(response.code).assert(response.code === expected, "to be status " + expected);
+      }
+
+      return this.derive(response.status).assert(response.status === expected, "to be status " + show(expected));
+    },
+    header: function (name, expected) {
+      const value = this.value.headers.get(name);
+      if (arguments.length < 2) return this.assert(undefined !== value, "to have header " + show(name));
+      return this.assert(value === expected, "to have header " + show(name) + " of " + show(expected));
+    },
+    body: function (expected) {
+      return this.derive(this.value.text()).assert(this.value.text() === expected, "to equal " + show(expected));
+    },
+    jsonBody: function (path, expected) {
+      let body;
+      try { body = JSON.parse(this.value.text()); } catch (e) { return this.assert(false, "to have a JSON body"); }
+      if (arguments.length < 1) return this.assert(true, "to have a JSON body");
+      let value = body;
+      for (const key of String(path).split(".")) value = null === value || undefined === value ? undefined : value[key];
+      if (arguments.length < 2) return this.derive(body).assert(undefined !== value, "to have JSON path " + show(path));
+      return this.derive(value).assert(deepEqual(value, expected), "to equal " + show(expected));
+    },
+  };
+
+  const aliases = {
+    equal: ["equals", "eq"], eql: ["eqls"], above: ["gt", "greaterThan"], below: ["lt", "lessThan"],
+    least: ["gte"], most: ["lte"], a: ["an"], include: ["includes", "contain", "contains"], keys: ["key"],
+  };
+
+  for (const name of Object.keys(methods)) {
+    for (const alias of [name].concat(aliases[name] ?? [])) {
+      Assertion.prototype[alias] = methods[name];
+    }
+  }
+
+  pm.expect = function (value, message) {
+    return new Assertion(value, false, false, message);
+  };
+
+  pm.test = function (name, fn) {
+    try {
+      fn();
+      record(String(name), true, "");
+    } catch (e) {
+      record(String(name), false, e && undefined !== e.message ? String(e.message) : String(e));
+    }
+
+    return pm;
+  };
+
+  if (pm.response) {
+    pm.response.json = function () { return JSON.parse(this.text()); };
+    Object.defineProperty(pm.response, "to", {get: function () { return new Assertion(pm.response); }});
+  }
+})`
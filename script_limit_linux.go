@@ -0,0 +1,38 @@
+package playground
+
+import (
+  "bytes"
+  "os"
+  "runtime/debug"
+  "runtime/metrics"
+  "strconv"
+  "syscall"
+)
+
+// limitScriptMemory keeps the process from mapping more than memory bytes of data on top of what it
+// has mapped so far: the garbage collector runs harder as the heap gets close to the limit, and the
+// kernel refuses the mappings past it, unless the race detector, whose shadow memory it would count,
+// is built in.
+func limitScriptMemory(memory uint64) error {
+  status, err := os.ReadFile("/proc/self/status")
+  if nil != err {
+    return err
+  }
+
+  _, line, _ := bytes.Cut(status, []byte("\nVmData:"))
+  line, _, _ = bytes.Cut(line, []byte("kB"))
+  mapped, err := strconv.ParseUint(string(bytes.TrimSpace(line)), 10, 64)
+  if nil != err {
+    return err
+  }
+
+  sample := []metrics.Sample{{Name: "/memory/classes/total:bytes"}}
+  metrics.Read(sample)
+  debug.SetMemoryLimit(int64(sample[0].Value.Uint64() + memory))
+  if raceDetector {
+    return nil
+  }
+
+  limit := mapped<<10 + memory
+  return syscall.Setrlimit(syscall.RLIMIT_DATA, &syscall.Rlimit{Cur: limit, Max: limit})
+}
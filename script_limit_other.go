@@ -0,0 +1,11 @@
+//go:build !linux
+
+package playground
+
+import "errors"
+
+// limitScriptMemory fails: the memory of a process can only be bounded on Linux, and scripts are not
+// run without a bound.
+func limitScriptMemory(memory uint64) error {
+  return errors.New("scripts can only be run on Linux, where their memory can be limited")
+}
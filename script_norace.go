@@ -0,0 +1,6 @@
+//go:build !race
+
+package playground
+
+// raceDetector tells whether the race detector is built in.
+const raceDetector = false
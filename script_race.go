@@ -0,0 +1,7 @@
+//go:build race
+
+package playground
+
+// raceDetector tells whether the race detector is built in. It maps shadow memory as the program
+// runs, so the memory of the processes that run scripts is not bounded by the kernel.
+const raceDetector = true
@@ -0,0 +1,212 @@
+package playground
+
+import (
+  "fmt"
+  "github.com/google/go-cmp/cmp"
+  "net/http"
+  "net/url"
+  "reflect"
+  "strings"
+  "sync"
+  "testing"
+  "time"
+)
+
+// scriptEvents returns the events of the given scripts, which listen to event.
+func scriptEvents(event string, scripts ...string) (events []collEvent) {
+  for _, script := range scripts {
+    events = append(events, collEvent{Listen: event, Script: &collScript{Exec: strings.Split(script, "\n")}})
+  }
+
+  return events
+}
+
+func TestScriptRun_prerequest(t *testing.T) {
+  target, _ := url.Parse("https://fontseca.dev/posts?page=1")
+  in := &request{method: "GET", target: target, header: http.Header{"Accept": {"*/*"}}}
+
+  c := &coll{Variable: []collVariable{{Key: "host", Value: "fontseca.dev"}, {Key: "old", Value: "1", Disabled: true}}}
+  script := &scriptRun{
+    name:    "Posts / List posts",
+    scope:   newScriptScope(c, &collEnvironment{Variable: []collVariable{{Key: "host", Value: "api.fontseca.dev"}}}),
+    request: in,
+    events: append(scriptEvents("prerequest", `
+      pm.environment.set("token", "s3cr3t");
+      pm.collectionVariables.set("retries", 3);
+      pm.variables.set("trace", {id: 7});
+      pm.request.headers.upsert({key: "Accept", value: "application/json"});
+      pm.request.headers.add({key: "X-Host", value: pm.variables.get("host")});
+      pm.request.headers.remove("X-Missing");
+      pm.request.method = "post";
+      pm.request.body.raw = pm.variables.replaceIn("{{host}}/{{token}}");
+      pm.request.url = pm.request.url.toString().replace("page=1", "page=2");
+      console.log(pm.info.eventName, pm.info.requestName, pm.collectionVariables.has("old"));
+    `), scriptEvents("test", `pm.environment.set("token", "nope");`)...),
+  }
+
+  script.exec("prerequest")
+
+  if 0 != len(script.tests) {
+    t.Fatalf("unexpected failures: %+v", script.tests)
+  }
+
+  want := &request{
+    method: "POST",
+    target: &url.URL{Scheme: "https", Host: "fontseca.dev", Path: "/posts", RawQuery: "page=2"},
+    header: http.Header{"Accept": {"application/json"}, "X-Host": {"api.fontseca.dev"}},
+    body:   "api.fontseca.dev/s3cr3t",
+  }
+
+  if !reflect.DeepEqual(want, in) {
+    t.Errorf("request:\n%s", cmp.Diff(want, in, cmp.AllowUnexported(request{})))
+  }
+
+  variables := map[string]string{"host": "api.fontseca.dev", "token": "s3cr3t", "retries": "3", "trace": `{"id":7}`}
+  for key, value := range variables {
+    if got := script.scope.variables()[key].Value; value != got {
+      t.Errorf("%s = %q, want %q", key, got, value)
+    }
+  }
+
+  if want := []string{"prerequest Posts / List posts false"}; !reflect.DeepEqual(want, script.logs) {
+    t.Errorf("logs = %q, want %q", script.logs, want)
+  }
+}
+
+func TestScriptRun_test(t *testing.T) {
+  response := &responseBuilder{
+    startLine: []byte("HTTP/1.1 201 Created"),
+    status:    201,
+    header:    http.Header{"Content-Type": {"application/json"}},
+    raw:       []byte(`{"id": 5, "title": "Hello", "tags": ["go", "http"], "author": {"name": "Jane"}}`),
+    duration:  120 * time.Millisecond,
+  }
+
+  script := &scriptRun{scope: newScriptScope(&coll{}, nil), request: &request{target: &url.URL{}, header: http.Header{}}, response: response}
+  script.events = scriptEvents("test", `
+pm.test("status", function () {
+  pm.response.to.have.status(201);
+  pm.response.to.have.status("Created");
+  pm.response.to.be.ok;
+  pm.response.to.have.header("Content-Type");
+  pm.response.to.have.jsonBody("author.name", "Jane");
+  pm.response.to.not.have.status(200);
+});
+
+pm.test("body", function () {
+  const body = pm.response.json();
+  pm.expect(body.id).to.equal(5).and.to.be.a("number").and.above(4);
+  pm.expect(body.tags).to.include("go").and.have.lengthOf(2);
+  pm.expect(body).to.have.property("title", "Hello");
+  pm.expect(body.author).to.eql({name: "Jane"});
+  pm.expect(body).to.deep.include({author: {name: "Jane"}});
+  pm.expect(body).to.have.keys("id", "title");
+  pm.expect(body.missing).to.not.exist;
+  pm.expect(pm.response.responseTime).to.be.below(500);
+  pm.environment.set("id", body.id);
+});
+
+pm.test("wrong status", () => pm.response.to.have.status(200));
+pm.test("wrong title", () => pm.expect(pm.response.json().title, "the title").to.equal("Bye"));
+pm.test("negated", () => pm.expect([1, 2]).to.not.include(2));
+pm.test("thrown", () => { throw "oops"; });
+`, `undefinedFunction();`, `pm.test("after a failed script", () => {});`, `while (true) {}`)
+
+  previous := scriptTimeout
+  scriptTimeout = 50 * time.Millisecond
+  if raceDetector { /* The scripts run many times slower.  */
+    scriptTimeout = 1 * time.Second
+  }
+  defer func() { scriptTimeout = previous }()
+
+  script.exec("test")
+
+  want := []assertionResult{
+    {Assertion: "status", Passed: true},
+    {Assertion: "body", Passed: true},
+    {Assertion: "wrong status", Message: "expected 201 to be status 200"},
+    {Assertion: "wrong title", Message: `the title: expected "Hello" to equal "Bye"`},
+    {Assertion: "negated", Message: "expected [1,2] not to include 2"},
+    {Assertion: "thrown", Message: "oops"},
+    {Assertion: "test script", Message: "ReferenceError: undefinedFunction is not defined"},
+    {Assertion: "after a failed script", Passed: true},
+    {Assertion: "test script", Message: fmt.Sprint("the script ran for more than ", scriptTimeout)},
+  }
+
+  if !reflect.DeepEqual(want, script.tests) {
+    t.Error(cmp.Diff(want, script.tests))
+  }
+
+  if "5" != script.scope.environment["id"] {
+    t.Errorf("id = %q, want \"5\"", script.scope.environment["id"])
+  }
+}
+
+func TestScriptRun_limits(t *testing.T) {
+  previous := scriptMemory
+  scriptMemory = 32 << 20
+  defer func() { scriptMemory = previous }()
+
+  tests := []struct {
+    name, script, want string
+  }{
+    {"memory", "const chunks = []; while (true) chunks.push('x'.repeat(1 << 16) + chunks.length);", "the script used too much memory"},
+    {"one allocation", "'x'.repeat(1 << 30);", "the script used too much memory"},
+    {"array", "new Array(1e9).fill(0);", "the script used too much memory"},
+    {"caught", "try { 'x'.repeat(1 << 30); } catch (e) {} pm.test('after', () => {});", "the script used too much memory"},
+    {"concatenation", `let s = "x"; for (;;) s += s;`, "the script used too much memory"},
+    {"template", "let s = 'x'; for (;;) s = `${s}${s}`;", "the script used too much memory"},
+    {"index", "const a = []; for (;;) a[a.length] = {n: a.length};", "the script used too much memory"},
+    {"recursion", "(function f() { f(); })();", "the script nested too many calls"},
+    {"syntax", "pm.test(", "SyntaxError"},
+    {"size", strings.Repeat(" ", maxScriptBytes+1), "the script is longer than 256 KB"},
+  }
+
+  for _, test := range tests {
+    if raceDetector && "the script used too much memory" == test.want { /* The kernel does not bound it.  */
+      continue
+    }
+
+    script := &scriptRun{scope: newScriptScope(&coll{}, nil), request: &request{target: &url.URL{}, header: http.Header{}}, events: scriptEvents("prerequest", test.script)}
+    script.exec("prerequest")
+
+    if 1 != len(script.tests) || !strings.HasPrefix(script.tests[0].Message, test.want) {
+      t.Errorf("%s: tests = %+v, want a failure starting with %q", test.name, script.tests, test.want)
+    }
+  }
+}
+
+func TestScriptRun_concurrentLimits(t *testing.T) {
+  if raceDetector {
+    t.Skip("the memory of scripts is not bounded by the kernel under the race detector")
+  }
+
+  previous := scriptMemory
+  scriptMemory = 32 << 20
+  defer func() { scriptMemory = previous }()
+
+  scripts := []string{
+    "const chunks = []; while (true) chunks.push('x'.repeat(1 << 16) + chunks.length);",
+    "const end = Date.now() + 200; let n = 0; while (Date.now() < end) n++; pm.test('quiet', () => {});",
+  }
+
+  runs := make([]*scriptRun, len(scripts))
+  var wg sync.WaitGroup
+  for n, script := range scripts {
+    runs[n] = &scriptRun{scope: newScriptScope(&coll{}, nil), request: &request{target: &url.URL{}, header: http.Header{}}, events: scriptEvents("prerequest", script)}
+    wg.Go(func() { runs[n].exec("prerequest") })
+  }
+
+  wg.Wait()
+
+  want := [][]assertionResult{
+    {{Assertion: "prerequest script", Message: "the script used too much memory"}},
+    {{Assertion: "quiet", Passed: true}},
+  }
+
+  for n := range runs {
+    if !reflect.DeepEqual(want[n], runs[n].tests) {
+      t.Error(cmp.Diff(want[n], runs[n].tests))
+    }
+  }
+}
@@ -0,0 +1,258 @@
+package playground
+
+import (
+  "bytes"
+  "context"
+  "encoding/json"
+  "fmt"
+  "io"
+  "maps"
+  "net/http"
+  "os"
+  "os/exec"
+  "slices"
+  "strings"
+  "time"
+)
+
+// scriptWorkerEnv is set in the environment of the processes that run scripts. goja cannot tell how
+// much a runtime allocates, so every script runs in a process of its own, started from the executable
+// of the playground, whose memory the kernel bounds: the process reads a scriptJob from its standard
+// input and writes a scriptOutcome to its standard output.
+const scriptWorkerEnv = "PLAYGROUND_SCRIPT_WORKER"
+
+// scriptWorkerGrace is how long a process running a script has to write its outcome once the
+// timeout is over, before it is killed.
+const scriptWorkerGrace = 1 * time.Second
+
+// outOfScriptMemory tells whether message, the first line that a process running a script wrote to
+// its standard error, means that it died because the kernel refused to map more memory: the runtime
+// throws a fatal error about memory, or faults when it is refused the memory of its own structures.
+func outOfScriptMemory(message string) bool {
+  return strings.HasPrefix(message, "fatal error:") && strings.Contains(message, "memory") ||
+    "SIGSEGV: segmentation violation" == message
+}
+
+func init() {
+  if "1" == os.Getenv(scriptWorkerEnv) {
+    os.Exit(serveScriptJob(os.Stdin, os.Stdout))
+  }
+}
+
+// scriptRequest is the part of a request that scripts can read and change.
+type scriptRequest struct {
+  Method string      `json:"method"`
+  Target string      `json:"target"`
+  Header http.Header `json:"header"`
+  Body   string      `json:"body"`
+}
+
+// scriptResponse is the part of a response that test scripts can read.
+type scriptResponse struct {
+  Status    int           `json:"status"`
+  StartLine string        `json:"startLine"`
+  Header    http.Header   `json:"header"`
+  Body      []byte        `json:"body"`
+  Duration  time.Duration `json:"duration"`
+}
+
+// scriptVariables holds the variables of a scriptScope.
+type scriptVariables struct {
+  Collection  map[string]string `json:"collection"`
+  Environment map[string]string `json:"environment"`
+  Data        map[string]string `json:"data"`
+  Local       map[string]string `json:"local"`
+}
+
+// A scriptJob is a script that a process is asked to run, along with what it can read and change.
+type scriptJob struct {
+  Event     string          `json:"event"`
+  Code      string          `json:"code"`
+  Name      string          `json:"name"`
+  Iteration int             `json:"iteration"`
+  Timeout   time.Duration   `json:"timeout"`
+  Memory    uint64          `json:"memory"`
+  Variables scriptVariables `json:"variables"`
+  Request   *scriptRequest  `json:"request,omitempty"`
+  Response  *scriptResponse `json:"response,omitempty"`
+}
+
+// A scriptOutcome is what a script left once it ran: its variables, its request, its tests and its
+// logs, and the error it failed with, if any.
+type scriptOutcome struct {
+  Variables scriptVariables   `json:"variables"`
+  Request   *scriptRequest    `json:"request,omitempty"`
+  Tests     []assertionResult `json:"tests"`
+  Logs      []string          `json:"logs"`
+  Error     string            `json:"error,omitempty"`
+}
+
+// job returns the job of running code on event with the variables, the request and the response of s.
+func (s *scriptRun) job(event, code string) scriptJob {
+  job := scriptJob{
+    Event:     event,
+    Code:      code,
+    Name:      s.name,
+    Iteration: s.iteration,
+    Timeout:   scriptTimeout,
+    Memory:    scriptMemory,
+    Variables: scriptVariables{s.scope.collection, s.scope.environment, s.scope.data, s.scope.local},
+  }
+
+  if nil != s.request {
+    job.Request = &scriptRequest{Method: s.request.method, Target: s.request.rawURL(), Header: s.request.header, Body: s.request.body}
+  }
+
+  if nil != s.response {
+    job.Response = &scriptResponse{
+      Status:    s.response.status,
+      StartLine: string(s.response.startLine),
+      Header:    s.response.header,
+      Body:      s.response.raw,
+      Duration:  s.response.duration,
+    }
+  }
+
+  return job
+}
+
+// apply takes the variables, the request, the tests and the logs of outcome into s. The maps of the
+// scopes are changed in place, since they may be shared.
+func (s *scriptRun) apply(outcome *scriptOutcome) {
+  for scope, changed := range map[*map[string]string]map[string]string{
+    &s.scope.collection:  outcome.Variables.Collection,
+    &s.scope.environment: outcome.Variables.Environment,
+    &s.scope.data:        outcome.Variables.Data,
+    &s.scope.local:       outcome.Variables.Local,
+  } {
+    clear(*scope)
+    maps.Copy(*scope, changed)
+  }
+
+  if nil != s.request && nil != outcome.Request {
+    s.request.method, s.request.header, s.request.body = outcome.Request.Method, outcome.Request.Header, outcome.Request.Body
+    if outcome.Request.Target != s.request.rawURL() {
+      s.request.target, s.request.rawTarget, _ = parseTarget(outcome.Request.Target) /* The script could only set a valid URL.  */
+    }
+  }
+
+  s.tests = append(s.tests, outcome.Tests...)
+  s.logs = append(s.logs, outcome.Logs[:min(len(outcome.Logs), max(maxScriptLogs-len(s.logs), 0))]...)
+}
+
+// runScriptWorker runs job in a process of its own and returns its outcome. A process that runs out
+// of memory or time is reported as such.
+func runScriptWorker(job scriptJob) (*scriptOutcome, error) {
+  executable, err := os.Executable()
+  if nil != err {
+    return nil, fmt.Errorf("the script could not be run: %s", err)
+  }
+
+  input, err := json.Marshal(job)
+  if nil != err {
+    return nil, err
+  }
+
+  ctx, cancel := context.WithTimeout(context.Background(), job.Timeout+scriptWorkerGrace)
+  defer cancel()
+
+  var (
+    cmd            = exec.CommandContext(ctx, executable)
+    stdout, stderr bytes.Buffer
+  )
+
+  cmd.Env = append(os.Environ(), fmt.Sprint(scriptWorkerEnv, "=1"))
+  cmd.Stdin, cmd.Stdout, cmd.Stderr = bytes.NewReader(input), &stdout, &stderr
+
+  err = cmd.Run()
+  message, _, _ := strings.Cut(strings.TrimSpace(stderr.String()), "\n")
+  switch {
+  case nil != ctx.Err():
+    return nil, fmt.Errorf("the script ran for more than %s", job.Timeout)
+  case outOfScriptMemory(message):
+    return nil, errScriptMemory
+  case nil != err:
+    return nil, fmt.Errorf("the script could not be run: %s", strings.TrimSpace(fmt.Sprint(err, " ", message)))
+  }
+
+  outcome := &scriptOutcome{}
+  if err := json.Unmarshal(stdout.Bytes(), outcome); nil != err {
+    return nil, fmt.Errorf("the script could not be run: %s", err)
+  }
+
+  return outcome, nil
+}
+
+// serveScriptJob runs the job read from r, within its memory, and writes its outcome to w. It returns
+// the exit code of the process.
+func serveScriptJob(r io.Reader, w io.Writer) int {
+  job := scriptJob{}
+  if err := json.NewDecoder(r).Decode(&job); nil != err {
+    fmt.Fprintln(os.Stderr, err)
+    return 1
+  }
+
+  if err := limitScriptMemory(job.Memory); nil != err {
+    fmt.Fprintln(os.Stderr, err)
+    return 1
+  }
+
+  scriptTimeout = job.Timeout
+  s := &scriptRun{
+    name:      job.Name,
+    iteration: job.Iteration,
+    scope: &scriptScope{
+      collection:  job.Variables.Collection,
+      environment: job.Variables.Environment,
+      data:        job.Variables.Data,
+      local:       job.Variables.Local,
+    },
+  }
+
+  for scope := range slices.Values([]*map[string]string{&s.scope.collection, &s.scope.environment, &s.scope.data, &s.scope.local}) {
+    if nil == *scope { /* Encoded as null when empty.  */
+      *scope = map[string]string{}
+    }
+  }
+
+  if nil != job.Request {
+    target, rawTarget, err := parseTarget(job.Request.Target)
+    if nil != err {
+      fmt.Fprintln(os.Stderr, err)
+      return 1
+    }
+
+    s.request = &request{method: job.Request.Method, target: target, rawTarget: rawTarget, header: job.Request.Header, body: job.Request.Body}
+    if nil == s.request.header {
+      s.request.header = http.Header{}
+    }
+  }
+
+  if nil != job.Response {
+    s.response = &responseBuilder{
+      status:    job.Response.Status,
+      startLine: []byte(job.Response.StartLine),
+      header:    job.Response.Header,
+      raw:       job.Response.Body,
+      duration:  job.Response.Duration,
+    }
+  }
+
+  outcome := scriptOutcome{}
+  if err := s.runScript(job.Event, job.Code); nil != err {
+    outcome.Error = err.Error()
+  }
+
+  outcome.Variables = scriptVariables{s.scope.collection, s.scope.environment, s.scope.data, s.scope.local}
+  outcome.Tests, outcome.Logs = s.tests, s.logs
+  if nil != s.request {
+    outcome.Request = &scriptRequest{Method: s.request.method, Target: s.request.rawURL(), Header: s.request.header, Body: s.request.body}
+  }
+
+  if err := json.NewEncoder(w).Encode(outcome); nil != err {
+    fmt.Fprintln(os.Stderr, err)
+    return 1
+  }
+
+  return 0
+}
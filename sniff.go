@@ -0,0 +1,123 @@
+package playground
+
+import (
+  "bytes"
+  "encoding/json"
+  "encoding/xml"
+  "errors"
+  "io"
+  "mime"
+  "net/http"
+  "gopkg.in/yaml.v3"
+)
+
+// genericMediaTypes are the media types that tell too little about a body to format it, so that its
+// type is sniffed instead.
+var genericMediaTypes = map[string]bool{
+  "":                         true,
+  "text/plain":               true,
+  "application/octet-stream": true,
+  "binary/octet-stream":      true,
+  "application/unknown":      true,
+}
+
+// sniffMediaType returns the media type that body looks like, or "" if it looks like none that has a
+// formatter. JSON, JSON Lines, XML and YAML are told by parsing body; HTML and the rest by
+// http.DetectContentType.
+func sniffMediaType(body []byte) string {
+  body = bytes.TrimSpace(bytes.TrimPrefix(body, []byte("\ufeff")))
+  if 0 == len(body) {
+    return ""
+  }
+
+  switch body[0] {
+  case '{', '[':
+    if json.Valid(body) {
+      return "application/json"
+    }
+
+    if sniffJSONLines(body) {
+      return "application/x-ndjson"
+    }
+  case '<':
+    detected, _, _ := mime.ParseMediaType(http.DetectContentType(body))
+    if "text/html" == detected {
+      return detected
+    }
+
+    if sniffXML(body) {
+      return "application/xml"
+    }
+  }
+
+  if sniffYAML(body) {
+    return "application/yaml"
+  }
+
+  if detected, _, _ := mime.ParseMediaType(http.DetectContentType(body)); !genericMediaTypes[detected] {
+    if _, ok := supportedMediaTypes[detected]; ok {
+      return detected
+    }
+  }
+
+  return ""
+}
+
+// sniffJSONLines reports whether body holds more than one line, each of which is a JSON text.
+func sniffJSONLines(body []byte) bool {
+  records := 0
+  for line := range bytes.Lines(body) {
+    if line = bytes.TrimSpace(line); 0 == len(line) {
+      continue
+    }
+
+    if !json.Valid(line) {
+      return false
+    }
+
+    records++
+  }
+
+  return records > 1
+}
+
+// sniffXML reports whether body is a well-formed XML document.
+func sniffXML(body []byte) bool {
+  decoder := xml.NewDecoder(bytes.NewReader(body))
+  decoder.Strict = true
+  elements := 0
+  for {
+    token, err := decoder.Token()
+    if errors.Is(err, io.EOF) {
+      return elements > 0
+    }
+
+    if nil != err {
+      return false
+    }
+
+    if _, ok := token.(xml.StartElement); ok {
+      elements++
+    }
+  }
+}
+
+// sniffYAML reports whether body is a YAML document that starts with a directive or a document marker,
+// or a mapping or a sequence of more than one line, rather than a single scalar, which any text is.
+func sniffYAML(body []byte) bool {
+  var document any
+  if err := yaml.Unmarshal(body, &document); nil != err {
+    return false
+  }
+
+  if bytes.HasPrefix(body, []byte("%YAML")) || bytes.HasPrefix(body, []byte("---")) {
+    return true
+  }
+
+  switch document.(type) {
+  case map[string]any, map[any]any, []any:
+    return bytes.Count(body, []byte{'\n'}) > 0
+  }
+
+  return false
+}
@@ -0,0 +1,35 @@
+package playground
+
+import (
+  "testing"
+)
+
+func TestSniffMediaType(t *testing.T) {
+  tests := []struct {
+    body string
+    want string
+  }{
+    {`{"id": 1, "tags": ["a"]}`, "application/json"},
+    {"\ufeff\n [1, 2]\n", "application/json"},
+    {"{\"id\": 1}\n{\"id\": 2}\n", "application/x-ndjson"},
+    {"{\"id\": 1", ""},
+    {`<?xml version="1.0"?><order id="1"><item/></order>`, "application/xml"},
+    {`<order id="1"><item>pen</item></order>`, "application/xml"},
+    {"<!DOCTYPE html><html><body><p>Hi</body></html>", "text/html"},
+    {"<p>unclosed <b>markup", "text/html"},
+    {"<order><item></order>", ""},
+    {"---\nname: playground\n", "application/yaml"},
+    {"name: playground\ntags:\n  - go\n", "application/yaml"},
+    {"- a\n- b\n", "application/yaml"},
+    {"key: value", ""},
+    {"Hello, world!\nSee you.", ""},
+    {"\x89PNG\r\n\x1a\n\x00\x00", ""},
+    {"   ", ""},
+  }
+
+  for _, test := range tests {
+    if got := sniffMediaType([]byte(test.body)); test.want != got {
+      t.Errorf("sniffMediaType(%q) = %q, want %q", test.body, got, test.want)
+    }
+  }
+}
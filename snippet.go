@@ -0,0 +1,627 @@
+package playground
+
+import (
+  "bytes"
+  "encoding/base64"
+  "encoding/json"
+  "errors"
+  "fmt"
+  "io"
+  "maps"
+  "mime"
+  "net/http"
+  "net/url"
+  "slices"
+  "strconv"
+  "strings"
+  "unicode/utf8"
+)
+
+type snippetGenerator interface {
+  generate(in *snippetRequest, output io.Writer)
+}
+
+var snippetGenerators = map[string]snippetGenerator{
+  "curl":       &curlSnippetImpl{},
+  "go":         &goSnippetImpl{},
+  "python":     &pythonSnippetImpl{},
+  "javascript": &fetchSnippetImpl{},
+  "node":       &axiosSnippetImpl{},
+  "httpie":     &httpieSnippetImpl{},
+  "powershell": &powershellSnippetImpl{},
+}
+
+// snippetRequest is a request prepared for the snippet generators: path variables are substituted,
+// and its body mode and authorization are recognized.
+type snippetRequest struct {
+  source   *request
+  method   string
+  url      string
+  header   [][2]string // Sorted by key.
+  body     string
+  json     bool        // The body is a JSON document sent as such.
+  form     [][2]string // The fields of a URL-encoded body.
+  auth     string      // "basic", "bearer" or empty.
+  username string
+  password string
+  token    string
+  insecure bool
+}
+
+// newSnippetRequest prepares in for the snippet generators.
+func newSnippetRequest(in *request) *snippetRequest {
+  target := *in.target
+  substitutePathVariables(&target, in.pathVariables)
+
+  s := &snippetRequest{
+    source:   in,
+    method:   in.method,
+    url:      target.String(),
+    body:     in.body,
+    insecure: in.insecure,
+  }
+
+  if "" != in.rawTarget {
+    s.url = in.rawTarget
+  }
+
+  for key := range slices.Values(slices.Sorted(maps.Keys(in.header))) {
+    for value := range slices.Values(in.header[key]) {
+      s.header = append(s.header, [2]string{key, value})
+    }
+  }
+
+  if authorization := in.header.Values("Authorization"); 1 == len(authorization) {
+    scheme, credentials, _ := strings.Cut(authorization[0], " ")
+    switch strings.ToLower(scheme) {
+    case "basic":
+      if decoded, err := base64.StdEncoding.DecodeString(credentials); nil == err {
+        if username, password, ok := strings.Cut(string(decoded), ":"); ok {
+          s.auth, s.username, s.password = "basic", username, password
+        }
+      }
+    case "bearer":
+      s.auth, s.token = "bearer", credentials
+    }
+  }
+
+  if "" == in.body {
+    return s
+  }
+
+  mediatype, _, _ := mime.ParseMediaType(in.header.Get("Content-Type"))
+  switch {
+  case ("application/json" == mediatype || strings.HasSuffix(mediatype, "+json")) && json.Valid([]byte(in.body)):
+    s.json = true
+  case "application/x-www-form-urlencoded" == mediatype:
+    s.form = parseFormFields(in.body)
+  }
+
+  return s
+}
+
+// headers returns the headers of the request, leaving out the Authorization header if omitAuthorization
+// and the authorization was recognized.
+func (s *snippetRequest) headers(omitAuthorization bool) [][2]string {
+  return slices.DeleteFunc(slices.Clone(s.header), func(h [2]string) bool {
+    return omitAuthorization && "" != s.auth && "Authorization" == h[0]
+  })
+}
+
+// parseFormFields parses a URL-encoded body keeping the order of its fields, or returns nil if
+// body is not URL-encoded.
+func parseFormFields(body string) (fields [][2]string) {
+  for pair := range strings.SplitSeq(body, "&") {
+    if "" == pair {
+      continue
+    }
+
+    key, value, _ := strings.Cut(pair, "=")
+    key, err := url.QueryUnescape(key)
+    if nil != err {
+      return nil
+    }
+
+    value, err = url.QueryUnescape(value)
+    if nil != err {
+      return nil
+    }
+
+    fields = append(fields, [2]string{key, value})
+  }
+
+  return fields
+}
+
+// mergeHeaders joins the values of repeated headers with commas, for the languages that hold
+// headers in dictionaries.
+func mergeHeaders(headers [][2]string) (merged [][2]string) {
+  for header := range slices.Values(headers) {
+    if n := len(merged) - 1; n >= 0 && merged[n][0] == header[0] {
+      merged[n][1] = fmt.Sprint(merged[n][1], ", ", header[1])
+      continue
+    }
+
+    merged = append(merged, header)
+  }
+
+  return merged
+}
+
+// hasDuplicateKeys reports whether any key is repeated in fields.
+func hasDuplicateKeys(fields [][2]string) bool {
+  seen := map[string]bool{}
+  for field := range slices.Values(fields) {
+    if seen[field[0]] {
+      return true
+    }
+
+    seen[field[0]] = true
+  }
+
+  return false
+}
+
+// translateJSON rewrites a JSON document as a literal of another language, indenting it with
+// indent after prefix and spelling true, false and null as literals[0], literals[1] and literals[2].
+func translateJSON(document, prefix, indent string, literals [3]string) (string, bool) {
+  var (
+    b       strings.Builder
+    decoder = json.NewDecoder(strings.NewReader(document))
+    value   func(depth int) error
+  )
+
+  decoder.UseNumber()
+
+  value = func(depth int) error {
+    token, err := decoder.Token()
+    if nil != err {
+      return err
+    }
+
+    switch t := token.(type) {
+    case json.Delim:
+      b.WriteRune(rune(t))
+      empty := true
+      for decoder.More() {
+        if !empty {
+          b.WriteByte(',')
+        }
+
+        empty = false
+        fmt.Fprint(&b, "\n", prefix, strings.Repeat(indent, depth+1))
+
+        if '{' == t {
+          key, err := decoder.Token()
+          if nil != err {
+            return err
+          }
+
+          fmt.Fprint(&b, jsonQuote(key.(string)), ": ")
+        }
+
+        if err := value(depth + 1); nil != err {
+          return err
+        }
+      }
+
+      closing, err := decoder.Token()
+      if nil != err {
+        return err
+      }
+
+      if !empty {
+        fmt.Fprint(&b, "\n", prefix, strings.Repeat(indent, depth))
+      }
+
+      b.WriteRune(rune(closing.(json.Delim)))
+    case string:
+      b.WriteString(jsonQuote(t))
+    case json.Number:
+      b.WriteString(t.String())
+    case bool:
+      if t {
+        b.WriteString(literals[0])
+      } else {
+        b.WriteString(literals[1])
+      }
+    case nil:
+      b.WriteString(literals[2])
+    }
+
+    return nil
+  }
+
+  if err := value(0); nil != err {
+    return "", false
+  }
+
+  if _, err := decoder.Token(); !errors.Is(err, io.EOF) {
+    return "", false
+  }
+
+  return b.String(), true
+}
+
+// jsonQuote quotes s as a JSON string, which is also a valid Python and JavaScript string.
+func jsonQuote(s string) string {
+  var b bytes.Buffer
+  encoder := json.NewEncoder(&b)
+  encoder.SetEscapeHTML(false)
+  _ = encoder.Encode(s)
+  return strings.TrimSuffix(b.String(), "\n")
+}
+
+// goQuote quotes s as a Go string, preferring a raw string literal for multi-line or quoted text.
+func goQuote(s string) string {
+  if utf8.ValidString(s) && strings.ContainsAny(s, "\"\\\n") && !strings.ContainsAny(s, "`\r") {
+    return fmt.Sprint("`", s, "`")
+  }
+
+  return strconv.Quote(s)
+}
+
+// powershellQuote quotes s as a verbatim PowerShell string.
+func powershellQuote(s string) string {
+  return fmt.Sprint("'", strings.ReplaceAll(s, "'", "''"), "'")
+}
+
+// snippet generates the code that sends in with the library of language.
+func snippet(language string, in *request) (string, error) {
+  generator, exists := snippetGenerators[language]
+  if !exists {
+    return "", fmt.Errorf("unsupported language %#q", language)
+  }
+
+  var b strings.Builder
+  generator.generate(newSnippetRequest(in), &b)
+  return b.String(), nil
+}
+
+type curlSnippetImpl struct{}
+
+// generate writes a curl command.
+func (curlSnippetImpl) generate(in *snippetRequest, output io.Writer) {
+  fmt.Fprint(output, curlCommand(in.source))
+}
+
+type goSnippetImpl struct{}
+
+var goMethods = map[string]string{
+  http.MethodGet:    "http.MethodGet",
+  http.MethodPost:   "http.MethodPost",
+  http.MethodPut:    "http.MethodPut",
+  http.MethodPatch:  "http.MethodPatch",
+  http.MethodDelete: "http.MethodDelete",
+}
+
+// generate writes a Go program that uses net/http.
+func (goSnippetImpl) generate(in *snippetRequest, output io.Writer) {
+  imports := []string{"fmt", "io", "net/http"}
+  switch {
+  case nil != in.form:
+    imports = append(imports, "net/url", "strings")
+  case "" != in.body:
+    imports = append(imports, "strings")
+  }
+
+  if in.insecure {
+    imports = append(imports, "crypto/tls")
+  }
+
+  slices.Sort(imports)
+
+  fmt.Fprint(output, "package main\n\nimport (\n")
+  for name := range slices.Values(imports) {
+    fmt.Fprintf(output, "\t%q\n", name)
+  }
+
+  fmt.Fprint(output, ")\n\nfunc main() {\n")
+
+  body := "nil"
+  switch {
+  case nil != in.form:
+    fmt.Fprint(output, "\tform := url.Values{}\n")
+    for field := range slices.Values(in.form) {
+      fmt.Fprintf(output, "\tform.Add(%s, %s)\n", goQuote(field[0]), goQuote(field[1]))
+    }
+
+    fmt.Fprint(output, "\n")
+    body = "strings.NewReader(form.Encode())"
+  case "" != in.body:
+    fmt.Fprintf(output, "\tbody := strings.NewReader(%s)\n\n", goQuote(in.body))
+    body = "body"
+  }
+
+  method, exists := goMethods[in.method]
+  if !exists {
+    method = strconv.Quote(in.method)
+  }
+
+  fmt.Fprintf(output, "\treq, err := http.NewRequest(%s, %s, %s)\n\tif err != nil {\n\t\tpanic(err)\n\t}\n\n", method, goQuote(in.url), body)
+
+  headers := in.headers("basic" == in.auth)
+  for header := range slices.Values(headers) {
+    fmt.Fprintf(output, "\treq.Header.Add(%s, %s)\n", goQuote(header[0]), goQuote(header[1]))
+  }
+
+  if "basic" == in.auth {
+    fmt.Fprintf(output, "\treq.SetBasicAuth(%s, %s)\n", goQuote(in.username), goQuote(in.password))
+  }
+
+  if len(headers) > 0 || "basic" == in.auth {
+    fmt.Fprint(output, "\n")
+  }
+
+  client := "http.DefaultClient"
+  if in.insecure {
+    fmt.Fprint(output, "\tclient := &http.Client{\n\t\tTransport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}},\n\t}\n\n")
+    client = "client"
+  }
+
+  fmt.Fprintf(output, "\tres, err := %s.Do(req)\n\tif err != nil {\n\t\tpanic(err)\n\t}\n\tdefer res.Body.Close()\n\n", client)
+  fmt.Fprint(output, "\tcontent, err := io.ReadAll(res.Body)\n\tif err != nil {\n\t\tpanic(err)\n\t}\n\n")
+  fmt.Fprint(output, "\tfmt.Println(res.Status)\n\tfmt.Println(string(content))\n}\n")
+}
+
+type pythonSnippetImpl struct{}
+
+// generate writes a Python script that uses requests.
+func (pythonSnippetImpl) generate(in *snippetRequest, output io.Writer) {
+  var arguments []string
+
+  fmt.Fprintf(output, "import requests\n\nurl = %s\n", jsonQuote(in.url))
+
+  if headers := mergeHeaders(in.headers("basic" == in.auth)); len(headers) > 0 {
+    fmt.Fprint(output, "\nheaders = {\n")
+    for header := range slices.Values(headers) {
+      fmt.Fprintf(output, "    %s: %s,\n", jsonQuote(header[0]), jsonQuote(header[1]))
+    }
+
+    fmt.Fprint(output, "}\n")
+    arguments = append(arguments, "headers=headers")
+  }
+
+  literal, isJSON := translateJSON(in.body, "", "    ", [3]string{"True", "False", "None"})
+  switch {
+  case in.json && isJSON:
+    fmt.Fprintf(output, "\npayload = %s\n", literal)
+    arguments = append(arguments, "json=payload")
+  case nil != in.form && hasDuplicateKeys(in.form):
+    fmt.Fprint(output, "\npayload = [\n")
+    for field := range slices.Values(in.form) {
+      fmt.Fprintf(output, "    (%s, %s),\n", jsonQuote(field[0]), jsonQuote(field[1]))
+    }
+
+    fmt.Fprint(output, "]\n")
+    arguments = append(arguments, "data=payload")
+  case nil != in.form:
+    fmt.Fprint(output, "\npayload = {\n")
+    for field := range slices.Values(in.form) {
+      fmt.Fprintf(output, "    %s: %s,\n", jsonQuote(field[0]), jsonQuote(field[1]))
+    }
+
+    fmt.Fprint(output, "}\n")
+    arguments = append(arguments, "data=payload")
+  case "" != in.body:
+    fmt.Fprintf(output, "\npayload = %s\n", jsonQuote(in.body))
+    arguments = append(arguments, "data=payload")
+  }
+
+  if "basic" == in.auth {
+    arguments = append(arguments, fmt.Sprintf("auth=(%s, %s)", jsonQuote(in.username), jsonQuote(in.password)))
+  }
+
+  if in.insecure {
+    arguments = append(arguments, "verify=False")
+  }
+
+  fmt.Fprintf(output, "\nresponse = requests.request(%s, %s)\n", jsonQuote(in.method), strings.Join(append([]string{"url"}, arguments...), ", "))
+  fmt.Fprint(output, "\nprint(response.status_code)\nprint(response.text)\n")
+}
+
+// writeJavaScriptHeaders writes the headers of a fetch or axios call.
+func writeJavaScriptHeaders(output io.Writer, headers [][2]string) {
+  if 0 == len(headers) {
+    return
+  }
+
+  fmt.Fprint(output, "  headers: {\n")
+  for header := range slices.Values(headers) {
+    fmt.Fprintf(output, "    %s: %s,\n", jsonQuote(header[0]), jsonQuote(header[1]))
+  }
+
+  fmt.Fprint(output, "  },\n")
+}
+
+// javaScriptFormFields returns the fields of a URL-encoded body as a JavaScript array of pairs.
+func javaScriptFormFields(fields [][2]string) string {
+  var b strings.Builder
+  b.WriteString("[\n")
+  for field := range slices.Values(fields) {
+    fmt.Fprintf(&b, "    [%s, %s],\n", jsonQuote(field[0]), jsonQuote(field[1]))
+  }
+
+  b.WriteString("  ]")
+  return b.String()
+}
+
+type fetchSnippetImpl struct{}
+
+// generate writes a JavaScript module that uses fetch.
+func (fetchSnippetImpl) generate(in *snippetRequest, output io.Writer) {
+  if in.insecure {
+    fmt.Fprint(output, "// fetch cannot skip the verification of TLS certificates.\n")
+  }
+
+  fmt.Fprintf(output, "const response = await fetch(%s, {\n", jsonQuote(in.url))
+  fmt.Fprintf(output, "  method: %s,\n", jsonQuote(in.method))
+  writeJavaScriptHeaders(output, mergeHeaders(in.headers(false)))
+
+  literal, isJSON := translateJSON(in.body, "  ", "  ", [3]string{"true", "false", "null"})
+  switch {
+  case in.json && isJSON:
+    fmt.Fprintf(output, "  body: JSON.stringify(%s),\n", literal)
+  case nil != in.form:
+    fmt.Fprintf(output, "  body: new URLSearchParams(%s),\n", javaScriptFormFields(in.form))
+  case "" != in.body:
+    fmt.Fprintf(output, "  body: %s,\n", jsonQuote(in.body))
+  }
+
+  fmt.Fprint(output, "});\n\nconsole.log(response.status);\nconsole.log(await response.text());\n")
+}
+
+type axiosSnippetImpl struct{}
+
+// generate writes a Node.js script that uses axios.
+func (axiosSnippetImpl) generate(in *snippetRequest, output io.Writer) {
+  fmt.Fprint(output, "const axios = require(\"axios\");\n")
+  if in.insecure {
+    fmt.Fprint(output, "const https = require(\"https\");\n")
+  }
+
+  fmt.Fprint(output, "\naxios.request({\n")
+  fmt.Fprintf(output, "  method: %s,\n", jsonQuote(strings.ToLower(in.method)))
+  fmt.Fprintf(output, "  url: %s,\n", jsonQuote(in.url))
+  writeJavaScriptHeaders(output, mergeHeaders(in.headers("basic" == in.auth)))
+
+  if "basic" == in.auth {
+    fmt.Fprintf(output, "  auth: {\n    username: %s,\n    password: %s,\n  },\n", jsonQuote(in.username), jsonQuote(in.password))
+  }
+
+  literal, isJSON := translateJSON(in.body, "  ", "  ", [3]string{"true", "false", "null"})
+  switch {
+  case in.json && isJSON:
+    fmt.Fprintf(output, "  data: %s,\n", literal)
+  case nil != in.form:
+    fmt.Fprintf(output, "  data: new URLSearchParams(%s),\n", javaScriptFormFields(in.form))
+  case "" != in.body:
+    fmt.Fprintf(output, "  data: %s,\n", jsonQuote(in.body))
+  }
+
+  if in.insecure {
+    fmt.Fprint(output, "  httpsAgent: new https.Agent({rejectUnauthorized: false}),\n")
+  }
+
+  fmt.Fprint(output, "}).then(response => {\n  console.log(response.status);\n  console.log(response.data);\n}).catch(error => {\n  console.error(error);\n});\n")
+}
+
+type httpieSnippetImpl struct{}
+
+// httpieEscaper escapes the characters that HTTPie reads as separators in the key of a request item.
+var httpieEscaper = strings.NewReplacer(`\`, `\\`, `:`, `\:`, `=`, `\=`, `@`, `\@`)
+
+// generate writes an HTTPie command.
+func (httpieSnippetImpl) generate(in *snippetRequest, output io.Writer) {
+  var words []string
+
+  switch {
+  case nil != in.form:
+    words = append(words, "--form")
+  case "" != in.body:
+    words = append(words, "--raw", shellQuote(in.body))
+  }
+
+  switch in.auth {
+  case "basic":
+    words = append(words, "--auth", shellQuote(fmt.Sprint(in.username, ":", in.password)))
+  case "bearer":
+    words = append(words, "--auth-type", "bearer", "--auth", shellQuote(in.token))
+  }
+
+  if in.insecure {
+    words = append(words, "--verify", "no")
+  }
+
+  fmt.Fprint(output, "http")
+  for word := range slices.Values(words) {
+    fmt.Fprint(output, " ", word)
+  }
+
+  fmt.Fprint(output, " ", in.method, " ", shellQuote(in.url))
+
+  for header := range slices.Values(in.headers(true)) {
+    if "" == header[1] {
+      fmt.Fprint(output, " \\\n  ", shellQuote(fmt.Sprint(httpieEscaper.Replace(header[0]), ";")))
+      continue
+    }
+
+    fmt.Fprint(output, " \\\n  ", shellQuote(fmt.Sprint(httpieEscaper.Replace(header[0]), ":", header[1])))
+  }
+
+  for field := range slices.Values(in.form) {
+    fmt.Fprint(output, " \\\n  ", shellQuote(fmt.Sprint(httpieEscaper.Replace(field[0]), "=", field[1])))
+  }
+}
+
+type powershellSnippetImpl struct{}
+
+// generate writes a PowerShell 7 script that uses Invoke-RestMethod.
+func (powershellSnippetImpl) generate(in *snippetRequest, output io.Writer) {
+  var (
+    parameters  = []string{fmt.Sprint("-Uri ", powershellQuote(in.url))}
+    contentType string
+    headers     [][2]string
+  )
+
+  parameters = append(parameters, fmt.Sprint("-Method ", strings.ToUpper(in.method[:1]), strings.ToLower(in.method[1:])))
+
+  for header := range slices.Values(mergeHeaders(in.headers(true))) {
+    if "Content-Type" == header[0] {
+      contentType = header[1]
+      continue
+    }
+
+    headers = append(headers, header)
+  }
+
+  if len(headers) > 0 {
+    fmt.Fprint(output, "$headers = [ordered]@{\n")
+    for header := range slices.Values(headers) {
+      fmt.Fprintf(output, "    %s = %s\n", powershellQuote(header[0]), powershellQuote(header[1]))
+    }
+
+    fmt.Fprint(output, "}\n\n")
+    parameters = append(parameters, "-Headers $headers")
+  }
+
+  switch {
+  case nil != in.form && !hasDuplicateKeys(in.form):
+    fmt.Fprint(output, "$body = [ordered]@{\n")
+    for field := range slices.Values(in.form) {
+      fmt.Fprintf(output, "    %s = %s\n", powershellQuote(field[0]), powershellQuote(field[1]))
+    }
+
+    fmt.Fprint(output, "}\n\n")
+  case "" != in.body:
+    fmt.Fprintf(output, "$body = %s\n\n", powershellQuote(in.body))
+  }
+
+  if "" != contentType {
+    parameters = append(parameters, fmt.Sprint("-ContentType ", powershellQuote(contentType)))
+  }
+
+  if "" != in.body {
+    parameters = append(parameters, "-Body $body")
+  }
+
+  switch in.auth {
+  case "basic":
+    fmt.Fprintf(output, "$credential = [PSCredential]::new(%s, (ConvertTo-SecureString %s -AsPlainText -Force))\n\n",
+      powershellQuote(in.username), powershellQuote(in.password))
+    parameters = append(parameters, "-Authentication Basic", "-Credential $credential")
+  case "bearer":
+    fmt.Fprintf(output, "$token = ConvertTo-SecureString %s -AsPlainText -Force\n\n", powershellQuote(in.token))
+    parameters = append(parameters, "-Authentication Bearer", "-Token $token")
+  }
+
+  if "" != in.auth && strings.HasPrefix(in.url, "http:") {
+    parameters = append(parameters, "-AllowUnencryptedAuthentication")
+  }
+
+  if in.insecure {
+    parameters = append(parameters, "-SkipCertificateCheck")
+  }
+
+  fmt.Fprintf(output, "$response = Invoke-RestMethod %s\n\n$response\n", strings.Join(parameters, " `\n    "))
+}
@@ -0,0 +1,376 @@
+package playground
+
+import (
+  "net/http"
+  "net/url"
+  "reflect"
+  "testing"
+)
+
+// snippetTestRequests returns a JSON request with a bearer token and a URL-encoded request with
+// basic authentication that skips the verification of TLS certificates.
+func snippetTestRequests() [2]*request {
+  jsonTarget, _ := url.Parse("https://fontseca.dev/users/:id?page=2")
+  formTarget, _ := url.Parse("http://localhost:8080/login")
+
+  return [2]*request{
+    {
+      method:        http.MethodPost,
+      target:        jsonTarget,
+      pathVariables: map[string]string{"id": "5"},
+      header: http.Header{
+        "Accept":        {"application/json", "text/plain"},
+        "Authorization": {"Bearer abc.def"},
+        "Content-Type":  {"application/json"},
+      },
+      body: `{"name":"Jane","admin":false,"tags":["a",1.5e3],"manager":null,"meta":{}}`,
+    },
+    {
+      method: http.MethodPut,
+      target: formTarget,
+      header: http.Header{
+        "Authorization": {"Basic amFuZTpzM2NyZXQ="},
+        "Content-Type":  {"application/x-www-form-urlencoded"},
+      },
+      body:     "user=jane&note=it%27s+a+test&tag=a&tag=b",
+      insecure: true,
+    },
+  }
+}
+
+func testSnippet(t *testing.T, language string, want [2]string) {
+  for n, in := range snippetTestRequests() {
+    got, err := snippet(language, in)
+    if nil != err {
+      t.Fatalf("snippet(%q, ...) failed: %s", language, err)
+    }
+
+    if want[n] != got {
+      t.Errorf("\n"+
+        "\nexpected:\n\n---\n%s\n---\n"+
+        "\ngot:\n\n---\n%s---",
+        want[n], got)
+    }
+  }
+}
+
+func TestSnippetGo(t *testing.T) {
+  testSnippet(t, "go", [...]string{`package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+func main() {
+	body := strings.NewReader(` + "`" + `{"name":"Jane","admin":false,"tags":["a",1.5e3],"manager":null,"meta":{}}` + "`" + `)
+
+	req, err := http.NewRequest(http.MethodPost, "https://fontseca.dev/users/5?page=2", body)
+	if err != nil {
+		panic(err)
+	}
+
+	req.Header.Add("Accept", "application/json")
+	req.Header.Add("Accept", "text/plain")
+	req.Header.Add("Authorization", "Bearer abc.def")
+	req.Header.Add("Content-Type", "application/json")
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		panic(err)
+	}
+	defer res.Body.Close()
+
+	content, err := io.ReadAll(res.Body)
+	if err != nil {
+		panic(err)
+	}
+
+	fmt.Println(res.Status)
+	fmt.Println(string(content))
+}
+`, `package main
+
+import (
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+func main() {
+	form := url.Values{}
+	form.Add("user", "jane")
+	form.Add("note", "it's a test")
+	form.Add("tag", "a")
+	form.Add("tag", "b")
+
+	req, err := http.NewRequest(http.MethodPut, "http://localhost:8080/login", strings.NewReader(form.Encode()))
+	if err != nil {
+		panic(err)
+	}
+
+	req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth("jane", "s3cret")
+
+	client := &http.Client{
+		Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}},
+	}
+
+	res, err := client.Do(req)
+	if err != nil {
+		panic(err)
+	}
+	defer res.Body.Close()
+
+	content, err := io.ReadAll(res.Body)
+	if err != nil {
+		panic(err)
+	}
+
+	fmt.Println(res.Status)
+	fmt.Println(string(content))
+}
+`})
+}
+
+func TestSnippetPython(t *testing.T) {
+  testSnippet(t, "python", [...]string{`import requests
+
+url = "https://fontseca.dev/users/5?page=2"
+
+headers = {
+    "Accept": "application/json, text/plain",
+    "Authorization": "Bearer abc.def",
+    "Content-Type": "application/json",
+}
+
+payload = {
+    "name": "Jane",
+    "admin": False,
+    "tags": [
+        "a",
+        1.5e3
+    ],
+    "manager": None,
+    "meta": {}
+}
+
+response = requests.request("POST", url, headers=headers, json=payload)
+
+print(response.status_code)
+print(response.text)
+`, `import requests
+
+url = "http://localhost:8080/login"
+
+headers = {
+    "Content-Type": "application/x-www-form-urlencoded",
+}
+
+payload = [
+    ("user", "jane"),
+    ("note", "it's a test"),
+    ("tag", "a"),
+    ("tag", "b"),
+]
+
+response = requests.request("PUT", url, headers=headers, data=payload, auth=("jane", "s3cret"), verify=False)
+
+print(response.status_code)
+print(response.text)
+`})
+}
+
+func TestSnippetJavaScript(t *testing.T) {
+  testSnippet(t, "javascript", [...]string{`const response = await fetch("https://fontseca.dev/users/5?page=2", {
+  method: "POST",
+  headers: {
+    "Accept": "application/json, text/plain",
+    "Authorization": "Bearer abc.def",
+    "Content-Type": "application/json",
+  },
+  body: JSON.stringify({
+    "name": "Jane",
+    "admin": false,
+    "tags": [
+      "a",
+      1.5e3
+    ],
+    "manager": null,
+    "meta": {}
+  }),
+});
+
+console.log(response.status);
+console.log(await response.text());
+`, `// fetch cannot skip the verification of TLS certificates.
+const response = await fetch("http://localhost:8080/login", {
+  method: "PUT",
+  headers: {
+    "Authorization": "Basic amFuZTpzM2NyZXQ=",
+    "Content-Type": "application/x-www-form-urlencoded",
+  },
+  body: new URLSearchParams([
+    ["user", "jane"],
+    ["note", "it's a test"],
+    ["tag", "a"],
+    ["tag", "b"],
+  ]),
+});
+
+console.log(response.status);
+console.log(await response.text());
+`})
+}
+
+func TestSnippetNode(t *testing.T) {
+  testSnippet(t, "node", [...]string{`const axios = require("axios");
+
+axios.request({
+  method: "post",
+  url: "https://fontseca.dev/users/5?page=2",
+  headers: {
+    "Accept": "application/json, text/plain",
+    "Authorization": "Bearer abc.def",
+    "Content-Type": "application/json",
+  },
+  data: {
+    "name": "Jane",
+    "admin": false,
+    "tags": [
+      "a",
+      1.5e3
+    ],
+    "manager": null,
+    "meta": {}
+  },
+}).then(response => {
+  console.log(response.status);
+  console.log(response.data);
+}).catch(error => {
+  console.error(error);
+});
+`, `const axios = require("axios");
+const https = require("https");
+
+axios.request({
+  method: "put",
+  url: "http://localhost:8080/login",
+  headers: {
+    "Content-Type": "application/x-www-form-urlencoded",
+  },
+  auth: {
+    username: "jane",
+    password: "s3cret",
+  },
+  data: new URLSearchParams([
+    ["user", "jane"],
+    ["note", "it's a test"],
+    ["tag", "a"],
+    ["tag", "b"],
+  ]),
+  httpsAgent: new https.Agent({rejectUnauthorized: false}),
+}).then(response => {
+  console.log(response.status);
+  console.log(response.data);
+}).catch(error => {
+  console.error(error);
+});
+`})
+}
+
+func TestSnippetHTTPie(t *testing.T) {
+  testSnippet(t, "httpie", [...]string{`http --raw '{"name":"Jane","admin":false,"tags":["a",1.5e3],"manager":null,"meta":{}}' --auth-type bearer --auth abc.def POST 'https://fontseca.dev/users/5?page=2' \
+  Accept:application/json \
+  Accept:text/plain \
+  Content-Type:application/json`, `http --form --auth jane:s3cret --verify no PUT http://localhost:8080/login \
+  Content-Type:application/x-www-form-urlencoded \
+  user=jane \
+  'note=it'\''s a test' \
+  tag=a \
+  tag=b`})
+}
+
+func TestSnippetPowerShell(t *testing.T) {
+  testSnippet(t, "powershell", [...]string{`$headers = [ordered]@{
+    'Accept' = 'application/json, text/plain'
+}
+
+$body = '{"name":"Jane","admin":false,"tags":["a",1.5e3],"manager":null,"meta":{}}'
+
+$token = ConvertTo-SecureString 'abc.def' -AsPlainText -Force
+
+$response = Invoke-RestMethod -Uri 'https://fontseca.dev/users/5?page=2' ` + "`" + `
+    -Method Post ` + "`" + `
+    -Headers $headers ` + "`" + `
+    -ContentType 'application/json' ` + "`" + `
+    -Body $body ` + "`" + `
+    -Authentication Bearer ` + "`" + `
+    -Token $token
+
+$response
+`, `$body = 'user=jane&note=it%27s+a+test&tag=a&tag=b'
+
+$credential = [PSCredential]::new('jane', (ConvertTo-SecureString 's3cret' -AsPlainText -Force))
+
+$response = Invoke-RestMethod -Uri 'http://localhost:8080/login' ` + "`" + `
+    -Method Put ` + "`" + `
+    -ContentType 'application/x-www-form-urlencoded' ` + "`" + `
+    -Body $body ` + "`" + `
+    -Authentication Basic ` + "`" + `
+    -Credential $credential ` + "`" + `
+    -AllowUnencryptedAuthentication ` + "`" + `
+    -SkipCertificateCheck
+
+$response
+`})
+}
+
+func TestSnippetCurl(t *testing.T) {
+  for _, in := range snippetTestRequests() {
+    got, _ := snippet("curl", in)
+    if want := curlCommand(in); want != got {
+      t.Errorf("snippet(\"curl\", ...) = %q, want %q", got, want)
+    }
+  }
+
+  if _, err := snippet("cobol", snippetTestRequests()[0]); nil == err {
+    t.Error("snippet(\"cobol\", ...) must fail")
+  }
+}
+
+func TestTranslateJSON(t *testing.T) {
+  tests := [...][2]string{
+    {`null`, `None`},
+    {` [ ] `, `[]`},
+    {`{"a":[true,{"b":"ñ\n"}]}`, "{\n>  \"a\": [\n>    True,\n>    {\n>      \"b\": \"ñ\\n\"\n>    }\n>  ]\n>}"},
+  }
+
+  for _, test := range tests {
+    got, ok := translateJSON(test[0], ">", "  ", [3]string{"True", "False", "None"})
+    if !ok || test[1] != got {
+      t.Errorf("translateJSON(%q) = (%q, %t), want %q", test[0], got, ok, test[1])
+    }
+  }
+
+  for _, document := range []string{``, `{`, `[1] [2]`, `{"a" 1}`} {
+    if _, ok := translateJSON(document, "", "  ", [3]string{"true", "false", "null"}); ok {
+      t.Errorf("translateJSON(%q) must fail", document)
+    }
+  }
+}
+
+func TestParseFormFields(t *testing.T) {
+  if want, got := [][2]string{{"a", "1"}, {"b", ""}, {"c d", "é"}}, parseFormFields("a=1&b&&c+d=%C3%A9"); !reflect.DeepEqual(want, got) {
+    t.Errorf("parseFormFields(...) = %q, want %q", got, want)
+  }
+
+  if got := parseFormFields("a=%zz"); nil != got {
+    t.Errorf("parseFormFields(\"a=%%zz\") = %q, want nil", got)
+  }
+}
@@ -0,0 +1,219 @@
+package playground
+
+import (
+  "fmt"
+  "maps"
+  "net/http"
+  "net/url"
+  "regexp"
+  "slices"
+  "strings"
+)
+
+// regexpVariable matches a variable reference such as {{host}}, {{api.base-url}}, {{user id}} or {{$guid}}.
+var regexpVariable = regexp.MustCompile(`\{\{(\$?[a-zA-Z0-9_.\- ]+)\}\}`)
+
+const (
+  // maxResolvedBytes bounds what the references expanded by the resolvers sharing a budget turn into.
+  maxResolvedBytes = 16 << 20 // 16 MB
+
+  // maxSubstitutions bounds the number of references expanded by the resolvers sharing a budget.
+  maxSubstitutions = 1 << 17
+)
+
+// A resolverBudget counts what resolvers expanded, so that references nested in variable values,
+// such as a={{b}}{{b}} and b={{c}}{{c}}, cannot grow the requests of a collection exponentially.
+type resolverBudget struct {
+  bytes         int
+  substitutions int
+}
+
+// A resolver expands variable references against a set of collection variables. References
+// inside variable values are expanded as well, and every reference that could not be expanded
+// is recorded as a warning rather than silently left in place.
+//
+// Dynamic variables are only expanded when the resolver has a dynamicGenerator, that is, when
+// the request is about to be sent; otherwise they are left in place without a warning.
+type resolver struct {
+  variables map[string]collVariable
+  dynamic   *dynamicGenerator
+  budget    *resolverBudget // Past maxResolvedBytes or maxSubstitutions, references are left in place.
+  warnings  []string
+}
+
+func newResolver(variables map[string]collVariable) *resolver {
+  return &resolver{
+    variables: variables,
+    budget:    &resolverBudget{},
+  }
+}
+
+// resolve replaces every variable reference in s with the value of its variable.
+func (r *resolver) resolve(s string) string {
+  return r.expand(s, nil)
+}
+
+// expand resolves the references in s; stack holds the names of the variables being expanded
+// so far, which is used to detect reference cycles.
+func (r *resolver) expand(s string, stack []string) string {
+  return regexpVariable.ReplaceAllStringFunc(s, func(match string) string {
+    vname := match[2 : len(match)-2]
+
+    if r.budget.substitutions >= maxSubstitutions {
+      r.warn(fmt.Sprintf("more than %d variable references to expand; the rest are left in place", maxSubstitutions))
+      return match
+    }
+
+    r.budget.substitutions++
+    if slices.Contains(stack, vname) {
+      r.warn(fmt.Sprintf("variable cycle %s", strings.Join(append(slices.Clone(stack), vname), " -> ")))
+      return match
+    }
+
+    if _, isDynamic := dynamicVariables[vname]; isDynamic {
+      if nil == r.dynamic {
+        return match
+      }
+
+      value, _ := r.dynamic.generate(vname)
+      return value
+    }
+
+    variable, exists := r.variables[vname]
+    if !exists || variable.Disabled {
+      r.warn(fmt.Sprintf("unresolved variable %s", match))
+      return match
+    }
+
+    value := r.expand(variable.Value, append(slices.Clone(stack), vname))
+    if r.budget.bytes += len(value); r.budget.bytes > maxResolvedBytes {
+      r.warn(fmt.Sprintf("the variables expand to more than %d MB; the rest of the references are left in place", maxResolvedBytes>>20))
+      return match
+    }
+
+    return value
+  })
+}
+
+// warn records a warning once.
+func (r *resolver) warn(warning string) {
+  if !slices.Contains(r.warnings, warning) {
+    r.warnings = append(r.warnings, warning)
+  }
+}
+
+// regexpEscapedVariable matches a variable reference whose braces and spaces url.URL.String escapes,
+// such as %7B%7Buser%20id%7D%7D, and not the braces of a URL that are escaped on their own.
+var regexpEscapedVariable = regexp.MustCompile(`%7[Bb]%7[Bb]((?:\$|%24)?(?:[a-zA-Z0-9_.\-]|%20)+)%7[Dd]%7[Dd]`)
+
+// unescapeVariables restores the variable references of target, a URL written by url.URL.String.
+func unescapeVariables(target string) string {
+  return regexpEscapedVariable.ReplaceAllStringFunc(target, func(match string) string {
+    name, _ := url.PathUnescape(match[len("%7B%7B") : len(match)-len("%7D%7D")])
+    return "{{" + name + "}}"
+  })
+}
+
+// parseTarget parses target, a URL whose variable references are yet to be resolved. A target that
+// is no URL until they are, such as https://{{host}}:{{port}}/users, is returned as raw along with an
+// empty URL, so that it is resolved before it is parsed.
+func parseTarget(target string) (parsed *url.URL, raw string, err error) {
+  if parsed, err = url.Parse(target); nil == err {
+    return parsed, "", nil
+  }
+
+  if regexpVariable.MatchString(target) {
+    return &url.URL{}, target, nil
+  }
+
+  return nil, "", fmt.Errorf("invalid target URL %#q", target)
+}
+
+// rawURL returns the target of r as written, with its variable references.
+func (r *request) rawURL() string {
+  if "" != r.rawTarget {
+    return r.rawTarget
+  }
+
+  // The braces of references are escaped when the target is parsed, so they are restored.
+  return unescapeVariables(r.target.String())
+}
+
+// queryEscapeVariables escapes s with url.QueryEscape, except for its variable references, which are
+// kept as they are so that they can still be resolved.
+func queryEscapeVariables(s string) string {
+  var (
+    escaped strings.Builder
+    last    int
+  )
+
+  for reference := range slices.Values(regexpVariable.FindAllStringIndex(s, -1)) {
+    escaped.WriteString(url.QueryEscape(s[last:reference[0]]))
+    escaped.WriteString(s[reference[0]:reference[1]])
+    last = reference[1]
+  }
+
+  escaped.WriteString(url.QueryEscape(s[last:]))
+  return escaped.String()
+}
+
+// resolveRequest expands the variable references left in the target, headers, body and path
+// variables of an outgoing request, and then substitutes the path variables of the target.
+func resolveRequest(in *request, res *resolver) error {
+  for key := range slices.Values(slices.Sorted(maps.Keys(in.pathVariables))) {
+    in.pathVariables[key] = res.resolve(in.pathVariables[key])
+  }
+
+  if nil != in.target {
+    target := res.resolve(in.rawURL())
+    resolved, err := url.Parse(target)
+    if nil != err {
+      return fmt.Errorf("resolved target %#q is not a valid URL", target)
+    }
+
+    in.target, in.rawTarget = resolved, ""
+    substitutePathVariables(in.target, in.pathVariables)
+  }
+
+  header := http.Header{}
+  for key := range slices.Values(slices.Sorted(maps.Keys(in.header))) {
+    for value := range slices.Values(in.header[key]) {
+      header.Add(http.CanonicalHeaderKey(res.resolve(key)), res.resolve(value))
+    }
+  }
+
+  in.header = header
+  in.body = res.resolve(in.body)
+  return nil
+}
+
+// substitutePathVariables replaces every path segment of target that names a path variable,
+// such as :id, with the escaped value of that variable. Segments naming unknown variables are
+// left untouched.
+func substitutePathVariables(target *url.URL, variables map[string]string) {
+  if 0 == len(variables) {
+    return
+  }
+
+  segments := strings.Split(target.EscapedPath(), "/")
+  for n, segment := range segments {
+    if !strings.HasPrefix(segment, ":") {
+      continue
+    }
+
+    name, err := url.PathUnescape(segment[1:])
+    if nil != err {
+      continue
+    }
+
+    if value, exists := variables[name]; exists {
+      segments[n] = url.PathEscape(value)
+    }
+  }
+
+  escaped := strings.Join(segments, "/")
+  if path, err := url.PathUnescape(escaped); nil == err {
+    target.Path = path
+    target.RawPath = escaped
+  }
+}
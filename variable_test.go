@@ -0,0 +1,213 @@
+package playground
+
+import (
+  "fmt"
+  "net/http"
+  "net/url"
+  "reflect"
+  "slices"
+  "strings"
+  "testing"
+  "time"
+)
+
+func TestResolver_resolve(t *testing.T) {
+  variables := map[string]collVariable{
+    "host":         {Key: "host", Value: "{{scheme}}://{{api.domain}}"},
+    "scheme":       {Key: "scheme", Value: "https"},
+    "api.domain":   {Key: "api.domain", Value: "api.{{base-domain}}"},
+    "base-domain":  {Key: "base-domain", Value: "fontseca.dev"},
+    "user id":      {Key: "user id", Value: "10"},
+    "ping":         {Key: "ping", Value: "{{pong}}"},
+    "pong":         {Key: "pong", Value: "{{ping}}"},
+    "self":         {Key: "self", Value: "a{{self}}"},
+    "disabled_var": {Key: "disabled_var", Value: "x", Disabled: true},
+  }
+
+  tests := []struct {
+    input    string
+    want     string
+    warnings []string
+  }{
+    {"", "", nil},
+    {"no variables", "no variables", nil},
+    {"{{host}}/users/{{user id}}", "https://api.fontseca.dev/users/10", nil},
+    {"{{host}}/{{missing}}/{{missing}}", "https://api.fontseca.dev/{{missing}}/{{missing}}", []string{"unresolved variable {{missing}}"}},
+    {"{{disabled_var}}", "{{disabled_var}}", []string{"unresolved variable {{disabled_var}}"}},
+    {"{{ping}}", "{{ping}}", []string{"variable cycle ping -> pong -> ping"}},
+    {"{{self}}", "a{{self}}", []string{"variable cycle self -> self"}},
+    {"{{not|a|variable}}", "{{not|a|variable}}", nil},
+    {"{{$guid}}-{{$randomInt}}", "{{$guid}}-{{$randomInt}}", nil},
+    {"{{$unknownDynamic}}", "{{$unknownDynamic}}", []string{"unresolved variable {{$unknownDynamic}}"}},
+  }
+
+  for _, test := range tests {
+    res := newResolver(variables)
+    got := res.resolve(test.input)
+
+    if test.want != got {
+      t.Errorf("resolve(%q) = %q, want %q", test.input, got, test.want)
+    }
+
+    if !reflect.DeepEqual(test.warnings, res.warnings) {
+      t.Errorf("resolve(%q) warnings = %q, want %q", test.input, res.warnings, test.warnings)
+    }
+  }
+}
+
+func TestResolver_budget(t *testing.T) {
+  tests := []struct {
+    leaf, warning string
+  }{
+    {"x", "more than 131072 variable references to expand; the rest are left in place"},
+    {strings.Repeat("x", 1<<10), "the variables expand to more than 16 MB; the rest of the references are left in place"},
+  }
+
+  for _, test := range tests {
+    variables := map[string]collVariable{"v40": {Key: "v40", Value: test.leaf}}
+    for n := range 40 {
+      key := fmt.Sprint("v", n)
+      variables[key] = collVariable{Key: key, Value: fmt.Sprintf("{{v%d}}{{v%[1]d}}", n+1)}
+    }
+
+    res := newResolver(variables)
+    got := res.resolve("{{v0}}")
+
+    if len(got) > 2*maxResolvedBytes {
+      t.Errorf("len(resolve(...)) = %d, want at most %d", len(got), 2*maxResolvedBytes)
+    }
+
+    if !slices.Contains(res.warnings, test.warning) {
+      t.Errorf("warnings = %q, want %q", res.warnings, test.warning)
+    }
+  }
+}
+
+func TestResolveRequest(t *testing.T) {
+  target, _ := url.Parse("https://fontseca.dev/users/{{user_id}}?page={{page}}&at={{$timestamp}}")
+  in := &request{
+    method: http.MethodPost,
+    target: target,
+    header: http.Header{
+      "Authorization": {"Bearer {{token}}"},
+      "X-Page":        {"{{page}}"},
+      "X-Sent-At":     {"{{$isoTimestamp}}"},
+    },
+    body: `{"name": "{{name}}"}`,
+  }
+
+  res := newResolver(map[string]collVariable{"page": {Key: "page", Value: "2"}})
+  res.dynamic = newSeededDynamicGenerator([32]byte{}, func() time.Time { return time.Unix(1729382400, 0) })
+  resolveRequest(in, res)
+
+  if want := "https://fontseca.dev/users/%7B%7Buser_id%7D%7D?page=2&at=1729382400"; want != in.target.String() {
+    t.Errorf("in.target = %q, want %q", in.target.String(), want)
+  }
+
+  wantHeader := http.Header{
+    "Authorization": {"Bearer {{token}}"},
+    "X-Page":        {"2"},
+    "X-Sent-At":     {"2024-10-20T00:00:00.000Z"},
+  }
+
+  if !reflect.DeepEqual(wantHeader, in.header) {
+    t.Errorf("in.header = %v, want %v", in.header, wantHeader)
+  }
+
+  wantWarnings := []string{"unresolved variable {{user_id}}", "unresolved variable {{token}}", "unresolved variable {{name}}"}
+
+  if !reflect.DeepEqual(wantWarnings, res.warnings) {
+    t.Errorf("warnings = %q, want %q", res.warnings, wantWarnings)
+  }
+}
+
+func TestResolveRequest_escapedBraces(t *testing.T) {
+  tests := []struct {
+    target, want string
+  }{
+    {"https://fontseca.dev/search?q=%7Bx%7D&page={{page}}", "https://fontseca.dev/search?q=%7Bx%7D&page=2"},
+    {"https://fontseca.dev/files/%7Bid%7D/{{user id}}", "https://fontseca.dev/files/%7Bid%7D/7"},
+    {"https://fontseca.dev/files/{x}/{{page}}", "https://fontseca.dev/files/%7Bx%7D/2"},
+    {"https://fontseca.dev/files/{{not a/reference}}", "https://fontseca.dev/files/%7B%7Bnot%20a/reference%7D%7D"},
+  }
+
+  for _, test := range tests {
+    target, _ := url.Parse(test.target)
+    in := &request{target: target, header: http.Header{}}
+    resolveRequest(in, newResolver(map[string]collVariable{"page": {Key: "page", Value: "2"}, "user id": {Key: "user id", Value: "7"}}))
+
+    if test.want != in.target.String() {
+      t.Errorf("resolveRequest(%q) target = %q, want %q", test.target, in.target.String(), test.want)
+    }
+  }
+}
+
+func TestResolveRequest_rawTarget(t *testing.T) {
+  variables := map[string]collVariable{
+    "host": {Key: "host", Value: "fontseca.dev"},
+    "port": {Key: "port", Value: "8080"},
+    "bad":  {Key: "bad", Value: "%zz"},
+  }
+
+  tests := []struct {
+    target, want, err string
+  }{
+    {"https://{{host}}/users", "https://fontseca.dev/users", ""},
+    {"https://{{host}}:{{port}}/users/{{id}}", "https://fontseca.dev:8080/users/%7B%7Bid%7D%7D", ""},
+    {"https://fontseca.dev:{{bad}}/users", "", "resolved target `https://fontseca.dev:%zz/users` is not a valid URL"},
+  }
+
+  for _, test := range tests {
+    target, rawTarget, err := parseTarget(test.target)
+    if nil != err {
+      t.Fatalf("parseTarget(%q) err = %v", test.target, err)
+    }
+
+    if test.target != rawTarget {
+      t.Errorf("parseTarget(%q) raw = %q, want %[1]q", test.target, rawTarget)
+    }
+
+    in := &request{target: target, rawTarget: rawTarget, header: http.Header{}}
+    err = resolveRequest(in, newResolver(variables))
+    if "" != test.err {
+      if nil == err || test.err != err.Error() {
+        t.Errorf("resolveRequest(%q) err = %v, want %q", test.target, err, test.err)
+      }
+      continue
+    }
+
+    if nil != err || test.want != in.target.String() || "" != in.rawTarget {
+      t.Errorf("resolveRequest(%q) = %q, %q, %v, want %q", test.target, in.target.String(), in.rawTarget, err, test.want)
+    }
+  }
+}
+
+func TestParseTarget_invalid(t *testing.T) {
+  if _, _, err := parseTarget("https://fontseca.dev:port/users"); nil == err {
+    t.Error("parseTarget(...) err = nil, want an error")
+  }
+}
+
+func TestSubstitutePathVariables(t *testing.T) {
+  tests := []struct {
+    target    string
+    variables map[string]string
+    want      string
+  }{
+    {"https://fontseca.dev/users/:id", nil, "https://fontseca.dev/users/:id"},
+    {"https://fontseca.dev/users/:id/posts/:postId?sort=:id", map[string]string{"id": "5", "postId": "12"}, "https://fontseca.dev/users/5/posts/12?sort=:id"},
+    {"https://fontseca.dev:8080/users/:id", map[string]string{"id": "a b/c?d#e"}, "https://fontseca.dev:8080/users/a%20b%2Fc%3Fd%23e"},
+    {"https://fontseca.dev/users/:id/:unknown", map[string]string{"id": "ñ"}, "https://fontseca.dev/users/%C3%B1/:unknown"},
+    {"https://fontseca.dev/files/a%2Fb/:name", map[string]string{"name": "c"}, "https://fontseca.dev/files/a%2Fb/c"},
+    {"https://fontseca.dev/users/:id", map[string]string{"id": ""}, "https://fontseca.dev/users/"},
+  }
+
+  for _, test := range tests {
+    target, _ := url.Parse(test.target)
+    substitutePathVariables(target, test.variables)
+
+    if test.want != target.String() {
+      t.Errorf("substitutePathVariables(%q, %v) = %q, want %q", test.target, test.variables, target.String(), test.want)
+    }
+  }
+}
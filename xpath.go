@@ -0,0 +1,1373 @@
+package playground
+
+import (
+  "bytes"
+  "cmp"
+  "context"
+  "encoding/xml"
+  "errors"
+  "fmt"
+  "html"
+  "io"
+  "math"
+  "slices"
+  "strconv"
+  "strings"
+  "unicode/utf8"
+  xhtml "golang.org/x/net/html"
+)
+
+// xmlNodeKind tells apart the kinds of nodes of an XML or HTML document.
+type xmlNodeKind int
+
+const (
+  documentNode xmlNodeKind = iota
+  elementNode
+  attributeNode
+  textNode
+  commentNode
+)
+
+// An xmlNode is a node of the tree of an XML or HTML document, which XPath expressions are evaluated on.
+type xmlNode struct {
+  kind     xmlNodeKind
+  name     string // The local name of an element or an attribute.
+  prefix   string // The namespace prefix of the name of an element or an attribute, which XPath ignores.
+  value    string // The value of an attribute, a text or a comment.
+  order    int    // The position of the node in the document.
+  parent   *xmlNode
+  attr     []*xmlNode
+  children []*xmlNode
+  xmlns    []xml.Attr // The namespace declarations of an element, which are kept out of its attributes.
+  all      []*xmlNode // The nodes of the document, attributes aside, in document order; only set on the document node.
+}
+
+// text returns the string value of n: the text it contains if it is an element or a document, and its
+// value otherwise.
+func (n *xmlNode) text() string {
+  if elementNode != n.kind && documentNode != n.kind {
+    return n.value
+  }
+
+  var text strings.Builder
+  var collect func(n *xmlNode)
+  collect = func(n *xmlNode) {
+    for child := range slices.Values(n.children) {
+      switch child.kind {
+      case textNode:
+        text.WriteString(child.value)
+      case elementNode:
+        collect(child)
+      }
+    }
+  }
+
+  collect(n)
+  return text.String()
+}
+
+// qualifiedName returns the name of n along with its prefix, if any, E.g: xs:element.
+func (n *xmlNode) qualifiedName() string {
+  if "" == n.prefix {
+    return n.name
+  }
+
+  return fmt.Sprint(n.prefix, ":", n.name)
+}
+
+// number gives every node of the tree of n, the document node, its position in the document, and
+// lists them in that order.
+func (n *xmlNode) number() {
+  order := 0
+  var visit func(node *xmlNode)
+  visit = func(node *xmlNode) {
+    node.order, order = order, order+1
+    n.all = append(n.all, node)
+    for a := range slices.Values(node.attr) {
+      a.order, order = order, order+1
+    }
+
+    for child := range slices.Values(node.children) {
+      visit(child)
+    }
+  }
+
+  visit(n)
+}
+
+// root returns the document node of the tree of n.
+func (n *xmlNode) root() *xmlNode {
+  for nil != n.parent {
+    n = n.parent
+  }
+
+  return n
+}
+
+// subtree returns n and the nodes it contains, attributes aside, in document order. They are a part
+// of the nodes of the document, which must not be changed.
+func (n *xmlNode) subtree() []*xmlNode {
+  if attributeNode == n.kind {
+    return []*xmlNode{n}
+  }
+
+  last := n
+  for len(last.children) > 0 {
+    last = last.children[len(last.children)-1]
+  }
+
+  all := n.root().all
+  return all[n.index(all) : last.index(all)+1]
+}
+
+// descendants returns the nodes contained in n, attributes aside, in document order. They are a part
+// of the nodes of the document, which must not be changed.
+func (n *xmlNode) descendants() []*xmlNode {
+  return n.subtree()[1:]
+}
+
+// index returns the position in all, the nodes of the document in document order, of the first node
+// that is not before n.
+func (n *xmlNode) index(all []*xmlNode) int {
+  index, _ := slices.BinarySearchFunc(all, n.order, func(node *xmlNode, order int) int { return cmp.Compare(node.order, order) })
+  return index
+}
+
+// markup writes n back as XML, or as HTML if asHTML is true, where void elements have no end tag
+// and the text of scripts and styles is not escaped. Attributes and texts are written as their value.
+func (n *xmlNode) markup(asHTML bool) string {
+  var out strings.Builder
+  escape := func(text string) string {
+    if asHTML {
+      return html.EscapeString(text)
+    }
+
+    var escaped bytes.Buffer
+    xml.EscapeText(&escaped, []byte(text))
+    return escaped.String()
+  }
+
+  var write func(n *xmlNode, raw bool)
+  write = func(n *xmlNode, raw bool) {
+    switch n.kind {
+    case documentNode:
+      for child := range slices.Values(n.children) {
+        write(child, false)
+      }
+    case textNode:
+      if raw {
+        out.WriteString(n.value)
+      } else {
+        out.WriteString(escape(n.value))
+      }
+    case commentNode:
+      out.WriteString(fmt.Sprint("<!--", n.value, "-->"))
+    case elementNode:
+      out.WriteString(fmt.Sprint("<", n.qualifiedName()))
+      for a := range slices.Values(n.xmlns) {
+        name := a.Name.Local
+        if "" != a.Name.Space {
+          name = fmt.Sprint(a.Name.Space, ":", a.Name.Local)
+        }
+
+        out.WriteString(fmt.Sprintf(` %s="%s"`, name, html.EscapeString(a.Value)))
+      }
+
+      for a := range slices.Values(n.attr) {
+        out.WriteString(fmt.Sprintf(` %s="%s"`, a.qualifiedName(), html.EscapeString(a.value)))
+      }
+
+      _, void := selfClosingTags[n.name]
+      switch {
+      case asHTML && void:
+        out.WriteString(">")
+        return
+      case !asHTML && 0 == len(n.children):
+        out.WriteString("/>")
+        return
+      }
+
+      out.WriteString(">")
+      for child := range slices.Values(n.children) {
+        write(child, asHTML && ("script" == n.name || "style" == n.name))
+      }
+
+      out.WriteString(fmt.Sprint("</", n.qualifiedName(), ">"))
+    }
+  }
+
+  if attributeNode == n.kind {
+    return n.value
+  }
+
+  write(n, false)
+  return out.String()
+}
+
+// parseXMLNodes reads an XML document into a tree of nodes. The namespaces of the names are dropped
+// by XPath, but kept to write the nodes back.
+func parseXMLNodes(data []byte) (*xmlNode, error) {
+  root := &xmlNode{kind: documentNode}
+  current := root
+  decoder := xml.NewDecoder(bytes.NewReader(data))
+  decoder.Strict = false
+
+  for {
+    token, err := decoder.RawToken()
+    if io.EOF == err {
+      break
+    }
+
+    if nil != err {
+      return nil, err
+    }
+
+    switch token := token.(type) {
+    case xml.StartElement:
+      element := &xmlNode{kind: elementNode, name: token.Name.Local, prefix: token.Name.Space, parent: current}
+      for a := range slices.Values(token.Attr) {
+        if "xmlns" == a.Name.Space || "xmlns" == a.Name.Local && "" == a.Name.Space {
+          element.xmlns = append(element.xmlns, a)
+          continue
+        }
+
+        element.attr = append(element.attr, &xmlNode{kind: attributeNode, name: a.Name.Local, prefix: a.Name.Space, value: a.Value, parent: element})
+      }
+
+      current.children = append(current.children, element)
+      current = element
+    case xml.EndElement:
+      if nil != current.parent {
+        current = current.parent
+      }
+    case xml.CharData:
+      current.children = append(current.children, &xmlNode{kind: textNode, value: string(token), parent: current})
+    case xml.Comment:
+      current.children = append(current.children, &xmlNode{kind: commentNode, value: string(token), parent: current})
+    }
+  }
+
+  if !slices.ContainsFunc(root.children, func(n *xmlNode) bool { return elementNode == n.kind }) {
+    return nil, errors.New("the document has no root element")
+  }
+
+  root.number()
+  return root, nil
+}
+
+// parseHTMLNodes reads an HTML document into a tree of nodes, the way browsers do.
+func parseHTMLNodes(data []byte) (*xmlNode, error) {
+  document, err := xhtml.Parse(bytes.NewReader(data))
+  if nil != err {
+    return nil, err
+  }
+
+  var convert func(from *xhtml.Node, to *xmlNode)
+  convert = func(from *xhtml.Node, to *xmlNode) {
+    for child := from.FirstChild; nil != child; child = child.NextSibling {
+      switch child.Type {
+      case xhtml.ElementNode:
+        element := &xmlNode{kind: elementNode, name: child.Data, parent: to}
+        for a := range slices.Values(child.Attr) {
+          element.attr = append(element.attr, &xmlNode{kind: attributeNode, name: a.Key, value: a.Val, parent: element})
+        }
+
+        to.children = append(to.children, element)
+        convert(child, element)
+      case xhtml.TextNode:
+        to.children = append(to.children, &xmlNode{kind: textNode, value: child.Data, parent: to})
+      case xhtml.CommentNode:
+        to.children = append(to.children, &xmlNode{kind: commentNode, value: child.Data, parent: to})
+      }
+    }
+  }
+
+  root := &xmlNode{kind: documentNode}
+  convert(document, root)
+  root.number()
+  return root, nil
+}
+
+// An xpathExpr is a parsed XPath 1.0 expression. Its value is a node-set ([]*xmlNode, in document
+// order), a string, a number (float64) or a boolean.
+type xpathExpr interface {
+  eval(c xpathContext) (any, error)
+}
+
+// An xpathContext is the node that an expression is evaluated on, along with its position, starting
+// at 1, among the size nodes that are being filtered, and the budget of the evaluation.
+type xpathContext struct {
+  node           *xmlNode
+  position, size int
+  budget         *xpathBudget
+}
+
+// maxXPathNodes is the number of nodes that the evaluation of an expression may go through.
+const maxXPathNodes = 16 << 20
+
+// An xpathBudget bounds the work of the evaluation of an expression, since a short one such as
+// //*/preceding::* goes through a number of nodes that grows with the square of the size of the
+// document.
+type xpathBudget struct {
+  ctx   context.Context // The evaluation stops when ctx is done.
+  nodes int             // The nodes that the evaluation may still go through.
+}
+
+// newXPathBudget returns the budget of an evaluation that runs until ctx is done.
+func newXPathBudget(ctx context.Context) *xpathBudget {
+  return &xpathBudget{ctx: ctx, nodes: maxXPathNodes}
+}
+
+// spend counts n nodes gone through, and fails once there are too many of them or the context of
+// the evaluation is done.
+func (b *xpathBudget) spend(n int) error {
+  if b.nodes -= n; b.nodes < 0 {
+    return fmt.Errorf("the XPath expression goes through more than %d nodes", maxXPathNodes)
+  }
+
+  return b.ctx.Err()
+}
+
+// An xpathLiteral is a string or a number.
+type xpathLiteral struct {
+  value any
+}
+
+func (l xpathLiteral) eval(xpathContext) (any, error) {
+  return l.value, nil
+}
+
+// An xpathBinary is an operation on two operands: or, and, =, !=, <, <=, >, >=, +, -, *, div, mod or |.
+type xpathBinary struct {
+  operator    string
+  left, right xpathExpr
+}
+
+func (b *xpathBinary) eval(c xpathContext) (any, error) {
+  left, err := b.left.eval(c)
+  if nil != err {
+    return nil, err
+  }
+
+  switch b.operator { /* Short-circuits.  */
+  case "or":
+    if xpathBoolean(left) {
+      return true, nil
+    }
+  case "and":
+    if !xpathBoolean(left) {
+      return false, nil
+    }
+  }
+
+  right, err := b.right.eval(c)
+  if nil != err {
+    return nil, err
+  }
+
+  switch b.operator {
+  case "or", "and":
+    return xpathBoolean(right), nil
+  case "=", "!=", "<", "<=", ">", ">=":
+    return xpathCompare(b.operator, left, right), nil
+  case "|":
+    l, isLeftSet := left.([]*xmlNode)
+    r, isRightSet := right.([]*xmlNode)
+    if !isLeftSet || !isRightSet {
+      return nil, errors.New("the operands of | must be node-sets")
+    }
+
+    return xpathSorted(slices.Concat(l, r)), nil
+  }
+
+  x, y := xpathNumber(left), xpathNumber(right)
+  switch b.operator {
+  case "+":
+    return x + y, nil
+  case "-":
+    return x - y, nil
+  case "*":
+    return x * y, nil
+  case "div":
+    return x / y, nil
+  default:
+    return math.Mod(x, y), nil
+  }
+}
+
+// An xpathNegation is the unary minus.
+type xpathNegation struct {
+  operand xpathExpr
+}
+
+func (n *xpathNegation) eval(c xpathContext) (any, error) {
+  value, err := n.operand.eval(c)
+  if nil != err {
+    return nil, err
+  }
+
+  return -xpathNumber(value), nil
+}
+
+// An xpathPath is a location path, such as //book[@lang='en']/title/text(), made of steps that select
+// nodes from the ones the steps before them selected. It may start from the node-set of a filter
+// expression instead, such as (//a | //b)[1]/@id or id('b1')/title.
+type xpathPath struct {
+  absolute   bool      // Whether the path starts at the root of the document rather than at the context node.
+  filter     xpathExpr // The expression that the path starts from, if any.
+  predicates []xpathExpr
+  steps      []xpathStep
+}
+
+// An xpathStep selects the nodes along an axis of the context node that pass its node test and its
+// predicates.
+type xpathStep struct {
+  axis       string // One of xpathAxes.
+  test       string // A name, *, prefix:*, text(), comment(), processing-instruction() or node().
+  predicates []xpathExpr
+}
+
+// xpathAxes are the axes of XPath 1.0.
+var xpathAxes = []string{
+  "ancestor", "ancestor-or-self", "attribute", "child", "descendant", "descendant-or-self", "following",
+  "following-sibling", "namespace", "parent", "preceding", "preceding-sibling", "self",
+}
+
+func (p *xpathPath) eval(c xpathContext) (any, error) {
+  nodes := []*xmlNode{c.node}
+  switch {
+  case p.absolute:
+    nodes = []*xmlNode{c.node.root()}
+  case nil != p.filter:
+    value, err := p.filter.eval(c)
+    if nil != err {
+      return nil, err
+    }
+
+    set, ok := value.([]*xmlNode)
+    if !ok {
+      if 0 == len(p.predicates) && 0 == len(p.steps) {
+        return value, nil
+      }
+
+      return nil, fmt.Errorf("%s is not a node-set", xpathString(value))
+    }
+
+    if nodes, err = xpathFilter(c.budget, set, p.predicates); nil != err {
+      return nil, err
+    }
+  }
+
+  for step := range slices.Values(p.steps) {
+    var selected []*xmlNode
+    for node := range slices.Values(nodes) {
+      candidates, err := step.eval(c.budget, node)
+      if nil != err {
+        return nil, err
+      }
+
+      selected = append(selected, candidates...)
+    }
+
+    nodes = xpathSorted(selected)
+  }
+
+  return nodes, nil
+}
+
+// eval returns the nodes that s selects from context, in the order of its axis, which is backwards
+// for the ancestor and preceding axes. The nodes of the axis are counted against budget.
+func (s *xpathStep) eval(budget *xpathBudget, context *xmlNode) ([]*xmlNode, error) {
+  var candidates []*xmlNode
+  switch s.axis {
+  case "self":
+    candidates = []*xmlNode{context}
+  case "parent":
+    if nil != context.parent {
+      candidates = []*xmlNode{context.parent}
+    }
+  case "ancestor", "ancestor-or-self":
+    if "ancestor-or-self" == s.axis {
+      candidates = append(candidates, context)
+    }
+
+    for ancestor := context.parent; nil != ancestor; ancestor = ancestor.parent {
+      candidates = append(candidates, ancestor)
+    }
+  case "attribute":
+    candidates = context.attr
+  case "child":
+    candidates = context.children
+  case "descendant":
+    candidates = context.descendants()
+  case "descendant-or-self":
+    candidates = context.subtree()
+  case "following-sibling", "preceding-sibling":
+    if nil == context.parent || attributeNode == context.kind {
+      break
+    }
+
+    siblings := context.parent.children
+    n := slices.Index(siblings, context)
+    if "following-sibling" == s.axis {
+      candidates = siblings[n+1:]
+    } else {
+      candidates = slices.Clone(siblings[:n])
+      slices.Reverse(candidates)
+    }
+  case "following":
+    all := context.root().all
+    start := context.index(all) /* The first node after an attribute, or after the subtree of the context.  */
+    if attributeNode != context.kind {
+      subtree := context.subtree()
+      start = subtree[len(subtree)-1].index(all) + 1
+    }
+
+    candidates = all[start:]
+  case "preceding":
+    all := context.root().all
+    before := all[:context.index(all)]
+    if err := budget.spend(len(before)); nil != err {
+      return nil, err
+    }
+
+    ancestor := context.parent /* The ancestors come up in reverse document order.  */
+    for n := len(before) - 1; n >= 0; n-- {
+      if before[n] == ancestor {
+        ancestor = ancestor.parent
+        continue
+      }
+
+      candidates = append(candidates, before[n])
+    }
+  }
+
+  if err := budget.spend(len(candidates)); nil != err {
+    return nil, err
+  }
+
+  var nodes []*xmlNode
+  for candidate := range slices.Values(candidates) {
+    if s.matches(candidate) {
+      nodes = append(nodes, candidate)
+    }
+  }
+
+  return xpathFilter(budget, nodes, s.predicates)
+}
+
+// matches reports whether node passes the node test of s.
+func (s *xpathStep) matches(node *xmlNode) bool {
+  switch s.test {
+  case "node()":
+    return true
+  case "text()":
+    return textNode == node.kind
+  case "comment()":
+    return commentNode == node.kind
+  case "processing-instruction()":
+    return false
+  }
+
+  principal := elementNode
+  if "attribute" == s.axis {
+    principal = attributeNode
+  }
+
+  if principal != node.kind {
+    return false
+  }
+
+  _, local, found := strings.Cut(s.test, ":") /* Prefixes are ignored, along with namespaces.  */
+  if !found {
+    local = s.test
+  }
+
+  return "*" == local || local == node.name
+}
+
+// xpathFilter returns the nodes that pass all the predicates, each of which is given the nodes that
+// passed the one before it. A predicate whose value is a number keeps the node at that position.
+func xpathFilter(budget *xpathBudget, nodes []*xmlNode, predicates []xpathExpr) ([]*xmlNode, error) {
+  for predicate := range slices.Values(predicates) {
+    if err := budget.spend(len(nodes)); nil != err {
+      return nil, err
+    }
+
+    var passed []*xmlNode
+    for n, node := range nodes {
+      value, err := predicate.eval(xpathContext{node: node, position: n + 1, size: len(nodes), budget: budget})
+      if nil != err {
+        return nil, err
+      }
+
+      if number, ok := value.(float64); ok && number == float64(n+1) || !ok && xpathBoolean(value) {
+        passed = append(passed, node)
+      }
+    }
+
+    nodes = passed
+  }
+
+  return nodes, nil
+}
+
+// xpathSorted returns nodes in document order, without duplicates.
+func xpathSorted(nodes []*xmlNode) []*xmlNode {
+  sorted := slices.SortedFunc(slices.Values(nodes), func(a, b *xmlNode) int { return a.order - b.order })
+  return slices.Compact(sorted)
+}
+
+// An xpathCall is a call to a function of the core library of XPath 1.0.
+type xpathCall struct {
+  name string
+  args []xpathExpr
+}
+
+// xpathFunctions are the functions of the core library of XPath 1.0, with the least and the most
+// arguments they take (-1 for any number).
+var xpathFunctions = map[string][2]int{
+  "last": {0, 0}, "position": {0, 0}, "count": {1, 1}, "id": {1, 1}, "local-name": {0, 1},
+  "namespace-uri": {0, 1}, "name": {0, 1}, "string": {0, 1}, "concat": {2, -1}, "starts-with": {2, 2},
+  "contains": {2, 2}, "substring-before": {2, 2}, "substring-after": {2, 2}, "substring": {2, 3},
+  "string-length": {0, 1}, "normalize-space": {0, 1}, "translate": {3, 3}, "boolean": {1, 1},
+  "not": {1, 1}, "true": {0, 0}, "false": {0, 0}, "lang": {1, 1}, "number": {0, 1}, "sum": {1, 1},
+  "floor": {1, 1}, "ceiling": {1, 1}, "round": {1, 1},
+}
+
+func (f *xpathCall) eval(c xpathContext) (any, error) {
+  args := make([]any, len(f.args))
+  for n, arg := range f.args {
+    value, err := arg.eval(c)
+    if nil != err {
+      return nil, err
+    }
+
+    args[n] = value
+  }
+
+  /* Most functions that take one argument default to the context node.  */
+  if 0 == len(args) && slices.Contains([]string{"local-name", "namespace-uri", "name", "string", "string-length", "normalize-space", "number"}, f.name) {
+    args = []any{[]*xmlNode{c.node}}
+  }
+
+  nodes := func(n int) ([]*xmlNode, error) {
+    set, ok := args[n].([]*xmlNode)
+    if !ok {
+      return nil, fmt.Errorf("%s() needs a node-set, got %s", f.name, xpathString(args[n]))
+    }
+
+    return set, nil
+  }
+
+  switch f.name {
+  case "last":
+    return float64(c.size), nil
+  case "position":
+    return float64(c.position), nil
+  case "count":
+    set, err := nodes(0)
+    return float64(len(set)), err
+  case "id":
+    var ids []string
+    if set, ok := args[0].([]*xmlNode); ok {
+      for node := range slices.Values(set) {
+        ids = append(ids, strings.Fields(node.text())...)
+      }
+    } else {
+      ids = strings.Fields(xpathString(args[0]))
+    }
+
+    all := c.node.root().all
+    if err := c.budget.spend(len(all)); nil != err {
+      return nil, err
+    }
+
+    var found []*xmlNode
+    for node := range slices.Values(all) {
+      if elementNode == node.kind && slices.ContainsFunc(node.attr, func(a *xmlNode) bool { return "id" == a.name && slices.Contains(ids, a.value) }) {
+        found = append(found, node)
+      }
+    }
+
+    return found, nil
+  case "local-name", "name", "namespace-uri":
+    set, err := nodes(0)
+    if nil != err || 0 == len(set) || "namespace-uri" == f.name {
+      return "", err
+    }
+
+    if "name" == f.name {
+      return set[0].qualifiedName(), nil
+    }
+
+    return set[0].name, nil
+  case "string":
+    return xpathString(args[0]), nil
+  case "concat":
+    var text strings.Builder
+    for arg := range slices.Values(args) {
+      text.WriteString(xpathString(arg))
+    }
+
+    return text.String(), nil
+  case "starts-with":
+    return strings.HasPrefix(xpathString(args[0]), xpathString(args[1])), nil
+  case "contains":
+    return strings.Contains(xpathString(args[0]), xpathString(args[1])), nil
+  case "substring-before":
+    before, _, _ := strings.Cut(xpathString(args[0]), xpathString(args[1]))
+    if !strings.Contains(xpathString(args[0]), xpathString(args[1])) {
+      return "", nil
+    }
+
+    return before, nil
+  case "substring-after":
+    _, after, _ := strings.Cut(xpathString(args[0]), xpathString(args[1]))
+    return after, nil
+  case "substring":
+    runes := []rune(xpathString(args[0]))
+    start, end := xpathRound(xpathNumber(args[1])), math.Inf(1)
+    if 3 == len(args) {
+      end = start + xpathRound(xpathNumber(args[2]))
+    }
+
+    var text strings.Builder
+    for n, r := range runes {
+      if position := float64(n + 1); position >= start && position < end {
+        text.WriteRune(r)
+      }
+    }
+
+    return text.String(), nil
+  case "string-length":
+    return float64(utf8.RuneCountInString(xpathString(args[0]))), nil
+  case "normalize-space":
+    return strings.Join(strings.Fields(xpathString(args[0])), " "), nil
+  case "translate":
+    from, to := []rune(xpathString(args[1])), []rune(xpathString(args[2]))
+    return strings.Map(func(r rune) rune {
+      n := slices.Index(from, r)
+      switch {
+      case -1 == n:
+        return r
+      case n < len(to):
+        return to[n]
+      default:
+        return -1
+      }
+    }, xpathString(args[0])), nil
+  case "boolean":
+    return xpathBoolean(args[0]), nil
+  case "not":
+    return !xpathBoolean(args[0]), nil
+  case "true":
+    return true, nil
+  case "false":
+    return false, nil
+  case "lang":
+    want := strings.ToLower(xpathString(args[0]))
+    for node := c.node; nil != node; node = node.parent {
+      for a := range slices.Values(node.attr) {
+        if "lang" == a.name {
+          lang := strings.ToLower(a.value)
+          return want == lang || strings.HasPrefix(lang, want+"-"), nil
+        }
+      }
+    }
+
+    return false, nil
+  case "number":
+    return xpathNumber(args[0]), nil
+  case "sum":
+    set, err := nodes(0)
+    sum := 0.0
+    for node := range slices.Values(set) {
+      sum += xpathNumber(node.text())
+    }
+
+    return sum, err
+  case "floor":
+    return math.Floor(xpathNumber(args[0])), nil
+  case "ceiling":
+    return math.Ceil(xpathNumber(args[0])), nil
+  default: /* round  */
+    return xpathRound(xpathNumber(args[0])), nil
+  }
+}
+
+// xpathRound rounds x to the closest integer, halves towards positive infinity.
+func xpathRound(x float64) float64 {
+  if math.IsNaN(x) || math.IsInf(x, 0) {
+    return x
+  }
+
+  return math.Floor(x + 0.5)
+}
+
+// xpathString converts a value to a string: the string value of the first node of a node-set, or
+// the written form of a number or a boolean.
+func xpathString(value any) string {
+  switch value := value.(type) {
+  case []*xmlNode:
+    if 0 == len(value) {
+      return ""
+    }
+
+    return value[0].text()
+  case float64:
+    switch {
+    case math.IsNaN(value):
+      return "NaN"
+    case math.IsInf(value, 1):
+      return "Infinity"
+    case math.IsInf(value, -1):
+      return "-Infinity"
+    case 0 == value:
+      return "0"
+    }
+
+    return strconv.FormatFloat(value, 'f', -1, 64)
+  case bool:
+    return strconv.FormatBool(value)
+  case string:
+    return value
+  }
+
+  return ""
+}
+
+// xpathNumber converts a value to a number, which is NaN if it is a string that is not one.
+func xpathNumber(value any) float64 {
+  switch value := value.(type) {
+  case float64:
+    return value
+  case bool:
+    if value {
+      return 1
+    }
+
+    return 0
+  }
+
+  text := strings.TrimSpace(xpathString(value))
+  if "" == text || strings.ContainsAny(text, "eE+xXnN") { /* Not in the grammar of XPath numbers.  */
+    return math.NaN()
+  }
+
+  number, err := strconv.ParseFloat(text, 64)
+  if nil != err {
+    return math.NaN()
+  }
+
+  return number
+}
+
+// xpathBoolean converts a value to a boolean: a non-empty node-set or string, or a number other than
+// zero and NaN.
+func xpathBoolean(value any) bool {
+  switch value := value.(type) {
+  case []*xmlNode:
+    return len(value) > 0
+  case float64:
+    return 0 != value && !math.IsNaN(value)
+  case string:
+    return "" != value
+  case bool:
+    return value
+  }
+
+  return false
+}
+
+// xpathCompare compares two values the way XPath 1.0 does: a node-set passes if any of its nodes
+// does; otherwise = and != compare booleans, numbers or strings, the first type that either value
+// has, and the other operators compare numbers.
+func xpathCompare(operator string, left, right any) bool {
+  if set, ok := left.([]*xmlNode); ok {
+    if _, isBoolean := right.(bool); isBoolean {
+      return xpathCompare(operator, len(set) > 0, right)
+    }
+
+    return slices.ContainsFunc(set, func(n *xmlNode) bool {
+      if other, ok := right.([]*xmlNode); ok {
+        return slices.ContainsFunc(other, func(m *xmlNode) bool { return xpathCompare(operator, n.text(), m.text()) })
+      }
+
+      if _, isNumber := right.(float64); isNumber {
+        return xpathCompare(operator, xpathNumber(n.text()), right)
+      }
+
+      return xpathCompare(operator, n.text(), right)
+    })
+  }
+
+  if _, ok := right.([]*xmlNode); ok {
+    reversed := map[string]string{"<": ">", "<=": ">=", ">": "<", ">=": "<="}[operator]
+    if "" == reversed {
+      reversed = operator
+    }
+
+    return xpathCompare(reversed, right, left)
+  }
+
+  if "=" == operator || "!=" == operator {
+    var equal bool
+    _, isLeftBoolean := left.(bool)
+    _, isRightBoolean := right.(bool)
+    _, isLeftNumber := left.(float64)
+    _, isRightNumber := right.(float64)
+    switch {
+    case isLeftBoolean || isRightBoolean:
+      equal = xpathBoolean(left) == xpathBoolean(right)
+    case isLeftNumber || isRightNumber:
+      equal = xpathNumber(left) == xpathNumber(right)
+    default:
+      equal = xpathString(left) == xpathString(right)
+    }
+
+    return equal == ("=" == operator)
+  }
+
+  x, y := xpathNumber(left), xpathNumber(right)
+  switch operator {
+  case "<":
+    return x < y
+  case "<=":
+    return x <= y
+  case ">":
+    return x > y
+  default:
+    return x >= y
+  }
+}
+
+// queryXPath returns the value of an XPath 1.0 expression evaluated on root: a node-set, in document
+// order, a string, a number or a boolean.
+func queryXPath(root *xmlNode, expression string) (any, error) {
+  expr, err := parseXPath(expression)
+  if nil != err {
+    return nil, err
+  }
+
+  return expr.eval(xpathContext{node: root, position: 1, size: 1, budget: newXPathBudget(context.Background())})
+}
+
+// evalXPath returns the nodes that the expression selects from root, in document order.
+func evalXPath(root *xmlNode, expression string) ([]*xmlNode, error) {
+  value, err := queryXPath(root, expression)
+  if nil != err {
+    return nil, err
+  }
+
+  nodes, ok := value.([]*xmlNode)
+  if !ok {
+    return nil, fmt.Errorf("XPath %q does not select nodes", expression)
+  }
+
+  return nodes, nil
+}
+
+// parseXPath parses an XPath 1.0 expression, such as /catalog/book[2]/@id,
+// //item[name='pen']/price/text() or count(//book[price > 20]).
+func parseXPath(expression string) (xpathExpr, error) {
+  parser := &xpathParser{input: expression}
+  expr, err := parser.or()
+  if nil != err {
+    return nil, err
+  }
+
+  if parser.skip(); parser.n < len(parser.input) {
+    return nil, parser.fail("unexpected %q", parser.input[parser.n:])
+  }
+
+  return expr, nil
+}
+
+// An xpathParser reads an expression from input, starting at n.
+type xpathParser struct {
+  input string
+  n     int
+}
+
+// fail returns an error about the expression that is being parsed.
+func (p *xpathParser) fail(format string, a ...any) error {
+  return fmt.Errorf("%s in XPath %q", fmt.Sprintf(format, a...), p.input)
+}
+
+// skip skips the whitespace at n.
+func (p *xpathParser) skip() {
+  for p.n < len(p.input) && strings.ContainsRune(" \t\r\n", rune(p.input[p.n])) {
+    p.n++
+  }
+}
+
+// consume skips prefix at n, if it is there, and reports whether it was.
+func (p *xpathParser) consume(prefix string) bool {
+  p.skip()
+  if strings.HasPrefix(p.input[p.n:], prefix) {
+    p.n += len(prefix)
+    return true
+  }
+
+  return false
+}
+
+// peek returns what is left of the input after the whitespace at n.
+func (p *xpathParser) peek() string {
+  p.skip()
+  return p.input[p.n:]
+}
+
+// name reads a name at n, such as book, xs:element or data-id, without skipping a :: after it.
+func (p *xpathParser) name() string {
+  p.skip()
+  start := p.n
+  for p.n < len(p.input) {
+    c := p.input[p.n]
+    if ':' == c && (strings.HasPrefix(p.input[p.n:], "::") || p.n == start) {
+      break
+    }
+
+    if !('a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '_' == c || c >= 0x80 || p.n > start && ('0' <= c && c <= '9' || '-' == c || '.' == c || ':' == c)) {
+      break
+    }
+
+    p.n++
+  }
+
+  return p.input[start:p.n]
+}
+
+// operator reads one of the named operators (and, or, div, mod) at n, if it is there.
+func (p *xpathParser) operator(name string) bool {
+  start := p.n
+  if p.name() == name {
+    return true
+  }
+
+  p.n = start
+  return false
+}
+
+// binary reads operands separated by the operators, with next reading the operands.
+func (p *xpathParser) binary(next func() (xpathExpr, error), operators ...string) (xpathExpr, error) {
+  left, err := next()
+  for nil == err {
+    var operator string
+    for candidate := range slices.Values(operators) {
+      if named := 'a' <= candidate[0] && candidate[0] <= 'z'; named && p.operator(candidate) || !named && p.consume(candidate) {
+        operator = candidate
+        break
+      }
+    }
+
+    if "" == operator {
+      return left, nil
+    }
+
+    var right xpathExpr
+    if right, err = next(); nil == err {
+      left = &xpathBinary{operator: operator, left: left, right: right}
+    }
+  }
+
+  return nil, err
+}
+
+func (p *xpathParser) or() (xpathExpr, error) {
+  return p.binary(p.and, "or")
+}
+
+func (p *xpathParser) and() (xpathExpr, error) {
+  return p.binary(p.equality, "and")
+}
+
+func (p *xpathParser) equality() (xpathExpr, error) {
+  return p.binary(p.relational, "!=", "=")
+}
+
+func (p *xpathParser) relational() (xpathExpr, error) {
+  return p.binary(p.additive, "<=", ">=", "<", ">")
+}
+
+func (p *xpathParser) additive() (xpathExpr, error) {
+  return p.binary(p.multiplicative, "+", "-")
+}
+
+func (p *xpathParser) multiplicative() (xpathExpr, error) {
+  return p.binary(p.unary, "*", "div", "mod")
+}
+
+// unary reads a union, negated by any number of minus signs.
+func (p *xpathParser) unary() (xpathExpr, error) {
+  if p.consume("-") {
+    operand, err := p.unary()
+    if nil != err {
+      return nil, err
+    }
+
+    return &xpathNegation{operand: operand}, nil
+  }
+
+  return p.binary(p.path, "|")
+}
+
+// path reads a location path, or a filter expression followed by a relative location path.
+func (p *xpathParser) path() (xpathExpr, error) {
+  path := &xpathPath{}
+  switch rest := p.peek(); {
+  case "" == rest:
+    return nil, p.fail("missing expression")
+  case strings.HasPrefix(rest, "//"):
+    p.n += 2
+    path.absolute = true
+    path.steps = append(path.steps, xpathStep{axis: "descendant-or-self", test: "node()"})
+  case strings.HasPrefix(rest, "/"):
+    p.n++
+    path.absolute = true
+    if !p.startsStep() {
+      return path, nil /* The root alone.  */
+    }
+  case !p.startsStep():
+    primary, err := p.primary()
+    if nil != err {
+      return nil, err
+    }
+
+    path.filter = primary
+    for p.consume("[") {
+      predicate, err := p.predicate()
+      if nil != err {
+        return nil, err
+      }
+
+      path.predicates = append(path.predicates, predicate)
+    }
+
+    switch {
+    case p.consume("//"):
+      path.steps = append(path.steps, xpathStep{axis: "descendant-or-self", test: "node()"})
+    case p.consume("/"):
+    default:
+      if 0 == len(path.predicates) {
+        return primary, nil
+      }
+
+      return path, nil
+    }
+  }
+
+  for {
+    step, err := p.step()
+    if nil != err {
+      return nil, err
+    }
+
+    path.steps = append(path.steps, step)
+    switch {
+    case p.consume("//"):
+      path.steps = append(path.steps, xpathStep{axis: "descendant-or-self", test: "node()"})
+    case p.consume("/"):
+    default:
+      return path, nil
+    }
+  }
+}
+
+// startsStep reports whether a step, rather than a filter expression, is at n: ., .., @, *, an axis,
+// a node type test, or a name that is not a function name.
+func (p *xpathParser) startsStep() bool {
+  rest := p.peek()
+  switch {
+  case "" == rest:
+    return false
+  case strings.HasPrefix(rest, "."):
+    return !(len(rest) > 1 && '0' <= rest[1] && rest[1] <= '9') /* Not a number such as .5.  */
+  case strings.HasPrefix(rest, "@"), strings.HasPrefix(rest, "*"):
+    return true
+  }
+
+  start := p.n
+  defer func() { p.n = start }()
+  name := p.name()
+  if "" == name {
+    return false
+  }
+
+  if p.consume("(") {
+    return slices.Contains([]string{"node", "text", "comment", "processing-instruction"}, name)
+  }
+
+  return true
+}
+
+// step reads a step along with its predicates.
+func (p *xpathParser) step() (step xpathStep, err error) {
+  switch {
+  case p.consume(".."):
+    return xpathStep{axis: "parent", test: "node()"}, nil
+  case p.consume("."):
+    return xpathStep{axis: "self", test: "node()"}, nil
+  case p.consume("@"):
+    step.axis = "attribute"
+  default:
+    step.axis = "child"
+  }
+
+  start := p.n
+  name := p.name()
+  if "child" == step.axis && "" != name && p.consume("::") {
+    if !slices.Contains(xpathAxes, name) {
+      return step, p.fail("unknown axis %q", name)
+    }
+
+    step.axis, start, name = name, p.n, p.name()
+  }
+
+  switch {
+  case "" == name && p.consume("*"):
+    step.test = "*"
+  case "" == name:
+    if "" == p.peek() {
+      return step, p.fail("missing step")
+    }
+
+    return step, p.fail("unexpected %q", p.input[p.n:])
+  case strings.HasSuffix(name, ":") && p.consume("*"):
+    step.test = fmt.Sprint(name, "*")
+  case slices.Contains([]string{"node", "text", "comment", "processing-instruction"}, name) && p.consume("("):
+    if !p.consume(")") {
+      if _, err := p.literal(); nil != err || "processing-instruction" != name || !p.consume(")") {
+        return step, p.fail("missing ) after %s(", name)
+      }
+    }
+
+    step.test = fmt.Sprint(name, "()")
+  default:
+    if p.consume("(") {
+      p.n = start
+      return step, p.fail("unexpected %q", p.input[p.n:])
+    }
+
+    step.test = name
+  }
+
+  if "namespace" == step.axis {
+    return step, p.fail("the namespace axis is not supported")
+  }
+
+  for p.consume("[") {
+    predicate, err := p.predicate()
+    if nil != err {
+      return step, err
+    }
+
+    step.predicates = append(step.predicates, predicate)
+  }
+
+  return step, nil
+}
+
+// predicate reads what is between the brackets of a predicate, after the [.
+func (p *xpathParser) predicate() (xpathExpr, error) {
+  predicate, err := p.or()
+  if nil != err {
+    return nil, err
+  }
+
+  if !p.consume("]") {
+    return nil, p.fail("unclosed [")
+  }
+
+  return predicate, nil
+}
+
+// primary reads a parenthesized expression, a literal, a number or a function call.
+func (p *xpathParser) primary() (xpathExpr, error) {
+  rest := p.peek()
+  switch {
+  case strings.HasPrefix(rest, "("):
+    p.n++
+    expr, err := p.or()
+    if nil != err {
+      return nil, err
+    }
+
+    if !p.consume(")") {
+      return nil, p.fail("missing )")
+    }
+
+    return expr, nil
+  case strings.HasPrefix(rest, "'"), strings.HasPrefix(rest, `"`):
+    text, err := p.literal()
+    return xpathLiteral{value: text}, err
+  case strings.HasPrefix(rest, "$"):
+    return nil, p.fail("variables are not supported")
+  case '0' <= rest[0] && rest[0] <= '9' || '.' == rest[0]:
+    start := p.n
+    for p.n < len(p.input) && strings.ContainsRune("0123456789.", rune(p.input[p.n])) {
+      p.n++
+    }
+
+    number, err := strconv.ParseFloat(p.input[start:p.n], 64)
+    if nil != err {
+      return nil, p.fail("invalid number %q", p.input[start:p.n])
+    }
+
+    return xpathLiteral{value: number}, nil
+  }
+
+  start := p.n
+  name := p.name()
+  if "" == name || !p.consume("(") {
+    p.n = start
+    return nil, p.fail("unexpected %q", p.input[p.n:])
+  }
+
+  arity, exists := xpathFunctions[name]
+  if !exists {
+    return nil, p.fail("unknown function %s()", name)
+  }
+
+  call := &xpathCall{name: name}
+  if !p.consume(")") {
+    for {
+      arg, err := p.or()
+      if nil != err {
+        return nil, err
+      }
+
+      call.args = append(call.args, arg)
+      if p.consume(")") {
+        break
+      }
+
+      if !p.consume(",") {
+        return nil, p.fail("missing ) after %s(", name)
+      }
+    }
+  }
+
+  if len(call.args) < arity[0] || -1 != arity[1] && len(call.args) > arity[1] {
+    return nil, p.fail("wrong number of arguments to %s()", name)
+  }
+
+  return call, nil
+}
+
+// literal reads a string between single or double quotes.
+func (p *xpathParser) literal() (string, error) {
+  rest := p.peek()
+  if "" == rest || '\'' != rest[0] && '"' != rest[0] {
+    return "", p.fail("missing string")
+  }
+
+  end := strings.IndexByte(rest[1:], rest[0])
+  if -1 == end {
+    return "", p.fail("unclosed string")
+  }
+
+  p.n += end + 2
+  return rest[1 : end+1], nil
+}
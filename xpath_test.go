@@ -0,0 +1,228 @@
+package playground
+
+import (
+  "context"
+  "reflect"
+  "strings"
+  "testing"
+)
+
+func TestEvalXPath(t *testing.T) {
+  root, err := parseXMLNodes([]byte(`<?xml version="1.0"?>
+<catalog xmlns="urn:books">
+  <book id="b1" lang="en"><title>Go</title><price>30</price></book>
+  <book id="b2" lang="es"><title>Rust</title><price>25.0</price></book>
+  <!-- Out of stock. -->
+  <book id="b3" lang="en"><title>Zig</title></book>
+</catalog>`))
+
+  if nil != err {
+    t.Fatalf("unexpected error: %s", err)
+  }
+
+  tests := []struct {
+    path string
+    want []string // The string values of the nodes.
+    err  string
+  }{
+    {"/catalog/book/title", []string{"Go", "Rust", "Zig"}, ""},
+    {"//title/text()", []string{"Go", "Rust", "Zig"}, ""},
+    {"/catalog/book[2]/@id", []string{"b2"}, ""},
+    {"//book[last()]/title", []string{"Zig"}, ""},
+    {"//book[@lang='en'][2]/title", []string{"Zig"}, ""},
+    {`//book[title="Rust"]/@lang`, []string{"es"}, ""},
+    {"//book[price=25]/title", []string{"Rust"}, ""},
+    {"//book[price]/@id", []string{"b1", "b2"}, ""},
+    {"//book[@lang!='en']/title", []string{"Rust"}, ""},
+    {"//price/../title", []string{"Go", "Rust"}, ""},
+    {"/catalog/*[3]/title/.", []string{"Zig"}, ""},
+    {"//comment()", []string{" Out of stock. "}, ""},
+    {"//book[4]", nil, ""},
+    {"/catalog/book[1]", []string{"Go30"}, ""},
+    {"//book[0]", nil, ""},
+    {"//book[@id=b1]", nil, ""},
+    {"//book[position() > 1 and price]/@id", []string{"b2"}, ""},
+    {"//book[price > 26 or title = 'Zig']/@id", []string{"b1", "b3"}, ""},
+    {"//book[price < 30]/title | //book[not(price)]/title", []string{"Rust", "Zig"}, ""},
+    {"(//title)[last() - 1]", []string{"Rust"}, ""},
+    {"(//book/@id)[2]/../title", []string{"Rust"}, ""},
+    {"//title[starts-with(., 'R') or contains(., 'ig')]", []string{"Rust", "Zig"}, ""},
+    {"//title[string-length() = 2]", []string{"Go"}, ""},
+    {"//book[@id = id('b2')/@id]/title", []string{"Rust"}, ""},
+    {"//price/ancestor::book/@id", []string{"b1", "b2"}, ""},
+    {"//title[.='Zig']/ancestor-or-self::*[2]/@id", []string{"b3"}, ""},
+    {"//book[1]/following-sibling::book/@id", []string{"b2", "b3"}, ""},
+    {"//book[3]/preceding-sibling::book[1]/@id", []string{"b2"}, ""},
+    {"//book[2]/following::title", []string{"Zig"}, ""},
+    {"//book[2]/preceding::*", []string{"Go30", "Go", "30"}, ""},
+    {"//book[3]/preceding::*[1]", []string{"25.0"}, ""},
+    {"//book[2]/@id/following::title", []string{"Rust", "Zig"}, ""},
+    {"//price/descendant-or-self::node()", []string{"30", "30", "25.0", "25.0"}, ""},
+    {"/descendant::price[2]/self::price", []string{"25.0"}, ""},
+    {"//book/child::*[local-name() = 'price']", []string{"30", "25.0"}, ""},
+    {"//book[@*[. = 'es']]/title", []string{"Rust"}, ""},
+    {"//xs:title[1]", []string{"Go", "Rust", "Zig"}, ""},
+    {"//book[", nil, `missing expression in XPath "//book["`},
+    {"//book[1", nil, `unclosed [ in XPath "//book[1"`},
+    {"//book[@id=]", nil, `unexpected "]" in XPath "//book[@id=]"`},
+    {"//book[@id='b1]", nil, `unclosed string in XPath "//book[@id='b1]"`},
+    {"//text(", nil, `missing ) after text( in XPath "//text("`},
+    {"//book)", nil, `unexpected ")" in XPath "//book)"`},
+    {"//book/sibling::title", nil, `unknown axis "sibling" in XPath "//book/sibling::title"`},
+    {"//book[matches(title, 'R')]", nil, `unknown function matches() in XPath "//book[matches(title, 'R')]"`},
+    {"//book[contains(title)]", nil, `wrong number of arguments to contains() in XPath "//book[contains(title)]"`},
+    {"count(//book)", nil, `XPath "count(//book)" does not select nodes`},
+    {"'a'/title", nil, "a is not a node-set"},
+  }
+
+  for _, test := range tests {
+    nodes, err := evalXPath(root, test.path)
+    if "" != test.err {
+      if nil == err || test.err != err.Error() {
+        t.Errorf("evalXPath(%q) error = %v, want %q", test.path, err, test.err)
+      }
+
+      continue
+    }
+
+    if nil != err {
+      t.Errorf("evalXPath(%q): unexpected error: %s", test.path, err)
+      continue
+    }
+
+    var got []string
+    for _, node := range nodes {
+      got = append(got, node.text())
+    }
+
+    if !reflect.DeepEqual(test.want, got) {
+      t.Errorf("evalXPath(%q) = %q, want %q", test.path, got, test.want)
+    }
+  }
+}
+
+func TestQueryXPath(t *testing.T) {
+  root, err := parseXMLNodes([]byte(`<order><item qty="2">4.5</item><item qty="1">10</item><note>  Leave at the   door </note></order>`))
+  if nil != err {
+    t.Fatalf("unexpected error: %s", err)
+  }
+
+  tests := []struct {
+    expression string
+    want       any
+  }{
+    {"count(//item)", 2.0},
+    {"sum(//item) * 2", 29.0},
+    {"sum(//item/@qty) div 4", 0.75},
+    {"7 mod 3 + -1", 0.0},
+    {"//item[1] < //item[2]", true},
+    {"//item = 10", true},
+    {"//item != 10", true},
+    {"not(//missing) and true()", true},
+    {"//item/@qty = '3'", false},
+    {"normalize-space(//note)", "Leave at the door"},
+    {"concat(name(/*), ':', string(count(//@qty)))", "order:2"},
+    {"substring('12345', 1.5, 2.6)", "234"},
+    {"substring-before('2024-01-02', '-')", "2024"},
+    {"substring-after('2024-01-02', '-')", "01-02"},
+    {"translate('bar', 'abc', 'AB')", "BAr"},
+    {"round(2.5) + floor(-1.5) + ceiling(0.2)", 2.0},
+    {"number('abc') = number('abc')", false},
+    {"string(1 div 0)", "Infinity"},
+    {"string(0.1 + 0.2 > 0.3)", "true"},
+    {"boolean('') or boolean(0)", false},
+  }
+
+  for _, test := range tests {
+    got, err := queryXPath(root, test.expression)
+    if nil != err {
+      t.Errorf("queryXPath(%q): unexpected error: %s", test.expression, err)
+      continue
+    }
+
+    if !reflect.DeepEqual(test.want, got) {
+      t.Errorf("queryXPath(%q) = %#v, want %#v", test.expression, got, test.want)
+    }
+  }
+}
+
+func TestXMLNodeMarkup(t *testing.T) {
+  xmlRoot, _ := parseXMLNodes([]byte(`<s:Envelope xmlns:s="urn:soap"><s:Body><r a="1 &amp; 2"/><t>x &lt; y</t></s:Body></s:Envelope>`))
+  htmlRoot, _ := parseHTMLNodes([]byte(`<ul><li>One<br>two</li><li class="x">A &amp; B</li></ul><script>if (a < b) {}</script>`))
+
+  tests := []struct {
+    root       *xmlNode
+    expression string
+    asHTML     bool
+    want       []string
+  }{
+    {xmlRoot, "//Body", false, []string{`<s:Body><r a="1 &amp; 2"/><t>x &lt; y</t></s:Body>`}},
+    {xmlRoot, "/*", false, []string{`<s:Envelope xmlns:s="urn:soap"><s:Body><r a="1 &amp; 2"/><t>x &lt; y</t></s:Body></s:Envelope>`}},
+    {xmlRoot, "//@a | //t/text()", false, []string{"1 & 2", "x &lt; y"}},
+    {htmlRoot, "//li", true, []string{"<li>One<br>two</li>", `<li class="x">A &amp; B</li>`}},
+    {htmlRoot, "//script", true, []string{"<script>if (a < b) {}</script>"}},
+  }
+
+  for _, test := range tests {
+    nodes, err := evalXPath(test.root, test.expression)
+    if nil != err {
+      t.Errorf("evalXPath(%q): unexpected error: %s", test.expression, err)
+      continue
+    }
+
+    var got []string
+    for _, node := range nodes {
+      got = append(got, node.markup(test.asHTML))
+    }
+
+    if !reflect.DeepEqual(test.want, got) {
+      t.Errorf("markup of %q = %q, want %q", test.expression, got, test.want)
+    }
+  }
+}
+
+func TestParseHTMLNodes(t *testing.T) {
+  root, err := parseHTMLNodes([]byte(`<!DOCTYPE html><title>Log in</title><form><input name="csrf" value="t0k3n"><p>Hello<br>world</form>`))
+  if nil != err {
+    t.Fatalf("unexpected error: %s", err)
+  }
+
+  for path, want := range map[string]string{
+    "/html/head/title":                 "Log in",
+    "//input[@name='csrf']/@value":     "t0k3n",
+    "//form/p":                         "Helloworld",
+  } {
+    if nodes, err := evalXPath(root, path); nil != err || 1 != len(nodes) || want != nodes[0].text() {
+      t.Errorf("evalXPath(%q) = %d nodes with error %v, want %q", path, len(nodes), err, want)
+    }
+  }
+
+  if _, err := parseXMLNodes([]byte("just text")); nil == err {
+    t.Error("parseXMLNodes read a document without a root element")
+  }
+}
+
+func TestFilterMarkup_budget(t *testing.T) {
+  document := []byte("<a>" + strings.Repeat("<b><c/></b>", 20000) + "</a>")
+  expr, err := parseXPath("//*/preceding::*")
+  if nil != err {
+    t.Fatalf("unexpected error: %s", err)
+  }
+
+  if _, _, err := filterMarkup(context.Background(), document, expr, xmlFormatter, "", false); nil == err || "the XPath expression goes through more than 16777216 nodes" != err.Error() {
+    t.Errorf("filterMarkup() error = %v", err)
+  }
+
+  // The evaluation stops at the step that overdraws the budget, which goes through no more nodes than the document has.
+  root, _ := parseXMLNodes(document)
+  budget := newXPathBudget(context.Background())
+  if _, err := expr.eval(xpathContext{node: root, position: 1, size: 1, budget: budget}); nil == err || budget.nodes >= 0 || budget.nodes < -len(root.all) {
+    t.Errorf("eval() error = %v with %d nodes left, want the budget used up by at most %d nodes", err, budget.nodes, len(root.all))
+  }
+
+  cancelled, cancel := context.WithCancel(context.Background())
+  cancel()
+  if _, _, err := filterMarkup(cancelled, document, expr, xmlFormatter, "", false); nil == err || "context canceled" != err.Error() {
+    t.Errorf("filterMarkup() error = %v, want context canceled", err)
+  }
+}